@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/durandj/ley/cmd/leyctl/subcommand"
+	"github.com/fatih/color"
+)
+
+func main() {
+	ctx := context.Background()
+	ctx, done := signal.NotifyContext(ctx, os.Interrupt, os.Kill)
+
+	cmd := subcommand.NewRootCommand()
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		color.Red(err.Error())
+		done()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,167 @@
+package subcommand
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newApplyCommand(options *globalOptions) *cobra.Command {
+	var files []string
+	var dryRun bool
+	var prune bool
+
+	cmd := cobra.Command{
+		Use:   "apply -f FILE",
+		Short: "Make networks and users match a manifest",
+		Long: `Make networks and users match the resources of YAML manifests.
+
+Files can hold several resources separated by '---' and '-' reads from standard
+input. With --prune, the networks and users that aren't in the manifests are
+deleted, but only for the kinds of resources that the manifests have. Deleting a
+network also removes all of its nodes.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(files) == 0 {
+				return fmt.Errorf("At least one manifest has to be given with -f")
+			}
+
+			resources := []client.Resource{}
+			for _, file := range files {
+				fileResources, err := readManifest(cmd, file)
+				if err != nil {
+					return err
+				}
+
+				resources = append(resources, fileResources...)
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			applyResponse, err := apiClient.Apply(cmd.Context(), client.ApplyRequest{
+				Resources: resources,
+				DryRun:    dryRun,
+				Prune:     prune,
+			})
+			if err != nil {
+				return err
+			}
+
+			return options.write(cmd, applyResponse, newChangeTable(applyResponse.Changes...))
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&files, "filename", "f", nil, "Manifest to apply, can be repeated")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the changes that would be made")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete the resources that aren't in the manifests")
+
+	return &cmd
+}
+
+// readManifest reads every resource of a YAML manifest, which is read
+// from standard input when the path is '-'.
+func readManifest(cmd *cobra.Command, path string) ([]client.Resource, error) {
+	var reader io.Reader
+	if path == "-" {
+		reader = cmd.InOrStdin()
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to open manifest: %w", err)
+		}
+
+		defer func() {
+			_ = file.Close()
+		}()
+
+		reader = file
+	}
+
+	resources := []client.Resource{}
+	decoder := yaml.NewDecoder(reader)
+	for document := 1; ; document++ {
+		var rawResource any
+		if err := decoder.Decode(&rawResource); err != nil {
+			if errors.Is(err, io.EOF) {
+				return resources, nil
+			}
+
+			return nil, fmt.Errorf("Unable to read document %d of manifest '%s': %w", document, path, err)
+		}
+
+		// Empty documents, like the one after a trailing '---', are
+		// skipped.
+		if rawResource == nil {
+			continue
+		}
+
+		// The resource goes through JSON so that it is read the same way
+		// the manager reads it.
+		rawJSON, err := json.Marshal(rawResource)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read document %d of manifest '%s': %w", document, path, err)
+		}
+
+		jsonDecoder := json.NewDecoder(bytes.NewReader(rawJSON))
+		jsonDecoder.DisallowUnknownFields()
+
+		var resource client.Resource
+		if err := jsonDecoder.Decode(&resource); err != nil {
+			return nil, fmt.Errorf("Invalid resource in document %d of manifest '%s': %w", document, path, err)
+		}
+
+		resources = append(resources, resource)
+	}
+}
+
+func newChangeTable(changes ...client.Change) output.Table {
+	table := output.Table{
+		Headers: []string{"KIND", "NAME", "ACTION", "CHANGES"},
+	}
+
+	for _, change := range changes {
+		fields := make([]string, len(change.Fields))
+		for index, field := range change.Fields {
+			fields[index] = fmt.Sprintf(
+				"%s: %s -> %s",
+				field.Field,
+				describeFieldValue(field.From),
+				describeFieldValue(field.To),
+			)
+		}
+
+		description := "-"
+		if len(fields) > 0 {
+			description = strings.Join(fields, ", ")
+		}
+
+		table.Rows = append(table.Rows, []string{
+			string(change.Kind),
+			change.Name,
+			string(change.Action),
+			description,
+		})
+	}
+
+	return table
+}
+
+func describeFieldValue(value *string) string {
+	if value == nil {
+		return "<none>"
+	}
+
+	return *value
+}
@@ -0,0 +1,181 @@
+package subcommand
+
+import (
+	"fmt"
+
+	"github.com/durandj/ley/internal/leyctl/configuration"
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/spf13/cobra"
+)
+
+func newContextCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "context",
+		Short: "Manage the manager servers leyctl talks to",
+	}
+
+	cmd.AddCommand(
+		newContextSetCommand(options),
+		newContextUseCommand(options),
+		newContextListCommand(options),
+		newContextDeleteCommand(options),
+	)
+
+	return &cmd
+}
+
+func newContextSetCommand(options *globalOptions) *cobra.Command {
+	var serverURL string
+	var token string
+
+	cmd := cobra.Command{
+		Use:   "set NAME",
+		Short: "Create or update a context",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, path, err := options.loadConfiguration()
+			if err != nil {
+				return err
+			}
+
+			name := args[0]
+			context := configuration.Context{
+				Name:      name,
+				ServerURL: serverURL,
+				Token:     token,
+			}
+
+			if existingContext, err := config.GetContext(name); err == nil {
+				if !cmd.Flags().Changed("server") {
+					context.ServerURL = existingContext.ServerURL
+				}
+
+				if !cmd.Flags().Changed("token") {
+					context.Token = existingContext.Token
+				}
+			}
+
+			if context.ServerURL == "" {
+				return fmt.Errorf("A server URL is required for context '%s'", name)
+			}
+
+			config.SetContext(context)
+			if config.CurrentContext == "" {
+				config.CurrentContext = name
+			}
+
+			return config.Save(path)
+		},
+	}
+
+	cmd.Flags().StringVar(&serverURL, "server", "", "Base URL of the manager, e.g. http://localhost:8080")
+	cmd.Flags().StringVar(&token, "token", "", "API token to send with every request")
+
+	return &cmd
+}
+
+func newContextUseCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:               "use NAME",
+		Short:             "Select the context used by default",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSingleContextName(options),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, path, err := options.loadConfiguration()
+			if err != nil {
+				return err
+			}
+
+			if _, err := config.GetContext(args[0]); err != nil {
+				return err
+			}
+
+			config.CurrentContext = args[0]
+
+			return config.Save(path)
+		},
+	}
+}
+
+func newContextListCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all contexts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, _, err := options.loadConfiguration()
+			if err != nil {
+				return err
+			}
+
+			table := output.Table{
+				Headers: []string{"CURRENT", "NAME", "SERVER"},
+			}
+
+			for _, context := range config.Contexts {
+				current := ""
+				if context.Name == config.CurrentContext {
+					current = "*"
+				}
+
+				table.Rows = append(table.Rows, []string{current, context.Name, context.ServerURL})
+			}
+
+			return options.write(cmd, redactTokens(config), table)
+		},
+	}
+}
+
+func newContextDeleteCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:               "delete NAME",
+		Short:             "Remove a context",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSingleContextName(options),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, path, err := options.loadConfiguration()
+			if err != nil {
+				return err
+			}
+
+			if err := config.DeleteContext(args[0]); err != nil {
+				return err
+			}
+
+			return config.Save(path)
+		},
+	}
+}
+
+func completeSingleContextName(options *globalOptions) func(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return options.completeContextNames(cmd, args, toComplete)
+	}
+}
+
+// redactTokens hides API tokens so that listing contexts doesn't leak
+// them into terminal history or logs.
+func redactTokens(config *configuration.Configuration) *configuration.Configuration {
+	redactedConfig := configuration.Configuration{
+		CurrentContext: config.CurrentContext,
+		Contexts:       make([]configuration.Context, len(config.Contexts)),
+	}
+
+	for index, context := range config.Contexts {
+		if context.Token != "" {
+			context.Token = "REDACTED"
+		}
+
+		redactedConfig.Contexts[index] = context
+	}
+
+	return &redactedConfig
+}
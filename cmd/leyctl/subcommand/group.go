@@ -0,0 +1,307 @@
+package subcommand
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newGroupCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "group",
+		Aliases: []string{"groups"},
+		Short:   "Manage groups of users and the groups nested in them",
+	}
+
+	cmd.AddCommand(
+		newGroupCreateCommand(options),
+		newGroupGetCommand(options),
+		newGroupListCommand(options),
+		newGroupDeleteCommand(options),
+		newGroupMemberCommand(options),
+		newGroupEffectiveCommand(options),
+	)
+
+	return &cmd
+}
+
+func newGroupCreateCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a new group",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			createGroupResponse, err := apiClient.CreateGroup(cmd.Context(), client.CreateGroupRequest{Name: args[0]})
+			if err != nil {
+				return err
+			}
+
+			return options.write(cmd, createGroupResponse, newGroupTable(createGroupResponse.Group))
+		},
+	}
+}
+
+func newGroupGetCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get NAME",
+		Short: "Get a group by its name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			getGroupResponse, err := apiClient.GetGroup(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return options.write(cmd, getGroupResponse, newGroupTable(getGroupResponse.Group))
+		},
+	}
+}
+
+func newGroupListCommand(options *globalOptions) *cobra.Command {
+	var flags listFlags
+
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "List groups",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listOpts, err := flags.opts()
+			if err != nil {
+				return err
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listGroupsResponse, err := apiClient.ListGroups(cmd.Context(), listOpts)
+			if err != nil {
+				return err
+			}
+
+			return options.writePage(
+				cmd,
+				listGroupsResponse,
+				newGroupTable(listGroupsResponse.Groups...),
+				listGroupsResponse.NextCursor,
+			)
+		},
+	}
+
+	flags.register(&cmd, false)
+
+	return &cmd
+}
+
+func newGroupDeleteCommand(options *globalOptions) *cobra.Command {
+	var expectedVersion int64
+
+	cmd := cobra.Command{
+		Use:   "delete NAME",
+		Short: "Remove a group along with its memberships",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			return apiClient.DeleteGroup(cmd.Context(), args[0], expectedVersion)
+		},
+	}
+
+	cmd.Flags().Int64Var(&expectedVersion, "if-version", 0, "Only remove the group if it is at this version")
+
+	return &cmd
+}
+
+func newGroupMemberCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "member",
+		Aliases: []string{"members"},
+		Short:   "Manage the users and groups in a group",
+	}
+
+	cmd.AddCommand(
+		newGroupMemberAddCommand(options),
+		newGroupMemberRemoveCommand(options),
+		newGroupMemberListCommand(options),
+	)
+
+	return &cmd
+}
+
+func newGroupMemberAddCommand(options *globalOptions) *cobra.Command {
+	var nested bool
+
+	cmd := cobra.Command{
+		Use:   "add GROUP NAME",
+		Short: "Add a user, or with --group a nested group, to a group",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			addMemberResponse, err := apiClient.AddGroupMember(cmd.Context(), args[0], memberKind(nested), args[1])
+			if err != nil {
+				return err
+			}
+
+			return options.write(cmd, addMemberResponse, newGroupMemberTable(addMemberResponse.GroupMember))
+		},
+	}
+
+	cmd.Flags().BoolVar(&nested, "group", false, "Treat NAME as a group to nest instead of a username")
+
+	return &cmd
+}
+
+func newGroupMemberRemoveCommand(options *globalOptions) *cobra.Command {
+	var nested bool
+
+	cmd := cobra.Command{
+		Use:   "remove GROUP NAME",
+		Short: "Take a user, or with --group a nested group, out of a group",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			return apiClient.RemoveGroupMember(cmd.Context(), args[0], memberKind(nested), args[1])
+		},
+	}
+
+	cmd.Flags().BoolVar(&nested, "group", false, "Treat NAME as a nested group instead of a username")
+
+	return &cmd
+}
+
+func newGroupMemberListCommand(options *globalOptions) *cobra.Command {
+	var flags listFlags
+
+	cmd := cobra.Command{
+		Use:   "list GROUP",
+		Short: "List the users and groups added directly to a group",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listOpts, err := flags.opts()
+			if err != nil {
+				return err
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listMembersResponse, err := apiClient.ListGroupMembers(cmd.Context(), args[0], listOpts)
+			if err != nil {
+				return err
+			}
+
+			return options.writePage(
+				cmd,
+				listMembersResponse,
+				newGroupMemberTable(listMembersResponse.Members...),
+				listMembersResponse.NextCursor,
+			)
+		},
+	}
+
+	flags.register(&cmd, false)
+
+	return &cmd
+}
+
+func newGroupEffectiveCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "effective USERNAME",
+		Short: "Show every group a user belongs to and why",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listEffectiveGroupsResponse, err := apiClient.ListEffectiveGroups(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			table := output.Table{
+				Headers: []string{"GROUP", "DIRECT", "VIA"},
+			}
+
+			for _, effectiveGroup := range listEffectiveGroupsResponse.Groups {
+				table.Rows = append(table.Rows, []string{
+					effectiveGroup.Name,
+					strconv.FormatBool(effectiveGroup.Direct),
+					strings.Join(effectiveGroup.Path, " > "),
+				})
+			}
+
+			return options.write(cmd, listEffectiveGroupsResponse, table)
+		},
+	}
+}
+
+func memberKind(nested bool) client.GroupMemberKind {
+	if nested {
+		return client.GroupMemberKindGroup
+	}
+
+	return client.GroupMemberKindUser
+}
+
+func newGroupTable(groups ...client.Group) output.Table {
+	table := output.Table{
+		Headers: []string{"NAME", "VERSION", "CREATED", "MODIFIED"},
+	}
+
+	for _, renderableGroup := range groups {
+		table.Rows = append(table.Rows, []string{
+			renderableGroup.Name,
+			strconv.FormatInt(renderableGroup.Version, 10),
+			time.Time(renderableGroup.CreatedOn).Format(time.RFC3339),
+			time.Time(renderableGroup.ModifiedOn).Format(time.RFC3339),
+		})
+	}
+
+	return table
+}
+
+func newGroupMemberTable(members ...client.GroupMember) output.Table {
+	table := output.Table{
+		Headers: []string{"KIND", "NAME", "ADDED"},
+	}
+
+	for _, renderableMember := range members {
+		table.Rows = append(table.Rows, []string{
+			string(renderableMember.Kind),
+			renderableMember.Name,
+			time.Time(renderableMember.AddedOn).Format(time.RFC3339),
+		})
+	}
+
+	return table
+}
@@ -0,0 +1,100 @@
+package subcommand
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+type listFlags struct {
+	limit         int
+	cursor        string
+	namePrefix    string
+	createdAfter  string
+	createdBefore string
+	labels        []string
+	sort          string
+	order         string
+}
+
+func (flags *listFlags) register(cmd *cobra.Command, supportsLabels bool) {
+	cmd.Flags().IntVar(&flags.limit, "limit", 0, "Maximum number of results to return")
+	cmd.Flags().StringVar(&flags.cursor, "cursor", "", "Cursor returned by the previous page of results")
+	cmd.Flags().StringVar(&flags.namePrefix, "name-prefix", "", "Only include results whose name starts with this")
+	cmd.Flags().StringVar(&flags.createdAfter, "created-after", "", "Only include results created at or after this time")
+	cmd.Flags().StringVar(&flags.createdBefore, "created-before", "", "Only include results created before this time")
+	cmd.Flags().StringVar(&flags.sort, "sort", "", "Field to sort by, one of createdOn or name")
+	cmd.Flags().StringVar(&flags.order, "order", "", "Sort order, one of asc or desc")
+
+	if supportsLabels {
+		cmd.Flags().StringArrayVar(&flags.labels, "label", nil, "Only include results with this key=value label")
+	}
+}
+
+func (flags *listFlags) opts() (client.ListOpts, error) {
+	opts := client.ListOpts{
+		Limit:      flags.limit,
+		Cursor:     flags.cursor,
+		NamePrefix: flags.namePrefix,
+		Sort:       flags.sort,
+		Order:      flags.order,
+	}
+
+	for _, timeFlag := range []struct {
+		name  string
+		raw   string
+		value *time.Time
+	}{
+		{name: "created-after", raw: flags.createdAfter, value: &opts.CreatedAfter},
+		{name: "created-before", raw: flags.createdBefore, value: &opts.CreatedBefore},
+	} {
+		if timeFlag.raw == "" {
+			continue
+		}
+
+		parsedTime, err := time.Parse(time.RFC3339, timeFlag.raw)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid value for --%s, expected an RFC 3339 time: %w", timeFlag.name, err)
+		}
+
+		*timeFlag.value = parsedTime
+	}
+
+	for _, rawLabel := range flags.labels {
+		key, value, ok := strings.Cut(rawLabel, "=")
+		if !ok {
+			return opts, fmt.Errorf("Invalid label '%s', expected key=value", rawLabel)
+		}
+
+		if opts.Labels == nil {
+			opts.Labels = map[string]string{}
+		}
+
+		opts.Labels[key] = value
+	}
+
+	return opts, nil
+}
+
+// writePage writes a page of list results and lets the user know how
+// to get the next page when showing a table.
+func (options *globalOptions) writePage(
+	cmd *cobra.Command,
+	value any,
+	table output.Table,
+	nextCursor string,
+) error {
+	if err := options.write(cmd, value, table); err != nil {
+		return err
+	}
+
+	if nextCursor != "" && options.outputFormat == output.FormatTable {
+		cmd.PrintErrf("More results are available, pass --cursor %s to see them\n", nextCursor)
+	}
+
+	return nil
+}
@@ -0,0 +1,354 @@
+package subcommand
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+	"inet.af/netaddr"
+)
+
+func newNetworkCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "network",
+		Aliases: []string{"networks"},
+		Short:   "Manage networks",
+	}
+
+	cmd.AddCommand(
+		newNetworkCreateCommand(options),
+		newNetworkGetCommand(options),
+		newNetworkListCommand(options),
+		newNetworkUpdateCommand(options),
+		newNetworkDeleteCommand(options),
+		newNetworkAddressSpaceCommand(options),
+	)
+
+	return &cmd
+}
+
+func newNetworkCreateCommand(options *globalOptions) *cobra.Command {
+	var ipv4CIDR string
+	var ipv6CIDR string
+	var labels map[string]string
+	var allowOverlap bool
+	var ipv4PrefixLength int
+	var generateIPv6 bool
+	var presharedKeys bool
+	var topology topologyFlags
+
+	cmd := cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a new network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			createNetworkRequest := client.CreateNetworkRequest{
+				Name:          args[0],
+				Labels:        labels,
+				AllowOverlap:  allowOverlap,
+				GenerateIPv6:  generateIPv6,
+				PresharedKeys: presharedKeys,
+			}
+
+			if cmd.Flags().Changed("ipv4-prefix-length") {
+				createNetworkRequest.IPv4PrefixLength = &ipv4PrefixLength
+			}
+
+			requestedTopology, err := topology.topology()
+			if err != nil {
+				return err
+			}
+
+			createNetworkRequest.Topology = requestedTopology
+
+			if ipv4CIDR != "" {
+				prefix, err := netaddr.ParseIPPrefix(ipv4CIDR)
+				if err != nil {
+					return fmt.Errorf("Invalid IPv4 CIDR '%s': %w", ipv4CIDR, err)
+				}
+
+				createNetworkRequest.IPv4CIDR = &prefix
+			}
+
+			if ipv6CIDR != "" {
+				prefix, err := netaddr.ParseIPPrefix(ipv6CIDR)
+				if err != nil {
+					return fmt.Errorf("Invalid IPv6 CIDR '%s': %w", ipv6CIDR, err)
+				}
+
+				createNetworkRequest.IPv6CIDR = &prefix
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			createNetworkResponse, err := apiClient.CreateNetwork(cmd.Context(), createNetworkRequest)
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				createNetworkResponse,
+				newNetworkTable(createNetworkResponse.Network),
+			)
+		},
+	}
+
+	cmd.Flags().StringVar(&ipv4CIDR, "ipv4-cidr", "", "IPv4 address range for the network, e.g. 10.0.0.0/24")
+	cmd.Flags().StringVar(&ipv6CIDR, "ipv6-cidr", "", "IPv6 address range for the network, e.g. fd00::/64")
+	cmd.Flags().IntVar(
+		&ipv4PrefixLength,
+		"ipv4-prefix-length",
+		0,
+		"Take the next free IPv4 range of this size from the manager's pools, e.g. 24",
+	)
+	cmd.Flags().BoolVar(&generateIPv6, "generate-ipv6", false, "Generate a random unique local IPv6 range")
+	cmd.Flags().StringToStringVar(&labels, "label", nil, "Labels to attach to the network as key=value pairs")
+	cmd.Flags().BoolVar(
+		&allowOverlap,
+		"allow-overlap",
+		false,
+		"Allow the network's ranges to overlap other networks, for isolated tenants",
+	)
+	cmd.Flags().BoolVar(&presharedKeys, "preshared-keys", false, "Give every pair of nodes a preshared key")
+	topology.register(&cmd)
+
+	return &cmd
+}
+
+func newNetworkListCommand(options *globalOptions) *cobra.Command {
+	var flags listFlags
+
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "List networks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listOpts, err := flags.opts()
+			if err != nil {
+				return err
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listNetworksResponse, err := apiClient.ListNetworks(cmd.Context(), listOpts)
+			if err != nil {
+				return err
+			}
+
+			return options.writePage(
+				cmd,
+				listNetworksResponse,
+				newNetworkTable(listNetworksResponse.Networks...),
+				listNetworksResponse.NextCursor,
+			)
+		},
+	}
+
+	flags.register(&cmd, true)
+
+	return &cmd
+}
+
+func newNetworkGetCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get NAME",
+		Short: "Get a network by its name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			getNetworkResponse, err := apiClient.GetNetwork(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				getNetworkResponse,
+				newNetworkTable(getNetworkResponse.Network),
+			)
+		},
+	}
+}
+
+func newNetworkUpdateCommand(options *globalOptions) *cobra.Command {
+	var newName string
+	var labels map[string]string
+	var expectedVersion int64
+	var presharedKeys bool
+	var topology topologyFlags
+
+	cmd := cobra.Command{
+		Use:   "update NAME",
+		Short: "Change a network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var updateNetworkRequest client.UpdateNetworkRequest
+			if cmd.Flags().Changed("name") {
+				updateNetworkRequest.Name = &newName
+			}
+
+			if cmd.Flags().Changed("label") {
+				updateNetworkRequest.Labels = labels
+			}
+
+			if cmd.Flags().Changed("preshared-keys") {
+				updateNetworkRequest.PresharedKeys = &presharedKeys
+			}
+
+			requestedTopology, err := topology.topology()
+			if err != nil {
+				return err
+			}
+
+			updateNetworkRequest.Topology = requestedTopology
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			updateNetworkResponse, err := apiClient.UpdateNetwork(
+				cmd.Context(),
+				args[0],
+				expectedVersion,
+				updateNetworkRequest,
+			)
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				updateNetworkResponse,
+				newNetworkTable(updateNetworkResponse.Network),
+			)
+		},
+	}
+
+	cmd.Flags().StringVar(&newName, "name", "", "New name for the network")
+	cmd.Flags().StringToStringVar(&labels, "label", nil, "Replace the network's labels with these key=value pairs")
+	cmd.Flags().BoolVar(&presharedKeys, "preshared-keys", false, "Give every pair of nodes a preshared key")
+	cmd.Flags().Int64Var(&expectedVersion, "if-version", 0, "Only change the network if it is at this version")
+	topology.register(&cmd)
+
+	return &cmd
+}
+
+func newNetworkDeleteCommand(options *globalOptions) *cobra.Command {
+	var expectedVersion int64
+
+	cmd := cobra.Command{
+		Use:   "delete NAME",
+		Short: "Remove a network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			return apiClient.DeleteNetwork(cmd.Context(), args[0], expectedVersion)
+		},
+	}
+
+	cmd.Flags().Int64Var(&expectedVersion, "if-version", 0, "Only remove the network if it is at this version")
+
+	return &cmd
+}
+
+func newNetworkAddressSpaceCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "address-space",
+		Short: "Show the used and free ranges within the configured supernets",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			addressSpace, err := apiClient.GetAddressSpace(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return options.write(cmd, addressSpace, newAddressSpaceTable(addressSpace))
+		},
+	}
+}
+
+func newAddressSpaceTable(addressSpace *client.AddressSpace) output.Table {
+	table := output.Table{
+		Headers: []string{"SUPERNET", "RANGE", "STATUS", "ORGANIZATION", "NETWORK"},
+	}
+
+	for _, space := range []*client.AddressFamilySpace{addressSpace.IPv4, addressSpace.IPv6} {
+		if space == nil {
+			continue
+		}
+
+		for _, usedRange := range space.Used {
+			table.Rows = append(table.Rows, []string{
+				space.Supernet.String(),
+				usedRange.CIDR.String(),
+				"used",
+				usedRange.Organization,
+				usedRange.Network,
+			})
+		}
+
+		for _, freeRange := range space.Free {
+			table.Rows = append(table.Rows, []string{
+				space.Supernet.String(),
+				freeRange.String(),
+				"free",
+				"-",
+				"-",
+			})
+		}
+	}
+
+	return table
+}
+
+func newNetworkTable(networks ...client.Network) output.Table {
+	table := output.Table{
+		Headers: []string{"NAME", "IPV4 CIDR", "IPV6 CIDR", "TOPOLOGY", "VERSION", "CREATED", "MODIFIED"},
+	}
+
+	for _, renderableNetwork := range networks {
+		table.Rows = append(table.Rows, []string{
+			renderableNetwork.Name,
+			formatPrefix(renderableNetwork.IPv4CIDR),
+			formatPrefix(renderableNetwork.IPv6CIDR),
+			string(renderableNetwork.Topology.Mode),
+			strconv.FormatInt(renderableNetwork.Version, 10),
+			time.Time(renderableNetwork.CreatedOn).Format(time.RFC3339),
+			time.Time(renderableNetwork.ModifiedOn).Format(time.RFC3339),
+		})
+	}
+
+	return table
+}
+
+func formatPrefix(prefix *netaddr.IPPrefix) string {
+	if prefix == nil {
+		return "-"
+	}
+
+	return prefix.String()
+}
@@ -0,0 +1,307 @@
+package subcommand
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+	"inet.af/netaddr"
+)
+
+// nodeSecretEnvironmentVariable can hold the secret of the node to
+// make requests as.
+const nodeSecretEnvironmentVariable = "LEYCTL_NODE_SECRET"
+
+func newNodeCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "node",
+		Aliases: []string{"nodes"},
+		Short:   "Manage nodes",
+	}
+
+	cmd.AddCommand(
+		newNodeGetCommand(options),
+		newNodeListCommand(options),
+		newNodeDeleteCommand(options),
+		newNodeRotateKeyCommand(options),
+		newNodeResetSecretCommand(options),
+		newNodePeerStatsCommand(options),
+	)
+
+	return &cmd
+}
+
+func newNodeListCommand(options *globalOptions) *cobra.Command {
+	var flags listFlags
+	var networkName string
+	var publicKey string
+	var status string
+
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "List nodes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listOpts, err := flags.opts()
+			if err != nil {
+				return err
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listNodesResponse, err := apiClient.ListNodes(cmd.Context(), client.ListNodesOpts{
+				ListOpts:  listOpts,
+				Network:   networkName,
+				PublicKey: publicKey,
+				Status:    client.NodeStatus(status),
+			})
+			if err != nil {
+				return err
+			}
+
+			return options.writePage(
+				cmd,
+				listNodesResponse,
+				newNodeTable(listNodesResponse.Nodes...),
+				listNodesResponse.NextCursor,
+			)
+		},
+	}
+
+	flags.register(&cmd, false)
+	cmd.Flags().StringVar(&networkName, "network", "", "Only include nodes in this network")
+	cmd.Flags().StringVar(&publicKey, "public-key", "", "Only include the node using this public key")
+	cmd.Flags().StringVar(&status, "status", "", "Only include nodes with this status, one of online, stale or offline")
+
+	return &cmd
+}
+
+func newNodeGetCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get ID",
+		Short: "Get a node by its ID",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			getNodeResponse, err := apiClient.GetNode(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				getNodeResponse,
+				newNodeTable(getNodeResponse.Node),
+			)
+		},
+	}
+}
+
+func newNodeDeleteCommand(options *globalOptions) *cobra.Command {
+	var expectedVersion int64
+
+	cmd := cobra.Command{
+		Use:   "delete ID",
+		Short: "Remove a node from its network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			return apiClient.DeleteNode(cmd.Context(), args[0], expectedVersion)
+		},
+	}
+
+	cmd.Flags().Int64Var(&expectedVersion, "if-version", 0, "Only remove the node if it is at this version")
+
+	return &cmd
+}
+
+func newNodeRotateKeyCommand(options *globalOptions) *cobra.Command {
+	var expectedVersion int64
+
+	cmd := cobra.Command{
+		Use:   "rotate-key ID",
+		Short: "Ask a node to rotate its key the next time it checks in",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			requestKeyRotationResponse, err := apiClient.RequestNodeKeyRotation(
+				cmd.Context(),
+				args[0],
+				expectedVersion,
+			)
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				requestKeyRotationResponse,
+				newNodeTable(requestKeyRotationResponse.Node),
+			)
+		},
+	}
+
+	cmd.Flags().Int64Var(&expectedVersion, "if-version", 0, "Only request the rotation if the node is at this version")
+
+	return &cmd
+}
+
+func newNodeResetSecretCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reset-secret ID",
+		Short: "Issue a node a new secret, the old one stops working",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			resetNodeSecretResponse, err := apiClient.ResetNodeSecret(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				resetNodeSecretResponse,
+				output.Table{
+					Headers: []string{"ID", "NAME", "SECRET"},
+					Rows: [][]string{{
+						resetNodeSecretResponse.ID,
+						resetNodeSecretResponse.Name,
+						resetNodeSecretResponse.Secret,
+					}},
+				},
+			)
+		},
+	}
+}
+
+func newNodePeerStatsCommand(options *globalOptions) *cobra.Command {
+	var secret string
+
+	cmd := cobra.Command{
+		Use:   "peer-stats ID",
+		Short: "Show the peer stats from a node's last heartbeat",
+		Long: fmt.Sprintf(
+			"Show the peer stats from a node's last heartbeat. Only the node can see these, "+
+				"so its secret has to be given with --secret or %s.",
+			nodeSecretEnvironmentVariable,
+		),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if secret == "" {
+				secret = os.Getenv(nodeSecretEnvironmentVariable)
+			}
+
+			if secret == "" {
+				return fmt.Errorf("The node's secret is required, set it with --secret or %s", nodeSecretEnvironmentVariable)
+			}
+
+			apiClient, err := options.newClientWithToken(secret)
+			if err != nil {
+				return err
+			}
+
+			listPeerStatsResponse, err := apiClient.ListPeerStats(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return options.write(cmd, listPeerStatsResponse, newPeerStatsTable(listPeerStatsResponse.Peers...))
+		},
+	}
+
+	cmd.Flags().StringVar(&secret, "secret", "", "Secret of the node")
+
+	return &cmd
+}
+
+func newPeerStatsTable(peerStats ...client.PeerStats) output.Table {
+	table := output.Table{
+		Headers: []string{"NAME", "PUBLIC KEY", "ENDPOINT", "LATEST HANDSHAKE", "RECEIVED", "SENT"},
+	}
+
+	for _, stats := range peerStats {
+		name := stats.Name
+		if name == "" {
+			name = "-"
+		}
+
+		endpoint := "-"
+		if stats.Endpoint != nil {
+			endpoint = *stats.Endpoint
+		}
+
+		latestHandshake := "-"
+		if stats.LatestHandshake != nil {
+			latestHandshake = time.Time(*stats.LatestHandshake).Format(time.RFC3339)
+		}
+
+		table.Rows = append(table.Rows, []string{
+			name,
+			stats.PublicKey,
+			endpoint,
+			latestHandshake,
+			strconv.FormatInt(stats.ReceiveBytes, 10),
+			strconv.FormatInt(stats.TransmitBytes, 10),
+		})
+	}
+
+	return table
+}
+
+func newNodeTable(nodes ...client.Node) output.Table {
+	table := output.Table{
+		Headers: []string{"ID", "NETWORK", "NAME", "IPV4", "IPV6", "STATUS", "LAST SEEN", "ROTATION DUE", "VERSION"},
+	}
+
+	for _, renderableNode := range nodes {
+		lastSeen := "-"
+		if renderableNode.LastSeenOn != nil {
+			lastSeen = time.Time(*renderableNode.LastSeenOn).Format(time.RFC3339)
+		}
+
+		table.Rows = append(table.Rows, []string{
+			renderableNode.ID,
+			renderableNode.Network,
+			renderableNode.Name,
+			formatIP(renderableNode.IPv4Address),
+			formatIP(renderableNode.IPv6Address),
+			string(renderableNode.Status),
+			lastSeen,
+			strconv.FormatBool(renderableNode.KeyRotationDue),
+			strconv.FormatInt(renderableNode.Version, 10),
+		})
+	}
+
+	return table
+}
+
+func formatIP(ip *netaddr.IP) string {
+	if ip == nil {
+		return "-"
+	}
+
+	return ip.String()
+}
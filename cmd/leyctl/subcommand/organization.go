@@ -0,0 +1,276 @@
+package subcommand
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newOrganizationCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "org",
+		Aliases: []string{"orgs", "organization", "organizations"},
+		Short:   "Manage organizations and their members",
+	}
+
+	cmd.AddCommand(
+		newOrganizationCreateCommand(options),
+		newOrganizationGetCommand(options),
+		newOrganizationListCommand(options),
+		newOrganizationDeleteCommand(options),
+		newOrganizationMemberCommand(options),
+	)
+
+	return &cmd
+}
+
+func newOrganizationCreateCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a new organization",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			createOrganizationResponse, err := apiClient.CreateOrganization(
+				cmd.Context(),
+				client.CreateOrganizationRequest{Name: args[0]},
+			)
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				createOrganizationResponse,
+				newOrganizationTable(createOrganizationResponse.Organization),
+			)
+		},
+	}
+}
+
+func newOrganizationGetCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get NAME",
+		Short: "Get an organization by its name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			getOrganizationResponse, err := apiClient.GetOrganization(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				getOrganizationResponse,
+				newOrganizationTable(getOrganizationResponse.Organization),
+			)
+		},
+	}
+}
+
+func newOrganizationListCommand(options *globalOptions) *cobra.Command {
+	var flags listFlags
+	var member string
+
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "List organizations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listOpts, err := flags.opts()
+			if err != nil {
+				return err
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listOrganizationsResponse, err := apiClient.ListOrganizations(cmd.Context(), client.ListOrganizationsOpts{
+				ListOpts: listOpts,
+				Member:   member,
+			})
+			if err != nil {
+				return err
+			}
+
+			return options.writePage(
+				cmd,
+				listOrganizationsResponse,
+				newOrganizationTable(listOrganizationsResponse.Organizations...),
+				listOrganizationsResponse.NextCursor,
+			)
+		},
+	}
+
+	flags.register(&cmd, false)
+	cmd.Flags().StringVar(&member, "member", "", "Only include organizations this user is a member of")
+
+	return &cmd
+}
+
+func newOrganizationDeleteCommand(options *globalOptions) *cobra.Command {
+	var expectedVersion int64
+
+	cmd := cobra.Command{
+		Use:   "delete NAME",
+		Short: "Remove an organization that has no networks left",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			return apiClient.DeleteOrganization(cmd.Context(), args[0], expectedVersion)
+		},
+	}
+
+	cmd.Flags().Int64Var(
+		&expectedVersion,
+		"if-version",
+		0,
+		"Only remove the organization if it is at this version",
+	)
+
+	return &cmd
+}
+
+func newOrganizationMemberCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "member",
+		Aliases: []string{"members"},
+		Short:   "Manage the members of an organization",
+	}
+
+	cmd.AddCommand(
+		newOrganizationMemberAddCommand(options),
+		newOrganizationMemberRemoveCommand(options),
+		newOrganizationMemberListCommand(options),
+	)
+
+	return &cmd
+}
+
+func newOrganizationMemberAddCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "add ORGANIZATION USERNAME",
+		Short: "Make a user a member of an organization",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			addMemberResponse, err := apiClient.AddOrganizationMember(cmd.Context(), args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				addMemberResponse,
+				newOrganizationMemberTable(addMemberResponse.OrganizationMember),
+			)
+		},
+	}
+}
+
+func newOrganizationMemberRemoveCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove ORGANIZATION USERNAME",
+		Short: "Take a user out of an organization",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			return apiClient.RemoveOrganizationMember(cmd.Context(), args[0], args[1])
+		},
+	}
+}
+
+func newOrganizationMemberListCommand(options *globalOptions) *cobra.Command {
+	var flags listFlags
+
+	cmd := cobra.Command{
+		Use:   "list ORGANIZATION",
+		Short: "List the members of an organization",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listOpts, err := flags.opts()
+			if err != nil {
+				return err
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listMembersResponse, err := apiClient.ListOrganizationMembers(cmd.Context(), args[0], listOpts)
+			if err != nil {
+				return err
+			}
+
+			return options.writePage(
+				cmd,
+				listMembersResponse,
+				newOrganizationMemberTable(listMembersResponse.Members...),
+				listMembersResponse.NextCursor,
+			)
+		},
+	}
+
+	flags.register(&cmd, false)
+
+	return &cmd
+}
+
+func newOrganizationTable(organizations ...client.Organization) output.Table {
+	table := output.Table{
+		Headers: []string{"NAME", "VERSION", "CREATED", "MODIFIED"},
+	}
+
+	for _, renderableOrganization := range organizations {
+		table.Rows = append(table.Rows, []string{
+			renderableOrganization.Name,
+			strconv.FormatInt(renderableOrganization.Version, 10),
+			time.Time(renderableOrganization.CreatedOn).Format(time.RFC3339),
+			time.Time(renderableOrganization.ModifiedOn).Format(time.RFC3339),
+		})
+	}
+
+	return table
+}
+
+func newOrganizationMemberTable(members ...client.OrganizationMember) output.Table {
+	table := output.Table{
+		Headers: []string{"USERNAME", "JOINED"},
+	}
+
+	for _, renderableMember := range members {
+		table.Rows = append(table.Rows, []string{
+			renderableMember.Username,
+			time.Time(renderableMember.JoinedOn).Format(time.RFC3339),
+		})
+	}
+
+	return table
+}
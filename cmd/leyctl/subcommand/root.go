@@ -0,0 +1,162 @@
+package subcommand
+
+import (
+	"fmt"
+
+	"github.com/durandj/ley/internal/leyctl/configuration"
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// NewRootCommand creates a root command for the CLI to run.
+func NewRootCommand() *cobra.Command {
+	options := globalOptions{
+		outputFormat: output.FormatTable,
+	}
+
+	cmd := cobra.Command{
+		Use:           "leyctl",
+		Short:         "Command line client for the Ley network manager",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVar(
+		&options.configPath,
+		"config",
+		"",
+		fmt.Sprintf(
+			"Path to the configuration file (defaults to $%s or the user configuration directory)",
+			configuration.PathEnvironmentVariable,
+		),
+	)
+	flags.StringVar(&options.contextName, "context", "", "Name of the context to use instead of the current one")
+	flags.VarP(&options.outputFormat, "output", "o", "Output format, one of table, json or yaml")
+	flags.StringVar(
+		&options.organization,
+		"org",
+		"",
+		"Organization whose networks and nodes to work with (defaults to the default organization)",
+	)
+
+	_ = cmd.RegisterFlagCompletionFunc("context", options.completeContextNames)
+	_ = cmd.RegisterFlagCompletionFunc(
+		"output",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			formats := make([]string, len(output.SupportedFormats))
+			for index, format := range output.SupportedFormats {
+				formats[index] = string(format)
+			}
+
+			return formats, cobra.ShellCompDirectiveNoFileComp
+		},
+	)
+
+	cmd.AddCommand(
+		newContextCommand(&options),
+		newTokenCommand(&options),
+		newUserCommand(&options),
+		newOrganizationCommand(&options),
+		newGroupCommand(&options),
+		newNetworkCommand(&options),
+		newNodeCommand(&options),
+		newWebhookCommand(&options),
+		newApplyCommand(&options),
+	)
+
+	return &cmd
+}
+
+type globalOptions struct {
+	configPath   string
+	contextName  string
+	outputFormat output.Format
+	organization string
+}
+
+func (options *globalOptions) resolveConfigPath() (string, error) {
+	if options.configPath != "" {
+		return options.configPath, nil
+	}
+
+	return configuration.DefaultPath()
+}
+
+func (options *globalOptions) loadConfiguration() (*configuration.Configuration, string, error) {
+	path, err := options.resolveConfigPath()
+	if err != nil {
+		return nil, "", err
+	}
+
+	config, err := configuration.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return config, path, nil
+}
+
+func (options *globalOptions) newClient() (*client.Client, error) {
+	return options.newClientWithToken("")
+}
+
+// newClientWithToken creates a client for the selected context that
+// sends the given token instead of the context's, like the secret of a
+// node. The context's token is used when the token is empty.
+func (options *globalOptions) newClientWithToken(token string) (*client.Client, error) {
+	config, _, err := options.loadConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	context, err := options.selectedContext(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == "" {
+		token = context.Token
+	}
+
+	return client.New(context.ServerURL, client.Opts{
+		Token:           token,
+		Organization:    options.organization,
+		IdempotencyKeys: true,
+	}), nil
+}
+
+// selectedContext gives the context named by the --context flag or
+// else the current context.
+func (options *globalOptions) selectedContext(
+	config *configuration.Configuration,
+) (*configuration.Context, error) {
+	contextName := options.contextName
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+
+	if contextName == "" {
+		return nil, fmt.Errorf("No context selected, create one with 'leyctl context set'")
+	}
+
+	return config.GetContext(contextName)
+}
+
+func (options *globalOptions) write(cmd *cobra.Command, value any, table output.Table) error {
+	return output.Write(cmd.OutOrStdout(), options.outputFormat, value, table)
+}
+
+func (options *globalOptions) completeContextNames(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	config, _, err := options.loadConfiguration()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	return config.ContextNames(), cobra.ShellCompDirectiveNoFileComp
+}
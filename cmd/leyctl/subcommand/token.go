@@ -0,0 +1,126 @@
+package subcommand
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/spf13/cobra"
+)
+
+// tokenEnvironmentVariable can hold the token to store so that it
+// doesn't end up in the shell history.
+const tokenEnvironmentVariable = "LEYCTL_TOKEN"
+
+func newTokenCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "token",
+		Aliases: []string{"tokens"},
+		Short:   "Manage the API token of a context",
+	}
+
+	cmd.AddCommand(
+		newTokenSetCommand(options),
+		newTokenShowCommand(options),
+		newTokenRevokeCommand(options),
+	)
+
+	return &cmd
+}
+
+func newTokenSetCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set [TOKEN]",
+		Short: "Store the token that the context sends with every request",
+		Long: fmt.Sprintf(
+			"Store the token that the context sends with every request. The token can be set with %s instead.",
+			tokenEnvironmentVariable,
+		),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := os.Getenv(tokenEnvironmentVariable)
+			if len(args) > 0 {
+				token = args[0]
+			}
+
+			if token == "" {
+				return fmt.Errorf("A token is required, give it as an argument or with %s", tokenEnvironmentVariable)
+			}
+
+			config, path, err := options.loadConfiguration()
+			if err != nil {
+				return err
+			}
+
+			context, err := options.selectedContext(config)
+			if err != nil {
+				return err
+			}
+
+			context.Token = token
+
+			return config.Save(path)
+		},
+	}
+}
+
+func newTokenShowCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show the session that the context's token belongs to",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			getSessionResponse, err := apiClient.GetSession(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return options.write(cmd, getSessionResponse, output.Table{
+				Headers: []string{"USERNAME", "CREATED", "EXPIRES"},
+				Rows: [][]string{{
+					getSessionResponse.Username,
+					getSessionResponse.CreatedOn.Format(time.RFC3339),
+					getSessionResponse.ExpiresOn.Format(time.RFC3339),
+				}},
+			})
+		},
+	}
+}
+
+func newTokenRevokeCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke",
+		Short: "End the session of the context's token and forget the token",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			if err := apiClient.Logout(cmd.Context()); err != nil {
+				return err
+			}
+
+			config, path, err := options.loadConfiguration()
+			if err != nil {
+				return err
+			}
+
+			context, err := options.selectedContext(config)
+			if err != nil {
+				return err
+			}
+
+			context.Token = ""
+
+			return config.Save(path)
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package subcommand
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+type topologyFlags struct {
+	cmd        *cobra.Command
+	mode       string
+	hubs       []string
+	peerGroups []string
+}
+
+func (flags *topologyFlags) register(cmd *cobra.Command) {
+	flags.cmd = cmd
+
+	cmd.Flags().StringVar(
+		&flags.mode,
+		"topology",
+		"",
+		"How nodes peer with each other, one of full-mesh, hub-and-spoke or custom",
+	)
+	cmd.Flags().StringSliceVar(&flags.hubs, "hub", nil, "Hub nodes for a hub-and-spoke topology")
+	cmd.Flags().StringArrayVar(
+		&flags.peerGroups,
+		"peer-group",
+		nil,
+		"Peer group for a custom topology as name=node,node,...",
+	)
+}
+
+// topology builds the requested topology or nil if the topology flag
+// wasn't given.
+func (flags *topologyFlags) topology() (*client.Topology, error) {
+	if !flags.cmd.Flags().Changed("topology") {
+		if len(flags.hubs) > 0 || len(flags.peerGroups) > 0 {
+			return nil, fmt.Errorf("The --hub and --peer-group flags need --topology to be set")
+		}
+
+		return nil, nil
+	}
+
+	topology := client.Topology{
+		Mode: client.TopologyMode(flags.mode),
+		Hubs: flags.hubs,
+	}
+
+	for _, rawPeerGroup := range flags.peerGroups {
+		name, rawNodes, ok := strings.Cut(rawPeerGroup, "=")
+		if !ok || name == "" || rawNodes == "" {
+			return nil, fmt.Errorf("Invalid peer group '%s', expected name=node,node,...", rawPeerGroup)
+		}
+
+		topology.PeerGroups = append(topology.PeerGroups, client.PeerGroup{
+			Name:  name,
+			Nodes: strings.Split(rawNodes, ","),
+		})
+	}
+
+	return &topology, nil
+}
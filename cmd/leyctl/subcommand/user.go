@@ -0,0 +1,277 @@
+package subcommand
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newUserCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "user",
+		Aliases: []string{"users"},
+		Short:   "Manage users",
+	}
+
+	cmd.AddCommand(
+		newUserCreateCommand(options),
+		newUserGetCommand(options),
+		newUserListCommand(options),
+		newUserUpdateCommand(options),
+		newUserDeleteCommand(options),
+	)
+
+	return &cmd
+}
+
+func newUserCreateCommand(options *globalOptions) *cobra.Command {
+	var createUserRequest client.CreateUserRequest
+
+	cmd := cobra.Command{
+		Use:   "create USERNAME",
+		Short: "Create a new user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			createUserRequest.Username = args[0]
+
+			createUserResponse, err := apiClient.CreateUser(cmd.Context(), createUserRequest)
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				createUserResponse,
+				newUserTable(createUserResponse.User),
+			)
+		},
+	}
+
+	cmd.Flags().StringVar(&createUserRequest.DisplayName, "display-name", "", "Name that the user goes by")
+	cmd.Flags().StringVar(&createUserRequest.Email, "email", "", "Email address of the user")
+	cmd.Flags().StringVar(
+		&createUserRequest.ExternalID,
+		"external-id",
+		"",
+		"ID of the user in the system they were provisioned from",
+	)
+	cmd.Flags().StringToStringVar(
+		&createUserRequest.Metadata,
+		"metadata",
+		nil,
+		"Metadata to attach to the user as key=value pairs",
+	)
+
+	return &cmd
+}
+
+func newUserGetCommand(options *globalOptions) *cobra.Command {
+	var email string
+	var externalID string
+
+	cmd := cobra.Command{
+		Use:   "get [USERNAME]",
+		Short: "Get a user by their username, email address or external ID",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lookups := len(args)
+			for _, flag := range []string{"email", "external-id"} {
+				if cmd.Flags().Changed(flag) {
+					lookups++
+				}
+			}
+
+			if lookups != 1 {
+				return fmt.Errorf("Exactly one of a username, --email or --external-id has to be given")
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			var getUserByUsernameResponse *client.GetUserByUsernameResponse
+			switch {
+			case cmd.Flags().Changed("email"):
+				getUserByUsernameResponse, err = apiClient.GetUserByEmail(cmd.Context(), email)
+
+			case cmd.Flags().Changed("external-id"):
+				getUserByUsernameResponse, err = apiClient.GetUserByExternalID(cmd.Context(), externalID)
+
+			default:
+				getUserByUsernameResponse, err = apiClient.GetUserByUsername(cmd.Context(), args[0])
+			}
+
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				getUserByUsernameResponse,
+				newUserTable(getUserByUsernameResponse.User),
+			)
+		},
+	}
+
+	cmd.Flags().StringVar(&email, "email", "", "Get the user with this email address instead")
+	cmd.Flags().StringVar(&externalID, "external-id", "", "Get the user with this external ID instead")
+
+	return &cmd
+}
+
+func newUserListCommand(options *globalOptions) *cobra.Command {
+	var flags listFlags
+
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "List users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listOpts, err := flags.opts()
+			if err != nil {
+				return err
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listUsersResponse, err := apiClient.ListUsers(cmd.Context(), listOpts)
+			if err != nil {
+				return err
+			}
+
+			return options.writePage(
+				cmd,
+				listUsersResponse,
+				newUserTable(listUsersResponse.Users...),
+				listUsersResponse.NextCursor,
+			)
+		},
+	}
+
+	flags.register(&cmd, false)
+
+	return &cmd
+}
+
+func newUserUpdateCommand(options *globalOptions) *cobra.Command {
+	var status string
+	var displayName string
+	var email string
+	var externalID string
+	var metadata map[string]string
+	var expectedVersion int64
+
+	cmd := cobra.Command{
+		Use:   "update USERNAME",
+		Short: "Change a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var updateUserRequest client.UpdateUserRequest
+			if cmd.Flags().Changed("status") {
+				userStatus := client.UserStatus(status)
+				updateUserRequest.Status = &userStatus
+			}
+
+			if cmd.Flags().Changed("display-name") {
+				updateUserRequest.DisplayName = &displayName
+			}
+
+			if cmd.Flags().Changed("email") {
+				updateUserRequest.Email = &email
+			}
+
+			if cmd.Flags().Changed("external-id") {
+				updateUserRequest.ExternalID = &externalID
+			}
+
+			if cmd.Flags().Changed("metadata") {
+				updateUserRequest.Metadata = metadata
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			updateUserResponse, err := apiClient.UpdateUser(
+				cmd.Context(),
+				args[0],
+				expectedVersion,
+				updateUserRequest,
+			)
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				updateUserResponse,
+				newUserTable(updateUserResponse.User),
+			)
+		},
+	}
+
+	cmd.Flags().StringVar(&status, "status", "", "New status for the user, either active or deactivated")
+	cmd.Flags().StringVar(&displayName, "display-name", "", "New name that the user goes by")
+	cmd.Flags().StringVar(&email, "email", "", "New email address of the user, empty to remove it")
+	cmd.Flags().StringVar(&externalID, "external-id", "", "New external ID of the user, empty to remove it")
+	cmd.Flags().StringToStringVar(&metadata, "metadata", nil, "Replace the user's metadata with these key=value pairs")
+	cmd.Flags().Int64Var(&expectedVersion, "if-version", 0, "Only change the user if they are at this version")
+
+	return &cmd
+}
+
+func newUserDeleteCommand(options *globalOptions) *cobra.Command {
+	var expectedVersion int64
+
+	cmd := cobra.Command{
+		Use:   "delete USERNAME",
+		Short: "Remove a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			return apiClient.DeleteUser(cmd.Context(), args[0], expectedVersion)
+		},
+	}
+
+	cmd.Flags().Int64Var(&expectedVersion, "if-version", 0, "Only remove the user if they are at this version")
+
+	return &cmd
+}
+
+func newUserTable(users ...client.User) output.Table {
+	table := output.Table{
+		Headers: []string{"NAME", "DISPLAY NAME", "EMAIL", "STATUS", "VERSION", "CREATED", "MODIFIED"},
+	}
+
+	for _, renderableUser := range users {
+		table.Rows = append(table.Rows, []string{
+			renderableUser.Username,
+			renderableUser.DisplayName,
+			renderableUser.Email,
+			string(renderableUser.Status),
+			strconv.FormatInt(renderableUser.Version, 10),
+			time.Time(renderableUser.CreatedOn).Format(time.RFC3339),
+			time.Time(renderableUser.ModifiedOn).Format(time.RFC3339),
+		})
+	}
+
+	return table
+}
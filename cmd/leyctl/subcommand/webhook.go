@@ -0,0 +1,236 @@
+package subcommand
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// webhookSecretEnvironmentVariable can hold the secret of a new
+// webhook so that it doesn't end up in the shell history.
+const webhookSecretEnvironmentVariable = "LEYCTL_WEBHOOK_SECRET"
+
+func newWebhookCommand(options *globalOptions) *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "webhook",
+		Aliases: []string{"webhooks"},
+		Short:   "Manage webhook subscriptions",
+	}
+
+	cmd.AddCommand(
+		newWebhookCreateCommand(options),
+		newWebhookGetCommand(options),
+		newWebhookListCommand(options),
+		newWebhookDeleteCommand(options),
+		newWebhookDeliveriesCommand(options),
+	)
+
+	return &cmd
+}
+
+func newWebhookCreateCommand(options *globalOptions) *cobra.Command {
+	var eventTypes []string
+	var secret string
+
+	cmd := cobra.Command{
+		Use:   "create URL",
+		Short: "Send events to a URL",
+		Long: fmt.Sprintf(
+			"Send events to a URL. The secret used to sign deliveries can be set with %s instead of --secret.",
+			webhookSecretEnvironmentVariable,
+		),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if secret == "" {
+				secret = os.Getenv(webhookSecretEnvironmentVariable)
+			}
+
+			createWebhookRequest := client.CreateWebhookRequest{
+				URL:    args[0],
+				Secret: secret,
+			}
+			for _, eventType := range eventTypes {
+				createWebhookRequest.EventTypes = append(
+					createWebhookRequest.EventTypes,
+					client.WebhookEventType(eventType),
+				)
+			}
+
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			createWebhookResponse, err := apiClient.CreateWebhook(cmd.Context(), createWebhookRequest)
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				createWebhookResponse,
+				newWebhookTable(createWebhookResponse.Webhook),
+			)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&eventTypes, "event", nil, "Event type to send, can be repeated")
+	cmd.Flags().StringVar(&secret, "secret", "", "Secret used to sign deliveries")
+
+	return &cmd
+}
+
+func newWebhookGetCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get ID",
+		Short: "Get a webhook by its ID",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			getWebhookResponse, err := apiClient.GetWebhook(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				getWebhookResponse,
+				newWebhookTable(getWebhookResponse.Webhook),
+			)
+		},
+	}
+}
+
+func newWebhookListCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List webhooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listWebhooksResponse, err := apiClient.ListWebhooks(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				listWebhooksResponse,
+				newWebhookTable(listWebhooksResponse.Webhooks...),
+			)
+		},
+	}
+}
+
+func newWebhookDeleteCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete ID",
+		Short: "Stop sending events to a webhook",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			return apiClient.DeleteWebhook(cmd.Context(), args[0])
+		},
+	}
+}
+
+func newWebhookDeliveriesCommand(options *globalOptions) *cobra.Command {
+	var limit int
+
+	cmd := cobra.Command{
+		Use:   "deliveries ID",
+		Short: "Show the recent deliveries of a webhook",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := options.newClient()
+			if err != nil {
+				return err
+			}
+
+			listDeliveriesResponse, err := apiClient.ListWebhookDeliveries(cmd.Context(), args[0], limit)
+			if err != nil {
+				return err
+			}
+
+			return options.write(
+				cmd,
+				listDeliveriesResponse,
+				newWebhookDeliveryTable(listDeliveriesResponse.Deliveries...),
+			)
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 0, "Maximum number of deliveries to show")
+
+	return &cmd
+}
+
+func newWebhookTable(webhooks ...client.Webhook) output.Table {
+	table := output.Table{
+		Headers: []string{"ID", "URL", "EVENTS", "CREATED"},
+	}
+
+	for _, renderableWebhook := range webhooks {
+		eventTypes := make([]string, len(renderableWebhook.EventTypes))
+		for index, eventType := range renderableWebhook.EventTypes {
+			eventTypes[index] = string(eventType)
+		}
+
+		table.Rows = append(table.Rows, []string{
+			renderableWebhook.ID,
+			renderableWebhook.URL,
+			strings.Join(eventTypes, ","),
+			time.Time(renderableWebhook.CreatedOn).Format(time.RFC3339),
+		})
+	}
+
+	return table
+}
+
+func newWebhookDeliveryTable(deliveries ...client.WebhookDelivery) output.Table {
+	table := output.Table{
+		Headers: []string{"ID", "EVENT", "STATUS", "ATTEMPTS", "LAST STATUS", "NEXT ATTEMPT", "CREATED"},
+	}
+
+	for _, delivery := range deliveries {
+		lastStatusCode := "-"
+		if delivery.LastStatusCode != nil {
+			lastStatusCode = strconv.Itoa(*delivery.LastStatusCode)
+		}
+
+		nextAttempt := "-"
+		if delivery.NextAttemptOn != nil {
+			nextAttempt = time.Time(*delivery.NextAttemptOn).Format(time.RFC3339)
+		}
+
+		table.Rows = append(table.Rows, []string{
+			strconv.FormatInt(delivery.ID, 10),
+			string(delivery.EventType),
+			string(delivery.Status),
+			strconv.Itoa(delivery.Attempts),
+			lastStatusCode,
+			nextAttempt,
+			time.Time(delivery.CreatedOn).Format(time.RFC3339),
+		})
+	}
+
+	return table
+}
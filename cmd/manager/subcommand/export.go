@@ -0,0 +1,66 @@
+package subcommand
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/durandj/ley/internal/manager"
+	"github.com/durandj/ley/internal/manager/archive"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/spf13/cobra"
+)
+
+func newExportCommand() *cobra.Command {
+	var outputPath string
+
+	cmd := cobra.Command{
+		Use:   "export",
+		Short: "Export the state of the manager to an archive",
+		Long: `Export users, networks, nodes, preshared keys and webhooks to a JSON archive.
+
+The archive holds secrets, like preshared keys and webhook secrets, so files are
+only readable by their owner.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configuration.NewFromEnvironment()
+			if err != nil {
+				return fmt.Errorf("Unable to load service configuration: %w", err)
+			}
+
+			db, err := manager.OpenDB(config)
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				_ = db.Close()
+			}()
+
+			exportedArchive, err := archive.Export(cmd.Context(), db)
+			if err != nil {
+				return err
+			}
+
+			if outputPath == "" || outputPath == "-" {
+				return archive.Write(cmd.OutOrStdout(), exportedArchive)
+			}
+
+			file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				return fmt.Errorf("Unable to create archive: %w", err)
+			}
+
+			if err := archive.Write(file, exportedArchive); err != nil {
+				_ = file.Close()
+
+				return err
+			}
+
+			return file.Close()
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "File to write the archive to instead of standard output")
+
+	return &cmd
+}
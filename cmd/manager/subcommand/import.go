@@ -0,0 +1,95 @@
+package subcommand
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/durandj/ley/internal/manager"
+	"github.com/durandj/ley/internal/manager/archive"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/spf13/cobra"
+)
+
+func newImportCommand() *cobra.Command {
+	var dryRun bool
+	var skipConflicts bool
+
+	cmd := cobra.Command{
+		Use:   "import FILE",
+		Short: "Import an archive into the manager",
+		Long: `Import an archive written by 'manager export', or from standard input with '-'.
+
+Records are only ever added. Records that already exist as they are in the
+archive are left alone. Records that clash with existing ones, like a network
+with a name that is already taken, are reported as conflicts and nothing is
+imported unless --skip-conflicts is given. The report is written as JSON.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importedArchive, err := readArchive(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			config, err := configuration.NewFromEnvironment()
+			if err != nil {
+				return fmt.Errorf("Unable to load service configuration: %w", err)
+			}
+
+			db, err := manager.OpenDB(config)
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				_ = db.Close()
+			}()
+
+			report, importErr := archive.Import(cmd.Context(), db, importedArchive, archive.ImportOpts{
+				DryRun:        dryRun,
+				SkipConflicts: skipConflicts,
+			})
+
+			// Conflicts come with a report that tells what they are.
+			if importErr != nil && len(report.Conflicts) == 0 {
+				return importErr
+			}
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return fmt.Errorf("Unable to write report: %w", err)
+			}
+
+			return importErr
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only report what would be imported")
+	cmd.Flags().BoolVar(&skipConflicts, "skip-conflicts", false, "Import the records that don't conflict")
+
+	return &cmd
+}
+
+// readArchive reads an archive from a file, or from standard input
+// when the path is '-'.
+func readArchive(cmd *cobra.Command, path string) (*archive.Archive, error) {
+	var reader io.Reader
+	if path == "-" {
+		reader = cmd.InOrStdin()
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to open archive: %w", err)
+		}
+
+		defer func() {
+			_ = file.Close()
+		}()
+
+		reader = file
+	}
+
+	return archive.Read(reader)
+}
@@ -32,5 +32,8 @@ func NewRootCommand() *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(newExportCommand())
+	cmd.AddCommand(newImportCommand())
+
 	return &cmd
 }
@@ -18,6 +18,7 @@ dagger.#Plan & {
 			".golangci.yml",
 			"**/*.go",
 			"**/*.sql",
+			"internal/manager/openapi.json",
 			"Dockerfile",
 		]
 	}
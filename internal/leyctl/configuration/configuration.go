@@ -0,0 +1,142 @@
+package configuration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PathEnvironmentVariable can be set to override where the
+	// configuration file is read from and written to.
+	PathEnvironmentVariable = "LEYCTL_CONFIG"
+)
+
+// Configuration holds the settings that leyctl keeps between runs.
+type Configuration struct {
+	CurrentContext string    `json:"currentContext,omitempty" yaml:"currentContext,omitempty"`
+	Contexts       []Context `json:"contexts" yaml:"contexts"`
+}
+
+// Context describes how to reach a single manager instance.
+type Context struct {
+	Name      string `json:"name" yaml:"name"`
+	ServerURL string `json:"serverURL" yaml:"serverURL"`
+	Token     string `json:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// DefaultPath gives the location of the configuration file when one
+// isn't explicitly given.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PathEnvironmentVariable); path != "" {
+		return path, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("Unable to find user configuration directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "ley", "leyctl.yaml"), nil
+}
+
+// Load reads the configuration file at the given path. A missing file
+// is treated as an empty configuration.
+func Load(path string) (*Configuration, error) {
+	config := Configuration{}
+
+	// nolint: gosec
+	rawConfig, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &config, nil
+		}
+
+		return nil, fmt.Errorf("Unable to read configuration file '%s': %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(rawConfig, &config); err != nil {
+		return nil, fmt.Errorf("Unable to parse configuration file '%s': %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// Save writes the configuration to the given path. The file is only
+// readable by the current user since it can contain API tokens.
+func (config *Configuration) Save(path string) error {
+	var rawConfig bytes.Buffer
+	encoder := yaml.NewEncoder(&rawConfig)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(config); err != nil {
+		return fmt.Errorf("Unable to serialize configuration: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("Unable to create configuration directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, rawConfig.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("Unable to write configuration file '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// GetContext finds a context by name.
+func (config *Configuration) GetContext(name string) (*Context, error) {
+	for index := range config.Contexts {
+		if config.Contexts[index].Name == name {
+			return &config.Contexts[index], nil
+		}
+	}
+
+	return nil, fmt.Errorf("No context named '%s'", name)
+}
+
+// SetContext adds a context or replaces an existing context with the
+// same name.
+func (config *Configuration) SetContext(context Context) {
+	for index := range config.Contexts {
+		if config.Contexts[index].Name == context.Name {
+			config.Contexts[index] = context
+
+			return
+		}
+	}
+
+	config.Contexts = append(config.Contexts, context)
+}
+
+// DeleteContext removes a context by name. If it was the current
+// context then no context will be selected afterwards.
+func (config *Configuration) DeleteContext(name string) error {
+	for index := range config.Contexts {
+		if config.Contexts[index].Name == name {
+			config.Contexts = append(config.Contexts[:index], config.Contexts[index+1:]...)
+
+			if config.CurrentContext == name {
+				config.CurrentContext = ""
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("No context named '%s'", name)
+}
+
+// ContextNames lists the names of all known contexts.
+func (config *Configuration) ContextNames() []string {
+	names := make([]string, len(config.Contexts))
+	for index, context := range config.Contexts {
+		names[index] = context.Name
+	}
+
+	return names
+}
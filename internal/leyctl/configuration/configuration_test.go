@@ -0,0 +1,89 @@
+package configuration_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/durandj/ley/internal/leyctl/configuration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPathShouldPreferTheEnvironmentVariable(t *testing.T) {
+	t.Setenv(configuration.PathEnvironmentVariable, "/tmp/leyctl.yaml")
+
+	path, err := configuration.DefaultPath()
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/leyctl.yaml", path)
+
+	t.Setenv(configuration.PathEnvironmentVariable, "")
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/config")
+	t.Setenv("HOME", "/tmp/home")
+
+	path, err = configuration.DefaultPath()
+	require.NoError(t, err)
+	require.Equal(t, "leyctl.yaml", filepath.Base(path))
+	require.Equal(t, "ley", filepath.Base(filepath.Dir(path)))
+}
+
+func TestLoadShouldTreatAMissingFileAsEmpty(t *testing.T) {
+	config, err := configuration.Load(filepath.Join(t.TempDir(), "leyctl.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "", config.CurrentContext)
+	require.Empty(t, config.Contexts)
+}
+
+func TestLoadShouldRejectInvalidFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leyctl.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("contexts: [\n"), 0o600))
+
+	_, err := configuration.Load(path)
+	require.Error(t, err)
+}
+
+func TestSaveShouldWriteAFileThatCanBeLoaded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "leyctl.yaml")
+	config := configuration.Configuration{
+		CurrentContext: "prod",
+		Contexts: []configuration.Context{
+			{Name: "prod", ServerURL: "https://ley.example.com", Token: "secret"},
+			{Name: "local", ServerURL: "http://localhost:8080"},
+		},
+	}
+
+	require.NoError(t, config.Save(path), "should create missing directories")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "should only be readable by the current user")
+
+	loadedConfig, err := configuration.Load(path)
+	require.NoError(t, err)
+	require.Equal(t, config, *loadedConfig)
+}
+
+func TestContextsShouldBeSetFoundAndDeletedByName(t *testing.T) {
+	config := configuration.Configuration{}
+	config.SetContext(configuration.Context{Name: "prod", ServerURL: "https://ley.example.com"})
+	config.SetContext(configuration.Context{Name: "local", ServerURL: "http://localhost:8080"})
+	config.SetContext(configuration.Context{Name: "prod", ServerURL: "https://ley.example.org"})
+	config.CurrentContext = "prod"
+
+	require.Equal(t, []string{"prod", "local"}, config.ContextNames(), "should replace contexts with the same name")
+
+	context, err := config.GetContext("prod")
+	require.NoError(t, err)
+	require.Equal(t, "https://ley.example.org", context.ServerURL)
+
+	_, err = config.GetContext("staging")
+	require.Error(t, err)
+
+	require.NoError(t, config.DeleteContext("local"))
+	require.Equal(t, "prod", config.CurrentContext, "should keep the current context")
+
+	require.NoError(t, config.DeleteContext("prod"))
+	require.Equal(t, "", config.CurrentContext, "should unset the current context when it is deleted")
+	require.Empty(t, config.ContextNames())
+
+	require.Error(t, config.DeleteContext("prod"))
+}
@@ -0,0 +1,140 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the way command results are written out.
+type Format string
+
+const (
+	// FormatTable writes results as human readable columns.
+	FormatTable Format = "table"
+
+	// FormatJSON writes results as the JSON returned by the API.
+	FormatJSON Format = "json"
+
+	// FormatYAML writes results as YAML using the same field names as
+	// the JSON output.
+	FormatYAML Format = "yaml"
+)
+
+var (
+	// SupportedFormats lists every output format that can be selected.
+	SupportedFormats = []Format{FormatTable, FormatJSON, FormatYAML}
+)
+
+func (format *Format) String() string {
+	return string(*format)
+}
+
+// Set parses an output format from a command line flag.
+func (format *Format) Set(value string) error {
+	for _, supportedFormat := range SupportedFormats {
+		if string(supportedFormat) == value {
+			*format = supportedFormat
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Unsupported output format '%s'", value)
+}
+
+// Type gives the name of the flag type shown in help text.
+func (format *Format) Type() string {
+	return "format"
+}
+
+var _ pflag.Value = (*Format)(nil)
+
+// Table is the tabular view of a result.
+type Table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// Write renders a result in the given format. Tables are built from
+// the given table while JSON and YAML serialize the value directly.
+func Write(writer io.Writer, format Format, value any, table Table) error {
+	switch format {
+	case FormatTable:
+		return writeTable(writer, table)
+
+	case FormatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(value); err != nil {
+			return fmt.Errorf("Unable to write JSON output: %w", err)
+		}
+
+		return nil
+
+	case FormatYAML:
+		return writeYAML(writer, value)
+
+	default:
+		return fmt.Errorf("Unsupported output format '%s'", format)
+	}
+}
+
+func writeTable(writer io.Writer, table Table) error {
+	tableWriter := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+
+	if _, err := fmt.Fprintln(tableWriter, strings.Join(table.Headers, "\t")); err != nil {
+		return fmt.Errorf("Unable to write table output: %w", err)
+	}
+
+	for _, row := range table.Rows {
+		if _, err := fmt.Fprintln(tableWriter, strings.Join(row, "\t")); err != nil {
+			return fmt.Errorf("Unable to write table output: %w", err)
+		}
+	}
+
+	if err := tableWriter.Flush(); err != nil {
+		return fmt.Errorf("Unable to write table output: %w", err)
+	}
+
+	return nil
+}
+
+// writeYAML goes through JSON first so that the YAML output uses the
+// same field names, field order and value formats as the API.
+func writeYAML(writer io.Writer, value any) error {
+	rawJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("Unable to write YAML output: %w", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(rawJSON, &document); err != nil {
+		return fmt.Errorf("Unable to write YAML output: %w", err)
+	}
+
+	resetStyle(&document)
+
+	encoder := yaml.NewEncoder(writer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&document); err != nil {
+		return fmt.Errorf("Unable to write YAML output: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
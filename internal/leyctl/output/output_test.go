@@ -0,0 +1,81 @@
+package output_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/durandj/ley/internal/leyctl/output"
+	"github.com/stretchr/testify/require"
+)
+
+type result struct {
+	Name   string            `json:"name"`
+	Count  int               `json:"count"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+var (
+	testResult = result{Name: "office", Count: 2, Labels: map[string]string{"env": "prod"}}
+	testTable  = output.Table{
+		Headers: []string{"NAME", "COUNT"},
+		Rows:    [][]string{{"office", "2"}, {"datacenter", "10"}},
+	}
+)
+
+func TestFormatShouldOnlyAcceptSupportedFormats(t *testing.T) {
+	var format output.Format
+	for _, supportedFormat := range output.SupportedFormats {
+		require.NoError(t, format.Set(string(supportedFormat)))
+		require.Equal(t, supportedFormat, format)
+		require.Equal(t, string(supportedFormat), format.String())
+	}
+
+	require.Error(t, format.Set("xml"))
+	require.Equal(t, output.FormatYAML, format, "should keep the previous format")
+}
+
+func TestWriteShouldAlignTables(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, output.Write(&buffer, output.FormatTable, testResult, testTable))
+	require.Equal(
+		t,
+		"NAME        COUNT\n"+
+			"office      2\n"+
+			"datacenter  10\n",
+		buffer.String(),
+	)
+}
+
+func TestWriteShouldUseTheJSONFieldNames(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, output.Write(&buffer, output.FormatJSON, testResult, testTable))
+	require.Equal(
+		t,
+		"{\n"+
+			"  \"name\": \"office\",\n"+
+			"  \"count\": 2,\n"+
+			"  \"labels\": {\n"+
+			"    \"env\": \"prod\"\n"+
+			"  }\n"+
+			"}\n",
+		buffer.String(),
+	)
+
+	buffer.Reset()
+	require.NoError(t, output.Write(&buffer, output.FormatYAML, testResult, testTable))
+	require.Equal(
+		t,
+		"name: office\n"+
+			"count: 2\n"+
+			"labels:\n"+
+			"  env: prod\n",
+		buffer.String(),
+		"should match the JSON output",
+	)
+}
+
+func TestWriteShouldRejectUnsupportedFormats(t *testing.T) {
+	var buffer bytes.Buffer
+	require.Error(t, output.Write(&buffer, output.Format("xml"), testResult, testTable))
+	require.Empty(t, buffer.String())
+}
@@ -0,0 +1,181 @@
+package apply
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Controller handles all the HTTP requests for applying manifests.
+type Controller struct {
+	ApplyService *Service
+}
+
+// RegisterRoutes registers HTTP request handlers for all apply API's.
+func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Post("/", controller.Apply)
+}
+
+// ApplyRequest is the expected request body for applying a manifest.
+type ApplyRequest struct {
+	Resources []Resource `json:"resources"`
+	DryRun    bool       `json:"dryRun,omitempty"`
+	Prune     bool       `json:"prune,omitempty"`
+}
+
+// Bind is used to determine how to map from a request body to an
+// apply request.
+func (applyRequest *ApplyRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*ApplyRequest)(nil)
+
+// ApplyResponse is the response body for an applied manifest. It lists
+// the changes that were made, or that would have been made for a dry
+// run.
+type ApplyResponse struct {
+	DryRun  bool               `json:"dryRun"`
+	Changes []RenderableChange `json:"changes"`
+}
+
+// Render provides a hook to customize the render process.
+func (applyResponse *ApplyResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ApplyResponse)(nil)
+
+// Apply handles requests to make the managed resources match a
+// manifest.
+func (controller *Controller) Apply(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var applyRequest ApplyRequest
+	if err := render.Bind(request, &applyRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	changes, err := controller.ApplyService.Apply(ctx, ApplyOpts{
+		Resources: applyRequest.Resources,
+		DryRun:    applyRequest.DryRun,
+		Prune:     applyRequest.Prune,
+	})
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	applyResponse := ApplyResponse{
+		DryRun:  applyRequest.DryRun,
+		Changes: make([]RenderableChange, 0, len(changes)),
+	}
+	for _, change := range changes {
+		applyResponse.Changes = append(applyResponse.Changes, NewRenderableChange(change))
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &applyResponse)
+}
+
+func handleError(
+	response http.ResponseWriter,
+	request *http.Request,
+	err error,
+) {
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
+	var userError errortypes.UserError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &validationError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: validationError.SafeMessage,
+		})
+
+	case errors.As(err, &notFoundError):
+		response.WriteHeader(http.StatusNotFound)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: notFoundError.SafeMessage,
+		})
+
+	case errors.As(err, &preconditionFailedError):
+		response.WriteHeader(http.StatusPreconditionFailed)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: preconditionFailedError.SafeMessage,
+		})
+
+	case errors.As(err, &userError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: userError.SafeMessage,
+		})
+
+	case errors.As(err, &systemError):
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: systemError.SafeMessage,
+		})
+
+	case err != nil:
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: "Internal server error, please try again later",
+		})
+	}
+}
+
+// RenderableChange defines what should be returned to a user for a
+// planned change to a resource.
+type RenderableChange struct {
+	Kind   Kind                    `json:"kind"`
+	Name   string                  `json:"name"`
+	Action Action                  `json:"action"`
+	Fields []RenderableFieldChange `json:"fields"`
+}
+
+// NewRenderableChange creates a new renderable change from a planned
+// change.
+func NewRenderableChange(change Change) RenderableChange {
+	fields := make([]RenderableFieldChange, 0, len(change.Fields))
+	for _, field := range change.Fields {
+		fields = append(fields, RenderableFieldChange(field))
+	}
+
+	return RenderableChange{
+		Kind:   change.Kind,
+		Name:   change.Name,
+		Action: change.Action,
+		Fields: fields,
+	}
+}
+
+// RenderableFieldChange defines what should be returned to a user for a
+// change to a single field. From is left out for new values and To is
+// left out for removed values.
+type RenderableFieldChange struct {
+	Field string  `json:"field"`
+	From  *string `json:"from,omitempty"`
+	To    *string `json:"to,omitempty"`
+}
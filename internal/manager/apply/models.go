@@ -0,0 +1,152 @@
+package apply
+
+import (
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/user"
+	"inet.af/netaddr"
+)
+
+// APIVersion is the version of the manifest format that resources
+// have to be written in.
+const APIVersion = "ley/v1"
+
+// Kind names a type of resource that can be described in a manifest.
+type Kind string
+
+const (
+	// KindNetwork describes a network along with its ranges and
+	// topology.
+	KindNetwork Kind = "Network"
+
+	// KindUser describes a user and whether they are active.
+	KindUser Kind = "User"
+)
+
+// Kinds lists every kind of resource in the order they are planned.
+var Kinds = []Kind{KindNetwork, KindUser}
+
+// Resource is a single resource of a manifest. The spec depends on the
+// kind of resource.
+type Resource struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       Kind           `json:"kind"`
+	Metadata   Metadata       `json:"metadata"`
+	Spec       map[string]any `json:"spec,omitempty"`
+}
+
+// Metadata identifies a resource.
+type Metadata struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NetworkSpec is the desired state of a network. Ranges can only be
+// chosen when the network is created. Hubs of a hub and spoke topology
+// act as the gateways of the network.
+type NetworkSpec struct {
+	IPv4CIDR         *netaddr.IPPrefix `json:"ipv4CIDR,omitempty"`
+	IPv4PrefixLength *int              `json:"ipv4PrefixLength,omitempty"`
+	IPv6CIDR         *netaddr.IPPrefix `json:"ipv6CIDR,omitempty"`
+	GenerateIPv6     bool              `json:"generateIPv6,omitempty"`
+	AllowOverlap     bool              `json:"allowOverlap,omitempty"`
+	Topology         *network.Topology `json:"topology,omitempty"`
+	PresharedKeys    bool              `json:"presharedKeys,omitempty"`
+}
+
+// UserSpec is the desired state of a user. Users are active unless
+// told otherwise.
+type UserSpec struct {
+	Status user.Status `json:"status,omitempty"`
+}
+
+// State is what currently exists, as far as manifests are concerned.
+type State struct {
+	Networks []NetworkState
+	Users    []UserState
+}
+
+// NetworkState is the current state of a network.
+type NetworkState struct {
+	Name          string
+	IPv4CIDR      *netaddr.IPPrefix
+	IPv6CIDR      *netaddr.IPPrefix
+	AllowOverlap  bool
+	Labels        map[string]string
+	Topology      network.Topology
+	PresharedKeys bool
+	Version       int64
+}
+
+// NewNetworkState captures the current state of a network.
+func NewNetworkState(existingNetwork *network.Network) NetworkState {
+	return NetworkState{
+		Name:          existingNetwork.Name(),
+		IPv4CIDR:      existingNetwork.IPv4CIDR(),
+		IPv6CIDR:      existingNetwork.IPv6CIDR(),
+		AllowOverlap:  existingNetwork.AllowOverlap(),
+		Labels:        existingNetwork.Labels(),
+		Topology:      existingNetwork.Topology(),
+		PresharedKeys: existingNetwork.PresharedKeys(),
+		Version:       existingNetwork.Version(),
+	}
+}
+
+// UserState is the current state of a user.
+type UserState struct {
+	Name    string
+	Status  user.Status
+	Version int64
+}
+
+// NewUserState captures the current state of a user.
+func NewUserState(existingUser *user.User) UserState {
+	return UserState{
+		Name:    existingUser.Username(),
+		Status:  existingUser.Status(),
+		Version: existingUser.Version(),
+	}
+}
+
+// Action is what has to happen to a resource to match the manifest.
+type Action string
+
+const (
+	// ActionCreate creates a resource that is in the manifest but
+	// doesn't exist yet.
+	ActionCreate Action = "create"
+
+	// ActionUpdate changes a resource to match the manifest.
+	ActionUpdate Action = "update"
+
+	// ActionDelete removes a resource that isn't in the manifest. It is
+	// only planned when pruning.
+	ActionDelete Action = "delete"
+
+	// ActionUnchanged means the resource already matches the manifest.
+	ActionUnchanged Action = "unchanged"
+)
+
+// Change is a planned change to a single resource.
+type Change struct {
+	Kind   Kind
+	Name   string
+	Action Action
+	Fields []FieldChange
+
+	// version is the version of the resource the change was planned
+	// against. Applying the change fails if the resource has changed
+	// since.
+	version int64
+
+	networkOpts   network.CreateNetworkOpts
+	networkUpdate network.UpdateNetworkOpts
+	userStatus    user.Status
+}
+
+// FieldChange is a change to a single field of a resource. From is nil
+// for new values and To is nil for removed values.
+type FieldChange struct {
+	Field string
+	From  *string
+	To    *string
+}
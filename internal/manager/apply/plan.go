@@ -0,0 +1,552 @@
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/user"
+	"inet.af/netaddr"
+)
+
+// Plan works out the changes needed to make the current state match
+// the resources of a manifest. Resources that exist but aren't in the
+// manifest are only deleted when pruning, and then only for the kinds
+// of resources that the manifest has. Changes are ordered by kind and
+// then by their order in the manifest, with deletes coming last.
+func Plan(resources []Resource, current State, prune bool) ([]Change, error) {
+	if err := validateResources(resources); err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	deletes := []Change{}
+	for _, kind := range Kinds {
+		kindResources := resourcesOfKind(resources, kind)
+
+		var kindChanges []Change
+		var kindDeletes []Change
+		var err error
+		switch kind {
+		case KindNetwork:
+			kindChanges, kindDeletes, err = planNetworks(kindResources, current.Networks)
+
+		case KindUser:
+			kindChanges, kindDeletes, err = planUsers(kindResources, current.Users)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, kindChanges...)
+		if prune && len(kindResources) > 0 {
+			deletes = append(deletes, kindDeletes...)
+		}
+	}
+
+	return append(changes, deletes...), nil
+}
+
+func validateResources(resources []Resource) error {
+	names := map[Kind]map[string]struct{}{}
+	for _, kind := range Kinds {
+		names[kind] = map[string]struct{}{}
+	}
+
+	for index, resource := range resources {
+		if resource.APIVersion != APIVersion {
+			return errortypes.NewValidationError(
+				"Resource %d has unsupported apiVersion '%s', expected '%s'",
+				index+1,
+				resource.APIVersion,
+				APIVersion,
+			)
+		}
+
+		kindNames, ok := names[resource.Kind]
+		if !ok {
+			return errortypes.NewValidationError(
+				"Resource %d has unsupported kind '%s'",
+				index+1,
+				resource.Kind,
+			)
+		}
+
+		if resource.Metadata.Name == "" {
+			return errortypes.NewValidationError("Resource %d is missing a name", index+1)
+		}
+
+		if _, ok := kindNames[resource.Metadata.Name]; ok {
+			return errortypes.NewValidationError(
+				"%s '%s' is defined more than once",
+				resource.Kind,
+				resource.Metadata.Name,
+			)
+		}
+
+		kindNames[resource.Metadata.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+func resourcesOfKind(resources []Resource, kind Kind) []Resource {
+	var matches []Resource
+	for _, resource := range resources {
+		if resource.Kind == kind {
+			matches = append(matches, resource)
+		}
+	}
+
+	return matches
+}
+
+// decodeSpec reads the spec of a resource into the spec type of its
+// kind. Unknown fields are rejected so that typos aren't ignored.
+func decodeSpec(resource Resource, spec any) error {
+	rawSpec, err := json.Marshal(resource.Spec)
+	if err != nil {
+		return errortypes.NewWrappedValidationError(
+			err,
+			"%s '%s' has an invalid spec",
+			resource.Kind,
+			resource.Metadata.Name,
+		)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(rawSpec))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(spec); err != nil {
+		return errortypes.NewWrappedValidationError(
+			err,
+			"%s '%s' has an invalid spec: %v",
+			resource.Kind,
+			resource.Metadata.Name,
+			err,
+		)
+	}
+
+	return nil
+}
+
+func planNetworks(resources []Resource, current []NetworkState) ([]Change, []Change, error) {
+	existingNetworks := map[string]NetworkState{}
+	for _, existingNetwork := range current {
+		existingNetworks[existingNetwork.Name] = existingNetwork
+	}
+
+	changes := []Change{}
+	wanted := map[string]struct{}{}
+	for _, resource := range resources {
+		var spec NetworkSpec
+		if err := decodeSpec(resource, &spec); err != nil {
+			return nil, nil, err
+		}
+
+		opts := network.CreateNetworkOpts{
+			Name:             resource.Metadata.Name,
+			IPv4CIDR:         spec.IPv4CIDR,
+			IPv6CIDR:         spec.IPv6CIDR,
+			Labels:           resource.Metadata.Labels,
+			AllowOverlap:     spec.AllowOverlap,
+			IPv4PrefixLength: spec.IPv4PrefixLength,
+			GenerateIPv6:     spec.GenerateIPv6,
+			Topology:         spec.Topology,
+			PresharedKeys:    spec.PresharedKeys,
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, nil, errortypes.NewWrappedValidationError(
+				err,
+				"Network '%s' is invalid: %v",
+				resource.Metadata.Name,
+				err,
+			)
+		}
+
+		wanted[resource.Metadata.Name] = struct{}{}
+
+		existingNetwork, ok := existingNetworks[resource.Metadata.Name]
+		if !ok {
+			changes = append(changes, Change{
+				Kind:        KindNetwork,
+				Name:        resource.Metadata.Name,
+				Action:      ActionCreate,
+				Fields:      networkCreateFields(opts),
+				networkOpts: opts,
+			})
+
+			continue
+		}
+
+		change, err := planNetworkUpdate(opts, existingNetwork)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	deletes := []Change{}
+	for _, existingNetwork := range current {
+		if _, ok := wanted[existingNetwork.Name]; ok {
+			continue
+		}
+
+		deletes = append(deletes, Change{
+			Kind:    KindNetwork,
+			Name:    existingNetwork.Name,
+			Action:  ActionDelete,
+			version: existingNetwork.Version,
+		})
+	}
+
+	sortByName(deletes)
+
+	return changes, deletes, nil
+}
+
+func networkCreateFields(opts network.CreateNetworkOpts) []FieldChange {
+	fields := []FieldChange{}
+	if opts.IPv4CIDR != nil {
+		fields = append(fields, FieldChange{Field: "ipv4CIDR", To: prefixValue(opts.IPv4CIDR)})
+	}
+
+	if opts.IPv4PrefixLength != nil {
+		fields = append(fields, FieldChange{
+			Field: "ipv4PrefixLength",
+			To:    stringValue(strconv.Itoa(*opts.IPv4PrefixLength)),
+		})
+	}
+
+	if opts.IPv6CIDR != nil {
+		fields = append(fields, FieldChange{Field: "ipv6CIDR", To: prefixValue(opts.IPv6CIDR)})
+	}
+
+	if opts.GenerateIPv6 {
+		fields = append(fields, FieldChange{Field: "generateIPv6", To: boolValue(true)})
+	}
+
+	if opts.AllowOverlap {
+		fields = append(fields, FieldChange{Field: "allowOverlap", To: boolValue(true)})
+	}
+
+	fields = append(fields, FieldChange{Field: "topology", To: topologyValue(desiredTopology(opts.Topology))})
+
+	if opts.PresharedKeys {
+		fields = append(fields, FieldChange{Field: "presharedKeys", To: boolValue(true)})
+	}
+
+	return append(fields, labelChanges(nil, opts.Labels)...)
+}
+
+func planNetworkUpdate(opts network.CreateNetworkOpts, existingNetwork NetworkState) (Change, error) {
+	if err := checkImmutableRanges(opts, existingNetwork); err != nil {
+		return Change{}, err
+	}
+
+	change := Change{
+		Kind:    KindNetwork,
+		Name:    existingNetwork.Name,
+		Action:  ActionUnchanged,
+		Fields:  []FieldChange{},
+		version: existingNetwork.Version,
+	}
+
+	currentTopology := topologyValue(existingNetwork.Topology)
+	topology := desiredTopology(opts.Topology)
+	if wantedTopology := topologyValue(topology); *wantedTopology != *currentTopology {
+		change.Fields = append(change.Fields, FieldChange{
+			Field: "topology",
+			From:  currentTopology,
+			To:    wantedTopology,
+		})
+		change.networkUpdate.Topology = &topology
+	}
+
+	if opts.PresharedKeys != existingNetwork.PresharedKeys {
+		presharedKeys := opts.PresharedKeys
+		change.Fields = append(change.Fields, FieldChange{
+			Field: "presharedKeys",
+			From:  boolValue(existingNetwork.PresharedKeys),
+			To:    boolValue(presharedKeys),
+		})
+		change.networkUpdate.PresharedKeys = &presharedKeys
+	}
+
+	if labelFields := labelChanges(existingNetwork.Labels, opts.Labels); len(labelFields) > 0 {
+		change.Fields = append(change.Fields, labelFields...)
+
+		// The labels of the manifest replace the existing ones.
+		change.networkUpdate.Labels = map[string]string{}
+		for key, value := range opts.Labels {
+			change.networkUpdate.Labels[key] = value
+		}
+	}
+
+	if len(change.Fields) > 0 {
+		change.Action = ActionUpdate
+	}
+
+	return change, nil
+}
+
+// checkImmutableRanges makes sure that the ranges of the manifest match
+// those of an existing network since they can't be changed without
+// recreating the network.
+func checkImmutableRanges(opts network.CreateNetworkOpts, existingNetwork NetworkState) error {
+	ipv4Matches := prefixesEqual(opts.IPv4CIDR, existingNetwork.IPv4CIDR)
+	if opts.IPv4PrefixLength != nil {
+		ipv4Matches = existingNetwork.IPv4CIDR != nil &&
+			int(existingNetwork.IPv4CIDR.Bits()) == *opts.IPv4PrefixLength
+	}
+
+	if !ipv4Matches {
+		wantedIPv4 := prefixValue(opts.IPv4CIDR)
+		if opts.IPv4PrefixLength != nil {
+			wantedIPv4 = stringValue(fmt.Sprintf("a /%d range", *opts.IPv4PrefixLength))
+		}
+
+		return immutableFieldError(existingNetwork.Name, "ipv4CIDR", prefixValue(existingNetwork.IPv4CIDR), wantedIPv4)
+	}
+
+	ipv6Matches := prefixesEqual(opts.IPv6CIDR, existingNetwork.IPv6CIDR)
+	if opts.GenerateIPv6 {
+		ipv6Matches = existingNetwork.IPv6CIDR != nil
+	}
+
+	if !ipv6Matches {
+		wantedIPv6 := prefixValue(opts.IPv6CIDR)
+		if opts.GenerateIPv6 {
+			wantedIPv6 = stringValue("a generated range")
+		}
+
+		return immutableFieldError(existingNetwork.Name, "ipv6CIDR", prefixValue(existingNetwork.IPv6CIDR), wantedIPv6)
+	}
+
+	if opts.AllowOverlap != existingNetwork.AllowOverlap {
+		return immutableFieldError(
+			existingNetwork.Name,
+			"allowOverlap",
+			boolValue(existingNetwork.AllowOverlap),
+			boolValue(opts.AllowOverlap),
+		)
+	}
+
+	return nil
+}
+
+func immutableFieldError(name string, field string, from *string, to *string) error {
+	return errortypes.NewValidationError(
+		"Network '%s' cannot change %s from %s to %s, it has to be deleted and created again",
+		name,
+		field,
+		describeValue(from),
+		describeValue(to),
+	)
+}
+
+func planUsers(resources []Resource, current []UserState) ([]Change, []Change, error) {
+	existingUsers := map[string]UserState{}
+	for _, existingUser := range current {
+		existingUsers[existingUser.Name] = existingUser
+	}
+
+	changes := []Change{}
+	wanted := map[string]struct{}{}
+	for _, resource := range resources {
+		if len(resource.Metadata.Labels) > 0 {
+			return nil, nil, errortypes.NewValidationError(
+				"User '%s' cannot have labels",
+				resource.Metadata.Name,
+			)
+		}
+
+		var spec UserSpec
+		if err := decodeSpec(resource, &spec); err != nil {
+			return nil, nil, err
+		}
+
+		if spec.Status == "" {
+			spec.Status = user.StatusActive
+		}
+
+		createOpts := user.CreateUserOpts{Username: resource.Metadata.Name}
+		if err := createOpts.Validate(); err != nil {
+			return nil, nil, errortypes.NewWrappedValidationError(err, "User is invalid: %v", err)
+		}
+
+		updateOpts := user.UpdateUserOpts{Status: &spec.Status}
+		if err := updateOpts.Validate(); err != nil {
+			return nil, nil, errortypes.NewWrappedValidationError(
+				err,
+				"User '%s' is invalid: %v",
+				resource.Metadata.Name,
+				err,
+			)
+		}
+
+		wanted[resource.Metadata.Name] = struct{}{}
+
+		existingUser, ok := existingUsers[resource.Metadata.Name]
+		if !ok {
+			changes = append(changes, Change{
+				Kind:   KindUser,
+				Name:   resource.Metadata.Name,
+				Action: ActionCreate,
+				Fields: []FieldChange{
+					{Field: "status", To: stringValue(string(spec.Status))},
+				},
+				userStatus: spec.Status,
+			})
+
+			continue
+		}
+
+		change := Change{
+			Kind:       KindUser,
+			Name:       existingUser.Name,
+			Action:     ActionUnchanged,
+			Fields:     []FieldChange{},
+			version:    existingUser.Version,
+			userStatus: spec.Status,
+		}
+		if spec.Status != existingUser.Status {
+			change.Action = ActionUpdate
+			change.Fields = append(change.Fields, FieldChange{
+				Field: "status",
+				From:  stringValue(string(existingUser.Status)),
+				To:    stringValue(string(spec.Status)),
+			})
+		}
+
+		changes = append(changes, change)
+	}
+
+	deletes := []Change{}
+	for _, existingUser := range current {
+		if _, ok := wanted[existingUser.Name]; ok {
+			continue
+		}
+
+		deletes = append(deletes, Change{
+			Kind:    KindUser,
+			Name:    existingUser.Name,
+			Action:  ActionDelete,
+			version: existingUser.Version,
+		})
+	}
+
+	sortByName(deletes)
+
+	return changes, deletes, nil
+}
+
+// labelChanges lists the labels that are added, changed or removed,
+// sorted by key.
+func labelChanges(current map[string]string, wanted map[string]string) []FieldChange {
+	keys := []string{}
+	for key := range current {
+		keys = append(keys, key)
+	}
+
+	for key := range wanted {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	changes := []FieldChange{}
+	for _, key := range keys {
+		currentValue, inCurrent := current[key]
+		wantedValue, inWanted := wanted[key]
+		if inCurrent && inWanted && currentValue == wantedValue {
+			continue
+		}
+
+		change := FieldChange{Field: "labels." + key}
+		if inCurrent {
+			change.From = stringValue(currentValue)
+		}
+
+		if inWanted {
+			change.To = stringValue(wantedValue)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func desiredTopology(topology *network.Topology) network.Topology {
+	if topology == nil {
+		return network.DefaultTopology()
+	}
+
+	return *topology
+}
+
+// topologyValue describes a topology as compact JSON. Peer groups
+// without nodes are described the same way however they were given so
+// that they compare as equal.
+func topologyValue(topology network.Topology) *string {
+	peerGroups := make([]network.PeerGroup, 0, len(topology.PeerGroups))
+	for _, peerGroup := range topology.PeerGroups {
+		if peerGroup.Nodes == nil {
+			peerGroup.Nodes = []string{}
+		}
+
+		peerGroups = append(peerGroups, peerGroup)
+	}
+
+	topology.PeerGroups = peerGroups
+
+	rawTopology, _ := json.Marshal(topology)
+
+	return stringValue(string(rawTopology))
+}
+
+func prefixesEqual(left *netaddr.IPPrefix, right *netaddr.IPPrefix) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+
+	return *left == *right
+}
+
+func prefixValue(prefix *netaddr.IPPrefix) *string {
+	if prefix == nil {
+		return nil
+	}
+
+	return stringValue(prefix.String())
+}
+
+func boolValue(value bool) *string {
+	return stringValue(strconv.FormatBool(value))
+}
+
+func stringValue(value string) *string {
+	return &value
+}
+
+func describeValue(value *string) string {
+	if value == nil {
+		return "nothing"
+	}
+
+	return *value
+}
+
+func sortByName(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+}
@@ -0,0 +1,270 @@
+package apply_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/apply"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func networkResource(name string, labels map[string]string, spec map[string]any) apply.Resource {
+	return apply.Resource{
+		APIVersion: apply.APIVersion,
+		Kind:       apply.KindNetwork,
+		Metadata:   apply.Metadata{Name: name, Labels: labels},
+		Spec:       spec,
+	}
+}
+
+func userResource(name string, spec map[string]any) apply.Resource {
+	return apply.Resource{
+		APIVersion: apply.APIVersion,
+		Kind:       apply.KindUser,
+		Metadata:   apply.Metadata{Name: name},
+		Spec:       spec,
+	}
+}
+
+func existingNetwork(name string, cidr string) apply.NetworkState {
+	prefix := netaddr.MustParseIPPrefix(cidr)
+
+	return apply.NetworkState{
+		Name:     name,
+		IPv4CIDR: &prefix,
+		Labels:   map[string]string{},
+		Topology: network.DefaultTopology(),
+		Version:  3,
+	}
+}
+
+func actions(changes []apply.Change) []string {
+	summary := []string{}
+	for _, change := range changes {
+		summary = append(summary, string(change.Kind)+"/"+change.Name+"="+string(change.Action))
+	}
+
+	return summary
+}
+
+func TestPlanShouldCreateMissingResources(t *testing.T) {
+	changes, err := apply.Plan(
+		[]apply.Resource{
+			networkResource("office", map[string]string{"team": "ops"}, map[string]any{
+				"ipv4CIDR": "10.1.0.0/24",
+			}),
+			userResource("alice", nil),
+		},
+		apply.State{},
+		false,
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Network/office=create", "User/alice=create"}, actions(changes))
+
+	fields := map[string]string{}
+	for _, field := range changes[0].Fields {
+		require.Nil(t, field.From)
+		fields[field.Field] = *field.To
+	}
+
+	require.Equal(t, "10.1.0.0/24", fields["ipv4CIDR"])
+	require.Equal(t, `{"mode":"full-mesh"}`, fields["topology"])
+	require.Equal(t, "ops", fields["labels.team"])
+
+	require.Len(t, changes[1].Fields, 1)
+	require.Equal(t, "active", *changes[1].Fields[0].To)
+}
+
+func TestPlanShouldLeaveMatchingResourcesUnchanged(t *testing.T) {
+	changes, err := apply.Plan(
+		[]apply.Resource{
+			networkResource("office", nil, map[string]any{
+				"ipv4CIDR": "10.1.0.0/24",
+				"topology": map[string]any{"mode": "full-mesh"},
+			}),
+			userResource("alice", map[string]any{"status": "active"}),
+		},
+		apply.State{
+			Networks: []apply.NetworkState{existingNetwork("office", "10.1.0.0/24")},
+			Users:    []apply.UserState{{Name: "alice", Status: user.StatusActive, Version: 1}},
+		},
+		false,
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Network/office=unchanged", "User/alice=unchanged"}, actions(changes))
+	require.Empty(t, changes[0].Fields)
+	require.Empty(t, changes[1].Fields)
+}
+
+func TestPlanShouldDiffChangedFields(t *testing.T) {
+	current := existingNetwork("office", "10.1.0.0/24")
+	current.Labels = map[string]string{"team": "ops", "site": "hq"}
+
+	changes, err := apply.Plan(
+		[]apply.Resource{
+			networkResource("office", map[string]string{"team": "dev", "tier": "1"}, map[string]any{
+				"ipv4PrefixLength": 24,
+				"topology":         map[string]any{"mode": "hub-and-spoke", "hubs": []any{"gateway"}},
+				"presharedKeys":    true,
+			}),
+			userResource("alice", map[string]any{"status": "deactivated"}),
+		},
+		apply.State{
+			Networks: []apply.NetworkState{current},
+			Users:    []apply.UserState{{Name: "alice", Status: user.StatusActive, Version: 1}},
+		},
+		false,
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Network/office=update", "User/alice=update"}, actions(changes))
+
+	type fieldChange struct {
+		From string
+		To   string
+	}
+
+	fields := map[string]fieldChange{}
+	for _, field := range changes[0].Fields {
+		var change fieldChange
+		if field.From != nil {
+			change.From = *field.From
+		}
+
+		if field.To != nil {
+			change.To = *field.To
+		}
+
+		fields[field.Field] = change
+	}
+
+	require.Equal(t, map[string]fieldChange{
+		"topology": {
+			From: `{"mode":"full-mesh"}`,
+			To:   `{"mode":"hub-and-spoke","hubs":["gateway"]}`,
+		},
+		"presharedKeys": {From: "false", To: "true"},
+		"labels.site":   {From: "hq"},
+		"labels.team":   {From: "ops", To: "dev"},
+		"labels.tier":   {To: "1"},
+	}, fields)
+
+	require.Equal(t, "active", *changes[1].Fields[0].From)
+	require.Equal(t, "deactivated", *changes[1].Fields[0].To)
+}
+
+func TestPlanShouldOnlyPruneKindsInTheManifest(t *testing.T) {
+	state := apply.State{
+		Networks: []apply.NetworkState{
+			existingNetwork("zeta", "10.3.0.0/24"),
+			existingNetwork("office", "10.1.0.0/24"),
+			existingNetwork("lab", "10.2.0.0/24"),
+		},
+		Users: []apply.UserState{{Name: "bob", Status: user.StatusActive, Version: 1}},
+	}
+	resources := []apply.Resource{
+		networkResource("office", nil, map[string]any{"ipv4CIDR": "10.1.0.0/24"}),
+	}
+
+	changes, err := apply.Plan(resources, state, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Network/office=unchanged"}, actions(changes))
+
+	changes, err = apply.Plan(resources, state, true)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{"Network/office=unchanged", "Network/lab=delete", "Network/zeta=delete"},
+		actions(changes),
+	)
+}
+
+func TestPlanShouldRejectRangeChanges(t *testing.T) {
+	tests := map[string]map[string]any{
+		"different range":         {"ipv4CIDR": "10.9.0.0/24"},
+		"different prefix length": {"ipv4PrefixLength": 16},
+		"added range":             {"ipv4CIDR": "10.1.0.0/24", "ipv6CIDR": "fd00::/64"},
+		"allow overlap":           {"ipv4CIDR": "10.1.0.0/24", "allowOverlap": true},
+	}
+
+	for name, spec := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := apply.Plan(
+				[]apply.Resource{networkResource("office", nil, spec)},
+				apply.State{Networks: []apply.NetworkState{existingNetwork("office", "10.1.0.0/24")}},
+				false,
+			)
+
+			var validationError errortypes.ValidationError
+			require.True(t, errors.As(err, &validationError))
+			require.Contains(t, validationError.SafeMessage, "has to be deleted and created again")
+		})
+	}
+}
+
+func TestPlanShouldRejectInvalidManifests(t *testing.T) {
+	tests := map[string]struct {
+		Resources []apply.Resource
+		Message   string
+	}{
+		"unsupported api version": {
+			Resources: []apply.Resource{{
+				APIVersion: "ley/v0",
+				Kind:       apply.KindUser,
+				Metadata:   apply.Metadata{Name: "alice"},
+			}},
+			Message: "unsupported apiVersion 'ley/v0'",
+		},
+		"unsupported kind": {
+			Resources: []apply.Resource{{
+				APIVersion: apply.APIVersion,
+				Kind:       "ACLRule",
+				Metadata:   apply.Metadata{Name: "allow"},
+			}},
+			Message: "unsupported kind 'ACLRule'",
+		},
+		"missing name": {
+			Resources: []apply.Resource{userResource("", nil)},
+			Message:   "missing a name",
+		},
+		"duplicate": {
+			Resources: []apply.Resource{userResource("alice", nil), userResource("alice", nil)},
+			Message:   "User 'alice' is defined more than once",
+		},
+		"unknown field": {
+			Resources: []apply.Resource{
+				networkResource("office", nil, map[string]any{"ipv4Range": "10.1.0.0/24"}),
+			},
+			Message: "Network 'office' has an invalid spec",
+		},
+		"invalid network": {
+			Resources: []apply.Resource{networkResource("office", nil, map[string]any{})},
+			Message:   "Must have at least one IP range defined",
+		},
+		"user labels": {
+			Resources: []apply.Resource{{
+				APIVersion: apply.APIVersion,
+				Kind:       apply.KindUser,
+				Metadata:   apply.Metadata{Name: "alice", Labels: map[string]string{"team": "ops"}},
+			}},
+			Message: "User 'alice' cannot have labels",
+		},
+		"invalid status": {
+			Resources: []apply.Resource{userResource("alice", map[string]any{"status": "banned"})},
+			Message:   "Invalid user status 'banned'",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := apply.Plan(test.Resources, apply.State{}, false)
+
+			var validationError errortypes.ValidationError
+			require.True(t, errors.As(err, &validationError))
+			require.Contains(t, validationError.SafeMessage, test.Message)
+		})
+	}
+}
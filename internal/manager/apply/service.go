@@ -0,0 +1,238 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/user"
+)
+
+// Service makes the managed resources match manifests.
+type Service struct {
+	networkService *network.Service
+	userService    *user.Service
+}
+
+// NewService creates a new apply service.
+func NewService(networkService *network.Service, userService *user.Service) *Service {
+	return &Service{
+		networkService: networkService,
+		userService:    userService,
+	}
+}
+
+// ApplyOpts gives the manifest to apply and how to apply it.
+type ApplyOpts struct {
+	Resources []Resource
+
+	// DryRun only plans the changes without making them.
+	DryRun bool
+
+	// Prune deletes the resources that aren't in the manifest, for the
+	// kinds of resources that the manifest has. Deleting a network
+	// also removes all of its nodes.
+	Prune bool
+}
+
+// Apply plans the changes needed to match the manifest and then makes
+// them one at a time unless it is a dry run. Every change is limited to
+// the version of the resource it was planned against so that nothing
+// changed since is overwritten. Changes aren't rolled back when a later
+// one fails, so applying the manifest again picks up where it stopped.
+func (service *Service) Apply(ctx context.Context, opts ApplyOpts) ([]Change, error) {
+	current, err := service.currentState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := Plan(opts.Resources, current, opts.Prune)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return changes, nil
+	}
+
+	for _, change := range changes {
+		if err := service.applyChange(ctx, change); err != nil {
+			return nil, describeChangeError(change, err)
+		}
+	}
+
+	return changes, nil
+}
+
+func (service *Service) currentState(ctx context.Context) (State, error) {
+	state := State{
+		Networks: []NetworkState{},
+		Users:    []UserState{},
+	}
+
+	params := listing.Params{
+		Limit:     listing.MaxLimit,
+		SortField: listing.SortFieldCreatedOn,
+		SortOrder: listing.SortOrderAscending,
+	}
+	for {
+		page, err := service.networkService.ListNetworks(ctx, organization.DefaultName, params)
+		if err != nil {
+			return State{}, err
+		}
+
+		for index := range page.Items {
+			state.Networks = append(state.Networks, NewNetworkState(&page.Items[index]))
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+
+		if params.Cursor, err = decodeNextCursor(page.NextCursor); err != nil {
+			return State{}, err
+		}
+	}
+
+	params.Cursor = nil
+	for {
+		page, err := service.userService.ListUsers(ctx, params)
+		if err != nil {
+			return State{}, err
+		}
+
+		for index := range page.Items {
+			state.Users = append(state.Users, NewUserState(&page.Items[index]))
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+
+		if params.Cursor, err = decodeNextCursor(page.NextCursor); err != nil {
+			return State{}, err
+		}
+	}
+
+	return state, nil
+}
+
+func decodeNextCursor(encodedCursor string) (*listing.Cursor, error) {
+	cursor, err := listing.DecodeCursor(encodedCursor)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to read current state due to a system error",
+			UnsafeMessage: "Unable to decode next page cursor",
+			WrappedError:  err,
+		}
+	}
+
+	return cursor, nil
+}
+
+func (service *Service) applyChange(ctx context.Context, change Change) error {
+	expectedVersions := []int64{change.version}
+
+	switch {
+	case change.Action == ActionUnchanged:
+		return nil
+
+	case change.Kind == KindNetwork && change.Action == ActionCreate:
+		_, err := service.networkService.CreateNetwork(ctx, organization.DefaultName, change.networkOpts)
+		return err
+
+	case change.Kind == KindNetwork && change.Action == ActionUpdate:
+		updateOpts := change.networkUpdate
+		updateOpts.ExpectedVersions = expectedVersions
+
+		_, err := service.networkService.UpdateNetwork(ctx, organization.DefaultName, change.Name, updateOpts)
+		return err
+
+	case change.Kind == KindNetwork && change.Action == ActionDelete:
+		return service.networkService.DeleteNetwork(ctx, organization.DefaultName, change.Name, expectedVersions)
+
+	case change.Kind == KindUser && change.Action == ActionCreate:
+		createdUser, err := service.userService.CreateUser(ctx, user.CreateUserOpts{Username: change.Name})
+		if err != nil || createdUser.Status() == change.userStatus {
+			return err
+		}
+
+		status := change.userStatus
+		_, err = service.userService.UpdateUser(ctx, change.Name, user.UpdateUserOpts{
+			Status:           &status,
+			ExpectedVersions: []int64{createdUser.Version()},
+		})
+
+		return err
+
+	case change.Kind == KindUser && change.Action == ActionUpdate:
+		status := change.userStatus
+		_, err := service.userService.UpdateUser(ctx, change.Name, user.UpdateUserOpts{
+			Status:           &status,
+			ExpectedVersions: expectedVersions,
+		})
+
+		return err
+
+	case change.Kind == KindUser && change.Action == ActionDelete:
+		return service.userService.DeleteUser(ctx, change.Name, expectedVersions)
+	}
+
+	return errortypes.SystemError{
+		SafeMessage:   "Unable to apply manifest due to a system error",
+		UnsafeMessage: fmt.Sprintf("Unsupported change %s of %s", change.Action, change.Kind),
+	}
+}
+
+// describeChangeError names the resource that couldn't be changed in
+// the message of the error while keeping its type.
+func describeChangeError(change Change, err error) error {
+	prefix := fmt.Sprintf(
+		"Unable to %s %s '%s'",
+		change.Action,
+		strings.ToLower(string(change.Kind)),
+		change.Name,
+	)
+
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
+	var userError errortypes.UserError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &validationError):
+		return errortypes.NewWrappedValidationError(err, "%s: %s", prefix, validationError.SafeMessage)
+
+	case errors.As(err, &notFoundError):
+		return errortypes.NotFoundError{UserError: prefixUserError(prefix, notFoundError.UserError, err)}
+
+	case errors.As(err, &preconditionFailedError):
+		return errortypes.PreconditionFailedError{
+			UserError: prefixUserError(prefix, preconditionFailedError.UserError, err),
+		}
+
+	case errors.As(err, &userError):
+		return prefixUserError(prefix, userError, err)
+
+	case errors.As(err, &systemError):
+		return errortypes.SystemError{
+			SafeMessage:   fmt.Sprintf("%s: %s", prefix, systemError.SafeMessage),
+			UnsafeMessage: systemError.UnsafeMessage,
+			WrappedError:  err,
+		}
+	}
+
+	return err
+}
+
+func prefixUserError(prefix string, userError errortypes.UserError, err error) errortypes.UserError {
+	return errortypes.UserError{
+		SafeMessage:  fmt.Sprintf("%s: %s", prefix, userError.SafeMessage),
+		WrappedError: err,
+	}
+}
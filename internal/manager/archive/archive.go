@@ -0,0 +1,554 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/group"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/durandj/ley/internal/manager/webhook"
+	"inet.af/netaddr"
+)
+
+// Read parses an archive and checks that it is complete.
+func Read(reader io.Reader) (*Archive, error) {
+	rawArchive, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read archive: %w", err)
+	}
+
+	var header struct {
+		Format  string `json:"format"`
+		Version int    `json:"version"`
+	}
+	if err := json.Unmarshal(rawArchive, &header); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Invalid archive: %v", err)
+	}
+
+	if header.Format != Format {
+		return nil, errortypes.NewValidationError("Not a Ley archive")
+	}
+
+	if header.Version < 1 || header.Version > Version {
+		return nil, errortypes.NewValidationError(
+			"Unsupported archive version %d, only versions up to %d can be read",
+			header.Version,
+			Version,
+		)
+	}
+
+	var archive Archive
+	if err := json.Unmarshal(rawArchive, &archive); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Invalid archive: %v", err)
+	}
+
+	if header.Version < 2 {
+		upgradeFromVersion1(&archive)
+	}
+
+	if err := Validate(&archive); err != nil {
+		return nil, err
+	}
+
+	return &archive, nil
+}
+
+// upgradeFromVersion1 moves the networks of an archive written before
+// organizations existed into the default organization.
+func upgradeFromVersion1(archive *Archive) {
+	archive.Version = Version
+	for index := range archive.Networks {
+		archive.Networks[index].OrganizationID = organization.DefaultID
+	}
+}
+
+// Write serializes an archive.
+func Write(writer io.Writer, archive *Archive) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(archive); err != nil {
+		return fmt.Errorf("Unable to write archive: %w", err)
+	}
+
+	return nil
+}
+
+// Validate checks that every record of an archive is valid on its own
+// and that the records refer to each other correctly, so that nodes
+// belong to networks in the archive and preshared keys to nodes of the
+// same network. Members of groups have to be users or groups of the
+// archive, without groups being nested in themselves. Networks and
+// memberships may refer to the default organization without it being
+// in the archive.
+func Validate(archive *Archive) error {
+	userIDs, err := validateUsers(archive.Users)
+	if err != nil {
+		return err
+	}
+
+	organizationIDs, err := validateOrganizations(archive.Organizations)
+	if err != nil {
+		return err
+	}
+
+	if err := validateMemberships(archive.Memberships, organizationIDs, userIDs); err != nil {
+		return err
+	}
+
+	groupIDs, err := validateGroups(archive.Groups)
+	if err != nil {
+		return err
+	}
+
+	if err := validateGroupMembers(archive.GroupMembers, groupIDs, userIDs); err != nil {
+		return err
+	}
+
+	networksByID, err := validateNetworks(archive.Networks, organizationIDs)
+	if err != nil {
+		return err
+	}
+
+	nodesByID, err := validateNodes(archive.Nodes, networksByID)
+	if err != nil {
+		return err
+	}
+
+	if err := validatePresharedKeys(archive.PresharedKeys, nodesByID); err != nil {
+		return err
+	}
+
+	return validateWebhooks(archive.Webhooks)
+}
+
+func validateUsers(users []User) (map[string]struct{}, error) {
+	ids := map[string]struct{}{}
+	usernames := map[string]struct{}{}
+	emails := map[string]struct{}{}
+	externalIDs := map[string]struct{}{}
+	for _, archivedUser := range users {
+		if err := checkUnique(ids, archivedUser.ID, KindUser, "ID"); err != nil {
+			return nil, err
+		}
+
+		if err := checkUnique(usernames, archivedUser.Username, KindUser, "username"); err != nil {
+			return nil, err
+		}
+
+		if archivedUser.Email != "" {
+			if err := checkUnique(emails, strings.ToLower(archivedUser.Email), KindUser, "email"); err != nil {
+				return nil, err
+			}
+		}
+
+		if archivedUser.ExternalID != "" {
+			if err := checkUnique(externalIDs, archivedUser.ExternalID, KindUser, "external ID"); err != nil {
+				return nil, err
+			}
+		}
+
+		createOpts := user.CreateUserOpts{
+			Username:      archivedUser.Username,
+			DisplayName:   archivedUser.DisplayName,
+			Email:         archivedUser.Email,
+			EmailVerified: archivedUser.EmailVerified,
+			ExternalID:    archivedUser.ExternalID,
+			Metadata:      archivedUser.Metadata,
+		}
+		if err := createOpts.Validate(); err != nil {
+			return nil, invalidRecord(KindUser, archivedUser.ID, err)
+		}
+
+		updateOpts := user.UpdateUserOpts{Status: &archivedUser.Status}
+		if err := updateOpts.Validate(); err != nil {
+			return nil, invalidRecord(KindUser, archivedUser.ID, err)
+		}
+	}
+
+	return ids, nil
+}
+
+func validateOrganizations(organizations []Organization) (map[string]struct{}, error) {
+	ids := map[string]struct{}{}
+	names := map[string]struct{}{}
+	for _, archivedOrganization := range organizations {
+		if err := checkUnique(ids, archivedOrganization.ID, KindOrganization, "ID"); err != nil {
+			return nil, err
+		}
+
+		if err := checkUnique(names, archivedOrganization.Name, KindOrganization, "name"); err != nil {
+			return nil, err
+		}
+
+		opts := organization.CreateOrganizationOpts{Name: archivedOrganization.Name}
+		if err := opts.Validate(); err != nil {
+			return nil, invalidRecord(KindOrganization, archivedOrganization.ID, err)
+		}
+	}
+
+	// The default organization exists in every manager.
+	ids[organization.DefaultID] = struct{}{}
+
+	return ids, nil
+}
+
+func validateMemberships(
+	memberships []Membership,
+	organizationIDs map[string]struct{},
+	userIDs map[string]struct{},
+) error {
+	pairs := map[string]struct{}{}
+	for _, membership := range memberships {
+		pairID := membership.pairID()
+		if err := checkUnique(pairs, pairID, KindMembership, "organization and user"); err != nil {
+			return err
+		}
+
+		_, organizationOK := organizationIDs[membership.OrganizationID]
+		_, userOK := userIDs[membership.UserID]
+		if !organizationOK || !userOK {
+			return errortypes.NewValidationError(
+				"Invalid archive: membership '%s' refers to an organization or user which isn't in the archive",
+				pairID,
+			)
+		}
+	}
+
+	return nil
+}
+
+func validateGroups(groups []Group) (map[string]struct{}, error) {
+	ids := map[string]struct{}{}
+	names := map[string]struct{}{}
+	for _, archivedGroup := range groups {
+		if err := checkUnique(ids, archivedGroup.ID, KindGroup, "ID"); err != nil {
+			return nil, err
+		}
+
+		if err := checkUnique(names, archivedGroup.Name, KindGroup, "name"); err != nil {
+			return nil, err
+		}
+
+		opts := group.CreateGroupOpts{Name: archivedGroup.Name}
+		if err := opts.Validate(); err != nil {
+			return nil, invalidRecord(KindGroup, archivedGroup.ID, err)
+		}
+	}
+
+	return ids, nil
+}
+
+func validateGroupMembers(
+	members []GroupMember,
+	groupIDs map[string]struct{},
+	userIDs map[string]struct{},
+) error {
+	pairs := map[string]struct{}{}
+	nestedGroups := map[string][]string{}
+	for _, member := range members {
+		pairID := member.pairID()
+		if (member.UserID == "") == (member.MemberGroupID == "") {
+			return invalidRecord(
+				KindGroupMember,
+				pairID,
+				fmt.Errorf("Exactly one of the user and the member group has to be set"),
+			)
+		}
+
+		if err := checkUnique(pairs, pairID, KindGroupMember, "group and member"); err != nil {
+			return err
+		}
+
+		_, groupOK := groupIDs[member.GroupID]
+		memberOK := false
+		if member.UserID != "" {
+			_, memberOK = userIDs[member.UserID]
+		} else {
+			_, memberOK = groupIDs[member.MemberGroupID]
+		}
+
+		if !groupOK || !memberOK {
+			return errortypes.NewValidationError(
+				"Invalid archive: group member '%s' refers to a group or user which isn't in the archive",
+				pairID,
+			)
+		}
+
+		if member.MemberGroupID != "" {
+			nestedGroups[member.GroupID] = append(nestedGroups[member.GroupID], member.MemberGroupID)
+		}
+	}
+
+	for groupID, memberGroupIDs := range nestedGroups {
+		for _, memberGroupID := range memberGroupIDs {
+			if containsGroup(nestedGroups, memberGroupID, groupID) {
+				return errortypes.NewValidationError(
+					"Invalid archive: group '%s' is nested in itself through group '%s'",
+					groupID,
+					memberGroupID,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// containsGroup tells whether the second group is the first one or one
+// of the groups nested in it, however deep.
+func containsGroup(nestedGroups map[string][]string, groupID string, otherGroupID string) bool {
+	visited := map[string]struct{}{}
+	pending := []string{groupID}
+	for len(pending) > 0 {
+		currentID := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if currentID == otherGroupID {
+			return true
+		}
+
+		if _, ok := visited[currentID]; ok {
+			continue
+		}
+
+		visited[currentID] = struct{}{}
+		pending = append(pending, nestedGroups[currentID]...)
+	}
+
+	return false
+}
+
+func validateNetworks(networks []Network, organizationIDs map[string]struct{}) (map[string]Network, error) {
+	networksByID := map[string]Network{}
+	names := map[string]struct{}{}
+	for index, archivedNetwork := range networks {
+		if _, ok := organizationIDs[archivedNetwork.OrganizationID]; !ok {
+			return nil, errortypes.NewValidationError(
+				"Invalid archive: network '%s' belongs to organization '%s' which isn't in the archive",
+				archivedNetwork.ID,
+				archivedNetwork.OrganizationID,
+			)
+		}
+
+		nameKey := archivedNetwork.OrganizationID + "/" + archivedNetwork.Name
+		if err := checkUnique(names, nameKey, KindNetwork, "organization and name"); err != nil {
+			return nil, err
+		}
+
+		if _, ok := networksByID[archivedNetwork.ID]; ok || archivedNetwork.ID == "" {
+			return nil, duplicateRecord(KindNetwork, "ID", archivedNetwork.ID)
+		}
+
+		opts := network.CreateNetworkOpts{
+			Name:         archivedNetwork.Name,
+			IPv4CIDR:     archivedNetwork.IPv4CIDR,
+			IPv6CIDR:     archivedNetwork.IPv6CIDR,
+			Labels:       archivedNetwork.Labels,
+			AllowOverlap: archivedNetwork.AllowOverlap,
+			Topology:     &archivedNetwork.Topology,
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, invalidRecord(KindNetwork, archivedNetwork.ID, err)
+		}
+
+		for _, otherNetwork := range networks[:index] {
+			if rangesOverlap(archivedNetwork, otherNetwork) {
+				return nil, errortypes.NewValidationError(
+					"Invalid archive: networks '%s' and '%s' overlap",
+					otherNetwork.Name,
+					archivedNetwork.Name,
+				)
+			}
+		}
+
+		networksByID[archivedNetwork.ID] = archivedNetwork
+	}
+
+	return networksByID, nil
+}
+
+func validateNodes(nodes []Node, networksByID map[string]Network) (map[string]Node, error) {
+	nodesByID := map[string]Node{}
+	names := map[string]struct{}{}
+	publicKeys := map[string]struct{}{}
+	addresses := map[string]struct{}{}
+	for _, archivedNode := range nodes {
+		if _, ok := nodesByID[archivedNode.ID]; ok || archivedNode.ID == "" {
+			return nil, duplicateRecord(KindNode, "ID", archivedNode.ID)
+		}
+
+		archivedNetwork, ok := networksByID[archivedNode.NetworkID]
+		if !ok {
+			return nil, errortypes.NewValidationError(
+				"Invalid archive: node '%s' belongs to network '%s' which isn't in the archive",
+				archivedNode.ID,
+				archivedNode.NetworkID,
+			)
+		}
+
+		if archivedNode.Name == "" {
+			return nil, invalidRecord(KindNode, archivedNode.ID, fmt.Errorf("Missing name"))
+		}
+
+		err := checkUnique(names, archivedNode.NetworkID+"/"+archivedNode.Name, KindNode, "network and name")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkUnique(publicKeys, archivedNode.PublicKey, KindNode, "public key"); err != nil {
+			return nil, err
+		}
+
+		if err := node.ValidateKey(archivedNode.PublicKey); err != nil {
+			return nil, invalidRecord(KindNode, archivedNode.ID, fmt.Errorf("Invalid public key: %w", err))
+		}
+
+		if archivedNode.PreviousPublicKey != nil {
+			if err := node.ValidateKey(*archivedNode.PreviousPublicKey); err != nil {
+				return nil, invalidRecord(
+					KindNode,
+					archivedNode.ID,
+					fmt.Errorf("Invalid previous public key: %w", err),
+				)
+			}
+		}
+
+		nodeAddresses := []struct {
+			Family  string
+			Address *netaddr.IP
+			Range   *netaddr.IPPrefix
+		}{
+			{Family: "IPv4", Address: archivedNode.IPv4Address, Range: archivedNetwork.IPv4CIDR},
+			{Family: "IPv6", Address: archivedNode.IPv6Address, Range: archivedNetwork.IPv6CIDR},
+		}
+		for _, nodeAddress := range nodeAddresses {
+			if nodeAddress.Address == nil {
+				continue
+			}
+
+			if nodeAddress.Range == nil || !nodeAddress.Range.Contains(*nodeAddress.Address) {
+				return nil, invalidRecord(
+					KindNode,
+					archivedNode.ID,
+					fmt.Errorf(
+						"%s address %s is outside of its network's range",
+						nodeAddress.Family,
+						nodeAddress.Address,
+					),
+				)
+			}
+
+			addressKey := archivedNode.NetworkID + "/" + nodeAddress.Address.String()
+			if err := checkUnique(addresses, addressKey, KindNode, "network and address"); err != nil {
+				return nil, err
+			}
+		}
+
+		nodesByID[archivedNode.ID] = archivedNode
+	}
+
+	return nodesByID, nil
+}
+
+func validatePresharedKeys(presharedKeys []PresharedKey, nodesByID map[string]Node) error {
+	pairs := map[string]struct{}{}
+	for _, presharedKey := range presharedKeys {
+		pairID := presharedKey.FirstNodeID + "/" + presharedKey.SecondNodeID
+		if presharedKey.FirstNodeID >= presharedKey.SecondNodeID {
+			return invalidRecord(
+				KindPresharedKey,
+				pairID,
+				fmt.Errorf("The first node ID must sort before the second"),
+			)
+		}
+
+		if err := checkUnique(pairs, pairID, KindPresharedKey, "pair of nodes"); err != nil {
+			return err
+		}
+
+		firstNode, firstOK := nodesByID[presharedKey.FirstNodeID]
+		secondNode, secondOK := nodesByID[presharedKey.SecondNodeID]
+		if !firstOK || !secondOK {
+			return errortypes.NewValidationError(
+				"Invalid archive: preshared key '%s' belongs to a node which isn't in the archive",
+				pairID,
+			)
+		}
+
+		if firstNode.NetworkID != secondNode.NetworkID {
+			return errortypes.NewValidationError(
+				"Invalid archive: preshared key '%s' pairs nodes of different networks",
+				pairID,
+			)
+		}
+
+		if err := node.ValidateKey(presharedKey.Key); err != nil {
+			return invalidRecord(KindPresharedKey, pairID, err)
+		}
+	}
+
+	return nil
+}
+
+func validateWebhooks(webhooks []Webhook) error {
+	ids := map[string]struct{}{}
+	for _, archivedWebhook := range webhooks {
+		if err := checkUnique(ids, archivedWebhook.ID, KindWebhook, "ID"); err != nil {
+			return err
+		}
+
+		opts := webhook.CreateSubscriptionOpts{
+			URL:        archivedWebhook.URL,
+			EventTypes: archivedWebhook.EventTypes,
+			Secret:     archivedWebhook.Secret,
+		}
+		if err := opts.Validate(); err != nil {
+			return invalidRecord(KindWebhook, archivedWebhook.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// rangesOverlap tells if two networks would clash. Networks that allow
+// overlap never clash.
+func rangesOverlap(left Network, right Network) bool {
+	if left.AllowOverlap || right.AllowOverlap {
+		return false
+	}
+
+	return prefixesOverlap(left.IPv4CIDR, right.IPv4CIDR) || prefixesOverlap(left.IPv6CIDR, right.IPv6CIDR)
+}
+
+func prefixesOverlap(left *netaddr.IPPrefix, right *netaddr.IPPrefix) bool {
+	return left != nil && right != nil && left.Overlaps(*right)
+}
+
+func checkUnique(seen map[string]struct{}, value string, kind Kind, field string) error {
+	if _, ok := seen[value]; ok || value == "" {
+		return duplicateRecord(kind, field, value)
+	}
+
+	seen[value] = struct{}{}
+
+	return nil
+}
+
+func duplicateRecord(kind Kind, field string, value string) error {
+	if value == "" {
+		return errortypes.NewValidationError("Invalid archive: a %s is missing its %s", kind, field)
+	}
+
+	return errortypes.NewValidationError("Invalid archive: more than one %s has the %s '%s'", kind, field, value)
+}
+
+func invalidRecord(kind Kind, id string, err error) error {
+	return errortypes.NewWrappedValidationError(err, "Invalid archive: %s '%s' is invalid: %v", kind, id, err)
+}
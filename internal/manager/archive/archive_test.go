@@ -0,0 +1,463 @@
+package archive_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/archive"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/durandj/ley/internal/manager/webhook"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+var createdOn = time.Date(2022, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+func key(fill byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, 32))
+}
+
+func archivedNetwork(id string, name string, cidr string) archive.Network {
+	prefix := netaddr.MustParseIPPrefix(cidr)
+
+	return archive.Network{
+		ID:             id,
+		OrganizationID: organization.DefaultID,
+		Name:           name,
+		IPv4CIDR:       &prefix,
+		Labels:         map[string]string{},
+		Topology:       network.DefaultTopology(),
+		Version:        1,
+		CreatedOn:      createdOn,
+		ModifiedOn:     createdOn,
+	}
+}
+
+func archivedNode(id string, networkID string, name string, address string, fill byte) archive.Node {
+	ip := netaddr.MustParseIP(address)
+
+	return archive.Node{
+		ID:           id,
+		NetworkID:    networkID,
+		Name:         name,
+		PublicKey:    key(fill),
+		KeyRotatedOn: createdOn,
+		IPv4Address:  &ip,
+		Version:      1,
+		CreatedOn:    createdOn,
+		ModifiedOn:   createdOn,
+	}
+}
+
+func sampleArchive() *archive.Archive {
+	return &archive.Archive{
+		Format:     archive.Format,
+		Version:    archive.Version,
+		ExportedOn: createdOn,
+		Users: []archive.User{
+			{
+				ID:         "user-1",
+				Username:   "alice",
+				Status:     user.StatusActive,
+				Version:    1,
+				CreatedOn:  createdOn,
+				ModifiedOn: createdOn,
+			},
+		},
+		Organizations: []archive.Organization{
+			{
+				ID:         "organization-1",
+				Name:       "acme",
+				Version:    1,
+				CreatedOn:  createdOn,
+				ModifiedOn: createdOn,
+			},
+		},
+		Memberships: []archive.Membership{
+			{OrganizationID: "organization-1", UserID: "user-1", JoinedOn: createdOn},
+		},
+		Groups: []archive.Group{
+			{ID: "group-1", Name: "admins", Version: 1, CreatedOn: createdOn, ModifiedOn: createdOn},
+			{ID: "group-2", Name: "staff", Version: 1, CreatedOn: createdOn, ModifiedOn: createdOn},
+		},
+		GroupMembers: []archive.GroupMember{
+			{GroupID: "group-1", UserID: "user-1", AddedOn: createdOn},
+			{GroupID: "group-2", MemberGroupID: "group-1", AddedOn: createdOn},
+		},
+		Networks: []archive.Network{archivedNetwork("network-1", "office", "10.1.0.0/24")},
+		Nodes: []archive.Node{
+			archivedNode("node-1", "network-1", "laptop", "10.1.0.2", 1),
+			archivedNode("node-2", "network-1", "desktop", "10.1.0.3", 2),
+		},
+		PresharedKeys: []archive.PresharedKey{
+			{FirstNodeID: "node-1", SecondNodeID: "node-2", Key: key(3), CreatedOn: createdOn},
+		},
+		Webhooks: []archive.Webhook{
+			{
+				ID:         "webhook-1",
+				URL:        "https://example.com/hooks",
+				EventTypes: []webhook.EventType{webhook.EventTypeNetworkCreated},
+				Secret:     "0123456789abcdef",
+				CreatedOn:  createdOn,
+			},
+		},
+	}
+}
+
+func emptyArchive() *archive.Archive {
+	return &archive.Archive{Format: archive.Format, Version: archive.Version}
+}
+
+func requireValidationError(t *testing.T, err error, message string) {
+	t.Helper()
+
+	var validationErr errortypes.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Contains(t, err.Error(), message)
+}
+
+func TestArchiveShouldRoundTrip(t *testing.T) {
+	original := sampleArchive()
+
+	var buffer bytes.Buffer
+	require.NoError(t, archive.Write(&buffer, original))
+
+	read, err := archive.Read(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, original, read)
+}
+
+func TestReadShouldRejectOtherFormats(t *testing.T) {
+	_, err := archive.Read(strings.NewReader(`{"format": "something-else", "version": 1}`))
+	requireValidationError(t, err, "Not a Ley archive")
+}
+
+func TestReadShouldRejectNewerVersions(t *testing.T) {
+	_, err := archive.Read(strings.NewReader(`{"format": "ley-archive", "version": 4}`))
+	requireValidationError(t, err, "Unsupported archive version 4")
+}
+
+func TestReadShouldMoveNetworksOfVersion1ArchivesToTheDefaultOrganization(t *testing.T) {
+	original := sampleArchive()
+	original.Version = 1
+	original.Organizations = nil
+	original.Memberships = nil
+	original.Groups = nil
+	original.GroupMembers = nil
+	original.Networks[0].OrganizationID = ""
+
+	var buffer bytes.Buffer
+	require.NoError(t, archive.Write(&buffer, original))
+
+	read, err := archive.Read(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, archive.Version, read.Version)
+	require.Equal(t, organization.DefaultID, read.Networks[0].OrganizationID)
+}
+
+func TestArchiveShouldRoundTripGroupsAndTheirMembers(t *testing.T) {
+	original := sampleArchive()
+
+	var buffer bytes.Buffer
+	require.NoError(t, archive.Write(&buffer, original))
+	require.Contains(t, buffer.String(), `"memberGroupId": "group-1"`)
+
+	read, err := archive.Read(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, original.Groups, read.Groups)
+	require.Equal(t, original.GroupMembers, read.GroupMembers)
+}
+
+func TestReadShouldAcceptVersion2ArchivesWithoutGroups(t *testing.T) {
+	original := sampleArchive()
+	original.Version = 2
+	original.Groups = nil
+	original.GroupMembers = nil
+
+	var buffer bytes.Buffer
+	require.NoError(t, archive.Write(&buffer, original))
+
+	read, err := archive.Read(&buffer)
+	require.NoError(t, err)
+	require.Empty(t, read.Groups)
+	require.Empty(t, read.GroupMembers)
+}
+
+func TestValidateShouldRejectGroupMembersOfMissingUsers(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.GroupMembers[0].UserID = "user-2"
+
+	requireValidationError(t, archive.Validate(invalid), "group member 'group-1/user/user-2'")
+}
+
+func TestValidateShouldRejectGroupMembersOfMissingGroups(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.GroupMembers[1].MemberGroupID = "group-3"
+
+	requireValidationError(t, archive.Validate(invalid), "group member 'group-2/group/group-3'")
+}
+
+func TestValidateShouldRejectGroupMembersThatAreBothAUserAndAGroup(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.GroupMembers[1].UserID = "user-1"
+
+	requireValidationError(t, archive.Validate(invalid), "Exactly one of the user and the member group")
+}
+
+func TestValidateShouldRejectGroupsNestedInThemselves(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.GroupMembers = append(
+		invalid.GroupMembers,
+		archive.GroupMember{GroupID: "group-1", MemberGroupID: "group-2", AddedOn: createdOn},
+	)
+
+	requireValidationError(t, archive.Validate(invalid), "is nested in itself")
+}
+
+func TestValidateShouldAllowTheSameNetworkNameInDifferentOrganizations(t *testing.T) {
+	valid := sampleArchive()
+	otherNetwork := archivedNetwork("network-2", "office", "10.2.0.0/24")
+	otherNetwork.OrganizationID = "organization-1"
+	valid.Networks = append(valid.Networks, otherNetwork)
+
+	require.NoError(t, archive.Validate(valid))
+
+	valid.Networks[1].OrganizationID = organization.DefaultID
+	requireValidationError(t, archive.Validate(valid), "organization and name")
+}
+
+func TestValidateShouldRejectNetworksOfMissingOrganizations(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.Networks[0].OrganizationID = "organization-2"
+
+	requireValidationError(t, archive.Validate(invalid), "belongs to organization 'organization-2'")
+}
+
+func TestValidateShouldRejectMembershipsOfMissingUsers(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.Memberships[0].UserID = "user-2"
+
+	requireValidationError(t, archive.Validate(invalid), "membership 'organization-1/user-2'")
+}
+
+func TestValidateShouldRejectNodesOfMissingNetworks(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.Nodes[1].NetworkID = "network-2"
+	invalid.PresharedKeys = nil
+
+	requireValidationError(t, archive.Validate(invalid), "belongs to network 'network-2'")
+}
+
+func TestValidateShouldRejectAddressesOutsideOfTheNetwork(t *testing.T) {
+	invalid := sampleArchive()
+	outside := netaddr.MustParseIP("10.2.0.2")
+	invalid.Nodes[0].IPv4Address = &outside
+
+	requireValidationError(t, archive.Validate(invalid), "outside of its network's range")
+}
+
+func TestValidateShouldRejectDuplicatePublicKeys(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.Nodes[1].PublicKey = invalid.Nodes[0].PublicKey
+
+	requireValidationError(t, archive.Validate(invalid), "public key")
+}
+
+func TestValidateShouldRejectPresharedKeysOfMissingNodes(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.PresharedKeys[0].SecondNodeID = "node-3"
+
+	requireValidationError(t, archive.Validate(invalid), "isn't in the archive")
+}
+
+func TestValidateShouldRejectOverlappingNetworks(t *testing.T) {
+	invalid := sampleArchive()
+	invalid.Networks = append(invalid.Networks, archivedNetwork("network-2", "lab", "10.1.0.128/25"))
+
+	requireValidationError(t, archive.Validate(invalid), "networks 'office' and 'lab' overlap")
+}
+
+func TestMergeShouldAddEverythingToAnEmptyState(t *testing.T) {
+	incoming := sampleArchive()
+
+	additions, report := archive.Merge(emptyArchive(), incoming)
+	require.Empty(t, report.Conflicts)
+	require.Equal(t, archive.Counts{
+		Users:         1,
+		Organizations: 1,
+		Memberships:   1,
+		Groups:        2,
+		GroupMembers:  2,
+		Networks:      1,
+		Nodes:         2,
+		PresharedKeys: 1,
+		Webhooks:      1,
+	}, report.Imported)
+	require.Equal(t, incoming.Nodes, additions.Nodes)
+}
+
+func TestMergeShouldLeaveIdenticalRecordsUnchanged(t *testing.T) {
+	current := sampleArchive()
+
+	// Records that were used since they were exported still match.
+	lastSeenOn := createdOn.Add(time.Hour)
+	current.Nodes[0].LastSeenOn = &lastSeenOn
+	current.Networks[0].Version = 5
+	current.Networks[0].ModifiedOn = lastSeenOn
+
+	additions, report := archive.Merge(current, sampleArchive())
+	require.Empty(t, report.Conflicts)
+	require.Equal(t, archive.Counts{}, report.Imported)
+	require.Equal(t, archive.Counts{
+		Users:         1,
+		Organizations: 1,
+		Memberships:   1,
+		Groups:        2,
+		GroupMembers:  2,
+		Networks:      1,
+		Nodes:         2,
+		PresharedKeys: 1,
+		Webhooks:      1,
+	}, report.Unchanged)
+	require.Empty(t, additions.Nodes)
+}
+
+func TestMergeShouldReportConflicts(t *testing.T) {
+	current := emptyArchive()
+	current.Users = []archive.User{
+		{ID: "user-9", Username: "alice", Status: user.StatusActive, CreatedOn: createdOn},
+	}
+	current.Networks = []archive.Network{archivedNetwork("network-9", "lab", "10.1.0.0/16")}
+
+	current.Organizations = []archive.Organization{{ID: "organization-9", Name: "acme", CreatedOn: createdOn}}
+
+	additions, report := archive.Merge(current, sampleArchive())
+	require.Empty(t, additions.Users)
+	require.Empty(t, additions.Organizations)
+	require.Empty(t, additions.Memberships)
+	require.Empty(t, additions.Networks)
+	require.Empty(t, additions.Nodes)
+	require.Empty(t, additions.PresharedKeys)
+	require.Len(t, additions.Webhooks, 1)
+
+	reasons := map[string]string{}
+	for _, conflict := range report.Conflicts {
+		reasons[string(conflict.Kind)+"/"+conflict.ID] = conflict.Reason
+	}
+
+	require.Equal(t, map[string]string{
+		"user/user-1":                      "Username is taken by user 'user-9'",
+		"organization/organization-1":      "Name is taken by organization 'organization-9'",
+		"membership/organization-1/user-1": "The organization or user isn't being imported",
+		"group-member/group-1/user/user-1": "The group or member isn't being imported",
+		"network/network-1":                "Ranges overlap network 'lab'",
+		"node/node-1":                      "Network 'network-1' isn't being imported",
+		"node/node-2":                      "Network 'network-1' isn't being imported",
+		"preshared-key/node-1/node-2":      "A node of the pair isn't being imported",
+	}, reasons)
+}
+
+func TestMergeShouldReportChangedRecords(t *testing.T) {
+	current := sampleArchive()
+	current.Users[0].Status = user.StatusDeactivated
+	current.Nodes[1].Name = "workstation"
+	current.PresharedKeys[0].Key = key(4)
+
+	_, report := archive.Merge(current, sampleArchive())
+	require.Equal(
+		t,
+		archive.Counts{
+			Organizations: 1,
+			Memberships:   1,
+			Groups:        2,
+			GroupMembers:  2,
+			Networks:      1,
+			Nodes:         1,
+			Webhooks:      1,
+		},
+		report.Unchanged,
+	)
+	require.Len(t, report.Conflicts, 3)
+	require.Equal(t, "Differs from the existing user", report.Conflicts[0].Reason)
+	require.Equal(t, "Differs from the existing node", report.Conflicts[1].Reason)
+	require.Equal(t, "Differs from the existing preshared key", report.Conflicts[2].Reason)
+}
+
+func TestMergeShouldReportTakenNodeAddresses(t *testing.T) {
+	current := emptyArchive()
+	current.Networks = []archive.Network{archivedNetwork("network-1", "office", "10.1.0.0/24")}
+	current.Nodes = []archive.Node{archivedNode("node-9", "network-1", "phone", "10.1.0.2", 9)}
+
+	additions, report := archive.Merge(current, sampleArchive())
+	require.Len(t, additions.Nodes, 1)
+	require.Equal(t, "node-2", additions.Nodes[0].ID)
+	require.Len(t, report.Conflicts, 2)
+	require.Equal(t, "Address is used by node 'node-9'", report.Conflicts[0].Reason)
+	require.Equal(t, archive.KindPresharedKey, report.Conflicts[1].Kind)
+}
+
+func TestMergeShouldAddMembershipsToExistingOrganizations(t *testing.T) {
+	current := sampleArchive()
+	current.Memberships = nil
+
+	additions, report := archive.Merge(current, sampleArchive())
+	require.Empty(t, report.Conflicts)
+	require.Equal(t, archive.Counts{Memberships: 1}, report.Imported)
+	require.Empty(t, additions.Organizations)
+	require.Equal(t, sampleArchive().Memberships, additions.Memberships)
+}
+
+func TestMergeShouldKeepNetworkNamesPerOrganization(t *testing.T) {
+	current := emptyArchive()
+	current.Organizations = sampleArchive().Organizations
+	otherNetwork := archivedNetwork("network-9", "office", "10.9.0.0/24")
+	otherNetwork.OrganizationID = "organization-1"
+	current.Networks = []archive.Network{otherNetwork}
+
+	additions, report := archive.Merge(current, sampleArchive())
+	require.Empty(t, report.Conflicts)
+	require.Len(t, additions.Networks, 1)
+}
+
+func TestMergeShouldAddMembersToExistingGroups(t *testing.T) {
+	current := sampleArchive()
+	current.GroupMembers = current.GroupMembers[:1]
+
+	additions, report := archive.Merge(current, sampleArchive())
+	require.Empty(t, report.Conflicts)
+	require.Equal(t, archive.Counts{GroupMembers: 1}, report.Imported)
+	require.Empty(t, additions.Groups)
+	require.Equal(t, sampleArchive().GroupMembers[1:], additions.GroupMembers)
+}
+
+func TestMergeShouldReportGroupsThatWouldContainThemselves(t *testing.T) {
+	current := sampleArchive()
+	current.GroupMembers = []archive.GroupMember{
+		{GroupID: "group-1", MemberGroupID: "group-2", AddedOn: createdOn},
+	}
+
+	additions, report := archive.Merge(current, sampleArchive())
+	require.Len(t, additions.GroupMembers, 1)
+	require.Equal(t, "user-1", additions.GroupMembers[0].UserID)
+	require.Len(t, report.Conflicts, 1)
+	require.Equal(t, archive.KindGroupMember, report.Conflicts[0].Kind)
+	require.Equal(t, "Group 'group-1' already contains group 'group-2'", report.Conflicts[0].Reason)
+}
+
+func TestMergeShouldReportTakenGroupNames(t *testing.T) {
+	current := emptyArchive()
+	current.Groups = []archive.Group{{ID: "group-9", Name: "admins", CreatedOn: createdOn}}
+
+	additions, report := archive.Merge(current, sampleArchive())
+	require.Equal(t, []archive.Group{sampleArchive().Groups[1]}, additions.Groups)
+	require.Empty(t, additions.GroupMembers)
+	require.Len(t, report.Conflicts, 3)
+	require.Equal(t, "Name is taken by group 'group-9'", report.Conflicts[0].Reason)
+}
@@ -0,0 +1,422 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/webhook"
+	"github.com/lib/pq"
+	"inet.af/netaddr"
+)
+
+var (
+	//go:embed export_users.sql
+	exportUsersSQL string
+
+	//go:embed export_organizations.sql
+	exportOrganizationsSQL string
+
+	//go:embed export_memberships.sql
+	exportMembershipsSQL string
+
+	//go:embed export_groups.sql
+	exportGroupsSQL string
+
+	//go:embed export_group_members.sql
+	exportGroupMembersSQL string
+
+	//go:embed export_networks.sql
+	exportNetworksSQL string
+
+	//go:embed export_nodes.sql
+	exportNodesSQL string
+
+	//go:embed export_preshared_keys.sql
+	exportPresharedKeysSQL string
+
+	//go:embed export_webhooks.sql
+	exportWebhooksSQL string
+)
+
+// Export reads the whole state of the manager. Everything is read from
+// a single snapshot so that the archive is consistent even while the
+// manager is being used.
+func Export(ctx context.Context, db *sql.DB) (*Archive, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to export due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	archive, err := exportState(ctx, tx)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to export due to a system error",
+			UnsafeMessage: "Unable to read the state",
+			WrappedError:  err,
+		}
+	}
+
+	return archive, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func exportState(ctx context.Context, db queryer) (*Archive, error) {
+	archive := Archive{
+		Format:     Format,
+		Version:    Version,
+		ExportedOn: time.Now().UTC(),
+	}
+
+	var err error
+	if archive.Users, err = exportRows(ctx, db, exportUsersSQL, scanUser); err != nil {
+		return nil, fmt.Errorf("Unable to export users: %w", err)
+	}
+
+	archive.Organizations, err = exportRows(ctx, db, exportOrganizationsSQL, scanOrganization)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to export organizations: %w", err)
+	}
+
+	if archive.Memberships, err = exportRows(ctx, db, exportMembershipsSQL, scanMembership); err != nil {
+		return nil, fmt.Errorf("Unable to export memberships: %w", err)
+	}
+
+	if archive.Groups, err = exportRows(ctx, db, exportGroupsSQL, scanGroup); err != nil {
+		return nil, fmt.Errorf("Unable to export groups: %w", err)
+	}
+
+	if archive.GroupMembers, err = exportRows(ctx, db, exportGroupMembersSQL, scanGroupMember); err != nil {
+		return nil, fmt.Errorf("Unable to export group members: %w", err)
+	}
+
+	if archive.Networks, err = exportRows(ctx, db, exportNetworksSQL, scanNetwork); err != nil {
+		return nil, fmt.Errorf("Unable to export networks: %w", err)
+	}
+
+	if archive.Nodes, err = exportRows(ctx, db, exportNodesSQL, scanNode); err != nil {
+		return nil, fmt.Errorf("Unable to export nodes: %w", err)
+	}
+
+	archive.PresharedKeys, err = exportRows(ctx, db, exportPresharedKeysSQL, scanPresharedKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to export preshared keys: %w", err)
+	}
+
+	if archive.Webhooks, err = exportRows(ctx, db, exportWebhooksSQL, scanWebhook); err != nil {
+		return nil, fmt.Errorf("Unable to export webhooks: %w", err)
+	}
+
+	return &archive, nil
+}
+
+func exportRows[Record any](
+	ctx context.Context,
+	db queryer,
+	query string,
+	scan func(*sql.Rows) (Record, error),
+) ([]Record, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	records := []Record{}
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func scanUser(rows *sql.Rows) (User, error) {
+	var archivedUser User
+	var email sql.NullString
+	var externalID sql.NullString
+	var rawMetadata []byte
+
+	err := rows.Scan(
+		&archivedUser.ID,
+		&archivedUser.Username,
+		&archivedUser.DisplayName,
+		&email,
+		&archivedUser.EmailVerified,
+		&externalID,
+		&rawMetadata,
+		&archivedUser.Status,
+		&archivedUser.Version,
+		&archivedUser.CreatedOn,
+		&archivedUser.ModifiedOn,
+	)
+	if err != nil {
+		return archivedUser, err
+	}
+
+	archivedUser.Email = email.String
+	archivedUser.ExternalID = externalID.String
+
+	if err := json.Unmarshal(rawMetadata, &archivedUser.Metadata); err != nil {
+		return archivedUser, fmt.Errorf("Unable to parse user metadata: %w", err)
+	}
+
+	if len(archivedUser.Metadata) == 0 {
+		archivedUser.Metadata = nil
+	}
+
+	return archivedUser, nil
+}
+
+func scanOrganization(rows *sql.Rows) (Organization, error) {
+	var archivedOrganization Organization
+	err := rows.Scan(
+		&archivedOrganization.ID,
+		&archivedOrganization.Name,
+		&archivedOrganization.Version,
+		&archivedOrganization.CreatedOn,
+		&archivedOrganization.ModifiedOn,
+	)
+
+	return archivedOrganization, err
+}
+
+func scanMembership(rows *sql.Rows) (Membership, error) {
+	var membership Membership
+	err := rows.Scan(
+		&membership.OrganizationID,
+		&membership.UserID,
+		&membership.JoinedOn,
+	)
+
+	return membership, err
+}
+
+func scanGroup(rows *sql.Rows) (Group, error) {
+	var archivedGroup Group
+	err := rows.Scan(
+		&archivedGroup.ID,
+		&archivedGroup.Name,
+		&archivedGroup.Version,
+		&archivedGroup.CreatedOn,
+		&archivedGroup.ModifiedOn,
+	)
+
+	return archivedGroup, err
+}
+
+func scanGroupMember(rows *sql.Rows) (GroupMember, error) {
+	var member GroupMember
+	var userID sql.NullString
+	var memberGroupID sql.NullString
+	err := rows.Scan(
+		&member.GroupID,
+		&userID,
+		&memberGroupID,
+		&member.AddedOn,
+	)
+
+	member.UserID = userID.String
+	member.MemberGroupID = memberGroupID.String
+
+	return member, err
+}
+
+func scanNetwork(rows *sql.Rows) (Network, error) {
+	var archivedNetwork Network
+	var ipv4CIDR sql.NullString
+	var ipv6CIDR sql.NullString
+	var rawLabels []byte
+	var rawTopology []byte
+
+	err := rows.Scan(
+		&archivedNetwork.ID,
+		&archivedNetwork.OrganizationID,
+		&archivedNetwork.Name,
+		&ipv4CIDR,
+		&ipv6CIDR,
+		&archivedNetwork.AllowOverlap,
+		&rawLabels,
+		&rawTopology,
+		&archivedNetwork.PresharedKeys,
+		&archivedNetwork.Version,
+		&archivedNetwork.CreatedOn,
+		&archivedNetwork.ModifiedOn,
+	)
+	if err != nil {
+		return archivedNetwork, err
+	}
+
+	if archivedNetwork.IPv4CIDR, err = nullStringToPrefix(ipv4CIDR); err != nil {
+		return archivedNetwork, err
+	}
+
+	if archivedNetwork.IPv6CIDR, err = nullStringToPrefix(ipv6CIDR); err != nil {
+		return archivedNetwork, err
+	}
+
+	if err := json.Unmarshal(rawLabels, &archivedNetwork.Labels); err != nil {
+		return archivedNetwork, fmt.Errorf("Unable to parse network labels: %w", err)
+	}
+
+	if err := json.Unmarshal(rawTopology, &archivedNetwork.Topology); err != nil {
+		return archivedNetwork, fmt.Errorf("Unable to parse network topology: %w", err)
+	}
+
+	return archivedNetwork, nil
+}
+
+func scanNode(rows *sql.Rows) (Node, error) {
+	var archivedNode Node
+	var previousPublicKey sql.NullString
+	var previousKeyExpiresOn sql.NullTime
+	var endpoint sql.NullString
+	var ipv4Address sql.NullString
+	var ipv6Address sql.NullString
+	var lastSeenOn sql.NullTime
+	var expiresOn sql.NullTime
+	var secretHash sql.NullString
+
+	err := rows.Scan(
+		&archivedNode.ID,
+		&archivedNode.NetworkID,
+		&archivedNode.Name,
+		&archivedNode.PublicKey,
+		&previousPublicKey,
+		&previousKeyExpiresOn,
+		&archivedNode.KeyRotatedOn,
+		&archivedNode.KeyRotationRequested,
+		&endpoint,
+		&ipv4Address,
+		&ipv6Address,
+		&lastSeenOn,
+		&archivedNode.Ephemeral,
+		&expiresOn,
+		&secretHash,
+		&archivedNode.Version,
+		&archivedNode.CreatedOn,
+		&archivedNode.ModifiedOn,
+	)
+	if err != nil {
+		return archivedNode, err
+	}
+
+	archivedNode.PreviousPublicKey = nullStringToPointer(previousPublicKey)
+	archivedNode.PreviousKeyExpiresOn = nullTimeToPointer(previousKeyExpiresOn)
+	archivedNode.Endpoint = nullStringToPointer(endpoint)
+	archivedNode.LastSeenOn = nullTimeToPointer(lastSeenOn)
+	archivedNode.ExpiresOn = nullTimeToPointer(expiresOn)
+	archivedNode.SecretHash = nullStringToPointer(secretHash)
+
+	if archivedNode.IPv4Address, err = nullStringToIP(ipv4Address); err != nil {
+		return archivedNode, err
+	}
+
+	if archivedNode.IPv6Address, err = nullStringToIP(ipv6Address); err != nil {
+		return archivedNode, err
+	}
+
+	return archivedNode, nil
+}
+
+func scanPresharedKey(rows *sql.Rows) (PresharedKey, error) {
+	var presharedKey PresharedKey
+	err := rows.Scan(
+		&presharedKey.FirstNodeID,
+		&presharedKey.SecondNodeID,
+		&presharedKey.Key,
+		&presharedKey.CreatedOn,
+	)
+
+	return presharedKey, err
+}
+
+func scanWebhook(rows *sql.Rows) (Webhook, error) {
+	var archivedWebhook Webhook
+	var eventTypes []string
+	err := rows.Scan(
+		&archivedWebhook.ID,
+		&archivedWebhook.URL,
+		pq.Array(&eventTypes),
+		&archivedWebhook.Secret,
+		&archivedWebhook.CreatedOn,
+	)
+	if err != nil {
+		return archivedWebhook, err
+	}
+
+	archivedWebhook.EventTypes = make([]webhook.EventType, len(eventTypes))
+	for index, eventType := range eventTypes {
+		archivedWebhook.EventTypes[index] = webhook.EventType(eventType)
+	}
+
+	return archivedWebhook, nil
+}
+
+func nullStringToPointer(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.String
+}
+
+func nullTimeToPointer(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
+}
+
+func nullStringToPrefix(value sql.NullString) (*netaddr.IPPrefix, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	prefix, err := netaddr.ParseIPPrefix(value.String)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse IP CIDR '%s': %w", value.String, err)
+	}
+
+	return &prefix, nil
+}
+
+func nullStringToIP(value sql.NullString) (*netaddr.IP, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	address, err := netaddr.ParseIP(value.String)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse IP address '%s': %w", value.String, err)
+	}
+
+	return &address, nil
+}
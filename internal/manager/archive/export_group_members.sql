@@ -0,0 +1,8 @@
+SELECT
+    GroupID,
+    UserID,
+    MemberGroupID,
+    CreatedOn
+FROM GroupMembers
+ORDER BY GroupID, UserID, MemberGroupID
+;
@@ -0,0 +1,9 @@
+SELECT
+    ID,
+    Name,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Groups
+ORDER BY CreatedOn, ID
+;
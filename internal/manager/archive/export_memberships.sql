@@ -0,0 +1,7 @@
+SELECT
+    OrganizationID,
+    UserID,
+    CreatedOn
+FROM OrganizationMembers
+ORDER BY OrganizationID, UserID
+;
@@ -0,0 +1,16 @@
+SELECT
+    ID,
+    OrganizationID,
+    Name,
+    IPv4CIDR,
+    IPv6CIDR,
+    AllowOverlap,
+    Labels,
+    Topology,
+    PresharedKeys,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Networks
+ORDER BY CreatedOn, ID
+;
@@ -0,0 +1,22 @@
+SELECT
+    ID,
+    NetworkID,
+    Name,
+    PublicKey,
+    PreviousPublicKey,
+    PreviousKeyExpiresOn,
+    KeyRotatedOn,
+    KeyRotationRequested,
+    Endpoint,
+    HOST(IPv4Address),
+    HOST(IPv6Address),
+    LastSeenOn,
+    Ephemeral,
+    ExpiresOn,
+    SecretHash,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Nodes
+ORDER BY CreatedOn, ID
+;
@@ -0,0 +1,9 @@
+SELECT
+    ID,
+    Name,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Organizations
+ORDER BY CreatedOn, ID
+;
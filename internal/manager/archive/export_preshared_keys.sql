@@ -0,0 +1,8 @@
+SELECT
+    FirstNodeID,
+    SecondNodeID,
+    Key,
+    CreatedOn
+FROM PresharedKeys
+ORDER BY FirstNodeID, SecondNodeID
+;
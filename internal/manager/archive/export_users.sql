@@ -0,0 +1,15 @@
+SELECT
+    ID,
+    Username,
+    DisplayName,
+    Email,
+    EmailVerified,
+    ExternalID,
+    Metadata,
+    Status,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Users
+ORDER BY CreatedOn, ID
+;
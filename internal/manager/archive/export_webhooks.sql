@@ -0,0 +1,9 @@
+SELECT
+    ID,
+    URL,
+    EventTypes,
+    Secret,
+    CreatedOn
+FROM WebhookSubscriptions
+ORDER BY CreatedOn, ID
+;
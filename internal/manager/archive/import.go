@@ -0,0 +1,382 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/lib/pq"
+	"inet.af/netaddr"
+)
+
+var (
+	//go:embed lock_tables.sql
+	lockTablesSQL string
+
+	//go:embed import_user.sql
+	importUserSQL string
+
+	//go:embed import_organization.sql
+	importOrganizationSQL string
+
+	//go:embed import_membership.sql
+	importMembershipSQL string
+
+	//go:embed import_group.sql
+	importGroupSQL string
+
+	//go:embed import_group_member.sql
+	importGroupMemberSQL string
+
+	//go:embed import_network.sql
+	importNetworkSQL string
+
+	//go:embed import_node.sql
+	importNodeSQL string
+
+	//go:embed import_preshared_key.sql
+	importPresharedKeySQL string
+
+	//go:embed import_webhook.sql
+	importWebhookSQL string
+)
+
+// ImportOpts is the options for importing an archive.
+type ImportOpts struct {
+	// DryRun only reports what would be imported.
+	DryRun bool
+
+	// SkipConflicts imports the records that don't conflict instead of
+	// importing nothing when there are conflicts.
+	SkipConflicts bool
+}
+
+// Import merges an archive into the state of the manager. Records are
+// only ever added, existing records are left alone. Unless conflicts
+// are skipped, nothing is imported when any record conflicts and the
+// report that lists the conflicts is returned with a validation error.
+func Import(ctx context.Context, db *sql.DB, archive *Archive, opts ImportOpts) (Report, error) {
+	if err := Validate(archive); err != nil {
+		return Report{}, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Report{}, errortypes.SystemError{
+			SafeMessage:   "Unable to import due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// The tables are locked so that the state can't change between
+	// checking for conflicts and adding the records.
+	if _, err := tx.ExecContext(ctx, lockTablesSQL); err != nil {
+		return Report{}, errortypes.SystemError{
+			SafeMessage:   "Unable to import due to a system error",
+			UnsafeMessage: "Unable to lock tables",
+			WrappedError:  err,
+		}
+	}
+
+	current, err := exportState(ctx, tx)
+	if err != nil {
+		return Report{}, errortypes.SystemError{
+			SafeMessage:   "Unable to import due to a system error",
+			UnsafeMessage: "Unable to read the current state",
+			WrappedError:  err,
+		}
+	}
+
+	additions, report := Merge(current, archive)
+	report.DryRun = opts.DryRun
+
+	if len(report.Conflicts) > 0 && !opts.SkipConflicts {
+		return report, errortypes.NewValidationError(
+			"Unable to import: %d records conflict with the existing state",
+			len(report.Conflicts),
+		)
+	}
+
+	if err := importAdditions(ctx, tx, additions); err != nil {
+		return Report{}, errortypes.SystemError{
+			SafeMessage:   "Unable to import due to a system error",
+			UnsafeMessage: "Unable to add the imported records",
+			WrappedError:  err,
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Report{}, errortypes.SystemError{
+			SafeMessage:   "Unable to import due to a system error",
+			UnsafeMessage: "Unable to commit import",
+			WrappedError:  err,
+		}
+	}
+
+	return report, nil
+}
+
+// importAdditions inserts records in the order they depend on each
+// other. Dry runs insert them too so that the database gets to check
+// them before everything is rolled back.
+func importAdditions(ctx context.Context, tx *sql.Tx, additions *Archive) error {
+	for _, archivedUser := range additions.Users {
+		metadata := archivedUser.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+
+		rawMetadata, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("Unable to encode metadata of user '%s': %w", archivedUser.ID, err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			importUserSQL,
+			archivedUser.ID,
+			archivedUser.Username,
+			archivedUser.DisplayName,
+			emptyToNullString(archivedUser.Email),
+			archivedUser.EmailVerified,
+			emptyToNullString(archivedUser.ExternalID),
+			string(rawMetadata),
+			archivedUser.Status,
+			archivedUser.Version,
+			archivedUser.CreatedOn.UTC(),
+			archivedUser.ModifiedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("Unable to import user '%s': %w", archivedUser.ID, err)
+		}
+	}
+
+	for _, archivedOrganization := range additions.Organizations {
+		_, err := tx.ExecContext(
+			ctx,
+			importOrganizationSQL,
+			archivedOrganization.ID,
+			archivedOrganization.Name,
+			archivedOrganization.Version,
+			archivedOrganization.CreatedOn.UTC(),
+			archivedOrganization.ModifiedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("Unable to import organization '%s': %w", archivedOrganization.ID, err)
+		}
+	}
+
+	for _, membership := range additions.Memberships {
+		_, err := tx.ExecContext(
+			ctx,
+			importMembershipSQL,
+			membership.OrganizationID,
+			membership.UserID,
+			membership.JoinedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Unable to import membership of '%s' in '%s': %w",
+				membership.UserID,
+				membership.OrganizationID,
+				err,
+			)
+		}
+	}
+
+	for _, archivedGroup := range additions.Groups {
+		_, err := tx.ExecContext(
+			ctx,
+			importGroupSQL,
+			archivedGroup.ID,
+			archivedGroup.Name,
+			archivedGroup.Version,
+			archivedGroup.CreatedOn.UTC(),
+			archivedGroup.ModifiedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("Unable to import group '%s': %w", archivedGroup.ID, err)
+		}
+	}
+
+	for _, member := range additions.GroupMembers {
+		_, err := tx.ExecContext(
+			ctx,
+			importGroupMemberSQL,
+			member.GroupID,
+			emptyToNullString(member.UserID),
+			emptyToNullString(member.MemberGroupID),
+			member.AddedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("Unable to import member '%s' of group '%s': %w", member.memberID(), member.GroupID, err)
+		}
+	}
+
+	for _, archivedNetwork := range additions.Networks {
+		labels := archivedNetwork.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+
+		rawLabels, err := json.Marshal(labels)
+		if err != nil {
+			return fmt.Errorf("Unable to encode labels of network '%s': %w", archivedNetwork.ID, err)
+		}
+
+		rawTopology, err := json.Marshal(archivedNetwork.Topology)
+		if err != nil {
+			return fmt.Errorf("Unable to encode topology of network '%s': %w", archivedNetwork.ID, err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			importNetworkSQL,
+			archivedNetwork.ID,
+			archivedNetwork.OrganizationID,
+			archivedNetwork.Name,
+			prefixToNullString(archivedNetwork.IPv4CIDR),
+			prefixToNullString(archivedNetwork.IPv6CIDR),
+			archivedNetwork.AllowOverlap,
+			string(rawLabels),
+			string(rawTopology),
+			archivedNetwork.PresharedKeys,
+			archivedNetwork.Version,
+			archivedNetwork.CreatedOn.UTC(),
+			archivedNetwork.ModifiedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("Unable to import network '%s': %w", archivedNetwork.ID, err)
+		}
+	}
+
+	changedNetworks := map[string]struct{}{}
+	for _, archivedNode := range additions.Nodes {
+		_, err := tx.ExecContext(
+			ctx,
+			importNodeSQL,
+			archivedNode.ID,
+			archivedNode.NetworkID,
+			archivedNode.Name,
+			archivedNode.PublicKey,
+			pointerToNullString(archivedNode.PreviousPublicKey),
+			pointerToNullTime(archivedNode.PreviousKeyExpiresOn),
+			archivedNode.KeyRotatedOn.UTC(),
+			archivedNode.KeyRotationRequested,
+			pointerToNullString(archivedNode.Endpoint),
+			ipToNullString(archivedNode.IPv4Address),
+			ipToNullString(archivedNode.IPv6Address),
+			pointerToNullTime(archivedNode.LastSeenOn),
+			archivedNode.Ephemeral,
+			pointerToNullTime(archivedNode.ExpiresOn),
+			pointerToNullString(archivedNode.SecretHash),
+			archivedNode.Version,
+			archivedNode.CreatedOn.UTC(),
+			archivedNode.ModifiedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("Unable to import node '%s': %w", archivedNode.ID, err)
+		}
+
+		changedNetworks[archivedNode.NetworkID] = struct{}{}
+	}
+
+	for _, presharedKey := range additions.PresharedKeys {
+		_, err := tx.ExecContext(
+			ctx,
+			importPresharedKeySQL,
+			presharedKey.FirstNodeID,
+			presharedKey.SecondNodeID,
+			presharedKey.Key,
+			presharedKey.CreatedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Unable to import preshared key of '%s' and '%s': %w",
+				presharedKey.FirstNodeID,
+				presharedKey.SecondNodeID,
+				err,
+			)
+		}
+	}
+
+	for _, archivedWebhook := range additions.Webhooks {
+		eventTypes := make([]string, len(archivedWebhook.EventTypes))
+		for index, eventType := range archivedWebhook.EventTypes {
+			eventTypes[index] = string(eventType)
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			importWebhookSQL,
+			archivedWebhook.ID,
+			archivedWebhook.URL,
+			pq.Array(eventTypes),
+			archivedWebhook.Secret,
+			archivedWebhook.CreatedOn.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("Unable to import webhook '%s': %w", archivedWebhook.ID, err)
+		}
+	}
+
+	// Networks that gained nodes have new peers for their existing
+	// nodes.
+	for networkID := range changedNetworks {
+		if err := notify.Notify(ctx, tx, notify.NetworkChannel, networkID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func prefixToNullString(prefix *netaddr.IPPrefix) sql.NullString {
+	if prefix == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: prefix.String(), Valid: true}
+}
+
+func ipToNullString(address *netaddr.IP) sql.NullString {
+	if address == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: address.String(), Valid: true}
+}
+
+func pointerToNullString(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *value, Valid: true}
+}
+
+func pointerToNullTime(value *time.Time) sql.NullTime {
+	if value == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: value.UTC(), Valid: true}
+}
+
+func emptyToNullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
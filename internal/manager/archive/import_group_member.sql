@@ -0,0 +1,13 @@
+INSERT INTO GroupMembers (
+    GroupID,
+    UserID,
+    MemberGroupID,
+    CreatedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+;
@@ -0,0 +1,11 @@
+INSERT INTO OrganizationMembers (
+    OrganizationID,
+    UserID,
+    CreatedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+;
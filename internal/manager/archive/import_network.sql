@@ -0,0 +1,29 @@
+INSERT INTO Networks (
+    ID,
+    OrganizationID,
+    Name,
+    IPv4CIDR,
+    IPv6CIDR,
+    AllowOverlap,
+    Labels,
+    Topology,
+    PresharedKeys,
+    Version,
+    CreatedOn,
+    ModifiedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12
+)
+;
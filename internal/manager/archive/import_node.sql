@@ -0,0 +1,41 @@
+INSERT INTO Nodes (
+    ID,
+    NetworkID,
+    Name,
+    PublicKey,
+    PreviousPublicKey,
+    PreviousKeyExpiresOn,
+    KeyRotatedOn,
+    KeyRotationRequested,
+    Endpoint,
+    IPv4Address,
+    IPv6Address,
+    LastSeenOn,
+    Ephemeral,
+    ExpiresOn,
+    SecretHash,
+    Version,
+    CreatedOn,
+    ModifiedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15,
+    $16,
+    $17,
+    $18
+)
+;
@@ -0,0 +1,15 @@
+INSERT INTO Organizations (
+    ID,
+    Name,
+    Version,
+    CreatedOn,
+    ModifiedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+;
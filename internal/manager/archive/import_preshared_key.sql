@@ -0,0 +1,13 @@
+INSERT INTO PresharedKeys (
+    FirstNodeID,
+    SecondNodeID,
+    Key,
+    CreatedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+;
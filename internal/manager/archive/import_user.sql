@@ -0,0 +1,27 @@
+INSERT INTO Users (
+    ID,
+    Username,
+    DisplayName,
+    Email,
+    EmailVerified,
+    ExternalID,
+    Metadata,
+    Status,
+    Version,
+    CreatedOn,
+    ModifiedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11
+)
+;
@@ -0,0 +1,12 @@
+LOCK TABLE
+    Users,
+    Organizations,
+    OrganizationMembers,
+    Groups,
+    GroupMembers,
+    Networks,
+    Nodes,
+    PresharedKeys,
+    WebhookSubscriptions
+IN SHARE ROW EXCLUSIVE MODE
+;
@@ -0,0 +1,662 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/organization"
+)
+
+// Merge works out which records of an archive can be added to the
+// current state. Records that already exist as they are in the archive
+// are left alone. Records that clash with existing ones, or that depend
+// on records which can't be added, are reported as conflicts. Existing
+// records are never changed or removed.
+func Merge(current *Archive, incoming *Archive) (*Archive, Report) {
+	merger := merger{
+		current: current,
+		additions: &Archive{
+			Format:        Format,
+			Version:       Version,
+			ExportedOn:    incoming.ExportedOn,
+			Users:         []User{},
+			Organizations: []Organization{},
+			Memberships:   []Membership{},
+			Groups:        []Group{},
+			GroupMembers:  []GroupMember{},
+			Networks:      []Network{},
+			Nodes:         []Node{},
+			PresharedKeys: []PresharedKey{},
+			Webhooks:      []Webhook{},
+		},
+		report: Report{
+			Conflicts: []Conflict{},
+		},
+		availableUsers:         map[string]struct{}{},
+		availableOrganizations: map[string]struct{}{},
+		availableGroups:        map[string]struct{}{},
+		availableNetworks:      map[string]struct{}{},
+		availableNodes:         map[string]struct{}{},
+	}
+
+	merger.mergeUsers(incoming.Users)
+	merger.mergeOrganizations(incoming.Organizations)
+	merger.mergeMemberships(incoming.Memberships)
+	merger.mergeGroups(incoming.Groups)
+	merger.mergeGroupMembers(incoming.GroupMembers)
+	merger.mergeNetworks(incoming.Networks)
+	merger.mergeNodes(incoming.Nodes)
+	merger.mergePresharedKeys(incoming.PresharedKeys)
+	merger.mergeWebhooks(incoming.Webhooks)
+
+	merger.report.Imported = Counts{
+		Users:         len(merger.additions.Users),
+		Organizations: len(merger.additions.Organizations),
+		Memberships:   len(merger.additions.Memberships),
+		Groups:        len(merger.additions.Groups),
+		GroupMembers:  len(merger.additions.GroupMembers),
+		Networks:      len(merger.additions.Networks),
+		Nodes:         len(merger.additions.Nodes),
+		PresharedKeys: len(merger.additions.PresharedKeys),
+		Webhooks:      len(merger.additions.Webhooks),
+	}
+
+	return merger.additions, merger.report
+}
+
+type merger struct {
+	current   *Archive
+	additions *Archive
+	report    Report
+
+	// The available maps hold the IDs of the records that will exist
+	// after the import, which others can depend on.
+	availableUsers         map[string]struct{}
+	availableOrganizations map[string]struct{}
+	availableGroups        map[string]struct{}
+	availableNetworks      map[string]struct{}
+	availableNodes         map[string]struct{}
+}
+
+func (merger *merger) conflict(kind Kind, id string, name string, reason string, values ...any) {
+	merger.report.Conflicts = append(merger.report.Conflicts, Conflict{
+		Kind:   kind,
+		ID:     id,
+		Name:   name,
+		Reason: fmt.Sprintf(reason, values...),
+	})
+}
+
+func (merger *merger) mergeUsers(users []User) {
+	byID := map[string]User{}
+	byUsername := map[string]User{}
+	byEmail := map[string]User{}
+	byExternalID := map[string]User{}
+	for _, existingUser := range merger.current.Users {
+		byID[existingUser.ID] = existingUser
+		byUsername[existingUser.Username] = existingUser
+
+		if existingUser.Email != "" {
+			byEmail[strings.ToLower(existingUser.Email)] = existingUser
+		}
+
+		if existingUser.ExternalID != "" {
+			byExternalID[existingUser.ExternalID] = existingUser
+		}
+	}
+
+	// Users that already exist can be made members of organizations
+	// too.
+	for id := range byID {
+		merger.availableUsers[id] = struct{}{}
+	}
+
+	for _, incomingUser := range users {
+		if existingUser, ok := byID[incomingUser.ID]; ok {
+			if sameRecord(existingUser.comparable(), incomingUser.comparable()) {
+				merger.report.Unchanged.Users++
+			} else {
+				merger.conflict(KindUser, incomingUser.ID, incomingUser.Username, "Differs from the existing user")
+			}
+
+			continue
+		}
+
+		if existingUser, ok := byUsername[incomingUser.Username]; ok {
+			merger.conflict(
+				KindUser,
+				incomingUser.ID,
+				incomingUser.Username,
+				"Username is taken by user '%s'",
+				existingUser.ID,
+			)
+
+			continue
+		}
+
+		if existingUser, ok := byEmail[strings.ToLower(incomingUser.Email)]; ok {
+			merger.conflict(
+				KindUser,
+				incomingUser.ID,
+				incomingUser.Username,
+				"Email address is used by user '%s'",
+				existingUser.ID,
+			)
+
+			continue
+		}
+
+		if existingUser, ok := byExternalID[incomingUser.ExternalID]; ok {
+			merger.conflict(
+				KindUser,
+				incomingUser.ID,
+				incomingUser.Username,
+				"External ID is used by user '%s'",
+				existingUser.ID,
+			)
+
+			continue
+		}
+
+		merger.additions.Users = append(merger.additions.Users, incomingUser)
+		merger.availableUsers[incomingUser.ID] = struct{}{}
+	}
+}
+
+func (merger *merger) mergeOrganizations(organizations []Organization) {
+	byID := map[string]Organization{}
+	byName := map[string]Organization{}
+	for _, existingOrganization := range merger.current.Organizations {
+		byID[existingOrganization.ID] = existingOrganization
+		byName[existingOrganization.Name] = existingOrganization
+	}
+
+	// Networks of archives that don't list the default organization
+	// still belong to it.
+	merger.availableOrganizations[organization.DefaultID] = struct{}{}
+
+	for _, incomingOrganization := range organizations {
+		if existingOrganization, ok := byID[incomingOrganization.ID]; ok {
+			if existingOrganization.Name == incomingOrganization.Name {
+				merger.report.Unchanged.Organizations++
+				merger.availableOrganizations[incomingOrganization.ID] = struct{}{}
+			} else {
+				merger.conflict(
+					KindOrganization,
+					incomingOrganization.ID,
+					incomingOrganization.Name,
+					"Differs from the existing organization",
+				)
+			}
+
+			continue
+		}
+
+		if existingOrganization, ok := byName[incomingOrganization.Name]; ok {
+			merger.conflict(
+				KindOrganization,
+				incomingOrganization.ID,
+				incomingOrganization.Name,
+				"Name is taken by organization '%s'",
+				existingOrganization.ID,
+			)
+
+			continue
+		}
+
+		merger.additions.Organizations = append(merger.additions.Organizations, incomingOrganization)
+		merger.availableOrganizations[incomingOrganization.ID] = struct{}{}
+	}
+}
+
+// mergeMemberships adds the memberships that don't exist yet, including
+// to organizations that already exist, so that memberships are merged
+// rather than conflicting.
+func (merger *merger) mergeMemberships(memberships []Membership) {
+	existingPairs := map[string]struct{}{}
+	for _, existingMembership := range merger.current.Memberships {
+		existingPairs[existingMembership.pairID()] = struct{}{}
+	}
+
+	for _, incomingMembership := range memberships {
+		pairID := incomingMembership.pairID()
+
+		if _, ok := existingPairs[pairID]; ok {
+			merger.report.Unchanged.Memberships++
+			continue
+		}
+
+		_, organizationOK := merger.availableOrganizations[incomingMembership.OrganizationID]
+		_, userOK := merger.availableUsers[incomingMembership.UserID]
+		if !organizationOK || !userOK {
+			merger.conflict(KindMembership, pairID, "", "The organization or user isn't being imported")
+			continue
+		}
+
+		merger.additions.Memberships = append(merger.additions.Memberships, incomingMembership)
+	}
+}
+
+func (merger *merger) mergeGroups(groups []Group) {
+	byID := map[string]Group{}
+	byName := map[string]Group{}
+	for _, existingGroup := range merger.current.Groups {
+		byID[existingGroup.ID] = existingGroup
+		byName[existingGroup.Name] = existingGroup
+	}
+
+	for _, incomingGroup := range groups {
+		if existingGroup, ok := byID[incomingGroup.ID]; ok {
+			if existingGroup.Name == incomingGroup.Name {
+				merger.report.Unchanged.Groups++
+				merger.availableGroups[incomingGroup.ID] = struct{}{}
+			} else {
+				merger.conflict(KindGroup, incomingGroup.ID, incomingGroup.Name, "Differs from the existing group")
+			}
+
+			continue
+		}
+
+		if existingGroup, ok := byName[incomingGroup.Name]; ok {
+			merger.conflict(
+				KindGroup,
+				incomingGroup.ID,
+				incomingGroup.Name,
+				"Name is taken by group '%s'",
+				existingGroup.ID,
+			)
+
+			continue
+		}
+
+		merger.additions.Groups = append(merger.additions.Groups, incomingGroup)
+		merger.availableGroups[incomingGroup.ID] = struct{}{}
+	}
+}
+
+// mergeGroupMembers adds the members that groups don't have yet, like
+// memberships are merged. Nesting a group that would end up containing
+// itself, because of how groups are already nested, is a conflict.
+func (merger *merger) mergeGroupMembers(members []GroupMember) {
+	existingPairs := map[string]struct{}{}
+	nestedGroups := map[string][]string{}
+	for _, existingMember := range merger.current.GroupMembers {
+		existingPairs[existingMember.pairID()] = struct{}{}
+
+		if existingMember.MemberGroupID != "" {
+			nestedGroups[existingMember.GroupID] = append(
+				nestedGroups[existingMember.GroupID],
+				existingMember.MemberGroupID,
+			)
+		}
+	}
+
+	for _, incomingMember := range members {
+		pairID := incomingMember.pairID()
+
+		if _, ok := existingPairs[pairID]; ok {
+			merger.report.Unchanged.GroupMembers++
+			continue
+		}
+
+		_, groupOK := merger.availableGroups[incomingMember.GroupID]
+		memberOK := false
+		if incomingMember.UserID != "" {
+			_, memberOK = merger.availableUsers[incomingMember.UserID]
+		} else {
+			_, memberOK = merger.availableGroups[incomingMember.MemberGroupID]
+		}
+
+		if !groupOK || !memberOK {
+			merger.conflict(KindGroupMember, pairID, "", "The group or member isn't being imported")
+			continue
+		}
+
+		if incomingMember.MemberGroupID != "" {
+			if containsGroup(nestedGroups, incomingMember.MemberGroupID, incomingMember.GroupID) {
+				merger.conflict(
+					KindGroupMember,
+					pairID,
+					"",
+					"Group '%s' already contains group '%s'",
+					incomingMember.MemberGroupID,
+					incomingMember.GroupID,
+				)
+
+				continue
+			}
+
+			nestedGroups[incomingMember.GroupID] = append(
+				nestedGroups[incomingMember.GroupID],
+				incomingMember.MemberGroupID,
+			)
+		}
+
+		merger.additions.GroupMembers = append(merger.additions.GroupMembers, incomingMember)
+	}
+}
+
+func (merger *merger) mergeNetworks(networks []Network) {
+	byID := map[string]Network{}
+	byName := map[string]Network{}
+	for _, existingNetwork := range merger.current.Networks {
+		byID[existingNetwork.ID] = existingNetwork
+		byName[existingNetwork.nameKey()] = existingNetwork
+	}
+
+	for _, incomingNetwork := range networks {
+		if _, ok := merger.availableOrganizations[incomingNetwork.OrganizationID]; !ok {
+			merger.conflict(
+				KindNetwork,
+				incomingNetwork.ID,
+				incomingNetwork.Name,
+				"Organization '%s' isn't being imported",
+				incomingNetwork.OrganizationID,
+			)
+
+			continue
+		}
+
+		if existingNetwork, ok := byID[incomingNetwork.ID]; ok {
+			if sameRecord(existingNetwork.comparable(), incomingNetwork.comparable()) {
+				merger.report.Unchanged.Networks++
+				merger.availableNetworks[incomingNetwork.ID] = struct{}{}
+			} else {
+				merger.conflict(
+					KindNetwork,
+					incomingNetwork.ID,
+					incomingNetwork.Name,
+					"Differs from the existing network",
+				)
+			}
+
+			continue
+		}
+
+		if existingNetwork, ok := byName[incomingNetwork.nameKey()]; ok {
+			merger.conflict(
+				KindNetwork,
+				incomingNetwork.ID,
+				incomingNetwork.Name,
+				"Name is taken by network '%s' in the same organization",
+				existingNetwork.ID,
+			)
+
+			continue
+		}
+
+		overlapping := ""
+		for _, existingNetwork := range merger.current.Networks {
+			if rangesOverlap(incomingNetwork, existingNetwork) {
+				overlapping = existingNetwork.Name
+				break
+			}
+		}
+
+		if overlapping != "" {
+			merger.conflict(
+				KindNetwork,
+				incomingNetwork.ID,
+				incomingNetwork.Name,
+				"Ranges overlap network '%s'",
+				overlapping,
+			)
+
+			continue
+		}
+
+		merger.additions.Networks = append(merger.additions.Networks, incomingNetwork)
+		merger.availableNetworks[incomingNetwork.ID] = struct{}{}
+	}
+}
+
+func (merger *merger) mergeNodes(nodes []Node) {
+	byID := map[string]Node{}
+	byName := map[string]Node{}
+	byPublicKey := map[string]Node{}
+	byAddress := map[string]Node{}
+	for _, existingNode := range merger.current.Nodes {
+		byID[existingNode.ID] = existingNode
+		byName[existingNode.NetworkID+"/"+existingNode.Name] = existingNode
+		byPublicKey[existingNode.PublicKey] = existingNode
+		if existingNode.PreviousPublicKey != nil {
+			byPublicKey[*existingNode.PreviousPublicKey] = existingNode
+		}
+
+		for _, address := range existingNode.addressKeys() {
+			byAddress[address] = existingNode
+		}
+	}
+
+	for _, incomingNode := range nodes {
+		if _, ok := merger.availableNetworks[incomingNode.NetworkID]; !ok {
+			merger.conflict(
+				KindNode,
+				incomingNode.ID,
+				incomingNode.Name,
+				"Network '%s' isn't being imported",
+				incomingNode.NetworkID,
+			)
+
+			continue
+		}
+
+		if existingNode, ok := byID[incomingNode.ID]; ok {
+			if sameRecord(existingNode.comparable(), incomingNode.comparable()) {
+				merger.report.Unchanged.Nodes++
+				merger.availableNodes[incomingNode.ID] = struct{}{}
+			} else {
+				merger.conflict(KindNode, incomingNode.ID, incomingNode.Name, "Differs from the existing node")
+			}
+
+			continue
+		}
+
+		if existingNode, ok := byName[incomingNode.NetworkID+"/"+incomingNode.Name]; ok {
+			merger.conflict(
+				KindNode,
+				incomingNode.ID,
+				incomingNode.Name,
+				"Name is taken by node '%s' in the same network",
+				existingNode.ID,
+			)
+
+			continue
+		}
+
+		if existingNode, ok := byPublicKey[incomingNode.PublicKey]; ok {
+			merger.conflict(
+				KindNode,
+				incomingNode.ID,
+				incomingNode.Name,
+				"Public key is used by node '%s'",
+				existingNode.ID,
+			)
+
+			continue
+		}
+
+		addressTaken := false
+		for _, address := range incomingNode.addressKeys() {
+			if existingNode, ok := byAddress[address]; ok {
+				merger.conflict(
+					KindNode,
+					incomingNode.ID,
+					incomingNode.Name,
+					"Address is used by node '%s'",
+					existingNode.ID,
+				)
+				addressTaken = true
+
+				break
+			}
+		}
+
+		if addressTaken {
+			continue
+		}
+
+		merger.additions.Nodes = append(merger.additions.Nodes, incomingNode)
+		merger.availableNodes[incomingNode.ID] = struct{}{}
+	}
+}
+
+func (merger *merger) mergePresharedKeys(presharedKeys []PresharedKey) {
+	byPair := map[string]PresharedKey{}
+	for _, existingKey := range merger.current.PresharedKeys {
+		byPair[existingKey.pairID()] = existingKey
+	}
+
+	for _, incomingKey := range presharedKeys {
+		pairID := incomingKey.pairID()
+
+		if existingKey, ok := byPair[pairID]; ok {
+			if existingKey.Key == incomingKey.Key {
+				merger.report.Unchanged.PresharedKeys++
+			} else {
+				merger.conflict(KindPresharedKey, pairID, "", "Differs from the existing preshared key")
+			}
+
+			continue
+		}
+
+		_, firstOK := merger.availableNodes[incomingKey.FirstNodeID]
+		_, secondOK := merger.availableNodes[incomingKey.SecondNodeID]
+		if !firstOK || !secondOK {
+			merger.conflict(KindPresharedKey, pairID, "", "A node of the pair isn't being imported")
+			continue
+		}
+
+		merger.additions.PresharedKeys = append(merger.additions.PresharedKeys, incomingKey)
+	}
+}
+
+func (merger *merger) mergeWebhooks(webhooks []Webhook) {
+	byID := map[string]Webhook{}
+	for _, existingWebhook := range merger.current.Webhooks {
+		byID[existingWebhook.ID] = existingWebhook
+	}
+
+	for _, incomingWebhook := range webhooks {
+		if existingWebhook, ok := byID[incomingWebhook.ID]; ok {
+			if sameRecord(existingWebhook.comparable(), incomingWebhook.comparable()) {
+				merger.report.Unchanged.Webhooks++
+			} else {
+				merger.conflict(
+					KindWebhook,
+					incomingWebhook.ID,
+					incomingWebhook.URL,
+					"Differs from the existing webhook",
+				)
+			}
+
+			continue
+		}
+
+		merger.additions.Webhooks = append(merger.additions.Webhooks, incomingWebhook)
+	}
+}
+
+// comparable leaves out the fields that change without the user being
+// changed.
+func (archivedUser User) comparable() User {
+	archivedUser.Version = 0
+	archivedUser.CreatedOn = archivedUser.CreatedOn.UTC()
+	archivedUser.ModifiedOn = time.Time{}
+
+	return archivedUser
+}
+
+// comparable leaves out the fields that change without the network
+// being changed.
+func (archivedNetwork Network) comparable() Network {
+	archivedNetwork.Version = 0
+	archivedNetwork.CreatedOn = archivedNetwork.CreatedOn.UTC()
+	archivedNetwork.ModifiedOn = time.Time{}
+
+	return archivedNetwork
+}
+
+// nameKey identifies the network by its name, which is only unique
+// within its organization.
+func (archivedNetwork Network) nameKey() string {
+	return archivedNetwork.OrganizationID + "/" + archivedNetwork.Name
+}
+
+// comparable leaves out the fields that change without the node being
+// changed, like when it was last seen.
+func (archivedNode Node) comparable() Node {
+	archivedNode.Version = 0
+	archivedNode.PreviousKeyExpiresOn = utcPointer(archivedNode.PreviousKeyExpiresOn)
+	archivedNode.KeyRotatedOn = archivedNode.KeyRotatedOn.UTC()
+	archivedNode.LastSeenOn = nil
+	archivedNode.ExpiresOn = utcPointer(archivedNode.ExpiresOn)
+	archivedNode.CreatedOn = archivedNode.CreatedOn.UTC()
+	archivedNode.ModifiedOn = time.Time{}
+
+	return archivedNode
+}
+
+func (archivedNode Node) addressKeys() []string {
+	keys := []string{}
+	if archivedNode.IPv4Address != nil {
+		keys = append(keys, archivedNode.NetworkID+"/"+archivedNode.IPv4Address.String())
+	}
+
+	if archivedNode.IPv6Address != nil {
+		keys = append(keys, archivedNode.NetworkID+"/"+archivedNode.IPv6Address.String())
+	}
+
+	return keys
+}
+
+func (membership Membership) pairID() string {
+	return membership.OrganizationID + "/" + membership.UserID
+}
+
+// memberID identifies the member by its kind and ID since users and
+// groups could share IDs.
+func (member GroupMember) memberID() string {
+	if member.UserID != "" {
+		return string(KindUser) + "/" + member.UserID
+	}
+
+	return string(KindGroup) + "/" + member.MemberGroupID
+}
+
+func (member GroupMember) pairID() string {
+	return member.GroupID + "/" + member.memberID()
+}
+
+func (presharedKey PresharedKey) pairID() string {
+	return presharedKey.FirstNodeID + "/" + presharedKey.SecondNodeID
+}
+
+// comparable normalizes the webhook so that it can be compared.
+func (archivedWebhook Webhook) comparable() Webhook {
+	archivedWebhook.CreatedOn = archivedWebhook.CreatedOn.UTC()
+
+	return archivedWebhook
+}
+
+func utcPointer(value *time.Time) *time.Time {
+	if value == nil {
+		return nil
+	}
+
+	utcValue := value.UTC()
+
+	return &utcValue
+}
+
+// sameRecord compares records by how they are archived so that maps
+// and times compare by value.
+func sameRecord(left any, right any) bool {
+	rawLeft, leftErr := json.Marshal(left)
+	rawRight, rightErr := json.Marshal(right)
+
+	return leftErr == nil && rightErr == nil && bytes.Equal(rawLeft, rawRight)
+}
@@ -0,0 +1,218 @@
+package archive
+
+import (
+	"time"
+
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/durandj/ley/internal/manager/webhook"
+	"inet.af/netaddr"
+)
+
+const (
+	// Format identifies a file as a Ley archive.
+	Format = "ley-archive"
+
+	// Version is the version of the archive format that is written.
+	// Archives written by newer versions can't be read. Version 1
+	// archives predate organizations, their networks belong to the
+	// default organization. Version 2 archives predate groups.
+	Version = 3
+)
+
+// Archive is the state of a manager at the time it was exported. It
+// holds secrets, like preshared keys and webhook secrets, so it has to
+// be stored as carefully as the database itself.
+type Archive struct {
+	Format        string         `json:"format"`
+	Version       int            `json:"version"`
+	ExportedOn    time.Time      `json:"exportedOn"`
+	Users         []User         `json:"users"`
+	Organizations []Organization `json:"organizations"`
+	Memberships   []Membership   `json:"memberships"`
+	Groups        []Group        `json:"groups"`
+	GroupMembers  []GroupMember  `json:"groupMembers"`
+	Networks      []Network      `json:"networks"`
+	Nodes         []Node         `json:"nodes"`
+	PresharedKeys []PresharedKey `json:"presharedKeys"`
+	Webhooks      []Webhook      `json:"webhooks"`
+}
+
+// User is an exported user. The profile fields are left out when
+// empty so that archives from before users had them still compare
+// equal.
+type User struct {
+	ID            string            `json:"id"`
+	Username      string            `json:"username"`
+	DisplayName   string            `json:"displayName,omitempty"`
+	Email         string            `json:"email,omitempty"`
+	EmailVerified bool              `json:"emailVerified,omitempty"`
+	ExternalID    string            `json:"externalId,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Status        user.Status       `json:"status"`
+	Version       int64             `json:"version"`
+	CreatedOn     time.Time         `json:"createdOn"`
+	ModifiedOn    time.Time         `json:"modifiedOn"`
+}
+
+// Organization is an exported organization. The default organization
+// is exported too, it exists in every manager.
+type Organization struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Version    int64     `json:"version"`
+	CreatedOn  time.Time `json:"createdOn"`
+	ModifiedOn time.Time `json:"modifiedOn"`
+}
+
+// Membership is an exported membership of a user in an organization.
+type Membership struct {
+	OrganizationID string    `json:"organizationId"`
+	UserID         string    `json:"userId"`
+	JoinedOn       time.Time `json:"joinedOn"`
+}
+
+// Group is an exported group.
+type Group struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Version    int64     `json:"version"`
+	CreatedOn  time.Time `json:"createdOn"`
+	ModifiedOn time.Time `json:"modifiedOn"`
+}
+
+// GroupMember is an exported member of a group. Only one of the user ID
+// and the member group ID is set.
+type GroupMember struct {
+	GroupID       string    `json:"groupId"`
+	UserID        string    `json:"userId,omitempty"`
+	MemberGroupID string    `json:"memberGroupId,omitempty"`
+	AddedOn       time.Time `json:"addedOn"`
+}
+
+// Network is an exported network.
+type Network struct {
+	ID             string            `json:"id"`
+	OrganizationID string            `json:"organizationId"`
+	Name           string            `json:"name"`
+	IPv4CIDR       *netaddr.IPPrefix `json:"ipv4CIDR,omitempty"`
+	IPv6CIDR       *netaddr.IPPrefix `json:"ipv6CIDR,omitempty"`
+	AllowOverlap   bool              `json:"allowOverlap"`
+	Labels         map[string]string `json:"labels"`
+	Topology       network.Topology  `json:"topology"`
+	PresharedKeys  bool              `json:"presharedKeys"`
+	Version        int64             `json:"version"`
+	CreatedOn      time.Time         `json:"createdOn"`
+	ModifiedOn     time.Time         `json:"modifiedOn"`
+}
+
+// Node is an exported node. Previous keys are kept even once they have
+// expired so that nothing is lost.
+type Node struct {
+	ID                   string      `json:"id"`
+	NetworkID            string      `json:"networkId"`
+	Name                 string      `json:"name"`
+	PublicKey            string      `json:"publicKey"`
+	PreviousPublicKey    *string     `json:"previousPublicKey,omitempty"`
+	PreviousKeyExpiresOn *time.Time  `json:"previousKeyExpiresOn,omitempty"`
+	KeyRotatedOn         time.Time   `json:"keyRotatedOn"`
+	KeyRotationRequested bool        `json:"keyRotationRequested"`
+	Endpoint             *string     `json:"endpoint,omitempty"`
+	IPv4Address          *netaddr.IP `json:"ipv4Address,omitempty"`
+	IPv6Address          *netaddr.IP `json:"ipv6Address,omitempty"`
+	LastSeenOn           *time.Time  `json:"lastSeenOn,omitempty"`
+	Ephemeral            bool        `json:"ephemeral"`
+	ExpiresOn            *time.Time  `json:"expiresOn,omitempty"`
+	SecretHash           *string     `json:"secretHash,omitempty"`
+	Version              int64       `json:"version"`
+	CreatedOn            time.Time   `json:"createdOn"`
+	ModifiedOn           time.Time   `json:"modifiedOn"`
+}
+
+// PresharedKey is the exported preshared key of a pair of nodes. The
+// first node's ID always sorts before the second's.
+type PresharedKey struct {
+	FirstNodeID  string    `json:"firstNodeId"`
+	SecondNodeID string    `json:"secondNodeId"`
+	Key          string    `json:"key"`
+	CreatedOn    time.Time `json:"createdOn"`
+}
+
+// Webhook is an exported webhook subscription. The secret is kept so
+// that receivers can keep checking signatures.
+type Webhook struct {
+	ID         string              `json:"id"`
+	URL        string              `json:"url"`
+	EventTypes []webhook.EventType `json:"eventTypes"`
+	Secret     string              `json:"secret"`
+	CreatedOn  time.Time           `json:"createdOn"`
+}
+
+// Kind names a type of record in an archive.
+type Kind string
+
+const (
+	// KindUser is a user record.
+	KindUser Kind = "user"
+
+	// KindOrganization is an organization record.
+	KindOrganization Kind = "organization"
+
+	// KindMembership is a membership record.
+	KindMembership Kind = "membership"
+
+	// KindGroup is a group record.
+	KindGroup Kind = "group"
+
+	// KindGroupMember is a group member record.
+	KindGroupMember Kind = "group-member"
+
+	// KindNetwork is a network record.
+	KindNetwork Kind = "network"
+
+	// KindNode is a node record.
+	KindNode Kind = "node"
+
+	// KindPresharedKey is a preshared key record.
+	KindPresharedKey Kind = "preshared-key"
+
+	// KindWebhook is a webhook subscription record.
+	KindWebhook Kind = "webhook"
+)
+
+// Counts tallies records by kind.
+type Counts struct {
+	Users         int `json:"users"`
+	Organizations int `json:"organizations"`
+	Memberships   int `json:"memberships"`
+	Groups        int `json:"groups"`
+	GroupMembers  int `json:"groupMembers"`
+	Networks      int `json:"networks"`
+	Nodes         int `json:"nodes"`
+	PresharedKeys int `json:"presharedKeys"`
+	Webhooks      int `json:"webhooks"`
+}
+
+// Conflict is a record of an archive that couldn't be merged into the
+// existing state.
+type Conflict struct {
+	Kind   Kind   `json:"kind"`
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Report tells what an import did, or would have done for a dry run.
+type Report struct {
+	DryRun bool `json:"dryRun"`
+
+	// Imported counts the records that were added.
+	Imported Counts `json:"imported"`
+
+	// Unchanged counts the records that already existed as they are in
+	// the archive.
+	Unchanged Counts `json:"unchanged"`
+
+	// Conflicts lists the records that clash with existing ones.
+	Conflicts []Conflict `json:"conflicts"`
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Controller handles all the HTTP requests for logging in and out.
+type Controller struct {
+	AuthService *Service
+}
+
+// RegisterRoutes registers HTTP request handlers for all auth API's.
+func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Get("/login", controller.Login)
+	router.Get("/callback", controller.Callback)
+	router.Get("/session", controller.GetSession)
+	router.Delete("/session", controller.Logout)
+}
+
+// Login handles requests to log in by sending the user to the single
+// sign-on provider.
+func (controller *Controller) Login(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	authURL, err := controller.AuthService.StartLogin(request.Context())
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	http.Redirect(response, request, authURL, http.StatusFound)
+}
+
+// LoginResponse is the response body for a finished login.
+type LoginResponse struct {
+	// Token can be sent as a bearer token instead of the session
+	// cookie.
+	Token     string          `json:"token"`
+	Username  string          `json:"username"`
+	ExpiresOn renderable.Time `json:"expiresOn"`
+}
+
+// Render customizes the rendering process for a response object.
+func (loginResponse *LoginResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*LoginResponse)(nil)
+
+// Callback handles the single sign-on provider sending the user back
+// after they logged in. A session cookie is set and the session token
+// is returned for clients that can't use cookies.
+func (controller *Controller) Callback(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	query := request.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		message := "The single sign-on provider refused the login: " + providerError
+		if description := query.Get("error_description"); description != "" {
+			message += ", " + description
+		}
+
+		handleError(response, request, errortypes.UnauthorizedError{
+			UserError: errortypes.UserError{
+				SafeMessage: message,
+			},
+		})
+
+		return
+	}
+
+	session, token, err := controller.AuthService.FinishLogin(request.Context(), query.Get("state"), query.Get("code"))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	http.SetCookie(response, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresOn(),
+		HttpOnly: true,
+		Secure:   controller.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	loginResponse := LoginResponse{
+		Token:     token,
+		Username:  session.Username(),
+		ExpiresOn: renderable.Time(session.ExpiresOn()),
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &loginResponse)
+}
+
+// secureCookies tells whether the session cookie should only be sent
+// over HTTPS, which is the case when users are sent back to the
+// manager over HTTPS.
+func (controller *Controller) secureCookies() bool {
+	redirectURL, err := url.Parse(controller.AuthService.config.RedirectURL)
+
+	return err == nil && redirectURL.Scheme == "https"
+}
+
+// GetSessionResponse is the response body for requesting the current
+// session.
+type GetSessionResponse struct {
+	Username  string          `json:"username"`
+	CreatedOn renderable.Time `json:"createdOn"`
+	ExpiresOn renderable.Time `json:"expiresOn"`
+}
+
+// Render customizes the rendering process for a response object.
+func (getSessionResponse *GetSessionResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*GetSessionResponse)(nil)
+
+// GetSession handles requests to find out who is logged in.
+func (controller *Controller) GetSession(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	session, ok := SessionFromContext(request.Context())
+	if !ok {
+		handleError(response, request, notLoggedInError())
+		return
+	}
+
+	getSessionResponse := GetSessionResponse{
+		Username:  session.Username(),
+		CreatedOn: renderable.Time(session.CreatedOn()),
+		ExpiresOn: renderable.Time(session.ExpiresOn()),
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &getSessionResponse)
+}
+
+// Logout handles requests to end the current session.
+func (controller *Controller) Logout(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	if _, ok := SessionFromContext(request.Context()); !ok {
+		handleError(response, request, notLoggedInError())
+		return
+	}
+
+	if err := controller.AuthService.Logout(request.Context(), sessionToken(request)); err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	clearSessionCookie(response, request)
+	response.WriteHeader(http.StatusNoContent)
+}
+
+func notLoggedInError() error {
+	return errortypes.UnauthorizedError{
+		UserError: errortypes.UserError{
+			SafeMessage: "Not logged in",
+		},
+	}
+}
+
+func handleError(
+	response http.ResponseWriter,
+	request *http.Request,
+	err error,
+) {
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var unauthorizedError errortypes.UnauthorizedError
+	var userError errortypes.UserError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &validationError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: validationError.SafeMessage,
+		})
+
+	case errors.As(err, &notFoundError):
+		response.WriteHeader(http.StatusNotFound)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: notFoundError.SafeMessage,
+		})
+
+	case errors.As(err, &unauthorizedError):
+		response.WriteHeader(http.StatusUnauthorized)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: unauthorizedError.SafeMessage,
+		})
+
+	case errors.As(err, &userError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: userError.SafeMessage,
+		})
+
+	case errors.As(err, &systemError):
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: systemError.SafeMessage,
+		})
+
+	case err != nil:
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: "Internal server error, please try again later",
+		})
+	}
+}
@@ -0,0 +1,15 @@
+INSERT INTO LoginStates (
+    State,
+    Nonce,
+    Verifier,
+    CreatedOn,
+    ExpiresOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+;
@@ -0,0 +1,26 @@
+WITH Created AS (
+    INSERT INTO Sessions (
+        ID,
+        TokenHash,
+        UserID,
+        CreatedOn,
+        ExpiresOn
+    )
+    VALUES (
+        $1,
+        $2,
+        $3,
+        $4,
+        $5
+    )
+    RETURNING ID, UserID, CreatedOn, ExpiresOn
+)
+SELECT
+    Created.ID,
+    Users.ID,
+    Users.Username,
+    Created.CreatedOn,
+    Created.ExpiresOn
+FROM Created
+JOIN Users ON Users.ID = Created.UserID
+;
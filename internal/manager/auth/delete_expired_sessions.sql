@@ -0,0 +1,15 @@
+WITH DeletedLoginStates AS (
+    DELETE FROM LoginStates
+    WHERE
+        ExpiresOn <= $1
+    RETURNING State
+),
+DeletedSessions AS (
+    DELETE FROM Sessions
+    WHERE
+        ExpiresOn <= $1
+    RETURNING ID
+)
+SELECT
+    (SELECT COUNT(*) FROM DeletedSessions)
+;
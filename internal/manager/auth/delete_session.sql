@@ -0,0 +1,4 @@
+DELETE FROM Sessions
+WHERE
+    TokenHash = $1
+;
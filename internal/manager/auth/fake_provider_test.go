@@ -0,0 +1,243 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeClientID     = "ley-manager"
+	fakeClientSecret = "client-secret"
+	fakeKeyID        = "fake-key"
+	fakeRedirectURL  = "http://manager.test/auth/callback"
+)
+
+// fakeProvider is an OpenID Connect provider that logs in whoever it
+// is told to and signs ID tokens with its own RSA key.
+type fakeProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	lock sync.Mutex
+
+	// username is who the next login is for.
+	username string
+
+	// overrides replace claims of the ID tokens that are issued.
+	overrides map[string]any
+
+	// signingKey signs ID tokens in place of the published key when it
+	// is set.
+	signingKey *rsa.PrivateKey
+
+	logins map[string]fakeLogin
+}
+
+type fakeLogin struct {
+	username    string
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err, "should be able to generate a signing key")
+
+	provider := &fakeProvider{
+		key:       key,
+		username:  "alice",
+		overrides: map[string]any{},
+		logins:    map[string]fakeLogin{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("/keys", provider.keys)
+	mux.HandleFunc("/authorize", provider.authorize)
+	mux.HandleFunc("/token", provider.token)
+
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+func (provider *fakeProvider) config() configuration.OIDCConfiguration {
+	return configuration.OIDCConfiguration{
+		Issuer:          provider.server.URL,
+		ClientID:        fakeClientID,
+		ClientSecret:    fakeClientSecret,
+		RedirectURL:     fakeRedirectURL,
+		Scopes:          []string{"profile"},
+		UsernameClaim:   "preferred_username",
+		SessionDuration: time.Hour,
+		LoginTimeout:    time.Minute,
+	}
+}
+
+// login follows a login URL the way a browser would and gives the
+// code and state that the provider sends back.
+func (provider *fakeProvider) login(t *testing.T, authURL string) (string, string) {
+	httpClient := &http.Client{
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	response, err := httpClient.Get(authURL)
+	require.Nil(t, err, "should be able to reach the provider")
+	_ = response.Body.Close()
+	require.Equal(t, http.StatusFound, response.StatusCode, "should be sent back to the manager")
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.Nil(t, err, "should be sent back to a valid URL")
+	require.Equal(t, fakeRedirectURL, location.Scheme+"://"+location.Host+location.Path)
+
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func (provider *fakeProvider) discovery(response http.ResponseWriter, request *http.Request) {
+	writeJSON(response, http.StatusOK, map[string]any{
+		"issuer":                 provider.server.URL,
+		"authorization_endpoint": provider.server.URL + "/authorize",
+		"token_endpoint":         provider.server.URL + "/token",
+		"jwks_uri":               provider.server.URL + "/keys",
+	})
+}
+
+func (provider *fakeProvider) keys(response http.ResponseWriter, request *http.Request) {
+	writeJSON(response, http.StatusOK, map[string]any{
+		"keys": []map[string]any{
+			{
+				"kty": "RSA",
+				"kid": fakeKeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(provider.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(provider.key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (provider *fakeProvider) authorize(response http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	if query.Get("client_id") != fakeClientID ||
+		query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" {
+		http.Error(response, "invalid_request", http.StatusBadRequest)
+
+		return
+	}
+
+	provider.lock.Lock()
+	code := fmt.Sprintf("code-%d", len(provider.logins))
+	provider.logins[code] = fakeLogin{
+		username:    provider.username,
+		nonce:       query.Get("nonce"),
+		challenge:   query.Get("code_challenge"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	provider.lock.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	redirectQuery := redirect.Query()
+	redirectQuery.Set("code", code)
+	redirectQuery.Set("state", query.Get("state"))
+	redirect.RawQuery = redirectQuery.Encode()
+
+	http.Redirect(response, request, redirect.String(), http.StatusFound)
+}
+
+func (provider *fakeProvider) token(response http.ResponseWriter, request *http.Request) {
+	clientID, clientSecret, ok := request.BasicAuth()
+	if !ok || clientID != fakeClientID || clientSecret != fakeClientSecret {
+		writeJSON(response, http.StatusUnauthorized, map[string]any{"error": "invalid_client"})
+
+		return
+	}
+
+	if err := request.ParseForm(); err != nil || request.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(response, http.StatusBadRequest, map[string]any{"error": "invalid_request"})
+
+		return
+	}
+
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	code := request.PostForm.Get("code")
+	login, ok := provider.logins[code]
+	delete(provider.logins, code)
+
+	challenge := sha256.Sum256([]byte(request.PostForm.Get("code_verifier")))
+	if !ok ||
+		login.redirectURI != request.PostForm.Get("redirect_uri") ||
+		login.challenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		writeJSON(response, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":                provider.server.URL,
+		"aud":                fakeClientID,
+		"sub":                "subject-" + login.username,
+		"preferred_username": login.username,
+		"nonce":              login.nonce,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+	}
+	for name, value := range provider.overrides {
+		claims[name] = value
+	}
+
+	signingKey := provider.key
+	if provider.signingKey != nil {
+		signingKey = provider.signingKey
+	}
+
+	writeJSON(response, http.StatusOK, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     signToken(signingKey, claims),
+	})
+}
+
+func signToken(key *rsa.PrivateKey, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]any{"alg": "RS256", "typ": "JWT", "kid": fakeKeyID})
+	payload, _ := json.Marshal(claims)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJSON(response http.ResponseWriter, statusCode int, body any) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(statusCode)
+	_ = json.NewEncoder(response).Encode(body)
+}
@@ -0,0 +1,15 @@
+-- Sessions of deactivated users stop working straight away.
+SELECT
+    Sessions.ID,
+    Users.ID,
+    Users.Username,
+    Sessions.CreatedOn,
+    Sessions.ExpiresOn
+FROM Sessions
+JOIN Users ON Users.ID = Sessions.UserID
+WHERE
+    Sessions.TokenHash = $1
+    AND Sessions.ExpiresOn > $2
+    AND Users.Status = 'active'
+LIMIT 1
+;
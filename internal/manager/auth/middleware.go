@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+)
+
+// SessionCookieName is the cookie that browsers keep their session
+// token in.
+const SessionCookieName = "ley_session"
+
+type sessionContextKey struct{}
+
+// SessionFromContext gives the session that a request was made with,
+// if any.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(*Session)
+
+	return session, ok
+}
+
+// ContextWithSession records the session that a request was made with.
+// The user of the session becomes the principal of the request.
+func ContextWithSession(ctx context.Context, session *Session) context.Context {
+	ctx = context.WithValue(ctx, sessionContextKey{}, session)
+
+	return ContextWithPrincipal(ctx, userPrincipalPrefix+session.UserID())
+}
+
+// Middleware finds the session of requests that carry a session token,
+// either in the session cookie or as a bearer token, so handlers can
+// get it with SessionFromContext. The user of the session becomes the
+// principal of the request. Requests with an expired or unknown
+// session are rejected while requests without one, or with another
+// kind of bearer token, are passed on untouched.
+func Middleware(service *Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			token := sessionToken(request)
+			if token == "" {
+				next.ServeHTTP(response, request)
+
+				return
+			}
+
+			session, err := service.Authenticate(request.Context(), token)
+			if err != nil {
+				var unauthorizedError errortypes.UnauthorizedError
+				if errors.As(err, &unauthorizedError) {
+					clearSessionCookie(response, request)
+				}
+
+				handleError(response, request, err)
+
+				return
+			}
+
+			next.ServeHTTP(response, request.WithContext(ContextWithSession(request.Context(), session)))
+		})
+	}
+}
+
+// RequireSession rejects requests that weren't made with a session
+// with a 401 response. It has to run after Middleware.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if _, ok := SessionFromContext(request.Context()); !ok {
+			response.Header().Set("WWW-Authenticate", `Bearer realm="ley"`)
+			handleError(response, request, notLoggedInError())
+
+			return
+		}
+
+		next.ServeHTTP(response, request)
+	})
+}
+
+// sessionToken gives the session token of a request. Bearer tokens
+// take precedence over the cookie.
+func sessionToken(request *http.Request) string {
+	authorization := request.Header.Get("Authorization")
+	if token := strings.TrimPrefix(authorization, "Bearer "); token != authorization {
+		if strings.HasPrefix(token, SessionTokenPrefix) {
+			return token
+		}
+	}
+
+	if cookie, err := request.Cookie(SessionCookieName); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+func clearSessionCookie(response http.ResponseWriter, request *http.Request) {
+	if _, err := request.Cookie(SessionCookieName); err != nil {
+		return
+	}
+
+	http.SetCookie(response, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
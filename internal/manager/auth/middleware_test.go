@@ -0,0 +1,116 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareShouldPassOnRequestsWithoutASession(t *testing.T) {
+	authService := auth.NewService(nil, nil, nil, configuration.OIDCConfiguration{})
+
+	var handled, hasSession bool
+	handler := auth.Middleware(authService)(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		handled = true
+		_, hasSession = auth.SessionFromContext(request.Context())
+
+		response.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/network", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.True(t, handled, "should handle the request without a session")
+	require.False(t, hasSession, "should not make up a session")
+
+	handled = false
+	request = httptest.NewRequest(http.MethodGet, "/network", nil)
+	request.Header.Set("Authorization", "Bearer some-other-token")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.True(t, handled, "should leave other bearer tokens alone")
+	require.False(t, hasSession, "should not make up a session")
+}
+
+func TestLoginShouldExplainThatSingleSignOnIsOff(t *testing.T) {
+	controller := auth.Controller{
+		AuthService: auth.NewService(nil, nil, nil, configuration.OIDCConfiguration{}),
+	}
+
+	recorder := httptest.NewRecorder()
+	controller.Login(recorder, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "Single sign-on isn't configured")
+}
+
+func TestRequireSessionShouldRejectAnonymousRequests(t *testing.T) {
+	authService := auth.NewService(nil, nil, nil, configuration.OIDCConfiguration{})
+
+	var handled bool
+	handler := auth.Middleware(authService)(auth.RequireSession(http.HandlerFunc(
+		func(response http.ResponseWriter, request *http.Request) {
+			handled = true
+			response.WriteHeader(http.StatusNoContent)
+		},
+	)))
+
+	request := httptest.NewRequest(http.MethodGet, "/network", nil)
+	request.Header.Set("Authorization", "Bearer some-other-token")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"), "should say how to authenticate")
+	require.False(t, handled, "should not handle the request")
+}
+
+func TestTokensShouldOnlyMakePrincipalsOfCheckedTokens(t *testing.T) {
+	var principal string
+	handler := auth.Tokens(map[string]auth.TokenCheck{
+		"scim": func(token string) error {
+			if token != "valid" {
+				return errors.New("invalid token")
+			}
+
+			return nil
+		},
+	})(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		principal, _ = auth.PrincipalFromContext(request.Context())
+		response.WriteHeader(http.StatusNoContent)
+	}))
+
+	send := func(token string) {
+		principal = ""
+		request := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusNoContent, recorder.Code, "should pass every request on")
+	}
+
+	send("valid")
+	require.Equal(t, "token:scim", principal)
+
+	send("bogus")
+	require.Empty(t, principal, "should not trust tokens that failed their check")
+}
+
+func TestUserIDFromContextShouldOnlyGiveUsers(t *testing.T) {
+	userID, ok := auth.UserIDFromContext(auth.ContextWithPrincipal(context.Background(), "user:1234"))
+	require.True(t, ok)
+	require.Equal(t, "1234", userID)
+
+	_, ok = auth.UserIDFromContext(auth.ContextWithPrincipal(context.Background(), "token:admin"))
+	require.False(t, ok, "should not take tokens for users")
+
+	_, ok = auth.UserIDFromContext(context.Background())
+	require.False(t, ok, "should not have a user without a principal")
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"time"
+)
+
+// Session is a logged in user. Sessions are identified by an opaque
+// token that is sent as a cookie or a bearer token.
+type Session struct {
+	id        string
+	userID    string
+	username  string
+	createdOn time.Time
+	expiresOn time.Time
+}
+
+// ID is the database ID of the session.
+func (session *Session) ID() string {
+	return session.id
+}
+
+// UserID is the database ID of the user that logged in.
+func (session *Session) UserID() string {
+	return session.userID
+}
+
+// Username is the username of the user that logged in.
+func (session *Session) Username() string {
+	return session.username
+}
+
+// CreatedOn is the date and time that the user logged in on.
+func (session *Session) CreatedOn() time.Time {
+	return session.createdOn
+}
+
+// ExpiresOn is the date and time after which the session can no
+// longer be used.
+func (session *Session) ExpiresOn() time.Time {
+	return session.expiresOn
+}
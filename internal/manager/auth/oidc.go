@@ -0,0 +1,489 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+)
+
+const (
+	// clockSkew is how far the clocks of the manager and the provider
+	// may drift apart before tokens are rejected.
+	clockSkew = time.Minute
+
+	maxResponseSize = 1 << 20
+)
+
+// Claims are the claims of a verified ID token.
+type Claims map[string]any
+
+// String gives the value of a claim when it is a string.
+func (claims Claims) String(name string) (string, bool) {
+	value, ok := claims[name].(string)
+
+	return value, ok
+}
+
+// Bool gives the value of a claim when it is a boolean. Some providers
+// send booleans as strings, which are accepted too.
+func (claims Claims) Bool(name string) (bool, bool) {
+	switch value := claims[name].(type) {
+	case bool:
+		return value, true
+
+	case string:
+		parsed, err := strconv.ParseBool(value)
+
+		return parsed, err == nil
+
+	default:
+		return false, false
+	}
+}
+
+// Provider talks to an OpenID Connect provider on behalf of the
+// manager. The provider's endpoints and keys are only fetched once
+// they are needed so that the manager can start while the provider is
+// down.
+type Provider struct {
+	config     configuration.OIDCConfiguration
+	httpClient *http.Client
+
+	lock     sync.Mutex
+	metadata *providerMetadata
+	keys     map[string]crypto.PublicKey
+}
+
+// NewProvider creates a client for the configured provider.
+func NewProvider(config configuration.OIDCConfiguration, httpClient *http.Client) *Provider {
+	return &Provider{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// AuthCodeURL builds the URL that users are sent to in order to log
+// in. The verifier is turned into a PKCE challenge.
+func (provider *Provider) AuthCodeURL(
+	ctx context.Context,
+	state string,
+	nonce string,
+	verifier string,
+) (string, error) {
+	metadata, err := provider.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("Invalid authorization endpoint '%s': %w", metadata.AuthorizationEndpoint, err)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.config.ClientID)
+	query.Set("redirect_uri", provider.config.RedirectURL)
+	query.Set("scope", strings.Join(append([]string{"openid"}, provider.config.Scopes...), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange trades an authorization code for an ID token and gives its
+// claims once the token has been verified. The nonce must match the
+// one that the login was started with.
+func (provider *Provider) Exchange(
+	ctx context.Context,
+	code string,
+	verifier string,
+	nonce string,
+) (Claims, error) {
+	metadata, err := provider.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", provider.config.ClientID)
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		metadata.TokenEndpoint,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create token request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if provider.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(provider.config.ClientID), url.QueryEscape(provider.config.ClientSecret))
+	}
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := provider.doJSON(request, &tokenResponse); err != nil {
+		if tokenResponse.ErrorDescription != "" {
+			return nil, fmt.Errorf("Provider rejected the code: %s", tokenResponse.ErrorDescription)
+		}
+
+		if tokenResponse.Error != "" {
+			return nil, fmt.Errorf("Provider rejected the code: %s", tokenResponse.Error)
+		}
+
+		return nil, err
+	}
+
+	if tokenResponse.IDToken == "" {
+		return nil, fmt.Errorf("Provider didn't return an ID token")
+	}
+
+	claims, err := provider.verify(ctx, tokenResponse.IDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenNonce, _ := claims.String("nonce"); tokenNonce != nonce {
+		return nil, fmt.Errorf("ID token is for a different login")
+	}
+
+	return claims, nil
+}
+
+// verify checks the signature, issuer, audience and lifetime of an ID
+// token and gives its claims.
+func (provider *Provider) verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("ID token is malformed")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("ID token header is malformed: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("ID token signature is malformed: %w", err)
+	}
+
+	key, err := provider.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Algorithm, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("ID token claims are malformed: %w", err)
+	}
+
+	metadata, err := provider.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if issuer, _ := claims.String("iss"); issuer != metadata.Issuer {
+		return nil, fmt.Errorf("ID token was issued by '%s' instead of '%s'", issuer, metadata.Issuer)
+	}
+
+	if !claims.hasAudience(provider.config.ClientID) {
+		return nil, fmt.Errorf("ID token isn't meant for this client")
+	}
+
+	now := time.Now()
+	expiresOn, ok := claims.time("exp")
+	if !ok || now.After(expiresOn.Add(clockSkew)) {
+		return nil, fmt.Errorf("ID token has expired")
+	}
+
+	if issuedOn, ok := claims.time("iat"); ok && issuedOn.After(now.Add(clockSkew)) {
+		return nil, fmt.Errorf("ID token was issued in the future")
+	}
+
+	return claims, nil
+}
+
+func (claims Claims) hasAudience(clientID string) bool {
+	switch audience := claims["aud"].(type) {
+	case string:
+		return audience == clientID
+
+	case []any:
+		for _, value := range audience {
+			if value == clientID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (claims Claims) time(name string) (time.Time, bool) {
+	seconds, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(seconds), 0), true
+}
+
+// discover fetches the provider's metadata the first time it is
+// needed.
+func (provider *Provider) discover(ctx context.Context) (*providerMetadata, error) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	if provider.metadata != nil {
+		return provider.metadata, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(provider.config.Issuer, "/") + "/.well-known/openid-configuration"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create discovery request: %w", err)
+	}
+
+	var metadata providerMetadata
+	if err := provider.doJSON(request, &metadata); err != nil {
+		return nil, fmt.Errorf("Unable to discover provider: %w", err)
+	}
+
+	if metadata.Issuer != provider.config.Issuer {
+		return nil, fmt.Errorf("Provider calls itself '%s' instead of '%s'", metadata.Issuer, provider.config.Issuer)
+	}
+
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("Provider is missing endpoints needed for logging in")
+	}
+
+	provider.metadata = &metadata
+
+	return provider.metadata, nil
+}
+
+// key finds the public key that an ID token was signed with. The keys
+// are fetched again when the token names one that isn't known yet, so
+// that the provider can rotate its keys.
+func (provider *Provider) key(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	metadata, err := provider.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	if key, ok := provider.keys[keyID]; ok {
+		return key, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create key request: %w", err)
+	}
+
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := provider.doJSON(request, &keySet); err != nil {
+		return nil, fmt.Errorf("Unable to fetch provider keys: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, webKey := range keySet.Keys {
+		if webKey.Use != "" && webKey.Use != "sig" {
+			continue
+		}
+
+		key, err := webKey.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[webKey.KeyID] = key
+	}
+
+	provider.keys = keys
+
+	key, ok := keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("ID token was signed with unknown key '%s'", keyID)
+	}
+
+	return key, nil
+}
+
+func (provider *Provider) doJSON(request *http.Request, target any) error {
+	response, err := provider.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("Unable to read response: %w", err)
+	}
+
+	// Errors from the token endpoint are JSON as well so they are
+	// decoded before the status is checked.
+	decodeErr := json.Unmarshal(body, target)
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Provider responded with %d", response.StatusCode)
+	}
+
+	if decodeErr != nil {
+		return fmt.Errorf("Unable to decode response: %w", decodeErr)
+	}
+
+	return nil
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (webKey jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch webKey.KeyType {
+	case "RSA":
+		modulus, err := decodeInt(webKey.N)
+		if err != nil {
+			return nil, err
+		}
+
+		exponent, err := decodeInt(webKey.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+
+	case "EC":
+		if webKey.Curve != "P-256" {
+			return nil, fmt.Errorf("Unsupported curve '%s'", webKey.Curve)
+		}
+
+		x, err := decodeInt(webKey.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeInt(webKey.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported key type '%s'", webKey.KeyType)
+}
+
+func verifySignature(algorithm string, key crypto.PublicKey, digest []byte, signature []byte) error {
+	switch algorithm {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("ID token algorithm doesn't match its key")
+		}
+
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest, signature); err != nil {
+			return fmt.Errorf("ID token signature is invalid: %w", err)
+		}
+
+		return nil
+
+	case "ES256":
+		ecdsaKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("ID token algorithm doesn't match its key")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecdsaKey, digest, r, s) {
+			return fmt.Errorf("ID token signature is invalid")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("ID token uses unsupported algorithm '%s'", algorithm)
+}
+
+func decodeSegment(segment string, target any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, target)
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid key parameter: %w", err)
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
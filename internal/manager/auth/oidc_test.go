@@ -0,0 +1,125 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderShouldExchangeACodeForVerifiedClaims(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	provider := auth.NewProvider(fake.config(), &http.Client{})
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	require.Nil(t, err, "should be able to build a login URL")
+
+	parsedURL, err := url.Parse(authURL)
+	require.Nil(t, err, "should build a valid URL")
+	require.Equal(t, "openid profile", parsedURL.Query().Get("scope"), "should always ask for openid")
+
+	code, state := fake.login(t, authURL)
+	require.Equal(t, "state", state, "should get the state back")
+
+	claims, err := provider.Exchange(ctx, code, "verifier", "nonce")
+	require.Nil(t, err, "should be able to exchange the code")
+
+	username, ok := claims.String("preferred_username")
+	require.True(t, ok, "should have the username claim")
+	require.Equal(t, "alice", username)
+}
+
+func TestClaimsShouldReadBooleansSentAsStrings(t *testing.T) {
+	claims := auth.Claims{
+		"bool":      true,
+		"string":    "true",
+		"malformed": "yes please",
+		"number":    1.0,
+	}
+
+	for name, expected := range map[string]bool{"bool": true, "string": true} {
+		value, ok := claims.Bool(name)
+		require.True(t, ok, "should read the %s claim", name)
+		require.Equal(t, expected, value)
+	}
+
+	for _, name := range []string{"malformed", "number", "missing"} {
+		_, ok := claims.Bool(name)
+		require.False(t, ok, "should not read the %s claim as a boolean", name)
+	}
+}
+
+func TestProviderShouldRequireTheLoginsVerifierAndNonce(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	provider := auth.NewProvider(fake.config(), &http.Client{})
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	require.Nil(t, err, "should be able to build a login URL")
+
+	code, _ := fake.login(t, authURL)
+	_, err = provider.Exchange(ctx, code, "other-verifier", "nonce")
+	require.Error(t, err, "should not exchange a code without its verifier")
+
+	code, _ = fake.login(t, authURL)
+	_, err = provider.Exchange(ctx, code, "verifier", "other-nonce")
+	require.Error(t, err, "should not accept an ID token of another login")
+}
+
+func TestProviderShouldRejectIDTokensItCantTrust(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err, "should be able to generate a key")
+
+	testCases := map[string]func(fake *fakeProvider){
+		"another audience": func(fake *fakeProvider) {
+			fake.overrides["aud"] = "another-client"
+		},
+		"another issuer": func(fake *fakeProvider) {
+			fake.overrides["iss"] = "https://attacker.test"
+		},
+		"expired": func(fake *fakeProvider) {
+			fake.overrides["exp"] = time.Now().Add(-time.Hour).Unix()
+		},
+		"signed with another key": func(fake *fakeProvider) {
+			fake.signingKey = otherKey
+		},
+	}
+
+	for name, setup := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := newFakeProvider(t)
+			setup(fake)
+			provider := auth.NewProvider(fake.config(), &http.Client{})
+
+			authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+			require.Nil(t, err, "should be able to build a login URL")
+
+			code, _ := fake.login(t, authURL)
+			_, err = provider.Exchange(ctx, code, "verifier", "nonce")
+			require.Error(t, err, "should reject the ID token")
+		})
+	}
+}
+
+func TestProviderShouldRejectTheWrongClientSecret(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	config := fake.config()
+	config.ClientSecret = "wrong"
+	provider := auth.NewProvider(config, &http.Client{})
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	require.Nil(t, err, "should be able to build a login URL")
+
+	code, _ := fake.login(t, authURL)
+	_, err = provider.Exchange(ctx, code, "verifier", "nonce")
+	require.Error(t, err, "should not exchange a code for the wrong client")
+}
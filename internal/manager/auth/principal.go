@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// userPrincipalPrefix starts the principal of requests made by a user.
+const userPrincipalPrefix = "user:"
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal records who a request was checked to come from,
+// like "user:<id>" for the user of a session.
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext gives who a request was checked to come from,
+// if anyone. Credentials that weren't checked never make a principal.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(string)
+
+	return principal, ok && principal != ""
+}
+
+// UserIDFromContext gives the ID of the user that a request was
+// checked to come from. Requests made with a token, or without any
+// credentials, don't have one.
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	principal, _ := PrincipalFromContext(ctx)
+	userID := strings.TrimPrefix(principal, userPrincipalPrefix)
+
+	return userID, userID != principal && userID != ""
+}
@@ -0,0 +1,369 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/google/uuid"
+)
+
+const (
+	// SessionTokenPrefix starts every session token so that they can
+	// be told apart from other bearer tokens.
+	SessionTokenPrefix = "ley_session_"
+
+	randomBytes = 32
+)
+
+var (
+	//go:embed create_login_state.sql
+	createLoginStateSQL string
+
+	//go:embed take_login_state.sql
+	takeLoginStateSQL string
+
+	//go:embed create_session.sql
+	createSessionSQL string
+
+	//go:embed get_session.sql
+	getSessionSQL string
+
+	//go:embed delete_session.sql
+	deleteSessionSQL string
+
+	//go:embed delete_expired_sessions.sql
+	deleteExpiredSessionsSQL string
+)
+
+// Service logs users in through an OpenID Connect provider and keeps
+// track of their sessions.
+type Service struct {
+	db          *sql.DB
+	provider    *Provider
+	userService *user.Service
+	config      configuration.OIDCConfiguration
+}
+
+// NewService creates a new auth service. The provider is only needed
+// when single sign-on is enabled.
+func NewService(
+	db *sql.DB,
+	provider *Provider,
+	userService *user.Service,
+	config configuration.OIDCConfiguration,
+) *Service {
+	return &Service{
+		db:          db,
+		provider:    provider,
+		userService: userService,
+		config:      config,
+	}
+}
+
+// StartLogin begins a login with the provider and gives the URL that
+// the user has to be sent to.
+func (service *Service) StartLogin(ctx context.Context) (string, error) {
+	if service.provider == nil {
+		return "", errortypes.NotFoundError{
+			UserError: errortypes.UserError{
+				SafeMessage: "Single sign-on isn't configured",
+			},
+		}
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", loginSystemError("Unable to generate login state", err)
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return "", loginSystemError("Unable to generate login nonce", err)
+	}
+
+	verifier, err := randomString()
+	if err != nil {
+		return "", loginSystemError("Unable to generate login verifier", err)
+	}
+
+	authURL, err := service.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", loginSystemError("Unable to reach the single sign-on provider", err)
+	}
+
+	now := time.Now().UTC()
+	_, err = service.db.ExecContext(
+		ctx,
+		createLoginStateSQL,
+		state,
+		nonce,
+		verifier,
+		now,
+		now.Add(service.config.LoginTimeout),
+	)
+	if err != nil {
+		return "", loginSystemError("Unable to save login state", err)
+	}
+
+	return authURL, nil
+}
+
+// FinishLogin completes a login that the provider sent back with an
+// authorization code. Users that don't exist yet are created from the
+// configured username claim. The new session is returned along with
+// its token, which is the only time that the token is available.
+func (service *Service) FinishLogin(ctx context.Context, state string, code string) (*Session, string, error) {
+	if service.provider == nil {
+		return nil, "", errortypes.NotFoundError{
+			UserError: errortypes.UserError{
+				SafeMessage: "Single sign-on isn't configured",
+			},
+		}
+	}
+
+	var nonce, verifier string
+	err := service.db.QueryRowContext(ctx, takeLoginStateSQL, state, time.Now().UTC()).Scan(&nonce, &verifier)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", errortypes.UnauthorizedError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "The login has expired or was already used, please log in again",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, "", loginSystemError("Unable to read login state", err)
+	}
+
+	claims, err := service.provider.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		return nil, "", errortypes.UnauthorizedError{
+			UserError: errortypes.UserError{
+				SafeMessage:  "The single sign-on provider didn't confirm the login",
+				WrappedError: err,
+			},
+		}
+	}
+
+	username, ok := claims.String(service.config.UsernameClaim)
+	if !ok || username == "" {
+		return nil, "", errortypes.UnauthorizedError{
+			UserError: errortypes.UserError{
+				SafeMessage: "The single sign-on provider didn't give a username",
+			},
+		}
+	}
+
+	loggedInUser, err := service.provisionUser(ctx, username, profileFromClaims(claims))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return service.createSession(ctx, loggedInUser)
+}
+
+// profile is what the single sign-on provider tells about a user on
+// top of their username.
+type profile struct {
+	email         string
+	emailVerified bool
+}
+
+// profileFromClaims reads the standard email claims of an ID token.
+// The address only counts as verified when the provider says so.
+func profileFromClaims(claims Claims) profile {
+	email, _ := claims.String("email")
+	emailVerified, _ := claims.Bool("email_verified")
+
+	return profile{
+		email:         email,
+		emailVerified: email != "" && emailVerified,
+	}
+}
+
+// provisionUser finds the user with a username or creates them if
+// they don't exist yet. Their email address is kept in line with what
+// the provider says. Deactivated users can't log in.
+func (service *Service) provisionUser(ctx context.Context, username string, claimed profile) (*user.User, error) {
+	existingUser, err := service.userService.GetUserByUsername(ctx, username)
+
+	var notFoundError errortypes.NotFoundError
+	if errors.As(err, &notFoundError) {
+		existingUser, err = service.userService.CreateUser(ctx, user.CreateUserOpts{
+			Username:      username,
+			Email:         claimed.email,
+			EmailVerified: claimed.emailVerified,
+		})
+
+		// Another login of the same user may have created them first.
+		var validationError errortypes.ValidationError
+		if errors.As(err, &validationError) && validationError.SafeMessage == "Username is already taken" {
+			existingUser, err = service.userService.GetUserByUsername(ctx, username)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if existingUser.Status() != user.StatusActive {
+		return nil, errortypes.UnauthorizedError{
+			UserError: errortypes.UserError{
+				SafeMessage: "The user has been deactivated",
+			},
+		}
+	}
+
+	// Providers that don't share email addresses leave the user's
+	// address alone.
+	emailChanged := !strings.EqualFold(claimed.email, existingUser.Email())
+	if claimed.email == "" || (!emailChanged && claimed.emailVerified == existingUser.EmailVerified()) {
+		return existingUser, nil
+	}
+
+	return service.userService.UpdateUser(ctx, existingUser.Username(), user.UpdateUserOpts{
+		Email:         &claimed.email,
+		EmailVerified: &claimed.emailVerified,
+	})
+}
+
+func (service *Service) createSession(ctx context.Context, loggedInUser *user.User) (*Session, string, error) {
+	secret, err := randomString()
+	if err != nil {
+		return nil, "", loginSystemError("Unable to generate session token", err)
+	}
+
+	token := SessionTokenPrefix + secret
+	now := time.Now().UTC()
+
+	session, err := scanSession(service.db.QueryRowContext(
+		ctx,
+		createSessionSQL,
+		uuid.NewString(),
+		hashToken(token),
+		loggedInUser.ID(),
+		now,
+		now.Add(service.config.SessionDuration),
+	))
+	if err != nil {
+		return nil, "", loginSystemError("Unable to save session", err)
+	}
+
+	return session, token, nil
+}
+
+// Authenticate finds the session that a token belongs to. Expired
+// sessions and sessions of deactivated users are rejected.
+func (service *Service) Authenticate(ctx context.Context, token string) (*Session, error) {
+	if !strings.HasPrefix(token, SessionTokenPrefix) {
+		return nil, errortypes.UnauthorizedError{
+			UserError: errortypes.UserError{
+				SafeMessage: "The session token is malformed",
+			},
+		}
+	}
+
+	session, err := scanSession(service.db.QueryRowContext(ctx, getSessionSQL, hashToken(token), time.Now().UTC()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.UnauthorizedError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "The session has expired, please log in again",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to check session due to a system error",
+			UnsafeMessage: "Unable to check session due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return session, nil
+}
+
+// Logout ends the session that a token belongs to. Ending a session
+// that already ended changes nothing.
+func (service *Service) Logout(ctx context.Context, token string) error {
+	if _, err := service.db.ExecContext(ctx, deleteSessionSQL, hashToken(token)); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to log out due to a system error",
+			UnsafeMessage: "Unable to log out due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpired removes sessions and unfinished logins that expired
+// before a cutoff and gives the number of sessions that were removed.
+func (service *Service) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	var deleted int64
+	if err := service.db.QueryRowContext(ctx, deleteExpiredSessionsSQL, cutoff).Scan(&deleted); err != nil {
+		return 0, errortypes.SystemError{
+			SafeMessage:   "Unable to delete expired sessions due to a system error",
+			UnsafeMessage: "Unable to delete expired sessions due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return deleted, nil
+}
+
+func loginSystemError(message string, err error) error {
+	return errortypes.SystemError{
+		SafeMessage:   "Unable to log in due to a system error",
+		UnsafeMessage: message,
+		WrappedError:  err,
+	}
+}
+
+func randomString() (string, error) {
+	raw := make([]byte, randomBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row rowScanner) (*Session, error) {
+	var session Session
+	err := row.Scan(
+		&session.id,
+		&session.userID,
+		&session.username,
+		&session.createdOn,
+		&session.expiresOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,193 @@
+package auth_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/user"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginShouldProvisionTheUserAndStartASession(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	authService, userService := newTestServices(t, fake)
+
+	fake.username = fmt.Sprintf("sso-test-%d", rng.RNG.Int63())
+
+	authURL, err := authService.StartLogin(ctx)
+	require.Nil(t, err, "should be able to start a login")
+
+	code, state := fake.login(t, authURL)
+	session, token, err := authService.FinishLogin(ctx, state, code)
+	require.Nil(t, err, "should be able to finish the login")
+	require.Equal(t, fake.username, session.Username())
+	require.True(t, strings.HasPrefix(token, auth.SessionTokenPrefix), "should be a session token")
+
+	provisionedUser, err := userService.GetUserByUsername(ctx, fake.username)
+	require.Nil(t, err, "should have created the user")
+	require.Equal(t, provisionedUser.ID(), session.UserID())
+
+	handler := auth.Middleware(authService)(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		requestSession, ok := auth.SessionFromContext(request.Context())
+		require.True(t, ok, "should find the session")
+		require.Equal(t, session.ID(), requestSession.ID())
+
+		response.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/auth/session", nil)
+	request.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: token})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code, "should accept the session cookie")
+
+	require.Nil(t, authService.Logout(ctx, token), "should be able to log out")
+
+	request = httptest.NewRequest(http.MethodGet, "/auth/session", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code, "should not accept an ended session")
+
+	authURL, err = authService.StartLogin(ctx)
+	require.Nil(t, err, "should be able to start another login")
+
+	code, state = fake.login(t, authURL)
+	again, _, err := authService.FinishLogin(ctx, state, code)
+	require.Nil(t, err, "should be able to log in again")
+	require.Equal(t, session.UserID(), again.UserID(), "should reuse the provisioned user")
+}
+
+func TestFinishLoginShouldOnlyAcceptAStateOnce(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	authService, _ := newTestServices(t, fake)
+
+	fake.username = fmt.Sprintf("sso-test-%d", rng.RNG.Int63())
+
+	authURL, err := authService.StartLogin(ctx)
+	require.Nil(t, err, "should be able to start a login")
+
+	code, state := fake.login(t, authURL)
+	_, _, err = authService.FinishLogin(ctx, state, code)
+	require.Nil(t, err, "should be able to finish the login")
+
+	var unauthorizedError errortypes.UnauthorizedError
+	_, _, err = authService.FinishLogin(ctx, state, code)
+	require.True(t, errors.As(err, &unauthorizedError), "should not finish the same login twice")
+
+	_, _, err = authService.FinishLogin(ctx, "made-up-state", code)
+	require.True(t, errors.As(err, &unauthorizedError), "should not finish a login that was never started")
+}
+
+func TestFinishLoginShouldRejectInvalidAndDeactivatedUsers(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	authService, userService := newTestServices(t, fake)
+
+	fake.username = "!not a username"
+
+	authURL, err := authService.StartLogin(ctx)
+	require.Nil(t, err, "should be able to start a login")
+
+	code, state := fake.login(t, authURL)
+	_, _, err = authService.FinishLogin(ctx, state, code)
+
+	var validationError errortypes.ValidationError
+	require.True(t, errors.As(err, &validationError), "should not provision users with invalid usernames")
+
+	fake.username = fmt.Sprintf("sso-test-%d", rng.RNG.Int63())
+	_, err = userService.CreateUser(ctx, user.CreateUserOpts{Username: fake.username})
+	require.Nil(t, err, "should be able to create a user")
+
+	deactivated := user.StatusDeactivated
+	_, err = userService.UpdateUser(ctx, fake.username, user.UpdateUserOpts{Status: &deactivated})
+	require.Nil(t, err, "should be able to deactivate the user")
+
+	authURL, err = authService.StartLogin(ctx)
+	require.Nil(t, err, "should be able to start a login")
+
+	code, state = fake.login(t, authURL)
+	_, _, err = authService.FinishLogin(ctx, state, code)
+
+	var unauthorizedError errortypes.UnauthorizedError
+	require.True(t, errors.As(err, &unauthorizedError), "should not log in deactivated users")
+}
+
+func TestFinishLoginShouldTakeTheEmailAddressFromTheProvider(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	authService, userService := newTestServices(t, fake)
+
+	fake.username = fmt.Sprintf("sso-test-%d", rng.RNG.Int63())
+	fake.overrides["email"] = fake.username + "@example.com"
+	fake.overrides["email_verified"] = true
+
+	authURL, err := authService.StartLogin(ctx)
+	require.Nil(t, err, "should be able to start a login")
+
+	code, state := fake.login(t, authURL)
+	_, _, err = authService.FinishLogin(ctx, state, code)
+	require.Nil(t, err, "should be able to finish the login")
+
+	provisionedUser, err := userService.GetUserByUsername(ctx, fake.username)
+	require.Nil(t, err, "should have created the user")
+	require.Equal(t, fake.username+"@example.com", provisionedUser.Email())
+	require.True(t, provisionedUser.EmailVerified(), "should trust the provider's verification")
+
+	fake.overrides["email"] = fake.username + "@example.org"
+	fake.overrides["email_verified"] = "false"
+
+	authURL, err = authService.StartLogin(ctx)
+	require.Nil(t, err, "should be able to start a login")
+
+	code, state = fake.login(t, authURL)
+	_, _, err = authService.FinishLogin(ctx, state, code)
+	require.Nil(t, err, "should be able to log in again")
+
+	updatedUser, err := userService.GetUserByUsername(ctx, fake.username)
+	require.Nil(t, err, "should be able to get the user")
+	require.Equal(t, fake.username+"@example.org", updatedUser.Email(), "should follow the provider's address")
+	require.False(t, updatedUser.EmailVerified(), "should not verify an address the provider didn't")
+}
+
+func newTestServices(t *testing.T, fake *fakeProvider) (*auth.Service, *user.Service) {
+	dbConfig := configuration.DBConfiguration{
+		Type: configuration.DBTypePostgres,
+		Postgres: configuration.PostgresConfiguration{
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Role:     "ley",
+			Password: "ley",
+			DBName:   "ley",
+			SSLMode:  "disable",
+		},
+	}
+
+	connectionString, err := dbConfig.ConnectionString()
+	require.Nil(t, err, "should be able to create a connection string")
+
+	db, err := sql.Open(string(dbConfig.Type), connectionString)
+	require.Nil(t, err, "should be able to open the database")
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	userService := user.NewService(db)
+	provider := auth.NewProvider(fake.config(), &http.Client{})
+
+	return auth.NewService(db, provider, userService, fake.config()), userService
+}
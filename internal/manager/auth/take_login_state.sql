@@ -0,0 +1,7 @@
+-- Each login can only be finished once.
+DELETE FROM LoginStates
+WHERE
+    State = $1
+    AND ExpiresOn > $2
+RETURNING Nonce, Verifier
+;
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+)
+
+// TokenCheck tells whether a bearer token is a valid one of its kind,
+// returning an error when it isn't.
+type TokenCheck func(token string) error
+
+// StaticToken checks that a bearer token is the expected one. No token
+// passes it when none is expected.
+func StaticToken(expected string) TokenCheck {
+	return func(token string) error {
+		// Comparing hashes keeps the length of the token from leaking.
+		tokenHash := sha256.Sum256([]byte(token))
+		expectedHash := sha256.Sum256([]byte(expected))
+		if expected == "" || subtle.ConstantTimeCompare(tokenHash[:], expectedHash[:]) != 1 {
+			return errortypes.UnauthorizedError{
+				UserError: errortypes.UserError{
+					SafeMessage: "The token is invalid",
+				},
+			}
+		}
+
+		return nil
+	}
+}
+
+// Tokens checks bearer tokens that aren't session tokens against the
+// given checks. The first check that passes makes "token:<name>" the
+// principal of the request. Other tokens are passed on untouched so
+// the routes that take them can reject them. It has to run after
+// Middleware.
+func Tokens(checks map[string]TokenCheck) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if _, ok := PrincipalFromContext(request.Context()); ok {
+				next.ServeHTTP(response, request)
+
+				return
+			}
+
+			authorization := request.Header.Get("Authorization")
+			token := strings.TrimPrefix(authorization, "Bearer ")
+			if token == authorization || token == "" || strings.HasPrefix(token, SessionTokenPrefix) {
+				next.ServeHTTP(response, request)
+
+				return
+			}
+
+			for name, check := range checks {
+				if err := check(token); err == nil {
+					ctx := ContextWithPrincipal(request.Context(), "token:"+name)
+					next.ServeHTTP(response, request.WithContext(ctx))
+
+					return
+				}
+			}
+
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
+// RequireToken rejects requests that didn't carry a token that passed
+// the check with the given name with a 401 response. It has to run
+// after Tokens.
+func RequireToken(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if principal, _ := PrincipalFromContext(request.Context()); principal != "token:"+name {
+				response.Header().Set("WWW-Authenticate", `Bearer realm="ley"`)
+				handleError(response, request, errortypes.UnauthorizedError{
+					UserError: errortypes.UserError{
+						SafeMessage: "A valid " + name + " token is required",
+					},
+				})
+
+				return
+			}
+
+			next.ServeHTTP(response, request)
+		})
+	}
+}
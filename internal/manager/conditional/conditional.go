@@ -0,0 +1,100 @@
+package conditional
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag creates the entity tag for a version of a resource.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag adds the entity tag for a version of a resource to the
+// response headers.
+func SetETag(response http.ResponseWriter, version int64) {
+	response.Header().Set("ETag", ETag(version))
+}
+
+// ParseIfMatch reads the versions that an update or delete is allowed
+// to apply to from the If-Match header. A nil result means any version
+// is allowed, either because the header is missing or because it is
+// "*". Entity tags that we didn't create can never match so they are
+// left out, which can result in an empty list that matches nothing.
+func ParseIfMatch(request *http.Request) []int64 {
+	headerValues := request.Header.Values("If-Match")
+	if len(headerValues) == 0 {
+		return nil
+	}
+
+	versions := []int64{}
+	for _, tag := range splitTags(headerValues) {
+		if tag == "*" {
+			return nil
+		}
+
+		// If-Match uses strong comparison so weak tags never match.
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+
+		if version, ok := parseTag(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions
+}
+
+// NotModified checks the If-None-Match header to see if the client
+// already has the given version of a resource.
+func NotModified(request *http.Request, version int64) bool {
+	headerValues := request.Header.Values("If-None-Match")
+
+	for _, tag := range splitTags(headerValues) {
+		if tag == "*" {
+			return true
+		}
+
+		// If-None-Match uses weak comparison.
+		if taggedVersion, ok := parseTag(strings.TrimPrefix(tag, "W/")); ok && taggedVersion == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteNotModified responds to a conditional GET for a resource the
+// client already has.
+func WriteNotModified(response http.ResponseWriter, version int64) {
+	SetETag(response, version)
+	response.WriteHeader(http.StatusNotModified)
+}
+
+func splitTags(headerValues []string) []string {
+	var tags []string
+	for _, headerValue := range headerValues {
+		for _, tag := range strings.Split(headerValue, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags
+}
+
+func parseTag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
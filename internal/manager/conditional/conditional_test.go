@@ -0,0 +1,78 @@
+package conditional_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/conditional"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIfMatchShouldReadVersions(t *testing.T) {
+	testCases := []struct {
+		headerValues []string
+		versions     []int64
+	}{
+		{headerValues: nil, versions: nil},
+		{headerValues: []string{`*`}, versions: nil},
+		{headerValues: []string{`"3"`}, versions: []int64{3}},
+		{headerValues: []string{`"3", "4"`, `"5"`}, versions: []int64{3, 4, 5}},
+		{headerValues: []string{`W/"3"`}, versions: []int64{}},
+		{headerValues: []string{`"not-ours"`}, versions: []int64{}},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodPatch, "/", nil)
+		for _, headerValue := range testCase.headerValues {
+			request.Header.Add("If-Match", headerValue)
+		}
+
+		require.Equal(
+			t,
+			testCase.versions,
+			conditional.ParseIfMatch(request),
+			"should parse %v",
+			testCase.headerValues,
+		)
+	}
+}
+
+func TestNotModifiedShouldUseWeakComparison(t *testing.T) {
+	testCases := []struct {
+		headerValue string
+		notModified bool
+	}{
+		{headerValue: "", notModified: false},
+		{headerValue: `*`, notModified: true},
+		{headerValue: `"2"`, notModified: true},
+		{headerValue: `W/"2"`, notModified: true},
+		{headerValue: `"1", "2"`, notModified: true},
+		{headerValue: `"1"`, notModified: false},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		if testCase.headerValue != "" {
+			request.Header.Set("If-None-Match", testCase.headerValue)
+		}
+
+		require.Equal(
+			t,
+			testCase.notModified,
+			conditional.NotModified(request, 2),
+			"should check '%s'",
+			testCase.headerValue,
+		)
+	}
+}
+
+func TestWriteNotModifiedShouldSendTheETag(t *testing.T) {
+	response := httptest.NewRecorder()
+
+	conditional.WriteNotModified(response, 7)
+
+	require.Equal(t, http.StatusNotModified, response.Code)
+	require.Equal(t, `"7"`, response.Header().Get("ETag"))
+	require.Empty(t, response.Body.Bytes(), "should not have a body")
+}
@@ -0,0 +1,38 @@
+package configuration
+
+import "time"
+
+// APIConfiguration controls how the HTTP API treats requests.
+type APIConfiguration struct {
+	// AdminToken is the bearer token that the /admin endpoints, like
+	// webhooks and jobs, ask for. They can't be used without one.
+	AdminToken string `envconfig:"admin_token"`
+
+	// IdempotencyWindow is how long the response to a request with an
+	// Idempotency-Key is kept around to be replayed. A window of zero
+	// turns idempotency keys off.
+	IdempotencyWindow time.Duration `default:"24h" envconfig:"idempotency_window"`
+
+	// MaxBodySize is the largest request body, in bytes, that is
+	// accepted. Zero accepts bodies of any size.
+	MaxBodySize int64 `default:"1048576" envconfig:"max_body_size"`
+
+	// IPRateLimit is how many requests per second every IP address
+	// can make on average, with bursts of up to IPBurstLimit requests.
+	// A rate of zero turns the limit off.
+	IPRateLimit  float64 `default:"50" envconfig:"ip_rate_limit"`
+	IPBurstLimit int     `default:"100" envconfig:"ip_burst_limit"`
+
+	// PrincipalRateLimit is how many requests per second every user
+	// or bearer token can make on average, with bursts of up to
+	// PrincipalBurstLimit requests. A rate of zero turns the limit off.
+	PrincipalRateLimit  float64 `default:"20" envconfig:"principal_rate_limit"`
+	PrincipalBurstLimit int     `default:"40" envconfig:"principal_burst_limit"`
+
+	// ExpensiveRateLimit is a separate budget for endpoints that take a
+	// lot of work, like enrolling nodes and applying manifests. It is
+	// kept for every user or bearer token, or for every IP address for
+	// anonymous requests. A rate of zero turns the limit off.
+	ExpensiveRateLimit  float64 `default:"1" envconfig:"expensive_rate_limit"`
+	ExpensiveBurstLimit int     `default:"10" envconfig:"expensive_burst_limit"`
+}
@@ -8,9 +8,17 @@ import (
 
 // Configuration holds service configuration.
 type Configuration struct {
-	Service ServiceConfiguration
-	Logging LoggingConfiguration
-	DB      DBConfiguration
+	Service   ServiceConfiguration
+	API       APIConfiguration
+	Network   NetworkConfiguration
+	Node      NodeConfiguration
+	DNS       DNSConfiguration
+	Scheduler SchedulerConfiguration
+	Webhook   WebhookConfiguration
+	OIDC      OIDCConfiguration
+	SCIM      SCIMConfiguration
+	Logging   LoggingConfiguration
+	DB        DBConfiguration
 }
 
 // NewFromEnvironment loads configuration from the environment.
@@ -21,5 +29,41 @@ func NewFromEnvironment() (*Configuration, error) {
 		return nil, fmt.Errorf("Unable to load configuration from environment: %w", err)
 	}
 
+	if config.API.MaxBodySize < 0 {
+		return nil, fmt.Errorf("The maximum request body size can't be negative")
+	}
+
+	if config.API.IPRateLimit < 0 || config.API.PrincipalRateLimit < 0 || config.API.ExpensiveRateLimit < 0 {
+		return nil, fmt.Errorf("Rate limits can't be negative")
+	}
+
+	if config.Node.OfflineAfter < config.Node.StaleAfter {
+		return nil, fmt.Errorf("Nodes must be stale before they can be offline")
+	}
+
+	if config.Node.ReapOfflineAfter < config.Node.OfflineAfter {
+		return nil, fmt.Errorf("Ephemeral nodes must be offline before they can be reaped")
+	}
+
+	if err := config.DNS.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Scheduler.PollInterval <= 0 {
+		return nil, fmt.Errorf("The scheduler poll interval must be positive")
+	}
+
+	if config.Webhook.MaxAttempts < 1 {
+		return nil, fmt.Errorf("Webhooks must be attempted at least once")
+	}
+
+	if config.OIDC.Enabled() && (config.OIDC.ClientID == "" || config.OIDC.RedirectURL == "") {
+		return nil, fmt.Errorf("Single sign-on needs a client ID and a redirect URL")
+	}
+
+	if config.OIDC.SessionDuration <= 0 {
+		return nil, fmt.Errorf("Sessions must last for a positive duration")
+	}
+
 	return &config, nil
 }
@@ -0,0 +1,119 @@
+package configuration
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"inet.af/netaddr"
+)
+
+// DNSConfiguration controls the DNS server that answers for the names
+// of nodes.
+type DNSConfiguration struct {
+	// ListenAddress is the host and port that the DNS server listens
+	// on for UDP and TCP queries. An empty address turns the DNS server
+	// off.
+	ListenAddress string `envconfig:"listen_address"`
+
+	// ResolverAddress is the IP address that nodes are told to send
+	// their DNS queries to. It defaults to the host of the listen
+	// address when that is a specific address.
+	ResolverAddress string `envconfig:"resolver_address"`
+
+	// Zone is the domain that node names are served under.
+	Zone string `default:"ley.internal." envconfig:"zone"`
+
+	// TTL is how long answers for node names may be cached.
+	TTL time.Duration `default:"60s" envconfig:"ttl"`
+
+	// Upstreams are the servers, as host:port, that queries outside of
+	// the zone are forwarded to.
+	Upstreams []string `envconfig:"upstreams"`
+
+	// RecursionSources are the networks, in CIDR notation, whose
+	// queries outside of the zone are forwarded to the upstreams. It
+	// defaults to the private and loopback ranges so that the server
+	// isn't an open resolver.
+	// nolint: lll
+	RecursionSources []string `default:"10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,127.0.0.0/8,fc00::/7,::1/128" envconfig:"recursion_sources"`
+
+	// QueryTimeout is how long a query may take to answer.
+	QueryTimeout time.Duration `default:"5s" envconfig:"query_timeout"`
+}
+
+// Enabled tells whether the DNS server should be started.
+func (config DNSConfiguration) Enabled() bool {
+	return config.ListenAddress != ""
+}
+
+// Resolver gives the address that nodes are told to use for DNS, if
+// there is one.
+func (config DNSConfiguration) Resolver() (netaddr.IP, bool) {
+	if !config.Enabled() {
+		return netaddr.IP{}, false
+	}
+
+	if config.ResolverAddress != "" {
+		resolver, err := netaddr.ParseIP(config.ResolverAddress)
+
+		return resolver, err == nil
+	}
+
+	host, _, err := net.SplitHostPort(config.ListenAddress)
+	if err != nil {
+		return netaddr.IP{}, false
+	}
+
+	resolver, err := netaddr.ParseIP(host)
+	if err != nil || resolver.IsUnspecified() {
+		return netaddr.IP{}, false
+	}
+
+	return resolver, true
+}
+
+// RecursionPrefixes parses the networks that queries may be forwarded
+// for.
+func (config DNSConfiguration) RecursionPrefixes() ([]netaddr.IPPrefix, error) {
+	prefixes := make([]netaddr.IPPrefix, 0, len(config.RecursionSources))
+	for _, source := range config.RecursionSources {
+		prefix, err := netaddr.ParseIPPrefix(source)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid DNS recursion source '%s': %w", source, err)
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
+}
+
+// Validate checks that the addresses can be used.
+func (config DNSConfiguration) Validate() error {
+	if !config.Enabled() {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(config.ListenAddress); err != nil {
+		return fmt.Errorf("Invalid DNS listen address '%s': %w", config.ListenAddress, err)
+	}
+
+	if config.ResolverAddress != "" {
+		if _, err := netaddr.ParseIP(config.ResolverAddress); err != nil {
+			return fmt.Errorf("Invalid DNS resolver address '%s': %w", config.ResolverAddress, err)
+		}
+	}
+
+	for _, upstream := range config.Upstreams {
+		if _, _, err := net.SplitHostPort(upstream); err != nil {
+			return fmt.Errorf("Invalid DNS upstream '%s': %w", upstream, err)
+		}
+	}
+
+	if _, err := config.RecursionPrefixes(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package configuration_test
+
+import (
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestDNSResolverShouldDefaultToTheListenAddress(t *testing.T) {
+	resolver, ok := configuration.DNSConfiguration{ListenAddress: "10.0.0.1:53"}.Resolver()
+	require.True(t, ok)
+	require.Equal(t, netaddr.MustParseIP("10.0.0.1"), resolver)
+
+	resolver, ok = configuration.DNSConfiguration{
+		ListenAddress:   "0.0.0.0:53",
+		ResolverAddress: "fd00::1",
+	}.Resolver()
+	require.True(t, ok)
+	require.Equal(t, netaddr.MustParseIP("fd00::1"), resolver)
+
+	_, ok = configuration.DNSConfiguration{ListenAddress: "0.0.0.0:53"}.Resolver()
+	require.False(t, ok, "should not tell nodes to use an unspecified address")
+
+	_, ok = configuration.DNSConfiguration{ResolverAddress: "10.0.0.1"}.Resolver()
+	require.False(t, ok, "should not tell nodes to use a server that isn't running")
+}
+
+func TestDNSConfigurationShouldRejectInvalidAddresses(t *testing.T) {
+	require.NoError(t, configuration.DNSConfiguration{}.Validate())
+	require.NoError(t, configuration.DNSConfiguration{
+		ListenAddress:    ":53",
+		Upstreams:        []string{"1.1.1.1:53"},
+		RecursionSources: []string{"10.0.0.0/8", "fd00::/8"},
+	}.Validate())
+
+	require.Error(t, configuration.DNSConfiguration{ListenAddress: "10.0.0.1"}.Validate())
+	require.Error(t, configuration.DNSConfiguration{ListenAddress: ":53", ResolverAddress: "nope"}.Validate())
+	require.Error(t, configuration.DNSConfiguration{ListenAddress: ":53", Upstreams: []string{"1.1.1.1"}}.Validate())
+	require.Error(t, configuration.DNSConfiguration{
+		ListenAddress:    ":53",
+		RecursionSources: []string{"10.0.0.1"},
+	}.Validate())
+}
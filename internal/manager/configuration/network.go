@@ -0,0 +1,19 @@
+package configuration
+
+import "inet.af/netaddr"
+
+// NetworkConfiguration describes the address space that managed
+// networks are planned in.
+type NetworkConfiguration struct {
+	// IPv4Supernet is the IPv4 range that network ranges are
+	// reported against when planning address space.
+	IPv4Supernet netaddr.IPPrefix `default:"10.0.0.0/8" envconfig:"ipv4_supernet"`
+
+	// IPv6Supernet is the IPv6 range that network ranges are
+	// reported against when planning address space.
+	IPv6Supernet netaddr.IPPrefix `default:"fd00::/8" envconfig:"ipv6_supernet"`
+
+	// IPv4Pools are the IPv4 ranges, tried in order, that networks
+	// asking for a prefix length are given a range from.
+	IPv4Pools []netaddr.IPPrefix `default:"10.0.0.0/8" envconfig:"ipv4_pools"`
+}
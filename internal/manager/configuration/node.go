@@ -0,0 +1,32 @@
+package configuration
+
+import "time"
+
+// NodeConfiguration controls how the keys, health and clean up of
+// nodes are managed.
+type NodeConfiguration struct {
+	// KeyRotationInterval is how long a node may keep using the same
+	// key pair before it is asked to rotate it. Zero turns scheduled
+	// rotation off.
+	KeyRotationInterval time.Duration `default:"2160h" envconfig:"key_rotation_interval"`
+
+	// KeyGracePeriod is how long the old public key of a node is still
+	// accepted after the node rotates to a new one.
+	KeyGracePeriod time.Duration `default:"24h" envconfig:"key_grace_period"`
+
+	// StaleAfter is how long a node can go without a heartbeat before
+	// it is reported as stale instead of online.
+	StaleAfter time.Duration `default:"2m" envconfig:"stale_after"`
+
+	// OfflineAfter is how long a node can go without a heartbeat before
+	// it is reported as offline.
+	OfflineAfter time.Duration `default:"10m" envconfig:"offline_after"`
+
+	// ReapInterval is how often expired and long offline ephemeral
+	// nodes are removed. Zero turns the reaper off.
+	ReapInterval time.Duration `default:"1m" envconfig:"reap_interval"`
+
+	// ReapOfflineAfter is how long an ephemeral node can go without a
+	// heartbeat before it is removed.
+	ReapOfflineAfter time.Duration `default:"1h" envconfig:"reap_offline_after"`
+}
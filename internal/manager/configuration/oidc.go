@@ -0,0 +1,43 @@
+package configuration
+
+import "time"
+
+// OIDCConfiguration controls single sign-on through an OpenID Connect
+// provider. Single sign-on is off unless an issuer is set.
+type OIDCConfiguration struct {
+	// Issuer is the URL of the provider. Its endpoints are discovered
+	// from the provider's /.well-known/openid-configuration.
+	Issuer string `envconfig:"issuer"`
+
+	// ClientID identifies the manager to the provider.
+	ClientID string `envconfig:"client_id"`
+
+	// ClientSecret authenticates the manager to the provider. It can be
+	// left empty for public clients.
+	ClientSecret string `envconfig:"client_secret"`
+
+	// RedirectURL is where the provider sends users back to. It must
+	// point at the manager's /auth/callback route.
+	RedirectURL string `envconfig:"redirect_url"`
+
+	// Scopes are requested from the provider on top of openid.
+	Scopes []string `default:"profile,email" envconfig:"scopes"`
+
+	// UsernameClaim is the claim of the ID token that holds the
+	// username. Users that don't exist yet are created with it the
+	// first time they log in.
+	UsernameClaim string `default:"preferred_username" envconfig:"username_claim"`
+
+	// SessionDuration is how long a session lasts before the user has
+	// to log in again.
+	SessionDuration time.Duration `default:"1h" envconfig:"session_duration"`
+
+	// LoginTimeout is how long a user has to finish logging in with
+	// the provider.
+	LoginTimeout time.Duration `default:"10m" envconfig:"login_timeout"`
+}
+
+// Enabled tells whether single sign-on is configured.
+func (config OIDCConfiguration) Enabled() bool {
+	return config.Issuer != ""
+}
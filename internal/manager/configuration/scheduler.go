@@ -0,0 +1,18 @@
+package configuration
+
+import "time"
+
+// SchedulerConfiguration controls how periodic jobs are shared between
+// manager replicas.
+type SchedulerConfiguration struct {
+	// ReplicaID identifies this replica when it holds a job lease. A
+	// name is generated from the host name when it isn't set.
+	ReplicaID string `envconfig:"replica_id"`
+
+	// PollInterval is how often the scheduler checks for jobs that are
+	// due.
+	PollInterval time.Duration `default:"10s" envconfig:"poll_interval"`
+
+	// RunRetention is how long the history of job runs is kept.
+	RunRetention time.Duration `default:"168h" envconfig:"run_retention"`
+}
@@ -0,0 +1,15 @@
+package configuration
+
+// SCIMConfiguration controls provisioning of users and groups by an
+// identity provider through SCIM. Provisioning is off unless a token is
+// set.
+type SCIMConfiguration struct {
+	// Token is the bearer token that the provisioning client has to
+	// send. It isn't accepted anywhere else in the API.
+	Token string `envconfig:"token"`
+}
+
+// Enabled tells whether SCIM provisioning is configured.
+func (config SCIMConfiguration) Enabled() bool {
+	return config.Token != ""
+}
@@ -12,6 +12,10 @@ type ServiceConfiguration struct {
 	EnvironmentType configuration.EnvironmentType `envconfig:"environment_type"`
 	Host            string                        `default:"127.0.0.1"`
 	Port            int                           `default:"8080"`
+
+	// GRPCPort is the port that the gRPC API listens on. A port of zero
+	// turns the gRPC API off.
+	GRPCPort int `default:"9090" envconfig:"grpc_port"`
 }
 
 // Address gets the host and port combination that the service should
@@ -19,3 +23,9 @@ type ServiceConfiguration struct {
 func (config ServiceConfiguration) Address() string {
 	return fmt.Sprintf("%s:%d", config.Host, config.Port)
 }
+
+// GRPCAddress gets the host and port combination that the gRPC API
+// should listen on.
+func (config ServiceConfiguration) GRPCAddress() string {
+	return fmt.Sprintf("%s:%d", config.Host, config.GRPCPort)
+}
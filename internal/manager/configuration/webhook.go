@@ -0,0 +1,34 @@
+package configuration
+
+import "time"
+
+// WebhookConfiguration controls how events are delivered to webhook
+// subscriptions.
+type WebhookConfiguration struct {
+	// DeliveryInterval is how often pending deliveries are sent. It
+	// can't be shorter than the scheduler's poll interval in practice.
+	DeliveryInterval time.Duration `default:"10s" envconfig:"delivery_interval"`
+
+	// Timeout is how long a receiver has to respond to a delivery.
+	Timeout time.Duration `default:"10s" envconfig:"timeout"`
+
+	// MaxAttempts is how many times a delivery is tried before it is
+	// given up on.
+	MaxAttempts int `default:"10" envconfig:"max_attempts"`
+
+	// RetryBackoff is how long to wait before the first retry. Each
+	// following retry waits twice as long as the one before it.
+	RetryBackoff time.Duration `default:"30s" envconfig:"retry_backoff"`
+
+	// MaxRetryBackoff caps how long to wait between retries.
+	MaxRetryBackoff time.Duration `default:"1h" envconfig:"max_retry_backoff"`
+
+	// AllowPrivateTargets lets webhooks be sent to loopback,
+	// link-local and private addresses. It is off so that webhooks
+	// can't be used to reach into the manager's own network.
+	AllowPrivateTargets bool `default:"false" envconfig:"allow_private_targets"`
+
+	// Retention is how long events and their deliveries are kept once
+	// they are no longer pending.
+	Retention time.Duration `default:"168h" envconfig:"retention"`
+}
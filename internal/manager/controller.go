@@ -5,22 +5,54 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/durandj/ley/internal/manager/apply"
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/group"
+	"github.com/durandj/ley/internal/manager/idempotency"
+	"github.com/durandj/ley/internal/manager/limit"
 	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/scheduler"
+	"github.com/durandj/ley/internal/manager/scim"
 	"github.com/durandj/ley/internal/manager/user"
+	"github.com/durandj/ley/internal/manager/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+// expensiveEndpoints take a lot more work than other requests so they
+// get a budget of their own on top of the usual rate limits.
+var expensiveEndpoints = []limit.Endpoint{
+	{Method: http.MethodGet, Pattern: "/auth/login"},
+	{Method: http.MethodGet, Pattern: "/auth/callback"},
+	{Method: http.MethodPost, Pattern: "/node"},
+	{Method: http.MethodPut, Pattern: "/node/{id}/key"},
+	{Method: http.MethodPost, Pattern: "/apply"},
+}
+
 // Controller handles HTTP requests as well as setting up any required
 // middleware across all endpoints.
 type Controller struct {
-	router            chi.Router
-	networkController *network.Controller
-	userController    *user.Controller
+	router                 chi.Router
+	authController         *auth.Controller
+	organizationController *organization.Controller
+	networkController      *network.Controller
+	nodeController         *node.Controller
+	userController         *user.Controller
+	groupController        *group.Controller
+	scimController         *scim.Controller
+	jobController          *scheduler.Controller
+	webhookController      *webhook.Controller
+	applyController        *apply.Controller
+	hub                    *notify.Hub
+	limiters               limit.Limiters
 }
 
 // NewController sets up a new controller and the required middleware.
-func NewController(db *sql.DB) *Controller {
+func NewController(db *sql.DB, config *configuration.Configuration) *Controller {
 	router := chi.NewRouter()
 
 	router.Use(middleware.RealIP)
@@ -30,27 +62,191 @@ func NewController(db *sql.DB) *Controller {
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.StripSlashes)
 	router.Use(middleware.Timeout(time.Minute))
-	router.Use(middleware.AllowContentType("application/json"))
+	router.Use(middleware.AllowContentType("application/json", scim.ContentType))
 	router.Use(middleware.CleanPath)
 	router.Use(middleware.Heartbeat("/healthcheck"))
 
+	if config.API.MaxBodySize > 0 {
+		router.Use(limit.Body(config.API.MaxBodySize))
+	}
+
+	// The limiters are shared with the gRPC API so that callers have
+	// one budget for both.
+	limiters := limit.NewLimiters(
+		limit.Budget{Rate: config.API.IPRateLimit, Burst: config.API.IPBurstLimit},
+		limit.Budget{Rate: config.API.PrincipalRateLimit, Burst: config.API.PrincipalBurstLimit},
+		limit.Budget{Rate: config.API.ExpensiveRateLimit, Burst: config.API.ExpensiveBurstLimit},
+	)
+
+	// Callers are limited by their IP address before anything is
+	// looked up for them, like their session.
+	if limiters.IP != nil {
+		router.Use(limit.Requests(limiters.IP, limit.ByIP))
+	}
+
+	userService := user.NewService(db)
+
+	var provider *auth.Provider
+	if config.OIDC.Enabled() {
+		provider = auth.NewProvider(config.OIDC, &http.Client{Timeout: 10 * time.Second})
+	}
+
+	authController := &auth.Controller{
+		AuthService: auth.NewService(db, provider, userService, config.OIDC),
+	}
+	router.Use(auth.Middleware(authController.AuthService))
+
+	groupService := group.NewService(db)
+	scimService := scim.NewService(userService, groupService, config.SCIM)
+
+	tokenChecks := map[string]auth.TokenCheck{}
+	if config.SCIM.Enabled() {
+		tokenChecks["scim"] = scimService.Authenticate
+	}
+	if config.API.AdminToken != "" {
+		tokenChecks["admin"] = auth.StaticToken(config.API.AdminToken)
+	}
+	router.Use(auth.Tokens(tokenChecks))
+
+	if limiters.Principal != nil {
+		router.Use(limit.Requests(limiters.Principal, limit.ByPrincipal))
+	}
+
+	if limiters.Expensive != nil {
+		router.Use(limit.Endpoints(
+			expensiveEndpoints,
+			limit.Requests(limiters.Expensive, limit.ByPrincipalOrIP),
+		))
+	}
+
+	if config.API.IdempotencyWindow > 0 {
+		router.Use(idempotency.Middleware(
+			idempotency.NewStore(db),
+			config.API.IdempotencyWindow,
+			idempotency.DefaultCaller,
+		))
+	}
+
+	organizationController := &organization.Controller{
+		OrganizationService: organization.NewService(db),
+	}
+
+	networkService := network.NewService(db, config.Network, organizationController.OrganizationService)
+
 	networkController := &network.Controller{
-		NetworkService: network.NewService(db),
+		NetworkService: networkService,
+	}
+
+	hub := notify.NewHub(config.DB.ConnectionString, notify.NetworkChannel)
+
+	nodeController := &node.Controller{
+		NodeService: node.NewService(
+			db,
+			networkService,
+			organizationController.OrganizationService,
+			config.Node,
+			config.DNS,
+		),
+		Hub: hub,
 	}
-	router.Route("/network", networkController.RegisterRoutes)
 
 	userController := &user.Controller{
-		UserService: user.NewService(db),
+		UserService: userService,
+	}
+	groupController := &group.Controller{
+		GroupService: groupService,
 	}
-	router.Route("/user", userController.RegisterRoutes)
+
+	scimController := &scim.Controller{
+		SCIMService: scimService,
+	}
+
+	applyController := &apply.Controller{
+		ApplyService: apply.NewService(networkService, userController.UserService),
+	}
+
+	jobController := &scheduler.Controller{
+		JobStore: scheduler.NewStore(db),
+	}
+
+	webhookController := &webhook.Controller{
+		WebhookService: webhook.NewService(db, config.Webhook),
+	}
+
+	router.Get("/openapi.json", serveOpenAPISpec)
+	router.Route("/auth", authController.RegisterRoutes)
+	router.Route("/scim/v2", scimController.RegisterRoutes)
+
+	// Sessions can only be had through single sign-on so they're only
+	// asked for once it's configured.
+	requireSession := func(next http.Handler) http.Handler {
+		return next
+	}
+	if config.OIDC.Enabled() {
+		requireSession = auth.RequireSession
+	}
+
+	// Nodes authenticate with their own secret instead of a session.
+	router.Route("/node", func(router chi.Router) {
+		router.Group(nodeController.RegisterAgentRoutes)
+		router.Group(func(router chi.Router) {
+			router.Use(requireSession)
+			nodeController.RegisterRoutes(router)
+		})
+	})
+
+	router.Group(func(router chi.Router) {
+		router.Use(requireSession)
+
+		router.Route("/network", networkController.RegisterRoutes)
+		router.Route("/org", func(router chi.Router) {
+			organizationController.RegisterRoutes(router)
+			router.Route("/{org}/network", networkController.RegisterOrganizationRoutes)
+			router.Route("/{org}/node", nodeController.RegisterRoutes)
+		})
+		router.Route("/group", groupController.RegisterRoutes)
+		router.Route("/user", func(router chi.Router) {
+			userController.RegisterRoutes(router)
+			router.Route("/{username}/group", groupController.RegisterUserRoutes)
+		})
+		router.Route("/apply", applyController.RegisterRoutes)
+	})
+
+	router.Route("/admin", func(router chi.Router) {
+		router.Use(auth.RequireToken("admin"))
+
+		router.Route("/job", jobController.RegisterRoutes)
+		router.Route("/webhook", webhookController.RegisterRoutes)
+	})
 
 	return &Controller{
-		router:            router,
-		networkController: networkController,
-		userController:    userController,
+		router:                 router,
+		authController:         authController,
+		organizationController: organizationController,
+		networkController:      networkController,
+		nodeController:         nodeController,
+		userController:         userController,
+		groupController:        groupController,
+		scimController:         scimController,
+		jobController:          jobController,
+		webhookController:      webhookController,
+		applyController:        applyController,
+		hub:                    hub,
+		limiters:               limiters,
 	}
 }
 
+// Close frees the resources held by the controller, like the
+// connection used to watch for changes.
+func (controller *Controller) Close() error {
+	return controller.hub.Close()
+}
+
+// Routes gives access to the routes that the controller handles.
+func (controller *Controller) Routes() chi.Routes {
+	return controller.router
+}
+
 func (controller *Controller) ServeHTTP(
 	response http.ResponseWriter,
 	request *http.Request,
@@ -0,0 +1,100 @@
+package manager_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControllerShouldRequireASessionWithSingleSignOn(t *testing.T) {
+	controller := manager.NewController(nil, &configuration.Configuration{
+		OIDC: configuration.OIDCConfiguration{
+			Issuer:   "https://sso.example.com",
+			ClientID: "ley",
+		},
+	})
+
+	for _, path := range []string{
+		"/user",
+		"/network",
+		"/org",
+		"/group",
+		"/node",
+	} {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		recorder := httptest.NewRecorder()
+		controller.ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusUnauthorized, recorder.Code, "should reject anonymous requests for %s", path)
+		require.Contains(t, recorder.Body.String(), "Not logged in")
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/apply", nil)
+	recorder := httptest.NewRecorder()
+	controller.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code, "should reject anonymous changes")
+
+	request = httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	recorder = httptest.NewRecorder()
+	controller.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, "should leave the OpenAPI document open")
+
+	request = httptest.NewRequest(http.MethodGet, "/healthcheck", nil)
+	recorder = httptest.NewRecorder()
+	controller.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, "should leave the health check open")
+}
+
+func TestControllerShouldRequireTheAdminToken(t *testing.T) {
+	controller := manager.NewController(nil, &configuration.Configuration{
+		API: configuration.APIConfiguration{
+			AdminToken: "admin-secret",
+		},
+	})
+
+	for _, token := range []string{"", "not-the-admin-secret"} {
+		for _, path := range []string{"/admin/job", "/admin/webhook"} {
+			request := httptest.NewRequest(http.MethodGet, path, nil)
+			if token != "" {
+				request.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			recorder := httptest.NewRecorder()
+			controller.ServeHTTP(recorder, request)
+
+			require.Equal(t, http.StatusUnauthorized, recorder.Code, "should reject %s without the admin token", path)
+			require.Contains(t, recorder.Body.String(), "A valid admin token is required")
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/admin/webhook", nil)
+	recorder := httptest.NewRecorder()
+	manager.NewController(nil, &configuration.Configuration{}).ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code, "should keep the admin endpoints closed without a token")
+}
+
+func TestControllerShouldRequireTheNodeSecret(t *testing.T) {
+	controller := manager.NewController(nil, &configuration.Configuration{})
+
+	for _, route := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPut, "/node/some-node/key"},
+		{http.MethodGet, "/node/some-node/config"},
+		{http.MethodGet, "/node/some-node/watch"},
+		{http.MethodPost, "/node/some-node/heartbeat"},
+		{http.MethodGet, "/node/some-node/peer-stats"},
+	} {
+		request := httptest.NewRequest(route.method, route.path, nil)
+		recorder := httptest.NewRecorder()
+		controller.ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusUnauthorized, recorder.Code, "should reject %s without the node's secret", route.path)
+		require.Contains(t, recorder.Body.String(), "A valid node secret is required")
+	}
+}
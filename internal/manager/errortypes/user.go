@@ -48,3 +48,19 @@ type NotFoundError struct {
 }
 
 var _ error = (*NotFoundError)(nil)
+
+// PreconditionFailedError is returned when a conditional request was
+// made against a version of the data that is no longer current.
+type PreconditionFailedError struct {
+	UserError
+}
+
+var _ error = (*PreconditionFailedError)(nil)
+
+// UnauthorizedError is returned when a request is missing valid
+// credentials or they don't allow what was asked for.
+type UnauthorizedError struct {
+	UserError
+}
+
+var _ error = (*UnauthorizedError)(nil)
@@ -0,0 +1,23 @@
+WITH Added AS (
+    INSERT INTO GroupMembers (
+        GroupID,
+        MemberGroupID,
+        CreatedOn
+    )
+    VALUES (
+        $1,
+        $2,
+        $3
+    )
+    -- Adding a group that is already a member keeps its membership as
+    -- it is but still returns it.
+    ON CONFLICT (GroupID, MemberGroupID) DO UPDATE SET CreatedOn = GroupMembers.CreatedOn
+    RETURNING MemberGroupID, CreatedOn
+)
+SELECT
+    Groups.ID,
+    Groups.Name,
+    Added.CreatedOn
+FROM Added
+JOIN Groups ON Groups.ID = Added.MemberGroupID
+;
@@ -0,0 +1,18 @@
+-- Checks whether the second group is the first one or one of the
+-- groups nested in it, however deep.
+WITH RECURSIVE Nested (ID) AS (
+    SELECT $1::VARCHAR
+    UNION
+    SELECT GroupMembers.MemberGroupID
+    FROM GroupMembers
+    JOIN Nested ON Nested.ID = GroupMembers.GroupID
+    WHERE
+        GroupMembers.MemberGroupID IS NOT NULL
+)
+SELECT EXISTS (
+    SELECT 1
+    FROM Nested
+    WHERE
+        ID = $2
+)
+;
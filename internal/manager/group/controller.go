@@ -0,0 +1,531 @@
+package group
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/durandj/ley/internal/manager/conditional"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Controller handles all the HTTP requests for group related API's.
+type Controller struct {
+	GroupService *Service
+}
+
+// RegisterRoutes registers HTTP request handlers for all group API's.
+func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Get("/", controller.ListGroups)
+	router.Post("/", controller.CreateGroup)
+	router.Get("/{group}", controller.GetGroup)
+	router.Delete("/{group}", controller.DeleteGroup)
+	router.Get("/{group}/member", controller.ListMembers)
+	router.Put("/{group}/member/user/{username}", controller.AddUser)
+	router.Delete("/{group}/member/user/{username}", controller.RemoveUser)
+	router.Put("/{group}/member/group/{member}", controller.AddGroup)
+	router.Delete("/{group}/member/group/{member}", controller.RemoveGroup)
+}
+
+// RegisterUserRoutes registers HTTP request handlers for the group
+// API's of a user, which expect the username in the route.
+func (controller *Controller) RegisterUserRoutes(router chi.Router) {
+	router.Get("/", controller.ListEffectiveGroups)
+}
+
+// CreateGroupRequest is the expected request body for creating a new
+// group.
+type CreateGroupRequest struct {
+	Name string `json:"name"`
+}
+
+// Bind is used to determine how to map from a request body to a group
+// creation request.
+func (createGroupRequest *CreateGroupRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*CreateGroupRequest)(nil)
+
+// CreateGroupResponse is the response body for a successful group
+// creation request.
+type CreateGroupResponse struct {
+	RenderableGroup
+}
+
+var _ render.Renderer = (*CreateGroupResponse)(nil)
+
+// CreateGroup handles requests to create a new group.
+func (controller *Controller) CreateGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var createGroupRequest CreateGroupRequest
+	if err := render.Bind(request, &createGroupRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	group, err := controller.GroupService.CreateGroup(ctx, CreateGroupOpts(createGroupRequest))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	createGroupResponse := CreateGroupResponse{
+		RenderableGroup: NewRenderableGroup(group),
+	}
+
+	conditional.SetETag(response, group.Version())
+	response.WriteHeader(http.StatusCreated)
+	_ = render.Render(response, request, &createGroupResponse)
+}
+
+// GetGroupResponse is the response body for requesting a single group.
+type GetGroupResponse struct {
+	RenderableGroup
+}
+
+var _ render.Renderer = (*GetGroupResponse)(nil)
+
+// GetGroup handles requests to fetch a group by name.
+func (controller *Controller) GetGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	group, err := controller.GroupService.GetGroupByName(ctx, chi.URLParam(request, "group"))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	if conditional.NotModified(request, group.Version()) {
+		conditional.WriteNotModified(response, group.Version())
+		return
+	}
+
+	getGroupResponse := GetGroupResponse{
+		RenderableGroup: NewRenderableGroup(group),
+	}
+
+	conditional.SetETag(response, group.Version())
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &getGroupResponse)
+}
+
+// DeleteGroup handles requests to remove a group. The If-Match header
+// can be used to make sure nobody else changed the group first.
+func (controller *Controller) DeleteGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	err := controller.GroupService.DeleteGroup(
+		ctx,
+		chi.URLParam(request, "group"),
+		conditional.ParseIfMatch(request),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ListGroupsResponse is the response for requesting a page of groups.
+type ListGroupsResponse struct {
+	Groups     []RenderableGroup `json:"groups"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// NewListGroupsResponse creates a group list response.
+func NewListGroupsResponse(page listing.Page[Group]) ListGroupsResponse {
+	renderableGroups := make([]RenderableGroup, len(page.Items))
+	for index := range page.Items {
+		renderableGroups[index] = NewRenderableGroup(&page.Items[index])
+	}
+
+	return ListGroupsResponse{
+		Groups:     renderableGroups,
+		NextCursor: page.NextCursor,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listGroupsResponse *ListGroupsResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ListGroupsResponse)(nil)
+
+// ListGroups handles requests to list groups.
+func (controller *Controller) ListGroups(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	params, err := listing.ParseParams(request.URL.Query())
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	page, err := controller.GroupService.ListGroups(ctx, params)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listGroupsResponse := NewListGroupsResponse(page)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listGroupsResponse)
+}
+
+// AddMemberResponse is the response body for adding a user or a group
+// to a group.
+type AddMemberResponse struct {
+	RenderableMember
+}
+
+var _ render.Renderer = (*AddMemberResponse)(nil)
+
+// AddUser handles requests to make a user a member of a group. Adding
+// a user that already is a member changes nothing.
+func (controller *Controller) AddUser(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	member, err := controller.GroupService.AddUser(
+		ctx,
+		chi.URLParam(request, "group"),
+		chi.URLParam(request, "username"),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	addMemberResponse := AddMemberResponse{
+		RenderableMember: NewRenderableMember(member),
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &addMemberResponse)
+}
+
+// RemoveUser handles requests to take a user out of a group.
+func (controller *Controller) RemoveUser(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	err := controller.GroupService.RemoveUser(
+		ctx,
+		chi.URLParam(request, "group"),
+		chi.URLParam(request, "username"),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// AddGroup handles requests to nest a group in another group. Adding
+// a group that already is a member changes nothing.
+func (controller *Controller) AddGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	member, err := controller.GroupService.AddGroup(
+		ctx,
+		chi.URLParam(request, "group"),
+		chi.URLParam(request, "member"),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	addMemberResponse := AddMemberResponse{
+		RenderableMember: NewRenderableMember(member),
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &addMemberResponse)
+}
+
+// RemoveGroup handles requests to take a nested group out of a group.
+func (controller *Controller) RemoveGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	err := controller.GroupService.RemoveGroup(
+		ctx,
+		chi.URLParam(request, "group"),
+		chi.URLParam(request, "member"),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ListMembersResponse is the response for requesting a page of the
+// members of a group.
+type ListMembersResponse struct {
+	Members    []RenderableMember `json:"members"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+}
+
+// NewListMembersResponse creates a member list response.
+func NewListMembersResponse(page listing.Page[Member]) ListMembersResponse {
+	renderableMembers := make([]RenderableMember, len(page.Items))
+	for index := range page.Items {
+		renderableMembers[index] = NewRenderableMember(&page.Items[index])
+	}
+
+	return ListMembersResponse{
+		Members:    renderableMembers,
+		NextCursor: page.NextCursor,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listMembersResponse *ListMembersResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ListMembersResponse)(nil)
+
+// ListMembers handles requests to list the users and groups that were
+// added directly to a group.
+func (controller *Controller) ListMembers(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	params, err := listing.ParseParams(request.URL.Query())
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	page, err := controller.GroupService.ListMembers(ctx, chi.URLParam(request, "group"), params)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listMembersResponse := NewListMembersResponse(page)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listMembersResponse)
+}
+
+// ListEffectiveGroupsResponse is the response for requesting every
+// group that a user belongs to.
+type ListEffectiveGroupsResponse struct {
+	Username string                     `json:"username"`
+	Groups   []RenderableEffectiveGroup `json:"groups"`
+}
+
+// Render customizes the rendering process for a response object.
+func (listEffectiveGroupsResponse *ListEffectiveGroupsResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ListEffectiveGroupsResponse)(nil)
+
+// ListEffectiveGroups handles requests to list every group that a
+// user belongs to, directly or through nested groups, and why.
+func (controller *Controller) ListEffectiveGroups(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	username := chi.URLParam(request, "username")
+
+	effectiveGroups, err := controller.GroupService.ListEffectiveGroups(ctx, username)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listEffectiveGroupsResponse := ListEffectiveGroupsResponse{
+		Username: username,
+		Groups:   make([]RenderableEffectiveGroup, len(effectiveGroups)),
+	}
+
+	for index := range effectiveGroups {
+		listEffectiveGroupsResponse.Groups[index] = NewRenderableEffectiveGroup(&effectiveGroups[index])
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listEffectiveGroupsResponse)
+}
+
+func handleError(
+	response http.ResponseWriter,
+	request *http.Request,
+	err error,
+) {
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
+	var userError errortypes.UserError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &validationError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: validationError.SafeMessage,
+		})
+
+	case errors.As(err, &notFoundError):
+		response.WriteHeader(http.StatusNotFound)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: notFoundError.SafeMessage,
+		})
+
+	case errors.As(err, &preconditionFailedError):
+		response.WriteHeader(http.StatusPreconditionFailed)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: preconditionFailedError.SafeMessage,
+		})
+
+	case errors.As(err, &userError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: userError.SafeMessage,
+		})
+
+	case errors.As(err, &systemError):
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: systemError.SafeMessage,
+		})
+
+	case err != nil:
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: "Internal server error, please try again later",
+		})
+	}
+}
+
+// RenderableGroup defines what should be returned to a user for a
+// group.
+type RenderableGroup struct {
+	Name       string          `json:"name"`
+	Version    int64           `json:"version"`
+	CreatedOn  renderable.Time `json:"createdOn"`
+	ModifiedOn renderable.Time `json:"modifiedOn"`
+}
+
+// NewRenderableGroup creates a renderable group from a backend group
+// instance.
+func NewRenderableGroup(group *Group) RenderableGroup {
+	return RenderableGroup{
+		Name:       group.Name(),
+		Version:    group.Version(),
+		CreatedOn:  renderable.Time(group.CreatedOn()),
+		ModifiedOn: renderable.Time(group.ModifiedOn()),
+	}
+}
+
+// Render provides a hook to customize the render process.
+func (renderableGroup *RenderableGroup) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*RenderableGroup)(nil)
+
+// RenderableMember defines what should be returned to a user for a
+// member of a group.
+type RenderableMember struct {
+	Kind    MemberKind      `json:"kind"`
+	Name    string          `json:"name"`
+	AddedOn renderable.Time `json:"addedOn"`
+}
+
+// NewRenderableMember creates a renderable member from a backend
+// member instance.
+func NewRenderableMember(member *Member) RenderableMember {
+	return RenderableMember{
+		Kind:    member.Kind(),
+		Name:    member.Name(),
+		AddedOn: renderable.Time(member.AddedOn()),
+	}
+}
+
+// Render provides a hook to customize the render process.
+func (renderableMember *RenderableMember) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*RenderableMember)(nil)
+
+// RenderableEffectiveGroup defines what should be returned to a user
+// for a group that a user belongs to.
+type RenderableEffectiveGroup struct {
+	Name   string   `json:"name"`
+	Direct bool     `json:"direct"`
+	Path   []string `json:"path"`
+}
+
+// NewRenderableEffectiveGroup creates a renderable effective group
+// from a backend effective group instance.
+func NewRenderableEffectiveGroup(effectiveGroup *EffectiveGroup) RenderableEffectiveGroup {
+	return RenderableEffectiveGroup{
+		Name:   effectiveGroup.Name(),
+		Direct: effectiveGroup.Direct(),
+		Path:   effectiveGroup.Path(),
+	}
+}
@@ -0,0 +1,14 @@
+INSERT INTO Groups (
+    ID,
+    Name,
+    CreatedOn,
+    ModifiedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $3
+)
+RETURNING ID, Name, Version, CreatedOn, ModifiedOn
+;
@@ -0,0 +1,11 @@
+SELECT
+    ID,
+    Name,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Groups
+WHERE
+    Name = $1
+LIMIT 1
+;
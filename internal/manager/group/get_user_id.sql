@@ -0,0 +1,7 @@
+SELECT
+    ID
+FROM Users
+WHERE
+    Username = $1
+LIMIT 1
+;
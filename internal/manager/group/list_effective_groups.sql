@@ -0,0 +1,34 @@
+-- Follows the user's memberships up through every group that contains
+-- one of their groups. Each group keeps the shortest chain of groups
+-- that leads to it, starting with the group the user was added to.
+WITH RECURSIVE Effective (GroupID, Path) AS (
+    SELECT
+        Groups.ID,
+        ARRAY[Groups.Name]::VARCHAR[]
+    FROM GroupMembers
+    JOIN Groups ON Groups.ID = GroupMembers.GroupID
+    WHERE
+        GroupMembers.UserID = $1
+    UNION ALL
+    SELECT
+        Groups.ID,
+        Effective.Path || Groups.Name
+    FROM Effective
+    JOIN GroupMembers ON GroupMembers.MemberGroupID = Effective.GroupID
+    JOIN Groups ON Groups.ID = GroupMembers.GroupID
+    WHERE
+        NOT Groups.Name = ANY(Effective.Path)
+)
+SELECT
+    Name,
+    Path
+FROM (
+    SELECT DISTINCT ON (Effective.GroupID)
+        Groups.Name,
+        Effective.Path
+    FROM Effective
+    JOIN Groups ON Groups.ID = Effective.GroupID
+    ORDER BY Effective.GroupID, array_length(Effective.Path, 1)
+) AS Shortest
+ORDER BY Name
+;
@@ -0,0 +1,7 @@
+SELECT
+    ID,
+    Name,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Groups
@@ -0,0 +1,24 @@
+SELECT
+    Kind,
+    MemberID,
+    Name,
+    CreatedOn
+FROM (
+    SELECT
+        GroupMembers.GroupID,
+        'user' AS Kind,
+        Users.ID AS MemberID,
+        Users.Username AS Name,
+        GroupMembers.CreatedOn
+    FROM GroupMembers
+    JOIN Users ON Users.ID = GroupMembers.UserID
+    UNION ALL
+    SELECT
+        GroupMembers.GroupID,
+        'group' AS Kind,
+        Groups.ID AS MemberID,
+        Groups.Name AS Name,
+        GroupMembers.CreatedOn
+    FROM GroupMembers
+    JOIN Groups ON Groups.ID = GroupMembers.MemberGroupID
+) AS Members
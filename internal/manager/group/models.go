@@ -0,0 +1,106 @@
+package group
+
+import (
+	"time"
+)
+
+// Group collects users and other groups so that they can be handled
+// together. The members of a nested group are also members of every
+// group that contains it.
+type Group struct {
+	id         string
+	name       string
+	version    int64
+	createdOn  time.Time
+	modifiedOn time.Time
+}
+
+// ID is the database ID of the group.
+func (group *Group) ID() string {
+	return group.id
+}
+
+// Name is the name of the group.
+func (group *Group) Name() string {
+	return group.name
+}
+
+// Version is incremented every time the group is changed. It is used
+// to detect conflicting updates.
+func (group *Group) Version() int64 {
+	return group.version
+}
+
+// CreatedOn is the date and time that the group was created on.
+func (group *Group) CreatedOn() time.Time {
+	return group.createdOn
+}
+
+// ModifiedOn is the date and time that the group was last modified on.
+func (group *Group) ModifiedOn() time.Time {
+	return group.modifiedOn
+}
+
+// MemberKind tells what a member of a group is.
+type MemberKind string
+
+const (
+	// MemberKindUser is a user that was added to the group.
+	MemberKindUser MemberKind = "user"
+
+	// MemberKindGroup is a group that was nested in the group.
+	MemberKindGroup MemberKind = "group"
+)
+
+// Member is a user or a group that was added directly to a group.
+type Member struct {
+	kind    MemberKind
+	id      string
+	name    string
+	addedOn time.Time
+}
+
+// Kind tells whether the member is a user or a group.
+func (member *Member) Kind() MemberKind {
+	return member.kind
+}
+
+// ID is the database ID of the user or group.
+func (member *Member) ID() string {
+	return member.id
+}
+
+// Name is the username of the user or the name of the group.
+func (member *Member) Name() string {
+	return member.name
+}
+
+// AddedOn is the date and time that the member was added to the group.
+func (member *Member) AddedOn() time.Time {
+	return member.addedOn
+}
+
+// EffectiveGroup is a group that a user belongs to, either directly or
+// through the groups nested in it.
+type EffectiveGroup struct {
+	name string
+	path []string
+}
+
+// Name is the name of the group.
+func (effectiveGroup *EffectiveGroup) Name() string {
+	return effectiveGroup.name
+}
+
+// Path explains why the user belongs to the group. It starts with the
+// group that the user was added to, followed by each group that
+// contains the one before it, and ends with the group itself.
+func (effectiveGroup *EffectiveGroup) Path() []string {
+	return effectiveGroup.path
+}
+
+// Direct tells whether the user was added to the group itself rather
+// than to a group nested in it.
+func (effectiveGroup *EffectiveGroup) Direct() bool {
+	return len(effectiveGroup.path) == 1
+}
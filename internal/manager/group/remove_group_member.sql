@@ -0,0 +1,6 @@
+DELETE FROM GroupMembers
+WHERE
+    GroupID = $1
+    AND MemberGroupID = (SELECT ID FROM Groups WHERE Name = $2)
+RETURNING MemberGroupID
+;
@@ -0,0 +1,6 @@
+DELETE FROM GroupMembers
+WHERE
+    GroupID = $1
+    AND UserID = (SELECT ID FROM Users WHERE Username = $2)
+RETURNING UserID
+;
@@ -0,0 +1,616 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxNameLength = 64
+
+	// nestingLockID is the advisory lock held while a group is nested
+	// in another so that two requests can't create a cycle between
+	// them.
+	nestingLockID = 0x6c65792d677270
+)
+
+var (
+	groupNameRegex = regexp.MustCompile(`^\w[-\w]+$`)
+
+	//go:embed create_group.sql
+	createGroupSQL string
+
+	//go:embed get_group_by_name.sql
+	getGroupByNameSQL string
+
+	//go:embed get_group_by_id.sql
+	getGroupByIDSQL string
+
+	//go:embed list_groups.sql
+	listGroupsSQL string
+
+	//go:embed delete_group.sql
+	deleteGroupSQL string
+
+	//go:embed add_user_member.sql
+	addUserMemberSQL string
+
+	//go:embed remove_user_member.sql
+	removeUserMemberSQL string
+
+	//go:embed add_group_member.sql
+	addGroupMemberSQL string
+
+	//go:embed remove_group_member.sql
+	removeGroupMemberSQL string
+
+	//go:embed list_members.sql
+	listMembersSQL string
+
+	//go:embed lock_nesting.sql
+	lockNestingSQL string
+
+	//go:embed contains_group.sql
+	containsGroupSQL string
+
+	//go:embed get_user_id.sql
+	getUserIDSQL string
+
+	//go:embed list_effective_groups.sql
+	listEffectiveGroupsSQL string
+
+	listGroupsColumns = listing.Columns{
+		ID:        "ID",
+		Name:      "Name",
+		CreatedOn: "CreatedOn",
+	}
+
+	listMembersColumns = listing.Columns{
+		ID:        "MemberID",
+		Name:      "Name",
+		CreatedOn: "CreatedOn",
+	}
+)
+
+// Service provides methods for working with groups and their members.
+// Memberships are resolved whenever they are asked for, so changes to
+// a group apply to everything nested in it right away.
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates a new group service.
+func NewService(db *sql.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// CreateGroupOpts gives the options for creating a new group.
+type CreateGroupOpts struct {
+	Name string
+}
+
+// Validate checks that the group creation options are valid.
+func (opts *CreateGroupOpts) Validate() error {
+	if !groupNameRegex.MatchString(opts.Name) || len(opts.Name) > maxNameLength {
+		return fmt.Errorf("Invalid group name '%s'", opts.Name)
+	}
+
+	return nil
+}
+
+// CreateGroup creates a new group without any members.
+func (service *Service) CreateGroup(ctx context.Context, opts CreateGroupOpts) (*Group, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to create group: %v", err)
+	}
+
+	group, err := scanGroup(service.db.QueryRowContext(
+		ctx,
+		createGroupSQL,
+		uuid.NewString(),
+		opts.Name,
+		time.Now().UTC(),
+	))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			errorName := pqErr.Code.Name()
+			constraint := pqErr.Constraint
+			if errorName == "unique_violation" && constraint == "groups_name_key" {
+				return nil, errortypes.NewValidationError("Group name is already taken")
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new group due to a system error",
+			UnsafeMessage: "Unable to create new group due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return group, nil
+}
+
+// GetGroupByName fetches a group by its name.
+func (service *Service) GetGroupByName(ctx context.Context, name string) (*Group, error) {
+	group, err := scanGroup(service.db.QueryRowContext(ctx, getGroupByNameSQL, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a group with that name",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get group by name due to a system error",
+			UnsafeMessage: "Unable to get group by name due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return group, nil
+}
+
+// GetGroupByID fetches a group by its database ID.
+func (service *Service) GetGroupByID(ctx context.Context, id string) (*Group, error) {
+	group, err := scanGroup(service.db.QueryRowContext(ctx, getGroupByIDSQL, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a group with that ID",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get group by ID due to a system error",
+			UnsafeMessage: "Unable to get group by ID due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return group, nil
+}
+
+// ListGroups retrieves a page of groups.
+func (service *Service) ListGroups(ctx context.Context, params listing.Params) (listing.Page[Group], error) {
+	query, args, err := params.SQL(listGroupsSQL, listGroupsColumns, nil, nil)
+	if err != nil {
+		return listing.Page[Group]{}, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return listing.Page[Group]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list groups due to a system error",
+			UnsafeMessage: "Unable to list groups due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	groups := []Group{}
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return listing.Page[Group]{}, errortypes.SystemError{
+				SafeMessage:   "Unable to list groups due to a system error",
+				UnsafeMessage: "Unable to read group row",
+				WrappedError:  err,
+			}
+		}
+
+		groups = append(groups, *group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return listing.Page[Group]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list groups due to a system error",
+			UnsafeMessage: "Unable to iterate over group rows",
+			WrappedError:  err,
+		}
+	}
+
+	return listing.NewPage(params, groups, func(group *Group) listing.Key {
+		return listing.Key{
+			ID:        group.ID(),
+			Name:      group.Name(),
+			CreatedOn: group.CreatedOn(),
+		}
+	}), nil
+}
+
+// DeleteGroup removes a group along with its memberships, including
+// its membership of other groups. The delete is limited to the
+// expected versions of the group unless they are nil.
+func (service *Service) DeleteGroup(ctx context.Context, name string, expectedVersions []int64) error {
+	_, err := scanGroup(service.db.QueryRowContext(ctx, deleteGroupSQL, name, pq.Array(expectedVersions)))
+	if err == sql.ErrNoRows {
+		return service.explainMissingGroup(ctx, name)
+	}
+
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete group due to a system error",
+			UnsafeMessage: "Unable to delete group due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// AddUser makes a user a member of a group. Adding a user that
+// already is a member changes nothing.
+func (service *Service) AddUser(ctx context.Context, groupName string, username string) (*Member, error) {
+	group, err := service.GetGroupByName(ctx, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	member := Member{kind: MemberKindUser}
+	err = service.db.QueryRowContext(
+		ctx,
+		addUserMemberSQL,
+		group.ID(),
+		username,
+		time.Now().UTC(),
+	).Scan(
+		&member.id,
+		&member.name,
+		&member.addedOn,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a user with that name",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, explainAddMemberError(err)
+	}
+
+	return &member, nil
+}
+
+// RemoveUser takes a user out of a group. The user stays a member of
+// the group if they also belong to a group nested in it.
+func (service *Service) RemoveUser(ctx context.Context, groupName string, username string) error {
+	group, err := service.GetGroupByName(ctx, groupName)
+	if err != nil {
+		return err
+	}
+
+	var userID string
+	err = service.db.QueryRowContext(ctx, removeUserMemberSQL, group.ID(), username).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "The user isn't a member of the group",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to remove member due to a system error",
+			UnsafeMessage: "Unable to remove member due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// AddGroup nests a group in another one, making all of its members
+// members of the other group as well. Groups can't contain themselves,
+// not even through other groups. Adding a group that already is a
+// member changes nothing.
+func (service *Service) AddGroup(ctx context.Context, groupName string, memberName string) (*Member, error) {
+	group, err := service.GetGroupByName(ctx, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	memberGroup, err := service.GetGroupByName(ctx, memberName)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to add member due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, lockNestingSQL, nestingLockID); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to add member due to a system error",
+			UnsafeMessage: "Unable to lock group nesting",
+			WrappedError:  err,
+		}
+	}
+
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, containsGroupSQL, memberGroup.ID(), group.ID()).Scan(&cycle); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to add member due to a system error",
+			UnsafeMessage: "Unable to check for nesting cycles",
+			WrappedError:  err,
+		}
+	}
+
+	if cycle {
+		return nil, errortypes.NewValidationError(
+			"Unable to add member: Group '%s' already contains group '%s'",
+			memberGroup.Name(),
+			group.Name(),
+		)
+	}
+
+	member := Member{kind: MemberKindGroup}
+	err = tx.QueryRowContext(
+		ctx,
+		addGroupMemberSQL,
+		group.ID(),
+		memberGroup.ID(),
+		time.Now().UTC(),
+	).Scan(
+		&member.id,
+		&member.name,
+		&member.addedOn,
+	)
+	if err != nil {
+		return nil, explainAddMemberError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to add member due to a system error",
+			UnsafeMessage: "Unable to commit transaction",
+			WrappedError:  err,
+		}
+	}
+
+	return &member, nil
+}
+
+// RemoveGroup takes a nested group out of a group.
+func (service *Service) RemoveGroup(ctx context.Context, groupName string, memberName string) error {
+	group, err := service.GetGroupByName(ctx, groupName)
+	if err != nil {
+		return err
+	}
+
+	var memberGroupID string
+	err = service.db.QueryRowContext(ctx, removeGroupMemberSQL, group.ID(), memberName).Scan(&memberGroupID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "The group isn't a member of the group",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to remove member due to a system error",
+			UnsafeMessage: "Unable to remove member due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// ListMembers retrieves a page of the users and groups that were
+// added directly to a group. Members are named and sorted by their
+// username or group name and their creation date is when they were
+// added.
+func (service *Service) ListMembers(
+	ctx context.Context,
+	groupName string,
+	params listing.Params,
+) (listing.Page[Member], error) {
+	group, err := service.GetGroupByName(ctx, groupName)
+	if err != nil {
+		return listing.Page[Member]{}, err
+	}
+
+	query, args, err := params.SQL(
+		listMembersSQL,
+		listMembersColumns,
+		[]string{"GroupID = $1"},
+		[]any{group.ID()},
+	)
+	if err != nil {
+		return listing.Page[Member]{}, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return listing.Page[Member]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list members due to a system error",
+			UnsafeMessage: "Unable to list members due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	members := []Member{}
+	for rows.Next() {
+		var member Member
+		if err := rows.Scan(&member.kind, &member.id, &member.name, &member.addedOn); err != nil {
+			return listing.Page[Member]{}, errortypes.SystemError{
+				SafeMessage:   "Unable to list members due to a system error",
+				UnsafeMessage: "Unable to read member row",
+				WrappedError:  err,
+			}
+		}
+
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return listing.Page[Member]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list members due to a system error",
+			UnsafeMessage: "Unable to iterate over member rows",
+			WrappedError:  err,
+		}
+	}
+
+	return listing.NewPage(params, members, func(member *Member) listing.Key {
+		return listing.Key{
+			ID:        member.ID(),
+			Name:      member.Name(),
+			CreatedOn: member.AddedOn(),
+		}
+	}), nil
+}
+
+// ListEffectiveGroups gives every group that a user belongs to, sorted
+// by name, along with the chain of groups that explains each one.
+// Users that belong to a group in several ways are given the shortest
+// chain.
+func (service *Service) ListEffectiveGroups(ctx context.Context, username string) ([]EffectiveGroup, error) {
+	var userID string
+	if err := service.db.QueryRowContext(ctx, getUserIDSQL, username).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a user with that name",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list effective groups due to a system error",
+			UnsafeMessage: "Unable to get user by name",
+			WrappedError:  err,
+		}
+	}
+
+	rows, err := service.db.QueryContext(ctx, listEffectiveGroupsSQL, userID)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list effective groups due to a system error",
+			UnsafeMessage: "Unable to list effective groups due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	effectiveGroups := []EffectiveGroup{}
+	for rows.Next() {
+		var effectiveGroup EffectiveGroup
+		if err := rows.Scan(&effectiveGroup.name, pq.Array(&effectiveGroup.path)); err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to list effective groups due to a system error",
+				UnsafeMessage: "Unable to read effective group row",
+				WrappedError:  err,
+			}
+		}
+
+		effectiveGroups = append(effectiveGroups, effectiveGroup)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list effective groups due to a system error",
+			UnsafeMessage: "Unable to iterate over effective group rows",
+			WrappedError:  err,
+		}
+	}
+
+	return effectiveGroups, nil
+}
+
+// explainMissingGroup figures out why a conditional change didn't
+// match any rows, either because the group doesn't exist or because it
+// was at a different version.
+func (service *Service) explainMissingGroup(ctx context.Context, name string) error {
+	if _, err := service.GetGroupByName(ctx, name); err != nil {
+		return err
+	}
+
+	return errortypes.PreconditionFailedError{
+		UserError: errortypes.UserError{
+			SafeMessage: "The group has been changed since it was last read",
+		},
+	}
+}
+
+func explainAddMemberError(err error) error {
+	// The group was deleted after it was read.
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+		return errortypes.NotFoundError{
+			UserError: errortypes.UserError{
+				SafeMessage:  "Could not find a group with that name",
+				WrappedError: err,
+			},
+		}
+	}
+
+	return errortypes.SystemError{
+		SafeMessage:   "Unable to add member due to a system error",
+		UnsafeMessage: "Unable to add member due to a system error",
+		WrappedError:  err,
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanGroup(row rowScanner) (*Group, error) {
+	var group Group
+	err := row.Scan(
+		&group.id,
+		&group.name,
+		&group.version,
+		&group.createdOn,
+		&group.modifiedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
@@ -0,0 +1,145 @@
+package group_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/group"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/user"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var listParams = listing.Params{
+	Limit:     listing.MaxLimit,
+	SortField: listing.SortFieldCreatedOn,
+	SortOrder: listing.SortOrderAscending,
+}
+
+func TestNestedGroupsShouldApplyToTheirMembersImmediately(t *testing.T) {
+	ctx := context.Background()
+	groupService, userService := newTestServices(t)
+
+	username := newTestUser(ctx, t, userService)
+	team := newTestGroup(ctx, t, groupService)
+	department := newTestGroup(ctx, t, groupService)
+
+	_, err := groupService.AddUser(ctx, team, username)
+	require.Nil(t, err, "should be able to add a user")
+
+	member, err := groupService.AddGroup(ctx, department, team)
+	require.Nil(t, err, "should be able to nest a group")
+	require.Equal(t, group.MemberKindGroup, member.Kind())
+	require.Equal(t, team, member.Name())
+
+	members, err := groupService.ListMembers(ctx, department, listParams)
+	require.Nil(t, err, "should be able to list members")
+	require.Len(t, members.Items, 1, "should only list direct members")
+	require.Equal(t, team, members.Items[0].Name())
+
+	effectiveGroups, err := groupService.ListEffectiveGroups(ctx, username)
+	require.Nil(t, err, "should be able to list effective groups")
+	require.Len(t, effectiveGroups, 2)
+
+	paths := map[string][]string{}
+	for index := range effectiveGroups {
+		paths[effectiveGroups[index].Name()] = effectiveGroups[index].Path()
+	}
+
+	require.Equal(t, []string{team}, paths[team], "should be a direct member")
+	require.Equal(t, []string{team, department}, paths[department], "should explain the nested membership")
+
+	require.Nil(t, groupService.RemoveGroup(ctx, department, team), "should be able to remove a nested group")
+
+	effectiveGroups, err = groupService.ListEffectiveGroups(ctx, username)
+	require.Nil(t, err, "should be able to list effective groups")
+	require.Len(t, effectiveGroups, 1, "should no longer belong to the outer group")
+	require.Equal(t, team, effectiveGroups[0].Name())
+}
+
+func TestAddGroupShouldRejectCycles(t *testing.T) {
+	ctx := context.Background()
+	groupService, _ := newTestServices(t)
+
+	inner := newTestGroup(ctx, t, groupService)
+	middle := newTestGroup(ctx, t, groupService)
+	outer := newTestGroup(ctx, t, groupService)
+
+	_, err := groupService.AddGroup(ctx, middle, inner)
+	require.Nil(t, err, "should be able to nest a group")
+
+	_, err = groupService.AddGroup(ctx, outer, middle)
+	require.Nil(t, err, "should be able to nest a group")
+
+	var validationError errortypes.ValidationError
+
+	_, err = groupService.AddGroup(ctx, inner, outer)
+	require.True(t, errors.As(err, &validationError), "should not nest a group in one of its members")
+
+	_, err = groupService.AddGroup(ctx, inner, inner)
+	require.True(t, errors.As(err, &validationError), "should not nest a group in itself")
+
+	again, err := groupService.AddGroup(ctx, outer, middle)
+	require.Nil(t, err, "should be able to nest a group twice")
+	require.Equal(t, middle, again.Name())
+}
+
+func TestListEffectiveGroupsShouldRequireAnExistingUser(t *testing.T) {
+	groupService, _ := newTestServices(t)
+
+	_, err := groupService.ListEffectiveGroups(context.Background(), fmt.Sprintf("missing-%d", rng.RNG.Int63()))
+
+	var notFoundError errortypes.NotFoundError
+	require.True(t, errors.As(err, &notFoundError), "should not find a missing user")
+}
+
+func newTestServices(t *testing.T) (*group.Service, *user.Service) {
+	dbConfig := configuration.DBConfiguration{
+		Type: configuration.DBTypePostgres,
+		Postgres: configuration.PostgresConfiguration{
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Role:     "ley",
+			Password: "ley",
+			DBName:   "ley",
+			SSLMode:  "disable",
+		},
+	}
+
+	connectionString, err := dbConfig.ConnectionString()
+	require.Nil(t, err, "should be able to create a connection string")
+
+	db, err := sql.Open(string(dbConfig.Type), connectionString)
+	require.Nil(t, err, "should be able to open the database")
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return group.NewService(db), user.NewService(db)
+}
+
+func newTestUser(ctx context.Context, t *testing.T, userService *user.Service) string {
+	username := fmt.Sprintf("group-test-%d", rng.RNG.Int63())
+
+	_, err := userService.CreateUser(ctx, user.CreateUserOpts{Username: username})
+	require.Nil(t, err, "should be able to create a user")
+
+	return username
+}
+
+func newTestGroup(ctx context.Context, t *testing.T, groupService *group.Service) string {
+	name := fmt.Sprintf("group-test-%d", rng.RNG.Int63())
+
+	_, err := groupService.CreateGroup(ctx, group.CreateGroupOpts{Name: name})
+	require.Nil(t, err, "should be able to create a group")
+
+	return name
+}
@@ -0,0 +1,56 @@
+package manager
+
+import (
+	"database/sql"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/grpcapi"
+	"github.com/durandj/ley/internal/manager/limit"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/user"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// NewGRPCServer sets up the gRPC API on top of the same services as the
+// REST API. Watchers share the hub of the REST API so that every
+// replica only holds one connection listening for changes, and callers
+// share their budgets with the REST API through the same limiters.
+// Like the REST API, calls need a session once single sign-on is
+// configured.
+func NewGRPCServer(
+	db *sql.DB,
+	config *configuration.Configuration,
+	hub *notify.Hub,
+	limiters limit.Limiters,
+	logger *zap.Logger,
+) *grpc.Server {
+	organizationService := organization.NewService(db)
+	networkService := network.NewService(db, config.Network, organizationService)
+	userService := user.NewService(db)
+
+	var authService *auth.Service
+	if config.OIDC.Enabled() {
+		authService = auth.NewService(db, nil, userService, config.OIDC)
+	}
+
+	return grpcapi.NewServer(
+		logger,
+		authService,
+		limiters,
+		&grpcapi.UserServer{
+			UserService: userService,
+		},
+		&grpcapi.NetworkServer{
+			NetworkService: networkService,
+		},
+		&grpcapi.NodeServer{
+			NodeService: node.NewService(db, networkService, organizationService, config.Node, config.DNS),
+			Hub:         hub,
+		},
+	)
+}
@@ -0,0 +1,111 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/pkg/api/leyv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NodeSecretHeader is the response header that carries the secret of
+// a newly registered node.
+const NodeSecretHeader = "ley-node-secret"
+
+// nodeMethods are called by the nodes themselves, which authenticate
+// with their own secret instead of a session.
+var nodeMethods = map[string]bool{
+	leyv1.NodeService_RotateNodeKey_FullMethodName:   true,
+	leyv1.NodeService_GetNodeConfig_FullMethodName:   true,
+	leyv1.NodeService_RecordHeartbeat_FullMethodName: true,
+	leyv1.NodeService_ListPeerStats_FullMethodName:   true,
+	leyv1.NodeService_WatchNodeConfig_FullMethodName: true,
+}
+
+// unarySessionInterceptor rejects calls that don't carry a valid
+// session token in their authorization metadata, the same way the
+// REST API does once single sign-on is configured. The user of the
+// session becomes the principal of the call.
+func unarySessionInterceptor(authService *auth.Service) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		request any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if nodeMethods[info.FullMethod] {
+			return handler(ctx, request)
+		}
+
+		ctx, err := authenticate(ctx, authService)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, request)
+	}
+}
+
+func streamSessionInterceptor(authService *auth.Service) grpc.StreamServerInterceptor {
+	return func(
+		server any,
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if nodeMethods[info.FullMethod] {
+			return handler(server, stream)
+		}
+
+		ctx, err := authenticate(stream.Context(), authService)
+		if err != nil {
+			return err
+		}
+
+		return handler(server, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, authService *auth.Service) (context.Context, error) {
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "Not logged in")
+	}
+
+	session, err := authService.Authenticate(ctx, token)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return auth.ContextWithSession(ctx, session), nil
+}
+
+// contextStream is a stream whose context carries more than the one
+// it was opened with, like the session of the call.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *contextStream) Context() context.Context {
+	return stream.ctx
+}
+
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, authorization := range md.Get("authorization") {
+		if token := strings.TrimPrefix(authorization, "Bearer "); token != authorization {
+			return token
+		}
+	}
+
+	return ""
+}
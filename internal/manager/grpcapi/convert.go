@@ -0,0 +1,117 @@
+package grpcapi
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/pkg/api/leyv1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	sortFields = map[leyv1.SortField]listing.SortField{
+		leyv1.SortField_SORT_FIELD_CREATED_ON: listing.SortFieldCreatedOn,
+		leyv1.SortField_SORT_FIELD_NAME:       listing.SortFieldName,
+	}
+
+	sortOrders = map[leyv1.SortOrder]listing.SortOrder{
+		leyv1.SortOrder_SORT_ORDER_ASCENDING:  listing.SortOrderAscending,
+		leyv1.SortOrder_SORT_ORDER_DESCENDING: listing.SortOrderDescending,
+	}
+)
+
+// listParams converts the list options of a request into listing
+// parameters. The options are checked the same way as the query
+// parameters of the REST API so that both APIs page the same way.
+func listParams(options *leyv1.ListOptions) (listing.Params, error) {
+	query := url.Values{}
+	if options == nil {
+		return listing.ParseParams(query)
+	}
+
+	if options.GetLimit() != 0 {
+		query.Set("limit", strconv.Itoa(int(options.GetLimit())))
+	}
+
+	if options.GetCursor() != "" {
+		query.Set("cursor", options.GetCursor())
+	}
+
+	if options.GetNamePrefix() != "" {
+		query.Set("namePrefix", options.GetNamePrefix())
+	}
+
+	for name, timestamp := range map[string]*timestamppb.Timestamp{
+		"createdAfter":  options.GetCreatedAfter(),
+		"createdBefore": options.GetCreatedBefore(),
+	} {
+		if timestamp == nil {
+			continue
+		}
+
+		parsedTime, err := fromTimestamp(timestamp)
+		if err != nil {
+			return listing.Params{}, errortypes.NewWrappedValidationError(err, "Option '%s' is invalid", name)
+		}
+
+		query.Set(name, parsedTime.Format(time.RFC3339Nano))
+	}
+
+	for key, value := range options.GetLabels() {
+		query.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
+
+	if options.GetSort() != leyv1.SortField_SORT_FIELD_UNSPECIFIED {
+		sortField, ok := sortFields[options.GetSort()]
+		if !ok {
+			return listing.Params{}, errortypes.NewValidationError("Unknown sort field '%s'", options.GetSort())
+		}
+
+		query.Set("sort", string(sortField))
+	}
+
+	if options.GetOrder() != leyv1.SortOrder_SORT_ORDER_UNSPECIFIED {
+		sortOrder, ok := sortOrders[options.GetOrder()]
+		if !ok {
+			return listing.Params{}, errortypes.NewValidationError("Unknown sort order '%s'", options.GetOrder())
+		}
+
+		query.Set("order", string(sortOrder))
+	}
+
+	return listing.ParseParams(query)
+}
+
+// fromTimestamp converts a protobuf timestamp into a UTC time, making
+// sure that it is in range.
+func fromTimestamp(timestamp *timestamppb.Timestamp) (time.Time, error) {
+	if err := timestamp.CheckValid(); err != nil {
+		return time.Time{}, err
+	}
+
+	return timestamp.AsTime().UTC(), nil
+}
+
+// toOptionalTimestamp converts a time that might not be set into a
+// protobuf timestamp.
+func toOptionalTimestamp(value *time.Time) *timestamppb.Timestamp {
+	if value == nil {
+		return nil
+	}
+
+	return timestamppb.New(*value)
+}
+
+// expectedVersions converts the expected versions of a request into
+// the form the services use, where nil allows any version.
+func expectedVersions(versions []int64) []int64 {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	return versions
+}
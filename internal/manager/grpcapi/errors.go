@@ -0,0 +1,57 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ToStatus converts an error from the service layer into a gRPC status
+// error with the same safe message that the REST API would have given.
+func ToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
+	var unauthorizedError errortypes.UnauthorizedError
+	var userError errortypes.UserError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "The request was canceled")
+
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "The request took too long")
+
+	case errors.As(err, &validationError):
+		return status.Error(codes.InvalidArgument, validationError.SafeMessage)
+
+	case errors.As(err, &notFoundError):
+		return status.Error(codes.NotFound, notFoundError.SafeMessage)
+
+	case errors.As(err, &preconditionFailedError):
+		return status.Error(codes.FailedPrecondition, preconditionFailedError.SafeMessage)
+
+	case errors.As(err, &unauthorizedError):
+		return status.Error(codes.Unauthenticated, unauthorizedError.SafeMessage)
+
+	case errors.As(err, &userError):
+		return status.Error(codes.InvalidArgument, userError.SafeMessage)
+
+	case errors.As(err, &systemError):
+		return status.Error(codes.Internal, systemError.SafeMessage)
+
+	default:
+		return status.Error(codes.Internal, "Internal server error, please try again later")
+	}
+}
@@ -0,0 +1,112 @@
+package grpcapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/grpcapi"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatusShouldMapErrorTypesToCodes(t *testing.T) {
+	testCases := []struct {
+		name            string
+		err             error
+		expectedCode    codes.Code
+		expectedMessage string
+	}{
+		{
+			name:            "validation",
+			err:             errortypes.NewValidationError("Invalid user name '%s'", "!"),
+			expectedCode:    codes.InvalidArgument,
+			expectedMessage: "Invalid user name '!'",
+		},
+		{
+			name: "not found",
+			err: errortypes.NotFoundError{
+				UserError: errortypes.UserError{SafeMessage: "Could not find a user with that name"},
+			},
+			expectedCode:    codes.NotFound,
+			expectedMessage: "Could not find a user with that name",
+		},
+		{
+			name: "precondition failed",
+			err: errortypes.PreconditionFailedError{
+				UserError: errortypes.UserError{SafeMessage: "The user has changed"},
+			},
+			expectedCode:    codes.FailedPrecondition,
+			expectedMessage: "The user has changed",
+		},
+		{
+			name: "unauthorized",
+			err: errortypes.UnauthorizedError{
+				UserError: errortypes.UserError{SafeMessage: "The session has expired, please log in again"},
+			},
+			expectedCode:    codes.Unauthenticated,
+			expectedMessage: "The session has expired, please log in again",
+		},
+		{
+			name:            "user",
+			err:             errortypes.UserError{SafeMessage: "Bad request"},
+			expectedCode:    codes.InvalidArgument,
+			expectedMessage: "Bad request",
+		},
+		{
+			name: "wrapped not found",
+			err: fmt.Errorf("Unable to get node: %w", errortypes.NotFoundError{
+				UserError: errortypes.UserError{SafeMessage: "Could not find a node with that ID"},
+			}),
+			expectedCode:    codes.NotFound,
+			expectedMessage: "Could not find a node with that ID",
+		},
+		{
+			name: "system",
+			err: errortypes.SystemError{
+				SafeMessage:   "Unable to list users due to a system error",
+				UnsafeMessage: "connection refused",
+			},
+			expectedCode:    codes.Internal,
+			expectedMessage: "Unable to list users due to a system error",
+		},
+		{
+			name: "canceled",
+			err: errortypes.SystemError{
+				SafeMessage:  "Unable to list users due to a system error",
+				WrappedError: context.Canceled,
+			},
+			expectedCode:    codes.Canceled,
+			expectedMessage: "The request was canceled",
+		},
+		{
+			name:            "unknown",
+			err:             errors.New("secret details"),
+			expectedCode:    codes.Internal,
+			expectedMessage: "Internal server error, please try again later",
+		},
+		{
+			name:            "status",
+			err:             status.Error(codes.Unavailable, "Try again"),
+			expectedCode:    codes.Unavailable,
+			expectedMessage: "Try again",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			statusErr, ok := status.FromError(grpcapi.ToStatus(testCase.err))
+			require.True(t, ok, "should be a status error")
+			require.Equal(t, testCase.expectedCode, statusErr.Code())
+			require.Equal(t, testCase.expectedMessage, statusErr.Message())
+		})
+	}
+}
+
+func TestToStatusShouldLeaveNilAlone(t *testing.T) {
+	require.Nil(t, grpcapi.ToStatus(nil))
+}
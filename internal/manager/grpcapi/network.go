@@ -0,0 +1,244 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/pkg/api/leyv1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"inet.af/netaddr"
+)
+
+var topologyModes = map[network.TopologyMode]leyv1.TopologyMode{
+	network.TopologyModeFullMesh:    leyv1.TopologyMode_TOPOLOGY_MODE_FULL_MESH,
+	network.TopologyModeHubAndSpoke: leyv1.TopologyMode_TOPOLOGY_MODE_HUB_AND_SPOKE,
+	network.TopologyModeCustom:      leyv1.TopologyMode_TOPOLOGY_MODE_CUSTOM,
+}
+
+// NetworkServer handles the gRPC calls for network related API's. The
+// networks are those of the default organization.
+type NetworkServer struct {
+	leyv1.UnimplementedNetworkServiceServer
+
+	NetworkService *network.Service
+}
+
+// CreateNetwork creates a new managed network.
+func (server *NetworkServer) CreateNetwork(
+	ctx context.Context,
+	request *leyv1.CreateNetworkRequest,
+) (*leyv1.Network, error) {
+	opts := network.CreateNetworkOpts{
+		Name:          request.GetName(),
+		Labels:        request.GetLabels(),
+		AllowOverlap:  request.GetAllowOverlap(),
+		GenerateIPv6:  request.GetGenerateIpv6(),
+		PresharedKeys: request.GetPresharedKeys(),
+	}
+
+	var err error
+	if opts.IPv4CIDR, err = parsePrefix("IPv4", request.Ipv4Cidr); err != nil {
+		return nil, ToStatus(err)
+	}
+
+	if opts.IPv6CIDR, err = parsePrefix("IPv6", request.Ipv6Cidr); err != nil {
+		return nil, ToStatus(err)
+	}
+
+	if request.Ipv4PrefixLength != nil {
+		prefixLength := int(request.GetIpv4PrefixLength())
+		opts.IPv4PrefixLength = &prefixLength
+	}
+
+	if request.GetTopology() != nil {
+		topology, err := fromTopology(request.GetTopology())
+		if err != nil {
+			return nil, ToStatus(err)
+		}
+
+		opts.Topology = &topology
+	}
+
+	newNetwork, err := server.NetworkService.CreateNetwork(ctx, organization.DefaultName, opts)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNetwork(newNetwork), nil
+}
+
+// GetNetwork gets a network by its name.
+func (server *NetworkServer) GetNetwork(
+	ctx context.Context,
+	request *leyv1.GetNetworkRequest,
+) (*leyv1.Network, error) {
+	existingNetwork, err := server.NetworkService.GetNetworkByName(ctx, organization.DefaultName, request.GetName())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNetwork(existingNetwork), nil
+}
+
+// ListNetworks lists a page of networks.
+func (server *NetworkServer) ListNetworks(
+	ctx context.Context,
+	request *leyv1.ListNetworksRequest,
+) (*leyv1.ListNetworksResponse, error) {
+	params, err := listParams(request.GetOptions())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	page, err := server.NetworkService.ListNetworks(ctx, organization.DefaultName, params)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	networks := make([]*leyv1.Network, len(page.Items))
+	for index := range page.Items {
+		networks[index] = toNetwork(&page.Items[index])
+	}
+
+	return &leyv1.ListNetworksResponse{
+		Networks:   networks,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+// UpdateNetwork changes a network.
+func (server *NetworkServer) UpdateNetwork(
+	ctx context.Context,
+	request *leyv1.UpdateNetworkRequest,
+) (*leyv1.Network, error) {
+	opts := network.UpdateNetworkOpts{
+		Name:             request.NewName,
+		PresharedKeys:    request.PresharedKeys,
+		ExpectedVersions: expectedVersions(request.GetExpectedVersions()),
+	}
+
+	if request.GetLabels() != nil {
+		opts.Labels = request.GetLabels().GetValues()
+		if opts.Labels == nil {
+			opts.Labels = map[string]string{}
+		}
+	}
+
+	if request.GetTopology() != nil {
+		topology, err := fromTopology(request.GetTopology())
+		if err != nil {
+			return nil, ToStatus(err)
+		}
+
+		opts.Topology = &topology
+	}
+
+	updatedNetwork, err := server.NetworkService.UpdateNetwork(ctx, organization.DefaultName, request.GetName(), opts)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNetwork(updatedNetwork), nil
+}
+
+// DeleteNetwork removes a network.
+func (server *NetworkServer) DeleteNetwork(
+	ctx context.Context,
+	request *leyv1.DeleteNetworkRequest,
+) (*leyv1.DeleteNetworkResponse, error) {
+	err := server.NetworkService.DeleteNetwork(
+		ctx,
+		organization.DefaultName,
+		request.GetName(),
+		expectedVersions(request.GetExpectedVersions()),
+	)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return &leyv1.DeleteNetworkResponse{}, nil
+}
+
+func toNetwork(existingNetwork *network.Network) *leyv1.Network {
+	protoNetwork := &leyv1.Network{
+		Id:            existingNetwork.ID(),
+		Name:          existingNetwork.Name(),
+		AllowOverlap:  existingNetwork.AllowOverlap(),
+		Labels:        existingNetwork.Labels(),
+		Topology:      toTopology(existingNetwork.Topology()),
+		PresharedKeys: existingNetwork.PresharedKeys(),
+		Version:       existingNetwork.Version(),
+		CreatedOn:     timestamppb.New(existingNetwork.CreatedOn()),
+		ModifiedOn:    timestamppb.New(existingNetwork.ModifiedOn()),
+	}
+
+	if prefix := existingNetwork.IPv4CIDR(); prefix != nil {
+		ipv4CIDR := prefix.String()
+		protoNetwork.Ipv4Cidr = &ipv4CIDR
+	}
+
+	if prefix := existingNetwork.IPv6CIDR(); prefix != nil {
+		ipv6CIDR := prefix.String()
+		protoNetwork.Ipv6Cidr = &ipv6CIDR
+	}
+
+	return protoNetwork
+}
+
+func toTopology(topology network.Topology) *leyv1.Topology {
+	peerGroups := make([]*leyv1.PeerGroup, len(topology.PeerGroups))
+	for index, peerGroup := range topology.PeerGroups {
+		peerGroups[index] = &leyv1.PeerGroup{
+			Name:  peerGroup.Name,
+			Nodes: peerGroup.Nodes,
+		}
+	}
+
+	return &leyv1.Topology{
+		Mode:       topologyModes[topology.Mode],
+		Hubs:       topology.Hubs,
+		PeerGroups: peerGroups,
+	}
+}
+
+func fromTopology(topology *leyv1.Topology) (network.Topology, error) {
+	var mode network.TopologyMode
+	for topologyMode, protoMode := range topologyModes {
+		if protoMode == topology.GetMode() {
+			mode = topologyMode
+		}
+	}
+
+	if mode == "" {
+		return network.Topology{}, errortypes.NewValidationError("Unknown topology mode '%s'", topology.GetMode())
+	}
+
+	var peerGroups []network.PeerGroup
+	for _, peerGroup := range topology.GetPeerGroups() {
+		peerGroups = append(peerGroups, network.PeerGroup{
+			Name:  peerGroup.GetName(),
+			Nodes: peerGroup.GetNodes(),
+		})
+	}
+
+	return network.Topology{
+		Mode:       mode,
+		Hubs:       topology.GetHubs(),
+		PeerGroups: peerGroups,
+	}, nil
+}
+
+func parsePrefix(family string, rawPrefix *string) (*netaddr.IPPrefix, error) {
+	if rawPrefix == nil {
+		return nil, nil
+	}
+
+	prefix, err := netaddr.ParseIPPrefix(*rawPrefix)
+	if err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Invalid %s range '%s'", family, *rawPrefix)
+	}
+
+	return &prefix, nil
+}
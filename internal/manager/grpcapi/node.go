@@ -0,0 +1,456 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/durandj/ley/pkg/api/leyv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	nodeStatuses = map[node.Status]leyv1.NodeStatus{
+		node.StatusOnline:  leyv1.NodeStatus_NODE_STATUS_ONLINE,
+		node.StatusStale:   leyv1.NodeStatus_NODE_STATUS_STALE,
+		node.StatusOffline: leyv1.NodeStatus_NODE_STATUS_OFFLINE,
+	}
+
+	nodeEventTypes = map[node.EventType]leyv1.NodeEventType{
+		node.EventTypeRegistered:           leyv1.NodeEventType_NODE_EVENT_TYPE_REGISTERED,
+		node.EventTypeKeyRotated:           leyv1.NodeEventType_NODE_EVENT_TYPE_KEY_ROTATED,
+		node.EventTypeKeyRotationRequested: leyv1.NodeEventType_NODE_EVENT_TYPE_KEY_ROTATION_REQUESTED,
+		node.EventTypeRemoved:              leyv1.NodeEventType_NODE_EVENT_TYPE_REMOVED,
+		node.EventTypeExpired:              leyv1.NodeEventType_NODE_EVENT_TYPE_EXPIRED,
+		node.EventTypeReaped:               leyv1.NodeEventType_NODE_EVENT_TYPE_REAPED,
+	}
+)
+
+// NodeServer handles the gRPC calls for node related API's. Calls used
+// to manage nodes work with the nodes of the organization they name,
+// or of the default organization when they don't name one, while calls
+// made by the nodes themselves work with the organization of the node.
+type NodeServer struct {
+	leyv1.UnimplementedNodeServiceServer
+
+	NodeService *node.Service
+
+	// Hub tells watchers when the config of their node may have changed.
+	Hub *notify.Hub
+}
+
+// RegisterNode adds a node to a network. The secret of the new node is
+// sent in the NodeSecretHeader header of the response.
+func (server *NodeServer) RegisterNode(
+	ctx context.Context,
+	request *leyv1.RegisterNodeRequest,
+) (*leyv1.Node, error) {
+	opts := node.RegisterNodeOpts{
+		Organization: request.GetOrganization(),
+		Network:      request.GetNetwork(),
+		Name:         request.GetName(),
+		PublicKey:    request.GetPublicKey(),
+		Endpoint:     request.Endpoint,
+		Ephemeral:    request.GetEphemeral(),
+	}
+
+	if request.GetExpiresOn() != nil {
+		expiresOn, err := fromTimestamp(request.GetExpiresOn())
+		if err != nil {
+			return nil, ToStatus(errortypes.NewWrappedValidationError(err, "Invalid expiry"))
+		}
+
+		opts.ExpiresOn = &expiresOn
+	}
+
+	newNode, err := server.NodeService.RegisterNode(ctx, opts)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	// The message can't carry the secret so it is sent as a header
+	// instead.
+	if err := grpc.SetHeader(ctx, metadata.Pairs(NodeSecretHeader, newNode.Secret())); err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNode(newNode), nil
+}
+
+// GetNode gets a node by its ID.
+func (server *NodeServer) GetNode(
+	ctx context.Context,
+	request *leyv1.GetNodeRequest,
+) (*leyv1.Node, error) {
+	existingNode, err := server.NodeService.GetNode(ctx, request.GetOrganization(), request.GetId())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNode(existingNode), nil
+}
+
+// ListNodes lists a page of nodes.
+func (server *NodeServer) ListNodes(
+	ctx context.Context,
+	request *leyv1.ListNodesRequest,
+) (*leyv1.ListNodesResponse, error) {
+	params, err := listParams(request.GetOptions())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	filter := node.ListNodesFilter{
+		Organization: request.GetOrganization(),
+		Network:      request.GetNetwork(),
+		PublicKey:    request.GetPublicKey(),
+	}
+
+	if request.GetStatus() != leyv1.NodeStatus_NODE_STATUS_UNSPECIFIED {
+		filter.Status, err = fromNodeStatus(request.GetStatus())
+		if err != nil {
+			return nil, ToStatus(err)
+		}
+	}
+
+	page, err := server.NodeService.ListNodes(ctx, params, filter)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	nodes := make([]*leyv1.Node, len(page.Items))
+	for index := range page.Items {
+		nodes[index] = toNode(&page.Items[index])
+	}
+
+	return &leyv1.ListNodesResponse{
+		Nodes:      nodes,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+// DeleteNode removes a node from its network.
+func (server *NodeServer) DeleteNode(
+	ctx context.Context,
+	request *leyv1.DeleteNodeRequest,
+) (*leyv1.DeleteNodeResponse, error) {
+	err := server.NodeService.DeleteNode(
+		ctx,
+		request.GetOrganization(),
+		request.GetId(),
+		expectedVersions(request.GetExpectedVersions()),
+	)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return &leyv1.DeleteNodeResponse{}, nil
+}
+
+// RotateNodeKey switches a node to a new public key.
+func (server *NodeServer) RotateNodeKey(
+	ctx context.Context,
+	request *leyv1.RotateNodeKeyRequest,
+) (*leyv1.Node, error) {
+	if _, err := server.authenticateNode(ctx, request.GetId()); err != nil {
+		return nil, err
+	}
+
+	rotatedNode, err := server.NodeService.RotateNodeKey(ctx, request.GetId(), node.RotateNodeKeyOpts{
+		PublicKey:        request.GetPublicKey(),
+		ExpectedVersions: expectedVersions(request.GetExpectedVersions()),
+	})
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNode(rotatedNode), nil
+}
+
+// RequestKeyRotation asks a node to rotate its key.
+func (server *NodeServer) RequestKeyRotation(
+	ctx context.Context,
+	request *leyv1.RequestKeyRotationRequest,
+) (*leyv1.Node, error) {
+	requestedNode, err := server.NodeService.RequestKeyRotation(
+		ctx,
+		request.GetOrganization(),
+		request.GetId(),
+		expectedVersions(request.GetExpectedVersions()),
+	)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNode(requestedNode), nil
+}
+
+// GetNodeConfig gets the WireGuard configuration of a node.
+func (server *NodeServer) GetNodeConfig(
+	ctx context.Context,
+	request *leyv1.GetNodeConfigRequest,
+) (*leyv1.NodeConfig, error) {
+	nodeOrganization, err := server.authenticateNode(ctx, request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := server.NodeService.GetNodeConfig(ctx, nodeOrganization, request.GetId())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNodeConfig(config), nil
+}
+
+// ListNodeEvents lists the events in a node's network.
+func (server *NodeServer) ListNodeEvents(
+	ctx context.Context,
+	request *leyv1.ListNodeEventsRequest,
+) (*leyv1.ListNodeEventsResponse, error) {
+	limit := node.DefaultEventLimit
+	if request.GetLimit() != 0 {
+		limit = int(request.GetLimit())
+	}
+
+	events, err := server.NodeService.ListNodeEvents(
+		ctx,
+		request.GetOrganization(),
+		request.GetId(),
+		request.GetAfter(),
+		limit,
+	)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	protoEvents := make([]*leyv1.NodeEvent, len(events))
+	for index := range events {
+		event := &events[index]
+		protoEvents[index] = &leyv1.NodeEvent{
+			Id:        event.ID(),
+			NodeId:    event.NodeID(),
+			NodeName:  event.NodeName(),
+			Type:      nodeEventTypes[event.Type()],
+			CreatedOn: timestamppb.New(event.CreatedOn()),
+		}
+	}
+
+	return &leyv1.ListNodeEventsResponse{
+		Events: protoEvents,
+	}, nil
+}
+
+// RecordHeartbeat marks a node as seen along with what it reported
+// about its peers.
+func (server *NodeServer) RecordHeartbeat(
+	ctx context.Context,
+	request *leyv1.RecordHeartbeatRequest,
+) (*leyv1.Node, error) {
+	if _, err := server.authenticateNode(ctx, request.GetId()); err != nil {
+		return nil, err
+	}
+
+	peers := make([]node.PeerHeartbeat, len(request.GetPeers()))
+	for index, peer := range request.GetPeers() {
+		peers[index] = node.PeerHeartbeat{
+			PublicKey:     peer.GetPublicKey(),
+			ReceiveBytes:  peer.GetReceiveBytes(),
+			TransmitBytes: peer.GetTransmitBytes(),
+			Endpoint:      peer.Endpoint,
+		}
+
+		if peer.GetLatestHandshakeOn() != nil {
+			latestHandshakeOn, err := fromTimestamp(peer.GetLatestHandshakeOn())
+			if err != nil {
+				return nil, ToStatus(errortypes.NewWrappedValidationError(err, "Invalid latest handshake"))
+			}
+
+			peers[index].LatestHandshakeOn = &latestHandshakeOn
+		}
+	}
+
+	seenNode, err := server.NodeService.RecordHeartbeat(ctx, request.GetId(), node.HeartbeatOpts{
+		Peers: peers,
+	})
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toNode(seenNode), nil
+}
+
+// ListPeerStats lists what a node last reported about its peers.
+func (server *NodeServer) ListPeerStats(
+	ctx context.Context,
+	request *leyv1.ListPeerStatsRequest,
+) (*leyv1.ListPeerStatsResponse, error) {
+	if _, err := server.authenticateNode(ctx, request.GetId()); err != nil {
+		return nil, err
+	}
+
+	peerStats, err := server.NodeService.ListPeerStats(ctx, request.GetId())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	peers := make([]*leyv1.PeerStats, len(peerStats))
+	for index, stats := range peerStats {
+		peers[index] = &leyv1.PeerStats{
+			Name:              stats.Name,
+			PublicKey:         stats.PublicKey,
+			LatestHandshakeOn: toOptionalTimestamp(stats.LatestHandshakeOn),
+			ReceiveBytes:      stats.ReceiveBytes,
+			TransmitBytes:     stats.TransmitBytes,
+			Endpoint:          stats.Endpoint,
+			ReportedOn:        timestamppb.New(stats.ReportedOn),
+		}
+	}
+
+	return &leyv1.ListPeerStatsResponse{
+		Peers: peers,
+	}, nil
+}
+
+// WatchNodeConfig streams the config of a node whenever it changes.
+// It shares its behaviour with the server-sent events of the REST API.
+func (server *NodeServer) WatchNodeConfig(
+	request *leyv1.WatchNodeConfigRequest,
+	stream leyv1.NodeService_WatchNodeConfigServer,
+) error {
+	nodeOrganization, err := server.authenticateNode(stream.Context(), request.GetId())
+	if err != nil {
+		return err
+	}
+
+	err = node.WatchConfig(
+		stream.Context(),
+		server.NodeService,
+		server.Hub,
+		nodeOrganization,
+		request.GetId(),
+		request.GetLastRevision(),
+		&streamWatcher{stream: stream},
+	)
+
+	return ToStatus(err)
+}
+
+// streamWatcher sends the config of a watched node over a gRPC stream.
+// HTTP/2 keeps the stream alive so there is nothing to do while it is
+// quiet.
+type streamWatcher struct {
+	stream leyv1.NodeService_WatchNodeConfigServer
+}
+
+func (watcher *streamWatcher) Started() error {
+	return nil
+}
+
+func (watcher *streamWatcher) Config(config *node.Config) error {
+	return watcher.stream.Send(&leyv1.WatchNodeConfigResponse{
+		Event: &leyv1.WatchNodeConfigResponse_Config{
+			Config: toNodeConfig(config),
+		},
+	})
+}
+
+func (watcher *streamWatcher) KeepAlive() error {
+	return nil
+}
+
+func (watcher *streamWatcher) Removed(removedNode *node.Node) error {
+	return watcher.stream.Send(&leyv1.WatchNodeConfigResponse{
+		Event: &leyv1.WatchNodeConfigResponse_Removed{
+			Removed: toNode(removedNode),
+		},
+	})
+}
+
+var _ node.ConfigWatcher = (*streamWatcher)(nil)
+
+func toNode(existingNode *node.Node) *leyv1.Node {
+	protoNode := &leyv1.Node{
+		Id:                   existingNode.ID(),
+		Network:              existingNode.Network(),
+		Name:                 existingNode.Name(),
+		PublicKey:            existingNode.PublicKey(),
+		PreviousPublicKey:    existingNode.PreviousPublicKey(),
+		PreviousKeyExpiresOn: toOptionalTimestamp(existingNode.PreviousKeyExpiresOn()),
+		KeyRotatedOn:         timestamppb.New(existingNode.KeyRotatedOn()),
+		KeyExpiresOn:         toOptionalTimestamp(existingNode.KeyExpiresOn()),
+		KeyRotationDue:       existingNode.KeyRotationDue(time.Now()),
+		Endpoint:             existingNode.Endpoint(),
+		Status:               nodeStatuses[existingNode.Status()],
+		LastSeenOn:           toOptionalTimestamp(existingNode.LastSeenOn()),
+		Ephemeral:            existingNode.Ephemeral(),
+		ExpiresOn:            toOptionalTimestamp(existingNode.ExpiresOn()),
+		Version:              existingNode.Version(),
+		CreatedOn:            timestamppb.New(existingNode.CreatedOn()),
+		ModifiedOn:           timestamppb.New(existingNode.ModifiedOn()),
+	}
+
+	if address := existingNode.IPv4Address(); address != nil {
+		ipv4Address := address.String()
+		protoNode.Ipv4Address = &ipv4Address
+	}
+
+	if address := existingNode.IPv6Address(); address != nil {
+		ipv6Address := address.String()
+		protoNode.Ipv6Address = &ipv6Address
+	}
+
+	return protoNode
+}
+
+func toNodeConfig(config *node.Config) *leyv1.NodeConfig {
+	peers := make([]*leyv1.PeerConfig, len(config.Peers))
+	for index, peer := range config.Peers {
+		allowedIPs := make([]string, len(peer.AllowedIPs))
+		for prefixIndex, prefix := range peer.AllowedIPs {
+			allowedIPs[prefixIndex] = prefix.String()
+		}
+
+		peers[index] = &leyv1.PeerConfig{
+			Name:         peer.Name,
+			PublicKey:    peer.PublicKey,
+			Endpoint:     peer.Endpoint,
+			AllowedIps:   allowedIPs,
+			PresharedKey: peer.PresharedKey,
+		}
+	}
+
+	return &leyv1.NodeConfig{
+		Node:           toNode(config.Node),
+		Peers:          peers,
+		KeyRotationDue: config.KeyRotationDue,
+		LastEventId:    config.LastEventID,
+		Revision:       config.Revision(),
+	}
+}
+
+func fromNodeStatus(status leyv1.NodeStatus) (node.Status, error) {
+	for nodeStatus, protoStatus := range nodeStatuses {
+		if protoStatus == status {
+			return nodeStatus, nil
+		}
+	}
+
+	return "", errortypes.NewValidationError("Unknown node status '%s'", status)
+}
+
+// authenticateNode checks that a call carries the secret of the node
+// it is for as a bearer token and gives the name of the organization
+// the node belongs to.
+func (server *NodeServer) authenticateNode(ctx context.Context, id string) (string, error) {
+	nodeOrganization, err := server.NodeService.AuthenticateNode(ctx, id, bearerToken(ctx))
+	if err != nil {
+		return "", ToStatus(err)
+	}
+
+	return nodeOrganization, nil
+}
@@ -0,0 +1,164 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/limit"
+	"github.com/durandj/ley/pkg/api/leyv1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// expensiveMethods take a lot more work than other calls so they get a
+// budget of their own, like the matching REST endpoints.
+var expensiveMethods = []string{
+	leyv1.NodeService_RegisterNode_FullMethodName,
+	leyv1.NodeService_RotateNodeKey_FullMethodName,
+}
+
+// NewServer creates a gRPC server with the Ley services registered.
+// Every call is logged and panics are turned into internal errors
+// instead of taking the manager down. Calls have to carry a session
+// token when an auth service is given. Callers are limited by the
+// same limiters as the REST API.
+func NewServer(
+	logger *zap.Logger,
+	authService *auth.Service,
+	limiters limit.Limiters,
+	userServer *UserServer,
+	networkServer *NetworkServer,
+	nodeServer *NodeServer,
+) *grpc.Server {
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		unaryLoggingInterceptor(logger),
+		unaryRecoveryInterceptor(logger),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		streamLoggingInterceptor(logger),
+		streamRecoveryInterceptor(logger),
+	}
+
+	// Callers are limited by their IP address before anything is
+	// looked up for them, like their session.
+	if limiters.IP != nil {
+		unaryInterceptors = append(unaryInterceptors, limit.UnaryCalls(limiters.IP, limit.CallByIP))
+		streamInterceptors = append(streamInterceptors, limit.StreamCalls(limiters.IP, limit.CallByIP))
+	}
+
+	if authService != nil {
+		unaryInterceptors = append(unaryInterceptors, unarySessionInterceptor(authService))
+		streamInterceptors = append(streamInterceptors, streamSessionInterceptor(authService))
+	}
+
+	if limiters.Principal != nil {
+		unaryInterceptors = append(unaryInterceptors, limit.UnaryCalls(limiters.Principal, limit.CallByPrincipal))
+		streamInterceptors = append(streamInterceptors, limit.StreamCalls(limiters.Principal, limit.CallByPrincipal))
+	}
+
+	if limiters.Expensive != nil {
+		unaryInterceptors = append(
+			unaryInterceptors,
+			limit.UnaryCalls(limiters.Expensive, limit.CallByPrincipalOrIP, expensiveMethods...),
+		)
+		streamInterceptors = append(
+			streamInterceptors,
+			limit.StreamCalls(limiters.Expensive, limit.CallByPrincipalOrIP, expensiveMethods...),
+		)
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+
+	leyv1.RegisterUserServiceServer(server, userServer)
+	leyv1.RegisterNetworkServiceServer(server, networkServer)
+	leyv1.RegisterNodeServiceServer(server, nodeServer)
+
+	return server
+}
+
+func unaryLoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		request any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		startTime := time.Now()
+		response, err := handler(ctx, request)
+		logCall(logger, info.FullMethod, startTime, err)
+
+		return response, err
+	}
+}
+
+func streamLoggingInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		server any,
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		startTime := time.Now()
+		err := handler(server, stream)
+		logCall(logger, info.FullMethod, startTime, err)
+
+		return err
+	}
+}
+
+func logCall(logger *zap.Logger, method string, startTime time.Time, err error) {
+	logger.Info(
+		"Handled gRPC call",
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(startTime)),
+	)
+}
+
+func unaryRecoveryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		request any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (response any, err error) {
+		defer recoverPanic(logger, info.FullMethod, &err)
+
+		return handler(ctx, request)
+	}
+}
+
+func streamRecoveryInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		server any,
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) (err error) {
+		defer recoverPanic(logger, info.FullMethod, &err)
+
+		return handler(server, stream)
+	}
+}
+
+func recoverPanic(logger *zap.Logger, method string, err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	logger.Error(
+		"gRPC call panicked",
+		zap.String("method", method),
+		zap.Error(fmt.Errorf("%v", recovered)),
+	)
+
+	*err = status.Error(codes.Internal, "Internal server error, please try again later")
+}
@@ -0,0 +1,380 @@
+package grpcapi_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/grpcapi"
+	"github.com/durandj/ley/internal/manager/limit"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/pkg/api/leyv1"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"inet.af/netaddr"
+)
+
+func TestGRPCShouldCreateAndGetAUser(t *testing.T) {
+	connection := newTestConnection(t)
+	userClient := leyv1.NewUserServiceClient(connection)
+	ctx := context.Background()
+
+	username := fmt.Sprintf("grpc%d", rng.RNG.Int63())
+	newUser, err := userClient.CreateUser(ctx, &leyv1.CreateUserRequest{Name: username})
+	require.Nil(t, err, "should be able to create a user")
+	require.Equal(t, username, newUser.GetName())
+	require.Equal(t, leyv1.UserStatus_USER_STATUS_ACTIVE, newUser.GetStatus())
+
+	existingUser, err := userClient.GetUser(ctx, &leyv1.GetUserRequest{Name: username})
+	require.Nil(t, err, "should be able to get the user")
+	require.Equal(t, newUser.GetId(), existingUser.GetId())
+	require.Equal(t, newUser.GetVersion(), existingUser.GetVersion())
+}
+
+func TestGRPCShouldKeepTheProfileOfAUser(t *testing.T) {
+	connection := newTestConnection(t)
+	userClient := leyv1.NewUserServiceClient(connection)
+	ctx := context.Background()
+
+	username := fmt.Sprintf("grpc%d", rng.RNG.Int63())
+	email := username + "@example.com"
+	externalID := "ext-" + username
+	newUser, err := userClient.CreateUser(ctx, &leyv1.CreateUserRequest{
+		Name:        username,
+		DisplayName: "gRPC User",
+		Email:       email,
+		ExternalId:  externalID,
+		Metadata:    map[string]string{"team": "grpc"},
+	})
+	require.Nil(t, err, "should be able to create a user")
+	require.Equal(t, "gRPC User", newUser.GetDisplayName())
+	require.Equal(t, email, newUser.GetEmail())
+	require.False(t, newUser.GetEmailVerified(), "should not verify the email address")
+	require.Equal(t, externalID, newUser.GetExternalId())
+	require.Equal(t, map[string]string{"team": "grpc"}, newUser.GetMetadata())
+
+	byEmail, err := userClient.GetUser(ctx, &leyv1.GetUserRequest{Email: email})
+	require.Nil(t, err, "should be able to get the user by their email address")
+	require.Equal(t, newUser.GetId(), byEmail.GetId())
+
+	byExternalID, err := userClient.GetUser(ctx, &leyv1.GetUserRequest{ExternalId: externalID})
+	require.Nil(t, err, "should be able to get the user by their external ID")
+	require.Equal(t, newUser.GetId(), byExternalID.GetId())
+
+	displayName := "Renamed"
+	updatedUser, err := userClient.UpdateUser(ctx, &leyv1.UpdateUserRequest{
+		Name:        username,
+		DisplayName: &displayName,
+		Metadata:    &leyv1.Metadata{},
+	})
+	require.Nil(t, err, "should be able to update the user")
+	require.Equal(t, "Renamed", updatedUser.GetDisplayName())
+	require.Equal(t, email, updatedUser.GetEmail(), "should leave the email address alone")
+	require.Empty(t, updatedUser.GetMetadata(), "should clear the metadata")
+}
+
+func TestGRPCShouldLookUpUsersByOneField(t *testing.T) {
+	connection := newTestConnection(t)
+	userClient := leyv1.NewUserServiceClient(connection)
+	ctx := context.Background()
+
+	_, err := userClient.GetUser(ctx, &leyv1.GetUserRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "should need something to look up by")
+
+	_, err = userClient.GetUser(ctx, &leyv1.GetUserRequest{Name: "user", Email: "user@example.com"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "should only look up by one field")
+}
+
+func TestGRPCShouldMapServiceErrorsToStatusCodes(t *testing.T) {
+	connection := newTestConnection(t)
+	userClient := leyv1.NewUserServiceClient(connection)
+	ctx := context.Background()
+
+	username := fmt.Sprintf("grpc%d", rng.RNG.Int63())
+	_, err := userClient.CreateUser(ctx, &leyv1.CreateUserRequest{Name: username})
+	require.Nil(t, err, "should be able to create a user")
+
+	_, err = userClient.CreateUser(ctx, &leyv1.CreateUserRequest{Name: username})
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "should reject a duplicate user")
+	require.Equal(t, "Username is already taken", status.Convert(err).Message())
+
+	_, err = userClient.GetUser(ctx, &leyv1.GetUserRequest{Name: "doesnotexist"})
+	require.Equal(t, codes.NotFound, status.Code(err), "should not find a missing user")
+
+	_, err = userClient.UpdateUser(ctx, &leyv1.UpdateUserRequest{
+		Name:             username,
+		Status:           leyv1.UserStatus_USER_STATUS_DEACTIVATED,
+		ExpectedVersions: []int64{-1},
+	})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err), "should reject a stale version")
+}
+
+func TestGRPCShouldCreateAndListNetworks(t *testing.T) {
+	connection := newTestConnection(t)
+	networkClient := leyv1.NewNetworkServiceClient(connection)
+	ctx := context.Background()
+
+	networkName := fmt.Sprintf("grpc-test-%d", rng.RNG.Int63())
+	ipv4CIDR := "10.5.0.0/24"
+	newNetwork, err := networkClient.CreateNetwork(ctx, &leyv1.CreateNetworkRequest{
+		Name:         networkName,
+		Ipv4Cidr:     &ipv4CIDR,
+		AllowOverlap: true,
+		Labels:       map[string]string{"team": "grpc"},
+		Topology: &leyv1.Topology{
+			Mode: leyv1.TopologyMode_TOPOLOGY_MODE_HUB_AND_SPOKE,
+			Hubs: []string{"hub"},
+		},
+	})
+	require.Nil(t, err, "should be able to create a network")
+	require.Equal(t, ipv4CIDR, newNetwork.GetIpv4Cidr())
+	require.Equal(t, leyv1.TopologyMode_TOPOLOGY_MODE_HUB_AND_SPOKE, newNetwork.GetTopology().GetMode())
+
+	listResponse, err := networkClient.ListNetworks(ctx, &leyv1.ListNetworksRequest{
+		Options: &leyv1.ListOptions{NamePrefix: networkName},
+	})
+	require.Nil(t, err, "should be able to list networks")
+	require.Len(t, listResponse.GetNetworks(), 1)
+	require.Equal(t, newNetwork.GetId(), listResponse.GetNetworks()[0].GetId())
+
+	_, err = networkClient.ListNetworks(ctx, &leyv1.ListNetworksRequest{
+		Options: &leyv1.ListOptions{Limit: 1000},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "should check list options like the REST API")
+}
+
+func TestGRPCShouldStreamNodeConfigChanges(t *testing.T) {
+	connection := newTestConnection(t)
+	networkClient := leyv1.NewNetworkServiceClient(connection)
+	nodeClient := leyv1.NewNodeServiceClient(connection)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	networkName := fmt.Sprintf("grpc-test-%d", rng.RNG.Int63())
+	ipv4CIDR := "10.6.0.0/24"
+	_, err := networkClient.CreateNetwork(ctx, &leyv1.CreateNetworkRequest{
+		Name:         networkName,
+		Ipv4Cidr:     &ipv4CIDR,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	var header metadata.MD
+	firstNode, err := nodeClient.RegisterNode(ctx, &leyv1.RegisterNodeRequest{
+		Network:   networkName,
+		Name:      "first",
+		PublicKey: newTestKey(t),
+	}, grpc.Header(&header))
+	require.Nil(t, err, "should be able to register a node")
+
+	secrets := header.Get(grpcapi.NodeSecretHeader)
+	require.Len(t, secrets, 1, "should send the node's secret")
+
+	stream, err := nodeClient.WatchNodeConfig(ctx, &leyv1.WatchNodeConfigRequest{Id: firstNode.GetId()})
+	require.Nil(t, err, "should be able to start the call")
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "should not watch without the node's secret")
+
+	nodeCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+secrets[0])
+	stream, err = nodeClient.WatchNodeConfig(nodeCtx, &leyv1.WatchNodeConfigRequest{Id: firstNode.GetId()})
+	require.Nil(t, err, "should be able to watch the node")
+
+	initialEvent, err := stream.Recv()
+	require.Nil(t, err, "should be sent the current config")
+	require.Empty(t, initialEvent.GetConfig().GetPeers(), "should start without peers")
+
+	_, err = nodeClient.RegisterNode(ctx, &leyv1.RegisterNodeRequest{
+		Network:   networkName,
+		Name:      "second",
+		PublicKey: newTestKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+
+	peerEvent, err := stream.Recv()
+	require.Nil(t, err, "should be sent the new config")
+	require.Len(t, peerEvent.GetConfig().GetPeers(), 1, "should send the new peer")
+	require.NotEqual(
+		t,
+		initialEvent.GetConfig().GetRevision(),
+		peerEvent.GetConfig().GetRevision(),
+		"should move to a new revision",
+	)
+
+	_, err = nodeClient.DeleteNode(ctx, &leyv1.DeleteNodeRequest{Id: firstNode.GetId()})
+	require.Nil(t, err, "should be able to delete the node")
+
+	removedEvent, err := stream.Recv()
+	require.Nil(t, err, "should be told the node was removed")
+	require.Equal(t, firstNode.GetId(), removedEvent.GetRemoved().GetId())
+
+	_, err = stream.Recv()
+	require.NotNil(t, err, "should end the stream once the node is removed")
+}
+
+func TestGRPCShouldWorkWithTheNodesOfAnOrganization(t *testing.T) {
+	connection := newTestConnection(t)
+	nodeClient := leyv1.NewNodeServiceClient(connection)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db, _ := newTestDB(t)
+	organizationService := organization.NewService(db)
+	networkService := network.NewService(db, configuration.NetworkConfiguration{}, organizationService)
+
+	organizationName := fmt.Sprintf("grpc-test-%d", rng.RNG.Int63())
+	_, err := organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: organizationName})
+	require.Nil(t, err, "should be able to create an organization")
+
+	networkName := fmt.Sprintf("grpc-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.6.0.0/24")
+	_, err = networkService.CreateNetwork(ctx, organizationName, network.CreateNetworkOpts{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	var header metadata.MD
+	registeredNode, err := nodeClient.RegisterNode(ctx, &leyv1.RegisterNodeRequest{
+		Organization: organizationName,
+		Network:      networkName,
+		Name:         "office",
+		PublicKey:    newTestKey(t),
+	}, grpc.Header(&header))
+	require.Nil(t, err, "should be able to register a node in the organization")
+
+	_, err = nodeClient.GetNode(ctx, &leyv1.GetNodeRequest{Id: registeredNode.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err), "should not find the node in the default organization")
+
+	foundNode, err := nodeClient.GetNode(ctx, &leyv1.GetNodeRequest{
+		Id:           registeredNode.GetId(),
+		Organization: organizationName,
+	})
+	require.Nil(t, err, "should find the node in its organization")
+	require.Equal(t, registeredNode.GetId(), foundNode.GetId())
+
+	secrets := header.Get(grpcapi.NodeSecretHeader)
+	require.Len(t, secrets, 1, "should send the node's secret")
+
+	nodeCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+secrets[0])
+	config, err := nodeClient.GetNodeConfig(nodeCtx, &leyv1.GetNodeConfigRequest{Id: registeredNode.GetId()})
+	require.Nil(t, err, "should get the config of the node without naming its organization")
+	require.Equal(t, registeredNode.GetId(), config.GetNode().GetId())
+
+	stream, err := nodeClient.WatchNodeConfig(nodeCtx, &leyv1.WatchNodeConfigRequest{Id: registeredNode.GetId()})
+	require.Nil(t, err, "should be able to watch the node")
+
+	initialEvent, err := stream.Recv()
+	require.Nil(t, err, "should be sent the current config without naming the organization")
+	require.Equal(t, registeredNode.GetId(), initialEvent.GetConfig().GetNode().GetId())
+}
+
+func TestGRPCShouldLimitCallers(t *testing.T) {
+	connection := newLimitedTestConnection(t, limit.NewLimiters(
+		limit.Budget{Rate: 0.001, Burst: 1},
+		limit.Budget{},
+		limit.Budget{},
+	))
+	userClient := leyv1.NewUserServiceClient(connection)
+	ctx := context.Background()
+
+	_, err := userClient.GetUser(ctx, &leyv1.GetUserRequest{Name: "doesnotexist"})
+	require.NotEqual(t, codes.ResourceExhausted, status.Code(err), "should allow the first call")
+
+	var header metadata.MD
+	_, err = userClient.GetUser(ctx, &leyv1.GetUserRequest{Name: "doesnotexist"}, grpc.Header(&header))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err), "should limit callers over their budget")
+	require.NotEmpty(t, header.Get(limit.RetryAfterHeader), "should say when to try again")
+}
+
+func newTestConnection(t *testing.T) *grpc.ClientConn {
+	return newLimitedTestConnection(t, limit.Limiters{})
+}
+
+func newLimitedTestConnection(t *testing.T, limiters limit.Limiters) *grpc.ClientConn {
+	db, dbConfig := newTestDB(t)
+
+	hub := notify.NewHub(dbConfig.ConnectionString, notify.NetworkChannel)
+	server := manager.NewGRPCServer(db, &configuration.Configuration{
+		Node: configuration.NodeConfiguration{
+			KeyRotationInterval: 24 * time.Hour,
+			KeyGracePeriod:      time.Hour,
+			StaleAfter:          time.Minute,
+			OfflineAfter:        5 * time.Minute,
+		},
+		DB: dbConfig,
+	}, hub, limiters, zap.NewNop())
+
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	connection, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.Nil(t, err, "should be able to connect to the server")
+
+	t.Cleanup(func() {
+		_ = connection.Close()
+		server.Stop()
+		_ = hub.Close()
+	})
+
+	return connection
+}
+
+func newTestDB(t *testing.T) (*sql.DB, configuration.DBConfiguration) {
+	dbConfig := configuration.DBConfiguration{
+		Type: configuration.DBTypePostgres,
+		Postgres: configuration.PostgresConfiguration{
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Role:     "ley",
+			Password: "ley",
+			DBName:   "ley",
+			SSLMode:  "disable",
+		},
+	}
+
+	connectionString, err := dbConfig.ConnectionString()
+	require.Nil(t, err, "should be able to create a connection string")
+
+	db, err := sql.Open(string(dbConfig.Type), connectionString)
+	require.Nil(t, err, "should be able to open the database")
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return db, dbConfig
+}
+
+func newTestKey(t *testing.T) string {
+	key, err := node.GeneratePresharedKey(rand.Reader)
+	require.Nil(t, err, "should be able to generate a key")
+
+	return key
+}
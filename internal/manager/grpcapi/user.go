@@ -0,0 +1,184 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/durandj/ley/pkg/api/leyv1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var userStatuses = map[user.Status]leyv1.UserStatus{
+	user.StatusActive:      leyv1.UserStatus_USER_STATUS_ACTIVE,
+	user.StatusDeactivated: leyv1.UserStatus_USER_STATUS_DEACTIVATED,
+}
+
+// UserServer handles the gRPC calls for user related API's.
+type UserServer struct {
+	leyv1.UnimplementedUserServiceServer
+
+	UserService *user.Service
+}
+
+// CreateUser creates a new user.
+func (server *UserServer) CreateUser(
+	ctx context.Context,
+	request *leyv1.CreateUserRequest,
+) (*leyv1.User, error) {
+	newUser, err := server.UserService.CreateUser(ctx, user.CreateUserOpts{
+		Username:    request.GetName(),
+		DisplayName: request.GetDisplayName(),
+		Email:       request.GetEmail(),
+		ExternalID:  request.GetExternalId(),
+		Metadata:    request.GetMetadata(),
+	})
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toUser(newUser), nil
+}
+
+// GetUser gets a user by their username, email address or external ID.
+func (server *UserServer) GetUser(
+	ctx context.Context,
+	request *leyv1.GetUserRequest,
+) (*leyv1.User, error) {
+	lookups := 0
+	for _, value := range []string{request.GetName(), request.GetEmail(), request.GetExternalId()} {
+		if value != "" {
+			lookups++
+		}
+	}
+
+	if lookups != 1 {
+		return nil, ToStatus(errortypes.NewValidationError(
+			"Exactly one of 'name', 'email' and 'external_id' has to be given",
+		))
+	}
+
+	var existingUser *user.User
+	var err error
+	switch {
+	case request.GetEmail() != "":
+		existingUser, err = server.UserService.GetUserByEmail(ctx, request.GetEmail())
+
+	case request.GetExternalId() != "":
+		existingUser, err = server.UserService.GetUserByExternalID(ctx, request.GetExternalId())
+
+	default:
+		existingUser, err = server.UserService.GetUserByUsername(ctx, request.GetName())
+	}
+
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toUser(existingUser), nil
+}
+
+// ListUsers lists a page of users.
+func (server *UserServer) ListUsers(
+	ctx context.Context,
+	request *leyv1.ListUsersRequest,
+) (*leyv1.ListUsersResponse, error) {
+	params, err := listParams(request.GetOptions())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	page, err := server.UserService.ListUsers(ctx, params)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	users := make([]*leyv1.User, len(page.Items))
+	for index := range page.Items {
+		users[index] = toUser(&page.Items[index])
+	}
+
+	return &leyv1.ListUsersResponse{
+		Users:      users,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+// UpdateUser changes a user.
+func (server *UserServer) UpdateUser(
+	ctx context.Context,
+	request *leyv1.UpdateUserRequest,
+) (*leyv1.User, error) {
+	opts := user.UpdateUserOpts{
+		DisplayName:      request.DisplayName,
+		Email:            request.Email,
+		ExternalID:       request.ExternalId,
+		ExpectedVersions: expectedVersions(request.GetExpectedVersions()),
+	}
+
+	if request.GetMetadata() != nil {
+		opts.Metadata = request.GetMetadata().GetValues()
+		if opts.Metadata == nil {
+			opts.Metadata = map[string]string{}
+		}
+	}
+
+	if request.GetStatus() != leyv1.UserStatus_USER_STATUS_UNSPECIFIED {
+		status, err := fromUserStatus(request.GetStatus())
+		if err != nil {
+			return nil, ToStatus(err)
+		}
+
+		opts.Status = &status
+	}
+
+	updatedUser, err := server.UserService.UpdateUser(ctx, request.GetName(), opts)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return toUser(updatedUser), nil
+}
+
+// DeleteUser removes a user.
+func (server *UserServer) DeleteUser(
+	ctx context.Context,
+	request *leyv1.DeleteUserRequest,
+) (*leyv1.DeleteUserResponse, error) {
+	err := server.UserService.DeleteUser(
+		ctx,
+		request.GetName(),
+		expectedVersions(request.GetExpectedVersions()),
+	)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+
+	return &leyv1.DeleteUserResponse{}, nil
+}
+
+func toUser(existingUser *user.User) *leyv1.User {
+	return &leyv1.User{
+		Id:            existingUser.ID(),
+		Name:          existingUser.Username(),
+		Status:        userStatuses[existingUser.Status()],
+		Version:       existingUser.Version(),
+		CreatedOn:     timestamppb.New(existingUser.CreatedOn()),
+		ModifiedOn:    timestamppb.New(existingUser.ModifiedOn()),
+		DisplayName:   existingUser.DisplayName(),
+		Email:         existingUser.Email(),
+		EmailVerified: existingUser.EmailVerified(),
+		ExternalId:    existingUser.ExternalID(),
+		Metadata:      existingUser.Metadata(),
+	}
+}
+
+func fromUserStatus(status leyv1.UserStatus) (user.Status, error) {
+	for userStatus, protoStatus := range userStatuses {
+		if protoStatus == status {
+			return userStatus, nil
+		}
+	}
+
+	return "", errortypes.NewValidationError("Unknown user status '%s'", status)
+}
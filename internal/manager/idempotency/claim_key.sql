@@ -0,0 +1,26 @@
+INSERT INTO IdempotencyKeys (
+    Caller,
+    Key,
+    RequestHash,
+    CreatedOn,
+    ExpiresOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (Caller, Key) DO UPDATE
+SET
+    RequestHash = EXCLUDED.RequestHash,
+    StatusCode = NULL,
+    ResponseHeaders = NULL,
+    ResponseBody = NULL,
+    CreatedOn = EXCLUDED.CreatedOn,
+    ExpiresOn = EXCLUDED.ExpiresOn
+WHERE
+    IdempotencyKeys.ExpiresOn <= EXCLUDED.CreatedOn
+RETURNING Key
+;
@@ -0,0 +1,9 @@
+UPDATE IdempotencyKeys
+SET
+    StatusCode = $3,
+    ResponseHeaders = $4,
+    ResponseBody = $5
+WHERE
+    Caller = $1
+    AND Key = $2
+;
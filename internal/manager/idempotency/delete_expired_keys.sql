@@ -0,0 +1,4 @@
+DELETE FROM IdempotencyKeys
+WHERE
+    ExpiresOn <= $1
+;
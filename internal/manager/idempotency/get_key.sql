@@ -0,0 +1,11 @@
+SELECT
+    RequestHash,
+    StatusCode,
+    ResponseHeaders,
+    ResponseBody
+FROM IdempotencyKeys
+WHERE
+    Caller = $1
+    AND Key = $2
+LIMIT 1
+;
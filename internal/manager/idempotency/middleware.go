@@ -0,0 +1,295 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	// HeaderName is the request header that carries the idempotency
+	// key.
+	HeaderName = "Idempotency-Key"
+
+	// ReplayedHeaderName is set on responses that were replayed from
+	// an earlier request instead of being handled again.
+	ReplayedHeaderName = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+var (
+	// replayedHeaders are the response headers that are kept so they
+	// can be sent again when a response is replayed.
+	replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+)
+
+// KeyStore is where idempotency keys and their responses are kept.
+type KeyStore interface {
+	Claim(ctx context.Context, caller string, key string, requestHash string, window time.Duration) (bool, error)
+	Get(ctx context.Context, caller string, key string) (StoredResponse, error)
+	Complete(ctx context.Context, caller string, key string, statusCode int, headers http.Header, body []byte) error
+	Release(ctx context.Context, caller string, key string) error
+}
+
+var _ KeyStore = (*Store)(nil)
+
+// CallerFunc identifies who made a request so that the same key used
+// by two different callers doesn't collide.
+type CallerFunc func(request *http.Request) string
+
+// DefaultCaller identifies callers by who they were checked to be,
+// like the user of a session, then by any other credentials they sent
+// and otherwise by their IP address. It has to run after
+// auth.Middleware.
+func DefaultCaller(request *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(request.Context()); ok {
+		return principal
+	}
+
+	if authorization := request.Header.Get("Authorization"); authorization != "" {
+		return "auth:" + hash([]byte(authorization))
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// Middleware makes POST requests that carry an Idempotency-Key safe to
+// retry. The first response for a key is stored for the length of the
+// window and sent again for any retry with the same key and body.
+// Reusing a key for a different request is rejected. Server errors
+// aren't stored so that the request can be retried for real, and
+// neither are responses marked "Cache-Control: no-store", like the
+// ones carrying a secret, so retrying them handles them again.
+func Middleware(
+	store KeyStore,
+	window time.Duration,
+	caller CallerFunc,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			key := request.Header.Get(HeaderName)
+			if request.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(response, request)
+
+				return
+			}
+
+			if len(key) > maxKeyLength {
+				writeError(
+					response,
+					request,
+					http.StatusBadRequest,
+					fmt.Sprintf("%s must be at most %d characters", HeaderName, maxKeyLength),
+				)
+
+				return
+			}
+
+			body, err := io.ReadAll(request.Body)
+			if err != nil {
+				writeError(response, request, http.StatusBadRequest, "Unable to read request body")
+
+				return
+			}
+			request.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := request.Context()
+			callerID := caller(request)
+			requestHash := hashRequest(request, body)
+
+			claimed, err := store.Claim(ctx, callerID, key, requestHash, window)
+			if err != nil {
+				writeStoreError(response, request, err)
+
+				return
+			}
+
+			if !claimed {
+				replay(response, request, store, callerID, key, requestHash)
+
+				return
+			}
+
+			handleClaimed(next, response, request, store, callerID, key)
+		})
+	}
+}
+
+func handleClaimed(
+	next http.Handler,
+	response http.ResponseWriter,
+	request *http.Request,
+	store KeyStore,
+	callerID string,
+	key string,
+) {
+	// The request context may already be cancelled by the time the
+	// handler returns but the key still needs to be settled.
+	settleCtx := context.Background()
+
+	completed := false
+	defer func() {
+		if !completed {
+			_ = store.Release(settleCtx, callerID, key)
+		}
+	}()
+
+	var recordedBody bytes.Buffer
+	wrappedResponse := middleware.NewWrapResponseWriter(response, request.ProtoMajor)
+	wrappedResponse.Tee(&recordedBody)
+
+	next.ServeHTTP(wrappedResponse, request)
+
+	statusCode := wrappedResponse.Status()
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	if statusCode >= http.StatusInternalServerError || noStore(response.Header()) {
+		return
+	}
+
+	headers := http.Header{}
+	for _, name := range replayedHeaders {
+		if values := response.Header().Values(name); len(values) > 0 {
+			headers[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	err := store.Complete(
+		settleCtx,
+		callerID,
+		key,
+		statusCode,
+		headers,
+		recordedBody.Bytes(),
+	)
+	completed = err == nil
+}
+
+func replay(
+	response http.ResponseWriter,
+	request *http.Request,
+	store KeyStore,
+	callerID string,
+	key string,
+	requestHash string,
+) {
+	storedResponse, err := store.Get(request.Context(), callerID, key)
+	if err != nil {
+		writeStoreError(response, request, err)
+
+		return
+	}
+
+	if storedResponse.RequestHash != requestHash {
+		writeError(
+			response,
+			request,
+			http.StatusUnprocessableEntity,
+			fmt.Sprintf("%s was already used for a different request", HeaderName),
+		)
+
+		return
+	}
+
+	if storedResponse.StatusCode == nil {
+		writeError(
+			response,
+			request,
+			http.StatusConflict,
+			fmt.Sprintf("A request with this %s is still being processed", HeaderName),
+		)
+
+		return
+	}
+
+	for name, values := range storedResponse.Headers {
+		response.Header()[http.CanonicalHeaderKey(name)] = values
+	}
+	response.Header().Set(ReplayedHeaderName, "true")
+	response.WriteHeader(*storedResponse.StatusCode)
+	_, _ = response.Write(storedResponse.Body)
+}
+
+// noStore checks if a response must not be kept, which is how handlers
+// keep secrets out of the key store.
+func noStore(headers http.Header) bool {
+	for _, value := range headers.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hashRequest identifies a request by everything that tells what it
+// does, so that a key can't be reused for a different one.
+func hashRequest(request *http.Request, body []byte) string {
+	content := make(
+		[]byte,
+		0,
+		len(request.Method)+len(request.URL.Path)+len(request.URL.RawQuery)+len(body)+3,
+	)
+	content = append(content, request.Method...)
+	content = append(content, '\n')
+	content = append(content, request.URL.Path...)
+	content = append(content, '\n')
+	content = append(content, request.URL.RawQuery...)
+	content = append(content, '\n')
+	content = append(content, body...)
+
+	return hash(content)
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func writeStoreError(response http.ResponseWriter, request *http.Request, err error) {
+	message := "Internal server error, please try again later"
+
+	var systemError errortypes.SystemError
+	if errors.As(err, &systemError) {
+		message = systemError.SafeMessage
+	}
+
+	writeError(response, request, http.StatusInternalServerError, message)
+}
+
+func writeError(
+	response http.ResponseWriter,
+	request *http.Request,
+	statusCode int,
+	message string,
+) {
+	response.WriteHeader(statusCode)
+	_ = render.Render(response, request, &renderable.ErrorResponse{
+		Message: message,
+	})
+}
@@ -0,0 +1,260 @@
+package idempotency_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/idempotency"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareShouldReplayTheFirstResponse(t *testing.T) {
+	handlerCalls := 0
+	handler := newTestHandler(newMemoryStore(), func(response http.ResponseWriter, request *http.Request) {
+		handlerCalls++
+		response.Header().Set("Content-Type", "application/json")
+		response.Header().Set("ETag", `"1"`)
+		response.WriteHeader(http.StatusCreated)
+		_, _ = response.Write([]byte(`{"name":"test"}`))
+	})
+
+	first := serve(handler, "key", `{"name":"test"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Empty(t, first.Header().Get(idempotency.ReplayedHeaderName), "should not mark the first response")
+
+	second := serve(handler, "key", `{"name":"test"}`)
+	require.Equal(t, http.StatusCreated, second.Code, "should replay the status code")
+	require.Equal(t, `{"name":"test"}`, second.Body.String(), "should replay the body")
+	require.Equal(t, `"1"`, second.Header().Get("ETag"), "should replay the entity tag")
+	require.Equal(t, "true", second.Header().Get(idempotency.ReplayedHeaderName))
+	require.Equal(t, 1, handlerCalls, "should only handle the request once")
+}
+
+func TestMiddlewareShouldRejectAKeyReusedForADifferentRequest(t *testing.T) {
+	handler := newTestHandler(newMemoryStore(), func(response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusCreated)
+	})
+
+	require.Equal(t, http.StatusCreated, serve(handler, "key", `{"name":"a"}`).Code)
+	require.Equal(t, http.StatusUnprocessableEntity, serve(handler, "key", `{"name":"b"}`).Code)
+}
+
+func TestMiddlewareShouldRejectAKeyReusedWithADifferentQuery(t *testing.T) {
+	handler := newTestHandler(newMemoryStore(), func(response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusCreated)
+	})
+
+	first := httptest.NewRequest(http.MethodPost, "/user?dryRun=true", strings.NewReader(`{}`))
+	first.Header.Set(idempotency.HeaderName, "key")
+	firstRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(firstRecorder, first)
+	require.Equal(t, http.StatusCreated, firstRecorder.Code)
+
+	require.Equal(
+		t,
+		http.StatusUnprocessableEntity,
+		serve(handler, "key", `{}`).Code,
+		"should not replay the response of a request with another query",
+	)
+}
+
+func TestMiddlewareShouldNotStoreServerErrors(t *testing.T) {
+	handlerCalls := 0
+	handler := newTestHandler(newMemoryStore(), func(response http.ResponseWriter, request *http.Request) {
+		handlerCalls++
+		if handlerCalls == 1 {
+			response.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response.WriteHeader(http.StatusCreated)
+	})
+
+	require.Equal(t, http.StatusInternalServerError, serve(handler, "key", `{}`).Code)
+	require.Equal(t, http.StatusCreated, serve(handler, "key", `{}`).Code, "should handle the retry")
+	require.Equal(t, 2, handlerCalls)
+}
+
+func TestMiddlewareShouldNotStoreResponsesMarkedNoStore(t *testing.T) {
+	store := newMemoryStore()
+	handlerCalls := 0
+	handler := newTestHandler(store, func(response http.ResponseWriter, request *http.Request) {
+		handlerCalls++
+		response.Header().Set("Cache-Control", "private, no-store")
+		response.WriteHeader(http.StatusCreated)
+		_, _ = response.Write([]byte(`{"secret":"hunter2"}`))
+	})
+
+	require.Equal(t, http.StatusCreated, serve(handler, "key", `{}`).Code)
+	require.Empty(t, store.keys, "should not keep the response")
+
+	retry := serve(handler, "key", `{}`)
+	require.Equal(t, http.StatusCreated, retry.Code, "should handle the retry")
+	require.Empty(t, retry.Header().Get(idempotency.ReplayedHeaderName), "should not replay the response")
+	require.Equal(t, 2, handlerCalls)
+}
+
+func TestMiddlewareShouldRejectAKeyThatIsStillInProgress(t *testing.T) {
+	var handler http.Handler
+	var concurrentStatus int
+	handler = newTestHandler(newMemoryStore(), func(response http.ResponseWriter, request *http.Request) {
+		concurrentStatus = serve(handler, "key", `{}`).Code
+		response.WriteHeader(http.StatusCreated)
+	})
+
+	require.Equal(t, http.StatusCreated, serve(handler, "key", `{}`).Code)
+	require.Equal(t, http.StatusConflict, concurrentStatus, "should reject the concurrent request")
+}
+
+func TestMiddlewareShouldIgnoreRequestsWithoutAKey(t *testing.T) {
+	handlerCalls := 0
+	handler := newTestHandler(newMemoryStore(), func(response http.ResponseWriter, request *http.Request) {
+		handlerCalls++
+		response.WriteHeader(http.StatusCreated)
+	})
+
+	serve(handler, "", `{}`)
+	serve(handler, "", `{}`)
+	require.Equal(t, 2, handlerCalls, "should handle every request")
+}
+
+func TestMiddlewareShouldPassTheBodyThrough(t *testing.T) {
+	var seenBody string
+	handler := newTestHandler(newMemoryStore(), func(response http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		seenBody = string(body)
+		response.WriteHeader(http.StatusCreated)
+	})
+
+	serve(handler, "key", `{"name":"test"}`)
+	require.Equal(t, `{"name":"test"}`, seenBody, "should let the handler read the body")
+}
+
+func TestDefaultCallerShouldPreferCredentials(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/user", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	require.Equal(t, "ip:192.0.2.1", idempotency.DefaultCaller(request))
+
+	request.Header.Set("Authorization", "Bearer secret")
+	caller := idempotency.DefaultCaller(request)
+	require.True(t, strings.HasPrefix(caller, "auth:"), "should identify the caller by their credentials")
+	require.NotContains(t, caller, "secret", "should not keep the credentials themselves")
+}
+
+func TestDefaultCallerShouldTellLoggedInUsersApart(t *testing.T) {
+	first := httptest.NewRequest(http.MethodPost, "/user", nil)
+	first.RemoteAddr = "192.0.2.1:1234"
+	first.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: "first"})
+	first = first.WithContext(auth.ContextWithPrincipal(first.Context(), "user:1"))
+
+	second := httptest.NewRequest(http.MethodPost, "/user", nil)
+	second.RemoteAddr = "192.0.2.1:5678"
+	second.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: "second"})
+	second = second.WithContext(auth.ContextWithPrincipal(second.Context(), "user:2"))
+
+	require.Equal(t, "user:1", idempotency.DefaultCaller(first), "should identify the caller by their user")
+	require.NotEqual(
+		t,
+		idempotency.DefaultCaller(first),
+		idempotency.DefaultCaller(second),
+		"should not share keys between users behind the same address",
+	)
+}
+
+func newTestHandler(store *memoryStore, handlerFunc http.HandlerFunc) http.Handler {
+	caller := func(request *http.Request) string {
+		return "ip:192.0.2.1"
+	}
+
+	return idempotency.Middleware(store, time.Hour, caller)(handlerFunc)
+}
+
+func serve(handler http.Handler, key string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(body))
+	if key != "" {
+		request.Header.Set(idempotency.HeaderName, key)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+type memoryStore struct {
+	lock sync.Mutex
+	keys map[string]*idempotency.StoredResponse
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		keys: map[string]*idempotency.StoredResponse{},
+	}
+}
+
+func (store *memoryStore) Claim(
+	ctx context.Context,
+	caller string,
+	key string,
+	requestHash string,
+	window time.Duration,
+) (bool, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if _, ok := store.keys[caller+"/"+key]; ok {
+		return false, nil
+	}
+
+	store.keys[caller+"/"+key] = &idempotency.StoredResponse{RequestHash: requestHash}
+
+	return true, nil
+}
+
+func (store *memoryStore) Get(
+	ctx context.Context,
+	caller string,
+	key string,
+) (idempotency.StoredResponse, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return *store.keys[caller+"/"+key], nil
+}
+
+func (store *memoryStore) Complete(
+	ctx context.Context,
+	caller string,
+	key string,
+	statusCode int,
+	headers http.Header,
+	body []byte,
+) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	storedResponse := store.keys[caller+"/"+key]
+	storedResponse.StatusCode = &statusCode
+	storedResponse.Headers = headers
+	storedResponse.Body = append([]byte(nil), body...)
+
+	return nil
+}
+
+func (store *memoryStore) Release(ctx context.Context, caller string, key string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	delete(store.keys, caller+"/"+key)
+
+	return nil
+}
+
+var _ idempotency.KeyStore = (*memoryStore)(nil)
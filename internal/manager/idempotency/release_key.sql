@@ -0,0 +1,5 @@
+DELETE FROM IdempotencyKeys
+WHERE
+    Caller = $1
+    AND Key = $2
+;
@@ -0,0 +1,193 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+)
+
+var (
+	//go:embed claim_key.sql
+	claimKeySQL string
+
+	//go:embed get_key.sql
+	getKeySQL string
+
+	//go:embed complete_key.sql
+	completeKeySQL string
+
+	//go:embed release_key.sql
+	releaseKeySQL string
+
+	//go:embed delete_expired_keys.sql
+	deleteExpiredKeysSQL string
+)
+
+// StoredResponse is a response that was recorded for an idempotency
+// key. A nil StatusCode means the original request is still being
+// handled.
+type StoredResponse struct {
+	RequestHash string
+	StatusCode  *int
+	Headers     http.Header
+	Body        []byte
+}
+
+// Store keeps track of idempotency keys and the responses that were
+// sent for them.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a new idempotency key store.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Claim attempts to take ownership of an idempotency key for a caller.
+// A key can be claimed if it has never been used or if the last use
+// has expired. When the key is already claimed false is returned and
+// the stored response should be looked up instead.
+func (store *Store) Claim(
+	ctx context.Context,
+	caller string,
+	key string,
+	requestHash string,
+	window time.Duration,
+) (bool, error) {
+	now := time.Now().UTC()
+
+	row := store.db.QueryRowContext(
+		ctx,
+		claimKeySQL,
+		caller,
+		key,
+		requestHash,
+		now,
+		now.Add(window),
+	)
+
+	var claimedKey string
+	if err := row.Scan(&claimedKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, errortypes.SystemError{
+			SafeMessage:   "Unable to check idempotency key",
+			UnsafeMessage: fmt.Sprintf("Unable to claim idempotency key: %v", err),
+			WrappedError:  err,
+		}
+	}
+
+	return true, nil
+}
+
+// Get looks up what is known about a claimed idempotency key.
+func (store *Store) Get(
+	ctx context.Context,
+	caller string,
+	key string,
+) (StoredResponse, error) {
+	row := store.db.QueryRowContext(ctx, getKeySQL, caller, key)
+
+	var storedResponse StoredResponse
+	var statusCode sql.NullInt32
+	var rawHeaders []byte
+	err := row.Scan(
+		&storedResponse.RequestHash,
+		&statusCode,
+		&rawHeaders,
+		&storedResponse.Body,
+	)
+	if err != nil {
+		return StoredResponse{}, errortypes.SystemError{
+			SafeMessage:   "Unable to check idempotency key",
+			UnsafeMessage: fmt.Sprintf("Unable to get idempotency key: %v", err),
+			WrappedError:  err,
+		}
+	}
+
+	if statusCode.Valid {
+		code := int(statusCode.Int32)
+		storedResponse.StatusCode = &code
+	}
+
+	if rawHeaders != nil {
+		if err := json.Unmarshal(rawHeaders, &storedResponse.Headers); err != nil {
+			return StoredResponse{}, errortypes.SystemError{
+				SafeMessage:   "Unable to check idempotency key",
+				UnsafeMessage: fmt.Sprintf("Unable to parse stored headers: %v", err),
+				WrappedError:  err,
+			}
+		}
+	}
+
+	return storedResponse, nil
+}
+
+// Complete records the response that was sent for a claimed key so
+// that it can be replayed.
+func (store *Store) Complete(
+	ctx context.Context,
+	caller string,
+	key string,
+	statusCode int,
+	headers http.Header,
+	body []byte,
+) error {
+	rawHeaders, err := json.Marshal(headers)
+	if err != nil {
+		return fmt.Errorf("Unable to serialize response headers: %w", err)
+	}
+
+	_, err = store.db.ExecContext(
+		ctx,
+		completeKeySQL,
+		caller,
+		key,
+		statusCode,
+		rawHeaders,
+		body,
+	)
+	if err != nil {
+		return fmt.Errorf("Unable to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+// Release gives up a claimed key without storing a response so that
+// the request can be tried again.
+func (store *Store) Release(ctx context.Context, caller string, key string) error {
+	if _, err := store.db.ExecContext(ctx, releaseKeySQL, caller, key); err != nil {
+		return fmt.Errorf("Unable to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes every key whose window has passed, returning
+// how many were removed.
+func (store *Store) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := store.db.ExecContext(ctx, deleteExpiredKeysSQL, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("Unable to delete expired idempotency keys: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Unable to count deleted idempotency keys: %w", err)
+	}
+
+	return deleted, nil
+}
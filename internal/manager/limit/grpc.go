@@ -0,0 +1,144 @@
+package limit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RetryAfterHeader is the gRPC response header that says how many
+// seconds a caller that was limited has to wait to try again.
+const RetryAfterHeader = "retry-after"
+
+// CallKeyFunc identifies the caller that made a gRPC call. Calls that
+// it gives an empty key for aren't limited.
+type CallKeyFunc func(ctx context.Context) string
+
+// CallByIP identifies gRPC callers by the IP address they connected
+// from. Callers have the same key as with ByIP so they share a budget
+// across both APIs.
+func CallByIP(ctx context.Context) string {
+	caller, ok := peer.FromContext(ctx)
+	if !ok || caller.Addr == nil {
+		return ""
+	}
+
+	return ipKey(caller.Addr.String())
+}
+
+// CallByPrincipal identifies gRPC callers by who they were checked to
+// be, like ByPrincipal does for requests.
+func CallByPrincipal(ctx context.Context) string {
+	principal, _ := auth.PrincipalFromContext(ctx)
+
+	return principal
+}
+
+// CallByPrincipalOrIP identifies gRPC callers by who they
+// authenticated as or, for anonymous calls, by their IP address.
+func CallByPrincipalOrIP(ctx context.Context) string {
+	if key := CallByPrincipal(ctx); key != "" {
+		return key
+	}
+
+	return CallByIP(ctx)
+}
+
+// UnaryCalls rejects unary gRPC calls from callers that used up their
+// budget with a ResourceExhausted status whose retry-after header says
+// when they can try again. Only the given methods are limited, or
+// every method when none are given.
+func UnaryCalls(limiter *Limiter, key CallKeyFunc, methods ...string) grpc.UnaryServerInterceptor {
+	limited := methodSet(methods)
+
+	return func(
+		ctx context.Context,
+		request any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if limited != nil && !limited[info.FullMethod] {
+			return handler(ctx, request)
+		}
+
+		if retryAfter, ok := allowCall(limiter, key(ctx)); !ok {
+			_ = grpc.SetHeader(ctx, retryAfterMetadata(retryAfter))
+
+			return nil, tooManyCallsError(retryAfter)
+		}
+
+		return handler(ctx, request)
+	}
+}
+
+// StreamCalls limits streaming gRPC calls the same way that UnaryCalls
+// limits unary ones. Only opening a stream counts against the budget.
+func StreamCalls(limiter *Limiter, key CallKeyFunc, methods ...string) grpc.StreamServerInterceptor {
+	limited := methodSet(methods)
+
+	return func(
+		server any,
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if limited != nil && !limited[info.FullMethod] {
+			return handler(server, stream)
+		}
+
+		if retryAfter, ok := allowCall(limiter, key(stream.Context())); !ok {
+			_ = stream.SetHeader(retryAfterMetadata(retryAfter))
+
+			return tooManyCallsError(retryAfter)
+		}
+
+		return handler(server, stream)
+	}
+}
+
+func methodSet(methods []string) map[string]bool {
+	if len(methods) == 0 {
+		return nil
+	}
+
+	set := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		set[method] = true
+	}
+
+	return set
+}
+
+// allowCall takes a token for the caller. When the caller used up
+// their budget the seconds until they can try again are given.
+func allowCall(limiter *Limiter, callerKey string) (int, bool) {
+	if callerKey == "" {
+		return 0, true
+	}
+
+	allowed, wait := limiter.Allow(callerKey, time.Now())
+	if allowed {
+		return 0, true
+	}
+
+	return retryAfterSeconds(wait), false
+}
+
+func retryAfterMetadata(retryAfter int) metadata.MD {
+	return metadata.Pairs(RetryAfterHeader, strconv.Itoa(retryAfter))
+}
+
+func tooManyCallsError(retryAfter int) error {
+	return status.Error(
+		codes.ResourceExhausted,
+		fmt.Sprintf("Too many requests, try again in %d seconds", retryAfter),
+	)
+}
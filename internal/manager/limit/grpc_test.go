@@ -0,0 +1,71 @@
+package limit_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/limit"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryCallsShouldShareTheBudgetOfRequests(t *testing.T) {
+	limiter := limit.NewLimiter(limit.Budget{Rate: 1, Burst: 1})
+	handler := limit.Requests(limiter, limit.ByIP)(okHandler())
+	interceptor := limit.UnaryCalls(limiter, limit.CallByIP)
+
+	require.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/network", "10.0.0.1:1234", "").Code)
+
+	_, err := call(interceptor, peerContext("10.0.0.1:5678"), "/ley.v1.UserService/GetUser")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err), "should limit the same address across both APIs")
+	require.Contains(t, status.Convert(err).Message(), "Too many requests")
+
+	_, err = call(interceptor, peerContext("10.0.0.2:1234"), "/ley.v1.UserService/GetUser")
+	require.Nil(t, err, "should not limit other addresses")
+}
+
+func TestUnaryCallsShouldOnlyLimitTheGivenMethods(t *testing.T) {
+	interceptor := limit.UnaryCalls(
+		limit.NewLimiter(limit.Budget{Rate: 1, Burst: 1}),
+		limit.CallByPrincipalOrIP,
+		"/ley.v1.NodeService/RegisterNode",
+	)
+	ctx := auth.ContextWithPrincipal(peerContext("10.0.0.1:1234"), "user:1")
+
+	for index := 0; index < 3; index++ {
+		_, err := call(interceptor, ctx, "/ley.v1.NodeService/GetNode")
+		require.Nil(t, err, "should not limit other methods")
+	}
+
+	_, err := call(interceptor, ctx, "/ley.v1.NodeService/RegisterNode")
+	require.Nil(t, err)
+
+	_, err = call(interceptor, ctx, "/ley.v1.NodeService/RegisterNode")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err), "should limit the given methods")
+
+	_, err = call(interceptor, auth.ContextWithPrincipal(ctx, "user:2"), "/ley.v1.NodeService/RegisterNode")
+	require.Nil(t, err, "should limit every principal separately")
+}
+
+func call(interceptor grpc.UnaryServerInterceptor, ctx context.Context, method string) (any, error) {
+	return interceptor(
+		ctx,
+		nil,
+		&grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, request any) (any, error) {
+			return "ok", nil
+		},
+	)
+}
+
+func peerContext(address string) context.Context {
+	tcpAddress, _ := net.ResolveTCPAddr("tcp", address)
+
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddress})
+}
@@ -0,0 +1,142 @@
+package limit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often buckets that filled up again are thrown
+// away so that callers who went quiet don't use memory forever.
+const pruneInterval = time.Minute
+
+// Budget is how many requests a caller can make. Callers can make
+// Burst requests at once, after which they get Rate more requests
+// every second.
+type Budget struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled tells whether the budget limits anything. A budget without a
+// rate or burst doesn't.
+func (budget Budget) Enabled() bool {
+	return budget.Rate > 0 && budget.Burst > 0
+}
+
+// Limiter keeps a token bucket for every caller. Buckets are only kept
+// in memory, so every manager instance limits callers on its own.
+type Limiter struct {
+	budget       Budget
+	mutex        sync.Mutex
+	buckets      map[string]*bucket
+	lastPrunedOn time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedOn time.Time
+}
+
+// NewLimiter creates a limiter that gives every caller the same
+// budget.
+func NewLimiter(budget Budget) *Limiter {
+	return &Limiter{
+		budget:  budget,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the bucket of the caller with the given
+// key. When the bucket is empty the request isn't allowed and the time
+// until a token is available again is returned.
+func (limiter *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if now.Sub(limiter.lastPrunedOn) >= pruneInterval {
+		limiter.prune(now)
+	}
+
+	callerBucket, ok := limiter.buckets[key]
+	if !ok {
+		callerBucket = &bucket{
+			tokens:    float64(limiter.budget.Burst),
+			updatedOn: now,
+		}
+		limiter.buckets[key] = callerBucket
+	}
+
+	limiter.refill(callerBucket, now)
+
+	if callerBucket.tokens < 1 {
+		missing := 1 - callerBucket.tokens
+		wait := time.Duration(math.Ceil(missing / limiter.budget.Rate * float64(time.Second)))
+
+		return false, wait
+	}
+
+	callerBucket.tokens--
+
+	return true, 0
+}
+
+func (limiter *Limiter) refill(callerBucket *bucket, now time.Time) {
+	elapsed := now.Sub(callerBucket.updatedOn).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	callerBucket.tokens = math.Min(
+		float64(limiter.budget.Burst),
+		callerBucket.tokens+elapsed*limiter.budget.Rate,
+	)
+	callerBucket.updatedOn = now
+}
+
+// prune removes the buckets that are full again. A caller without a
+// bucket gets a full one, so forgetting them changes nothing.
+func (limiter *Limiter) prune(now time.Time) {
+	for key, callerBucket := range limiter.buckets {
+		limiter.refill(callerBucket, now)
+		if callerBucket.tokens >= float64(limiter.budget.Burst) {
+			delete(limiter.buckets, key)
+		}
+	}
+
+	limiter.lastPrunedOn = now
+}
+
+// Limiters are the budgets that callers are limited by. They're shared
+// between the REST and gRPC APIs so that callers have one budget no
+// matter which API they call. Budgets that aren't enabled are nil and
+// don't limit anything.
+type Limiters struct {
+	// IP limits every call by the IP address of the caller.
+	IP *Limiter
+
+	// Principal limits the calls of callers that were checked to be
+	// someone.
+	Principal *Limiter
+
+	// Expensive limits the calls to expensive endpoints by the caller's
+	// principal or, for anonymous calls, their IP address.
+	Expensive *Limiter
+}
+
+// NewLimiters creates limiters for the budgets that are enabled.
+func NewLimiters(ip Budget, principal Budget, expensive Budget) Limiters {
+	return Limiters{
+		IP:        newEnabledLimiter(ip),
+		Principal: newEnabledLimiter(principal),
+		Expensive: newEnabledLimiter(expensive),
+	}
+}
+
+func newEnabledLimiter(budget Budget) *Limiter {
+	if !budget.Enabled() {
+		return nil
+	}
+
+	return NewLimiter(budget)
+}
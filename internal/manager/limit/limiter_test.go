@@ -0,0 +1,52 @@
+package limit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/limit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterShouldAllowABurstAndThenRefill(t *testing.T) {
+	limiter := limit.NewLimiter(limit.Budget{Rate: 2, Burst: 3})
+	now := time.Now()
+
+	for index := 0; index < 3; index++ {
+		allowed, _ := limiter.Allow("caller", now)
+		require.True(t, allowed, "should allow the burst")
+	}
+
+	allowed, wait := limiter.Allow("caller", now)
+	require.False(t, allowed, "should reject requests past the burst")
+	require.Equal(t, 500*time.Millisecond, wait, "should wait for the next token")
+
+	allowed, _ = limiter.Allow("caller", now.Add(500*time.Millisecond))
+	require.True(t, allowed, "should allow requests once a token is back")
+
+	allowed, _ = limiter.Allow("caller", now.Add(500*time.Millisecond))
+	require.False(t, allowed, "should only give back tokens at the rate")
+}
+
+func TestLimiterShouldKeepABucketForEveryCaller(t *testing.T) {
+	limiter := limit.NewLimiter(limit.Budget{Rate: 1, Burst: 1})
+	now := time.Now()
+
+	allowed, _ := limiter.Allow("first", now)
+	require.True(t, allowed)
+
+	allowed, _ = limiter.Allow("first", now)
+	require.False(t, allowed, "should limit the first caller")
+
+	allowed, _ = limiter.Allow("second", now)
+	require.True(t, allowed, "should not limit the second caller")
+
+	allowed, _ = limiter.Allow("first", now.Add(time.Hour))
+	require.True(t, allowed, "should forget callers that went quiet")
+}
+
+func TestBudgetShouldOnlyBeEnabledWithARateAndBurst(t *testing.T) {
+	require.True(t, limit.Budget{Rate: 1, Burst: 1}.Enabled())
+	require.False(t, limit.Budget{Burst: 1}.Enabled(), "should need a rate")
+	require.False(t, limit.Budget{Rate: 1}.Enabled(), "should need a burst")
+}
@@ -0,0 +1,235 @@
+package limit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// KeyFunc identifies the caller that made a request. Requests that it
+// gives an empty key for aren't limited.
+type KeyFunc func(request *http.Request) string
+
+// ByIP identifies callers by their IP address. It relies on
+// middleware.RealIP having run first to find the address of clients
+// behind a proxy.
+func ByIP(request *http.Request) string {
+	return ipKey(request.RemoteAddr)
+}
+
+// ipKey gives the key of the host of an address so that callers have
+// the same key no matter which port or API they called from.
+func ipKey(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	return "ip:" + host
+}
+
+// ByPrincipal identifies callers by who they were checked to be.
+// Users that logged in share one budget across all of their sessions
+// while tokens, like the SCIM token, only count once they were checked.
+// Anonymous requests and requests with credentials that weren't
+// checked aren't limited by it. It has to run after auth.Middleware
+// and auth.Tokens.
+func ByPrincipal(request *http.Request) string {
+	principal, _ := auth.PrincipalFromContext(request.Context())
+
+	return principal
+}
+
+// ByPrincipalOrIP identifies callers by who they authenticated as or,
+// for anonymous requests, by their IP address.
+func ByPrincipalOrIP(request *http.Request) string {
+	if key := ByPrincipal(request); key != "" {
+		return key
+	}
+
+	return ByIP(request)
+}
+
+// Requests rejects requests from callers that used up their budget
+// with a 429 response whose Retry-After header says when they can try
+// again.
+func Requests(limiter *Limiter, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			callerKey := key(request)
+			if callerKey == "" {
+				next.ServeHTTP(response, request)
+
+				return
+			}
+
+			allowed, wait := limiter.Allow(callerKey, time.Now())
+			if !allowed {
+				retryAfter := retryAfterSeconds(wait)
+				response.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				writeError(
+					response,
+					request,
+					http.StatusTooManyRequests,
+					fmt.Sprintf("Too many requests, try again in %d seconds", retryAfter),
+				)
+
+				return
+			}
+
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
+// retryAfterSeconds rounds the time until a caller can try again up to
+// whole seconds, which is what the Retry-After header takes.
+func retryAfterSeconds(wait time.Duration) int {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	return retryAfter
+}
+
+// Endpoint is a route given by its method and path pattern. Path
+// parameters, like `{id}`, match any single segment of a path.
+type Endpoint struct {
+	Method  string
+	Pattern string
+}
+
+func (endpoint Endpoint) matches(method string, path string) bool {
+	if method != endpoint.Method {
+		return false
+	}
+
+	patternSegments := strings.Split(strings.Trim(endpoint.Pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return false
+	}
+
+	for index, patternSegment := range patternSegments {
+		if strings.HasPrefix(patternSegment, "{") && strings.HasSuffix(patternSegment, "}") {
+			if pathSegments[index] == "" {
+				return false
+			}
+
+			continue
+		}
+
+		if patternSegment != pathSegments[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Endpoints only applies a middleware to requests for the given
+// endpoints. Every other request skips it.
+func Endpoints(
+	endpoints []Endpoint,
+	middleware func(http.Handler) http.Handler,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := middleware(next)
+
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			path := requestPath(request)
+			for _, endpoint := range endpoints {
+				if endpoint.matches(request.Method, path) {
+					limited.ServeHTTP(response, request)
+
+					return
+				}
+			}
+
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
+// requestPath gives the path that the router will match, which
+// middleware like middleware.StripSlashes may have cleaned up.
+func requestPath(request *http.Request) string {
+	if routeContext := chi.RouteContext(request.Context()); routeContext != nil && routeContext.RoutePath != "" {
+		return routeContext.RoutePath
+	}
+
+	return request.URL.Path
+}
+
+// Body rejects requests with a body of more than maxSize bytes with a
+// 413 response. Bodies are read up front so that handlers never get
+// one that was cut off part way.
+func Body(maxSize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if request.Body == nil || request.Body == http.NoBody {
+				next.ServeHTTP(response, request)
+
+				return
+			}
+
+			if request.ContentLength > maxSize {
+				writeBodyTooLarge(response, request, maxSize)
+
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(request.Body, maxSize+1))
+			if err != nil {
+				writeError(response, request, http.StatusBadRequest, "Unable to read request body")
+
+				return
+			}
+
+			if int64(len(body)) > maxSize {
+				writeBodyTooLarge(response, request, maxSize)
+
+				return
+			}
+
+			request.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
+func writeBodyTooLarge(response http.ResponseWriter, request *http.Request, maxSize int64) {
+	// Closing the connection keeps the client from sending the rest of
+	// a body nobody is going to read.
+	response.Header().Set("Connection", "close")
+	writeError(
+		response,
+		request,
+		http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("Request body must be at most %d bytes", maxSize),
+	)
+}
+
+func writeError(
+	response http.ResponseWriter,
+	request *http.Request,
+	statusCode int,
+	message string,
+) {
+	response.WriteHeader(statusCode)
+	_ = render.Render(response, request, &renderable.ErrorResponse{
+		Message: message,
+	})
+}
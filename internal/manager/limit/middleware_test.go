@@ -0,0 +1,165 @@
+package limit_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/limit"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestsShouldRejectCallersOverTheirBudget(t *testing.T) {
+	handler := limit.Requests(
+		limit.NewLimiter(limit.Budget{Rate: 1, Burst: 1}),
+		limit.ByIP,
+	)(okHandler())
+
+	require.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/network", "10.0.0.1:1234", "").Code)
+
+	limited := serve(handler, http.MethodGet, "/network", "10.0.0.1:5678", "")
+	require.Equal(t, http.StatusTooManyRequests, limited.Code, "should limit the same address")
+	require.Equal(t, "1", limited.Header().Get("Retry-After"), "should say when to try again")
+	require.Contains(t, limited.Body.String(), "Too many requests")
+
+	other := serve(handler, http.MethodGet, "/network", "10.0.0.2:1234", "")
+	require.Equal(t, http.StatusOK, other.Code, "should not limit other addresses")
+}
+
+func TestRequestsShouldLimitCheckedTokensSeparately(t *testing.T) {
+	handler := withTokens(limit.Requests(
+		limit.NewLimiter(limit.Budget{Rate: 1, Burst: 1}),
+		limit.ByPrincipal,
+	)(okHandler()))
+
+	send := func(token string) int {
+		request := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusOK, send("first"))
+	require.Equal(t, http.StatusTooManyRequests, send("first"), "should limit the token")
+	require.Equal(t, http.StatusOK, send("second"), "should not limit other tokens")
+	require.Equal(t, http.StatusOK, send(""), "should leave anonymous requests alone")
+	require.Equal(t, http.StatusOK, send(""), "should leave anonymous requests alone")
+}
+
+func TestRequestsShouldNotTrustUncheckedTokens(t *testing.T) {
+	handler := withTokens(limit.Endpoints(
+		[]limit.Endpoint{{Method: http.MethodPost, Pattern: "/node"}},
+		limit.Requests(limit.NewLimiter(limit.Budget{Rate: 1, Burst: 1}), limit.ByPrincipalOrIP),
+	)(okHandler()))
+
+	for index := 0; index < 3; index++ {
+		request := httptest.NewRequest(http.MethodPost, "/node", strings.NewReader("{}"))
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer bogus-%d", index))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		expectedCode := http.StatusOK
+		if index > 0 {
+			expectedCode = http.StatusTooManyRequests
+		}
+		require.Equal(t, expectedCode, recorder.Code, "should limit rotating tokens by their address")
+	}
+}
+
+func TestEndpointsShouldOnlyApplyToMatchingRequests(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(limit.Endpoints(
+		[]limit.Endpoint{
+			{Method: http.MethodPost, Pattern: "/node"},
+			{Method: http.MethodPut, Pattern: "/node/{id}/key"},
+		},
+		limit.Requests(limit.NewLimiter(limit.Budget{Rate: 1, Burst: 1}), limit.ByIP),
+	))
+	router.Post("/node", okHandler().ServeHTTP)
+	router.Get("/node", okHandler().ServeHTTP)
+	router.Put("/node/{id}/key", okHandler().ServeHTTP)
+
+	address := "10.0.0.1:1234"
+	require.Equal(t, http.StatusOK, serve(router, http.MethodPost, "/node", address, "{}").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve(router, http.MethodPost, "/node", address, "{}").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve(router, http.MethodPut, "/node/abc/key", address, "{}").Code)
+	require.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/node", address, "").Code, "should skip others")
+}
+
+func TestBodyShouldRejectLargeBodies(t *testing.T) {
+	var received string
+	handler := limit.Body(8)(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		received = string(body)
+		response.WriteHeader(http.StatusOK)
+	}))
+
+	require.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/user", "10.0.0.1:1234", "12345678").Code)
+	require.Equal(t, "12345678", received, "should pass the body on")
+
+	tooLarge := serve(handler, http.MethodPost, "/user", "10.0.0.1:1234", "123456789")
+	require.Equal(t, http.StatusRequestEntityTooLarge, tooLarge.Code)
+	require.Contains(t, tooLarge.Body.String(), "Request body must be at most 8 bytes")
+
+	// Bodies of an unknown length are only found out while reading.
+	request := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader("123456789"))
+	request.ContentLength = -1
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code, "should reject streamed bodies too")
+}
+
+// withTokens checks bearer tokens the way the manager does, with
+// "first" and "second" being the only valid ones.
+func withTokens(handler http.Handler) http.Handler {
+	check := func(expected string) auth.TokenCheck {
+		return func(token string) error {
+			if token != expected {
+				return errors.New("invalid token")
+			}
+
+			return nil
+		}
+	}
+
+	return auth.Tokens(map[string]auth.TokenCheck{
+		"first":  check("first"),
+		"second": check("second"),
+	})(handler)
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusOK)
+	})
+}
+
+func serve(
+	handler http.Handler,
+	method string,
+	path string,
+	remoteAddress string,
+	body string,
+) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.RemoteAddr = remoteAddress
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
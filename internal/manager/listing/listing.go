@@ -0,0 +1,203 @@
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+)
+
+const (
+	// DefaultLimit is the page size used when none is requested.
+	DefaultLimit = 50
+
+	// MaxLimit is the largest page size that can be requested.
+	MaxLimit = 500
+)
+
+// SortField is the field that list results are ordered by.
+type SortField string
+
+const (
+	// SortFieldCreatedOn orders results by when they were created.
+	SortFieldCreatedOn SortField = "createdOn"
+
+	// SortFieldName orders results by their name.
+	SortFieldName SortField = "name"
+)
+
+// SortOrder is the direction that list results are ordered in.
+type SortOrder string
+
+const (
+	// SortOrderAscending orders results from smallest to largest.
+	SortOrderAscending SortOrder = "asc"
+
+	// SortOrderDescending orders results from largest to smallest.
+	SortOrderDescending SortOrder = "desc"
+)
+
+// Params holds the pagination, filtering and sorting options shared by
+// every list endpoint.
+type Params struct {
+	Limit         int
+	Cursor        *Cursor
+	NamePrefix    string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Labels        map[string]string
+	SortField     SortField
+	SortOrder     SortOrder
+}
+
+// Cursor marks where the previous page of results ended. It is handed
+// to clients as an opaque string.
+type Cursor struct {
+	SortField SortField `json:"f"`
+	SortOrder SortOrder `json:"o"`
+	LastValue string    `json:"v"`
+	LastID    string    `json:"i"`
+}
+
+// Encode converts the cursor into the opaque string given to clients.
+func (cursor *Cursor) Encode() string {
+	rawCursor, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(rawCursor)
+}
+
+// DecodeCursor parses an opaque cursor string given by a client.
+func DecodeCursor(encodedCursor string) (*Cursor, error) {
+	rawCursor, err := base64.RawURLEncoding.DecodeString(encodedCursor)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cursor: %w", err)
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(rawCursor, &cursor); err != nil {
+		return nil, fmt.Errorf("Invalid cursor: %w", err)
+	}
+
+	return &cursor, nil
+}
+
+// ParseParams reads list options from the query parameters of a
+// request:
+//
+//	limit          the maximum number of results to return
+//	cursor         the nextCursor value from the previous page
+//	namePrefix     only include results whose name starts with this
+//	createdAfter   only include results created at or after this time
+//	createdBefore  only include results created before this time
+//	label          key=value, may be given multiple times
+//	sort           createdOn (default) or name
+//	order          asc (default) or desc
+func ParseParams(query url.Values) (Params, error) {
+	params := Params{
+		Limit:     DefaultLimit,
+		SortField: SortFieldCreatedOn,
+		SortOrder: SortOrderAscending,
+	}
+
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return params, errortypes.NewValidationError(
+				"Query parameter 'limit' must be between 1 and %d",
+				MaxLimit,
+			)
+		}
+
+		params.Limit = limit
+	}
+
+	params.NamePrefix = query.Get("namePrefix")
+
+	for _, timeParam := range []struct {
+		name  string
+		value **time.Time
+	}{
+		{name: "createdAfter", value: &params.CreatedAfter},
+		{name: "createdBefore", value: &params.CreatedBefore},
+	} {
+		rawTime := query.Get(timeParam.name)
+		if rawTime == "" {
+			continue
+		}
+
+		parsedTime, err := time.Parse(time.RFC3339, rawTime)
+		if err != nil {
+			return params, errortypes.NewWrappedValidationError(
+				err,
+				"Query parameter '%s' must be an ISO-8601 timestamp",
+				timeParam.name,
+			)
+		}
+
+		*timeParam.value = &parsedTime
+	}
+
+	for _, rawLabel := range query["label"] {
+		key, value, ok := strings.Cut(rawLabel, "=")
+		if !ok || key == "" {
+			return params, errortypes.NewValidationError(
+				"Query parameter 'label' must be in the form key=value, got '%s'",
+				rawLabel,
+			)
+		}
+
+		if params.Labels == nil {
+			params.Labels = map[string]string{}
+		}
+
+		params.Labels[key] = value
+	}
+
+	switch sortField := SortField(query.Get("sort")); sortField {
+	case "":
+	case SortFieldCreatedOn, SortFieldName:
+		params.SortField = sortField
+
+	default:
+		return params, errortypes.NewValidationError(
+			"Query parameter 'sort' must be one of '%s' or '%s'",
+			SortFieldCreatedOn,
+			SortFieldName,
+		)
+	}
+
+	switch sortOrder := SortOrder(query.Get("order")); sortOrder {
+	case "":
+	case SortOrderAscending, SortOrderDescending:
+		params.SortOrder = sortOrder
+
+	default:
+		return params, errortypes.NewValidationError(
+			"Query parameter 'order' must be one of '%s' or '%s'",
+			SortOrderAscending,
+			SortOrderDescending,
+		)
+	}
+
+	if rawCursor := query.Get("cursor"); rawCursor != "" {
+		cursor, err := DecodeCursor(rawCursor)
+		if err != nil {
+			return params, errortypes.NewWrappedValidationError(err, "Query parameter 'cursor' is invalid")
+		}
+
+		if cursor.SortField != params.SortField || cursor.SortOrder != params.SortOrder {
+			return params, errortypes.NewValidationError(
+				"Query parameter 'cursor' was created with a different sort order",
+			)
+		}
+
+		params.Cursor = cursor
+	}
+
+	return params, nil
+}
@@ -0,0 +1,217 @@
+package listing_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/stretchr/testify/require"
+)
+
+var testColumns = listing.Columns{
+	ID:        "ID",
+	Name:      "Name",
+	CreatedOn: "CreatedOn",
+	Labels:    "Labels",
+}
+
+func TestParseParamsShouldUseDefaults(t *testing.T) {
+	params, err := listing.ParseParams(url.Values{})
+	require.Nil(t, err, "should be able to parse empty params")
+
+	require.Equal(
+		t,
+		listing.Params{
+			Limit:     listing.DefaultLimit,
+			SortField: listing.SortFieldCreatedOn,
+			SortOrder: listing.SortOrderAscending,
+		},
+		params,
+	)
+}
+
+func TestParseParamsShouldReadEveryOption(t *testing.T) {
+	params, err := listing.ParseParams(url.Values{
+		"limit":         {"10"},
+		"namePrefix":    {"prod"},
+		"createdAfter":  {"2022-01-01T00:00:00Z"},
+		"createdBefore": {"2022-02-01T00:00:00Z"},
+		"label":         {"team=infra", "env=prod"},
+		"sort":          {"name"},
+		"order":         {"desc"},
+	})
+	require.Nil(t, err, "should be able to parse params")
+
+	createdAfter := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	createdBefore := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(
+		t,
+		listing.Params{
+			Limit:         10,
+			NamePrefix:    "prod",
+			CreatedAfter:  &createdAfter,
+			CreatedBefore: &createdBefore,
+			Labels:        map[string]string{"team": "infra", "env": "prod"},
+			SortField:     listing.SortFieldName,
+			SortOrder:     listing.SortOrderDescending,
+		},
+		params,
+	)
+}
+
+func TestParseParamsShouldRejectInvalidValues(t *testing.T) {
+	cursor := listing.Cursor{
+		SortField: listing.SortFieldName,
+		SortOrder: listing.SortOrderAscending,
+		LastValue: "example",
+		LastID:    "1",
+	}
+
+	testCases := []struct {
+		query        url.Values
+		errorMessage string
+	}{
+		{
+			query:        url.Values{"limit": {"0"}},
+			errorMessage: "Query parameter 'limit' must be between 1 and 500",
+		},
+		{
+			query:        url.Values{"limit": {"five"}},
+			errorMessage: "Query parameter 'limit' must be between 1 and 500",
+		},
+		{
+			query:        url.Values{"createdAfter": {"yesterday"}},
+			errorMessage: "Query parameter 'createdAfter' must be an ISO-8601 timestamp",
+		},
+		{
+			query:        url.Values{"label": {"team"}},
+			errorMessage: "Query parameter 'label' must be in the form key=value, got 'team'",
+		},
+		{
+			query:        url.Values{"sort": {"status"}},
+			errorMessage: "Query parameter 'sort' must be one of 'createdOn' or 'name'",
+		},
+		{
+			query:        url.Values{"order": {"up"}},
+			errorMessage: "Query parameter 'order' must be one of 'asc' or 'desc'",
+		},
+		{
+			query:        url.Values{"cursor": {"not a cursor"}},
+			errorMessage: "Query parameter 'cursor' is invalid",
+		},
+		{
+			query:        url.Values{"cursor": {cursor.Encode()}},
+			errorMessage: "Query parameter 'cursor' was created with a different sort order",
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := listing.ParseParams(testCase.query)
+
+		var validationError errortypes.ValidationError
+		require.True(t, errors.As(err, &validationError), "should be a validation error for %v", testCase.query)
+		require.Equal(t, testCase.errorMessage, validationError.SafeMessage)
+	}
+}
+
+func TestSQLShouldBuildFiltersAndKeysetConditions(t *testing.T) {
+	createdAfter := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	params := listing.Params{
+		Limit:        10,
+		NamePrefix:   "prod_",
+		CreatedAfter: &createdAfter,
+		Labels:       map[string]string{"team": "infra"},
+		SortField:    listing.SortFieldName,
+		SortOrder:    listing.SortOrderDescending,
+		Cursor: &listing.Cursor{
+			SortField: listing.SortFieldName,
+			SortOrder: listing.SortOrderDescending,
+			LastValue: "prod_b",
+			LastID:    "42",
+		},
+	}
+
+	query, args, err := params.SQL(
+		"SELECT ID FROM Networks\n;",
+		testColumns,
+		[]string{"OrgID = $1"},
+		[]any{"org"},
+	)
+	require.Nil(t, err, "should be able to build the query")
+
+	require.Equal(
+		t,
+		"SELECT ID FROM Networks\n"+
+			"WHERE\n"+
+			"    OrgID = $1\n"+
+			"    AND Name LIKE $2\n"+
+			"    AND CreatedOn >= $3\n"+
+			"    AND Labels @> $4::jsonb\n"+
+			"    AND (Name, ID) < ($5, $6)\n"+
+			"ORDER BY Name DESC, ID DESC\n"+
+			"LIMIT $7\n"+
+			";",
+		query,
+	)
+	require.Equal(
+		t,
+		[]any{"org", `prod\_%`, createdAfter, `{"team":"infra"}`, "prod_b", "42", 11},
+		args,
+	)
+}
+
+func TestSQLShouldRejectLabelFiltersWithoutALabelColumn(t *testing.T) {
+	params := listing.Params{
+		Limit:     10,
+		Labels:    map[string]string{"team": "infra"},
+		SortField: listing.SortFieldCreatedOn,
+		SortOrder: listing.SortOrderAscending,
+	}
+
+	_, _, err := params.SQL("SELECT ID FROM Users", listing.Columns{ID: "ID"}, nil, nil)
+
+	var validationError errortypes.ValidationError
+	require.True(t, errors.As(err, &validationError), "should be a validation error")
+}
+
+func TestNewPageShouldCreateACursorWhenThereAreMoreResults(t *testing.T) {
+	params := listing.Params{
+		Limit:     2,
+		SortField: listing.SortFieldCreatedOn,
+		SortOrder: listing.SortOrderAscending,
+	}
+
+	createdOn := time.Date(2022, 1, 1, 0, 0, 0, 123000, time.UTC)
+	keys := []listing.Key{
+		{ID: "1", Name: "a", CreatedOn: createdOn},
+		{ID: "2", Name: "b", CreatedOn: createdOn},
+		{ID: "3", Name: "c", CreatedOn: createdOn},
+	}
+
+	page := listing.NewPage(params, keys, func(key *listing.Key) listing.Key {
+		return *key
+	})
+	require.Equal(t, keys[:2], page.Items, "should drop the extra result")
+
+	cursor, err := listing.DecodeCursor(page.NextCursor)
+	require.Nil(t, err, "should be able to decode the cursor")
+	require.Equal(
+		t,
+		&listing.Cursor{
+			SortField: listing.SortFieldCreatedOn,
+			SortOrder: listing.SortOrderAscending,
+			LastValue: "2022-01-01T00:00:00.000123Z",
+			LastID:    "2",
+		},
+		cursor,
+	)
+
+	lastPage := listing.NewPage(params, keys[2:], func(key *listing.Key) listing.Key {
+		return *key
+	})
+	require.Equal(t, keys[2:], lastPage.Items, "should keep every result")
+	require.Empty(t, lastPage.NextCursor, "should not have a cursor on the last page")
+}
@@ -0,0 +1,156 @@
+package listing
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+)
+
+// Columns maps the fields that can be filtered and sorted on to the
+// columns of a table.
+type Columns struct {
+	ID        string
+	Name      string
+	CreatedOn string
+
+	// Labels is a JSONB column holding string key/value pairs. Label
+	// filters are rejected when the table has no labels.
+	Labels string
+}
+
+// SQL appends the filtering, ordering and limit clauses for the params
+// to a SELECT statement. Conditions the caller needs on top of the
+// list filters are passed in along with their arguments, which use
+// placeholders starting at $1.
+//
+// One more row than the limit is selected so that NewPage can tell if
+// there is another page.
+func (params Params) SQL(
+	selectQuery string,
+	columns Columns,
+	conditions []string,
+	args []any,
+) (string, []any, error) {
+	conditions = append([]string(nil), conditions...)
+	args = append([]any(nil), args...)
+
+	addArg := func(value any) string {
+		args = append(args, value)
+
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if params.NamePrefix != "" {
+		escapedPrefix := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(params.NamePrefix)
+		conditions = append(conditions, fmt.Sprintf("%s LIKE %s", columns.Name, addArg(escapedPrefix+"%")))
+	}
+
+	if params.CreatedAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("%s >= %s", columns.CreatedOn, addArg(*params.CreatedAfter)))
+	}
+
+	if params.CreatedBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("%s < %s", columns.CreatedOn, addArg(*params.CreatedBefore)))
+	}
+
+	if len(params.Labels) > 0 {
+		if columns.Labels == "" {
+			return "", nil, errortypes.NewValidationError("Filtering by label is not supported here")
+		}
+
+		rawLabels, err := json.Marshal(params.Labels)
+		if err != nil {
+			return "", nil, fmt.Errorf("Unable to encode label filter: %w", err)
+		}
+
+		conditions = append(conditions, fmt.Sprintf("%s @> %s::jsonb", columns.Labels, addArg(string(rawLabels))))
+	}
+
+	sortColumn := columns.CreatedOn
+	if params.SortField == SortFieldName {
+		sortColumn = columns.Name
+	}
+
+	direction, comparison := "ASC", ">"
+	if params.SortOrder == SortOrderDescending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if params.Cursor != nil {
+		var lastValue any = params.Cursor.LastValue
+		if params.SortField == SortFieldCreatedOn {
+			lastTime, err := time.Parse(time.RFC3339Nano, params.Cursor.LastValue)
+			if err != nil {
+				return "", nil, errortypes.NewWrappedValidationError(err, "Query parameter 'cursor' is invalid")
+			}
+
+			lastValue = lastTime
+		}
+
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s, %s) %s (%s, %s)",
+			sortColumn,
+			columns.ID,
+			comparison,
+			addArg(lastValue),
+			addArg(params.Cursor.LastID),
+		))
+	}
+
+	var query strings.Builder
+	query.WriteString(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(selectQuery), ";")))
+
+	if len(conditions) > 0 {
+		query.WriteString("\nWHERE\n    ")
+		query.WriteString(strings.Join(conditions, "\n    AND "))
+	}
+
+	fmt.Fprintf(&query, "\nORDER BY %s %s, %s %s", sortColumn, direction, columns.ID, direction)
+	fmt.Fprintf(&query, "\nLIMIT %s\n;", addArg(params.Limit+1))
+
+	return query.String(), args, nil
+}
+
+// Key holds the values of an item that pagination is based on.
+type Key struct {
+	ID        string
+	Name      string
+	CreatedOn time.Time
+}
+
+// Page is a single page of list results.
+type Page[Item any] struct {
+	Items      []Item
+	NextCursor string
+}
+
+// NewPage creates a page from the rows selected by a query built with
+// Params.SQL, dropping the extra row and creating the cursor for the
+// next page if there is one.
+func NewPage[Item any](params Params, items []Item, keyOf func(item *Item) Key) Page[Item] {
+	if len(items) <= params.Limit {
+		return Page[Item]{Items: items}
+	}
+
+	items = items[:params.Limit]
+	lastKey := keyOf(&items[len(items)-1])
+
+	cursor := Cursor{
+		SortField: params.SortField,
+		SortOrder: params.SortOrder,
+		LastValue: lastKey.CreatedOn.UTC().Format(time.RFC3339Nano),
+		LastID:    lastKey.ID,
+	}
+
+	if params.SortField == SortFieldName {
+		cursor.LastValue = lastKey.Name
+	}
+
+	return Page[Item]{
+		Items:      items,
+		NextCursor: cursor.Encode(),
+	}
+}
@@ -4,18 +4,37 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/durandj/ley/internal/common/logging"
+	"github.com/durandj/ley/internal/manager/auth"
 	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/idempotency"
+	"github.com/durandj/ley/internal/manager/nameserver"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/scheduler"
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/durandj/ley/internal/manager/webhook"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // Server runs the management part of Ley.
 type Server struct {
-	logger     *zap.Logger
-	httpServer http.Server
-	db         *sql.DB
+	logger       *zap.Logger
+	httpServer   http.Server
+	grpcServer   *grpc.Server
+	grpcAddress  string
+	nameserver   *nameserver.Server
+	dnsAddress   string
+	controller   *Controller
+	db           *sql.DB
+	scheduler    *scheduler.Scheduler
+	pollInterval time.Duration
 }
 
 // New creates a service instance from the configuration.
@@ -28,6 +47,58 @@ func New(config *configuration.Configuration) (*Server, error) {
 		return nil, fmt.Errorf("Unable to setup logger: %w", err)
 	}
 
+	db, err := OpenDB(config)
+	if err != nil {
+		return nil, err
+	}
+
+	jobScheduler, err := newScheduler(db, config, logger)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to setup scheduler: %w", err)
+	}
+
+	controller := NewController(db, config)
+
+	var grpcServer *grpc.Server
+	if config.Service.GRPCPort != 0 {
+		grpcServer = NewGRPCServer(db, config, controller.hub, controller.limiters, logger)
+	}
+
+	var nameServer *nameserver.Server
+	if config.DNS.Enabled() {
+		recursionSources, err := config.DNS.RecursionPrefixes()
+		if err != nil {
+			return nil, err
+		}
+
+		nameServer = nameserver.New(newNodeService(db, config), nameserver.Opts{
+			Zone:             config.DNS.Zone,
+			TTL:              config.DNS.TTL,
+			Upstreams:        config.DNS.Upstreams,
+			RecursionSources: recursionSources,
+			QueryTimeout:     config.DNS.QueryTimeout,
+		})
+	}
+
+	return &Server{
+		logger: logger,
+		httpServer: http.Server{
+			Addr:    config.Service.Address(),
+			Handler: controller,
+		},
+		grpcServer:   grpcServer,
+		grpcAddress:  config.Service.GRPCAddress(),
+		nameserver:   nameServer,
+		dnsAddress:   config.DNS.ListenAddress,
+		controller:   controller,
+		db:           db,
+		scheduler:    jobScheduler,
+		pollInterval: config.Scheduler.PollInterval,
+	}, nil
+}
+
+// OpenDB connects to the configured database.
+func OpenDB(config *configuration.Configuration) (*sql.DB, error) {
 	dbConnectionString, err := config.DB.ConnectionString()
 	if err != nil {
 		return nil, fmt.Errorf("Unable to create database connection string: %w", err)
@@ -39,24 +110,127 @@ func New(config *configuration.Configuration) (*Server, error) {
 	}
 
 	if err := db.Ping(); err != nil {
+		_ = db.Close()
+
 		return nil, fmt.Errorf("Unable to connect to database: %w", err)
 	}
 
-	return &Server{
-		logger: logger,
-		httpServer: http.Server{
-			Addr:    config.Service.Address(),
-			Handler: NewController(db),
+	return db, nil
+}
+
+func newNodeService(db *sql.DB, config *configuration.Configuration) *node.Service {
+	organizationService := organization.NewService(db)
+	networkService := network.NewService(db, config.Network, organizationService)
+
+	return node.NewService(db, networkService, organizationService, config.Node, config.DNS)
+}
+
+// newScheduler registers the periodic jobs of the manager. Every
+// replica registers the same jobs and the scheduler makes sure that
+// only one of them runs each job at a time.
+func newScheduler(
+	db *sql.DB,
+	config *configuration.Configuration,
+	logger *zap.Logger,
+) (*scheduler.Scheduler, error) {
+	holder := config.Scheduler.ReplicaID
+	if holder == "" {
+		holder = scheduler.DefaultHolder()
+	}
+
+	jobStore := scheduler.NewStore(db)
+	jobScheduler := scheduler.New(jobStore, logger, holder)
+
+	jobs := []scheduler.Job{
+		{
+			Name:     "prune-job-runs",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				cutoff := time.Now().UTC().Add(-config.Scheduler.RunRetention)
+				_, err := jobStore.DeleteRunsBefore(ctx, cutoff)
+
+				return err
+			},
 		},
-		db: db,
-	}, nil
+	}
+
+	webhookService := webhook.NewService(db, config.Webhook)
+	dispatcher := webhook.NewDispatcher(webhookService, webhook.NewHTTPClient(config.Webhook), config.Webhook)
+	jobs = append(
+		jobs,
+		scheduler.Job{
+			Name:     "deliver-webhooks",
+			Interval: config.Webhook.DeliveryInterval,
+			Run: func(ctx context.Context) error {
+				return dispatcher.DeliverDue(ctx, time.Now().UTC())
+			},
+		},
+		scheduler.Job{
+			Name:     "prune-webhook-events",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				cutoff := time.Now().UTC().Add(-config.Webhook.Retention)
+				_, err := webhookService.DeleteEventsBefore(ctx, cutoff)
+
+				return err
+			},
+		},
+	)
+
+	authService := auth.NewService(db, nil, user.NewService(db), config.OIDC)
+	jobs = append(jobs, scheduler.Job{
+		Name:     "delete-expired-sessions",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			_, err := authService.DeleteExpired(ctx, time.Now().UTC())
+
+			return err
+		},
+	})
+
+	if config.Node.ReapInterval > 0 {
+		nodeService := newNodeService(db, config)
+		jobs = append(jobs, scheduler.Job{
+			Name:     "reap-nodes",
+			Interval: config.Node.ReapInterval,
+			Run: func(ctx context.Context) error {
+				reaped, err := nodeService.ReapNodes(ctx, time.Now().UTC())
+				if reaped > 0 {
+					logger.Info(fmt.Sprintf("Reaped %d nodes", reaped))
+				}
+
+				return err
+			},
+		})
+	}
+
+	if config.API.IdempotencyWindow > 0 {
+		keyStore := idempotency.NewStore(db)
+		jobs = append(jobs, scheduler.Job{
+			Name:     "delete-expired-idempotency-keys",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				_, err := keyStore.DeleteExpired(ctx)
+
+				return err
+			},
+		})
+	}
+
+	for _, job := range jobs {
+		if err := jobScheduler.Register(job); err != nil {
+			return nil, err
+		}
+	}
+
+	return jobScheduler, nil
 }
 
 // Run starts the service.
 func (server *Server) Run(ctx context.Context) error {
 	server.logger.Info(fmt.Sprintf("Starting HTTP server '%s'", server.httpServer.Addr))
 
-	errChannel := make(chan error, 1)
+	errChannel := make(chan error, 3)
 
 	go func() {
 		if err := server.httpServer.ListenAndServe(); err != nil {
@@ -66,6 +240,33 @@ func (server *Server) Run(ctx context.Context) error {
 		errChannel <- nil
 	}()
 
+	if server.grpcServer != nil {
+		listener, err := net.Listen("tcp", server.grpcAddress)
+		if err != nil {
+			return fmt.Errorf("Unable to listen for gRPC on '%s': %w", server.grpcAddress, err)
+		}
+
+		server.logger.Info(fmt.Sprintf("Starting gRPC server '%s'", server.grpcAddress))
+
+		go func() {
+			if err := server.grpcServer.Serve(listener); err != nil {
+				errChannel <- fmt.Errorf("gRPC server stopped: %w", err)
+			}
+		}()
+	}
+
+	if server.nameserver != nil {
+		server.logger.Info(fmt.Sprintf("Starting DNS server '%s'", server.dnsAddress))
+
+		go func() {
+			if err := server.nameserver.ListenAndServe(ctx, server.dnsAddress); err != nil && ctx.Err() == nil {
+				errChannel <- err
+			}
+		}()
+	}
+
+	go server.scheduler.Run(ctx, server.pollInterval)
+
 	select {
 	case err := <-errChannel:
 		return err
@@ -78,5 +279,14 @@ func (server *Server) Run(ctx context.Context) error {
 // CleanUp is called when the server needs resources freed to terminate
 // cleanly.
 func (server *Server) CleanUp() {
+	if server.grpcServer != nil {
+		server.grpcServer.Stop()
+	}
+
+	// Jobs that are still running need the database until they notice
+	// that the context is done.
+	server.scheduler.Wait()
+
+	_ = server.controller.Close()
 	_ = server.db.Close()
 }
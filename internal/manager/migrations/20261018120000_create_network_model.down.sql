@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS NetworksUpdateModifiedOn ON Networks;
+
+DROP FUNCTION IF EXISTS update_network_modified_on_timestamp;
+
+DROP TABLE IF EXISTS Networks;
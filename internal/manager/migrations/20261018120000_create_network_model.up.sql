@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS Networks (
+    ID          VARCHAR(255) PRIMARY KEY,
+    Name        VARCHAR(64) UNIQUE,
+    IPv4CIDR    CIDR,
+    IPv6CIDR    CIDR,
+    Labels      JSONB NOT NULL DEFAULT '{}',
+    CreatedOn   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ModifiedOn  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS NetworksCreatedOnIndex ON Networks (CreatedOn, ID);
+
+CREATE INDEX IF NOT EXISTS NetworksLabelsIndex ON Networks USING GIN (Labels);
+
+CREATE OR REPLACE FUNCTION update_network_modified_on_timestamp()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.ModifiedOn = now();
+
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE OR REPLACE TRIGGER NetworksUpdateModifiedOn BEFORE UPDATE
+ON Networks
+FOR EACH ROW EXECUTE PROCEDURE update_network_modified_on_timestamp()
+;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS UsersCreatedOnIndex ON Users (CreatedOn, ID);
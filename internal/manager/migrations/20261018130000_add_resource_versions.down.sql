@@ -0,0 +1,3 @@
+ALTER TABLE Networks DROP COLUMN IF EXISTS Version;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS Version;
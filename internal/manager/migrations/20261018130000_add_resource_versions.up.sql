@@ -0,0 +1,3 @@
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS Version BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE Networks ADD COLUMN IF NOT EXISTS Version BIGINT NOT NULL DEFAULT 1;
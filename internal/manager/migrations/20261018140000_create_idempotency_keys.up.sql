@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS IdempotencyKeys (
+    Caller          VARCHAR(255),
+    Key             VARCHAR(255),
+    RequestHash     VARCHAR(64) NOT NULL,
+    StatusCode      INTEGER,
+    ResponseHeaders JSONB,
+    ResponseBody    BYTEA,
+    CreatedOn       TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ExpiresOn       TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (Caller, Key)
+);
+
+CREATE INDEX IF NOT EXISTS IdempotencyKeysExpiresOnIndex ON IdempotencyKeys (ExpiresOn);
@@ -0,0 +1,5 @@
+ALTER TABLE Networks DROP CONSTRAINT IF EXISTS networks_ipv6cidr_excl;
+
+ALTER TABLE Networks DROP CONSTRAINT IF EXISTS networks_ipv4cidr_excl;
+
+ALTER TABLE Networks DROP COLUMN IF EXISTS AllowOverlap;
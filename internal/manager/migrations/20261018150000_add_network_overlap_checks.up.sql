@@ -0,0 +1,13 @@
+ALTER TABLE Networks ADD COLUMN IF NOT EXISTS AllowOverlap BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE Networks
+ADD CONSTRAINT networks_ipv4cidr_excl
+EXCLUDE USING gist (IPv4CIDR inet_ops WITH &&)
+WHERE (NOT AllowOverlap)
+;
+
+ALTER TABLE Networks
+ADD CONSTRAINT networks_ipv6cidr_excl
+EXCLUDE USING gist (IPv6CIDR inet_ops WITH &&)
+WHERE (NOT AllowOverlap)
+;
@@ -0,0 +1 @@
+ALTER TABLE Networks DROP COLUMN IF EXISTS Topology;
@@ -0,0 +1 @@
+ALTER TABLE Networks ADD COLUMN IF NOT EXISTS Topology JSONB NOT NULL DEFAULT '{"mode": "full-mesh"}';
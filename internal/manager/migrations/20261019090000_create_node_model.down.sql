@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS NodeEvents;
+
+DROP TABLE IF EXISTS PresharedKeys;
+
+DROP TABLE IF EXISTS Nodes;
+
+ALTER TABLE Networks DROP COLUMN IF EXISTS PresharedKeys;
@@ -0,0 +1,48 @@
+ALTER TABLE Networks ADD COLUMN IF NOT EXISTS PresharedKeys BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS Nodes (
+    ID                      VARCHAR(255) PRIMARY KEY,
+    NetworkID               VARCHAR(255) NOT NULL REFERENCES Networks (ID) ON DELETE CASCADE,
+    Name                    VARCHAR(63) NOT NULL,
+    PublicKey               VARCHAR(44) NOT NULL,
+    PreviousPublicKey       VARCHAR(44),
+    PreviousKeyExpiresOn    TIMESTAMP WITH TIME ZONE,
+    KeyRotatedOn            TIMESTAMP WITH TIME ZONE NOT NULL,
+    KeyRotationRequested    BOOLEAN NOT NULL DEFAULT FALSE,
+    Endpoint                VARCHAR(255),
+    IPv4Address             INET,
+    IPv6Address             INET,
+    Version                 BIGINT NOT NULL DEFAULT 1,
+    CreatedOn               TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ModifiedOn              TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT nodes_network_name_key UNIQUE (NetworkID, Name),
+    CONSTRAINT nodes_public_key_key UNIQUE (PublicKey),
+    CONSTRAINT nodes_ipv4_address_key UNIQUE (NetworkID, IPv4Address),
+    CONSTRAINT nodes_ipv6_address_key UNIQUE (NetworkID, IPv6Address)
+);
+
+CREATE INDEX IF NOT EXISTS NodesCreatedOnIndex ON Nodes (CreatedOn, ID);
+
+CREATE INDEX IF NOT EXISTS NodesPreviousPublicKeyIndex ON Nodes (PreviousPublicKey);
+
+CREATE TABLE IF NOT EXISTS PresharedKeys (
+    FirstNodeID     VARCHAR(255) NOT NULL REFERENCES Nodes (ID) ON DELETE CASCADE,
+    SecondNodeID    VARCHAR(255) NOT NULL REFERENCES Nodes (ID) ON DELETE CASCADE,
+    Key             VARCHAR(44) NOT NULL,
+    CreatedOn       TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (FirstNodeID, SecondNodeID),
+    CHECK (FirstNodeID < SecondNodeID)
+);
+
+CREATE INDEX IF NOT EXISTS PresharedKeysSecondNodeIndex ON PresharedKeys (SecondNodeID);
+
+CREATE TABLE IF NOT EXISTS NodeEvents (
+    ID          BIGSERIAL PRIMARY KEY,
+    NetworkID   VARCHAR(255) NOT NULL REFERENCES Networks (ID) ON DELETE CASCADE,
+    NodeID      VARCHAR(255) NOT NULL,
+    NodeName    VARCHAR(63) NOT NULL,
+    Type        VARCHAR(64) NOT NULL,
+    CreatedOn   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS NodeEventsNetworkIndex ON NodeEvents (NetworkID, ID);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS NodePeerStats;
+
+DROP INDEX IF EXISTS NodesLastSeenOnIndex;
+
+ALTER TABLE Nodes DROP COLUMN IF EXISTS LastSeenOn;
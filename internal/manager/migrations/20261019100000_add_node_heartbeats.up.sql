@@ -0,0 +1,14 @@
+ALTER TABLE Nodes ADD COLUMN IF NOT EXISTS LastSeenOn TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS NodesLastSeenOnIndex ON Nodes (LastSeenOn);
+
+CREATE TABLE IF NOT EXISTS NodePeerStats (
+    NodeID              VARCHAR(255) NOT NULL REFERENCES Nodes (ID) ON DELETE CASCADE,
+    PeerPublicKey       VARCHAR(44) NOT NULL,
+    LatestHandshakeOn   TIMESTAMP WITH TIME ZONE,
+    ReceiveBytes        BIGINT NOT NULL DEFAULT 0,
+    TransmitBytes       BIGINT NOT NULL DEFAULT 0,
+    Endpoint            VARCHAR(255),
+    ReportedOn          TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (NodeID, PeerPublicKey)
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS NodesEphemeralIndex;
+
+DROP INDEX IF EXISTS NodesExpiresOnIndex;
+
+ALTER TABLE Nodes DROP COLUMN IF EXISTS ExpiresOn;
+
+ALTER TABLE Nodes DROP COLUMN IF EXISTS Ephemeral;
@@ -0,0 +1,7 @@
+ALTER TABLE Nodes ADD COLUMN IF NOT EXISTS Ephemeral BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE Nodes ADD COLUMN IF NOT EXISTS ExpiresOn TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS NodesExpiresOnIndex ON Nodes (ExpiresOn) WHERE ExpiresOn IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS NodesEphemeralIndex ON Nodes (LastSeenOn) WHERE Ephemeral;
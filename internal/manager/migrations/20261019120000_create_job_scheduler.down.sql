@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS JobRuns;
+
+DROP TABLE IF EXISTS JobLeases;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS JobLeases (
+    Name        VARCHAR(255) PRIMARY KEY,
+    Holder      VARCHAR(255) NOT NULL,
+    ExpiresOn   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS JobRuns (
+    ID          BIGSERIAL PRIMARY KEY,
+    JobName     VARCHAR(255) NOT NULL,
+    Holder      VARCHAR(255) NOT NULL,
+    Status      VARCHAR(16) NOT NULL,
+    Error       TEXT,
+    StartedOn   TIMESTAMP WITH TIME ZONE NOT NULL,
+    FinishedOn  TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS JobRunsJobIndex ON JobRuns (JobName, ID);
+
+CREATE INDEX IF NOT EXISTS JobRunsStartedOnIndex ON JobRuns (StartedOn);
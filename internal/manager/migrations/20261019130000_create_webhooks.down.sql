@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS WebhookDeliveries;
+
+DROP TABLE IF EXISTS WebhookEvents;
+
+DROP TABLE IF EXISTS WebhookSubscriptions;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS WebhookSubscriptions (
+    ID          VARCHAR(255) PRIMARY KEY,
+    URL         TEXT NOT NULL,
+    EventTypes  TEXT[] NOT NULL,
+    Secret      VARCHAR(255) NOT NULL,
+    CreatedOn   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS WebhookSubscriptionsCreatedOnIndex ON WebhookSubscriptions (CreatedOn, ID);
+
+CREATE TABLE IF NOT EXISTS WebhookEvents (
+    ID          VARCHAR(255) PRIMARY KEY,
+    Type        VARCHAR(64) NOT NULL,
+    Payload     JSONB NOT NULL,
+    CreatedOn   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS WebhookEventsCreatedOnIndex ON WebhookEvents (CreatedOn);
+
+CREATE TABLE IF NOT EXISTS WebhookDeliveries (
+    ID              BIGSERIAL PRIMARY KEY,
+    SubscriptionID  VARCHAR(255) NOT NULL REFERENCES WebhookSubscriptions (ID) ON DELETE CASCADE,
+    EventID         VARCHAR(255) NOT NULL REFERENCES WebhookEvents (ID) ON DELETE CASCADE,
+    Status          VARCHAR(16) NOT NULL,
+    Attempts        INTEGER NOT NULL DEFAULT 0,
+    NextAttemptOn   TIMESTAMP WITH TIME ZONE,
+    LastAttemptOn   TIMESTAMP WITH TIME ZONE,
+    LastStatusCode  INTEGER,
+    LastError       TEXT,
+    CreatedOn       TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS WebhookDeliveriesDueIndex ON WebhookDeliveries (NextAttemptOn) WHERE Status = 'pending';
+
+CREATE INDEX IF NOT EXISTS WebhookDeliveriesSubscriptionIndex ON WebhookDeliveries (SubscriptionID, ID);
+
+CREATE INDEX IF NOT EXISTS WebhookDeliveriesEventIndex ON WebhookDeliveries (EventID);
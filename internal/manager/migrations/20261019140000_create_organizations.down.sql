@@ -0,0 +1,9 @@
+ALTER TABLE Networks DROP CONSTRAINT IF EXISTS networks_organization_name_key;
+
+ALTER TABLE Networks ADD CONSTRAINT networks_name_key UNIQUE (Name);
+
+ALTER TABLE Networks DROP COLUMN IF EXISTS OrganizationID;
+
+DROP TABLE IF EXISTS OrganizationMembers;
+
+DROP TABLE IF EXISTS Organizations;
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS Organizations (
+    ID          VARCHAR(255) PRIMARY KEY,
+    Name        VARCHAR(64) NOT NULL UNIQUE,
+    Version     BIGINT NOT NULL DEFAULT 1,
+    CreatedOn   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ModifiedOn  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS OrganizationsCreatedOnIndex ON Organizations (CreatedOn, ID);
+
+-- Everything that existed before organizations belongs to the default
+-- organization, which is also what the routes without an organization
+-- use.
+INSERT INTO Organizations (ID, Name)
+VALUES ('default', 'default')
+ON CONFLICT DO NOTHING
+;
+
+CREATE TABLE IF NOT EXISTS OrganizationMembers (
+    OrganizationID  VARCHAR(255) NOT NULL REFERENCES Organizations (ID) ON DELETE CASCADE,
+    UserID          VARCHAR(255) NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+    CreatedOn       TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (OrganizationID, UserID)
+);
+
+CREATE INDEX IF NOT EXISTS OrganizationMembersUserIndex ON OrganizationMembers (UserID);
+
+INSERT INTO OrganizationMembers (OrganizationID, UserID)
+SELECT 'default', ID FROM Users
+ON CONFLICT DO NOTHING
+;
+
+ALTER TABLE Networks
+ADD COLUMN IF NOT EXISTS OrganizationID VARCHAR(255) NOT NULL DEFAULT 'default'
+REFERENCES Organizations (ID) ON DELETE RESTRICT
+;
+
+ALTER TABLE Networks DROP CONSTRAINT IF EXISTS networks_name_key;
+
+ALTER TABLE Networks
+ADD CONSTRAINT networks_organization_name_key UNIQUE (OrganizationID, Name)
+;
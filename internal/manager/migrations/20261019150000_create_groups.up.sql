@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS Groups (
+    ID          VARCHAR(255) PRIMARY KEY,
+    Name        VARCHAR(64) NOT NULL UNIQUE,
+    Version     BIGINT NOT NULL DEFAULT 1,
+    CreatedOn   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ModifiedOn  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS GroupsCreatedOnIndex ON Groups (CreatedOn, ID);
+
+-- Each member of a group is either a user or another group, whose
+-- members then also belong to the group.
+CREATE TABLE IF NOT EXISTS GroupMembers (
+    GroupID         VARCHAR(255) NOT NULL REFERENCES Groups (ID) ON DELETE CASCADE,
+    UserID          VARCHAR(255) REFERENCES Users (ID) ON DELETE CASCADE,
+    MemberGroupID   VARCHAR(255) REFERENCES Groups (ID) ON DELETE CASCADE,
+    CreatedOn       TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT group_members_one_member_check CHECK ((UserID IS NULL) <> (MemberGroupID IS NULL)),
+    CONSTRAINT group_members_user_key UNIQUE (GroupID, UserID),
+    CONSTRAINT group_members_group_key UNIQUE (GroupID, MemberGroupID)
+);
+
+CREATE INDEX IF NOT EXISTS GroupMembersUserIndex ON GroupMembers (UserID);
+
+CREATE INDEX IF NOT EXISTS GroupMembersMemberGroupIndex ON GroupMembers (MemberGroupID);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS Sessions;
+
+DROP TABLE IF EXISTS LoginStates;
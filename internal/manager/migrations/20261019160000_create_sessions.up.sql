@@ -0,0 +1,23 @@
+-- Logins that were started with the OpenID Connect provider but
+-- haven't come back yet.
+CREATE TABLE IF NOT EXISTS LoginStates (
+    State       VARCHAR(255) PRIMARY KEY,
+    Nonce       VARCHAR(255) NOT NULL,
+    Verifier    VARCHAR(255) NOT NULL,
+    CreatedOn   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ExpiresOn   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS LoginStatesExpiresOnIndex ON LoginStates (ExpiresOn);
+
+-- Only a hash of each session token is kept so that the tokens can't
+-- be read back from the database.
+CREATE TABLE IF NOT EXISTS Sessions (
+    ID          VARCHAR(255) PRIMARY KEY,
+    TokenHash   VARCHAR(64) NOT NULL UNIQUE,
+    UserID      VARCHAR(255) NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+    CreatedOn   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ExpiresOn   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS SessionsExpiresOnIndex ON Sessions (ExpiresOn);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS users_external_id_key;
+
+DROP INDEX IF EXISTS users_email_key;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS Metadata;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS ExternalID;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS EmailVerified;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS Email;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS DisplayName;
@@ -0,0 +1,15 @@
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS DisplayName VARCHAR(128) NOT NULL DEFAULT '';
+
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS Email VARCHAR(254);
+
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS EmailVerified BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS ExternalID VARCHAR(255);
+
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS Metadata JSONB NOT NULL DEFAULT '{}';
+
+-- Email addresses are looked up without regard to case so the same
+-- address can't be given to two users by writing it differently.
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON Users (LOWER(Email));
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_external_id_key ON Users (ExternalID);
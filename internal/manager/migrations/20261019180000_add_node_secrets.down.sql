@@ -0,0 +1 @@
+ALTER TABLE Nodes DROP COLUMN IF EXISTS SecretHash;
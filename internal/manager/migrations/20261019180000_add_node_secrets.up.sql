@@ -0,0 +1,3 @@
+-- Nodes registered before secrets existed have none and have to be
+-- given one before they can fetch their config.
+ALTER TABLE Nodes ADD COLUMN IF NOT EXISTS SecretHash VARCHAR(64);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS networks_organization_name_key;
+
+ALTER TABLE Networks
+ADD CONSTRAINT networks_organization_name_key UNIQUE (OrganizationID, Name)
+;
+
+DROP INDEX IF EXISTS organizations_name_key;
+
+ALTER TABLE Organizations
+ADD CONSTRAINT organizations_name_key UNIQUE (Name)
+;
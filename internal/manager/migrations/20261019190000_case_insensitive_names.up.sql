@@ -0,0 +1,10 @@
+-- The nameserver looks organizations and networks up by name without
+-- caring about case, so names that only differ in case can't be told
+-- apart and are no longer allowed.
+ALTER TABLE Organizations DROP CONSTRAINT IF EXISTS organizations_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS organizations_name_key ON Organizations (LOWER(Name));
+
+ALTER TABLE Networks DROP CONSTRAINT IF EXISTS networks_organization_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS networks_organization_name_key ON Networks (OrganizationID, LOWER(Name));
@@ -0,0 +1,509 @@
+// Package nameserver serves DNS for the nodes in each network. Names
+// take the form <node>.<network>.<zone> for networks of the default
+// organization and <node>.<network>.<organization>.<zone> for the
+// others. Anything outside of the zone is forwarded to the configured
+// upstream servers for the source networks that are allowed to recurse.
+package nameserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"inet.af/netaddr"
+)
+
+const (
+	// DefaultZone is the zone that node names are served under when no
+	// other zone is configured.
+	DefaultZone = "ley.internal."
+
+	// DefaultTTL is how long answers for node names may be cached when
+	// no other TTL is configured.
+	DefaultTTL = 60 * time.Second
+
+	// DefaultQueryTimeout is how long a query may take to answer when
+	// no other timeout is configured.
+	DefaultQueryTimeout = 5 * time.Second
+
+	upstreamTimeout = 2 * time.Second
+)
+
+// Source looks up the addresses that were assigned to nodes. An empty
+// organization stands for the default organization.
+type Source interface {
+	// LookupNode gives the addresses of a node in a network. Found is
+	// false when there is no such node.
+	LookupNode(
+		ctx context.Context,
+		organization string,
+		network string,
+		node string,
+	) (ips []netaddr.IP, found bool, err error)
+
+	// LookupIP gives the node that an address was assigned to, as long
+	// as it is in the same organization as a node using the source
+	// address that asked. Found is false otherwise.
+	LookupIP(
+		ctx context.Context,
+		ip netaddr.IP,
+		source netaddr.IP,
+	) (organization string, network string, node string, found bool, err error)
+}
+
+// NetworkDomain gives the domain that the names of a network's nodes
+// are under. An empty organization stands for the default
+// organization.
+func NetworkDomain(zone string, organization string, network string) string {
+	if zone == "" {
+		zone = DefaultZone
+	}
+
+	domain := network + "."
+	if organization != "" {
+		domain += organization + "."
+	}
+
+	return dns.CanonicalName(domain + dns.Fqdn(zone))
+}
+
+// Opts gives the optional settings for a server.
+type Opts struct {
+	// Zone is the domain that node names are served under. Zero uses
+	// DefaultZone.
+	Zone string
+
+	// TTL is how long answers for node names may be cached. Zero uses
+	// DefaultTTL.
+	TTL time.Duration
+
+	// Upstreams are the servers, as host:port, that queries outside of
+	// the zone are forwarded to. They are tried in order. Without any
+	// upstreams those queries are refused.
+	Upstreams []string
+
+	// RecursionSources are the networks that queries are forwarded to
+	// the upstreams for. Queries outside of the zone from anywhere else
+	// are refused so that the server isn't an open resolver.
+	RecursionSources []netaddr.IPPrefix
+
+	// QueryTimeout is how long a query may take to answer, including
+	// forwarding it. Zero uses DefaultQueryTimeout.
+	QueryTimeout time.Duration
+}
+
+// Server answers DNS queries for node names.
+type Server struct {
+	source           Source
+	zone             string
+	ttl              uint32
+	upstreams        []string
+	recursionSources []netaddr.IPPrefix
+	queryTimeout     time.Duration
+	udpClient        *dns.Client
+	tcpClient        *dns.Client
+}
+
+// New creates a server that answers from the given source.
+func New(source Source, opts Opts) *Server {
+	zone := opts.Zone
+	if zone == "" {
+		zone = DefaultZone
+	}
+
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	queryTimeout := opts.QueryTimeout
+	if queryTimeout == 0 {
+		queryTimeout = DefaultQueryTimeout
+	}
+
+	return &Server{
+		source:           source,
+		zone:             dns.CanonicalName(zone),
+		ttl:              uint32(ttl / time.Second),
+		upstreams:        opts.Upstreams,
+		recursionSources: opts.RecursionSources,
+		queryTimeout:     queryTimeout,
+		udpClient: &dns.Client{
+			Net:     "udp",
+			Timeout: upstreamTimeout,
+		},
+		tcpClient: &dns.Client{
+			Net:     "tcp",
+			Timeout: upstreamTimeout,
+		},
+	}
+}
+
+// Serve answers queries that arrive on the packet connection or the
+// listener until the context is done. Clients whose answer didn't fit
+// in UDP retry over TCP.
+func (server *Server) Serve(ctx context.Context, packetConn net.PacketConn, listener net.Listener) error {
+	udpServer := dns.Server{
+		PacketConn: packetConn,
+		Handler:    server,
+	}
+
+	tcpServer := dns.Server{
+		Listener: listener,
+		Handler:  server,
+	}
+
+	errChannel := make(chan error, 2)
+	go func() {
+		errChannel <- udpServer.ActivateAndServe()
+	}()
+	go func() {
+		errChannel <- tcpServer.ActivateAndServe()
+	}()
+
+	defer func() {
+		_ = udpServer.Shutdown()
+		_ = tcpServer.Shutdown()
+		_ = packetConn.Close()
+		_ = listener.Close()
+	}()
+
+	select {
+	case err := <-errChannel:
+		if err != nil {
+			return fmt.Errorf("DNS server stopped: %w", err)
+		}
+
+		return nil
+
+	case <-ctx.Done():
+		return fmt.Errorf("DNS server stopped: %w", ctx.Err())
+	}
+}
+
+// ListenAndServe listens for UDP and TCP queries on the address and
+// answers them until the context is done.
+func (server *Server) ListenAndServe(ctx context.Context, address string) error {
+	packetConn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return fmt.Errorf("Unable to listen for DNS queries on '%s': %w", address, err)
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		_ = packetConn.Close()
+
+		return fmt.Errorf("Unable to listen for DNS queries on '%s': %w", address, err)
+	}
+
+	return server.Serve(ctx, packetConn, listener)
+}
+
+// query is a question along with where it came from.
+type query struct {
+	request *dns.Msg
+	source  netaddr.IP
+	overTCP bool
+}
+
+// ServeDNS answers a single query. Answers that don't fit in the UDP
+// message size of the client are truncated so that it retries over
+// TCP.
+func (server *Server) ServeDNS(writer dns.ResponseWriter, request *dns.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), server.queryTimeout)
+	defer cancel()
+
+	currentQuery := query{request: request}
+	switch remoteAddress := writer.RemoteAddr().(type) {
+	case *net.UDPAddr:
+		currentQuery.source, _ = netaddr.FromStdIP(remoteAddress.IP)
+
+	case *net.TCPAddr:
+		currentQuery.source, _ = netaddr.FromStdIP(remoteAddress.IP)
+		currentQuery.overTCP = true
+	}
+
+	reply := server.answer(ctx, currentQuery)
+	reply.RecursionAvailable = len(server.upstreams) > 0 && server.mayRecurse(currentQuery.source)
+	if !currentQuery.overTCP {
+		udpSize := dns.MinMsgSize
+		if opt := request.IsEdns0(); opt != nil {
+			udpSize = int(opt.UDPSize())
+		}
+
+		reply.Truncate(udpSize)
+	}
+
+	_ = writer.WriteMsg(reply)
+}
+
+var _ dns.Handler = (*Server)(nil)
+
+func (server *Server) answer(ctx context.Context, currentQuery query) *dns.Msg {
+	request := currentQuery.request
+	if len(request.Question) != 1 {
+		return newReply(request, dns.RcodeFormatError)
+	}
+
+	question := request.Question[0]
+	name := dns.CanonicalName(question.Name)
+
+	if dns.IsSubDomain(server.zone, name) {
+		return server.answerZone(ctx, request, question, name)
+	}
+
+	if question.Qtype == dns.TypePTR {
+		if ip, ok := ipFromReverseName(name); ok {
+			reply, handled := server.answerReverse(ctx, currentQuery, question, ip)
+			if handled {
+				return reply
+			}
+		}
+	}
+
+	return server.forward(ctx, currentQuery)
+}
+
+func (server *Server) answerZone(
+	ctx context.Context,
+	request *dns.Msg,
+	question dns.Question,
+	name string,
+) *dns.Msg {
+	labels := dns.SplitDomainName(strings.TrimSuffix(name, server.zone))
+	if len(labels) != 2 && len(labels) != 3 {
+		if name == server.zone {
+			return server.negativeReply(request, dns.RcodeSuccess)
+		}
+
+		return server.negativeReply(request, dns.RcodeNameError)
+	}
+
+	node, network, organization := labels[0], labels[1], ""
+	if len(labels) == 3 {
+		organization = labels[2]
+	}
+
+	ips, found, err := server.source.LookupNode(ctx, organization, network, node)
+	if err != nil {
+		return newReply(request, dns.RcodeServerFailure)
+	}
+
+	if !found {
+		return server.negativeReply(request, dns.RcodeNameError)
+	}
+
+	reply := newReply(request, dns.RcodeSuccess)
+	reply.Authoritative = true
+
+	header := dns.RR_Header{
+		Name:  question.Name,
+		Class: dns.ClassINET,
+		Ttl:   server.ttl,
+	}
+
+	for _, ip := range ips {
+		switch {
+		case ip.Is4() && (question.Qtype == dns.TypeA || question.Qtype == dns.TypeANY):
+			header.Rrtype = dns.TypeA
+			reply.Answer = append(reply.Answer, &dns.A{Hdr: header, A: ip.IPAddr().IP})
+
+		case ip.Is6() && (question.Qtype == dns.TypeAAAA || question.Qtype == dns.TypeANY):
+			header.Rrtype = dns.TypeAAAA
+			reply.Answer = append(reply.Answer, &dns.AAAA{Hdr: header, AAAA: ip.IPAddr().IP})
+		}
+	}
+
+	if len(reply.Answer) == 0 {
+		return server.negativeReply(request, dns.RcodeSuccess)
+	}
+
+	return reply
+}
+
+// answerReverse answers a reverse lookup for an address that belongs
+// to a node in the organization of the node asking. Handled is false
+// otherwise so that the query can be forwarded instead.
+func (server *Server) answerReverse(
+	ctx context.Context,
+	currentQuery query,
+	question dns.Question,
+	ip netaddr.IP,
+) (*dns.Msg, bool) {
+	request := currentQuery.request
+	organization, network, node, found, err := server.source.LookupIP(ctx, ip, currentQuery.source)
+	if err != nil {
+		return newReply(request, dns.RcodeServerFailure), true
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	reply := newReply(request, dns.RcodeSuccess)
+	reply.Authoritative = true
+	reply.Answer = append(reply.Answer, &dns.PTR{
+		Hdr: dns.RR_Header{
+			Name:   question.Name,
+			Rrtype: dns.TypePTR,
+			Class:  dns.ClassINET,
+			Ttl:    server.ttl,
+		},
+		Ptr: node + "." + NetworkDomain(server.zone, organization, network),
+	})
+
+	return reply, true
+}
+
+// forward sends a query outside of the zone to the upstreams, as long
+// as it comes from a network that is allowed to recurse.
+func (server *Server) forward(ctx context.Context, currentQuery query) *dns.Msg {
+	request := currentQuery.request
+	if len(server.upstreams) == 0 || !server.mayRecurse(currentQuery.source) {
+		return newReply(request, dns.RcodeRefused)
+	}
+
+	for _, upstream := range server.upstreams {
+		reply, err := server.exchange(ctx, request, upstream, currentQuery.overTCP)
+		if err != nil {
+			continue
+		}
+
+		reply.Id = request.Id
+
+		return reply
+	}
+
+	return newReply(request, dns.RcodeServerFailure)
+}
+
+// exchange sends a query to an upstream over the same transport it came
+// in on, falling back to TCP when the answer didn't fit in UDP.
+func (server *Server) exchange(
+	ctx context.Context,
+	request *dns.Msg,
+	upstream string,
+	overTCP bool,
+) (*dns.Msg, error) {
+	if !overTCP {
+		reply, _, err := server.udpClient.ExchangeContext(ctx, request, upstream)
+		if err != nil || !reply.Truncated {
+			return reply, err
+		}
+	}
+
+	reply, _, err := server.tcpClient.ExchangeContext(ctx, request, upstream)
+
+	return reply, err
+}
+
+func (server *Server) mayRecurse(source netaddr.IP) bool {
+	for _, prefix := range server.recursionSources {
+		if prefix.Contains(source.Unmap()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// negativeReply creates an authoritative answer without any records,
+// including the zone's SOA record so that it can be cached.
+func (server *Server) negativeReply(request *dns.Msg, rcode int) *dns.Msg {
+	reply := newReply(request, rcode)
+	reply.Authoritative = true
+	reply.Ns = append(reply.Ns, &dns.SOA{
+		Hdr: dns.RR_Header{
+			Name:   server.zone,
+			Rrtype: dns.TypeSOA,
+			Class:  dns.ClassINET,
+			Ttl:    server.ttl,
+		},
+		Ns:      "ns." + server.zone,
+		Mbox:    "hostmaster." + server.zone,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  server.ttl,
+	})
+
+	return reply
+}
+
+func newReply(request *dns.Msg, rcode int) *dns.Msg {
+	reply := new(dns.Msg)
+	reply.SetRcode(request, rcode)
+
+	return reply
+}
+
+// ipFromReverseName reads the address out of a name in in-addr.arpa
+// or ip6.arpa.
+func ipFromReverseName(name string) (netaddr.IP, bool) {
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa."):
+		labels := dns.SplitDomainName(strings.TrimSuffix(name, ".in-addr.arpa."))
+		if len(labels) != 4 {
+			return netaddr.IP{}, false
+		}
+
+		for left, right := 0, len(labels)-1; left < right; left, right = left+1, right-1 {
+			labels[left], labels[right] = labels[right], labels[left]
+		}
+
+		ip, err := netaddr.ParseIP(strings.Join(labels, "."))
+		if err != nil || !ip.Is4() {
+			return netaddr.IP{}, false
+		}
+
+		return ip, true
+
+	case strings.HasSuffix(name, ".ip6.arpa."):
+		nibbles := dns.SplitDomainName(strings.TrimSuffix(name, ".ip6.arpa."))
+		if len(nibbles) != 32 {
+			return netaddr.IP{}, false
+		}
+
+		var address [16]byte
+		for index, nibble := range nibbles {
+			if len(nibble) != 1 {
+				return netaddr.IP{}, false
+			}
+
+			value, ok := hexValue(nibble[0])
+			if !ok {
+				return netaddr.IP{}, false
+			}
+
+			// The nibbles are listed from the least significant one.
+			position := 31 - index
+			if position%2 == 0 {
+				address[position/2] |= value << 4
+			} else {
+				address[position/2] |= value
+			}
+		}
+
+		return netaddr.IPFrom16(address), true
+
+	default:
+		return netaddr.IP{}, false
+	}
+}
+
+func hexValue(character byte) (byte, bool) {
+	switch {
+	case '0' <= character && character <= '9':
+		return character - '0', true
+
+	case 'a' <= character && character <= 'f':
+		return character - 'a' + 10, true
+
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,400 @@
+package nameserver_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/nameserver"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestServerShouldAnswerForNodes(t *testing.T) {
+	address := startServer(t, nameserver.Opts{})
+
+	reply := query(t, address, "web.prod.ley.internal.", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.True(t, reply.Authoritative, "should be authoritative for the zone")
+	require.Len(t, reply.Answer, 1, "should only return the IPv4 address")
+	require.Equal(t, "10.0.0.2", reply.Answer[0].(*dns.A).A.String())
+
+	reply = query(t, address, "WEB.prod.ley.internal.", dns.TypeAAAA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Len(t, reply.Answer, 1, "should only return the IPv6 address")
+	require.Equal(t, "fd00::2", reply.Answer[0].(*dns.AAAA).AAAA.String())
+}
+
+func TestServerShouldReportMissingNodes(t *testing.T) {
+	address := startServer(t, nameserver.Opts{})
+
+	reply := query(t, address, "db.prod.ley.internal.", dns.TypeA)
+	require.Equal(t, dns.RcodeNameError, reply.Rcode, "should not find the node")
+	require.Len(t, reply.Ns, 1, "should include the zone's SOA record")
+
+	reply = query(t, address, "extra.db.lab.acme.ley.internal.", dns.TypeA)
+	require.Equal(t, dns.RcodeNameError, reply.Rcode, "should not find names deeper than nodes")
+
+	reply = query(t, address, "v4only.prod.ley.internal.", dns.TypeAAAA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode, "should find the node")
+	require.Empty(t, reply.Answer, "should not have any IPv6 addresses")
+}
+
+func TestServerShouldAnswerOverTCP(t *testing.T) {
+	address := startServer(t, nameserver.Opts{})
+
+	reply := queryOver(t, "tcp", address, "web.prod.ley.internal.", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Len(t, reply.Answer, 1)
+	require.Equal(t, "10.0.0.2", reply.Answer[0].(*dns.A).A.String())
+}
+
+func TestServerShouldAnswerForNodesOfOtherOrganizations(t *testing.T) {
+	address := startServer(t, nameserver.Opts{})
+
+	reply := query(t, address, "db.lab.acme.ley.internal.", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Len(t, reply.Answer, 1)
+	require.Equal(t, "10.1.0.2", reply.Answer[0].(*dns.A).A.String())
+
+	reply = query(t, address, "db.lab.ley.internal.", dns.TypeA)
+	require.Equal(t, dns.RcodeNameError, reply.Rcode, "should not find the node in the default organization")
+
+	reverseName, err := dns.ReverseAddr("10.1.0.2")
+	require.Nil(t, err, "should be able to create a reverse name")
+
+	reply = query(t, address, reverseName, dns.TypePTR)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Len(t, reply.Answer, 1)
+	require.Equal(t, "db.lab.acme.ley.internal.", reply.Answer[0].(*dns.PTR).Ptr)
+}
+
+func TestServerShouldOnlyAnswerReverseLookupsForTheOrganizationAsking(t *testing.T) {
+	address := startServerWithSource(t, staticSource{
+		askers: map[netaddr.IP][]string{
+			netaddr.MustParseIP("127.0.0.1"): {""},
+		},
+	}, nameserver.Opts{})
+
+	reverseName, err := dns.ReverseAddr("10.1.0.2")
+	require.Nil(t, err, "should be able to create a reverse name")
+
+	reply := query(t, address, reverseName, dns.TypePTR)
+	require.Equal(t, dns.RcodeRefused, reply.Rcode, "should not reveal nodes of other organizations")
+
+	reverseName, err = dns.ReverseAddr("10.0.0.2")
+	require.Nil(t, err, "should be able to create a reverse name")
+
+	reply = query(t, address, reverseName, dns.TypePTR)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Len(t, reply.Answer, 1)
+}
+
+func TestNetworkDomainShouldOnlyNameOtherOrganizations(t *testing.T) {
+	require.Equal(t, "prod.ley.internal.", nameserver.NetworkDomain("", "", "prod"))
+	require.Equal(t, "lab.acme.mesh.example.", nameserver.NetworkDomain("Mesh.Example", "acme", "lab"))
+}
+
+func TestServerShouldAnswerReverseLookups(t *testing.T) {
+	address := startServer(t, nameserver.Opts{})
+
+	reverseName, err := dns.ReverseAddr("10.0.0.2")
+	require.Nil(t, err, "should be able to create a reverse name")
+
+	reply := query(t, address, reverseName, dns.TypePTR)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Len(t, reply.Answer, 1)
+	require.Equal(t, "web.prod.ley.internal.", reply.Answer[0].(*dns.PTR).Ptr)
+
+	reverseName, err = dns.ReverseAddr("fd00::2")
+	require.Nil(t, err, "should be able to create a reverse name")
+
+	reply = query(t, address, reverseName, dns.TypePTR)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Len(t, reply.Answer, 1)
+	require.Equal(t, "web.prod.ley.internal.", reply.Answer[0].(*dns.PTR).Ptr)
+}
+
+func TestServerShouldForwardOtherQueries(t *testing.T) {
+	upstreamAddress := startUpstream(t)
+	address := startServer(t, nameserver.Opts{
+		Upstreams:        []string{"127.0.0.1:1", upstreamAddress},
+		RecursionSources: []netaddr.IPPrefix{netaddr.MustParseIPPrefix("127.0.0.0/8")},
+	})
+
+	reply := query(t, address, "example.com.", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.True(t, reply.RecursionAvailable, "should offer recursion to allowed networks")
+	require.Len(t, reply.Answer, 1, "should return the upstream's answer")
+	require.Equal(t, "192.0.2.1", reply.Answer[0].(*dns.A).A.String())
+
+	reverseName, err := dns.ReverseAddr("192.0.2.1")
+	require.Nil(t, err, "should be able to create a reverse name")
+
+	reply = query(t, address, reverseName, dns.TypePTR)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Equal(t, "example.com.", reply.Answer[0].(*dns.PTR).Ptr, "should forward unknown addresses")
+}
+
+func TestServerShouldFallBackToTCPForLargeAnswers(t *testing.T) {
+	upstreamAddress := startUpstream(t)
+	address := startServer(t, nameserver.Opts{
+		Upstreams:        []string{upstreamAddress},
+		RecursionSources: []netaddr.IPPrefix{netaddr.MustParseIPPrefix("127.0.0.0/8")},
+	})
+
+	reply := query(t, address, "large.example.com.", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.True(t, reply.Truncated, "should tell the client to retry over TCP")
+
+	reply = queryOver(t, "tcp", address, "large.example.com.", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.False(t, reply.Truncated)
+	require.Len(t, reply.Answer, largeAnswerSize, "should get the full answer from the upstream over TCP")
+}
+
+func TestServerShouldRefuseOtherQueriesWithoutUpstreams(t *testing.T) {
+	address := startServer(t, nameserver.Opts{})
+
+	reply := query(t, address, "example.com.", dns.TypeA)
+	require.Equal(t, dns.RcodeRefused, reply.Rcode)
+}
+
+func TestServerShouldRefuseToRecurseForOtherNetworks(t *testing.T) {
+	upstreamAddress := startUpstream(t)
+	address := startServer(t, nameserver.Opts{
+		Upstreams:        []string{upstreamAddress},
+		RecursionSources: []netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.0/8")},
+	})
+
+	reply := query(t, address, "example.com.", dns.TypeA)
+	require.Equal(t, dns.RcodeRefused, reply.Rcode, "should not be an open resolver")
+	require.False(t, reply.RecursionAvailable)
+
+	reply = query(t, address, "web.prod.ley.internal.", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode, "should still answer for nodes")
+}
+
+func TestServerShouldTimeOutSlowQueries(t *testing.T) {
+	address := startServerWithSource(t, slowSource{}, nameserver.Opts{
+		QueryTimeout: 50 * time.Millisecond,
+	})
+
+	reply := query(t, address, "web.prod.ley.internal.", dns.TypeA)
+	require.Equal(t, dns.RcodeServerFailure, reply.Rcode)
+}
+
+func TestServerShouldUseTheConfiguredZone(t *testing.T) {
+	address := startServer(t, nameserver.Opts{Zone: "mesh.example"})
+
+	reply := query(t, address, "web.prod.mesh.example.", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, reply.Rcode)
+	require.Len(t, reply.Answer, 1)
+}
+
+// largeAnswerSize is the number of records the upstream gives for
+// large.example.com, which doesn't fit in a UDP message.
+const largeAnswerSize = 100
+
+func startServer(t *testing.T, opts nameserver.Opts) string {
+	return startServerWithSource(t, staticSource{
+		askers: map[netaddr.IP][]string{
+			netaddr.MustParseIP("127.0.0.1"): {"", "acme"},
+		},
+	}, opts)
+}
+
+func startServerWithSource(t *testing.T, source nameserver.Source, opts nameserver.Opts) string {
+	packetConn, listener := listen(t)
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	t.Cleanup(cancelCtx)
+
+	server := nameserver.New(source, opts)
+	go func() {
+		_ = server.Serve(ctx, packetConn, listener)
+	}()
+
+	return packetConn.LocalAddr().String()
+}
+
+// listen opens a UDP and TCP socket on the same port of localhost.
+func listen(t *testing.T) (net.PacketConn, net.Listener) {
+	// The port picked for UDP may already be taken for TCP so give it
+	// a few tries.
+	for attempt := 0; attempt < 5; attempt++ {
+		packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.Nil(t, err, "should be able to listen on localhost")
+
+		listener, err := net.Listen("tcp", packetConn.LocalAddr().String())
+		if err == nil {
+			return packetConn, listener
+		}
+
+		_ = packetConn.Close()
+	}
+
+	t.Fatal("should be able to listen on localhost")
+
+	return nil, nil
+}
+
+func startUpstream(t *testing.T) string {
+	packetConn, listener := listen(t)
+
+	handler := dns.HandlerFunc(func(writer dns.ResponseWriter, request *dns.Msg) {
+		reply := new(dns.Msg)
+		reply.SetReply(request)
+
+		question := request.Question[0]
+		switch {
+		case question.Name == "large.example.com.":
+			for index := 0; index < largeAnswerSize; index++ {
+				record, _ := dns.NewRR(fmt.Sprintf("%s 60 IN A 192.0.2.%d", question.Name, index))
+				reply.Answer = append(reply.Answer, record)
+			}
+
+			if _, ok := writer.RemoteAddr().(*net.UDPAddr); ok {
+				reply.Truncate(dns.MinMsgSize)
+			}
+
+		case question.Qtype == dns.TypeA:
+			record, _ := dns.NewRR(question.Name + " 60 IN A 192.0.2.1")
+			reply.Answer = append(reply.Answer, record)
+
+		case question.Qtype == dns.TypePTR:
+			record, _ := dns.NewRR(question.Name + " 60 IN PTR example.com.")
+			reply.Answer = append(reply.Answer, record)
+		}
+
+		_ = writer.WriteMsg(reply)
+	})
+
+	for _, upstream := range []*dns.Server{
+		{PacketConn: packetConn, Handler: handler},
+		{Listener: listener, Handler: handler},
+	} {
+		upstream := upstream
+		started := make(chan struct{})
+		upstream.NotifyStartedFunc = func() { close(started) }
+
+		go func() {
+			_ = upstream.ActivateAndServe()
+		}()
+		<-started
+
+		t.Cleanup(func() {
+			_ = upstream.Shutdown()
+		})
+	}
+
+	return packetConn.LocalAddr().String()
+}
+
+func query(t *testing.T, address string, name string, qtype uint16) *dns.Msg {
+	return queryOver(t, "udp", address, name, qtype)
+}
+
+func queryOver(t *testing.T, network string, address string, name string, qtype uint16) *dns.Msg {
+	request := new(dns.Msg)
+	request.SetQuestion(name, qtype)
+
+	var reply *dns.Msg
+	var err error
+	client := dns.Client{Net: network}
+	// The server may still be starting up so give it a few tries.
+	for attempt := 0; attempt < 5; attempt++ {
+		reply, _, err = client.Exchange(request, address)
+		if err == nil {
+			break
+		}
+	}
+	require.Nil(t, err, "should be able to query the server")
+
+	return reply
+}
+
+// staticSource answers from a fixed set of nodes. Askers are the
+// organizations of the nodes using each source address.
+type staticSource struct {
+	askers map[netaddr.IP][]string
+}
+
+var nodes = map[string][]netaddr.IP{
+	"/prod/web": {
+		netaddr.MustParseIP("10.0.0.2"),
+		netaddr.MustParseIP("fd00::2"),
+	},
+	"/prod/v4only": {
+		netaddr.MustParseIP("10.0.0.3"),
+	},
+	"acme/lab/db": {
+		netaddr.MustParseIP("10.1.0.2"),
+	},
+}
+
+func (source staticSource) LookupNode(
+	ctx context.Context,
+	organization string,
+	network string,
+	node string,
+) ([]netaddr.IP, bool, error) {
+	ips, ok := nodes[organization+"/"+network+"/"+node]
+
+	return ips, ok, nil
+}
+
+func (source staticSource) LookupIP(
+	ctx context.Context,
+	ip netaddr.IP,
+	asker netaddr.IP,
+) (string, string, string, bool, error) {
+	for key, ips := range nodes {
+		for _, nodeIP := range ips {
+			if nodeIP != ip {
+				continue
+			}
+
+			parts := strings.Split(key, "/")
+			for _, organization := range source.askers[asker] {
+				if organization == parts[0] {
+					return parts[0], parts[1], parts[2], true, nil
+				}
+			}
+		}
+	}
+
+	return "", "", "", false, nil
+}
+
+var _ nameserver.Source = staticSource{}
+
+// slowSource takes as long as it is allowed to for every lookup.
+type slowSource struct{}
+
+func (source slowSource) LookupNode(
+	ctx context.Context,
+	organization string,
+	network string,
+	node string,
+) ([]netaddr.IP, bool, error) {
+	<-ctx.Done()
+
+	return nil, false, ctx.Err()
+}
+
+func (source slowSource) LookupIP(
+	ctx context.Context,
+	ip netaddr.IP,
+	asker netaddr.IP,
+) (string, string, string, bool, error) {
+	<-ctx.Done()
+
+	return "", "", "", false, ctx.Err()
+}
+
+var _ nameserver.Source = slowSource{}
@@ -0,0 +1,252 @@
+package network
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"inet.af/netaddr"
+)
+
+const (
+	// maxIPv4PrefixBits is the longest IPv4 prefix that still leaves
+	// room for two hosts once the network and broadcast addresses are
+	// taken out.
+	maxIPv4PrefixBits = 30
+
+	// maxIPv6PrefixBits is the longest IPv6 prefix that still leaves
+	// room for more than two hosts.
+	maxIPv6PrefixBits = 126
+)
+
+// AddressSpace reports how the configured supernets are used by
+// networks.
+type AddressSpace struct {
+	IPv4 *AddressFamilySpace
+	IPv6 *AddressFamilySpace
+}
+
+// AddressFamilySpace reports how one supernet is used by networks.
+type AddressFamilySpace struct {
+	Supernet netaddr.IPPrefix
+	Used     []UsedRange
+	Free     []netaddr.IPPrefix
+}
+
+// UsedRange is a range that belongs to a network.
+type UsedRange struct {
+	Network      string
+	Organization string
+	CIDR         netaddr.IPPrefix
+}
+
+// FreeRanges gives the smallest set of prefixes that cover everything
+// in the supernet that isn't used.
+func FreeRanges(supernet netaddr.IPPrefix, used []netaddr.IPPrefix) ([]netaddr.IPPrefix, error) {
+	var builder netaddr.IPSetBuilder
+	builder.AddPrefix(supernet)
+	for _, prefix := range used {
+		builder.RemovePrefix(prefix)
+	}
+
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to calculate free ranges: %w", err)
+	}
+
+	return ipSet.Prefixes(), nil
+}
+
+// GetAddressSpace reports the used and free ranges within the
+// configured supernets.
+func (service *Service) GetAddressSpace(ctx context.Context) (*AddressSpace, error) {
+	ipv4Supernet := prefixOrNil(service.config.IPv4Supernet)
+	ipv6Supernet := prefixOrNil(service.config.IPv6Supernet)
+
+	rows, err := service.db.QueryContext(
+		ctx,
+		listNetworkRangesSQL,
+		prefixToNullString(ipv4Supernet),
+		prefixToNullString(ipv6Supernet),
+	)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get address space due to a system error",
+			UnsafeMessage: "Unable to list network ranges",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var ipv4Used []UsedRange
+	var ipv6Used []UsedRange
+	for rows.Next() {
+		var name string
+		var organizationName string
+		var rawIPv4CIDR sql.NullString
+		var rawIPv6CIDR sql.NullString
+		if err := rows.Scan(&name, &organizationName, &rawIPv4CIDR, &rawIPv6CIDR); err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to get address space due to a system error",
+				UnsafeMessage: "Unable to read network range row",
+				WrappedError:  err,
+			}
+		}
+
+		ipv4CIDR, err := nullStringToPrefix(rawIPv4CIDR)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to get address space due to a system error",
+				UnsafeMessage: "Unable to parse network range",
+				WrappedError:  err,
+			}
+		}
+
+		ipv6CIDR, err := nullStringToPrefix(rawIPv6CIDR)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to get address space due to a system error",
+				UnsafeMessage: "Unable to parse network range",
+				WrappedError:  err,
+			}
+		}
+
+		if ipv4Supernet != nil && ipv4CIDR != nil && ipv4Supernet.Overlaps(*ipv4CIDR) {
+			ipv4Used = append(ipv4Used, UsedRange{Network: name, Organization: organizationName, CIDR: *ipv4CIDR})
+		}
+
+		if ipv6Supernet != nil && ipv6CIDR != nil && ipv6Supernet.Overlaps(*ipv6CIDR) {
+			ipv6Used = append(ipv6Used, UsedRange{Network: name, Organization: organizationName, CIDR: *ipv6CIDR})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get address space due to a system error",
+			UnsafeMessage: "Unable to iterate over network range rows",
+			WrappedError:  err,
+		}
+	}
+
+	addressSpace := AddressSpace{}
+
+	if ipv4Supernet != nil {
+		if addressSpace.IPv4, err = newAddressFamilySpace(*ipv4Supernet, ipv4Used); err != nil {
+			return nil, err
+		}
+	}
+
+	if ipv6Supernet != nil {
+		if addressSpace.IPv6, err = newAddressFamilySpace(*ipv6Supernet, ipv6Used); err != nil {
+			return nil, err
+		}
+	}
+
+	return &addressSpace, nil
+}
+
+func newAddressFamilySpace(supernet netaddr.IPPrefix, used []UsedRange) (*AddressFamilySpace, error) {
+	usedPrefixes := make([]netaddr.IPPrefix, 0, len(used))
+	for _, usedRange := range used {
+		usedPrefixes = append(usedPrefixes, usedRange.CIDR)
+	}
+
+	free, err := FreeRanges(supernet, usedPrefixes)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get address space due to a system error",
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	if used == nil {
+		used = []UsedRange{}
+	}
+
+	return &AddressFamilySpace{
+		Supernet: supernet,
+		Used:     used,
+		Free:     free,
+	}, nil
+}
+
+// findOverlappingNetwork looks for a network, other than ones that
+// allow overlaps, with a range that overlaps either of the given
+// ranges. It gives the name of the network and the ID of its
+// organization, and an empty name means there wasn't one.
+func (service *Service) findOverlappingNetwork(
+	ctx context.Context,
+	db queryer,
+	ipv4CIDR *netaddr.IPPrefix,
+	ipv6CIDR *netaddr.IPPrefix,
+) (string, string, error) {
+	var name string
+	var organizationID string
+	err := db.QueryRowContext(
+		ctx,
+		findOverlappingNetworkSQL,
+		prefixToNullString(ipv4CIDR),
+		prefixToNullString(ipv6CIDR),
+	).Scan(&name, &organizationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", nil
+		}
+
+		return "", "", errortypes.SystemError{
+			SafeMessage:   "Unable to create new network due to a system error",
+			UnsafeMessage: "Unable to check for overlapping networks",
+			WrappedError:  err,
+		}
+	}
+
+	return name, organizationID, nil
+}
+
+// validatePrefix rejects ranges that can't be used for a network.
+func validatePrefix(family string, prefix *netaddr.IPPrefix, is4 bool, maxBits uint8) error {
+	if prefix == nil {
+		return nil
+	}
+
+	if !prefix.IsValid() || prefix.IP().Is4() != is4 || prefix.IP().Is4in6() {
+		return fmt.Errorf("Invalid %s range '%s'", family, prefix)
+	}
+
+	if masked := prefix.Masked(); masked != *prefix {
+		return fmt.Errorf("%s range '%s' has host bits set, did you mean '%s'", family, prefix, masked)
+	}
+
+	if prefix.Bits() == 0 {
+		return fmt.Errorf("%s range '%s' cannot cover every address", family, prefix)
+	}
+
+	if prefix.Bits() > maxBits {
+		return fmt.Errorf(
+			"%s range '%s' is too small to hold any hosts, use /%d or larger",
+			family,
+			prefix,
+			maxBits,
+		)
+	}
+
+	ip := prefix.IP()
+	if ip.IsLoopback() || ip.IsMulticast() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s range '%s' is reserved and cannot be used", family, prefix)
+	}
+
+	return nil
+}
+
+func prefixOrNil(prefix netaddr.IPPrefix) *netaddr.IPPrefix {
+	if prefix.IsZero() {
+		return nil
+	}
+
+	return &prefix
+}
@@ -0,0 +1,89 @@
+package network_test
+
+import (
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestFreeRangesShouldLeaveOutUsedRanges(t *testing.T) {
+	free, err := network.FreeRanges(
+		netaddr.MustParseIPPrefix("10.0.0.0/22"),
+		[]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("10.0.1.0/24"),
+			netaddr.MustParseIPPrefix("192.168.0.0/24"),
+		},
+	)
+	require.Nil(t, err, "should be able to calculate free ranges")
+	require.Equal(
+		t,
+		[]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("10.0.0.0/24"),
+			netaddr.MustParseIPPrefix("10.0.2.0/23"),
+		},
+		free,
+		"should only report ranges inside the supernet that aren't used",
+	)
+}
+
+func TestFreeRangesShouldBeEmptyForAFullSupernet(t *testing.T) {
+	free, err := network.FreeRanges(
+		netaddr.MustParseIPPrefix("10.0.0.0/24"),
+		[]netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.0/16")},
+	)
+	require.Nil(t, err, "should be able to calculate free ranges")
+	require.Empty(t, free, "should have no free ranges")
+}
+
+func TestCreateNetworkOptsShouldValidateRanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ipv4CIDR string
+		ipv6CIDR string
+		valid    bool
+	}{
+		{name: "ipv4", ipv4CIDR: "10.0.0.0/24", valid: true},
+		{name: "ipv6", ipv6CIDR: "fd00:1234::/64", valid: true},
+		{name: "both", ipv4CIDR: "10.0.0.0/30", ipv6CIDR: "fd00::/126", valid: true},
+		{name: "host bits", ipv4CIDR: "10.0.0.1/24"},
+		{name: "single ipv4 address", ipv4CIDR: "10.0.0.1/32"},
+		{name: "point to point ipv4", ipv4CIDR: "10.0.0.0/31"},
+		{name: "single ipv6 address", ipv6CIDR: "fd00::1/128"},
+		{name: "everything", ipv4CIDR: "0.0.0.0/0"},
+		{name: "loopback", ipv4CIDR: "127.0.0.0/8"},
+		{name: "multicast", ipv6CIDR: "ff00::/8"},
+		{name: "wrong family", ipv4CIDR: "fd00::/64"},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			opts := network.CreateNetworkOpts{Name: "test"}
+			if testCase.ipv4CIDR != "" {
+				prefix := netaddr.MustParseIPPrefix(testCase.ipv4CIDR)
+				opts.IPv4CIDR = &prefix
+			}
+
+			if testCase.ipv6CIDR != "" {
+				prefix := netaddr.MustParseIPPrefix(testCase.ipv6CIDR)
+				opts.IPv6CIDR = &prefix
+			}
+
+			err := opts.Validate()
+			if testCase.valid {
+				require.Nil(t, err, "should accept the range")
+			} else {
+				require.NotNil(t, err, "should reject the range")
+			}
+		})
+	}
+}
+
+func TestCreateNetworkOptsShouldRejectReservedNames(t *testing.T) {
+	prefix := netaddr.MustParseIPPrefix("10.0.0.0/24")
+	opts := network.CreateNetworkOpts{Name: "address-space", IPv4CIDR: &prefix}
+
+	require.NotNil(t, opts.Validate(), "should reject a name used by another endpoint")
+}
@@ -0,0 +1,185 @@
+package network
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/lib/pq"
+	"inet.af/netaddr"
+)
+
+const (
+	// minIPv4PrefixBits is the shortest IPv4 prefix that can be
+	// requested from a pool.
+	minIPv4PrefixBits = 8
+
+	// allocationLockID is the advisory lock held while ranges are
+	// carved out of the pools so that two networks can't be given the
+	// same range.
+	allocationLockID = 0x6c65792d6e6574
+)
+
+// AllocatePrefix finds the first range with the given prefix length
+// that is inside one of the pools and doesn't overlap any of the used
+// ranges. Pools are tried in order.
+func AllocatePrefix(
+	pools []netaddr.IPPrefix,
+	used []netaddr.IPPrefix,
+	bits uint8,
+) (netaddr.IPPrefix, bool, error) {
+	for _, pool := range pools {
+		if bits < pool.Bits() || bits > pool.IP().BitLen() {
+			continue
+		}
+
+		free, err := FreeRanges(pool, used)
+		if err != nil {
+			return netaddr.IPPrefix{}, false, err
+		}
+
+		for _, freeRange := range free {
+			if freeRange.Bits() <= bits {
+				return netaddr.IPPrefixFrom(freeRange.IP(), bits), true, nil
+			}
+		}
+	}
+
+	return netaddr.IPPrefix{}, false, nil
+}
+
+// GenerateULA creates a random RFC 4193 unique local /48 and returns
+// the first /64 inside of it.
+func GenerateULA(random io.Reader) (netaddr.IPPrefix, error) {
+	var address [16]byte
+	address[0] = 0xfd
+	if _, err := io.ReadFull(random, address[1:6]); err != nil {
+		return netaddr.IPPrefix{}, fmt.Errorf("Unable to generate unique local address: %w", err)
+	}
+
+	return netaddr.IPPrefixFrom(netaddr.IPFrom16(address), 64), nil
+}
+
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var _ queryer = (*sql.DB)(nil)
+var _ queryer = (*sql.Tx)(nil)
+
+// allocateRanges fills in any ranges that were requested instead of
+// given. It has to run in the same transaction that creates the
+// network.
+func (service *Service) allocateRanges(
+	ctx context.Context,
+	tx *sql.Tx,
+	opts *CreateNetworkOpts,
+) error {
+	if opts.IPv4PrefixLength != nil {
+		if _, err := tx.ExecContext(ctx, lockAddressPoolsSQL, allocationLockID); err != nil {
+			return errortypes.SystemError{
+				SafeMessage:   "Unable to create new network due to a system error",
+				UnsafeMessage: "Unable to lock the address pools",
+				WrappedError:  err,
+			}
+		}
+
+		used, err := service.listPoolRanges(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		prefix, ok, err := AllocatePrefix(service.config.IPv4Pools, used, uint8(*opts.IPv4PrefixLength))
+		if err != nil {
+			return errortypes.SystemError{
+				SafeMessage:   "Unable to create new network due to a system error",
+				UnsafeMessage: err.Error(),
+				WrappedError:  err,
+			}
+		}
+
+		if !ok {
+			return errortypes.NewValidationError(
+				"Unable to create network: No free /%d range left in the IPv4 pools",
+				*opts.IPv4PrefixLength,
+			)
+		}
+
+		opts.IPv4CIDR = &prefix
+	}
+
+	if opts.GenerateIPv6 {
+		prefix, err := GenerateULA(rand.Reader)
+		if err != nil {
+			return errortypes.SystemError{
+				SafeMessage:   "Unable to create new network due to a system error",
+				UnsafeMessage: err.Error(),
+				WrappedError:  err,
+			}
+		}
+
+		opts.IPv6CIDR = &prefix
+	}
+
+	return nil
+}
+
+func (service *Service) listPoolRanges(ctx context.Context, tx *sql.Tx) ([]netaddr.IPPrefix, error) {
+	pools := make([]string, 0, len(service.config.IPv4Pools))
+	for _, pool := range service.config.IPv4Pools {
+		pools = append(pools, pool.String())
+	}
+
+	rows, err := tx.QueryContext(ctx, listPoolRangesSQL, pq.Array(pools))
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new network due to a system error",
+			UnsafeMessage: "Unable to list ranges in the address pools",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var used []netaddr.IPPrefix
+	for rows.Next() {
+		var rawPrefix sql.NullString
+		if err := rows.Scan(&rawPrefix); err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to create new network due to a system error",
+				UnsafeMessage: "Unable to read pool range row",
+				WrappedError:  err,
+			}
+		}
+
+		prefix, err := nullStringToPrefix(rawPrefix)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to create new network due to a system error",
+				UnsafeMessage: "Unable to parse pool range",
+				WrappedError:  err,
+			}
+		}
+
+		if prefix != nil {
+			used = append(used, *prefix)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new network due to a system error",
+			UnsafeMessage: "Unable to iterate over pool range rows",
+			WrappedError:  err,
+		}
+	}
+
+	return used, nil
+}
@@ -0,0 +1,83 @@
+package network_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestAllocatePrefixShouldTakeTheFirstFreeRange(t *testing.T) {
+	prefix, ok, err := network.AllocatePrefix(
+		[]netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.0/16")},
+		[]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("10.0.0.0/24"),
+			netaddr.MustParseIPPrefix("10.0.1.0/25"),
+		},
+		24,
+	)
+	require.Nil(t, err, "should be able to allocate a range")
+	require.True(t, ok, "should find a free range")
+	require.Equal(t, netaddr.MustParseIPPrefix("10.0.2.0/24"), prefix, "should skip partly used ranges")
+}
+
+func TestAllocatePrefixShouldFillGapsWithSmallerRanges(t *testing.T) {
+	prefix, ok, err := network.AllocatePrefix(
+		[]netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.0/16")},
+		[]netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.0/25")},
+		25,
+	)
+	require.Nil(t, err, "should be able to allocate a range")
+	require.True(t, ok, "should find a free range")
+	require.Equal(t, netaddr.MustParseIPPrefix("10.0.0.128/25"), prefix)
+}
+
+func TestAllocatePrefixShouldMoveOnToTheNextPool(t *testing.T) {
+	prefix, ok, err := network.AllocatePrefix(
+		[]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("10.0.0.0/24"),
+			netaddr.MustParseIPPrefix("172.16.0.0/12"),
+		},
+		[]netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.0/25")},
+		24,
+	)
+	require.Nil(t, err, "should be able to allocate a range")
+	require.True(t, ok, "should find a free range")
+	require.Equal(t, netaddr.MustParseIPPrefix("172.16.0.0/24"), prefix)
+}
+
+func TestAllocatePrefixShouldReportExhaustedPools(t *testing.T) {
+	_, ok, err := network.AllocatePrefix(
+		[]netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.0/24")},
+		[]netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.64/26")},
+		24,
+	)
+	require.Nil(t, err, "should not fail")
+	require.False(t, ok, "should not find a free range")
+}
+
+func TestGenerateULAShouldCreateAUniqueLocalRange(t *testing.T) {
+	prefix, err := network.GenerateULA(bytes.NewReader([]byte{0x12, 0x34, 0x56, 0x78, 0x9a}))
+	require.Nil(t, err, "should be able to generate a range")
+	require.Equal(t, netaddr.MustParseIPPrefix("fd12:3456:789a::/64"), prefix)
+	require.True(t, netaddr.MustParseIPPrefix("fc00::/7").Contains(prefix.IP()), "should be a unique local address")
+}
+
+func TestCreateNetworkOptsShouldValidateRequestedRanges(t *testing.T) {
+	prefixLength := 24
+	opts := network.CreateNetworkOpts{Name: "test", IPv4PrefixLength: &prefixLength}
+	require.Nil(t, opts.Validate(), "should accept a prefix length instead of a range")
+
+	opts = network.CreateNetworkOpts{Name: "test", GenerateIPv6: true}
+	require.Nil(t, opts.Validate(), "should accept a generated IPv6 range")
+
+	prefix := netaddr.MustParseIPPrefix("10.0.0.0/24")
+	opts = network.CreateNetworkOpts{Name: "test", IPv4CIDR: &prefix, IPv4PrefixLength: &prefixLength}
+	require.NotNil(t, opts.Validate(), "should reject both a range and a prefix length")
+
+	tooSmall := 31
+	opts = network.CreateNetworkOpts{Name: "test", IPv4PrefixLength: &tooSmall}
+	require.NotNil(t, opts.Validate(), "should reject a prefix length with no room for hosts")
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/durandj/ley/internal/manager/conditional"
 	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/organization"
 	"github.com/durandj/ley/internal/manager/renderable"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -19,9 +22,32 @@ type Controller struct {
 }
 
 // RegisterRoutes registers HTTP request handlers for all network API's.
+// The networks are those of the default organization.
 func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Get("/address-space", controller.GetAddressSpace)
+	controller.RegisterOrganizationRoutes(router)
+}
+
+// RegisterOrganizationRoutes registers HTTP request handlers for the
+// network API's of the organization named by the "org" URL parameter.
+// The address space is shared by all organizations so it isn't part of
+// these routes.
+func (controller *Controller) RegisterOrganizationRoutes(router chi.Router) {
 	router.Get("/", controller.ListNetworks)
 	router.Post("/", controller.CreateNetwork)
+	router.Get("/{name}", controller.GetNetwork)
+	router.Patch("/{name}", controller.UpdateNetwork)
+	router.Delete("/{name}", controller.DeleteNetwork)
+}
+
+// organizationName gives the organization that a request is for, which
+// is the default organization for the routes without one.
+func organizationName(request *http.Request) string {
+	if name := chi.URLParam(request, "org"); name != "" {
+		return name
+	}
+
+	return organization.DefaultName
 }
 
 // CreateNetworkRequest is the expected request body for creating a new
@@ -30,6 +56,16 @@ type CreateNetworkRequest struct {
 	Name     string            `json:"name"`
 	IPv4CIDR *netaddr.IPPrefix `json:"ipv4CIDR,omitempty"`
 	IPv6CIDR *netaddr.IPPrefix `json:"ipv6CIDR,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+
+	AllowOverlap bool `json:"allowOverlap,omitempty"`
+
+	IPv4PrefixLength *int `json:"ipv4PrefixLength,omitempty"`
+	GenerateIPv6     bool `json:"generateIPv6,omitempty"`
+
+	Topology *Topology `json:"topology,omitempty"`
+
+	PresharedKeys bool `json:"presharedKeys,omitempty"`
 }
 
 // Bind is used to determine how to map from a request body to a
@@ -71,6 +107,7 @@ func (controller *Controller) CreateNetwork(
 
 	network, err := controller.NetworkService.CreateNetwork(
 		ctx,
+		organizationName(request),
 		CreateNetworkOpts(createNetworkRequest),
 	)
 	if err != nil {
@@ -82,25 +119,166 @@ func (controller *Controller) CreateNetwork(
 		RenderableNetwork: NewRenderableNetwork(network),
 	}
 
+	conditional.SetETag(response, network.Version())
 	response.WriteHeader(http.StatusCreated)
 	// TODO: set location header
 	_ = render.Render(response, request, &createNetworkResponse)
 }
 
-// ListNetworksResponse is the response for requesting all networks.
+// GetNetworkResponse is the response body for requesting a single
+// network.
+type GetNetworkResponse struct {
+	RenderableNetwork
+}
+
+var _ render.Renderer = (*GetNetworkResponse)(nil)
+
+// GetNetwork handles requests to fetch a network by name. The network
+// isn't sent again if the client already has the current version.
+func (controller *Controller) GetNetwork(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	network, err := controller.NetworkService.GetNetworkByName(
+		ctx,
+		organizationName(request),
+		chi.URLParam(request, "name"),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	if conditional.NotModified(request, network.Version()) {
+		conditional.WriteNotModified(response, network.Version())
+		return
+	}
+
+	getNetworkResponse := GetNetworkResponse{
+		RenderableNetwork: NewRenderableNetwork(network),
+	}
+
+	conditional.SetETag(response, network.Version())
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &getNetworkResponse)
+}
+
+// UpdateNetworkRequest is the expected request body for changing a
+// network. Fields that are left out are not changed.
+type UpdateNetworkRequest struct {
+	Name          *string           `json:"name,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Topology      *Topology         `json:"topology,omitempty"`
+	PresharedKeys *bool             `json:"presharedKeys,omitempty"`
+}
+
+// Bind is used to determine how to map from a request body to a
+// network update request.
+func (updateNetworkRequest *UpdateNetworkRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*UpdateNetworkRequest)(nil)
+
+// UpdateNetworkResponse is the response body for a successful network
+// update.
+type UpdateNetworkResponse struct {
+	RenderableNetwork
+}
+
+var _ render.Renderer = (*UpdateNetworkResponse)(nil)
+
+// UpdateNetwork handles requests to change a network. The If-Match
+// header can be used to make sure nobody else changed the network
+// first.
+func (controller *Controller) UpdateNetwork(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var updateNetworkRequest UpdateNetworkRequest
+	if err := render.Bind(request, &updateNetworkRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	network, err := controller.NetworkService.UpdateNetwork(
+		ctx,
+		organizationName(request),
+		chi.URLParam(request, "name"),
+		UpdateNetworkOpts{
+			Name:             updateNetworkRequest.Name,
+			Labels:           updateNetworkRequest.Labels,
+			Topology:         updateNetworkRequest.Topology,
+			PresharedKeys:    updateNetworkRequest.PresharedKeys,
+			ExpectedVersions: conditional.ParseIfMatch(request),
+		},
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	updateNetworkResponse := UpdateNetworkResponse{
+		RenderableNetwork: NewRenderableNetwork(network),
+	}
+
+	conditional.SetETag(response, network.Version())
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &updateNetworkResponse)
+}
+
+// DeleteNetwork handles requests to remove a network. The If-Match
+// header can be used to make sure nobody else changed the network
+// first.
+func (controller *Controller) DeleteNetwork(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	err := controller.NetworkService.DeleteNetwork(
+		ctx,
+		organizationName(request),
+		chi.URLParam(request, "name"),
+		conditional.ParseIfMatch(request),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ListNetworksResponse is the response for requesting a page of
+// networks.
 type ListNetworksResponse struct {
-	Networks []RenderableNetwork `json:"networks"`
+	Networks   []RenderableNetwork `json:"networks"`
+	NextCursor string              `json:"nextCursor,omitempty"`
 }
 
 // NewListNetworksResponse creates a network list response.
-func NewListNetworksResponse(networks []Network) ListNetworksResponse {
-	renderableNetworks := make([]RenderableNetwork, len(networks))
-	for index := range networks {
-		renderableNetworks[index] = NewRenderableNetwork(&networks[index])
+func NewListNetworksResponse(page listing.Page[Network]) ListNetworksResponse {
+	renderableNetworks := make([]RenderableNetwork, len(page.Items))
+	for index := range page.Items {
+		renderableNetworks[index] = NewRenderableNetwork(&page.Items[index])
 	}
 
 	return ListNetworksResponse{
-		Networks: renderableNetworks,
+		Networks:   renderableNetworks,
+		NextCursor: page.NextCursor,
 	}
 }
 
@@ -119,24 +297,116 @@ func (controller *Controller) ListNetworks(
 ) {
 	ctx := request.Context()
 
-	networks, err := controller.NetworkService.ListNetworks(ctx)
+	params, err := listing.ParseParams(request.URL.Query())
 	if err != nil {
 		handleError(response, request, err)
 		return
 	}
 
-	listNetworksResponse := NewListNetworksResponse(networks)
+	page, err := controller.NetworkService.ListNetworks(ctx, organizationName(request), params)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listNetworksResponse := NewListNetworksResponse(page)
 
 	response.WriteHeader(http.StatusOK)
 	_ = render.Render(response, request, &listNetworksResponse)
 }
 
+// GetAddressSpaceResponse reports how the configured supernets are
+// used by networks.
+type GetAddressSpaceResponse struct {
+	IPv4 *RenderableAddressFamilySpace `json:"ipv4,omitempty"`
+	IPv6 *RenderableAddressFamilySpace `json:"ipv6,omitempty"`
+}
+
+// NewGetAddressSpaceResponse creates the response for an address space
+// report.
+func NewGetAddressSpaceResponse(addressSpace *AddressSpace) GetAddressSpaceResponse {
+	return GetAddressSpaceResponse{
+		IPv4: newRenderableAddressFamilySpace(addressSpace.IPv4),
+		IPv6: newRenderableAddressFamilySpace(addressSpace.IPv6),
+	}
+}
+
+// Render provides a hook to customize the render process.
+func (getAddressSpaceResponse *GetAddressSpaceResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*GetAddressSpaceResponse)(nil)
+
+// RenderableAddressFamilySpace defines what should be returned to a
+// user for the use of a single supernet.
+type RenderableAddressFamilySpace struct {
+	Supernet netaddr.IPPrefix      `json:"supernet"`
+	Used     []RenderableUsedRange `json:"used"`
+	Free     []netaddr.IPPrefix    `json:"free"`
+}
+
+// RenderableUsedRange defines what should be returned to a user for a
+// range that belongs to a network.
+type RenderableUsedRange struct {
+	Network      string           `json:"network"`
+	Organization string           `json:"organization"`
+	CIDR         netaddr.IPPrefix `json:"cidr"`
+}
+
+func newRenderableAddressFamilySpace(space *AddressFamilySpace) *RenderableAddressFamilySpace {
+	if space == nil {
+		return nil
+	}
+
+	used := make([]RenderableUsedRange, 0, len(space.Used))
+	for _, usedRange := range space.Used {
+		used = append(used, RenderableUsedRange(usedRange))
+	}
+
+	free := space.Free
+	if free == nil {
+		free = []netaddr.IPPrefix{}
+	}
+
+	return &RenderableAddressFamilySpace{
+		Supernet: space.Supernet,
+		Used:     used,
+		Free:     free,
+	}
+}
+
+// GetAddressSpace handles requests to report the used and free ranges
+// within the configured supernets.
+func (controller *Controller) GetAddressSpace(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	addressSpace, err := controller.NetworkService.GetAddressSpace(ctx)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	getAddressSpaceResponse := NewGetAddressSpaceResponse(addressSpace)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &getAddressSpaceResponse)
+}
+
 func handleError(
 	response http.ResponseWriter,
 	request *http.Request,
 	err error,
 ) {
 	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
 	var userError errortypes.UserError
 	var systemError errortypes.SystemError
 	switch {
@@ -148,6 +418,22 @@ func handleError(
 
 		return
 
+	case errors.As(err, &notFoundError):
+		response.WriteHeader(http.StatusNotFound)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: notFoundError.SafeMessage,
+		})
+
+		return
+
+	case errors.As(err, &preconditionFailedError):
+		response.WriteHeader(http.StatusPreconditionFailed)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: preconditionFailedError.SafeMessage,
+		})
+
+		return
+
 	case errors.As(err, &userError):
 		response.WriteHeader(http.StatusBadRequest)
 		_ = render.Render(response, request, &renderable.ErrorResponse{
@@ -177,22 +463,34 @@ func handleError(
 // RenderableNetwork defines what should returned to a user for a
 // network.
 type RenderableNetwork struct {
-	Name       string            `json:"name"`
-	IPv4CIDR   *netaddr.IPPrefix `json:"ipv4CIDR,omitempty"`
-	IPv6CIDR   *netaddr.IPPrefix `json:"ipv6CIDR,omitempty"`
-	CreatedOn  renderable.Time   `json:"createdOn"`
-	ModifiedOn renderable.Time   `json:"modifiedOn"`
+	Name          string            `json:"name"`
+	Organization  string            `json:"organization"`
+	IPv4CIDR      *netaddr.IPPrefix `json:"ipv4CIDR,omitempty"`
+	IPv6CIDR      *netaddr.IPPrefix `json:"ipv6CIDR,omitempty"`
+	AllowOverlap  bool              `json:"allowOverlap,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Topology      Topology          `json:"topology"`
+	PresharedKeys bool              `json:"presharedKeys,omitempty"`
+	Version       int64             `json:"version"`
+	CreatedOn     renderable.Time   `json:"createdOn"`
+	ModifiedOn    renderable.Time   `json:"modifiedOn"`
 }
 
 // NewRenderableNetwork creates a new renderable network from a backend
 // network instance.
 func NewRenderableNetwork(network *Network) RenderableNetwork {
 	return RenderableNetwork{
-		Name:       network.Name(),
-		IPv4CIDR:   network.IPv4CIDR(),
-		IPv6CIDR:   network.IPv6CIDR(),
-		CreatedOn:  renderable.Time(network.CreatedOn()),
-		ModifiedOn: renderable.Time(network.ModifiedOn()),
+		Name:          network.Name(),
+		Organization:  network.Organization(),
+		IPv4CIDR:      network.IPv4CIDR(),
+		IPv6CIDR:      network.IPv6CIDR(),
+		AllowOverlap:  network.AllowOverlap(),
+		Labels:        network.Labels(),
+		Topology:      network.Topology(),
+		PresharedKeys: network.PresharedKeys(),
+		Version:       network.Version(),
+		CreatedOn:     renderable.Time(network.CreatedOn()),
+		ModifiedOn:    renderable.Time(network.ModifiedOn()),
 	}
 }
 
@@ -0,0 +1,41 @@
+INSERT INTO Networks (
+    ID,
+    Name,
+    IPv4CIDR,
+    IPv6CIDR,
+    AllowOverlap,
+    Labels,
+    Topology,
+    PresharedKeys,
+    CreatedOn,
+    ModifiedOn,
+    OrganizationID
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $9,
+    $10
+)
+RETURNING
+    ID,
+    Name,
+    IPv4CIDR,
+    IPv6CIDR,
+    AllowOverlap,
+    Labels,
+    Topology,
+    PresharedKeys,
+    Version,
+    CreatedOn,
+    ModifiedOn,
+    OrganizationID,
+    (SELECT Organizations.Name FROM Organizations WHERE Organizations.ID = Networks.OrganizationID)
+;
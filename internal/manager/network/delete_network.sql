@@ -0,0 +1,20 @@
+DELETE FROM Networks
+WHERE
+    OrganizationID = $3
+    AND Name = $1
+    AND ($2::BIGINT[] IS NULL OR Version = ANY($2))
+RETURNING
+    ID,
+    Name,
+    IPv4CIDR,
+    IPv6CIDR,
+    AllowOverlap,
+    Labels,
+    Topology,
+    PresharedKeys,
+    Version,
+    CreatedOn,
+    ModifiedOn,
+    OrganizationID,
+    (SELECT Organizations.Name FROM Organizations WHERE Organizations.ID = Networks.OrganizationID)
+;
@@ -0,0 +1,13 @@
+SELECT
+    Name,
+    OrganizationID
+FROM Networks
+WHERE
+    NOT AllowOverlap
+    AND (
+        IPv4CIDR && $1::CIDR
+        OR IPv6CIDR && $2::CIDR
+    )
+ORDER BY Name
+LIMIT 1
+;
@@ -0,0 +1,19 @@
+SELECT
+    ID,
+    Name,
+    IPv4CIDR,
+    IPv6CIDR,
+    AllowOverlap,
+    Labels,
+    Topology,
+    PresharedKeys,
+    Version,
+    CreatedOn,
+    ModifiedOn,
+    OrganizationID,
+    (SELECT Organizations.Name FROM Organizations WHERE Organizations.ID = Networks.OrganizationID)
+FROM Networks
+WHERE
+    ID = $1
+LIMIT 1
+;
@@ -0,0 +1,20 @@
+SELECT
+    ID,
+    Name,
+    IPv4CIDR,
+    IPv6CIDR,
+    AllowOverlap,
+    Labels,
+    Topology,
+    PresharedKeys,
+    Version,
+    CreatedOn,
+    ModifiedOn,
+    OrganizationID,
+    (SELECT Organizations.Name FROM Organizations WHERE Organizations.ID = Networks.OrganizationID)
+FROM Networks
+WHERE
+    OrganizationID = $1
+    AND Name = $2
+LIMIT 1
+;
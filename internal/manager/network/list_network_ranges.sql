@@ -0,0 +1,11 @@
+SELECT
+    Name,
+    (SELECT Organizations.Name FROM Organizations WHERE Organizations.ID = Networks.OrganizationID),
+    IPv4CIDR,
+    IPv6CIDR
+FROM Networks
+WHERE
+    IPv4CIDR && $1::CIDR
+    OR IPv6CIDR && $2::CIDR
+ORDER BY Name
+;
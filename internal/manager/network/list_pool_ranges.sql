@@ -0,0 +1,6 @@
+SELECT
+    IPv4CIDR
+FROM Networks
+WHERE
+    IPv4CIDR && ANY($1::CIDR[])
+;
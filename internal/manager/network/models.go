@@ -8,16 +8,23 @@ import (
 
 // Network represents a virtual network powered by WireGuard.
 type Network struct {
-	id        string
-	name      string
-	ipv4CIDR  *netaddr.IPPrefix
-	ipv6CIDR  *netaddr.IPPrefix
-	createdOn time.Time
+	id             string
+	name           string
+	organizationID string
+	organization   string
+	ipv4CIDR       *netaddr.IPPrefix
+	ipv6CIDR       *netaddr.IPPrefix
+	allowOverlap   bool
+	labels         map[string]string
+	topology       Topology
+	presharedKeys  bool
+	version        int64
+	createdOn      time.Time
 	// TODO: createdBy
 	modifiedOn time.Time
 	// TODO: modifiedBy
 	// TODO: add list of nodes that belong to network
-	// TODO: ACL/permissions policy
+	// TODO: ACL/permissions policy, with groups as source selectors
 	// TODO: add ingress settings
 	// TODO: add egress settings
 }
@@ -32,6 +39,17 @@ func (network *Network) Name() string {
 	return network.name
 }
 
+// OrganizationID is the database ID of the organization that owns the
+// network.
+func (network *Network) OrganizationID() string {
+	return network.organizationID
+}
+
+// Organization is the name of the organization that owns the network.
+func (network *Network) Organization() string {
+	return network.organization
+}
+
 // IPv4CIDR is the set of IPv4 addresses that this network can use.
 func (network *Network) IPv4CIDR() *netaddr.IPPrefix {
 	return network.ipv4CIDR
@@ -42,6 +60,35 @@ func (network *Network) IPv6CIDR() *netaddr.IPPrefix {
 	return network.ipv6CIDR
 }
 
+// AllowOverlap tells if the network may use ranges that overlap other
+// networks.
+func (network *Network) AllowOverlap() bool {
+	return network.allowOverlap
+}
+
+// Labels are the user defined key/value pairs attached to the
+// network. They can be used to filter networks when listing them.
+func (network *Network) Labels() map[string]string {
+	return network.labels
+}
+
+// Topology decides which nodes in the network peer with each other.
+func (network *Network) Topology() Topology {
+	return network.topology
+}
+
+// PresharedKeys tells if the manager hands out a preshared key for
+// every pair of peers in the network on top of their key pairs.
+func (network *Network) PresharedKeys() bool {
+	return network.presharedKeys
+}
+
+// Version is incremented every time the network is changed. It is
+// used to detect conflicting updates.
+func (network *Network) Version() int64 {
+	return network.version
+}
+
 // CreatedOn is the date and time that the network was created on.
 func (network *Network) CreatedOn() time.Time {
 	return network.createdOn
@@ -0,0 +1,145 @@
+package network_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/organization"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestNetworkNamesShouldBeUniquePerOrganization(t *testing.T) {
+	ctx := context.Background()
+	networkService, organizationService := newTestServices(t)
+
+	firstOrganization := newTestOrganization(ctx, t, organizationService)
+	secondOrganization := newTestOrganization(ctx, t, organizationService)
+	networkName := fmt.Sprintf("org-test-%d", rng.RNG.Int63())
+
+	first := createTestNetwork(ctx, t, networkService, firstOrganization, networkName)
+	second := createTestNetwork(ctx, t, networkService, secondOrganization, networkName)
+	require.NotEqual(t, first.ID(), second.ID(), "should be separate networks")
+	require.Equal(t, firstOrganization, first.Organization())
+	require.Equal(t, secondOrganization, second.Organization())
+
+	prefix := netaddr.MustParseIPPrefix("10.5.0.0/29")
+	_, err := networkService.CreateNetwork(ctx, firstOrganization, network.CreateNetworkOpts{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+
+	var validationError errortypes.ValidationError
+	require.True(t, errors.As(err, &validationError), "should reject a name taken in the same organization")
+
+	_, err = networkService.CreateNetwork(ctx, firstOrganization, network.CreateNetworkOpts{
+		Name:         strings.ToUpper(networkName),
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.True(t, errors.As(err, &validationError), "should reject a name that only differs in case")
+}
+
+func TestOrganizationsShouldNotSeeEachOthersNetworks(t *testing.T) {
+	ctx := context.Background()
+	networkService, organizationService := newTestServices(t)
+
+	owner := newTestOrganization(ctx, t, organizationService)
+	other := newTestOrganization(ctx, t, organizationService)
+	networkName := fmt.Sprintf("org-test-%d", rng.RNG.Int63())
+	ownedNetwork := createTestNetwork(ctx, t, networkService, owner, networkName)
+
+	var notFoundError errortypes.NotFoundError
+
+	_, err := networkService.GetNetworkByName(ctx, other, networkName)
+	require.True(t, errors.As(err, &notFoundError), "should not get another organization's network")
+
+	newName := networkName + "-renamed"
+	_, err = networkService.UpdateNetwork(ctx, other, networkName, network.UpdateNetworkOpts{Name: &newName})
+	require.True(t, errors.As(err, &notFoundError), "should not update another organization's network")
+
+	err = networkService.DeleteNetwork(ctx, other, networkName, nil)
+	require.True(t, errors.As(err, &notFoundError), "should not delete another organization's network")
+
+	page, err := networkService.ListNetworks(ctx, other, listing.Params{
+		Limit:     listing.MaxLimit,
+		SortField: listing.SortFieldCreatedOn,
+		SortOrder: listing.SortOrderAscending,
+	})
+	require.Nil(t, err, "should be able to list networks")
+	require.Empty(t, page.Items, "should not list another organization's networks")
+
+	unchangedNetwork, err := networkService.GetNetworkByName(ctx, owner, networkName)
+	require.Nil(t, err, "should still get the network from its own organization")
+	require.Equal(t, ownedNetwork.Version(), unchangedNetwork.Version(), "should not have changed the network")
+
+	_, err = networkService.GetNetworkByName(ctx, fmt.Sprintf("missing-%d", rng.RNG.Int63()), networkName)
+	require.True(t, errors.As(err, &notFoundError), "should not find networks of a missing organization")
+}
+
+func newTestServices(t *testing.T) (*network.Service, *organization.Service) {
+	dbConfig := configuration.DBConfiguration{
+		Type: configuration.DBTypePostgres,
+		Postgres: configuration.PostgresConfiguration{
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Role:     "ley",
+			Password: "ley",
+			DBName:   "ley",
+			SSLMode:  "disable",
+		},
+	}
+
+	connectionString, err := dbConfig.ConnectionString()
+	require.Nil(t, err, "should be able to create a connection string")
+
+	db, err := sql.Open(string(dbConfig.Type), connectionString)
+	require.Nil(t, err, "should be able to open the database")
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	organizationService := organization.NewService(db)
+
+	return network.NewService(db, configuration.NetworkConfiguration{}, organizationService), organizationService
+}
+
+func newTestOrganization(ctx context.Context, t *testing.T, organizationService *organization.Service) string {
+	name := fmt.Sprintf("org-test-%d", rng.RNG.Int63())
+
+	_, err := organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: name})
+	require.Nil(t, err, "should be able to create an organization")
+
+	return name
+}
+
+func createTestNetwork(
+	ctx context.Context,
+	t *testing.T,
+	networkService *network.Service,
+	organizationName string,
+	networkName string,
+) *network.Network {
+	prefix := netaddr.MustParseIPPrefix("10.5.0.0/29")
+
+	createdNetwork, err := networkService.CreateNetwork(ctx, organizationName, network.CreateNetworkOpts{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	return createdNetwork
+}
@@ -3,31 +3,95 @@ package network
 import (
 	"context"
 	"database/sql"
+	_ "embed"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"time"
 
+	"github.com/durandj/ley/internal/manager/configuration"
 	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/webhook"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"inet.af/netaddr"
 )
 
+const (
+	maxLabels           = 64
+	maxLabelValueLength = 256
+)
+
 var (
 	networkNameRegex = regexp.MustCompile(`^\w[\w-_]+$`)
+	labelKeyRegex    = regexp.MustCompile(`^[A-Za-z0-9][-A-Za-z0-9_./]{0,62}$`)
+
+	// reservedNetworkNames can't be used because they would be
+	// shadowed by other network endpoints.
+	reservedNetworkNames = map[string]struct{}{
+		"address-space": {},
+	}
+
+	//go:embed create_network.sql
+	createNetworkSQL string
+
+	//go:embed list_networks.sql
+	listNetworksSQL string
+
+	//go:embed get_network_by_name.sql
+	getNetworkByNameSQL string
+
+	//go:embed get_network.sql
+	getNetworkSQL string
+
+	//go:embed update_network.sql
+	updateNetworkSQL string
+
+	//go:embed delete_network.sql
+	deleteNetworkSQL string
+
+	//go:embed find_overlapping_network.sql
+	findOverlappingNetworkSQL string
+
+	//go:embed list_network_ranges.sql
+	listNetworkRangesSQL string
+
+	//go:embed lock_address_pools.sql
+	lockAddressPoolsSQL string
+
+	//go:embed list_pool_ranges.sql
+	listPoolRangesSQL string
+
+	listNetworksColumns = listing.Columns{
+		ID:        "ID",
+		Name:      "Name",
+		CreatedOn: "CreatedOn",
+		Labels:    "Labels",
+	}
 )
 
-// Service provides methods for working with networks.
+// Service provides methods for working with networks. Networks belong
+// to an organization and every method that takes the name of an
+// organization only ever sees that organization's networks.
 type Service struct {
-	// TODO: replace this with a database
-	networks []Network
-	db       *sql.DB
+	db                  *sql.DB
+	config              configuration.NetworkConfiguration
+	organizationService *organization.Service
 }
 
 // NewService creates a new network service.
-func NewService(db *sql.DB) *Service {
+func NewService(
+	db *sql.DB,
+	config configuration.NetworkConfiguration,
+	organizationService *organization.Service,
+) *Service {
 	return &Service{
-		networks: nil,
-		db:       db,
+		db:                  db,
+		config:              config,
+		organizationService: organizationService,
 	}
 }
 
@@ -37,54 +101,696 @@ type CreateNetworkOpts struct {
 	Name     string
 	IPv4CIDR *netaddr.IPPrefix
 	IPv6CIDR *netaddr.IPPrefix
+	Labels   map[string]string
+
+	// AllowOverlap lets the network use ranges that overlap other
+	// networks. It is meant for isolated tenants that never share
+	// nodes with the networks they overlap.
+	AllowOverlap bool
+
+	// IPv4PrefixLength asks for the next free range of this size to be
+	// taken from the configured IPv4 pools instead of giving IPv4CIDR.
+	IPv4PrefixLength *int
+
+	// GenerateIPv6 asks for a random unique local range to be
+	// generated instead of giving IPv6CIDR.
+	GenerateIPv6 bool
+
+	// Topology decides which nodes peer with each other. Nil uses a
+	// full mesh.
+	Topology *Topology
+
+	// PresharedKeys has the manager generate a preshared key for every
+	// pair of peers, which adds a layer of symmetric encryption.
+	PresharedKeys bool
 }
 
 // Validate validates that the options which were given are valid.
 func (opts *CreateNetworkOpts) Validate() error {
-	if !networkNameRegex.MatchString(opts.Name) {
-		return fmt.Errorf("Invalid network name '%s'", opts.Name)
+	if err := validateName(opts.Name); err != nil {
+		return err
 	}
 
-	if opts.IPv4CIDR == nil && opts.IPv6CIDR == nil {
+	if opts.IPv4CIDR != nil && opts.IPv4PrefixLength != nil {
+		return fmt.Errorf("Cannot give both an IPv4 range and an IPv4 prefix length")
+	}
+
+	if opts.IPv6CIDR != nil && opts.GenerateIPv6 {
+		return fmt.Errorf("Cannot give an IPv6 range and also ask for one to be generated")
+	}
+
+	if opts.IPv4CIDR == nil && opts.IPv4PrefixLength == nil && opts.IPv6CIDR == nil && !opts.GenerateIPv6 {
 		return fmt.Errorf("Must have at least one IP range defined")
 	}
 
+	if opts.IPv4PrefixLength != nil {
+		prefixLength := *opts.IPv4PrefixLength
+		if prefixLength < minIPv4PrefixBits || prefixLength > maxIPv4PrefixBits {
+			return fmt.Errorf(
+				"IPv4 prefix length must be between %d and %d",
+				minIPv4PrefixBits,
+				maxIPv4PrefixBits,
+			)
+		}
+	}
+
+	if err := validatePrefix("IPv4", opts.IPv4CIDR, true, maxIPv4PrefixBits); err != nil {
+		return err
+	}
+
+	if err := validatePrefix("IPv6", opts.IPv6CIDR, false, maxIPv6PrefixBits); err != nil {
+		return err
+	}
+
+	if opts.Topology != nil {
+		if err := opts.Topology.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return validateLabels(opts.Labels)
+}
+
+func validateName(name string) error {
+	if !networkNameRegex.MatchString(name) {
+		return fmt.Errorf("Invalid network name '%s'", name)
+	}
+
+	if _, ok := reservedNetworkNames[name]; ok {
+		return fmt.Errorf("Network name '%s' is reserved", name)
+	}
+
+	return nil
+}
+
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("Cannot have more than %d labels", maxLabels)
+	}
+
+	for key, value := range labels {
+		if !labelKeyRegex.MatchString(key) {
+			return fmt.Errorf("Invalid label key '%s'", key)
+		}
+
+		if len(value) > maxLabelValueLength {
+			return fmt.Errorf("Label '%s' cannot be longer than %d characters", key, maxLabelValueLength)
+		}
+	}
+
 	return nil
 }
 
-// CreateNetwork creates a new managed network.
+// CreateNetwork creates a new managed network in an organization.
 func (service *Service) CreateNetwork(
 	ctx context.Context,
+	organizationName string,
 	opts CreateNetworkOpts,
 ) (*Network, error) {
 	if err := opts.Validate(); err != nil {
 		return nil, errortypes.NewWrappedValidationError(err, "Unable to create network: %v", err)
 	}
 
-	for _, network := range service.networks {
-		if network.Name() == opts.Name {
-			return nil, errortypes.NewValidationError("Network name is already taken")
+	owner, err := service.organizationService.GetOrganizationByName(ctx, organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := opts.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	rawLabels, err := json.Marshal(labels)
+	if err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to create network: Invalid labels")
+	}
+
+	topology := DefaultTopology()
+	if opts.Topology != nil {
+		topology = *opts.Topology
+	}
+
+	rawTopology, err := json.Marshal(topology)
+	if err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to create network: Invalid topology")
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new network due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := service.allocateRanges(ctx, tx, &opts); err != nil {
+		return nil, err
+	}
+
+	if !opts.AllowOverlap {
+		overlappingNetwork, overlappingOrganizationID, err := service.findOverlappingNetwork(
+			ctx,
+			tx,
+			opts.IPv4CIDR,
+			opts.IPv6CIDR,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Ranges are shared by every organization but the names of other
+		// organizations' networks aren't given away.
+		if overlappingNetwork != "" && overlappingOrganizationID != owner.ID() {
+			return nil, errortypes.NewValidationError(
+				"Unable to create network: IP range overlaps a network of another organization",
+			)
+		}
+
+		if overlappingNetwork != "" {
+			return nil, errortypes.NewValidationError(
+				"Unable to create network: IP range overlaps network '%s'",
+				overlappingNetwork,
+			)
 		}
 	}
 
 	creationTime := time.Now().UTC()
 
-	network := Network{
-		id:         uuid.NewString(),
-		name:       opts.Name,
-		ipv4CIDR:   opts.IPv4CIDR,
-		ipv6CIDR:   opts.IPv6CIDR,
-		createdOn:  creationTime,
-		modifiedOn: creationTime,
+	network, err := scanNetwork(tx.QueryRowContext(
+		ctx,
+		createNetworkSQL,
+		uuid.NewString(),
+		opts.Name,
+		prefixToNullString(opts.IPv4CIDR),
+		prefixToNullString(opts.IPv6CIDR),
+		opts.AllowOverlap,
+		string(rawLabels),
+		string(rawTopology),
+		opts.PresharedKeys,
+		creationTime,
+		owner.ID(),
+	))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			errorName := pqErr.Code.Name()
+			constraint := pqErr.Constraint
+			if errorName == "unique_violation" && constraint == "networks_organization_name_key" {
+				return nil, errortypes.NewValidationError("Network name is already taken")
+			}
+
+			// Another network with an overlapping range was created
+			// after the overlap check ran.
+			if errorName == "exclusion_violation" {
+				return nil, errortypes.NewValidationError(
+					"Unable to create network: IP range overlaps another network",
+				)
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new network due to a system error",
+			UnsafeMessage: "Unable to create new network due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	err = recordWebhookEvent(
+		ctx,
+		tx,
+		webhook.EventTypeNetworkCreated,
+		network,
+		"Unable to create new network due to a system error",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new network due to a system error",
+			UnsafeMessage: "Unable to commit new network",
+			WrappedError:  err,
+		}
+	}
+
+	return network, nil
+}
+
+// ListNetworks retrieves a page of the networks of an organization.
+func (service *Service) ListNetworks(
+	ctx context.Context,
+	organizationName string,
+	params listing.Params,
+) (listing.Page[Network], error) {
+	owner, err := service.organizationService.GetOrganizationByName(ctx, organizationName)
+	if err != nil {
+		return listing.Page[Network]{}, err
+	}
+
+	query, args, err := params.SQL(
+		listNetworksSQL,
+		listNetworksColumns,
+		[]string{"OrganizationID = $1"},
+		[]any{owner.ID()},
+	)
+	if err != nil {
+		return listing.Page[Network]{}, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return listing.Page[Network]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list networks due to a system error",
+			UnsafeMessage: "Unable to list networks due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	networks := []Network{}
+	for rows.Next() {
+		network, err := scanNetwork(rows)
+		if err != nil {
+			return listing.Page[Network]{}, errortypes.SystemError{
+				SafeMessage:   "Unable to list networks due to a system error",
+				UnsafeMessage: "Unable to read network row",
+				WrappedError:  err,
+			}
+		}
+
+		networks = append(networks, *network)
+	}
+
+	if err := rows.Err(); err != nil {
+		return listing.Page[Network]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list networks due to a system error",
+			UnsafeMessage: "Unable to iterate over network rows",
+			WrappedError:  err,
+		}
 	}
 
-	service.networks = append(service.networks, network)
-	// TODO: write to RDS here
+	return listing.NewPage(params, networks, func(network *Network) listing.Key {
+		return listing.Key{
+			ID:        network.ID(),
+			Name:      network.Name(),
+			CreatedOn: network.CreatedOn(),
+		}
+	}), nil
+}
+
+// GetNetworkByName fetches a network of an organization by its name.
+func (service *Service) GetNetworkByName(
+	ctx context.Context,
+	organizationName string,
+	name string,
+) (*Network, error) {
+	owner, err := service.organizationService.GetOrganizationByName(ctx, organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	network, err := scanNetwork(service.db.QueryRowContext(ctx, getNetworkByNameSQL, owner.ID(), name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a network with that name",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get network by name due to a system error",
+			UnsafeMessage: "Unable to get network by name due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return network, nil
+}
+
+// GetNetwork fetches a network by its ID, whichever organization it
+// belongs to. It is meant for looking up the network of a node, never
+// for a network that a caller named.
+func (service *Service) GetNetwork(
+	ctx context.Context,
+	id string,
+) (*Network, error) {
+	network, err := scanNetwork(service.db.QueryRowContext(ctx, getNetworkSQL, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a network with that ID",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get network due to a system error",
+			UnsafeMessage: "Unable to get network due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return network, nil
+}
+
+// UpdateNetworkOpts gives the changes to make to a network. Nil fields
+// are left as they are.
+type UpdateNetworkOpts struct {
+	Name          *string
+	Labels        map[string]string
+	Topology      *Topology
+	PresharedKeys *bool
+
+	// ExpectedVersions limits the update to these versions of the
+	// network. Nil allows any version.
+	ExpectedVersions []int64
+}
+
+// Validate checks that the update options are valid.
+func (opts *UpdateNetworkOpts) Validate() error {
+	if opts.Name != nil {
+		if err := validateName(*opts.Name); err != nil {
+			return err
+		}
+	}
+
+	if opts.Topology != nil {
+		if err := opts.Topology.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return validateLabels(opts.Labels)
+}
+
+// UpdateNetwork changes a network of an organization.
+func (service *Service) UpdateNetwork(
+	ctx context.Context,
+	organizationName string,
+	name string,
+	opts UpdateNetworkOpts,
+) (*Network, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to update network: %v", err)
+	}
+
+	owner, err := service.organizationService.GetOrganizationByName(ctx, organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawLabels *string
+	if opts.Labels != nil {
+		labelBytes, err := json.Marshal(opts.Labels)
+		if err != nil {
+			return nil, errortypes.NewWrappedValidationError(err, "Unable to update network: Invalid labels")
+		}
+
+		labelString := string(labelBytes)
+		rawLabels = &labelString
+	}
+
+	var rawTopology *string
+	if opts.Topology != nil {
+		topologyBytes, err := json.Marshal(opts.Topology)
+		if err != nil {
+			return nil, errortypes.NewWrappedValidationError(err, "Unable to update network: Invalid topology")
+		}
+
+		topologyString := string(topologyBytes)
+		rawTopology = &topologyString
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to update network due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	network, err := scanNetwork(tx.QueryRowContext(
+		ctx,
+		updateNetworkSQL,
+		name,
+		opts.Name,
+		rawLabels,
+		time.Now().UTC(),
+		pq.Array(opts.ExpectedVersions),
+		rawTopology,
+		opts.PresharedKeys,
+		owner.ID(),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, service.explainMissingNetwork(ctx, organizationName, name)
+		}
+
+		if pqErr, ok := err.(*pq.Error); ok {
+			errorName := pqErr.Code.Name()
+			constraint := pqErr.Constraint
+			if errorName == "unique_violation" && constraint == "networks_organization_name_key" {
+				return nil, errortypes.NewValidationError("Network name is already taken")
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to update network due to a system error",
+			UnsafeMessage: "Unable to update network due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	err = recordWebhookEvent(
+		ctx,
+		tx,
+		webhook.EventTypeNetworkUpdated,
+		network,
+		"Unable to update network due to a system error",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The topology and preshared keys decide the config of every node
+	// so watchers need to know about any change.
+	if err := notify.Notify(ctx, tx, notify.NetworkChannel, network.ID()); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to update network due to a system error",
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to update network due to a system error",
+			UnsafeMessage: "Unable to commit network update",
+			WrappedError:  err,
+		}
+	}
+
+	return network, nil
+}
+
+// DeleteNetwork removes a network of an organization. The delete is
+// limited to the expected versions of the network unless they are nil.
+func (service *Service) DeleteNetwork(
+	ctx context.Context,
+	organizationName string,
+	name string,
+	expectedVersions []int64,
+) error {
+	owner, err := service.organizationService.GetOrganizationByName(ctx, organizationName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete network due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	network, err := scanNetwork(tx.QueryRowContext(
+		ctx,
+		deleteNetworkSQL,
+		name,
+		pq.Array(expectedVersions),
+		owner.ID(),
+	))
+	if err == sql.ErrNoRows {
+		return service.explainMissingNetwork(ctx, organizationName, name)
+	}
+
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete network due to a system error",
+			UnsafeMessage: "Unable to delete network due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	err = recordWebhookEvent(
+		ctx,
+		tx,
+		webhook.EventTypeNetworkDeleted,
+		network,
+		"Unable to delete network due to a system error",
+	)
+	if err != nil {
+		return err
+	}
+
+	// The nodes of the network go with it so their watchers need to
+	// find out.
+	if err := notify.Notify(ctx, tx, notify.NetworkChannel, network.ID()); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete network due to a system error",
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete network due to a system error",
+			UnsafeMessage: "Unable to commit network removal",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// recordWebhookEvent queues a change to a network for the webhooks
+// subscribed to it.
+func recordWebhookEvent(
+	ctx context.Context,
+	tx *sql.Tx,
+	eventType webhook.EventType,
+	network *Network,
+	safeMessage string,
+) error {
+	renderableNetwork := NewRenderableNetwork(network)
+	if err := webhook.Record(ctx, tx, eventType, &renderableNetwork); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   safeMessage,
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// explainMissingNetwork figures out why a conditional change didn't
+// match any rows, either because the network doesn't exist or because
+// it was at a different version.
+func (service *Service) explainMissingNetwork(ctx context.Context, organizationName string, name string) error {
+	if _, err := service.GetNetworkByName(ctx, organizationName, name); err != nil {
+		return err
+	}
+
+	return errortypes.PreconditionFailedError{
+		UserError: errortypes.UserError{
+			SafeMessage: "The network has been changed since it was last read",
+		},
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNetwork(row rowScanner) (*Network, error) {
+	var network Network
+	var ipv4CIDR sql.NullString
+	var ipv6CIDR sql.NullString
+	var rawLabels []byte
+	var rawTopology []byte
+
+	err := row.Scan(
+		&network.id,
+		&network.name,
+		&ipv4CIDR,
+		&ipv6CIDR,
+		&network.allowOverlap,
+		&rawLabels,
+		&rawTopology,
+		&network.presharedKeys,
+		&network.version,
+		&network.createdOn,
+		&network.modifiedOn,
+		&network.organizationID,
+		&network.organization,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if network.ipv4CIDR, err = nullStringToPrefix(ipv4CIDR); err != nil {
+		return nil, err
+	}
+
+	if network.ipv6CIDR, err = nullStringToPrefix(ipv6CIDR); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(rawLabels, &network.labels); err != nil {
+		return nil, fmt.Errorf("Unable to parse network labels: %w", err)
+	}
+
+	if err := json.Unmarshal(rawTopology, &network.topology); err != nil {
+		return nil, fmt.Errorf("Unable to parse network topology: %w", err)
+	}
 
 	return &network, nil
 }
 
-// ListNetworks retrieves all managed networks.
-func (service *Service) ListNetworks(ctx context.Context) ([]Network, error) {
-	return service.networks, nil
+func prefixToNullString(prefix *netaddr.IPPrefix) sql.NullString {
+	if prefix == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: prefix.String(), Valid: true}
+}
+
+func nullStringToPrefix(value sql.NullString) (*netaddr.IPPrefix, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	prefix, err := netaddr.ParseIPPrefix(value.String)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse IP CIDR '%s': %w", value.String, err)
+	}
+
+	return &prefix, nil
 }
@@ -0,0 +1,185 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TopologyMode decides which nodes in a network peer with each other.
+type TopologyMode string
+
+const (
+	// TopologyModeFullMesh has every node peer with every other node.
+	TopologyModeFullMesh TopologyMode = "full-mesh"
+
+	// TopologyModeHubAndSpoke has spokes only peer with the hubs, which
+	// route traffic between the spokes.
+	TopologyModeHubAndSpoke TopologyMode = "hub-and-spoke"
+
+	// TopologyModeCustom has nodes peer with the other members of the
+	// peer groups they are in.
+	TopologyModeCustom TopologyMode = "custom"
+)
+
+const (
+	maxPeerGroups = 64
+)
+
+// Topology describes how the nodes in a network are connected.
+type Topology struct {
+	Mode TopologyMode `json:"mode"`
+
+	// Hubs are the nodes that every other node peers with when using
+	// hub and spoke.
+	Hubs []string `json:"hubs,omitempty"`
+
+	// PeerGroups are the groups of nodes that peer with each other when
+	// using a custom topology.
+	PeerGroups []PeerGroup `json:"peerGroups,omitempty"`
+}
+
+// PeerGroup is a set of nodes that all peer with each other.
+type PeerGroup struct {
+	Name  string   `json:"name"`
+	Nodes []string `json:"nodes"`
+}
+
+// DefaultTopology is used for networks that weren't given one.
+func DefaultTopology() Topology {
+	return Topology{Mode: TopologyModeFullMesh}
+}
+
+// Validate checks that the topology is complete and only uses the
+// settings for its mode.
+func (topology *Topology) Validate() error {
+	switch topology.Mode {
+	case TopologyModeFullMesh:
+		if len(topology.Hubs) > 0 || len(topology.PeerGroups) > 0 {
+			return fmt.Errorf("A full mesh topology cannot have hubs or peer groups")
+		}
+
+	case TopologyModeHubAndSpoke:
+		if len(topology.Hubs) == 0 {
+			return fmt.Errorf("A hub and spoke topology needs at least one hub")
+		}
+
+		if len(topology.PeerGroups) > 0 {
+			return fmt.Errorf("A hub and spoke topology cannot have peer groups")
+		}
+
+	case TopologyModeCustom:
+		if len(topology.Hubs) > 0 {
+			return fmt.Errorf("A custom topology cannot have hubs")
+		}
+
+		if len(topology.PeerGroups) > maxPeerGroups {
+			return fmt.Errorf("Cannot have more than %d peer groups", maxPeerGroups)
+		}
+
+		groupNames := map[string]struct{}{}
+		for _, peerGroup := range topology.PeerGroups {
+			if !networkNameRegex.MatchString(peerGroup.Name) {
+				return fmt.Errorf("Invalid peer group name '%s'", peerGroup.Name)
+			}
+
+			if _, ok := groupNames[peerGroup.Name]; ok {
+				return fmt.Errorf("Peer group '%s' is defined more than once", peerGroup.Name)
+			}
+
+			groupNames[peerGroup.Name] = struct{}{}
+		}
+
+	default:
+		return fmt.Errorf("Unsupported topology mode '%s'", topology.Mode)
+	}
+
+	return nil
+}
+
+// Peer is a node that another node should set up a tunnel with.
+type Peer struct {
+	Node string
+
+	// Routes is true when traffic for the rest of the network should
+	// be sent through this peer, which is how spokes reach each other
+	// through a hub.
+	Routes bool
+}
+
+// Peers works out who a node should peer with given all the nodes in
+// the network. Nodes that aren't in the network don't get any peers
+// and are never given as a peer. Peers are sorted by node name.
+func (topology Topology) Peers(node string, nodes []string) []Peer {
+	members := map[string]struct{}{}
+	for _, member := range nodes {
+		members[member] = struct{}{}
+	}
+
+	if _, ok := members[node]; !ok {
+		return nil
+	}
+
+	peers := map[string]bool{}
+	addPeer := func(peer string, routes bool) {
+		if _, ok := members[peer]; !ok || peer == node {
+			return
+		}
+
+		peers[peer] = peers[peer] || routes
+	}
+
+	switch topology.Mode {
+	case TopologyModeFullMesh:
+		for member := range members {
+			addPeer(member, false)
+		}
+
+	case TopologyModeHubAndSpoke:
+		hubs := map[string]struct{}{}
+		for _, hub := range topology.Hubs {
+			hubs[hub] = struct{}{}
+		}
+
+		if _, isHub := hubs[node]; isHub {
+			for member := range members {
+				addPeer(member, false)
+			}
+		} else {
+			for hub := range hubs {
+				addPeer(hub, true)
+			}
+		}
+
+	case TopologyModeCustom:
+		for _, peerGroup := range topology.PeerGroups {
+			if !contains(peerGroup.Nodes, node) {
+				continue
+			}
+
+			for _, member := range peerGroup.Nodes {
+				addPeer(member, false)
+			}
+		}
+	}
+
+	peerList := make([]Peer, 0, len(peers))
+	for peer, routes := range peers {
+		peerList = append(peerList, Peer{Node: peer, Routes: routes})
+	}
+
+	sort.Slice(peerList, func(left, right int) bool {
+		return peerList[left].Node < peerList[right].Node
+	})
+
+	return peerList
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,222 @@
+package network_test
+
+import (
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologyPeers(t *testing.T) {
+	nodes := []string{"alpha", "bravo", "charlie", "delta"}
+
+	hubAndSpoke := network.Topology{
+		Mode: network.TopologyModeHubAndSpoke,
+		Hubs: []string{"alpha", "bravo"},
+	}
+
+	custom := network.Topology{
+		Mode: network.TopologyModeCustom,
+		PeerGroups: []network.PeerGroup{
+			{Name: "frontend", Nodes: []string{"alpha", "bravo"}},
+			{Name: "backend", Nodes: []string{"bravo", "charlie", "missing"}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		topology network.Topology
+		node     string
+		nodes    []string
+		expected []network.Peer
+	}{
+		{
+			name:     "full mesh peers with everyone else",
+			topology: network.DefaultTopology(),
+			node:     "bravo",
+			nodes:    nodes,
+			expected: []network.Peer{{Node: "alpha"}, {Node: "charlie"}, {Node: "delta"}},
+		},
+		{
+			name:     "full mesh with a single node has no peers",
+			topology: network.DefaultTopology(),
+			node:     "alpha",
+			nodes:    []string{"alpha"},
+			expected: []network.Peer{},
+		},
+		{
+			name:     "full mesh ignores duplicate nodes",
+			topology: network.DefaultTopology(),
+			node:     "alpha",
+			nodes:    []string{"alpha", "bravo", "bravo"},
+			expected: []network.Peer{{Node: "bravo"}},
+		},
+		{
+			name:     "nodes outside of the network have no peers",
+			topology: network.DefaultTopology(),
+			node:     "echo",
+			nodes:    nodes,
+			expected: nil,
+		},
+		{
+			name:     "spokes peer with the hubs and route through them",
+			topology: hubAndSpoke,
+			node:     "charlie",
+			nodes:    nodes,
+			expected: []network.Peer{{Node: "alpha", Routes: true}, {Node: "bravo", Routes: true}},
+		},
+		{
+			name:     "hubs peer with everyone else",
+			topology: hubAndSpoke,
+			node:     "alpha",
+			nodes:    nodes,
+			expected: []network.Peer{{Node: "bravo"}, {Node: "charlie"}, {Node: "delta"}},
+		},
+		{
+			name:     "hubs that aren't in the network are left out",
+			topology: hubAndSpoke,
+			node:     "charlie",
+			nodes:    []string{"bravo", "charlie", "delta"},
+			expected: []network.Peer{{Node: "bravo", Routes: true}},
+		},
+		{
+			name:     "spokes without any hubs have no peers",
+			topology: hubAndSpoke,
+			node:     "charlie",
+			nodes:    []string{"charlie", "delta"},
+			expected: []network.Peer{},
+		},
+		{
+			name:     "custom peers with the members of every group",
+			topology: custom,
+			node:     "bravo",
+			nodes:    nodes,
+			expected: []network.Peer{{Node: "alpha"}, {Node: "charlie"}},
+		},
+		{
+			name:     "custom only peers within the node's groups",
+			topology: custom,
+			node:     "alpha",
+			nodes:    nodes,
+			expected: []network.Peer{{Node: "bravo"}},
+		},
+		{
+			name:     "custom leaves nodes without a group alone",
+			topology: custom,
+			node:     "delta",
+			nodes:    nodes,
+			expected: []network.Peer{},
+		},
+		{
+			name:     "custom leaves out group members that aren't in the network",
+			topology: custom,
+			node:     "charlie",
+			nodes:    nodes,
+			expected: []network.Peer{{Node: "bravo"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, testCase.topology.Peers(testCase.node, testCase.nodes))
+		})
+	}
+}
+
+func TestTopologyPeersShouldBeSymmetric(t *testing.T) {
+	nodes := []string{"alpha", "bravo", "charlie", "delta", "echo"}
+	topologies := []network.Topology{
+		network.DefaultTopology(),
+		{Mode: network.TopologyModeHubAndSpoke, Hubs: []string{"alpha", "charlie"}},
+		{
+			Mode: network.TopologyModeCustom,
+			PeerGroups: []network.PeerGroup{
+				{Name: "first", Nodes: []string{"alpha", "bravo", "charlie"}},
+				{Name: "second", Nodes: []string{"charlie", "delta"}},
+			},
+		},
+	}
+
+	for _, topology := range topologies {
+		for _, node := range nodes {
+			for _, peer := range topology.Peers(node, nodes) {
+				peerNames := []string{}
+				for _, reversePeer := range topology.Peers(peer.Node, nodes) {
+					peerNames = append(peerNames, reversePeer.Node)
+				}
+
+				require.Contains(
+					t,
+					peerNames,
+					node,
+					"should have '%s' peer back with '%s' in a %s topology",
+					peer.Node,
+					node,
+					topology.Mode,
+				)
+			}
+		}
+	}
+}
+
+func TestTopologyValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		topology network.Topology
+		valid    bool
+	}{
+		{name: "full mesh", topology: network.DefaultTopology(), valid: true},
+		{
+			name:     "full mesh with hubs",
+			topology: network.Topology{Mode: network.TopologyModeFullMesh, Hubs: []string{"alpha"}},
+		},
+		{
+			name:     "hub and spoke",
+			topology: network.Topology{Mode: network.TopologyModeHubAndSpoke, Hubs: []string{"alpha"}},
+			valid:    true,
+		},
+		{
+			name:     "hub and spoke without hubs",
+			topology: network.Topology{Mode: network.TopologyModeHubAndSpoke},
+		},
+		{
+			name: "custom",
+			topology: network.Topology{
+				Mode:       network.TopologyModeCustom,
+				PeerGroups: []network.PeerGroup{{Name: "web", Nodes: []string{"alpha", "bravo"}}},
+			},
+			valid: true,
+		},
+		{
+			name: "custom with duplicate groups",
+			topology: network.Topology{
+				Mode: network.TopologyModeCustom,
+				PeerGroups: []network.PeerGroup{
+					{Name: "web", Nodes: []string{"alpha"}},
+					{Name: "web", Nodes: []string{"bravo"}},
+				},
+			},
+		},
+		{
+			name: "custom with an invalid group name",
+			topology: network.Topology{
+				Mode:       network.TopologyModeCustom,
+				PeerGroups: []network.PeerGroup{{Name: "not valid", Nodes: []string{"alpha"}}},
+			},
+		},
+		{name: "unknown mode", topology: network.Topology{Mode: "ring"}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			err := testCase.topology.Validate()
+			if testCase.valid {
+				require.Nil(t, err, "should accept the topology")
+			} else {
+				require.NotNil(t, err, "should reject the topology")
+			}
+		})
+	}
+}
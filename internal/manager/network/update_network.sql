@@ -0,0 +1,27 @@
+UPDATE Networks
+SET
+    Name = COALESCE($2, Name),
+    Labels = COALESCE($3::JSONB, Labels),
+    Topology = COALESCE($6::JSONB, Topology),
+    PresharedKeys = COALESCE($7, PresharedKeys),
+    Version = Version + 1,
+    ModifiedOn = $4
+WHERE
+    OrganizationID = $8
+    AND Name = $1
+    AND ($5::BIGINT[] IS NULL OR Version = ANY($5))
+RETURNING
+    ID,
+    Name,
+    IPv4CIDR,
+    IPv6CIDR,
+    AllowOverlap,
+    Labels,
+    Topology,
+    PresharedKeys,
+    Version,
+    CreatedOn,
+    ModifiedOn,
+    OrganizationID,
+    (SELECT Organizations.Name FROM Organizations WHERE Organizations.ID = Networks.OrganizationID)
+;
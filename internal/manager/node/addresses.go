@@ -0,0 +1,30 @@
+package node
+
+import (
+	"inet.af/netaddr"
+)
+
+// NextFreeAddress gives the lowest host address in the range that
+// isn't already used. The network address is never handed out, and
+// neither is the broadcast address of an IPv4 range. False is returned
+// when the range is full.
+func NextFreeAddress(prefix netaddr.IPPrefix, used []netaddr.IP) (netaddr.IP, bool) {
+	usedAddresses := make(map[netaddr.IP]struct{}, len(used))
+	for _, address := range used {
+		usedAddresses[address] = struct{}{}
+	}
+
+	addressRange := prefix.Masked().Range()
+	last := addressRange.To()
+	if last.Is4() {
+		last = last.Prior()
+	}
+
+	for address := addressRange.From().Next(); !address.IsZero() && address.Compare(last) <= 0; address = address.Next() {
+		if _, ok := usedAddresses[address]; !ok {
+			return address, true
+		}
+	}
+
+	return netaddr.IP{}, false
+}
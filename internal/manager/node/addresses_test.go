@@ -0,0 +1,69 @@
+package node_test
+
+import (
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestNextFreeAddress(t *testing.T) {
+	testCases := []struct {
+		name     string
+		prefix   string
+		used     []string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "skips the network address",
+			prefix:   "10.0.0.0/24",
+			expected: "10.0.0.1",
+			ok:       true,
+		},
+		{
+			name:     "fills gaps first",
+			prefix:   "10.0.0.0/24",
+			used:     []string{"10.0.0.1", "10.0.0.3"},
+			expected: "10.0.0.2",
+			ok:       true,
+		},
+		{
+			name:     "masks the range",
+			prefix:   "10.0.0.7/30",
+			used:     []string{"10.0.0.5"},
+			expected: "10.0.0.6",
+			ok:       true,
+		},
+		{
+			name:   "never gives out the broadcast address",
+			prefix: "10.0.0.0/30",
+			used:   []string{"10.0.0.1", "10.0.0.2"},
+			ok:     false,
+		},
+		{
+			name:     "uses the last address of an IPv6 range",
+			prefix:   "fd00::/126",
+			used:     []string{"fd00::1", "fd00::2"},
+			expected: "fd00::3",
+			ok:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			used := make([]netaddr.IP, 0, len(testCase.used))
+			for _, address := range testCase.used {
+				used = append(used, netaddr.MustParseIP(address))
+			}
+
+			address, ok := node.NextFreeAddress(netaddr.MustParseIPPrefix(testCase.prefix), used)
+			require.Equal(t, testCase.ok, ok)
+
+			if testCase.ok {
+				require.Equal(t, netaddr.MustParseIP(testCase.expected), address)
+			}
+		})
+	}
+}
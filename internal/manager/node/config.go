@@ -0,0 +1,125 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/durandj/ley/internal/manager/network"
+	"inet.af/netaddr"
+)
+
+// Config is everything a node needs to set up its WireGuard interface.
+type Config struct {
+	Node  *Node
+	Peers []PeerConfig
+
+	// DNS is where the node resolves the names of its peers. It is nil
+	// when the manager doesn't serve DNS to nodes.
+	DNS *DNSConfig
+
+	// KeyRotationDue tells the node to generate a new key pair and
+	// submit the public key.
+	KeyRotationDue bool
+
+	// LastEventID is the newest event in the node's network when the
+	// config was built. Events after it mean the config is stale.
+	LastEventID int64
+
+	// NetworkVersion is the version of the node's network when the
+	// config was built. Changes to the network's topology or preshared
+	// key setting bump it.
+	NetworkVersion int64
+}
+
+// Revision identifies the state the config was built from. Configs
+// with the same revision are the same apart from KeyRotationDue.
+func (config *Config) Revision() string {
+	return fmt.Sprintf("%d-%d", config.LastEventID, config.NetworkVersion)
+}
+
+// DNSConfig tells a node where to resolve the names of its peers.
+type DNSConfig struct {
+	// Resolver is the address of the nameserver.
+	Resolver netaddr.IP
+
+	// Domain is the domain that the names of the peers are under, for
+	// the node to search.
+	Domain string
+}
+
+// PeerConfig is a single peer in a node's WireGuard configuration.
+type PeerConfig struct {
+	Name       string
+	PublicKey  string
+	Endpoint   *string
+	AllowedIPs []netaddr.IPPrefix
+
+	// PresharedKey is only set when the network uses preshared keys.
+	PresharedKey string
+}
+
+// buildPeerConfigs works out the peers of a node from the network's
+// topology. Peers that route for the network are given the network's
+// ranges on top of their own addresses. Only the first of them gets
+// the ranges since WireGuard can't send the same range to two peers.
+func buildPeerConfigs(
+	node *Node,
+	managedNetwork *network.Network,
+	nodes []Node,
+	presharedKeys map[peerPair]string,
+) []PeerConfig {
+	nodesByName := make(map[string]*Node, len(nodes))
+	names := make([]string, 0, len(nodes))
+	for index := range nodes {
+		nodesByName[nodes[index].Name()] = &nodes[index]
+		names = append(names, nodes[index].Name())
+	}
+
+	peers := managedNetwork.Topology().Peers(node.Name(), names)
+	peerConfigs := make([]PeerConfig, 0, len(peers))
+	routesAssigned := false
+	for _, peer := range peers {
+		peerNode := nodesByName[peer.Node]
+
+		peerConfig := PeerConfig{
+			Name:       peerNode.Name(),
+			PublicKey:  peerNode.PublicKey(),
+			Endpoint:   peerNode.Endpoint(),
+			AllowedIPs: hostPrefixes(peerNode),
+		}
+
+		if peer.Routes && !routesAssigned {
+			routesAssigned = true
+			peerConfig.AllowedIPs = networkPrefixes(managedNetwork)
+		}
+
+		if managedNetwork.PresharedKeys() {
+			peerConfig.PresharedKey = presharedKeys[newPeerPair(node.ID(), peerNode.ID())]
+		}
+
+		peerConfigs = append(peerConfigs, peerConfig)
+	}
+
+	return peerConfigs
+}
+
+func hostPrefixes(node *Node) []netaddr.IPPrefix {
+	var prefixes []netaddr.IPPrefix
+	for _, address := range []*netaddr.IP{node.IPv4Address(), node.IPv6Address()} {
+		if address != nil {
+			prefixes = append(prefixes, netaddr.IPPrefixFrom(*address, address.BitLen()))
+		}
+	}
+
+	return prefixes
+}
+
+func networkPrefixes(managedNetwork *network.Network) []netaddr.IPPrefix {
+	var prefixes []netaddr.IPPrefix
+	for _, prefix := range []*netaddr.IPPrefix{managedNetwork.IPv4CIDR(), managedNetwork.IPv6CIDR()} {
+		if prefix != nil {
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+
+	return prefixes
+}
@@ -0,0 +1,918 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/conditional"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"inet.af/netaddr"
+)
+
+const (
+	// DefaultEventLimit is how many events are returned when no limit
+	// is given.
+	DefaultEventLimit = 100
+
+	// secretCacheControl keeps responses carrying a node's secret from
+	// being stored anywhere, including by the idempotency middleware.
+	secretCacheControl = "no-store"
+)
+
+// Controller handles all the HTTP requests for node related API's.
+type Controller struct {
+	NodeService *Service
+
+	// Hub tells watchers when the config of their node may have changed.
+	Hub *notify.Hub
+}
+
+// RegisterRoutes registers HTTP request handlers for the node API's
+// used to manage nodes. The nodes are those of the organization named
+// by the "org" URL parameter, or of the default organization for the
+// routes without one.
+func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Get("/", controller.ListNodes)
+	router.Post("/", controller.RegisterNode)
+	router.Get("/{id}", controller.GetNode)
+	router.Delete("/{id}", controller.DeleteNode)
+	router.Post("/{id}/key/rotation", controller.RequestKeyRotation)
+	router.Post("/{id}/secret", controller.ResetNodeSecret)
+	router.Get("/{id}/events", controller.ListNodeEvents)
+}
+
+type nodeOrganizationContextKey struct{}
+
+// organizationName gives the organization that a request is for. It is
+// named by the "org" URL parameter for the routes used to manage nodes
+// and is the one of the authenticated node for the agent routes. The
+// node service uses the default organization when it is empty.
+func organizationName(request *http.Request) string {
+	if name := chi.URLParam(request, "org"); name != "" {
+		return name
+	}
+
+	name, _ := request.Context().Value(nodeOrganizationContextKey{}).(string)
+
+	return name
+}
+
+// RegisterAgentRoutes registers HTTP request handlers for the node
+// API's used by the nodes themselves. Each of them needs the secret of
+// the node as a bearer token.
+func (controller *Controller) RegisterAgentRoutes(router chi.Router) {
+	router.Use(controller.authenticateNode)
+
+	router.Put("/{id}/key", controller.RotateNodeKey)
+	router.Get("/{id}/config", controller.GetNodeConfig)
+	router.Get("/{id}/watch", controller.WatchNode)
+	router.Post("/{id}/heartbeat", controller.RecordHeartbeat)
+	router.Get("/{id}/peer-stats", controller.ListPeerStats)
+}
+
+// authenticateNode rejects requests that don't carry the secret of the
+// node they are for. The secret also tells which organization the node
+// belongs to.
+func (controller *Controller) authenticateNode(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		authorization := request.Header.Get("Authorization")
+		secret := strings.TrimPrefix(authorization, "Bearer ")
+		if secret == authorization {
+			secret = ""
+		}
+
+		ctx := request.Context()
+		nodeOrganization, err := controller.NodeService.AuthenticateNode(ctx, chi.URLParam(request, "id"), secret)
+		if err != nil {
+			handleError(response, request, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, nodeOrganizationContextKey{}, nodeOrganization)
+		next.ServeHTTP(response, request.WithContext(ctx))
+	})
+}
+
+// RegisterNodeRequest is the expected request body for adding a node
+// to a network.
+type RegisterNodeRequest struct {
+	Network   string  `json:"network"`
+	Name      string  `json:"name"`
+	PublicKey string  `json:"publicKey"`
+	Endpoint  *string `json:"endpoint,omitempty"`
+
+	Ephemeral bool             `json:"ephemeral,omitempty"`
+	ExpiresOn *renderable.Time `json:"expiresOn,omitempty"`
+}
+
+// Bind is used to determine how to map from a request body to a node
+// registration request.
+func (registerNodeRequest *RegisterNodeRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*RegisterNodeRequest)(nil)
+
+// RegisterNodeResponse is the response body for a successful node
+// registration.
+type RegisterNodeResponse struct {
+	RenderableNode
+
+	// Secret is what the node authenticates with. It is only ever
+	// returned here.
+	Secret string `json:"secret"`
+}
+
+var _ render.Renderer = (*RegisterNodeResponse)(nil)
+
+// RegisterNode handles requests to add a node to a network.
+func (controller *Controller) RegisterNode(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var registerNodeRequest RegisterNodeRequest
+	if err := render.Bind(request, &registerNodeRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	node, err := controller.NodeService.RegisterNode(ctx, RegisterNodeOpts{
+		Organization: organizationName(request),
+		Network:      registerNodeRequest.Network,
+		Name:         registerNodeRequest.Name,
+		PublicKey:    registerNodeRequest.PublicKey,
+		Endpoint:     registerNodeRequest.Endpoint,
+		Ephemeral:    registerNodeRequest.Ephemeral,
+		ExpiresOn:    (*time.Time)(registerNodeRequest.ExpiresOn),
+	})
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	registerNodeResponse := RegisterNodeResponse{
+		RenderableNode: NewRenderableNode(node),
+		Secret:         node.Secret(),
+	}
+
+	conditional.SetETag(response, node.Version())
+	response.Header().Set("Cache-Control", secretCacheControl)
+	response.WriteHeader(http.StatusCreated)
+	_ = render.Render(response, request, &registerNodeResponse)
+}
+
+// GetNodeResponse is the response body for requesting a single node.
+type GetNodeResponse struct {
+	RenderableNode
+}
+
+var _ render.Renderer = (*GetNodeResponse)(nil)
+
+// GetNode handles requests to fetch a node by ID. The node isn't sent
+// again if the client already has the current version.
+func (controller *Controller) GetNode(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	node, err := controller.NodeService.GetNode(ctx, organizationName(request), chi.URLParam(request, "id"))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	if conditional.NotModified(request, node.Version()) {
+		conditional.WriteNotModified(response, node.Version())
+		return
+	}
+
+	getNodeResponse := GetNodeResponse{
+		RenderableNode: NewRenderableNode(node),
+	}
+
+	conditional.SetETag(response, node.Version())
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &getNodeResponse)
+}
+
+// DeleteNode handles requests to remove a node from its network. The
+// If-Match header can be used to make sure nobody else changed the
+// node first.
+func (controller *Controller) DeleteNode(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	err := controller.NodeService.DeleteNode(
+		ctx,
+		organizationName(request),
+		chi.URLParam(request, "id"),
+		conditional.ParseIfMatch(request),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// RotateNodeKeyRequest is the expected request body for submitting a
+// node's new public key.
+type RotateNodeKeyRequest struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// Bind is used to determine how to map from a request body to a key
+// rotation request.
+func (rotateNodeKeyRequest *RotateNodeKeyRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*RotateNodeKeyRequest)(nil)
+
+// RotateNodeKeyResponse is the response body for a successful key
+// rotation.
+type RotateNodeKeyResponse struct {
+	RenderableNode
+}
+
+var _ render.Renderer = (*RotateNodeKeyResponse)(nil)
+
+// RotateNodeKey handles requests from a node to switch to a new public
+// key. The If-Match header can be used to make sure nobody else
+// changed the node first.
+func (controller *Controller) RotateNodeKey(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var rotateNodeKeyRequest RotateNodeKeyRequest
+	if err := render.Bind(request, &rotateNodeKeyRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	node, err := controller.NodeService.RotateNodeKey(
+		ctx,
+		chi.URLParam(request, "id"),
+		RotateNodeKeyOpts{
+			PublicKey:        rotateNodeKeyRequest.PublicKey,
+			ExpectedVersions: conditional.ParseIfMatch(request),
+		},
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	rotateNodeKeyResponse := RotateNodeKeyResponse{
+		RenderableNode: NewRenderableNode(node),
+	}
+
+	conditional.SetETag(response, node.Version())
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &rotateNodeKeyResponse)
+}
+
+// RequestKeyRotationResponse is the response body for a successful
+// request to rotate a node's key.
+type RequestKeyRotationResponse struct {
+	RenderableNode
+}
+
+var _ render.Renderer = (*RequestKeyRotationResponse)(nil)
+
+// RequestKeyRotation handles requests to have a node rotate its key
+// ahead of schedule. The If-Match header can be used to make sure
+// nobody else changed the node first.
+func (controller *Controller) RequestKeyRotation(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	node, err := controller.NodeService.RequestKeyRotation(
+		ctx,
+		organizationName(request),
+		chi.URLParam(request, "id"),
+		conditional.ParseIfMatch(request),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	requestKeyRotationResponse := RequestKeyRotationResponse{
+		RenderableNode: NewRenderableNode(node),
+	}
+
+	conditional.SetETag(response, node.Version())
+	response.WriteHeader(http.StatusAccepted)
+	_ = render.Render(response, request, &requestKeyRotationResponse)
+}
+
+// ResetNodeSecretResponse is the response body for issuing a new
+// secret to a node.
+type ResetNodeSecretResponse struct {
+	RenderableNode
+
+	// Secret is what the node authenticates with from now on. It is
+	// only ever returned here.
+	Secret string `json:"secret"`
+}
+
+var _ render.Renderer = (*ResetNodeSecretResponse)(nil)
+
+// ResetNodeSecret handles requests to issue a new secret to a node. The
+// node's old secret stops working straight away.
+func (controller *Controller) ResetNodeSecret(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	node, err := controller.NodeService.ResetNodeSecret(ctx, organizationName(request), chi.URLParam(request, "id"))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	resetNodeSecretResponse := ResetNodeSecretResponse{
+		RenderableNode: NewRenderableNode(node),
+		Secret:         node.Secret(),
+	}
+
+	response.Header().Set("Cache-Control", secretCacheControl)
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &resetNodeSecretResponse)
+}
+
+// ListNodesResponse is the response for requesting a page of nodes.
+type ListNodesResponse struct {
+	Nodes      []RenderableNode `json:"nodes"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
+
+// NewListNodesResponse creates a node list response.
+func NewListNodesResponse(page listing.Page[Node]) ListNodesResponse {
+	renderableNodes := make([]RenderableNode, len(page.Items))
+	for index := range page.Items {
+		renderableNodes[index] = NewRenderableNode(&page.Items[index])
+	}
+
+	return ListNodesResponse{
+		Nodes:      renderableNodes,
+		NextCursor: page.NextCursor,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listNodesResponse *ListNodesResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+// ListNodes handles requests to list nodes, optionally only those in a
+// network, using a public key or with a status.
+func (controller *Controller) ListNodes(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	query := request.URL.Query()
+
+	params, err := listing.ParseParams(query)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	filter := ListNodesFilter{
+		Organization: organizationName(request),
+		Network:      query.Get("network"),
+		PublicKey:    query.Get("publicKey"),
+	}
+
+	if rawStatus := query.Get("status"); rawStatus != "" {
+		status, err := ParseStatus(rawStatus)
+		if err != nil {
+			handleError(response, request, errortypes.NewWrappedValidationError(err, "%v", err))
+			return
+		}
+
+		filter.Status = status
+	}
+
+	page, err := controller.NodeService.ListNodes(ctx, params, filter)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listNodesResponse := NewListNodesResponse(page)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listNodesResponse)
+}
+
+// GetNodeConfigResponse is the response body for requesting a node's
+// WireGuard configuration.
+type GetNodeConfigResponse struct {
+	Node           RenderableNode         `json:"node"`
+	Peers          []RenderablePeerConfig `json:"peers"`
+	DNS            *RenderableDNSConfig   `json:"dns,omitempty"`
+	KeyRotationDue bool                   `json:"keyRotationDue"`
+	LastEventID    int64                  `json:"lastEventID"`
+}
+
+// NewGetNodeConfigResponse creates the response for a node's config.
+func NewGetNodeConfigResponse(config *Config) GetNodeConfigResponse {
+	peers := make([]RenderablePeerConfig, len(config.Peers))
+	for index, peer := range config.Peers {
+		peers[index] = RenderablePeerConfig(peer)
+	}
+
+	var dnsConfig *RenderableDNSConfig
+	if config.DNS != nil {
+		dnsConfig = &RenderableDNSConfig{
+			Resolver: config.DNS.Resolver,
+			Domain:   config.DNS.Domain,
+		}
+	}
+
+	return GetNodeConfigResponse{
+		Node:           NewRenderableNode(config.Node),
+		Peers:          peers,
+		DNS:            dnsConfig,
+		KeyRotationDue: config.KeyRotationDue,
+		LastEventID:    config.LastEventID,
+	}
+}
+
+// Render provides a hook to customize the render process.
+func (getNodeConfigResponse *GetNodeConfigResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*GetNodeConfigResponse)(nil)
+
+// RenderableDNSConfig tells a node where to resolve the names of its
+// peers.
+type RenderableDNSConfig struct {
+	Resolver netaddr.IP `json:"resolver"`
+	Domain   string     `json:"domain"`
+}
+
+// RenderablePeerConfig defines what should be returned to a node for
+// each of its peers.
+type RenderablePeerConfig struct {
+	Name         string             `json:"name"`
+	PublicKey    string             `json:"publicKey"`
+	Endpoint     *string            `json:"endpoint,omitempty"`
+	AllowedIPs   []netaddr.IPPrefix `json:"allowedIPs"`
+	PresharedKey string             `json:"presharedKey,omitempty"`
+}
+
+// GetNodeConfig handles requests from a node for its WireGuard
+// configuration.
+func (controller *Controller) GetNodeConfig(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	config, err := controller.NodeService.GetNodeConfig(ctx, organizationName(request), chi.URLParam(request, "id"))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	getNodeConfigResponse := NewGetNodeConfigResponse(config)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &getNodeConfigResponse)
+}
+
+// ListNodeEventsResponse is the response for requesting the events in
+// a node's network.
+type ListNodeEventsResponse struct {
+	Events []RenderableEvent `json:"events"`
+}
+
+// NewListNodeEventsResponse creates a node event list response.
+func NewListNodeEventsResponse(events []Event) ListNodeEventsResponse {
+	renderableEvents := make([]RenderableEvent, len(events))
+	for index := range events {
+		renderableEvents[index] = NewRenderableEvent(&events[index])
+	}
+
+	return ListNodeEventsResponse{
+		Events: renderableEvents,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listNodeEventsResponse *ListNodeEventsResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+// ListNodeEvents handles requests from a node for the changes in its
+// network since the last event it saw.
+func (controller *Controller) ListNodeEvents(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	query := request.URL.Query()
+
+	var afterEventID int64
+	if rawAfter := query.Get("after"); rawAfter != "" {
+		parsedAfter, err := strconv.ParseInt(rawAfter, 10, 64)
+		if err != nil {
+			handleError(response, request, errortypes.NewWrappedValidationError(err, "Invalid event ID '%s'", rawAfter))
+			return
+		}
+
+		afterEventID = parsedAfter
+	}
+
+	limit := DefaultEventLimit
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			handleError(response, request, errortypes.NewWrappedValidationError(err, "Invalid limit '%s'", rawLimit))
+			return
+		}
+
+		limit = parsedLimit
+	}
+
+	events, err := controller.NodeService.ListNodeEvents(
+		ctx,
+		organizationName(request),
+		chi.URLParam(request, "id"),
+		afterEventID,
+		limit,
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listNodeEventsResponse := NewListNodeEventsResponse(events)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listNodeEventsResponse)
+}
+
+// HeartbeatRequest is the expected request body for a node reporting
+// that it is alive.
+type HeartbeatRequest struct {
+	Peers []RenderablePeerHeartbeat `json:"peers"`
+}
+
+// Bind is used to determine how to map from a request body to a
+// heartbeat.
+func (heartbeatRequest *HeartbeatRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*HeartbeatRequest)(nil)
+
+// RenderablePeerHeartbeat is what a node reports about one of its
+// peers in a heartbeat.
+type RenderablePeerHeartbeat struct {
+	PublicKey       string           `json:"publicKey"`
+	LatestHandshake *renderable.Time `json:"latestHandshake,omitempty"`
+	ReceiveBytes    int64            `json:"receiveBytes"`
+	TransmitBytes   int64            `json:"transmitBytes"`
+	Endpoint        *string          `json:"endpoint,omitempty"`
+}
+
+// HeartbeatResponse is the response body for a recorded heartbeat.
+type HeartbeatResponse struct {
+	RenderableNode
+}
+
+var _ render.Renderer = (*HeartbeatResponse)(nil)
+
+// RecordHeartbeat handles requests from a node reporting that it is
+// alive along with its WireGuard stats.
+func (controller *Controller) RecordHeartbeat(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var heartbeatRequest HeartbeatRequest
+	if err := render.Bind(request, &heartbeatRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	peers := make([]PeerHeartbeat, len(heartbeatRequest.Peers))
+	for index, peer := range heartbeatRequest.Peers {
+		peers[index] = PeerHeartbeat{
+			PublicKey:         peer.PublicKey,
+			LatestHandshakeOn: (*time.Time)(peer.LatestHandshake),
+			ReceiveBytes:      peer.ReceiveBytes,
+			TransmitBytes:     peer.TransmitBytes,
+			Endpoint:          peer.Endpoint,
+		}
+	}
+
+	node, err := controller.NodeService.RecordHeartbeat(
+		ctx,
+		chi.URLParam(request, "id"),
+		HeartbeatOpts{Peers: peers},
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	heartbeatResponse := HeartbeatResponse{
+		RenderableNode: NewRenderableNode(node),
+	}
+
+	conditional.SetETag(response, node.Version())
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &heartbeatResponse)
+}
+
+// ListPeerStatsResponse is the response for requesting the stats a
+// node last reported about its peers.
+type ListPeerStatsResponse struct {
+	Peers []RenderablePeerStats `json:"peers"`
+}
+
+// NewListPeerStatsResponse creates a peer stats list response.
+func NewListPeerStatsResponse(peerStats []PeerStats) ListPeerStatsResponse {
+	renderablePeerStats := make([]RenderablePeerStats, len(peerStats))
+	for index, stats := range peerStats {
+		renderablePeerStats[index] = RenderablePeerStats{
+			Name:            stats.Name,
+			PublicKey:       stats.PublicKey,
+			LatestHandshake: renderableTime(stats.LatestHandshakeOn),
+			ReceiveBytes:    stats.ReceiveBytes,
+			TransmitBytes:   stats.TransmitBytes,
+			Endpoint:        stats.Endpoint,
+			ReportedOn:      renderable.Time(stats.ReportedOn),
+		}
+	}
+
+	return ListPeerStatsResponse{
+		Peers: renderablePeerStats,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listPeerStatsResponse *ListPeerStatsResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+// RenderablePeerStats defines what should be returned to a user for
+// the stats of a single peer.
+type RenderablePeerStats struct {
+	Name            string           `json:"name,omitempty"`
+	PublicKey       string           `json:"publicKey"`
+	LatestHandshake *renderable.Time `json:"latestHandshake,omitempty"`
+	ReceiveBytes    int64            `json:"receiveBytes"`
+	TransmitBytes   int64            `json:"transmitBytes"`
+	Endpoint        *string          `json:"endpoint,omitempty"`
+	ReportedOn      renderable.Time  `json:"reportedOn"`
+}
+
+// ListPeerStats handles requests for the stats a node reported about
+// its peers in its last heartbeat.
+func (controller *Controller) ListPeerStats(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	peerStats, err := controller.NodeService.ListPeerStats(ctx, chi.URLParam(request, "id"))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listPeerStatsResponse := NewListPeerStatsResponse(peerStats)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listPeerStatsResponse)
+}
+
+func handleError(
+	response http.ResponseWriter,
+	request *http.Request,
+	err error,
+) {
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
+	var unauthorizedError errortypes.UnauthorizedError
+	var userError errortypes.UserError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &validationError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: validationError.SafeMessage,
+		})
+
+		return
+
+	case errors.As(err, &notFoundError):
+		response.WriteHeader(http.StatusNotFound)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: notFoundError.SafeMessage,
+		})
+
+		return
+
+	case errors.As(err, &preconditionFailedError):
+		response.WriteHeader(http.StatusPreconditionFailed)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: preconditionFailedError.SafeMessage,
+		})
+
+		return
+
+	case errors.As(err, &unauthorizedError):
+		response.Header().Set("WWW-Authenticate", `Bearer realm="ley"`)
+		response.WriteHeader(http.StatusUnauthorized)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: unauthorizedError.SafeMessage,
+		})
+
+		return
+
+	case errors.As(err, &userError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: userError.SafeMessage,
+		})
+
+		return
+
+	case errors.As(err, &systemError):
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: systemError.SafeMessage,
+		})
+
+		return
+
+	case err != nil:
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: "Internal server error, please try again later",
+		})
+
+		return
+	}
+}
+
+// RenderableNode defines what should be returned to a user for a node.
+type RenderableNode struct {
+	ID                   string           `json:"id"`
+	Network              string           `json:"network"`
+	Name                 string           `json:"name"`
+	PublicKey            string           `json:"publicKey"`
+	PreviousPublicKey    *string          `json:"previousPublicKey,omitempty"`
+	PreviousKeyExpiresOn *renderable.Time `json:"previousKeyExpiresOn,omitempty"`
+	KeyRotatedOn         renderable.Time  `json:"keyRotatedOn"`
+	KeyExpiresOn         *renderable.Time `json:"keyExpiresOn,omitempty"`
+	KeyRotationDue       bool             `json:"keyRotationDue"`
+	Endpoint             *string          `json:"endpoint,omitempty"`
+	Status               Status           `json:"status"`
+	LastSeenOn           *renderable.Time `json:"lastSeenOn,omitempty"`
+	Ephemeral            bool             `json:"ephemeral,omitempty"`
+	ExpiresOn            *renderable.Time `json:"expiresOn,omitempty"`
+	IPv4Address          *netaddr.IP      `json:"ipv4Address,omitempty"`
+	IPv6Address          *netaddr.IP      `json:"ipv6Address,omitempty"`
+	Version              int64            `json:"version"`
+	CreatedOn            renderable.Time  `json:"createdOn"`
+	ModifiedOn           renderable.Time  `json:"modifiedOn"`
+}
+
+// NewRenderableNode creates a new renderable node from a backend node
+// instance.
+func NewRenderableNode(node *Node) RenderableNode {
+	return RenderableNode{
+		ID:                   node.ID(),
+		Network:              node.Network(),
+		Name:                 node.Name(),
+		PublicKey:            node.PublicKey(),
+		PreviousPublicKey:    node.PreviousPublicKey(),
+		PreviousKeyExpiresOn: renderableTime(node.PreviousKeyExpiresOn()),
+		KeyRotatedOn:         renderable.Time(node.KeyRotatedOn()),
+		KeyExpiresOn:         renderableTime(node.KeyExpiresOn()),
+		KeyRotationDue:       node.KeyRotationDue(time.Now()),
+		Endpoint:             node.Endpoint(),
+		Status:               node.Status(),
+		LastSeenOn:           renderableTime(node.LastSeenOn()),
+		Ephemeral:            node.Ephemeral(),
+		ExpiresOn:            renderableTime(node.ExpiresOn()),
+		IPv4Address:          node.IPv4Address(),
+		IPv6Address:          node.IPv6Address(),
+		Version:              node.Version(),
+		CreatedOn:            renderable.Time(node.CreatedOn()),
+		ModifiedOn:           renderable.Time(node.ModifiedOn()),
+	}
+}
+
+// Render provides a hook to customize the render process.
+func (renderableNode *RenderableNode) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*RenderableNode)(nil)
+
+// RenderableEvent defines what should be returned to a user for a node
+// event.
+type RenderableEvent struct {
+	ID        int64           `json:"id"`
+	NodeID    string          `json:"nodeID"`
+	NodeName  string          `json:"nodeName"`
+	Type      EventType       `json:"type"`
+	CreatedOn renderable.Time `json:"createdOn"`
+}
+
+// NewRenderableEvent creates a new renderable event from a backend
+// event instance.
+func NewRenderableEvent(event *Event) RenderableEvent {
+	return RenderableEvent{
+		ID:        event.ID(),
+		NodeID:    event.NodeID(),
+		NodeName:  event.NodeName(),
+		Type:      event.Type(),
+		CreatedOn: renderable.Time(event.CreatedOn()),
+	}
+}
+
+func renderableTime(value *time.Time) *renderable.Time {
+	if value == nil {
+		return nil
+	}
+
+	renderableValue := renderable.Time(*value)
+
+	return &renderableValue
+}
@@ -0,0 +1,16 @@
+INSERT INTO NodeEvents (
+    NetworkID,
+    NodeID,
+    NodeName,
+    Type,
+    CreatedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING ID
+;
@@ -0,0 +1,19 @@
+INSERT INTO NodePeerStats (
+    NodeID,
+    PeerPublicKey,
+    LatestHandshakeOn,
+    ReceiveBytes,
+    TransmitBytes,
+    Endpoint,
+    ReportedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+;
@@ -0,0 +1,14 @@
+INSERT INTO PresharedKeys (
+    FirstNodeID,
+    SecondNodeID,
+    Key,
+    CreatedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (FirstNodeID, SecondNodeID) DO NOTHING
+;
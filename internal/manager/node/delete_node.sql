@@ -0,0 +1,27 @@
+DELETE FROM Nodes
+USING Networks
+WHERE
+    Nodes.ID = $1
+    AND Networks.ID = Nodes.NetworkID
+    AND Networks.OrganizationID = $2
+    AND ($3::BIGINT[] IS NULL OR Nodes.Version = ANY($3))
+RETURNING
+    Nodes.ID,
+    Nodes.NetworkID,
+    Networks.Name,
+    Nodes.Name,
+    Nodes.PublicKey,
+    Nodes.PreviousPublicKey,
+    Nodes.PreviousKeyExpiresOn,
+    Nodes.KeyRotatedOn,
+    Nodes.KeyRotationRequested,
+    Nodes.Endpoint,
+    Nodes.LastSeenOn,
+    Nodes.Ephemeral,
+    Nodes.ExpiresOn,
+    HOST(Nodes.IPv4Address),
+    HOST(Nodes.IPv6Address),
+    Nodes.Version,
+    Nodes.CreatedOn,
+    Nodes.ModifiedOn
+;
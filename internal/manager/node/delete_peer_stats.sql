@@ -0,0 +1,4 @@
+DELETE FROM NodePeerStats
+WHERE
+    NodeID = $1
+;
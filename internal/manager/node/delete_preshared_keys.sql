@@ -0,0 +1,5 @@
+DELETE FROM PresharedKeys
+WHERE
+    FirstNodeID = $1
+    OR SecondNodeID = $1
+;
@@ -0,0 +1,6 @@
+SELECT
+    COALESCE(MAX(ID), 0)
+FROM NodeEvents
+WHERE
+    NetworkID = $1
+;
@@ -0,0 +1,25 @@
+SELECT
+    Nodes.ID,
+    Nodes.NetworkID,
+    Networks.Name,
+    Nodes.Name,
+    Nodes.PublicKey,
+    Nodes.PreviousPublicKey,
+    Nodes.PreviousKeyExpiresOn,
+    Nodes.KeyRotatedOn,
+    Nodes.KeyRotationRequested,
+    Nodes.Endpoint,
+    Nodes.LastSeenOn,
+    Nodes.Ephemeral,
+    Nodes.ExpiresOn,
+    HOST(Nodes.IPv4Address),
+    HOST(Nodes.IPv6Address),
+    Nodes.Version,
+    Nodes.CreatedOn,
+    Nodes.ModifiedOn
+FROM Nodes
+JOIN Networks ON Networks.ID = Nodes.NetworkID
+WHERE
+    Nodes.ID = $1
+LIMIT 1
+;
@@ -0,0 +1,10 @@
+SELECT
+    Nodes.SecretHash,
+    Organizations.Name
+FROM Nodes
+JOIN Networks ON Networks.ID = Nodes.NetworkID
+JOIN Organizations ON Organizations.ID = Networks.OrganizationID
+WHERE
+    Nodes.ID = $1
+LIMIT 1
+;
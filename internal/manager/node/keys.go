@@ -0,0 +1,54 @@
+package node
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+const (
+	// keyLength is the size in bytes of WireGuard public and preshared
+	// keys.
+	keyLength = 32
+)
+
+// ValidateKey checks that a key is a base64 encoded 32 byte WireGuard
+// key.
+func ValidateKey(key string) error {
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("Key must be base64 encoded")
+	}
+
+	if len(rawKey) != keyLength {
+		return fmt.Errorf("Key must be %d bytes long", keyLength)
+	}
+
+	return nil
+}
+
+// GeneratePresharedKey creates a random preshared key for a pair of
+// peers.
+func GeneratePresharedKey(random io.Reader) (string, error) {
+	rawKey := make([]byte, keyLength)
+	if _, err := io.ReadFull(random, rawKey); err != nil {
+		return "", fmt.Errorf("Unable to generate preshared key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(rawKey), nil
+}
+
+// peerPair identifies a pair of nodes regardless of which one is
+// asking. The smaller ID always comes first.
+type peerPair struct {
+	first  string
+	second string
+}
+
+func newPeerPair(left string, right string) peerPair {
+	if right < left {
+		left, right = right, left
+	}
+
+	return peerPair{first: left, second: right}
+}
@@ -0,0 +1,46 @@
+package node_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKeyShouldAcceptWireGuardKeys(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+	require.Nil(t, node.ValidateKey(key))
+}
+
+func TestValidateKeyShouldRejectInvalidKeys(t *testing.T) {
+	testCases := []struct {
+		name string
+		key  string
+	}{
+		{name: "empty", key: ""},
+		{name: "not base64", key: "not a key"},
+		{name: "too short", key: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16))},
+		{name: "too long", key: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 33))},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.NotNil(t, node.ValidateKey(testCase.key))
+		})
+	}
+}
+
+func TestGeneratePresharedKeyShouldCreateAValidKey(t *testing.T) {
+	key, err := node.GeneratePresharedKey(bytes.NewReader(bytes.Repeat([]byte{7}, 32)))
+	require.Nil(t, err, "should be able to generate a key")
+	require.Nil(t, node.ValidateKey(key), "should generate a valid key")
+	require.Equal(t, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)), key)
+}
+
+func TestGeneratePresharedKeyShouldFailWithoutEnoughRandomness(t *testing.T) {
+	_, err := node.GeneratePresharedKey(bytes.NewReader([]byte{1, 2, 3}))
+	require.NotNil(t, err)
+}
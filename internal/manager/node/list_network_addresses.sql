@@ -0,0 +1,7 @@
+SELECT
+    HOST(IPv4Address),
+    HOST(IPv6Address)
+FROM Nodes
+WHERE
+    NetworkID = $1
+;
@@ -0,0 +1,13 @@
+SELECT
+    ID,
+    NodeID,
+    NodeName,
+    Type,
+    CreatedOn
+FROM NodeEvents
+WHERE
+    NetworkID = $1
+    AND ID > $2
+ORDER BY ID
+LIMIT $3
+;
@@ -0,0 +1,17 @@
+SELECT
+    Peers.Name,
+    NodePeerStats.PeerPublicKey,
+    NodePeerStats.LatestHandshakeOn,
+    NodePeerStats.ReceiveBytes,
+    NodePeerStats.TransmitBytes,
+    NodePeerStats.Endpoint,
+    NodePeerStats.ReportedOn
+FROM NodePeerStats
+JOIN Nodes ON Nodes.ID = NodePeerStats.NodeID
+LEFT JOIN Nodes AS Peers ON
+    Peers.NetworkID = Nodes.NetworkID
+    AND Peers.PublicKey = NodePeerStats.PeerPublicKey
+WHERE
+    NodePeerStats.NodeID = $1
+ORDER BY NodePeerStats.PeerPublicKey
+;
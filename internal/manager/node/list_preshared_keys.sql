@@ -0,0 +1,9 @@
+SELECT
+    FirstNodeID,
+    SecondNodeID,
+    Key
+FROM PresharedKeys
+WHERE
+    FirstNodeID = $1
+    OR SecondNodeID = $1
+;
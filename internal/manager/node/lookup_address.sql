@@ -0,0 +1,27 @@
+-- Networks that allow overlap can hand out the same address, the
+-- oldest node keeps the name. Only nodes in the organization of a node
+-- using the source address are given so that reverse lookups don't
+-- reveal the nodes of other organizations.
+SELECT
+    Organizations.Name,
+    Networks.Name,
+    Nodes.Name
+FROM Nodes
+JOIN Networks ON Networks.ID = Nodes.NetworkID
+JOIN Organizations ON Organizations.ID = Networks.OrganizationID
+WHERE
+    (
+        Nodes.IPv4Address = $1::INET
+        OR Nodes.IPv6Address = $1::INET
+    )
+    AND Networks.OrganizationID IN (
+        SELECT SourceNetworks.OrganizationID
+        FROM Nodes AS SourceNodes
+        JOIN Networks AS SourceNetworks ON SourceNetworks.ID = SourceNodes.NetworkID
+        WHERE
+            SourceNodes.IPv4Address = $2::INET
+            OR SourceNodes.IPv6Address = $2::INET
+    )
+ORDER BY Nodes.CreatedOn, Nodes.ID
+LIMIT 1
+;
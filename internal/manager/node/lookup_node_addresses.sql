@@ -0,0 +1,14 @@
+-- Names in DNS questions don't keep their case, so they are compared
+-- the same way the names are kept unique.
+SELECT
+    HOST(Nodes.IPv4Address),
+    HOST(Nodes.IPv6Address)
+FROM Nodes
+JOIN Networks ON Networks.ID = Nodes.NetworkID
+JOIN Organizations ON Organizations.ID = Networks.OrganizationID
+WHERE
+    LOWER(Organizations.Name) = LOWER($1)
+    AND LOWER(Networks.Name) = LOWER($2)
+    AND Nodes.Name = LOWER($3)
+LIMIT 1
+;
@@ -0,0 +1,295 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/webhook"
+	"inet.af/netaddr"
+)
+
+// Node is a machine that has joined a network.
+type Node struct {
+	id                   string
+	networkID            string
+	network              string
+	name                 string
+	publicKey            string
+	previousPublicKey    *string
+	previousKeyExpiresOn *time.Time
+	keyRotatedOn         time.Time
+	keyExpiresOn         *time.Time
+	keyRotationRequested bool
+	endpoint             *string
+	lastSeenOn           *time.Time
+	status               Status
+	ephemeral            bool
+	expiresOn            *time.Time
+	ipv4Address          *netaddr.IP
+	ipv6Address          *netaddr.IP
+	version              int64
+	createdOn            time.Time
+	modifiedOn           time.Time
+
+	// secret is only known right after it was issued.
+	secret string
+}
+
+// ID is the database ID of the node.
+func (node *Node) ID() string {
+	return node.id
+}
+
+// NetworkID is the database ID of the network the node belongs to.
+func (node *Node) NetworkID() string {
+	return node.networkID
+}
+
+// Network is the name of the network the node belongs to.
+func (node *Node) Network() string {
+	return node.network
+}
+
+// Name is the name of the node, which is unique within its network.
+func (node *Node) Name() string {
+	return node.name
+}
+
+// PublicKey is the WireGuard public key that the node currently uses.
+func (node *Node) PublicKey() string {
+	return node.publicKey
+}
+
+// PreviousPublicKey is the key the node used before its last rotation.
+// It is only kept while it is still accepted, otherwise nil is
+// returned.
+func (node *Node) PreviousPublicKey() *string {
+	return node.previousPublicKey
+}
+
+// PreviousKeyExpiresOn is when the previous public key stops being
+// accepted.
+func (node *Node) PreviousKeyExpiresOn() *time.Time {
+	return node.previousKeyExpiresOn
+}
+
+// KeyRotatedOn is when the node started using its current key.
+func (node *Node) KeyRotatedOn() time.Time {
+	return node.keyRotatedOn
+}
+
+// KeyExpiresOn is when the node is due to rotate its current key. It
+// is nil when scheduled rotation is turned off.
+func (node *Node) KeyExpiresOn() *time.Time {
+	return node.keyExpiresOn
+}
+
+// KeyRotationDue tells if the node should submit a new key, either
+// because a rotation was requested or because its key has expired.
+func (node *Node) KeyRotationDue(now time.Time) bool {
+	if node.keyRotationRequested {
+		return true
+	}
+
+	return node.keyExpiresOn != nil && !now.Before(*node.keyExpiresOn)
+}
+
+// Endpoint is the host:port that peers can reach the node on. Nodes
+// without an endpoint can only be reached after they connect out.
+func (node *Node) Endpoint() *string {
+	return node.endpoint
+}
+
+// LastSeenOn is when the node last sent a heartbeat. It is nil when
+// the node has never sent one.
+func (node *Node) LastSeenOn() *time.Time {
+	return node.lastSeenOn
+}
+
+// Status tells if the node has sent a heartbeat recently. Heartbeats
+// don't change the version of the node.
+func (node *Node) Status() Status {
+	return node.status
+}
+
+// Ephemeral nodes are removed once they have been offline for a while
+// since they aren't expected to come back.
+func (node *Node) Ephemeral() bool {
+	return node.ephemeral
+}
+
+// ExpiresOn is when the node is removed from its network. It is nil
+// when the node doesn't expire.
+func (node *Node) ExpiresOn() *time.Time {
+	return node.expiresOn
+}
+
+// IPv4Address is the address the node was given in the network's IPv4
+// range.
+func (node *Node) IPv4Address() *netaddr.IP {
+	return node.ipv4Address
+}
+
+// IPv6Address is the address the node was given in the network's IPv6
+// range.
+func (node *Node) IPv6Address() *netaddr.IP {
+	return node.ipv6Address
+}
+
+// Version is incremented every time the node is changed. It is used to
+// detect conflicting updates.
+func (node *Node) Version() int64 {
+	return node.version
+}
+
+// CreatedOn is the date and time that the node registered on.
+func (node *Node) CreatedOn() time.Time {
+	return node.createdOn
+}
+
+// ModifiedOn is the date and time that the node was last modified on.
+func (node *Node) ModifiedOn() time.Time {
+	return node.modifiedOn
+}
+
+// Secret is what the node authenticates with. It is only set on the
+// node returned when the secret is issued, by registering the node or
+// resetting its secret, and is empty otherwise.
+func (node *Node) Secret() string {
+	return node.secret
+}
+
+// Status tells how recently a node has been heard from.
+type Status string
+
+const (
+	// StatusOnline means the node sent a heartbeat recently.
+	StatusOnline Status = "online"
+
+	// StatusStale means the node has missed some heartbeats but hasn't
+	// been gone long enough to be considered offline.
+	StatusStale Status = "stale"
+
+	// StatusOffline means the node hasn't sent a heartbeat in a long
+	// time, or never has.
+	StatusOffline Status = "offline"
+)
+
+// ParseStatus converts a string into a node status.
+func ParseStatus(rawStatus string) (Status, error) {
+	switch status := Status(rawStatus); status {
+	case StatusOnline, StatusStale, StatusOffline:
+		return status, nil
+
+	default:
+		return "", fmt.Errorf("Unknown node status '%s'", rawStatus)
+	}
+}
+
+// StatusAt works out the status of a node that was last seen at the
+// given time. A nil last seen time means the node was never seen.
+func StatusAt(lastSeenOn *time.Time, now time.Time, staleAfter time.Duration, offlineAfter time.Duration) Status {
+	if lastSeenOn == nil {
+		return StatusOffline
+	}
+
+	silence := now.Sub(*lastSeenOn)
+	switch {
+	case silence < staleAfter:
+		return StatusOnline
+
+	case silence < offlineAfter:
+		return StatusStale
+
+	default:
+		return StatusOffline
+	}
+}
+
+// PeerStats is what a node last reported about one of its WireGuard
+// peers.
+type PeerStats struct {
+	// Name of the peer, which is empty when the key doesn't belong to a
+	// node in the network anymore.
+	Name              string
+	PublicKey         string
+	LatestHandshakeOn *time.Time
+	ReceiveBytes      int64
+	TransmitBytes     int64
+	Endpoint          *string
+	ReportedOn        time.Time
+}
+
+// EventType tells what happened to a node.
+type EventType string
+
+const (
+	// EventTypeRegistered is recorded when a node joins a network.
+	EventTypeRegistered EventType = "registered"
+
+	// EventTypeKeyRotated is recorded when a node starts using a new
+	// key. Peers need to update their configuration to keep talking to
+	// it.
+	EventTypeKeyRotated EventType = "key-rotated"
+
+	// EventTypeKeyRotationRequested is recorded when a node is asked to
+	// rotate its key ahead of schedule.
+	EventTypeKeyRotationRequested EventType = "key-rotation-requested"
+
+	// EventTypeRemoved is recorded when a node leaves a network.
+	EventTypeRemoved EventType = "removed"
+
+	// EventTypeExpired is recorded when a node is removed because it
+	// reached its expiry.
+	EventTypeExpired EventType = "expired"
+
+	// EventTypeReaped is recorded when an ephemeral node is removed
+	// because it has been offline for too long.
+	EventTypeReaped EventType = "reaped"
+)
+
+// webhookEventTypes gives the webhook event sent for each node event.
+var webhookEventTypes = map[EventType]webhook.EventType{
+	EventTypeRegistered:           webhook.EventTypeNodeRegistered,
+	EventTypeKeyRotated:           webhook.EventTypeNodeKeyRotated,
+	EventTypeKeyRotationRequested: webhook.EventTypeNodeKeyRotationRequested,
+	EventTypeRemoved:              webhook.EventTypeNodeRemoved,
+	EventTypeExpired:              webhook.EventTypeNodeExpired,
+	EventTypeReaped:               webhook.EventTypeNodeReaped,
+}
+
+// Event is a change to a node that its peers need to know about.
+// Events are numbered in the order they happened so that a node can
+// ask for everything after the last event it saw.
+type Event struct {
+	id        int64
+	nodeID    string
+	nodeName  string
+	eventType EventType
+	createdOn time.Time
+}
+
+// ID orders the event among the other events of the network.
+func (event *Event) ID() int64 {
+	return event.id
+}
+
+// NodeID is the database ID of the node the event happened to.
+func (event *Event) NodeID() string {
+	return event.nodeID
+}
+
+// NodeName is the name the node had when the event happened.
+func (event *Event) NodeName() string {
+	return event.nodeName
+}
+
+// Type tells what happened.
+func (event *Event) Type() EventType {
+	return event.eventType
+}
+
+// CreatedOn is when the event happened.
+func (event *Event) CreatedOn() time.Time {
+	return event.createdOn
+}
@@ -0,0 +1,50 @@
+package node_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusAtShouldUseTheThresholds(t *testing.T) {
+	now := time.Date(2022, 5, 30, 18, 0, 0, 0, time.UTC)
+	ago := func(duration time.Duration) *time.Time {
+		lastSeenOn := now.Add(-duration)
+
+		return &lastSeenOn
+	}
+
+	testCases := []struct {
+		name       string
+		lastSeenOn *time.Time
+		expected   node.Status
+	}{
+		{name: "never seen", lastSeenOn: nil, expected: node.StatusOffline},
+		{name: "just seen", lastSeenOn: ago(0), expected: node.StatusOnline},
+		{name: "before stale", lastSeenOn: ago(time.Minute), expected: node.StatusOnline},
+		{name: "stale", lastSeenOn: ago(2 * time.Minute), expected: node.StatusStale},
+		{name: "before offline", lastSeenOn: ago(9 * time.Minute), expected: node.StatusStale},
+		{name: "offline", lastSeenOn: ago(10 * time.Minute), expected: node.StatusOffline},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expected,
+				node.StatusAt(testCase.lastSeenOn, now, 2*time.Minute, 10*time.Minute),
+			)
+		})
+	}
+}
+
+func TestParseStatusShouldRejectUnknownStatuses(t *testing.T) {
+	status, err := node.ParseStatus("stale")
+	require.Nil(t, err, "should parse a known status")
+	require.Equal(t, node.StatusStale, status)
+
+	_, err = node.ParseStatus("dead")
+	require.NotNil(t, err, "should reject an unknown status")
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/durandj/ley/internal/manager/nameserver"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/organization"
+	"inet.af/netaddr"
+)
+
+var (
+	//go:embed lookup_node_addresses.sql
+	lookupNodeAddressesSQL string
+
+	//go:embed lookup_address.sql
+	lookupAddressSQL string
+)
+
+// LookupNode gives the addresses that were assigned to a node so that
+// the nameserver can answer for its name.
+func (service *Service) LookupNode(
+	ctx context.Context,
+	organizationName string,
+	networkName string,
+	nodeName string,
+) ([]netaddr.IP, bool, error) {
+	if organizationName == "" {
+		organizationName = organization.DefaultName
+	}
+
+	var rawIPv4Address sql.NullString
+	var rawIPv6Address sql.NullString
+	err := service.db.QueryRowContext(ctx, lookupNodeAddressesSQL, organizationName, networkName, nodeName).
+		Scan(&rawIPv4Address, &rawIPv6Address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("Unable to look up node '%s': %w", nodeName, err)
+	}
+
+	ips := []netaddr.IP{}
+	for _, rawAddress := range []sql.NullString{rawIPv4Address, rawIPv6Address} {
+		if !rawAddress.Valid {
+			continue
+		}
+
+		ip, err := netaddr.ParseIP(rawAddress.String)
+		if err != nil {
+			return nil, false, fmt.Errorf("Unable to parse address of node '%s': %w", nodeName, err)
+		}
+
+		ips = append(ips, ip)
+	}
+
+	return ips, true, nil
+}
+
+// LookupIP gives the node that an address was assigned to so that the
+// nameserver can answer reverse lookups. Only nodes in the organization
+// of a node using the source address are found. The default
+// organization is given as an empty name.
+func (service *Service) LookupIP(
+	ctx context.Context,
+	ip netaddr.IP,
+	source netaddr.IP,
+) (string, string, string, bool, error) {
+	var organizationName string
+	var networkName string
+	var nodeName string
+	err := service.db.QueryRowContext(ctx, lookupAddressSQL, ip.String(), source.String()).
+		Scan(&organizationName, &networkName, &nodeName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", "", false, nil
+	}
+
+	if err != nil {
+		return "", "", "", false, fmt.Errorf("Unable to look up address %s: %w", ip, err)
+	}
+
+	if organizationName == organization.DefaultName {
+		organizationName = ""
+	}
+
+	return organizationName, networkName, nodeName, true, nil
+}
+
+var _ nameserver.Source = (*Service)(nil)
+
+// buildDNSConfig tells a node of the network where to resolve the names
+// of its peers. There is nothing to tell when the manager doesn't serve
+// DNS to nodes.
+func (service *Service) buildDNSConfig(managedNetwork *network.Network) *DNSConfig {
+	resolver, ok := service.dnsConfig.Resolver()
+	if !ok {
+		return nil
+	}
+
+	organizationName := managedNetwork.Organization()
+	if managedNetwork.OrganizationID() == organization.DefaultID {
+		organizationName = ""
+	}
+
+	return &DNSConfig{
+		Resolver: resolver,
+		Domain:   nameserver.NetworkDomain(service.dnsConfig.Zone, organizationName, managedNetwork.Name()),
+	}
+}
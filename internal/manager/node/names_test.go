@@ -0,0 +1,93 @@
+package node_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestNodesShouldBeFoundByName(t *testing.T) {
+	ctx := context.Background()
+	networkService, nodeService, organizationService := newTestServices(t)
+
+	// Names can have uppercase letters and underscores while the names
+	// in DNS questions are in lowercase.
+	owner := fmt.Sprintf("Node_Test-%d", rng.RNG.Int63())
+	_, err := organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: owner})
+	require.Nil(t, err, "should be able to create an organization")
+
+	// A range of its own keeps the address from belonging to the nodes
+	// of other tests.
+	random := rng.RNG.Uint64()
+	prefix := netaddr.IPPrefixFrom(netaddr.IPFrom16([16]byte{
+		0xfd,
+		byte(random >> 32),
+		byte(random >> 24),
+		byte(random >> 16),
+		byte(random >> 8),
+		byte(random),
+	}), 64)
+
+	networkName := fmt.Sprintf("Node_Test-%d", rng.RNG.Int63())
+	_, err = networkService.CreateNetwork(ctx, owner, network.CreateNetworkOpts{
+		Name:     networkName,
+		IPv6CIDR: &prefix,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	registeredNode, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Organization: owner,
+		Network:      networkName,
+		Name:         "web",
+		PublicKey:    newKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+	require.NotNil(t, registeredNode.IPv6Address(), "should assign an IPv6 address")
+
+	ips, found, err := nodeService.LookupNode(ctx, owner, networkName, "web")
+	require.Nil(t, err, "should be able to look up the node")
+	require.True(t, found, "should find the node")
+	require.Equal(t, []netaddr.IP{*registeredNode.IPv6Address()}, ips)
+
+	ips, found, err = nodeService.LookupNode(ctx, strings.ToLower(owner), strings.ToLower(networkName), "WEB")
+	require.Nil(t, err, "should be able to look up the node")
+	require.True(t, found, "should find the node without caring about case")
+	require.Equal(t, []netaddr.IP{*registeredNode.IPv6Address()}, ips)
+
+	_, found, err = nodeService.LookupNode(ctx, "", networkName, "web")
+	require.Nil(t, err, "should be able to look up the node")
+	require.False(t, found, "should not find the node in the default organization")
+
+	organizationName, foundNetwork, foundNode, found, err := nodeService.LookupIP(
+		ctx,
+		*registeredNode.IPv6Address(),
+		*registeredNode.IPv6Address(),
+	)
+	require.Nil(t, err, "should be able to look up the address")
+	require.True(t, found, "should find the node by its address")
+	require.Equal(t, owner, organizationName)
+	require.Equal(t, networkName, foundNetwork)
+	require.Equal(t, "web", foundNode)
+
+	_, _, _, found, err = nodeService.LookupIP(
+		ctx,
+		*registeredNode.IPv6Address(),
+		netaddr.MustParseIP("192.0.2.1"),
+	)
+	require.Nil(t, err, "should be able to look up the address")
+	require.False(t, found, "should not find the node for someone outside of its organization")
+
+	config, err := nodeService.GetNodeConfig(ctx, owner, registeredNode.ID())
+	require.Nil(t, err, "should be able to get the node's config")
+	require.NotNil(t, config.DNS, "should tell the node where to resolve names")
+	require.Equal(t, netaddr.MustParseIP("10.4.0.1"), config.DNS.Resolver)
+	require.Equal(t, strings.ToLower(networkName+"."+owner+".ley.internal."), config.DNS.Domain)
+}
@@ -0,0 +1,204 @@
+package node_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestOrganizationsShouldNotSeeEachOthersNodes(t *testing.T) {
+	ctx := context.Background()
+	networkService, nodeService, organizationService := newTestServices(t)
+
+	owner := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	_, err := organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: owner})
+	require.Nil(t, err, "should be able to create an organization")
+
+	other := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	_, err = organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: other})
+	require.Nil(t, err, "should be able to create an organization")
+
+	networkName := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.4.0.0/29")
+	_, err = networkService.CreateNetwork(ctx, owner, network.CreateNetworkOpts{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	ownedNode, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Organization: owner,
+		Network:      networkName,
+		Name:         "owned",
+		PublicKey:    newKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+
+	var notFoundError errortypes.NotFoundError
+
+	_, err = nodeService.GetNode(ctx, other, ownedNode.ID())
+	require.True(t, errors.As(err, &notFoundError), "should not get another organization's node")
+
+	_, err = nodeService.GetNode(ctx, "", ownedNode.ID())
+	require.True(t, errors.As(err, &notFoundError), "should not get the node from the default organization")
+
+	_, err = nodeService.GetNodeConfig(ctx, other, ownedNode.ID())
+	require.True(t, errors.As(err, &notFoundError), "should not get the config of another organization's node")
+
+	listParams := listing.Params{
+		Limit:     listing.MaxLimit,
+		SortField: listing.SortFieldCreatedOn,
+		SortOrder: listing.SortOrderAscending,
+	}
+
+	page, err := nodeService.ListNodes(ctx, listParams, node.ListNodesFilter{Organization: other})
+	require.Nil(t, err, "should be able to list nodes")
+	require.Empty(t, page.Items, "should not list another organization's nodes")
+
+	page, err = nodeService.ListNodes(ctx, listParams, node.ListNodesFilter{})
+	require.Nil(t, err, "should be able to list nodes")
+	for _, listedNode := range page.Items {
+		require.NotEqual(t, ownedNode.ID(), listedNode.ID(), "should not list the node in the default organization")
+	}
+
+	page, err = nodeService.ListNodes(ctx, listParams, node.ListNodesFilter{Organization: owner})
+	require.Nil(t, err, "should be able to list nodes")
+	require.Len(t, page.Items, 1, "should list the node in its own organization")
+
+	foundNode, err := nodeService.GetNode(ctx, owner, ownedNode.ID())
+	require.Nil(t, err, "should get the node from its own organization")
+	require.Equal(t, ownedNode.ID(), foundNode.ID(), "should get the same node")
+
+	_, err = nodeService.GetNodeConfig(ctx, owner, ownedNode.ID())
+	require.Nil(t, err, "should get the config of the node from its own organization")
+}
+
+func TestOrganizationsShouldNotChangeEachOthersNodes(t *testing.T) {
+	ctx := context.Background()
+	networkService, nodeService, organizationService := newTestServices(t)
+
+	owner := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	_, err := organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: owner})
+	require.Nil(t, err, "should be able to create an organization")
+
+	other := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	_, err = organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: other})
+	require.Nil(t, err, "should be able to create an organization")
+
+	networkName := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.4.0.0/29")
+	_, err = networkService.CreateNetwork(ctx, owner, network.CreateNetworkOpts{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	ownedNode, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Organization: owner,
+		Network:      networkName,
+		Name:         "owned",
+		PublicKey:    newKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+
+	var notFoundError errortypes.NotFoundError
+
+	_, err = nodeService.RequestKeyRotation(ctx, other, ownedNode.ID(), nil)
+	require.True(t, errors.As(err, &notFoundError), "should not ask another organization's node to rotate its key")
+
+	_, err = nodeService.ResetNodeSecret(ctx, other, ownedNode.ID())
+	require.True(t, errors.As(err, &notFoundError), "should not reset the secret of another organization's node")
+
+	_, err = nodeService.ListNodeEvents(ctx, other, ownedNode.ID(), 0, node.DefaultEventLimit)
+	require.True(t, errors.As(err, &notFoundError), "should not list the events of another organization's node")
+
+	err = nodeService.DeleteNode(ctx, other, ownedNode.ID(), nil)
+	require.True(t, errors.As(err, &notFoundError), "should not delete another organization's node")
+
+	err = nodeService.DeleteNode(ctx, "", ownedNode.ID(), nil)
+	require.True(t, errors.As(err, &notFoundError), "should not delete the node from the default organization")
+
+	unchangedNode, err := nodeService.GetNode(ctx, owner, ownedNode.ID())
+	require.Nil(t, err, "should still have the node")
+	require.Equal(t, ownedNode.Version(), unchangedNode.Version(), "should not have changed the node")
+	nodeOrganization, err := nodeService.AuthenticateNode(ctx, ownedNode.ID(), ownedNode.Secret())
+	require.Nil(t, err, "should keep the secret")
+	require.Equal(t, owner, nodeOrganization, "should authenticate the node in its organization")
+
+	events, err := nodeService.ListNodeEvents(ctx, owner, ownedNode.ID(), 0, node.DefaultEventLimit)
+	require.Nil(t, err, "should list the events of the node from its own organization")
+	require.Len(t, events, 1, "should only have the registration event")
+
+	err = nodeService.DeleteNode(ctx, owner, ownedNode.ID(), nil)
+	require.Nil(t, err, "should delete the node from its own organization")
+}
+
+func TestUsersShouldNotSeeTheNodesOfOrganizationsTheyAreNotMembersOf(t *testing.T) {
+	ctx := context.Background()
+	networkService, nodeService, organizationService := newTestServices(t)
+
+	owner := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	_, err := organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: owner})
+	require.Nil(t, err, "should be able to create an organization")
+
+	networkName := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.4.0.0/29")
+	_, err = networkService.CreateNetwork(ctx, owner, network.CreateNetworkOpts{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	ownedNode, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Organization: owner,
+		Network:      networkName,
+		Name:         "owned",
+		PublicKey:    newKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+
+	nonMemberCtx := auth.ContextWithPrincipal(ctx, fmt.Sprintf("user:node-test-%d", rng.RNG.Int63()))
+
+	var notFoundError errortypes.NotFoundError
+
+	_, err = nodeService.GetNode(nonMemberCtx, owner, ownedNode.ID())
+	require.True(t, errors.As(err, &notFoundError), "should not get the node of another organization")
+
+	_, err = nodeService.ListNodes(nonMemberCtx, listing.Params{
+		Limit:     listing.MaxLimit,
+		SortField: listing.SortFieldCreatedOn,
+		SortOrder: listing.SortOrderAscending,
+	}, node.ListNodesFilter{Organization: owner})
+	require.True(t, errors.As(err, &notFoundError), "should not list the nodes of another organization")
+
+	_, err = nodeService.RegisterNode(nonMemberCtx, node.RegisterNodeOpts{
+		Organization: owner,
+		Network:      networkName,
+		Name:         "intruder",
+		PublicKey:    newKey(t),
+	})
+	require.True(t, errors.As(err, &notFoundError), "should not register a node in another organization")
+
+	_, err = nodeService.ResetNodeSecret(nonMemberCtx, owner, ownedNode.ID())
+	require.True(t, errors.As(err, &notFoundError), "should not reset the secret of a node of another organization")
+
+	err = nodeService.DeleteNode(nonMemberCtx, owner, ownedNode.ID(), nil)
+	require.True(t, errors.As(err, &notFoundError), "should not delete the node of another organization")
+
+	_, err = nodeService.GetNode(ctx, owner, ownedNode.ID())
+	require.Nil(t, err, "should still have the node")
+}
@@ -0,0 +1,28 @@
+DELETE FROM Nodes
+USING Networks
+WHERE
+    Networks.ID = Nodes.NetworkID
+    AND (
+        Nodes.ExpiresOn <= $1
+        OR (Nodes.Ephemeral AND COALESCE(Nodes.LastSeenOn, Nodes.CreatedOn) <= $2)
+    )
+RETURNING
+    Nodes.ID,
+    Nodes.NetworkID,
+    Networks.Name,
+    Nodes.Name,
+    Nodes.PublicKey,
+    Nodes.PreviousPublicKey,
+    Nodes.PreviousKeyExpiresOn,
+    Nodes.KeyRotatedOn,
+    Nodes.KeyRotationRequested,
+    Nodes.Endpoint,
+    Nodes.LastSeenOn,
+    Nodes.Ephemeral,
+    Nodes.ExpiresOn,
+    HOST(Nodes.IPv4Address),
+    HOST(Nodes.IPv6Address),
+    Nodes.Version,
+    Nodes.CreatedOn,
+    Nodes.ModifiedOn
+;
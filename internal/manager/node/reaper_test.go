@@ -0,0 +1,231 @@
+package node_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/webhook"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestReapNodesShouldRemoveExpiredAndOfflineEphemeralNodes(t *testing.T) {
+	ctx := context.Background()
+	networkService, nodeService, _ := newTestServices(t)
+
+	networkName := newTestNetwork(ctx, t, networkService)
+	expiresOn := time.Now().Add(time.Hour)
+
+	expiring, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Network:   networkName,
+		Name:      "expiring",
+		PublicKey: newKey(t),
+		ExpiresOn: &expiresOn,
+	})
+	require.Nil(t, err, "should be able to register an expiring node")
+
+	ephemeral, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Network:   networkName,
+		Name:      "ephemeral",
+		PublicKey: newKey(t),
+		Ephemeral: true,
+	})
+	require.Nil(t, err, "should be able to register an ephemeral node")
+
+	permanent, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Network:   networkName,
+		Name:      "permanent",
+		PublicKey: newKey(t),
+	})
+	require.Nil(t, err, "should be able to register a permanent node")
+
+	_, err = nodeService.ReapNodes(ctx, time.Now().Add(30*time.Minute))
+	require.Nil(t, err, "should be able to reap nodes")
+
+	for _, id := range []string{expiring.ID(), ephemeral.ID(), permanent.ID()} {
+		_, err := nodeService.GetNode(ctx, "", id)
+		require.Nil(t, err, "should keep nodes that are not due yet")
+	}
+
+	_, err = nodeService.ReapNodes(ctx, time.Now().Add(2*time.Hour))
+	require.Nil(t, err, "should be able to reap nodes")
+
+	for _, id := range []string{expiring.ID(), ephemeral.ID()} {
+		_, err := nodeService.GetNode(ctx, "", id)
+
+		var notFoundError errortypes.NotFoundError
+		require.True(t, errors.As(err, &notFoundError), "should remove nodes that are due")
+	}
+
+	_, err = nodeService.GetNode(ctx, "", permanent.ID())
+	require.Nil(t, err, "should keep permanent nodes")
+
+	events, err := nodeService.ListNodeEvents(ctx, "", permanent.ID(), 0, 10)
+	require.Nil(t, err, "should be able to list events")
+
+	removals := map[string]node.EventType{}
+	for index := range events {
+		if events[index].Type() != node.EventTypeRegistered {
+			removals[events[index].NodeName()] = events[index].Type()
+		}
+	}
+
+	require.Equal(
+		t,
+		map[string]node.EventType{"expiring": node.EventTypeExpired, "ephemeral": node.EventTypeReaped},
+		removals,
+		"should record the removals",
+	)
+
+	replacement, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Network:   networkName,
+		Name:      "replacement",
+		PublicKey: newKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+	require.Equal(t, expiring.IPv4Address(), replacement.IPv4Address(), "should reuse the released address")
+}
+
+func TestRegisterNodeShouldRejectPastExpiries(t *testing.T) {
+	ctx := context.Background()
+	_, nodeService, _ := newTestServices(t)
+
+	expiresOn := time.Now().Add(-time.Minute)
+	_, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Network:   "anything",
+		Name:      "expired",
+		PublicKey: newKey(t),
+		ExpiresOn: &expiresOn,
+	})
+
+	var validationError errortypes.ValidationError
+	require.True(t, errors.As(err, &validationError), "should reject an expiry in the past")
+}
+
+func TestRemovedNodesShouldBeSentToWebhooksInFull(t *testing.T) {
+	ctx := context.Background()
+	networkService, nodeService, _ := newTestServices(t)
+	db := newTestDB(t)
+
+	networkName := newTestNetwork(ctx, t, networkService)
+	expiresOn := time.Now().Add(time.Hour)
+
+	deleted, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Network:   networkName,
+		Name:      "deleted",
+		PublicKey: newKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+
+	expiring, err := nodeService.RegisterNode(ctx, node.RegisterNodeOpts{
+		Network:   networkName,
+		Name:      "expiring",
+		PublicKey: newKey(t),
+		ExpiresOn: &expiresOn,
+	})
+	require.Nil(t, err, "should be able to register an expiring node")
+
+	err = nodeService.DeleteNode(ctx, "", deleted.ID(), nil)
+	require.Nil(t, err, "should be able to delete the node")
+
+	_, err = nodeService.ReapNodes(ctx, time.Now().Add(2*time.Hour))
+	require.Nil(t, err, "should be able to reap nodes")
+
+	for eventType, removedNode := range map[webhook.EventType]*node.Node{
+		webhook.EventTypeNodeRemoved: deleted,
+		webhook.EventTypeNodeExpired: expiring,
+	} {
+		var rawPayload []byte
+		err := db.QueryRowContext(
+			ctx,
+			"SELECT Payload FROM WebhookEvents WHERE Type = $1 AND Payload->>'id' = $2",
+			eventType,
+			removedNode.ID(),
+		).Scan(&rawPayload)
+		require.Nil(t, err, "should record a webhook event for the removal")
+
+		var payload map[string]any
+		require.Nil(t, json.Unmarshal(rawPayload, &payload), "should be able to read the payload")
+		require.Equal(t, networkName, payload["network"], "should name the network of the node")
+		require.Equal(t, removedNode.PublicKey(), payload["publicKey"], "should have the key of the node")
+		require.Equal(t, removedNode.IPv4Address().String(), payload["ipv4Address"], "should have the address of the node")
+		require.NotEmpty(t, payload["createdOn"], "should have when the node was created")
+	}
+}
+
+func newTestServices(t *testing.T) (*network.Service, *node.Service, *organization.Service) {
+	db := newTestDB(t)
+
+	organizationService := organization.NewService(db)
+	networkService := network.NewService(db, configuration.NetworkConfiguration{}, organizationService)
+	nodeService := node.NewService(db, networkService, organizationService, configuration.NodeConfiguration{
+		StaleAfter:       time.Minute,
+		OfflineAfter:     5 * time.Minute,
+		ReapOfflineAfter: time.Hour,
+	}, configuration.DNSConfiguration{
+		ListenAddress: "10.4.0.1:53",
+		Zone:          "ley.internal.",
+	})
+
+	return networkService, nodeService, organizationService
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	dbConfig := configuration.DBConfiguration{
+		Type: configuration.DBTypePostgres,
+		Postgres: configuration.PostgresConfiguration{
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Role:     "ley",
+			Password: "ley",
+			DBName:   "ley",
+			SSLMode:  "disable",
+		},
+	}
+
+	connectionString, err := dbConfig.ConnectionString()
+	require.Nil(t, err, "should be able to create a connection string")
+
+	db, err := sql.Open(string(dbConfig.Type), connectionString)
+	require.Nil(t, err, "should be able to open the database")
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return db
+}
+
+func newTestNetwork(ctx context.Context, t *testing.T, networkService *network.Service) string {
+	networkName := fmt.Sprintf("node-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.4.0.0/29")
+
+	_, err := networkService.CreateNetwork(ctx, organization.DefaultName, network.CreateNetworkOpts{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	return networkName
+}
+
+func newKey(t *testing.T) string {
+	key, err := node.GeneratePresharedKey(rand.Reader)
+	require.Nil(t, err, "should be able to generate a key")
+
+	return key
+}
@@ -0,0 +1,55 @@
+WITH Registered AS (
+    INSERT INTO Nodes (
+        ID,
+        NetworkID,
+        Name,
+        PublicKey,
+        KeyRotatedOn,
+        Endpoint,
+        IPv4Address,
+        IPv6Address,
+        Ephemeral,
+        ExpiresOn,
+        SecretHash,
+        CreatedOn,
+        ModifiedOn
+    )
+    VALUES (
+        $1,
+        $2,
+        $3,
+        $4,
+        $5,
+        $6,
+        $7,
+        $8,
+        $9,
+        $10,
+        $11,
+        $5,
+        $5
+    )
+    RETURNING *
+)
+SELECT
+    Registered.ID,
+    Registered.NetworkID,
+    Networks.Name,
+    Registered.Name,
+    Registered.PublicKey,
+    Registered.PreviousPublicKey,
+    Registered.PreviousKeyExpiresOn,
+    Registered.KeyRotatedOn,
+    Registered.KeyRotationRequested,
+    Registered.Endpoint,
+    Registered.LastSeenOn,
+    Registered.Ephemeral,
+    Registered.ExpiresOn,
+    HOST(Registered.IPv4Address),
+    HOST(Registered.IPv6Address),
+    Registered.Version,
+    Registered.CreatedOn,
+    Registered.ModifiedOn
+FROM Registered
+JOIN Networks ON Networks.ID = Registered.NetworkID
+;
@@ -0,0 +1,36 @@
+WITH Requested AS (
+    UPDATE Nodes
+    SET
+        KeyRotationRequested = TRUE,
+        Version = Nodes.Version + 1,
+        ModifiedOn = $3
+    FROM Networks
+    WHERE
+        Nodes.ID = $1
+        AND Networks.ID = Nodes.NetworkID
+        AND Networks.OrganizationID = $2
+        AND ($4::BIGINT[] IS NULL OR Nodes.Version = ANY($4))
+    RETURNING Nodes.*
+)
+SELECT
+    Requested.ID,
+    Requested.NetworkID,
+    Networks.Name,
+    Requested.Name,
+    Requested.PublicKey,
+    Requested.PreviousPublicKey,
+    Requested.PreviousKeyExpiresOn,
+    Requested.KeyRotatedOn,
+    Requested.KeyRotationRequested,
+    Requested.Endpoint,
+    Requested.LastSeenOn,
+    Requested.Ephemeral,
+    Requested.ExpiresOn,
+    HOST(Requested.IPv4Address),
+    HOST(Requested.IPv6Address),
+    Requested.Version,
+    Requested.CreatedOn,
+    Requested.ModifiedOn
+FROM Requested
+JOIN Networks ON Networks.ID = Requested.NetworkID
+;
@@ -0,0 +1,38 @@
+WITH Rotated AS (
+    UPDATE Nodes
+    SET
+        PreviousPublicKey = PublicKey,
+        PreviousKeyExpiresOn = $3,
+        PublicKey = $2,
+        KeyRotatedOn = $4,
+        KeyRotationRequested = FALSE,
+        Version = Version + 1,
+        ModifiedOn = $4
+    WHERE
+        ID = $1
+        AND PublicKey <> $2
+        AND ($5::BIGINT[] IS NULL OR Version = ANY($5))
+    RETURNING *
+)
+SELECT
+    Rotated.ID,
+    Rotated.NetworkID,
+    Networks.Name,
+    Rotated.Name,
+    Rotated.PublicKey,
+    Rotated.PreviousPublicKey,
+    Rotated.PreviousKeyExpiresOn,
+    Rotated.KeyRotatedOn,
+    Rotated.KeyRotationRequested,
+    Rotated.Endpoint,
+    Rotated.LastSeenOn,
+    Rotated.Ephemeral,
+    Rotated.ExpiresOn,
+    HOST(Rotated.IPv4Address),
+    HOST(Rotated.IPv6Address),
+    Rotated.Version,
+    Rotated.CreatedOn,
+    Rotated.ModifiedOn
+FROM Rotated
+JOIN Networks ON Networks.ID = Rotated.NetworkID
+;
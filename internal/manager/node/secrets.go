@@ -0,0 +1,143 @@
+package node
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+)
+
+const (
+	// SecretPrefix starts every node secret so that they can be told
+	// apart from other bearer tokens.
+	SecretPrefix = "ley_node_"
+
+	// secretLength is the number of random bytes in a node secret.
+	secretLength = 32
+)
+
+var (
+	//go:embed get_node_secret_hash.sql
+	getNodeSecretHashSQL string
+
+	//go:embed set_node_secret_hash.sql
+	setNodeSecretHashSQL string
+)
+
+// generateSecret creates a secret for a node along with the hash that
+// is kept of it. The secret itself is never stored.
+func generateSecret(random io.Reader) (string, string, error) {
+	rawSecret := make([]byte, secretLength)
+	if _, err := io.ReadFull(random, rawSecret); err != nil {
+		return "", "", fmt.Errorf("Unable to generate node secret: %w", err)
+	}
+
+	secret := SecretPrefix + base64.RawURLEncoding.EncodeToString(rawSecret)
+
+	return secret, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthenticateNode checks that a secret is the one that was issued to
+// the node with the given ID and gives the name of the organization
+// that the node belongs to. Unknown nodes are rejected the same way as
+// wrong secrets so that node IDs can't be probed for.
+func (service *Service) AuthenticateNode(ctx context.Context, id string, secret string) (string, error) {
+	unauthorizedError := errortypes.UnauthorizedError{
+		UserError: errortypes.UserError{
+			SafeMessage: "A valid node secret is required",
+		},
+	}
+
+	if secret == "" {
+		return "", unauthorizedError
+	}
+
+	var secretHash sql.NullString
+	var organizationName string
+	err := service.db.QueryRowContext(ctx, getNodeSecretHashSQL, id).Scan(&secretHash, &organizationName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", unauthorizedError
+		}
+
+		return "", errortypes.SystemError{
+			SafeMessage:   "Unable to check node secret due to a system error",
+			UnsafeMessage: "Unable to get node secret hash",
+			WrappedError:  err,
+		}
+	}
+
+	expectedHash := []byte(secretHash.String)
+	actualHash := []byte(hashSecret(secret))
+	if !secretHash.Valid || subtle.ConstantTimeCompare(expectedHash, actualHash) != 1 {
+		return "", unauthorizedError
+	}
+
+	return organizationName, nil
+}
+
+// ResetNodeSecret issues a new secret to a node, replacing its old
+// one. Only nodes in the networks of the named organization, or of the
+// default organization when none is given, can be reset. The node that
+// is returned is the only place the secret can be read from.
+func (service *Service) ResetNodeSecret(ctx context.Context, organizationName string, id string) (*Node, error) {
+	nodeOrganization, err := service.getOrganization(ctx, organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, secretHash, err := generateSecret(rand.Reader)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to reset node secret due to a system error",
+			UnsafeMessage: "Unable to generate node secret",
+			WrappedError:  err,
+		}
+	}
+
+	result, err := service.db.ExecContext(
+		ctx,
+		setNodeSecretHashSQL,
+		id,
+		nodeOrganization.ID(),
+		secretHash,
+	)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to reset node secret due to a system error",
+			UnsafeMessage: "Unable to save node secret hash",
+			WrappedError:  err,
+		}
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return nil, errortypes.NotFoundError{
+			UserError: errortypes.UserError{
+				SafeMessage: "Could not find a node with that ID",
+			},
+		}
+	}
+
+	node, err := service.GetNode(ctx, organizationName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	node.secret = secret
+
+	return node, nil
+}
@@ -0,0 +1,1461 @@
+package node
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/webhook"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"inet.af/netaddr"
+)
+
+const (
+	// addressLockClass namespaces the advisory locks held while
+	// addresses are handed out in a network.
+	addressLockClass = 0x6c6579
+
+	// maxEventLimit is the most events that are returned at once.
+	maxEventLimit = 500
+
+	// reaperLockID is the advisory lock held while nodes are reaped so
+	// that only one manager replica does it at a time.
+	reaperLockID = 0x6c65792d72656170
+)
+
+var (
+	// nodeNameRegex keeps node names usable as DNS labels.
+	nodeNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+	//go:embed register_node.sql
+	registerNodeSQL string
+
+	//go:embed get_node.sql
+	getNodeSQL string
+
+	//go:embed get_organization_node.sql
+	getOrganizationNodeSQL string
+
+	//go:embed list_nodes.sql
+	listNodesSQL string
+
+	//go:embed list_network_nodes.sql
+	listNetworkNodesSQL string
+
+	//go:embed rotate_node_key.sql
+	rotateNodeKeySQL string
+
+	//go:embed request_key_rotation.sql
+	requestKeyRotationSQL string
+
+	//go:embed delete_node.sql
+	deleteNodeSQL string
+
+	//go:embed lock_network_addresses.sql
+	lockNetworkAddressesSQL string
+
+	//go:embed list_network_addresses.sql
+	listNetworkAddressesSQL string
+
+	//go:embed create_preshared_key.sql
+	createPresharedKeySQL string
+
+	//go:embed list_preshared_keys.sql
+	listPresharedKeysSQL string
+
+	//go:embed delete_preshared_keys.sql
+	deletePresharedKeysSQL string
+
+	//go:embed create_node_event.sql
+	createNodeEventSQL string
+
+	//go:embed list_node_events.sql
+	listNodeEventsSQL string
+
+	//go:embed get_last_node_event_id.sql
+	getLastNodeEventIDSQL string
+
+	//go:embed update_last_seen.sql
+	updateLastSeenSQL string
+
+	//go:embed delete_peer_stats.sql
+	deletePeerStatsSQL string
+
+	//go:embed create_peer_stats.sql
+	createPeerStatsSQL string
+
+	//go:embed list_peer_stats.sql
+	listPeerStatsSQL string
+
+	//go:embed try_lock_reaper.sql
+	tryLockReaperSQL string
+
+	//go:embed reap_nodes.sql
+	reapNodesSQL string
+
+	listNodesColumns = listing.Columns{
+		ID:        "Nodes.ID",
+		Name:      "Nodes.Name",
+		CreatedOn: "Nodes.CreatedOn",
+	}
+)
+
+// Service provides methods for working with nodes.
+type Service struct {
+	db                  *sql.DB
+	networkService      *network.Service
+	organizationService *organization.Service
+	config              configuration.NodeConfiguration
+	dnsConfig           configuration.DNSConfiguration
+}
+
+// NewService creates a new node service. The DNS configuration tells
+// nodes where to resolve the names of their peers.
+func NewService(
+	db *sql.DB,
+	networkService *network.Service,
+	organizationService *organization.Service,
+	config configuration.NodeConfiguration,
+	dnsConfig configuration.DNSConfiguration,
+) *Service {
+	return &Service{
+		db:                  db,
+		networkService:      networkService,
+		organizationService: organizationService,
+		config:              config,
+		dnsConfig:           dnsConfig,
+	}
+}
+
+// RegisterNodeOpts gives the options for adding a node to a network.
+type RegisterNodeOpts struct {
+	// Organization owns the network to join. Empty means the default
+	// organization.
+	Organization string
+
+	Network   string
+	Name      string
+	PublicKey string
+	Endpoint  *string
+
+	// Ephemeral nodes are removed once they have been offline for the
+	// configured time.
+	Ephemeral bool
+
+	// ExpiresOn is when the node is removed. Nil keeps the node until
+	// it is deleted.
+	ExpiresOn *time.Time
+}
+
+// Validate checks that the registration options are valid.
+func (opts *RegisterNodeOpts) Validate() error {
+	if opts.Network == "" {
+		return fmt.Errorf("Must give the network to join")
+	}
+
+	if !nodeNameRegex.MatchString(opts.Name) {
+		return fmt.Errorf("Invalid node name '%s'", opts.Name)
+	}
+
+	if err := ValidateKey(opts.PublicKey); err != nil {
+		return fmt.Errorf("Invalid public key: %w", err)
+	}
+
+	if opts.Endpoint != nil {
+		if _, _, err := net.SplitHostPort(*opts.Endpoint); err != nil {
+			return fmt.Errorf("Endpoint must be given as host:port")
+		}
+	}
+
+	if opts.ExpiresOn != nil && !opts.ExpiresOn.After(time.Now()) {
+		return fmt.Errorf("Expiry must be in the future")
+	}
+
+	return nil
+}
+
+// RegisterNode adds a node to a network and gives it an address from
+// each of the network's ranges. The node that is returned carries the
+// secret it authenticates with, which can't be read again later.
+func (service *Service) RegisterNode(
+	ctx context.Context,
+	opts RegisterNodeOpts,
+) (*Node, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to register node: %v", err)
+	}
+
+	managedNetwork, err := service.networkService.GetNetworkByName(
+		ctx,
+		organizationOrDefault(opts.Organization),
+		opts.Network,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to register node due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	ipv4Address, ipv6Address, err := service.assignAddresses(ctx, tx, managedNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, secretHash, err := generateSecret(rand.Reader)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to register node due to a system error",
+			UnsafeMessage: "Unable to generate node secret",
+			WrappedError:  err,
+		}
+	}
+
+	registrationTime := time.Now().UTC()
+
+	node, err := service.scanNode(tx.QueryRowContext(
+		ctx,
+		registerNodeSQL,
+		uuid.NewString(),
+		managedNetwork.ID(),
+		opts.Name,
+		opts.PublicKey,
+		registrationTime,
+		opts.Endpoint,
+		ipToNullString(ipv4Address),
+		ipToNullString(ipv6Address),
+		opts.Ephemeral,
+		opts.ExpiresOn,
+		secretHash,
+	))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			errorName := pqErr.Code.Name()
+			switch {
+			case errorName == "unique_violation" && pqErr.Constraint == "nodes_network_name_key":
+				return nil, errortypes.NewValidationError("Node name is already taken in this network")
+
+			case errorName == "unique_violation" && pqErr.Constraint == "nodes_public_key_key":
+				return nil, errortypes.NewValidationError("Public key is already in use")
+
+			case errorName == "foreign_key_violation":
+				return nil, errortypes.NotFoundError{
+					UserError: errortypes.UserError{
+						SafeMessage:  "Could not find a network with that name",
+						WrappedError: err,
+					},
+				}
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to register node due to a system error",
+			UnsafeMessage: "Unable to register node due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	if err := recordEvent(ctx, tx, node, EventTypeRegistered, registrationTime); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to register node due to a system error",
+			UnsafeMessage: "Unable to commit new node",
+			WrappedError:  err,
+		}
+	}
+
+	node.secret = secret
+
+	return node, nil
+}
+
+// assignAddresses picks the next free address in each of the
+// network's ranges. It has to run in the same transaction that
+// registers the node.
+func (service *Service) assignAddresses(
+	ctx context.Context,
+	tx *sql.Tx,
+	managedNetwork *network.Network,
+) (*netaddr.IP, *netaddr.IP, error) {
+	if _, err := tx.ExecContext(ctx, lockNetworkAddressesSQL, addressLockClass, managedNetwork.ID()); err != nil {
+		return nil, nil, errortypes.SystemError{
+			SafeMessage:   "Unable to register node due to a system error",
+			UnsafeMessage: "Unable to lock the network's addresses",
+			WrappedError:  err,
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, listNetworkAddressesSQL, managedNetwork.ID())
+	if err != nil {
+		return nil, nil, errortypes.SystemError{
+			SafeMessage:   "Unable to register node due to a system error",
+			UnsafeMessage: "Unable to list the network's addresses",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var used []netaddr.IP
+	for rows.Next() {
+		var rawIPv4Address sql.NullString
+		var rawIPv6Address sql.NullString
+		if err := rows.Scan(&rawIPv4Address, &rawIPv6Address); err != nil {
+			return nil, nil, errortypes.SystemError{
+				SafeMessage:   "Unable to register node due to a system error",
+				UnsafeMessage: "Unable to read address row",
+				WrappedError:  err,
+			}
+		}
+
+		for _, rawAddress := range []sql.NullString{rawIPv4Address, rawIPv6Address} {
+			address, err := nullStringToIP(rawAddress)
+			if err != nil {
+				return nil, nil, errortypes.SystemError{
+					SafeMessage:   "Unable to register node due to a system error",
+					UnsafeMessage: "Unable to parse address",
+					WrappedError:  err,
+				}
+			}
+
+			if address != nil {
+				used = append(used, *address)
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, errortypes.SystemError{
+			SafeMessage:   "Unable to register node due to a system error",
+			UnsafeMessage: "Unable to iterate over address rows",
+			WrappedError:  err,
+		}
+	}
+
+	var addresses [2]*netaddr.IP
+	for index, prefix := range []*netaddr.IPPrefix{managedNetwork.IPv4CIDR(), managedNetwork.IPv6CIDR()} {
+		if prefix == nil {
+			continue
+		}
+
+		address, ok := NextFreeAddress(*prefix, used)
+		if !ok {
+			return nil, nil, errortypes.NewValidationError(
+				"Unable to register node: No free addresses left in %s",
+				prefix.String(),
+			)
+		}
+
+		addresses[index] = &address
+	}
+
+	return addresses[0], addresses[1], nil
+}
+
+// GetNode fetches a node by its ID. Nodes in the networks of other
+// organizations than the one named can't be found, and the default
+// organization is used when no organization is given.
+func (service *Service) GetNode(ctx context.Context, organizationName string, id string) (*Node, error) {
+	nodeOrganization, err := service.getOrganization(ctx, organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.findNode(service.db.QueryRowContext(ctx, getOrganizationNodeSQL, id, nodeOrganization.ID()))
+}
+
+// getNode fetches a node by its ID no matter which organization it
+// belongs to. It is only for nodes that were already found or
+// authenticated.
+func (service *Service) getNode(ctx context.Context, id string) (*Node, error) {
+	return service.findNode(service.db.QueryRowContext(ctx, getNodeSQL, id))
+}
+
+func (service *Service) findNode(row rowScanner) (*Node, error) {
+	node, err := service.scanNode(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a node with that ID",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get node due to a system error",
+			UnsafeMessage: "Unable to get node due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return node, nil
+}
+
+// ListNodesFilter narrows down the nodes that are listed. Empty fields
+// don't filter anything.
+type ListNodesFilter struct {
+	// Organization only includes nodes in the networks of the
+	// organization with this name, which is the default organization
+	// when it is empty. Nodes of other organizations are never listed.
+	Organization string
+
+	// Network only includes nodes in the network with this name.
+	Network string
+
+	// PublicKey only includes the node using this key. A node's
+	// previous key matches too until its grace period is over.
+	PublicKey string
+
+	// Status only includes nodes with this status.
+	Status Status
+}
+
+// ListNodes retrieves a page of nodes.
+func (service *Service) ListNodes(
+	ctx context.Context,
+	params listing.Params,
+	filter ListNodesFilter,
+) (listing.Page[Node], error) {
+	nodeOrganization, err := service.getOrganization(ctx, filter.Organization)
+	if err != nil {
+		return listing.Page[Node]{}, err
+	}
+
+	var conditions []string
+	var args []any
+	if filter.Network != "" {
+		args = append(args, filter.Network)
+		conditions = append(conditions, fmt.Sprintf("Networks.Name = $%d", len(args)))
+	}
+
+	args = append(args, nodeOrganization.ID())
+	conditions = append(conditions, fmt.Sprintf("Networks.OrganizationID = $%d", len(args)))
+
+	if filter.PublicKey != "" {
+		args = append(args, filter.PublicKey, time.Now().UTC())
+		conditions = append(conditions, fmt.Sprintf(
+			"(Nodes.PublicKey = $%[1]d OR (Nodes.PreviousPublicKey = $%[1]d AND Nodes.PreviousKeyExpiresOn > $%[2]d))",
+			len(args)-1,
+			len(args),
+		))
+	}
+
+	if filter.Status != "" {
+		statusCondition, statusArgs := service.statusCondition(filter.Status, len(args), time.Now().UTC())
+		args = append(args, statusArgs...)
+		conditions = append(conditions, statusCondition)
+	}
+
+	query, args, err := params.SQL(listNodesSQL, listNodesColumns, conditions, args)
+	if err != nil {
+		return listing.Page[Node]{}, err
+	}
+
+	nodes, err := service.queryNodes(ctx, query, args...)
+	if err != nil {
+		return listing.Page[Node]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list nodes due to a system error",
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	return listing.NewPage(params, nodes, func(node *Node) listing.Key {
+		return listing.Key{
+			ID:        node.ID(),
+			Name:      node.Name(),
+			CreatedOn: node.CreatedOn(),
+		}
+	}), nil
+}
+
+// statusCondition gives the SQL condition that matches nodes with the
+// given status along with its arguments, which are numbered after the
+// arguments that are already used.
+func (service *Service) statusCondition(status Status, usedArgs int, now time.Time) (string, []any) {
+	staleCutoff := now.Add(-service.config.StaleAfter)
+	offlineCutoff := now.Add(-service.config.OfflineAfter)
+
+	switch status {
+	case StatusOnline:
+		return fmt.Sprintf("Nodes.LastSeenOn > $%d", usedArgs+1), []any{staleCutoff}
+
+	case StatusStale:
+		return fmt.Sprintf(
+			"(Nodes.LastSeenOn <= $%d AND Nodes.LastSeenOn > $%d)",
+			usedArgs+1,
+			usedArgs+2,
+		), []any{staleCutoff, offlineCutoff}
+
+	default:
+		return fmt.Sprintf(
+			"(Nodes.LastSeenOn IS NULL OR Nodes.LastSeenOn <= $%d)",
+			usedArgs+1,
+		), []any{offlineCutoff}
+	}
+}
+
+// PeerHeartbeat is what a node reports about one of its WireGuard
+// peers in a heartbeat.
+type PeerHeartbeat struct {
+	PublicKey         string
+	LatestHandshakeOn *time.Time
+	ReceiveBytes      int64
+	TransmitBytes     int64
+	Endpoint          *string
+}
+
+// HeartbeatOpts gives the WireGuard stats that a node reports with a
+// heartbeat.
+type HeartbeatOpts struct {
+	Peers []PeerHeartbeat
+}
+
+// Validate checks that the reported stats are valid.
+func (opts *HeartbeatOpts) Validate() error {
+	seenKeys := make(map[string]struct{}, len(opts.Peers))
+	for _, peer := range opts.Peers {
+		if err := ValidateKey(peer.PublicKey); err != nil {
+			return fmt.Errorf("Invalid peer public key: %w", err)
+		}
+
+		if _, ok := seenKeys[peer.PublicKey]; ok {
+			return fmt.Errorf("Peer '%s' was reported more than once", peer.PublicKey)
+		}
+
+		seenKeys[peer.PublicKey] = struct{}{}
+
+		if peer.ReceiveBytes < 0 || peer.TransmitBytes < 0 {
+			return fmt.Errorf("Transferred bytes can't be negative")
+		}
+	}
+
+	return nil
+}
+
+// RecordHeartbeat marks a node as seen and replaces the stats it last
+// reported about its peers. Heartbeats don't change the version of the
+// node since they don't change what it is configured to do.
+func (service *Service) RecordHeartbeat(
+	ctx context.Context,
+	id string,
+	opts HeartbeatOpts,
+) (*Node, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to record heartbeat: %v", err)
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to record heartbeat due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	reportTime := time.Now().UTC()
+
+	result, err := tx.ExecContext(ctx, updateLastSeenSQL, id, reportTime)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to record heartbeat due to a system error",
+			UnsafeMessage: "Unable to update when the node was last seen",
+			WrappedError:  err,
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to record heartbeat due to a system error",
+			UnsafeMessage: "Unable to check if the node was updated",
+			WrappedError:  err,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return nil, errortypes.NotFoundError{
+			UserError: errortypes.UserError{
+				SafeMessage: "Could not find a node with that ID",
+			},
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, deletePeerStatsSQL, id); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to record heartbeat due to a system error",
+			UnsafeMessage: "Unable to remove the previous peer stats",
+			WrappedError:  err,
+		}
+	}
+
+	for _, peer := range opts.Peers {
+		_, err := tx.ExecContext(
+			ctx,
+			createPeerStatsSQL,
+			id,
+			peer.PublicKey,
+			peer.LatestHandshakeOn,
+			peer.ReceiveBytes,
+			peer.TransmitBytes,
+			peer.Endpoint,
+			reportTime,
+		)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to record heartbeat due to a system error",
+				UnsafeMessage: "Unable to save peer stats",
+				WrappedError:  err,
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to record heartbeat due to a system error",
+			UnsafeMessage: "Unable to commit heartbeat",
+			WrappedError:  err,
+		}
+	}
+
+	return service.getNode(ctx, id)
+}
+
+// ListPeerStats gives the stats a node reported about its peers in its
+// last heartbeat.
+func (service *Service) ListPeerStats(ctx context.Context, id string) ([]PeerStats, error) {
+	if _, err := service.getNode(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, listPeerStatsSQL, id)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list peer stats due to a system error",
+			UnsafeMessage: "Unable to list peer stats due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	peerStats := []PeerStats{}
+	for rows.Next() {
+		var stats PeerStats
+		var name sql.NullString
+		var latestHandshakeOn sql.NullTime
+		var endpoint sql.NullString
+		err := rows.Scan(
+			&name,
+			&stats.PublicKey,
+			&latestHandshakeOn,
+			&stats.ReceiveBytes,
+			&stats.TransmitBytes,
+			&endpoint,
+			&stats.ReportedOn,
+		)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to list peer stats due to a system error",
+				UnsafeMessage: "Unable to read peer stats row",
+				WrappedError:  err,
+			}
+		}
+
+		stats.Name = name.String
+
+		if latestHandshakeOn.Valid {
+			stats.LatestHandshakeOn = &latestHandshakeOn.Time
+		}
+
+		if endpoint.Valid {
+			stats.Endpoint = &endpoint.String
+		}
+
+		peerStats = append(peerStats, stats)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list peer stats due to a system error",
+			UnsafeMessage: "Unable to iterate over peer stats rows",
+			WrappedError:  err,
+		}
+	}
+
+	return peerStats, nil
+}
+
+// RotateNodeKeyOpts gives the new key for a node.
+type RotateNodeKeyOpts struct {
+	PublicKey string
+
+	// ExpectedVersions limits the rotation to these versions of the
+	// node. Nil allows any version.
+	ExpectedVersions []int64
+}
+
+// RotateNodeKey switches a node to a new public key. The old key keeps
+// being accepted for the configured grace period so that the node can
+// still be found while its peers catch up. Any preshared keys of the
+// node are thrown away so that new ones are handed out with the new
+// key. Submitting the key the node already uses changes nothing.
+func (service *Service) RotateNodeKey(
+	ctx context.Context,
+	id string,
+	opts RotateNodeKeyOpts,
+) (*Node, error) {
+	if err := ValidateKey(opts.PublicKey); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to rotate node key: Invalid public key: %v", err)
+	}
+
+	currentNode, err := service.getNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentNode.PublicKey() == opts.PublicKey {
+		return currentNode, nil
+	}
+
+	if previousKey := currentNode.PreviousPublicKey(); previousKey != nil && *previousKey == opts.PublicKey {
+		return nil, errortypes.NewValidationError("Unable to rotate node key: Cannot go back to the previous key")
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to rotate node key due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rotationTime := time.Now().UTC()
+
+	node, err := service.scanNode(tx.QueryRowContext(
+		ctx,
+		rotateNodeKeySQL,
+		id,
+		opts.PublicKey,
+		rotationTime.Add(service.config.KeyGracePeriod),
+		rotationTime,
+		pq.Array(opts.ExpectedVersions),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, explainMissingNode(service.getNode(ctx, id))
+		}
+
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "nodes_public_key_key" {
+				return nil, errortypes.NewValidationError("Public key is already in use")
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to rotate node key due to a system error",
+			UnsafeMessage: "Unable to rotate node key due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, deletePresharedKeysSQL, id); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to rotate node key due to a system error",
+			UnsafeMessage: "Unable to remove the node's preshared keys",
+			WrappedError:  err,
+		}
+	}
+
+	if err := recordEvent(ctx, tx, node, EventTypeKeyRotated, rotationTime); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to rotate node key due to a system error",
+			UnsafeMessage: "Unable to commit key rotation",
+			WrappedError:  err,
+		}
+	}
+
+	return node, nil
+}
+
+// RequestKeyRotation asks a node to rotate its key the next time it
+// fetches its config, without waiting for the key to expire. Only
+// nodes in the networks of the named organization, or of the default
+// organization when none is given, can be asked. The request is
+// limited to the expected versions of the node unless they are nil.
+func (service *Service) RequestKeyRotation(
+	ctx context.Context,
+	organizationName string,
+	id string,
+	expectedVersions []int64,
+) (*Node, error) {
+	nodeOrganization, err := service.getOrganization(ctx, organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to request key rotation due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	requestTime := time.Now().UTC()
+
+	node, err := service.scanNode(tx.QueryRowContext(
+		ctx,
+		requestKeyRotationSQL,
+		id,
+		nodeOrganization.ID(),
+		requestTime,
+		pq.Array(expectedVersions),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, explainMissingNode(service.GetNode(ctx, organizationName, id))
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to request key rotation due to a system error",
+			UnsafeMessage: "Unable to request key rotation due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	if err := recordEvent(ctx, tx, node, EventTypeKeyRotationRequested, requestTime); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to request key rotation due to a system error",
+			UnsafeMessage: "Unable to commit key rotation request",
+			WrappedError:  err,
+		}
+	}
+
+	return node, nil
+}
+
+// DeleteNode removes a node from its network, which frees up its
+// addresses. Only nodes in the networks of the named organization, or
+// of the default organization when none is given, can be removed. The
+// delete is limited to the expected versions of the node unless they
+// are nil.
+func (service *Service) DeleteNode(
+	ctx context.Context,
+	organizationName string,
+	id string,
+	expectedVersions []int64,
+) error {
+	nodeOrganization, err := service.getOrganization(ctx, organizationName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete node due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// The whole node is given back so that the removal event has all of
+	// its details, even though it's gone by the time it is delivered.
+	node, err := service.scanNode(tx.QueryRowContext(
+		ctx,
+		deleteNodeSQL,
+		id,
+		nodeOrganization.ID(),
+		pq.Array(expectedVersions),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return explainMissingNode(service.GetNode(ctx, organizationName, id))
+		}
+
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete node due to a system error",
+			UnsafeMessage: "Unable to delete node due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	if err := recordEvent(ctx, tx, node, EventTypeRemoved, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete node due to a system error",
+			UnsafeMessage: "Unable to commit node removal",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// GetNodeConfig builds the WireGuard configuration of a node from the
+// other nodes in its network. Preshared keys that are missing for a
+// pair of peers are generated along the way. The node has to be in a
+// network of the named organization, or of the default organization
+// when none is given.
+func (service *Service) GetNodeConfig(ctx context.Context, organizationName string, id string) (*Config, error) {
+	node, err := service.GetNode(ctx, organizationName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	managedNetwork, err := service.networkService.GetNetwork(ctx, node.NetworkID())
+	if err != nil {
+		return nil, err
+	}
+
+	// Reading the last event first means anything that changes while
+	// the config is built shows up as a newer event.
+	var lastEventID int64
+	err = service.db.QueryRowContext(ctx, getLastNodeEventIDSQL, node.NetworkID()).Scan(&lastEventID)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get node config due to a system error",
+			UnsafeMessage: "Unable to get the last node event",
+			WrappedError:  err,
+		}
+	}
+
+	nodes, err := service.queryNodes(ctx, listNetworkNodesSQL, node.NetworkID())
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get node config due to a system error",
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	var presharedKeys map[peerPair]string
+	if managedNetwork.PresharedKeys() {
+		presharedKeys, err = service.ensurePresharedKeys(ctx, node, nodes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Config{
+		Node:           node,
+		Peers:          buildPeerConfigs(node, managedNetwork, nodes, presharedKeys),
+		DNS:            service.buildDNSConfig(managedNetwork),
+		KeyRotationDue: node.KeyRotationDue(time.Now().UTC()),
+		LastEventID:    lastEventID,
+		NetworkVersion: managedNetwork.Version(),
+	}, nil
+}
+
+// ensurePresharedKeys makes sure there is a preshared key shared with
+// every other node in the network and returns all of the node's keys.
+// Whichever of the two nodes asks first generates the key and the
+// other one is given the same key.
+func (service *Service) ensurePresharedKeys(
+	ctx context.Context,
+	node *Node,
+	nodes []Node,
+) (map[peerPair]string, error) {
+	creationTime := time.Now().UTC()
+	for index := range nodes {
+		if nodes[index].ID() == node.ID() {
+			continue
+		}
+
+		key, err := GeneratePresharedKey(rand.Reader)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to get node config due to a system error",
+				UnsafeMessage: err.Error(),
+				WrappedError:  err,
+			}
+		}
+
+		pair := newPeerPair(node.ID(), nodes[index].ID())
+		_, err = service.db.ExecContext(ctx, createPresharedKeySQL, pair.first, pair.second, key, creationTime)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to get node config due to a system error",
+				UnsafeMessage: "Unable to create preshared key",
+				WrappedError:  err,
+			}
+		}
+	}
+
+	rows, err := service.db.QueryContext(ctx, listPresharedKeysSQL, node.ID())
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get node config due to a system error",
+			UnsafeMessage: "Unable to list preshared keys",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	presharedKeys := map[peerPair]string{}
+	for rows.Next() {
+		var pair peerPair
+		var key string
+		if err := rows.Scan(&pair.first, &pair.second, &key); err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to get node config due to a system error",
+				UnsafeMessage: "Unable to read preshared key row",
+				WrappedError:  err,
+			}
+		}
+
+		presharedKeys[pair] = key
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get node config due to a system error",
+			UnsafeMessage: "Unable to iterate over preshared key rows",
+			WrappedError:  err,
+		}
+	}
+
+	return presharedKeys, nil
+}
+
+// ListNodeEvents gives the events in a node's network that happened
+// after the given event ID, oldest first. The node has to be in a
+// network of the named organization, or of the default organization
+// when none is given.
+func (service *Service) ListNodeEvents(
+	ctx context.Context,
+	organizationName string,
+	id string,
+	afterEventID int64,
+	limit int,
+) ([]Event, error) {
+	if limit < 1 || limit > maxEventLimit {
+		return nil, errortypes.NewValidationError("Event limit must be between 1 and %d", maxEventLimit)
+	}
+
+	node, err := service.GetNode(ctx, organizationName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, listNodeEventsSQL, node.NetworkID(), afterEventID, limit)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list node events due to a system error",
+			UnsafeMessage: "Unable to list node events due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		err := rows.Scan(&event.id, &event.nodeID, &event.nodeName, &event.eventType, &event.createdOn)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to list node events due to a system error",
+				UnsafeMessage: "Unable to read node event row",
+				WrappedError:  err,
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list node events due to a system error",
+			UnsafeMessage: "Unable to iterate over node event rows",
+			WrappedError:  err,
+		}
+	}
+
+	return events, nil
+}
+
+// ReapNodes removes the nodes that expired by the given time along
+// with the ephemeral nodes that haven't been seen for the configured
+// time, which frees up their addresses. Nothing is removed when
+// another manager is already reaping. The number of removed nodes is
+// returned.
+func (service *Service) ReapNodes(ctx context.Context, now time.Time) (int, error) {
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errortypes.SystemError{
+			SafeMessage:   "Unable to reap nodes due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, tryLockReaperSQL, reaperLockID).Scan(&locked); err != nil {
+		return 0, errortypes.SystemError{
+			SafeMessage:   "Unable to reap nodes due to a system error",
+			UnsafeMessage: "Unable to lock the reaper",
+			WrappedError:  err,
+		}
+	}
+
+	if !locked {
+		return 0, nil
+	}
+
+	reaped, err := service.reapNodes(ctx, tx, now, now.Add(-service.config.ReapOfflineAfter))
+	if err != nil {
+		return 0, err
+	}
+
+	for index := range reaped {
+		eventType := EventTypeReaped
+		if reaped[index].expired {
+			eventType = EventTypeExpired
+		}
+
+		if err := recordEvent(ctx, tx, &reaped[index].node, eventType, now); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errortypes.SystemError{
+			SafeMessage:   "Unable to reap nodes due to a system error",
+			UnsafeMessage: "Unable to commit reaped nodes",
+			WrappedError:  err,
+		}
+	}
+
+	return len(reaped), nil
+}
+
+type reapedNode struct {
+	node    Node
+	expired bool
+}
+
+// reapNodes removes the nodes that are due. The whole nodes are given
+// back so that the removal events have all of their details.
+func (service *Service) reapNodes(
+	ctx context.Context,
+	tx *sql.Tx,
+	now time.Time,
+	offlineCutoff time.Time,
+) ([]reapedNode, error) {
+	rows, err := tx.QueryContext(ctx, reapNodesSQL, now, offlineCutoff)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to reap nodes due to a system error",
+			UnsafeMessage: "Unable to remove nodes",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var reaped []reapedNode
+	for rows.Next() {
+		node, err := service.scanNode(rows)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to reap nodes due to a system error",
+				UnsafeMessage: "Unable to read removed node row",
+				WrappedError:  err,
+			}
+		}
+
+		reaped = append(reaped, reapedNode{
+			node:    *node,
+			expired: node.ExpiresOn() != nil && !node.ExpiresOn().After(now),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to reap nodes due to a system error",
+			UnsafeMessage: "Unable to iterate over removed node rows",
+			WrappedError:  err,
+		}
+	}
+
+	return reaped, nil
+}
+
+// explainMissingNode figures out why a conditional change didn't match
+// any rows from looking the node up again, either because it can't be
+// found or because it was at a different version.
+func explainMissingNode(_ *Node, err error) error {
+	if err != nil {
+		return err
+	}
+
+	return errortypes.PreconditionFailedError{
+		UserError: errortypes.UserError{
+			SafeMessage: "The node has been changed since it was last read",
+		},
+	}
+}
+
+// recordEvent adds an event to the node's network, queues it for the
+// webhooks subscribed to it and lets the managers watching the network
+// know about it once the transaction commits.
+func recordEvent(
+	ctx context.Context,
+	tx *sql.Tx,
+	node *Node,
+	eventType EventType,
+	eventTime time.Time,
+) error {
+	_, err := tx.ExecContext(
+		ctx,
+		createNodeEventSQL,
+		node.NetworkID(),
+		node.ID(),
+		node.Name(),
+		eventType,
+		eventTime,
+	)
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to record node event due to a system error",
+			UnsafeMessage: fmt.Sprintf("Unable to record '%s' event", eventType),
+			WrappedError:  err,
+		}
+	}
+
+	renderableNode := NewRenderableNode(node)
+	if err := webhook.Record(ctx, tx, webhookEventTypes[eventType], &renderableNode); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to record node event due to a system error",
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	if err := notify.Notify(ctx, tx, notify.NetworkChannel, node.NetworkID()); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to record node event due to a system error",
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// getOrganization fetches the named organization, or the default
+// organization when none is given. Users that aren't members of the
+// organization can't find it.
+func (service *Service) getOrganization(
+	ctx context.Context,
+	organizationName string,
+) (*organization.Organization, error) {
+	return service.organizationService.GetOrganizationByName(ctx, organizationOrDefault(organizationName))
+}
+
+// organizationOrDefault gives the name of the organization to use when
+// the given one may be empty.
+func organizationOrDefault(organizationName string) string {
+	if organizationName == "" {
+		return organization.DefaultName
+	}
+
+	return organizationName
+}
+
+func (service *Service) queryNodes(ctx context.Context, query string, args ...any) ([]Node, error) {
+	rows, err := service.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to query nodes: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	nodes := []Node{}
+	for rows.Next() {
+		node, err := service.scanNode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read node row: %w", err)
+		}
+
+		nodes = append(nodes, *node)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to iterate over node rows: %w", err)
+	}
+
+	return nodes, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanNode reads a node and fills in the key details that depend on
+// the configuration.
+func (service *Service) scanNode(row rowScanner) (*Node, error) {
+	var node Node
+	var previousPublicKey sql.NullString
+	var previousKeyExpiresOn sql.NullTime
+	var endpoint sql.NullString
+	var lastSeenOn sql.NullTime
+	var expiresOn sql.NullTime
+	var rawIPv4Address sql.NullString
+	var rawIPv6Address sql.NullString
+
+	err := row.Scan(
+		&node.id,
+		&node.networkID,
+		&node.network,
+		&node.name,
+		&node.publicKey,
+		&previousPublicKey,
+		&previousKeyExpiresOn,
+		&node.keyRotatedOn,
+		&node.keyRotationRequested,
+		&endpoint,
+		&lastSeenOn,
+		&node.ephemeral,
+		&expiresOn,
+		&rawIPv4Address,
+		&rawIPv6Address,
+		&node.version,
+		&node.createdOn,
+		&node.modifiedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if previousPublicKey.Valid && previousKeyExpiresOn.Valid && time.Now().Before(previousKeyExpiresOn.Time) {
+		node.previousPublicKey = &previousPublicKey.String
+		node.previousKeyExpiresOn = &previousKeyExpiresOn.Time
+	}
+
+	if endpoint.Valid {
+		node.endpoint = &endpoint.String
+	}
+
+	if lastSeenOn.Valid {
+		node.lastSeenOn = &lastSeenOn.Time
+	}
+
+	if expiresOn.Valid {
+		node.expiresOn = &expiresOn.Time
+	}
+
+	node.status = StatusAt(node.lastSeenOn, time.Now(), service.config.StaleAfter, service.config.OfflineAfter)
+
+	if service.config.KeyRotationInterval > 0 {
+		keyExpiresOn := node.keyRotatedOn.Add(service.config.KeyRotationInterval)
+		node.keyExpiresOn = &keyExpiresOn
+	}
+
+	if node.ipv4Address, err = nullStringToIP(rawIPv4Address); err != nil {
+		return nil, err
+	}
+
+	if node.ipv6Address, err = nullStringToIP(rawIPv6Address); err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+func ipToNullString(ip *netaddr.IP) sql.NullString {
+	if ip == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: ip.String(), Valid: true}
+}
+
+func nullStringToIP(value sql.NullString) (*netaddr.IP, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	ip, err := netaddr.ParseIP(value.String)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse IP address '%s': %w", value.String, err)
+	}
+
+	return &ip, nil
+}
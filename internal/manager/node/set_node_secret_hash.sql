@@ -0,0 +1,8 @@
+UPDATE Nodes
+SET SecretHash = $3
+FROM Networks
+WHERE
+    Nodes.ID = $1
+    AND Networks.ID = Nodes.NetworkID
+    AND Networks.OrganizationID = $2
+;
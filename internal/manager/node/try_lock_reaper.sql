@@ -0,0 +1,2 @@
+SELECT pg_try_advisory_xact_lock($1)
+;
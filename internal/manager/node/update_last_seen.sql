@@ -0,0 +1,6 @@
+UPDATE Nodes
+SET
+    LastSeenOn = $2
+WHERE
+    ID = $1
+;
@@ -0,0 +1,281 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/notify"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// watchKeepAliveInterval is how often a comment is sent so that
+	// proxies don't close idle streams. The config is checked again at
+	// the same time since a key rotation can become due without any
+	// event.
+	watchKeepAliveInterval = 15 * time.Second
+
+	// watchDeadlineMargin is how long before the request times out that
+	// a stream is ended so that it closes cleanly.
+	watchDeadlineMargin = 5 * time.Second
+)
+
+const (
+	// WatchEventConfig carries the latest config of the watched node.
+	WatchEventConfig = "config"
+
+	// WatchEventRemoved is sent when the watched node no longer exists.
+	// It is the last event of the stream.
+	WatchEventRemoved = "removed"
+
+	// WatchEventError is sent when the config couldn't be built. It is
+	// the last event of the stream and the client should reconnect.
+	WatchEventError = "error"
+)
+
+// ConfigWatcher is told about the config of a watched node. An error
+// from any of its methods ends the watch.
+type ConfigWatcher interface {
+	// Started is called once the node has been found, before its first
+	// config is sent. Errors after this point happen mid-stream.
+	Started() error
+
+	// Config is called with the latest config of the node.
+	Config(config *Config) error
+
+	// KeepAlive is called whenever the stream has been quiet for a
+	// while.
+	KeepAlive() error
+
+	// Removed is called with the last known state of the node when it
+	// no longer exists. It is the last call of the watch.
+	Removed(node *Node) error
+}
+
+// WatchConfig hands the config of a node to the watcher straight away,
+// unless lastRevision names its revision, and then again whenever it
+// changes. Changes made through any manager reach every watcher
+// through Postgres notifications. The watch ends cleanly shortly
+// before the context's deadline, or when the context is done. Only
+// nodes in the networks of the named organization can be watched.
+func WatchConfig(
+	ctx context.Context,
+	nodeService *Service,
+	hub *notify.Hub,
+	organizationName string,
+	id string,
+	lastRevision string,
+	watcher ConfigWatcher,
+) error {
+	node, err := nodeService.GetNode(ctx, organizationName, id)
+	if err != nil {
+		return err
+	}
+
+	// Subscribing before building the config means that no change can
+	// slip through in between.
+	changes, unsubscribe, err := hub.Subscribe(node.NetworkID())
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to watch node due to a system error",
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	defer unsubscribe()
+
+	config, err := nodeService.GetNodeConfig(ctx, organizationName, node.ID())
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Started(); err != nil {
+		return err
+	}
+
+	keepAlive := time.NewTicker(watchKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	var deadline <-chan time.Time
+	if ctxDeadline, ok := ctx.Deadline(); ok {
+		deadlineTimer := time.NewTimer(time.Until(ctxDeadline) - watchDeadlineMargin)
+		defer deadlineTimer.Stop()
+
+		deadline = deadlineTimer.C
+	}
+
+	lastKeyRotationDue := false
+	for {
+		if config.Revision() != lastRevision || config.KeyRotationDue != lastKeyRotationDue {
+			if err := watcher.Config(config); err != nil {
+				return err
+			}
+
+			lastRevision = config.Revision()
+			lastKeyRotationDue = config.KeyRotationDue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-deadline:
+			return nil
+
+		case <-keepAlive.C:
+			if err := watcher.KeepAlive(); err != nil {
+				return err
+			}
+
+		case <-changes:
+		}
+
+		latestConfig, err := nodeService.GetNodeConfig(ctx, organizationName, node.ID())
+		if err != nil {
+			var notFoundError errortypes.NotFoundError
+			if errors.As(err, &notFoundError) {
+				return watcher.Removed(config.Node)
+			}
+
+			return err
+		}
+
+		config = latestConfig
+	}
+}
+
+// WatchNode streams the config of a node as server-sent events. The
+// Last-Event-ID header can name the revision of the config the client
+// already has.
+func (controller *Controller) WatchNode(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	flusher, ok := response.(http.Flusher)
+	if !ok {
+		handleError(response, request, errortypes.SystemError{
+			SafeMessage:   "Unable to watch node due to a system error",
+			UnsafeMessage: "Response writer doesn't support streaming",
+		})
+
+		return
+	}
+
+	watcher := &eventStreamWatcher{
+		response: response,
+		flusher:  flusher,
+	}
+
+	err := WatchConfig(
+		request.Context(),
+		controller.NodeService,
+		controller.Hub,
+		organizationName(request),
+		chi.URLParam(request, "id"),
+		request.Header.Get("Last-Event-ID"),
+		watcher,
+	)
+	if err == nil || watcher.writeFailed {
+		return
+	}
+
+	if !watcher.started {
+		handleError(response, request, err)
+		return
+	}
+
+	_ = writeEvent(response, "", WatchEventError, &renderable.ErrorResponse{
+		Message: "Unable to watch node due to a system error",
+	})
+	flusher.Flush()
+}
+
+// eventStreamWatcher writes the config of a watched node as server-sent
+// events.
+type eventStreamWatcher struct {
+	response http.ResponseWriter
+	flusher  http.Flusher
+
+	// started is set once the response headers have been written.
+	started bool
+
+	// writeFailed is set when the client can't be written to anymore.
+	writeFailed bool
+}
+
+func (watcher *eventStreamWatcher) Started() error {
+	watcher.response.Header().Set("Content-Type", "text/event-stream")
+	watcher.response.Header().Set("Cache-Control", "no-cache")
+	watcher.response.Header().Set("X-Accel-Buffering", "no")
+	watcher.response.WriteHeader(http.StatusOK)
+	watcher.flusher.Flush()
+
+	watcher.started = true
+
+	return nil
+}
+
+func (watcher *eventStreamWatcher) Config(config *Config) error {
+	configResponse := NewGetNodeConfigResponse(config)
+
+	return watcher.write(config.Revision(), WatchEventConfig, &configResponse)
+}
+
+func (watcher *eventStreamWatcher) KeepAlive() error {
+	if _, err := io.WriteString(watcher.response, ": keep-alive\n\n"); err != nil {
+		watcher.writeFailed = true
+		return fmt.Errorf("Unable to write keep-alive: %w", err)
+	}
+
+	watcher.flusher.Flush()
+
+	return nil
+}
+
+func (watcher *eventStreamWatcher) Removed(node *Node) error {
+	removedNode := NewRenderableNode(node)
+
+	return watcher.write("", WatchEventRemoved, &removedNode)
+}
+
+func (watcher *eventStreamWatcher) write(id string, eventType string, data any) error {
+	if err := writeEvent(watcher.response, id, eventType, data); err != nil {
+		watcher.writeFailed = true
+		return err
+	}
+
+	watcher.flusher.Flush()
+
+	return nil
+}
+
+var _ ConfigWatcher = (*eventStreamWatcher)(nil)
+
+// writeEvent writes a single server-sent event. Events without an ID
+// leave the client's last event ID alone.
+func writeEvent(writer io.Writer, id string, eventType string, data any) error {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("Unable to marshal '%s' event: %w", eventType, err)
+	}
+
+	if id != "" {
+		if _, err := fmt.Fprintf(writer, "id: %s\n", id); err != nil {
+			return fmt.Errorf("Unable to write event ID: %w", err)
+		}
+	}
+
+	if _, err := fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", eventType, rawData); err != nil {
+		return fmt.Errorf("Unable to write '%s' event: %w", eventType, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NetworkChannel is the Postgres channel used to announce that the
+// desired state of a network's nodes changed. The payload is the ID of
+// the network.
+const NetworkChannel = "ley_network_changes"
+
+const (
+	minReconnectInterval = 10 * time.Millisecond
+	maxReconnectInterval = time.Minute
+)
+
+// Execer runs statements against the database, either directly or as
+// part of a transaction.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Notify sends a notification to every manager listening on the
+// channel. Notifications sent in a transaction are only delivered once
+// it commits.
+func Notify(ctx context.Context, execer Execer, channel string, payload string) error {
+	if _, err := execer.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload); err != nil {
+		return fmt.Errorf("Unable to notify channel '%s': %w", channel, err)
+	}
+
+	return nil
+}
+
+// Hub fans the notifications of a channel out to subscribers in this
+// process so that a single database connection serves every watcher.
+// The connection is only opened once there is a subscriber.
+type Hub struct {
+	connectionString func() (string, error)
+	channel          string
+
+	lock        sync.Mutex
+	listener    *pq.Listener
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+// NewHub creates a hub for a channel. The connection string is only
+// requested when the first subscriber arrives.
+func NewHub(connectionString func() (string, error), channel string) *Hub {
+	return &Hub{
+		connectionString: connectionString,
+		channel:          channel,
+		subscribers:      map[string]map[chan struct{}]struct{}{},
+	}
+}
+
+// Subscribe registers interest in notifications with the given
+// payload. The returned channel receives a value whenever there was at
+// least one notification since it was last read, including when the
+// connection was lost and notifications may have been missed. The
+// returned function has to be called once the subscriber is done.
+func (hub *Hub) Subscribe(payload string) (<-chan struct{}, func(), error) {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	if hub.listener == nil {
+		if err := hub.listen(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	subscription := make(chan struct{}, 1)
+	if _, ok := hub.subscribers[payload]; !ok {
+		hub.subscribers[payload] = map[chan struct{}]struct{}{}
+	}
+
+	hub.subscribers[payload][subscription] = struct{}{}
+
+	unsubscribe := func() {
+		hub.lock.Lock()
+		defer hub.lock.Unlock()
+
+		delete(hub.subscribers[payload], subscription)
+		if len(hub.subscribers[payload]) == 0 {
+			delete(hub.subscribers, payload)
+		}
+	}
+
+	return subscription, unsubscribe, nil
+}
+
+// Close stops listening for notifications.
+func (hub *Hub) Close() error {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	if hub.listener == nil {
+		return nil
+	}
+
+	err := hub.listener.Close()
+	hub.listener = nil
+
+	return err
+}
+
+func (hub *Hub) listen() error {
+	connectionString, err := hub.connectionString()
+	if err != nil {
+		return fmt.Errorf("Unable to create database connection string: %w", err)
+	}
+
+	listener := pq.NewListener(connectionString, minReconnectInterval, maxReconnectInterval, nil)
+	if err := listener.Listen(hub.channel); err != nil {
+		_ = listener.Close()
+
+		return fmt.Errorf("Unable to listen to channel '%s': %w", hub.channel, err)
+	}
+
+	hub.listener = listener
+	go hub.relay(listener)
+
+	return nil
+}
+
+// relay passes notifications on to the subscribers until the listener
+// is closed. The listener sends nil after reconnecting since notifications
+// may have been lost, so everyone is woken up.
+func (hub *Hub) relay(listener *pq.Listener) {
+	for notification := range listener.Notify {
+		hub.lock.Lock()
+		if notification == nil {
+			for _, subscriptions := range hub.subscribers {
+				wake(subscriptions)
+			}
+		} else {
+			wake(hub.subscribers[notification.Extra])
+		}
+		hub.lock.Unlock()
+	}
+}
+
+func wake(subscriptions map[chan struct{}]struct{}) {
+	for subscription := range subscriptions {
+		select {
+		case subscription <- struct{}{}:
+		default:
+		}
+	}
+}
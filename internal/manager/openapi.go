@@ -0,0 +1,23 @@
+package manager
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+var (
+	//go:embed openapi.json
+	openAPISpec []byte
+)
+
+// OpenAPISpec gives the OpenAPI document that describes every route
+// handled by the controller.
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+func serveOpenAPISpec(response http.ResponseWriter, request *http.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	_, _ = response.Write(openAPISpec)
+}
@@ -0,0 +1,513 @@
+package manager_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager"
+	"github.com/durandj/ley/internal/manager/apply"
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/group"
+	"github.com/durandj/ley/internal/manager/network"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/durandj/ley/internal/manager/scheduler"
+	"github.com/durandj/ley/internal/manager/scim"
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/durandj/ley/internal/manager/webhook"
+	"github.com/durandj/ley/pkg/client"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaTypes maps every object schema in the OpenAPI document to the
+// Go types that are serialized using it, on the manager's side and on
+// the client's, so that neither can drift from the document.
+var schemaTypes = map[string][]any{
+	"ErrorResponse": {renderable.ErrorResponse{}},
+	"User": {
+		user.RenderableUser{},
+		user.CreateUserResponse{},
+		user.GetUserByUsernameResponse{},
+		user.UpdateUserResponse{},
+		client.User{},
+		client.CreateUserResponse{},
+		client.GetUserByUsernameResponse{},
+		client.UpdateUserResponse{},
+	},
+	"CreateUserRequest": {
+		user.CreateUserRequest{},
+		client.CreateUserRequest{},
+	},
+	"UpdateUserRequest": {
+		user.UpdateUserRequest{},
+		client.UpdateUserRequest{},
+	},
+	"Network": {
+		network.RenderableNetwork{},
+		network.CreateNetworkResponse{},
+		network.GetNetworkResponse{},
+		network.UpdateNetworkResponse{},
+		client.Network{},
+		client.CreateNetworkResponse{},
+		client.GetNetworkResponse{},
+		client.UpdateNetworkResponse{},
+	},
+	"CreateNetworkRequest": {
+		network.CreateNetworkRequest{},
+		client.CreateNetworkRequest{},
+	},
+	"UpdateNetworkRequest": {
+		network.UpdateNetworkRequest{},
+		client.UpdateNetworkRequest{},
+	},
+	"ListNetworksResponse": {
+		network.ListNetworksResponse{},
+		client.ListNetworksResponse{},
+	},
+	"ListUsersResponse": {
+		user.ListUsersResponse{},
+		client.ListUsersResponse{},
+	},
+	"AddressSpace": {
+		network.GetAddressSpaceResponse{},
+		client.AddressSpace{},
+	},
+	"AddressFamilySpace": {
+		network.RenderableAddressFamilySpace{},
+		client.AddressFamilySpace{},
+	},
+	"UsedAddressRange": {
+		network.RenderableUsedRange{},
+		client.UsedAddressRange{},
+	},
+	"Topology": {
+		network.Topology{},
+		client.Topology{},
+	},
+	"PeerGroup": {
+		network.PeerGroup{},
+		client.PeerGroup{},
+	},
+	"Node": {
+		node.RenderableNode{},
+		node.GetNodeResponse{},
+		node.RotateNodeKeyResponse{},
+		node.RequestKeyRotationResponse{},
+		node.HeartbeatResponse{},
+		client.Node{},
+		client.GetNodeResponse{},
+		client.RotateNodeKeyResponse{},
+		client.RequestKeyRotationResponse{},
+		client.HeartbeatResponse{},
+	},
+	"RegisterNodeRequest": {
+		node.RegisterNodeRequest{},
+		client.RegisterNodeRequest{},
+	},
+	"RotateNodeKeyRequest": {
+		node.RotateNodeKeyRequest{},
+		client.RotateNodeKeyRequest{},
+	},
+	"ListNodesResponse": {
+		node.ListNodesResponse{},
+		client.ListNodesResponse{},
+	},
+	"NodeConfig": {
+		node.GetNodeConfigResponse{},
+		client.NodeConfig{},
+	},
+	"PeerConfig": {
+		node.RenderablePeerConfig{},
+		client.PeerConfig{},
+	},
+	"NodeEvent": {
+		node.RenderableEvent{},
+		client.NodeEvent{},
+	},
+	"ListNodeEventsResponse": {
+		node.ListNodeEventsResponse{},
+		client.ListNodeEventsResponse{},
+	},
+	"HeartbeatRequest": {
+		node.HeartbeatRequest{},
+		client.HeartbeatRequest{},
+	},
+	"PeerHeartbeat": {
+		node.RenderablePeerHeartbeat{},
+		client.PeerHeartbeat{},
+	},
+	"PeerStats": {
+		node.RenderablePeerStats{},
+		client.PeerStats{},
+	},
+	"ListPeerStatsResponse": {
+		node.ListPeerStatsResponse{},
+		client.ListPeerStatsResponse{},
+	},
+	"Job":                 {scheduler.RenderableJob{}},
+	"JobRun":              {scheduler.RenderableRun{}},
+	"ListJobsResponse":    {scheduler.ListJobsResponse{}},
+	"ListJobRunsResponse": {scheduler.ListRunsResponse{}},
+	"Webhook": {
+		webhook.RenderableWebhook{},
+		webhook.CreateWebhookResponse{},
+		webhook.GetWebhookResponse{},
+		client.Webhook{},
+		client.CreateWebhookResponse{},
+		client.GetWebhookResponse{},
+	},
+	"CreateWebhookRequest": {
+		webhook.CreateWebhookRequest{},
+		client.CreateWebhookRequest{},
+	},
+	"ListWebhooksResponse": {
+		webhook.ListWebhooksResponse{},
+		client.ListWebhooksResponse{},
+	},
+	"WebhookDelivery": {
+		webhook.RenderableDelivery{},
+		client.WebhookDelivery{},
+	},
+	"ListWebhookDeliveriesResponse": {
+		webhook.ListDeliveriesResponse{},
+		client.ListWebhookDeliveriesResponse{},
+	},
+	"Organization": {
+		organization.RenderableOrganization{},
+		organization.CreateOrganizationResponse{},
+		organization.GetOrganizationResponse{},
+		client.Organization{},
+		client.CreateOrganizationResponse{},
+		client.GetOrganizationResponse{},
+	},
+	"CreateOrganizationRequest": {
+		organization.CreateOrganizationRequest{},
+		client.CreateOrganizationRequest{},
+	},
+	"ListOrganizationsResponse": {
+		organization.ListOrganizationsResponse{},
+		client.ListOrganizationsResponse{},
+	},
+	"OrganizationMember": {
+		organization.RenderableMember{},
+		organization.AddMemberResponse{},
+		client.OrganizationMember{},
+		client.AddOrganizationMemberResponse{},
+	},
+	"ListOrganizationMembersResponse": {
+		organization.ListMembersResponse{},
+		client.ListOrganizationMembersResponse{},
+	},
+	"Resource": {
+		apply.Resource{},
+		client.Resource{},
+	},
+	"ResourceMetadata": {
+		apply.Metadata{},
+		client.ResourceMetadata{},
+	},
+	"NetworkSpec": {apply.NetworkSpec{}},
+	"UserSpec":    {apply.UserSpec{}},
+	"ApplyRequest": {
+		apply.ApplyRequest{},
+		client.ApplyRequest{},
+	},
+	"ApplyResponse": {
+		apply.ApplyResponse{},
+		client.ApplyResponse{},
+	},
+	"Change": {
+		apply.RenderableChange{},
+		client.Change{},
+	},
+	"FieldChange": {
+		apply.RenderableFieldChange{},
+		client.FieldChange{},
+	},
+	"Group": {
+		group.RenderableGroup{},
+		group.CreateGroupResponse{},
+		group.GetGroupResponse{},
+		client.Group{},
+		client.CreateGroupResponse{},
+		client.GetGroupResponse{},
+	},
+	"CreateGroupRequest": {
+		group.CreateGroupRequest{},
+		client.CreateGroupRequest{},
+	},
+	"ListGroupsResponse": {
+		group.ListGroupsResponse{},
+		client.ListGroupsResponse{},
+	},
+	"GroupMember": {
+		group.RenderableMember{},
+		group.AddMemberResponse{},
+		client.GroupMember{},
+		client.AddGroupMemberResponse{},
+	},
+	"ListGroupMembersResponse": {
+		group.ListMembersResponse{},
+		client.ListGroupMembersResponse{},
+	},
+	"EffectiveGroup": {
+		group.RenderableEffectiveGroup{},
+		client.EffectiveGroup{},
+	},
+	"ListEffectiveGroupsResponse": {
+		group.ListEffectiveGroupsResponse{},
+		client.ListEffectiveGroupsResponse{},
+	},
+	"LoginResponse": {auth.LoginResponse{}},
+	"Session": {
+		auth.GetSessionResponse{},
+		client.GetSessionResponse{},
+	},
+	"ScimMeta":               {scim.Meta{}},
+	"ScimUser":               {scim.UserResource{}},
+	"ScimMemberReference":    {scim.MemberReference{}},
+	"ScimGroup":              {scim.GroupResource{}},
+	"ScimCreateUserRequest":  {scim.CreateUserRequest{}},
+	"ScimCreateGroupRequest": {scim.CreateGroupRequest{}},
+	"ScimPatchOperation":     {scim.PatchOperation{}},
+	"ScimPatchRequest":       {scim.PatchRequest{}},
+	"ScimListUsersResponse":  {scim.ListUsersResponse{}},
+	"ScimListGroupsResponse": {scim.ListGroupsResponse{}},
+	"ScimError":              {scim.ErrorResponse{}},
+	"ScimEmail":              {scim.EmailAddress{}},
+	"RegisterNodeResponse": {
+		node.RegisterNodeResponse{},
+		client.RegisterNodeResponse{},
+	},
+	"ResetNodeSecretResponse": {
+		node.ResetNodeSecretResponse{},
+		client.ResetNodeSecretResponse{},
+	},
+	"DNSConfig": {
+		node.RenderableDNSConfig{},
+		client.DNSConfig{},
+	},
+}
+
+// middlewareRoutes are handled by middleware instead of the router so
+// they don't show up when walking the routes.
+var middlewareRoutes = map[string]any{
+	"GET /healthcheck": nil,
+}
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPISchema struct {
+	Type       string                   `json:"type"`
+	Ref        string                   `json:"$ref"`
+	Properties map[string]openAPISchema `json:"properties"`
+}
+
+func TestOpenAPISpecShouldDescribeEveryRoute(t *testing.T) {
+	document := loadOpenAPIDocument(t)
+	controller := manager.NewController(nil, &configuration.Configuration{})
+
+	var routes []string
+	err := chi.Walk(
+		controller.Routes(),
+		func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+			routes = append(routes, method+" "+normalizeRoute(route))
+
+			return nil
+		},
+	)
+	require.Nil(t, err, "should be able to walk the routes")
+
+	var specRoutes []string
+	for path, operations := range document.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+
+			specRoute := strings.ToUpper(method) + " " + path
+			if _, ok := middlewareRoutes[specRoute]; ok {
+				continue
+			}
+
+			specRoutes = append(specRoutes, specRoute)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(specRoutes)
+
+	require.Equal(t, routes, specRoutes, "should have a spec entry for every route")
+}
+
+func TestOpenAPISpecShouldMatchTheRequestAndResponseTypes(t *testing.T) {
+	document := loadOpenAPIDocument(t)
+
+	for schemaName, schema := range document.Components.Schemas {
+		if schema.Type != "object" || schema.Properties == nil {
+			continue
+		}
+
+		_, ok := schemaTypes[schemaName]
+		require.True(t, ok, "should map schema '%s' to a Go type", schemaName)
+	}
+
+	for schemaName, values := range schemaTypes {
+		schema, ok := document.Components.Schemas[schemaName]
+		require.True(t, ok, "should have a schema named '%s'", schemaName)
+
+		for _, value := range values {
+			fields := jsonFields(reflect.TypeOf(value))
+
+			var fieldNames []string
+			for fieldName := range fields {
+				fieldNames = append(fieldNames, fieldName)
+			}
+
+			var propertyNames []string
+			for propertyName := range schema.Properties {
+				propertyNames = append(propertyNames, propertyName)
+			}
+
+			sort.Strings(fieldNames)
+			sort.Strings(propertyNames)
+
+			require.Equal(
+				t,
+				fieldNames,
+				propertyNames,
+				"should have the same properties for schema '%s' and type %T",
+				schemaName,
+				value,
+			)
+
+			for propertyName, property := range schema.Properties {
+				if property.Ref != "" {
+					continue
+				}
+
+				require.Equal(
+					t,
+					property.Type,
+					openAPIType(fields[propertyName]),
+					"should have the same type for '%s' in schema '%s' and type %T",
+					propertyName,
+					schemaName,
+					value,
+				)
+			}
+		}
+	}
+}
+
+func TestOpenAPISpecShouldBeServed(t *testing.T) {
+	server := httptest.NewServer(manager.NewController(nil, &configuration.Configuration{}))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/openapi.json")
+	require.Nil(t, err, "should be able to complete the request")
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "application/json", response.Header.Get("Content-Type"))
+
+	body, err := ioutil.ReadAll(response.Body)
+	require.Nil(t, err, "should be able to read the response body")
+	require.Equal(t, manager.OpenAPISpec(), body, "should serve the OpenAPI document")
+}
+
+func loadOpenAPIDocument(t *testing.T) openAPIDocument {
+	var document openAPIDocument
+	err := json.Unmarshal(manager.OpenAPISpec(), &document)
+	require.Nil(t, err, "should be able to parse the OpenAPI document")
+
+	return document
+}
+
+func normalizeRoute(route string) string {
+	route = strings.ReplaceAll(route, "/*/", "/")
+	if len(route) > 1 {
+		route = strings.TrimSuffix(route, "/")
+	}
+
+	return route
+}
+
+// jsonFields gives the JSON field names of a struct along with their
+// types, flattening embedded structs the same way encoding/json does.
+func jsonFields(structType reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embeddedName, embeddedType := range jsonFields(field.Type) {
+				fields[embeddedName] = embeddedType
+			}
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+func openAPIType(fieldType reflect.Type) string {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		return "string"
+
+	case reflect.Bool:
+		return "boolean"
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+
+	case reflect.Float32, reflect.Float64:
+		return "number"
+
+	case reflect.Slice, reflect.Array:
+		return "array"
+
+	default:
+		return "object"
+	}
+}
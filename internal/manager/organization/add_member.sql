@@ -0,0 +1,25 @@
+WITH Added AS (
+    INSERT INTO OrganizationMembers (
+        OrganizationID,
+        UserID,
+        CreatedOn
+    )
+    SELECT
+        $1,
+        ID,
+        $3
+    FROM Users
+    WHERE
+        Username = $2
+    -- Adding a user that is already a member keeps their membership as
+    -- it is but still returns it.
+    ON CONFLICT (OrganizationID, UserID) DO UPDATE SET CreatedOn = OrganizationMembers.CreatedOn
+    RETURNING UserID, CreatedOn
+)
+SELECT
+    Users.ID,
+    Users.Username,
+    Added.CreatedOn
+FROM Added
+JOIN Users ON Users.ID = Added.UserID
+;
@@ -0,0 +1,424 @@
+package organization
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/durandj/ley/internal/manager/conditional"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Controller handles all the HTTP requests for organization related
+// API's.
+type Controller struct {
+	OrganizationService *Service
+}
+
+// RegisterRoutes registers HTTP request handlers for all organization
+// API's.
+func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Get("/", controller.ListOrganizations)
+	router.Post("/", controller.CreateOrganization)
+	router.Get("/{org}", controller.GetOrganization)
+	router.Delete("/{org}", controller.DeleteOrganization)
+	router.Get("/{org}/member", controller.ListMembers)
+	router.Put("/{org}/member/{username}", controller.AddMember)
+	router.Delete("/{org}/member/{username}", controller.RemoveMember)
+}
+
+// CreateOrganizationRequest is the expected request body for creating
+// a new organization.
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+// Bind is used to determine how to map from a request body to an
+// organization creation request.
+func (createOrganizationRequest *CreateOrganizationRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*CreateOrganizationRequest)(nil)
+
+// CreateOrganizationResponse is the response body for a successful
+// organization creation request.
+type CreateOrganizationResponse struct {
+	RenderableOrganization
+}
+
+var _ render.Renderer = (*CreateOrganizationResponse)(nil)
+
+// CreateOrganization handles requests to create a new organization.
+func (controller *Controller) CreateOrganization(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var createOrganizationRequest CreateOrganizationRequest
+	if err := render.Bind(request, &createOrganizationRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	organization, err := controller.OrganizationService.CreateOrganization(
+		ctx,
+		CreateOrganizationOpts(createOrganizationRequest),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	createOrganizationResponse := CreateOrganizationResponse{
+		RenderableOrganization: NewRenderableOrganization(organization),
+	}
+
+	conditional.SetETag(response, organization.Version())
+	response.WriteHeader(http.StatusCreated)
+	_ = render.Render(response, request, &createOrganizationResponse)
+}
+
+// GetOrganizationResponse is the response body for requesting a single
+// organization.
+type GetOrganizationResponse struct {
+	RenderableOrganization
+}
+
+var _ render.Renderer = (*GetOrganizationResponse)(nil)
+
+// GetOrganization handles requests to fetch an organization by name.
+func (controller *Controller) GetOrganization(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	organization, err := controller.OrganizationService.GetOrganizationByName(ctx, chi.URLParam(request, "org"))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	if conditional.NotModified(request, organization.Version()) {
+		conditional.WriteNotModified(response, organization.Version())
+		return
+	}
+
+	getOrganizationResponse := GetOrganizationResponse{
+		RenderableOrganization: NewRenderableOrganization(organization),
+	}
+
+	conditional.SetETag(response, organization.Version())
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &getOrganizationResponse)
+}
+
+// DeleteOrganization handles requests to remove an organization. The
+// If-Match header can be used to make sure nobody else changed the
+// organization first.
+func (controller *Controller) DeleteOrganization(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	err := controller.OrganizationService.DeleteOrganization(
+		ctx,
+		chi.URLParam(request, "org"),
+		conditional.ParseIfMatch(request),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ListOrganizationsResponse is the response for requesting a page of
+// organizations.
+type ListOrganizationsResponse struct {
+	Organizations []RenderableOrganization `json:"organizations"`
+	NextCursor    string                   `json:"nextCursor,omitempty"`
+}
+
+// NewListOrganizationsResponse creates an organization list response.
+func NewListOrganizationsResponse(page listing.Page[Organization]) ListOrganizationsResponse {
+	renderableOrganizations := make([]RenderableOrganization, len(page.Items))
+	for index := range page.Items {
+		renderableOrganizations[index] = NewRenderableOrganization(&page.Items[index])
+	}
+
+	return ListOrganizationsResponse{
+		Organizations: renderableOrganizations,
+		NextCursor:    page.NextCursor,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listOrganizationsResponse *ListOrganizationsResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ListOrganizationsResponse)(nil)
+
+// ListOrganizations handles requests to list organizations, optionally
+// only those that a user is a member of.
+func (controller *Controller) ListOrganizations(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	query := request.URL.Query()
+
+	params, err := listing.ParseParams(query)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	page, err := controller.OrganizationService.ListOrganizations(ctx, params, ListOrganizationsFilter{
+		Member: query.Get("member"),
+	})
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listOrganizationsResponse := NewListOrganizationsResponse(page)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listOrganizationsResponse)
+}
+
+// AddMemberResponse is the response body for adding a user to an
+// organization.
+type AddMemberResponse struct {
+	RenderableMember
+}
+
+var _ render.Renderer = (*AddMemberResponse)(nil)
+
+// AddMember handles requests to make a user a member of an
+// organization. Adding a user that already is a member changes
+// nothing.
+func (controller *Controller) AddMember(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	member, err := controller.OrganizationService.AddMember(
+		ctx,
+		chi.URLParam(request, "org"),
+		chi.URLParam(request, "username"),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	addMemberResponse := AddMemberResponse{
+		RenderableMember: NewRenderableMember(member),
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &addMemberResponse)
+}
+
+// RemoveMember handles requests to take a user out of an organization.
+func (controller *Controller) RemoveMember(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	err := controller.OrganizationService.RemoveMember(
+		ctx,
+		chi.URLParam(request, "org"),
+		chi.URLParam(request, "username"),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ListMembersResponse is the response for requesting a page of the
+// members of an organization.
+type ListMembersResponse struct {
+	Members    []RenderableMember `json:"members"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+}
+
+// NewListMembersResponse creates a member list response.
+func NewListMembersResponse(page listing.Page[Member]) ListMembersResponse {
+	renderableMembers := make([]RenderableMember, len(page.Items))
+	for index := range page.Items {
+		renderableMembers[index] = NewRenderableMember(&page.Items[index])
+	}
+
+	return ListMembersResponse{
+		Members:    renderableMembers,
+		NextCursor: page.NextCursor,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listMembersResponse *ListMembersResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ListMembersResponse)(nil)
+
+// ListMembers handles requests to list the members of an organization.
+func (controller *Controller) ListMembers(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	params, err := listing.ParseParams(request.URL.Query())
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	page, err := controller.OrganizationService.ListMembers(ctx, chi.URLParam(request, "org"), params)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listMembersResponse := NewListMembersResponse(page)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listMembersResponse)
+}
+
+func handleError(
+	response http.ResponseWriter,
+	request *http.Request,
+	err error,
+) {
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
+	var userError errortypes.UserError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &validationError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: validationError.SafeMessage,
+		})
+
+	case errors.As(err, &notFoundError):
+		response.WriteHeader(http.StatusNotFound)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: notFoundError.SafeMessage,
+		})
+
+	case errors.As(err, &preconditionFailedError):
+		response.WriteHeader(http.StatusPreconditionFailed)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: preconditionFailedError.SafeMessage,
+		})
+
+	case errors.As(err, &userError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: userError.SafeMessage,
+		})
+
+	case errors.As(err, &systemError):
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: systemError.SafeMessage,
+		})
+
+	case err != nil:
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: "Internal server error, please try again later",
+		})
+	}
+}
+
+// RenderableOrganization defines what should be returned to a user for
+// an organization.
+type RenderableOrganization struct {
+	Name       string          `json:"name"`
+	Version    int64           `json:"version"`
+	CreatedOn  renderable.Time `json:"createdOn"`
+	ModifiedOn renderable.Time `json:"modifiedOn"`
+}
+
+// NewRenderableOrganization creates a renderable organization from a
+// backend organization instance.
+func NewRenderableOrganization(organization *Organization) RenderableOrganization {
+	return RenderableOrganization{
+		Name:       organization.Name(),
+		Version:    organization.Version(),
+		CreatedOn:  renderable.Time(organization.CreatedOn()),
+		ModifiedOn: renderable.Time(organization.ModifiedOn()),
+	}
+}
+
+// Render provides a hook to customize the render process.
+func (renderableOrganization *RenderableOrganization) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*RenderableOrganization)(nil)
+
+// RenderableMember defines what should be returned to a user for a
+// member of an organization.
+type RenderableMember struct {
+	Username string          `json:"username"`
+	JoinedOn renderable.Time `json:"joinedOn"`
+}
+
+// NewRenderableMember creates a renderable member from a backend
+// member instance.
+func NewRenderableMember(member *Member) RenderableMember {
+	return RenderableMember{
+		Username: member.Username(),
+		JoinedOn: renderable.Time(member.JoinedOn()),
+	}
+}
+
+// Render provides a hook to customize the render process.
+func (renderableMember *RenderableMember) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*RenderableMember)(nil)
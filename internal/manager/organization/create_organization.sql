@@ -0,0 +1,39 @@
+WITH Created AS (
+    INSERT INTO Organizations (
+        ID,
+        Name,
+        CreatedOn,
+        ModifiedOn
+    )
+    VALUES (
+        $1,
+        $2,
+        $3,
+        $3
+    )
+    RETURNING ID, Name, Version, CreatedOn, ModifiedOn
+),
+-- The user creating the organization becomes its first member so they
+-- can still see it afterwards.
+Joined AS (
+    INSERT INTO OrganizationMembers (
+        OrganizationID,
+        UserID,
+        CreatedOn
+    )
+    SELECT
+        Created.ID,
+        $4,
+        $3
+    FROM Created
+    WHERE
+        $4::VARCHAR IS NOT NULL
+)
+SELECT
+    ID,
+    Name,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Created
+;
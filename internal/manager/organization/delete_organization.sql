@@ -0,0 +1,6 @@
+DELETE FROM Organizations
+WHERE
+    Name = $1
+    AND ($2::BIGINT[] IS NULL OR Version = ANY($2))
+RETURNING ID, Name, Version, CreatedOn, ModifiedOn
+;
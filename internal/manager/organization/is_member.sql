@@ -0,0 +1,8 @@
+SELECT EXISTS (
+    SELECT 1
+    FROM OrganizationMembers
+    WHERE
+        OrganizationID = $1
+        AND UserID = $2
+)
+;
@@ -0,0 +1,6 @@
+SELECT
+    Users.ID,
+    Users.Username,
+    OrganizationMembers.CreatedOn
+FROM OrganizationMembers
+JOIN Users ON Users.ID = OrganizationMembers.UserID
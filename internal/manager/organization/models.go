@@ -0,0 +1,74 @@
+package organization
+
+import (
+	"time"
+)
+
+const (
+	// DefaultID is the database ID of the default organization.
+	DefaultID = "default"
+
+	// DefaultName is the name of the default organization. It owns
+	// everything that was created before organizations existed and is
+	// what the routes without an organization use.
+	DefaultName = "default"
+)
+
+// Organization owns networks and has users as members. Names of
+// networks only have to be unique within their organization.
+type Organization struct {
+	id         string
+	name       string
+	version    int64
+	createdOn  time.Time
+	modifiedOn time.Time
+}
+
+// ID is the database ID of the organization.
+func (organization *Organization) ID() string {
+	return organization.id
+}
+
+// Name is the name of the organization.
+func (organization *Organization) Name() string {
+	return organization.name
+}
+
+// Version is incremented every time the organization is changed. It
+// is used to detect conflicting updates.
+func (organization *Organization) Version() int64 {
+	return organization.version
+}
+
+// CreatedOn is the date and time that the organization was created on.
+func (organization *Organization) CreatedOn() time.Time {
+	return organization.createdOn
+}
+
+// ModifiedOn is the date and time that the organization was last
+// modified on.
+func (organization *Organization) ModifiedOn() time.Time {
+	return organization.modifiedOn
+}
+
+// Member is a user that belongs to an organization.
+type Member struct {
+	userID   string
+	username string
+	joinedOn time.Time
+}
+
+// UserID is the database ID of the user.
+func (member *Member) UserID() string {
+	return member.userID
+}
+
+// Username is the username of the user.
+func (member *Member) Username() string {
+	return member.username
+}
+
+// JoinedOn is the date and time that the user joined the organization.
+func (member *Member) JoinedOn() time.Time {
+	return member.joinedOn
+}
@@ -0,0 +1,6 @@
+DELETE FROM OrganizationMembers
+WHERE
+    OrganizationID = $1
+    AND UserID = (SELECT ID FROM Users WHERE Username = $2)
+RETURNING UserID
+;
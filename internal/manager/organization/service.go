@@ -0,0 +1,508 @@
+package organization
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxNameLength = 64
+)
+
+var (
+	organizationNameRegex = regexp.MustCompile(`^\w[-\w]+$`)
+
+	//go:embed create_organization.sql
+	createOrganizationSQL string
+
+	//go:embed get_organization_by_name.sql
+	getOrganizationByNameSQL string
+
+	//go:embed list_organizations.sql
+	listOrganizationsSQL string
+
+	//go:embed delete_organization.sql
+	deleteOrganizationSQL string
+
+	//go:embed add_member.sql
+	addMemberSQL string
+
+	//go:embed remove_member.sql
+	removeMemberSQL string
+
+	//go:embed list_members.sql
+	listMembersSQL string
+
+	//go:embed is_member.sql
+	isMemberSQL string
+
+	listOrganizationsColumns = listing.Columns{
+		ID:        "ID",
+		Name:      "Name",
+		CreatedOn: "CreatedOn",
+	}
+
+	listMembersColumns = listing.Columns{
+		ID:        "Users.ID",
+		Name:      "Users.Username",
+		CreatedOn: "OrganizationMembers.CreatedOn",
+	}
+)
+
+// Service provides methods for working with organizations and their
+// members.
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates a new organization service.
+func NewService(db *sql.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// CreateOrganizationOpts gives the options for creating a new
+// organization.
+type CreateOrganizationOpts struct {
+	Name string
+}
+
+// Validate checks that the organization creation options are valid.
+func (opts *CreateOrganizationOpts) Validate() error {
+	if !organizationNameRegex.MatchString(opts.Name) || len(opts.Name) > maxNameLength {
+		return fmt.Errorf("Invalid organization name '%s'", opts.Name)
+	}
+
+	return nil
+}
+
+// CreateOrganization creates a new organization. When a user creates
+// it they become its first member, otherwise it starts without any
+// members.
+func (service *Service) CreateOrganization(
+	ctx context.Context,
+	opts CreateOrganizationOpts,
+) (*Organization, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to create organization: %v", err)
+	}
+
+	var creatorID *string
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		creatorID = &userID
+	}
+
+	organization, err := scanOrganization(service.db.QueryRowContext(
+		ctx,
+		createOrganizationSQL,
+		uuid.NewString(),
+		opts.Name,
+		time.Now().UTC(),
+		creatorID,
+	))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			errorName := pqErr.Code.Name()
+			constraint := pqErr.Constraint
+			if errorName == "unique_violation" && constraint == "organizations_name_key" {
+				return nil, errortypes.NewValidationError("Organization name is already taken")
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new organization due to a system error",
+			UnsafeMessage: "Unable to create new organization due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return organization, nil
+}
+
+// GetOrganizationByName fetches an organization by its name. Users
+// that aren't members of the organization can't see it, except for the
+// default organization which every user can see. Requests without a
+// user, like the ones made with tokens, can see every organization.
+func (service *Service) GetOrganizationByName(
+	ctx context.Context,
+	name string,
+) (*Organization, error) {
+	organization, err := scanOrganization(service.db.QueryRowContext(ctx, getOrganizationByNameSQL, name))
+	if err == sql.ErrNoRows {
+		return nil, missingOrganizationError(err)
+	}
+
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get organization by name due to a system error",
+			UnsafeMessage: "Unable to get organization by name due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	canSee, err := service.canSee(ctx, organization)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users shouldn't be able to tell apart the organizations they
+	// don't belong to from ones that don't exist.
+	if !canSee {
+		return nil, missingOrganizationError(nil)
+	}
+
+	return organization, nil
+}
+
+// canSee checks if the user making the request, if any, is allowed to
+// see the organization.
+func (service *Service) canSee(ctx context.Context, organization *Organization) (bool, error) {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok || organization.ID() == DefaultID {
+		return true, nil
+	}
+
+	var isMember bool
+	if err := service.db.QueryRowContext(ctx, isMemberSQL, organization.ID(), userID).Scan(&isMember); err != nil {
+		return false, errortypes.SystemError{
+			SafeMessage:   "Unable to get organization by name due to a system error",
+			UnsafeMessage: "Unable to check organization membership",
+			WrappedError:  err,
+		}
+	}
+
+	return isMember, nil
+}
+
+func missingOrganizationError(err error) error {
+	return errortypes.NotFoundError{
+		UserError: errortypes.UserError{
+			SafeMessage:  "Could not find an organization with that name",
+			WrappedError: err,
+		},
+	}
+}
+
+// ListOrganizationsFilter narrows down the organizations that are
+// listed.
+type ListOrganizationsFilter struct {
+	// Member only includes the organizations that the user with this
+	// username belongs to.
+	Member string
+}
+
+// ListOrganizations retrieves a page of organizations. Users only see
+// the organizations they are members of along with the default
+// organization.
+func (service *Service) ListOrganizations(
+	ctx context.Context,
+	params listing.Params,
+	filter ListOrganizationsFilter,
+) (listing.Page[Organization], error) {
+	var conditions []string
+	var args []any
+	if filter.Member != "" {
+		args = append(args, filter.Member)
+		conditions = append(conditions, fmt.Sprintf(
+			"ID IN (SELECT OrganizationID FROM OrganizationMembers WHERE UserID = "+
+				"(SELECT ID FROM Users WHERE Username = $%d))",
+			len(args),
+		))
+	}
+
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		args = append(args, userID)
+		conditions = append(conditions, fmt.Sprintf(
+			"(ID = '%s' OR ID IN (SELECT OrganizationID FROM OrganizationMembers WHERE UserID = $%d))",
+			DefaultID,
+			len(args),
+		))
+	}
+
+	query, args, err := params.SQL(listOrganizationsSQL, listOrganizationsColumns, conditions, args)
+	if err != nil {
+		return listing.Page[Organization]{}, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return listing.Page[Organization]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list organizations due to a system error",
+			UnsafeMessage: "Unable to list organizations due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	organizations := []Organization{}
+	for rows.Next() {
+		organization, err := scanOrganization(rows)
+		if err != nil {
+			return listing.Page[Organization]{}, errortypes.SystemError{
+				SafeMessage:   "Unable to list organizations due to a system error",
+				UnsafeMessage: "Unable to read organization row",
+				WrappedError:  err,
+			}
+		}
+
+		organizations = append(organizations, *organization)
+	}
+
+	if err := rows.Err(); err != nil {
+		return listing.Page[Organization]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list organizations due to a system error",
+			UnsafeMessage: "Unable to iterate over organization rows",
+			WrappedError:  err,
+		}
+	}
+
+	return listing.NewPage(params, organizations, func(organization *Organization) listing.Key {
+		return listing.Key{
+			ID:        organization.ID(),
+			Name:      organization.Name(),
+			CreatedOn: organization.CreatedOn(),
+		}
+	}), nil
+}
+
+// DeleteOrganization removes an organization along with its
+// memberships. Organizations that still own networks can't be removed
+// and neither can the default organization. The delete is limited to
+// the expected versions of the organization unless they are nil.
+func (service *Service) DeleteOrganization(
+	ctx context.Context,
+	name string,
+	expectedVersions []int64,
+) error {
+	if name == DefaultName {
+		return errortypes.NewValidationError("The default organization can't be deleted")
+	}
+
+	if _, err := service.GetOrganizationByName(ctx, name); err != nil {
+		return err
+	}
+
+	_, err := scanOrganization(service.db.QueryRowContext(
+		ctx,
+		deleteOrganizationSQL,
+		name,
+		pq.Array(expectedVersions),
+	))
+	if err == sql.ErrNoRows {
+		return service.explainMissingOrganization(ctx, name)
+	}
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			return errortypes.NewValidationError(
+				"Unable to delete organization: It still has networks, delete them first",
+			)
+		}
+
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete organization due to a system error",
+			UnsafeMessage: "Unable to delete organization due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// AddMember makes a user a member of an organization. Adding a user
+// that already is a member changes nothing.
+func (service *Service) AddMember(
+	ctx context.Context,
+	organizationName string,
+	username string,
+) (*Member, error) {
+	organization, err := service.GetOrganizationByName(ctx, organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	var member Member
+	err = service.db.QueryRowContext(
+		ctx,
+		addMemberSQL,
+		organization.ID(),
+		username,
+		time.Now().UTC(),
+	).Scan(
+		&member.userID,
+		&member.username,
+		&member.joinedOn,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a user with that name",
+					WrappedError: err,
+				},
+			}
+		}
+
+		// The organization was deleted after it was read.
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			return nil, missingOrganizationError(err)
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to add member due to a system error",
+			UnsafeMessage: "Unable to add member due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return &member, nil
+}
+
+// RemoveMember takes a user out of an organization.
+func (service *Service) RemoveMember(
+	ctx context.Context,
+	organizationName string,
+	username string,
+) error {
+	organization, err := service.GetOrganizationByName(ctx, organizationName)
+	if err != nil {
+		return err
+	}
+
+	var userID string
+	err = service.db.QueryRowContext(ctx, removeMemberSQL, organization.ID(), username).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "The user isn't a member of the organization",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to remove member due to a system error",
+			UnsafeMessage: "Unable to remove member due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// ListMembers retrieves a page of the members of an organization.
+// Members are named and sorted by their username and their creation
+// date is when they joined.
+func (service *Service) ListMembers(
+	ctx context.Context,
+	organizationName string,
+	params listing.Params,
+) (listing.Page[Member], error) {
+	organization, err := service.GetOrganizationByName(ctx, organizationName)
+	if err != nil {
+		return listing.Page[Member]{}, err
+	}
+
+	query, args, err := params.SQL(
+		listMembersSQL,
+		listMembersColumns,
+		[]string{"OrganizationMembers.OrganizationID = $1"},
+		[]any{organization.ID()},
+	)
+	if err != nil {
+		return listing.Page[Member]{}, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return listing.Page[Member]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list members due to a system error",
+			UnsafeMessage: "Unable to list members due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	members := []Member{}
+	for rows.Next() {
+		var member Member
+		if err := rows.Scan(&member.userID, &member.username, &member.joinedOn); err != nil {
+			return listing.Page[Member]{}, errortypes.SystemError{
+				SafeMessage:   "Unable to list members due to a system error",
+				UnsafeMessage: "Unable to read member row",
+				WrappedError:  err,
+			}
+		}
+
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return listing.Page[Member]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list members due to a system error",
+			UnsafeMessage: "Unable to iterate over member rows",
+			WrappedError:  err,
+		}
+	}
+
+	return listing.NewPage(params, members, func(member *Member) listing.Key {
+		return listing.Key{
+			ID:        member.UserID(),
+			Name:      member.Username(),
+			CreatedOn: member.JoinedOn(),
+		}
+	}), nil
+}
+
+// explainMissingOrganization figures out why a conditional change
+// didn't match any rows, either because the organization doesn't exist
+// or because it was at a different version.
+func (service *Service) explainMissingOrganization(ctx context.Context, name string) error {
+	if _, err := service.GetOrganizationByName(ctx, name); err != nil {
+		return err
+	}
+
+	return errortypes.PreconditionFailedError{
+		UserError: errortypes.UserError{
+			SafeMessage: "The organization has been changed since it was last read",
+		},
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrganization(row rowScanner) (*Organization, error) {
+	var organization Organization
+	err := row.Scan(
+		&organization.id,
+		&organization.name,
+		&organization.version,
+		&organization.createdOn,
+		&organization.modifiedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &organization, nil
+}
@@ -0,0 +1,178 @@
+package organization_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager/auth"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/organization"
+	"github.com/durandj/ley/internal/manager/user"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var listParams = listing.Params{
+	Limit:     listing.MaxLimit,
+	SortField: listing.SortFieldCreatedOn,
+	SortOrder: listing.SortOrderAscending,
+}
+
+func TestMembersShouldOnlyBelongToTheirOrganizations(t *testing.T) {
+	ctx := context.Background()
+	organizationService, userService := newTestServices(t)
+
+	username := fmt.Sprintf("org-test-%d", rng.RNG.Int63())
+	_, err := userService.CreateUser(ctx, user.CreateUserOpts{Username: username})
+	require.Nil(t, err, "should be able to create a user")
+
+	joined := newTestOrganization(ctx, t, organizationService)
+	other := newTestOrganization(ctx, t, organizationService)
+
+	member, err := organizationService.AddMember(ctx, joined, username)
+	require.Nil(t, err, "should be able to add a member")
+	require.Equal(t, username, member.Username())
+
+	again, err := organizationService.AddMember(ctx, joined, username)
+	require.Nil(t, err, "should be able to add a member twice")
+	require.Equal(t, member.JoinedOn(), again.JoinedOn(), "should not change the membership")
+
+	members, err := organizationService.ListMembers(ctx, joined, listParams)
+	require.Nil(t, err, "should be able to list members")
+	require.Len(t, members.Items, 1)
+	require.Equal(t, username, members.Items[0].Username())
+
+	members, err = organizationService.ListMembers(ctx, other, listParams)
+	require.Nil(t, err, "should be able to list members")
+	require.Empty(t, members.Items, "should not list members of other organizations")
+
+	organizations, err := organizationService.ListOrganizations(ctx, listParams, organization.ListOrganizationsFilter{
+		Member: username,
+	})
+	require.Nil(t, err, "should be able to list organizations")
+	require.Len(t, organizations.Items, 1)
+	require.Equal(t, joined, organizations.Items[0].Name())
+
+	var notFoundError errortypes.NotFoundError
+	err = organizationService.RemoveMember(ctx, other, username)
+	require.True(t, errors.As(err, &notFoundError), "should not remove a member of another organization")
+
+	require.Nil(t, organizationService.RemoveMember(ctx, joined, username), "should be able to remove a member")
+}
+
+func TestUsersShouldOnlySeeTheirOrganizations(t *testing.T) {
+	ctx := context.Background()
+	organizationService, userService := newTestServices(t)
+
+	username := fmt.Sprintf("org-test-%d", rng.RNG.Int63())
+	createdUser, err := userService.CreateUser(ctx, user.CreateUserOpts{Username: username})
+	require.Nil(t, err, "should be able to create a user")
+
+	userCtx := auth.ContextWithPrincipal(ctx, "user:"+createdUser.ID())
+
+	joined := newTestOrganization(userCtx, t, organizationService)
+	other := newTestOrganization(ctx, t, organizationService)
+
+	_, err = organizationService.GetOrganizationByName(userCtx, joined)
+	require.Nil(t, err, "should see an organization the user created")
+
+	_, err = organizationService.GetOrganizationByName(userCtx, organization.DefaultName)
+	require.Nil(t, err, "should see the default organization")
+
+	var notFoundError errortypes.NotFoundError
+	_, err = organizationService.GetOrganizationByName(userCtx, other)
+	require.True(t, errors.As(err, &notFoundError), "should not see an organization the user isn't a member of")
+
+	_, err = organizationService.AddMember(userCtx, other, username)
+	require.True(t, errors.As(err, &notFoundError), "should not join an organization the user isn't a member of")
+
+	_, err = organizationService.ListMembers(userCtx, other, listParams)
+	require.True(t, errors.As(err, &notFoundError), "should not list the members of another organization")
+
+	err = organizationService.DeleteOrganization(userCtx, other, nil)
+	require.True(t, errors.As(err, &notFoundError), "should not delete an organization the user isn't a member of")
+
+	_, err = organizationService.GetOrganizationByName(ctx, other)
+	require.Nil(t, err, "should see every organization without a user")
+
+	organizations, err := organizationService.ListOrganizations(
+		userCtx,
+		listParams,
+		organization.ListOrganizationsFilter{},
+	)
+	require.Nil(t, err, "should be able to list organizations")
+
+	var names []string
+	for _, listedOrganization := range organizations.Items {
+		names = append(names, listedOrganization.Name())
+	}
+
+	require.Contains(t, names, joined)
+	require.Contains(t, names, organization.DefaultName)
+	require.NotContains(t, names, other, "should not list organizations the user isn't a member of")
+}
+
+func TestOrganizationNamesShouldBeUniqueWithoutCaringAboutCase(t *testing.T) {
+	ctx := context.Background()
+	organizationService, _ := newTestServices(t)
+
+	name := newTestOrganization(ctx, t, organizationService)
+
+	_, err := organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{
+		Name: strings.ToUpper(name),
+	})
+
+	var validationError errortypes.ValidationError
+	require.True(t, errors.As(err, &validationError), "should reject a name that only differs in case")
+}
+
+func TestDeleteOrganizationShouldRefuseTheDefaultOrganization(t *testing.T) {
+	organizationService, _ := newTestServices(t)
+
+	err := organizationService.DeleteOrganization(context.Background(), organization.DefaultName, nil)
+
+	var validationError errortypes.ValidationError
+	require.True(t, errors.As(err, &validationError), "should not delete the default organization")
+}
+
+func newTestServices(t *testing.T) (*organization.Service, *user.Service) {
+	dbConfig := configuration.DBConfiguration{
+		Type: configuration.DBTypePostgres,
+		Postgres: configuration.PostgresConfiguration{
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Role:     "ley",
+			Password: "ley",
+			DBName:   "ley",
+			SSLMode:  "disable",
+		},
+	}
+
+	connectionString, err := dbConfig.ConnectionString()
+	require.Nil(t, err, "should be able to create a connection string")
+
+	db, err := sql.Open(string(dbConfig.Type), connectionString)
+	require.Nil(t, err, "should be able to open the database")
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return organization.NewService(db), user.NewService(db)
+}
+
+func newTestOrganization(ctx context.Context, t *testing.T, organizationService *organization.Service) string {
+	name := fmt.Sprintf("org-test-%d", rng.RNG.Int63())
+
+	_, err := organizationService.CreateOrganization(ctx, organization.CreateOrganizationOpts{Name: name})
+	require.Nil(t, err, "should be able to create an organization")
+
+	return name
+}
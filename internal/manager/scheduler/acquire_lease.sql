@@ -0,0 +1,18 @@
+INSERT INTO JobLeases (
+    Name,
+    Holder,
+    ExpiresOn
+)
+VALUES (
+    $1,
+    $2,
+    CURRENT_TIMESTAMP + $3::BIGINT * INTERVAL '1 microsecond'
+)
+ON CONFLICT (Name) DO UPDATE
+SET
+    Holder = EXCLUDED.Holder,
+    ExpiresOn = EXCLUDED.ExpiresOn
+WHERE
+    JobLeases.ExpiresOn <= CURRENT_TIMESTAMP
+RETURNING ExpiresOn
+;
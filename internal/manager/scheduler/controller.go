@@ -0,0 +1,218 @@
+package scheduler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+const defaultRunLimit = 50
+
+// Controller handles all the HTTP requests for the job admin API's.
+type Controller struct {
+	JobStore *Store
+}
+
+// RegisterRoutes registers HTTP request handlers for all job API's.
+func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Get("/", controller.ListJobs)
+	router.Get("/{name}/runs", controller.ListRuns)
+}
+
+// ListJobsResponse is the response for requesting the state of the
+// scheduled jobs.
+type ListJobsResponse struct {
+	Jobs []RenderableJob `json:"jobs"`
+}
+
+// NewListJobsResponse creates a job list response.
+func NewListJobsResponse(jobs []JobState) ListJobsResponse {
+	renderableJobs := make([]RenderableJob, len(jobs))
+	for index := range jobs {
+		renderableJobs[index] = NewRenderableJob(&jobs[index])
+	}
+
+	return ListJobsResponse{
+		Jobs: renderableJobs,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listJobsResponse *ListJobsResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+// ListJobs handles requests for the state of every scheduled job.
+func (controller *Controller) ListJobs(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	jobs, err := controller.JobStore.ListJobs(ctx)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listJobsResponse := NewListJobsResponse(jobs)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listJobsResponse)
+}
+
+// ListRunsResponse is the response for requesting the run history of
+// a job.
+type ListRunsResponse struct {
+	Runs []RenderableRun `json:"runs"`
+}
+
+// NewListRunsResponse creates a job run list response.
+func NewListRunsResponse(runs []Run) ListRunsResponse {
+	renderableRuns := make([]RenderableRun, len(runs))
+	for index := range runs {
+		renderableRuns[index] = NewRenderableRun(&runs[index])
+	}
+
+	return ListRunsResponse{
+		Runs: renderableRuns,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listRunsResponse *ListRunsResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+// ListRuns handles requests for the most recent runs of a job.
+func (controller *Controller) ListRuns(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	limit := defaultRunLimit
+	if rawLimit := request.URL.Query().Get("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			handleError(response, request, errortypes.NewWrappedValidationError(err, "Invalid limit '%s'", rawLimit))
+			return
+		}
+
+		limit = parsedLimit
+	}
+
+	runs, err := controller.JobStore.ListRuns(ctx, chi.URLParam(request, "name"), limit)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listRunsResponse := NewListRunsResponse(runs)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listRunsResponse)
+}
+
+func handleError(
+	response http.ResponseWriter,
+	request *http.Request,
+	err error,
+) {
+	var validationError errortypes.ValidationError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &validationError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: validationError.SafeMessage,
+		})
+
+		return
+
+	case errors.As(err, &systemError):
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: systemError.SafeMessage,
+		})
+
+		return
+
+	case err != nil:
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: "Internal server error, please try again later",
+		})
+
+		return
+	}
+}
+
+// RenderableJob defines what should be returned to a user for a
+// scheduled job.
+type RenderableJob struct {
+	Name           string          `json:"name"`
+	Holder         string          `json:"holder"`
+	LeaseExpiresOn renderable.Time `json:"leaseExpiresOn"`
+	LastRun        *RenderableRun  `json:"lastRun,omitempty"`
+}
+
+// NewRenderableJob creates a new renderable job from the state of a
+// job.
+func NewRenderableJob(job *JobState) RenderableJob {
+	renderableJob := RenderableJob{
+		Name:           job.Name,
+		Holder:         job.Holder,
+		LeaseExpiresOn: renderable.Time(job.LeaseExpiresOn),
+	}
+
+	if job.LastRun != nil {
+		lastRun := NewRenderableRun(job.LastRun)
+		renderableJob.LastRun = &lastRun
+	}
+
+	return renderableJob
+}
+
+// RenderableRun defines what should be returned to a user for a job
+// run.
+type RenderableRun struct {
+	ID         int64            `json:"id"`
+	Job        string           `json:"job"`
+	Holder     string           `json:"holder"`
+	Status     RunStatus        `json:"status"`
+	Error      *string          `json:"error,omitempty"`
+	StartedOn  renderable.Time  `json:"startedOn"`
+	FinishedOn *renderable.Time `json:"finishedOn,omitempty"`
+}
+
+// NewRenderableRun creates a new renderable run from a backend run
+// instance.
+func NewRenderableRun(run *Run) RenderableRun {
+	renderableRun := RenderableRun{
+		ID:        run.ID,
+		Job:       run.JobName,
+		Holder:    run.Holder,
+		Status:    run.Status,
+		Error:     run.Error,
+		StartedOn: renderable.Time(run.StartedOn),
+	}
+
+	if run.FinishedOn != nil {
+		finishedOn := renderable.Time(*run.FinishedOn)
+		renderableRun.FinishedOn = &finishedOn
+	}
+
+	return renderableRun
+}
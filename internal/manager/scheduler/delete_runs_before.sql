@@ -0,0 +1,5 @@
+DELETE FROM JobRuns
+WHERE
+    StartedOn < $1
+    AND Status <> 'running'
+;
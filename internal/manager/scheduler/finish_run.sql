@@ -0,0 +1,8 @@
+UPDATE JobRuns
+SET
+    Status = $2,
+    Error = $3,
+    FinishedOn = $4
+WHERE
+    ID = $1
+;
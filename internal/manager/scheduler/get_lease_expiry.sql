@@ -0,0 +1,6 @@
+SELECT
+    ExpiresOn
+FROM JobLeases
+WHERE
+    Name = $1
+;
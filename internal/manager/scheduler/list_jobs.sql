@@ -0,0 +1,27 @@
+SELECT
+    JobLeases.Name,
+    JobLeases.Holder,
+    JobLeases.ExpiresOn,
+    LastRun.ID,
+    LastRun.Holder,
+    LastRun.Status,
+    LastRun.Error,
+    LastRun.StartedOn,
+    LastRun.FinishedOn
+FROM JobLeases
+LEFT JOIN LATERAL (
+    SELECT
+        JobRuns.ID,
+        JobRuns.Holder,
+        JobRuns.Status,
+        JobRuns.Error,
+        JobRuns.StartedOn,
+        JobRuns.FinishedOn
+    FROM JobRuns
+    WHERE
+        JobRuns.JobName = JobLeases.Name
+    ORDER BY JobRuns.ID DESC
+    LIMIT 1
+) AS LastRun ON TRUE
+ORDER BY JobLeases.Name
+;
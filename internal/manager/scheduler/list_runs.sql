@@ -0,0 +1,13 @@
+SELECT
+    ID,
+    Holder,
+    Status,
+    Error,
+    StartedOn,
+    FinishedOn
+FROM JobRuns
+WHERE
+    JobName = $1
+ORDER BY ID DESC
+LIMIT $2
+;
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// Job is a named piece of work that runs periodically on exactly one
+// manager replica at a time.
+type Job struct {
+	// Name identifies the job across replicas.
+	Name string
+
+	// Interval is how long to wait between runs.
+	Interval time.Duration
+
+	// Run does the work. The returned error is recorded in the job's
+	// run history.
+	Run func(ctx context.Context) error
+}
+
+// RunStatus tells how a job run went.
+type RunStatus string
+
+const (
+	// RunStatusRunning means the run hasn't finished yet, or the
+	// replica running it went away.
+	RunStatusRunning RunStatus = "running"
+
+	// RunStatusSucceeded means the job finished without an error.
+	RunStatusSucceeded RunStatus = "succeeded"
+
+	// RunStatusFailed means the job returned an error or panicked.
+	RunStatusFailed RunStatus = "failed"
+)
+
+// Run is a single run of a job.
+type Run struct {
+	ID         int64
+	JobName    string
+	Holder     string
+	Status     RunStatus
+	Error      *string
+	StartedOn  time.Time
+	FinishedOn *time.Time
+}
+
+// JobState is what is known about a job across every replica.
+type JobState struct {
+	Name string
+
+	// Holder is the replica that last ran the job.
+	Holder string
+
+	// LeaseExpiresOn is when the job can run again.
+	LeaseExpiresOn time.Time
+
+	// LastRun is nil until the job has run.
+	LastRun *Run
+}
@@ -0,0 +1,8 @@
+UPDATE JobLeases
+SET
+    ExpiresOn = GREATEST(ExpiresOn, CURRENT_TIMESTAMP + $3::BIGINT * INTERVAL '1 microsecond')
+WHERE
+    Name = $1
+    AND Holder = $2
+RETURNING ExpiresOn
+;
@@ -0,0 +1,250 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// JobStore is where job leases and run history are kept.
+type JobStore interface {
+	AcquireLease(ctx context.Context, jobName string, holder string, duration time.Duration) (bool, time.Time, error)
+	RenewLease(ctx context.Context, jobName string, holder string, duration time.Duration) (bool, error)
+	StartRun(ctx context.Context, jobName string, holder string, startedOn time.Time) (int64, error)
+	FinishRun(ctx context.Context, id int64, finishedOn time.Time, runErr error) error
+}
+
+var _ JobStore = (*Store)(nil)
+
+// Scheduler runs periodic jobs. Every replica registers the same jobs
+// and a job only runs on the replica that takes its lease, which lasts
+// for the job's interval. The lease is renewed while the job runs so
+// that a slow run can't overlap with the next one.
+type Scheduler struct {
+	store  JobStore
+	logger *zap.Logger
+	holder string
+	jobs   []*scheduledJob
+
+	// mutex guards whether each job is running.
+	mutex sync.Mutex
+
+	// inFlight tracks the runs that haven't finished yet so that
+	// shutting down can wait for them.
+	inFlight sync.WaitGroup
+}
+
+type scheduledJob struct {
+	Job
+
+	// nextCheck is when the lease might be free again. It saves asking
+	// the store about jobs that can't run yet.
+	nextCheck time.Time
+
+	// running is set while a run of the job is in flight on this
+	// replica.
+	running bool
+}
+
+// New creates a scheduler that identifies itself as the holder when
+// taking leases.
+func New(store JobStore, logger *zap.Logger, holder string) *Scheduler {
+	return &Scheduler{
+		store:  store,
+		logger: logger,
+		holder: holder,
+	}
+}
+
+// DefaultHolder names the current replica after its host name with a
+// random suffix so that restarts aren't mistaken for the same process.
+func DefaultHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "manager"
+	}
+
+	return fmt.Sprintf("%s-%s", hostname, uuid.NewString()[:8])
+}
+
+// Register adds a job to the scheduler. Jobs have to be registered
+// before the scheduler starts running.
+func (scheduler *Scheduler) Register(job Job) error {
+	if job.Name == "" {
+		return fmt.Errorf("Jobs must have a name")
+	}
+
+	if job.Interval <= 0 {
+		return fmt.Errorf("Job '%s' must have a positive interval", job.Name)
+	}
+
+	for _, registeredJob := range scheduler.jobs {
+		if registeredJob.Name == job.Name {
+			return fmt.Errorf("Job '%s' is already registered", job.Name)
+		}
+	}
+
+	scheduler.jobs = append(scheduler.jobs, &scheduledJob{Job: job})
+
+	return nil
+}
+
+// Run checks for jobs that are due every poll interval until the
+// context is done. It then waits for the runs that are still in flight
+// to finish.
+func (scheduler *Scheduler) Run(ctx context.Context, pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		scheduler.RunDue(ctx, time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			scheduler.Wait()
+
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue starts every job whose lease this replica is able to take and
+// returns without waiting for them. Jobs whose lease was last seen
+// running past the given time aren't checked, and neither are jobs
+// that are still running here from an earlier call so that a slow run
+// doesn't hold up the others or overlap with itself.
+func (scheduler *Scheduler) RunDue(ctx context.Context, now time.Time) {
+	for _, job := range scheduler.jobs {
+		if now.Before(job.nextCheck) || scheduler.isRunning(job) {
+			continue
+		}
+
+		acquired, expiresOn, err := scheduler.store.AcquireLease(ctx, job.Name, scheduler.holder, job.Interval)
+		if err != nil {
+			scheduler.logger.Error("Unable to acquire job lease", zap.String("job", job.Name), zap.Error(err))
+			continue
+		}
+
+		job.nextCheck = expiresOn
+		if !acquired {
+			continue
+		}
+
+		scheduler.setRunning(job, true)
+		scheduler.inFlight.Add(1)
+		go func(job *scheduledJob) {
+			defer scheduler.inFlight.Done()
+			defer scheduler.setRunning(job, false)
+
+			scheduler.runJob(ctx, job.Job)
+		}(job)
+	}
+}
+
+// Wait blocks until every run that was started has finished. It is
+// meant for shutting down, once the context given to the runs is done.
+func (scheduler *Scheduler) Wait() {
+	scheduler.inFlight.Wait()
+}
+
+func (scheduler *Scheduler) isRunning(job *scheduledJob) bool {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	return job.running
+}
+
+func (scheduler *Scheduler) setRunning(job *scheduledJob, running bool) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	job.running = running
+}
+
+func (scheduler *Scheduler) runJob(ctx context.Context, job Job) {
+	logger := scheduler.logger.With(zap.String("job", job.Name))
+
+	runID, err := scheduler.store.StartRun(ctx, job.Name, scheduler.holder, time.Now().UTC())
+	if err != nil {
+		logger.Error("Unable to record job run", zap.Error(err))
+		return
+	}
+
+	stopRenewing := scheduler.renewLease(ctx, job, logger)
+	runErr := runSafely(ctx, job)
+	stopRenewing()
+
+	if runErr != nil {
+		logger.Error("Job failed", zap.Error(runErr))
+	}
+
+	if err := scheduler.store.FinishRun(ctx, runID, time.Now().UTC(), runErr); err != nil {
+		logger.Error("Unable to record job result", zap.Error(err))
+	}
+}
+
+// renewLease keeps renewing the lease of a running job every half
+// interval, until the returned function is called.
+func (scheduler *Scheduler) renewLease(ctx context.Context, job Job, logger *zap.Logger) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		renewEvery := job.Interval / 2
+		if renewEvery <= 0 {
+			renewEvery = job.Interval
+		}
+
+		ticker := time.NewTicker(renewEvery)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+			}
+
+			held, err := scheduler.store.RenewLease(ctx, job.Name, scheduler.holder, job.Interval)
+			if err != nil {
+				logger.Error("Unable to renew job lease", zap.Error(err))
+				continue
+			}
+
+			if !held {
+				logger.Warn("Lost job lease while running")
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// runSafely turns a panicking job into a failed run instead of taking
+// the whole manager down.
+func runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("Job panicked: %v", recovered)
+		}
+	}()
+
+	return job.Run(ctx)
+}
@@ -0,0 +1,331 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/scheduler"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var startOfTest = time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+func TestSchedulerShouldOnlyRunAJobOnOneReplica(t *testing.T) {
+	store := newMemoryStore()
+	runs := 0
+	job := scheduler.Job{
+		Name:     "test",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			runs++
+			return nil
+		},
+	}
+
+	first := newTestScheduler(t, store, "first", job)
+	second := newTestScheduler(t, store, "second", job)
+
+	first.RunDue(context.Background(), startOfTest)
+	second.RunDue(context.Background(), startOfTest)
+	first.Wait()
+	second.Wait()
+	require.Equal(t, 1, runs, "should only run the job on the replica holding the lease")
+
+	store.setNow(startOfTest.Add(30 * time.Second))
+	second.RunDue(context.Background(), startOfTest.Add(30*time.Second))
+	second.Wait()
+	require.Equal(t, 1, runs, "should not run the job before the lease runs out")
+
+	store.setNow(startOfTest.Add(time.Minute))
+	second.RunDue(context.Background(), startOfTest.Add(time.Minute))
+	second.Wait()
+	require.Equal(t, 2, runs, "should run the job once the lease runs out")
+	require.Equal(t, "second", store.runs[1].Holder)
+}
+
+func TestSchedulerShouldHoldTheLeaseWhileAJobRuns(t *testing.T) {
+	store := newMemoryStore()
+	job := scheduler.Job{
+		Name:     "slow",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			deadline := time.Now().Add(5 * time.Second)
+			for store.renewalCount() < 2 {
+				if time.Now().After(deadline) {
+					return errors.New("lease was never renewed")
+				}
+
+				time.Sleep(time.Millisecond)
+			}
+
+			return nil
+		},
+	}
+
+	first := newTestScheduler(t, store, "first", job)
+	first.RunDue(context.Background(), startOfTest)
+	first.Wait()
+
+	require.Len(t, store.runs, 1)
+	require.Equal(t, scheduler.RunStatusSucceeded, store.runs[0].Status, "should renew the lease while running")
+
+	renewals := store.renewalCount()
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, renewals, store.renewalCount(), "should stop renewing once the job finished")
+}
+
+func TestSchedulerShouldRecordFailedRuns(t *testing.T) {
+	store := newMemoryStore()
+	jobScheduler := newTestScheduler(
+		t,
+		store,
+		"first",
+		scheduler.Job{
+			Name:     "fails",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				return errors.New("failed")
+			},
+		},
+		scheduler.Job{
+			Name:     "panics",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				panic("oops")
+			},
+		},
+		scheduler.Job{
+			Name:     "succeeds",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				return nil
+			},
+		},
+	)
+
+	jobScheduler.RunDue(context.Background(), startOfTest)
+	jobScheduler.Wait()
+
+	statuses := map[string]scheduler.RunStatus{}
+	for _, run := range store.runs {
+		statuses[run.JobName] = run.Status
+	}
+
+	require.Equal(
+		t,
+		map[string]scheduler.RunStatus{
+			"fails":    scheduler.RunStatusFailed,
+			"panics":   scheduler.RunStatusFailed,
+			"succeeds": scheduler.RunStatusSucceeded,
+		},
+		statuses,
+	)
+}
+
+func TestSchedulerShouldNotWaitForRunsOrOverlapThem(t *testing.T) {
+	store := newMemoryStore()
+	release := make(chan struct{})
+	slowStarted := make(chan struct{}, 2)
+	fastRuns := make(chan struct{}, 2)
+	jobScheduler := newTestScheduler(
+		t,
+		store,
+		"first",
+		scheduler.Job{
+			Name:     "slow",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				slowStarted <- struct{}{}
+				<-release
+
+				return nil
+			},
+		},
+		scheduler.Job{
+			Name:     "fast",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				fastRuns <- struct{}{}
+
+				return nil
+			},
+		},
+	)
+
+	jobScheduler.RunDue(context.Background(), startOfTest)
+	<-slowStarted
+	<-fastRuns
+
+	// The lease of the slow job runs out while it is still running,
+	// like when renewing it failed, but it must not start again.
+	store.setNow(startOfTest.Add(time.Minute))
+	deadline := time.After(5 * time.Second)
+	for ranAgain := false; !ranAgain; {
+		jobScheduler.RunDue(context.Background(), startOfTest.Add(time.Minute))
+
+		select {
+		case <-fastRuns:
+			ranAgain = true
+
+		case <-time.After(time.Millisecond):
+
+		case <-deadline:
+			t.Fatal("should run the fast job again while the slow one is running")
+		}
+	}
+	require.Len(t, slowStarted, 0, "should not start a job that is still running")
+
+	close(release)
+	jobScheduler.Wait()
+	require.Len(t, store.runs, 3, "should have run the fast job while the slow one was running")
+}
+
+func TestSchedulerShouldRejectInvalidJobs(t *testing.T) {
+	jobScheduler := scheduler.New(newMemoryStore(), zap.NewNop(), "first")
+	noop := func(ctx context.Context) error { return nil }
+
+	require.Error(t, jobScheduler.Register(scheduler.Job{Interval: time.Minute, Run: noop}), "should require a name")
+	require.Error(t, jobScheduler.Register(scheduler.Job{Name: "test", Run: noop}), "should require an interval")
+	require.NoError(t, jobScheduler.Register(scheduler.Job{Name: "test", Interval: time.Minute, Run: noop}))
+	require.Error(
+		t,
+		jobScheduler.Register(scheduler.Job{Name: "test", Interval: time.Minute, Run: noop}),
+		"should reject duplicate names",
+	)
+}
+
+func newTestScheduler(
+	t *testing.T,
+	store *memoryStore,
+	holder string,
+	jobs ...scheduler.Job,
+) *scheduler.Scheduler {
+	jobScheduler := scheduler.New(store, zap.NewNop(), holder)
+	for _, job := range jobs {
+		require.NoError(t, jobScheduler.Register(job))
+	}
+
+	return jobScheduler
+}
+
+type memoryLease struct {
+	holder    string
+	expiresOn time.Time
+}
+
+// memoryStore keeps leases in memory. Its clock stands in for the
+// database's and only moves when a test moves it.
+type memoryStore struct {
+	lock     sync.Mutex
+	now      time.Time
+	leases   map[string]memoryLease
+	runs     []scheduler.Run
+	renewals int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		now:    startOfTest,
+		leases: map[string]memoryLease{},
+	}
+}
+
+func (store *memoryStore) AcquireLease(
+	ctx context.Context,
+	jobName string,
+	holder string,
+	duration time.Duration,
+) (bool, time.Time, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if lease, ok := store.leases[jobName]; ok && store.now.Before(lease.expiresOn) {
+		return false, lease.expiresOn, nil
+	}
+
+	expiresOn := store.now.Add(duration)
+	store.leases[jobName] = memoryLease{holder: holder, expiresOn: expiresOn}
+
+	return true, expiresOn, nil
+}
+
+func (store *memoryStore) RenewLease(
+	ctx context.Context,
+	jobName string,
+	holder string,
+	duration time.Duration,
+) (bool, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	lease, ok := store.leases[jobName]
+	if !ok || lease.holder != holder {
+		return false, nil
+	}
+
+	store.renewals++
+	if expiresOn := store.now.Add(duration); expiresOn.After(lease.expiresOn) {
+		store.leases[jobName] = memoryLease{holder: holder, expiresOn: expiresOn}
+	}
+
+	return true, nil
+}
+
+func (store *memoryStore) setNow(now time.Time) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.now = now
+}
+
+func (store *memoryStore) renewalCount() int {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.renewals
+}
+
+func (store *memoryStore) StartRun(
+	ctx context.Context,
+	jobName string,
+	holder string,
+	startedOn time.Time,
+) (int64, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.runs = append(store.runs, scheduler.Run{
+		ID:        int64(len(store.runs) + 1),
+		JobName:   jobName,
+		Holder:    holder,
+		Status:    scheduler.RunStatusRunning,
+		StartedOn: startedOn,
+	})
+
+	return int64(len(store.runs)), nil
+}
+
+func (store *memoryStore) FinishRun(
+	ctx context.Context,
+	id int64,
+	finishedOn time.Time,
+	runErr error,
+) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	run := &store.runs[id-1]
+	run.FinishedOn = &finishedOn
+	run.Status = scheduler.RunStatusSucceeded
+	if runErr != nil {
+		message := runErr.Error()
+		run.Status = scheduler.RunStatusFailed
+		run.Error = &message
+	}
+
+	return nil
+}
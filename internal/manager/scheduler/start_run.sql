@@ -0,0 +1,14 @@
+INSERT INTO JobRuns (
+    JobName,
+    Holder,
+    Status,
+    StartedOn
+)
+VALUES (
+    $1,
+    $2,
+    'running',
+    $3
+)
+RETURNING ID
+;
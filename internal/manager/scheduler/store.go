@@ -0,0 +1,295 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+)
+
+// maxRunLimit is the most runs that are returned at once.
+const maxRunLimit = 500
+
+var (
+	//go:embed acquire_lease.sql
+	acquireLeaseSQL string
+
+	//go:embed renew_lease.sql
+	renewLeaseSQL string
+
+	//go:embed get_lease_expiry.sql
+	getLeaseExpirySQL string
+
+	//go:embed start_run.sql
+	startRunSQL string
+
+	//go:embed finish_run.sql
+	finishRunSQL string
+
+	//go:embed list_jobs.sql
+	listJobsSQL string
+
+	//go:embed list_runs.sql
+	listRunsSQL string
+
+	//go:embed delete_runs_before.sql
+	deleteRunsBeforeSQL string
+)
+
+// Store keeps the job leases and run history in the database so that
+// every replica sees the same state.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a new job store.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// AcquireLease attempts to take the lease of a job for the given
+// duration. A lease can only be taken once the previous one has run
+// out. The database's clock decides both, so replicas whose clocks
+// drift apart still agree on who holds a lease. When the lease is held
+// by someone else false is returned along with when their lease runs
+// out.
+func (store *Store) AcquireLease(
+	ctx context.Context,
+	jobName string,
+	holder string,
+	duration time.Duration,
+) (bool, time.Time, error) {
+	var expiresOn time.Time
+	err := store.db.QueryRowContext(ctx, acquireLeaseSQL, jobName, holder, duration.Microseconds()).Scan(&expiresOn)
+	if err == nil {
+		return true, expiresOn, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, time.Time{}, fmt.Errorf("Unable to acquire lease for job '%s': %w", jobName, err)
+	}
+
+	if err := store.db.QueryRowContext(ctx, getLeaseExpirySQL, jobName).Scan(&expiresOn); err != nil {
+		return false, time.Time{}, fmt.Errorf("Unable to get lease for job '%s': %w", jobName, err)
+	}
+
+	return false, expiresOn, nil
+}
+
+// RenewLease makes a lease that the holder still has last for at least
+// the given duration from now. False is returned when the lease was
+// lost to someone else.
+func (store *Store) RenewLease(
+	ctx context.Context,
+	jobName string,
+	holder string,
+	duration time.Duration,
+) (bool, error) {
+	var expiresOn time.Time
+	err := store.db.QueryRowContext(ctx, renewLeaseSQL, jobName, holder, duration.Microseconds()).Scan(&expiresOn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Unable to renew lease for job '%s': %w", jobName, err)
+	}
+
+	return true, nil
+}
+
+// StartRun records that a job started running and returns the ID of
+// the run.
+func (store *Store) StartRun(
+	ctx context.Context,
+	jobName string,
+	holder string,
+	startedOn time.Time,
+) (int64, error) {
+	var id int64
+	if err := store.db.QueryRowContext(ctx, startRunSQL, jobName, holder, startedOn).Scan(&id); err != nil {
+		return 0, fmt.Errorf("Unable to record start of job '%s': %w", jobName, err)
+	}
+
+	return id, nil
+}
+
+// FinishRun records how a run went.
+func (store *Store) FinishRun(
+	ctx context.Context,
+	id int64,
+	finishedOn time.Time,
+	runErr error,
+) error {
+	status := RunStatusSucceeded
+	var errorMessage *string
+	if runErr != nil {
+		status = RunStatusFailed
+		message := runErr.Error()
+		errorMessage = &message
+	}
+
+	if _, err := store.db.ExecContext(ctx, finishRunSQL, id, status, errorMessage, finishedOn); err != nil {
+		return fmt.Errorf("Unable to record end of job run %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// DeleteRunsBefore removes the history of finished runs that started
+// before the cutoff, returning how many were removed.
+func (store *Store) DeleteRunsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := store.db.ExecContext(ctx, deleteRunsBeforeSQL, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to delete job runs: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Unable to count deleted job runs: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// ListJobs gives the state of every job that has ever been scheduled
+// by any replica.
+func (store *Store) ListJobs(ctx context.Context) ([]JobState, error) {
+	rows, err := store.db.QueryContext(ctx, listJobsSQL)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list jobs due to a system error",
+			UnsafeMessage: "Unable to list jobs due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	jobs := []JobState{}
+	for rows.Next() {
+		var job JobState
+		var runID sql.NullInt64
+		var runHolder sql.NullString
+		var runStatus sql.NullString
+		var runError sql.NullString
+		var runStartedOn sql.NullTime
+		var runFinishedOn sql.NullTime
+		err := rows.Scan(
+			&job.Name,
+			&job.Holder,
+			&job.LeaseExpiresOn,
+			&runID,
+			&runHolder,
+			&runStatus,
+			&runError,
+			&runStartedOn,
+			&runFinishedOn,
+		)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to list jobs due to a system error",
+				UnsafeMessage: "Unable to read job row",
+				WrappedError:  err,
+			}
+		}
+
+		if runID.Valid {
+			job.LastRun = &Run{
+				ID:         runID.Int64,
+				JobName:    job.Name,
+				Holder:     runHolder.String,
+				Status:     RunStatus(runStatus.String),
+				Error:      nullStringToPointer(runError),
+				StartedOn:  runStartedOn.Time,
+				FinishedOn: nullTimeToPointer(runFinishedOn),
+			}
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list jobs due to a system error",
+			UnsafeMessage: "Unable to iterate over job rows",
+			WrappedError:  err,
+		}
+	}
+
+	return jobs, nil
+}
+
+// ListRuns gives the most recent runs of a job, newest first.
+func (store *Store) ListRuns(ctx context.Context, jobName string, limit int) ([]Run, error) {
+	if limit < 1 || limit > maxRunLimit {
+		return nil, errortypes.NewValidationError("Run limit must be between 1 and %d", maxRunLimit)
+	}
+
+	rows, err := store.db.QueryContext(ctx, listRunsSQL, jobName, limit)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list job runs due to a system error",
+			UnsafeMessage: "Unable to list job runs due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	runs := []Run{}
+	for rows.Next() {
+		run := Run{JobName: jobName}
+		var runError sql.NullString
+		var finishedOn sql.NullTime
+		err := rows.Scan(&run.ID, &run.Holder, &run.Status, &runError, &run.StartedOn, &finishedOn)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to list job runs due to a system error",
+				UnsafeMessage: "Unable to read job run row",
+				WrappedError:  err,
+			}
+		}
+
+		run.Error = nullStringToPointer(runError)
+		run.FinishedOn = nullTimeToPointer(finishedOn)
+
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list job runs due to a system error",
+			UnsafeMessage: "Unable to iterate over job run rows",
+			WrappedError:  err,
+		}
+	}
+
+	return runs, nil
+}
+
+func nullStringToPointer(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.String
+}
+
+func nullTimeToPointer(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
+}
@@ -0,0 +1,447 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/go-chi/chi/v5"
+)
+
+// ContentType is the media type of SCIM requests and responses.
+const ContentType = "application/scim+json"
+
+// Controller handles all the HTTP requests of the SCIM API that
+// identity providers use to provision users and groups.
+type Controller struct {
+	SCIMService *Service
+}
+
+// RegisterRoutes registers HTTP request handlers for all SCIM API's.
+// Every request needs the provisioning client's bearer token.
+func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Use(controller.authenticate)
+
+	router.Get("/Users", controller.ListUsers)
+	router.Post("/Users", controller.CreateUser)
+	router.Get("/Users/{id}", controller.GetUser)
+	router.Patch("/Users/{id}", controller.PatchUser)
+	router.Delete("/Users/{id}", controller.DeleteUser)
+
+	router.Get("/Groups", controller.ListGroups)
+	router.Post("/Groups", controller.CreateGroup)
+	router.Get("/Groups/{id}", controller.GetGroup)
+	router.Patch("/Groups/{id}", controller.PatchGroup)
+	router.Delete("/Groups/{id}", controller.DeleteGroup)
+}
+
+func (controller *Controller) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		authorization := request.Header.Get("Authorization")
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if token == authorization {
+			token = ""
+		}
+
+		if err := controller.SCIMService.Authenticate(token); err != nil {
+			handleError(response, err)
+			return
+		}
+
+		next.ServeHTTP(response, request)
+	})
+}
+
+// ListUsers handles requests to list users, optionally filtered by
+// their userName.
+func (controller *Controller) ListUsers(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	filter, startIndex, count, err := parseListQuery(request)
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	users, err := controller.SCIMService.ListUsers(request.Context(), filter)
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	total := len(users)
+	users = paginate(users, startIndex, count)
+
+	listUsersResponse := ListUsersResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(users),
+		Resources:    make([]UserResource, 0, len(users)),
+	}
+	for index := range users {
+		listUsersResponse.Resources = append(
+			listUsersResponse.Resources,
+			NewUserResource(&users[index], resourceURL(request, "Users", users[index].ID())),
+		)
+	}
+
+	writeResponse(response, http.StatusOK, &listUsersResponse)
+}
+
+// CreateUser handles requests to create a user.
+func (controller *Controller) CreateUser(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	var createUserRequest CreateUserRequest
+	if err := decodeRequest(request, &createUserRequest); err != nil {
+		handleError(response, err)
+		return
+	}
+
+	opts := CreateUserOpts{
+		UserName:    createUserRequest.UserName,
+		DisplayName: createUserRequest.DisplayName,
+		Email:       primaryEmail(createUserRequest.Emails),
+		ExternalID:  createUserRequest.ExternalID,
+		Active:      createUserRequest.Active == nil || *createUserRequest.Active,
+	}
+
+	createdUser, err := controller.SCIMService.CreateUser(request.Context(), opts)
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	location := resourceURL(request, "Users", createdUser.ID())
+	userResource := NewUserResource(createdUser, location)
+
+	response.Header().Set("Location", location)
+	writeResponse(response, http.StatusCreated, &userResource)
+}
+
+// GetUser handles requests to get a user by their ID.
+func (controller *Controller) GetUser(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	existingUser, err := controller.SCIMService.GetUser(request.Context(), chi.URLParam(request, "id"))
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	userResource := NewUserResource(existingUser, resourceURL(request, "Users", existingUser.ID()))
+	writeResponse(response, http.StatusOK, &userResource)
+}
+
+// PatchUser handles requests to change a user.
+func (controller *Controller) PatchUser(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	var patchRequest PatchRequest
+	if err := decodeRequest(request, &patchRequest); err != nil {
+		handleError(response, err)
+		return
+	}
+
+	patchedUser, err := controller.SCIMService.PatchUser(
+		request.Context(),
+		chi.URLParam(request, "id"),
+		patchRequest.Operations,
+	)
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	userResource := NewUserResource(patchedUser, resourceURL(request, "Users", patchedUser.ID()))
+	writeResponse(response, http.StatusOK, &userResource)
+}
+
+// DeleteUser handles requests to delete a user.
+func (controller *Controller) DeleteUser(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	if err := controller.SCIMService.DeleteUser(request.Context(), chi.URLParam(request, "id")); err != nil {
+		handleError(response, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ListGroups handles requests to list groups, optionally filtered by
+// their displayName. Members are left out when they're excluded with
+// the excludedAttributes query parameter.
+func (controller *Controller) ListGroups(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	filter, startIndex, count, err := parseListQuery(request)
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	withMembers := true
+	for _, attribute := range strings.Split(request.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(attributeName(strings.TrimSpace(attribute)), "members") {
+			withMembers = false
+		}
+	}
+
+	groups, err := controller.SCIMService.ListGroups(request.Context(), filter, withMembers)
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	total := len(groups)
+	groups = paginate(groups, startIndex, count)
+
+	listGroupsResponse := ListGroupsResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(groups),
+		Resources:    make([]GroupResource, 0, len(groups)),
+	}
+	for index := range groups {
+		listGroupsResponse.Resources = append(
+			listGroupsResponse.Resources,
+			NewGroupResource(&groups[index], resourceURL(request, "Groups", groups[index].Group().ID())),
+		)
+	}
+
+	writeResponse(response, http.StatusOK, &listGroupsResponse)
+}
+
+// CreateGroup handles requests to create a group.
+func (controller *Controller) CreateGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	var createGroupRequest CreateGroupRequest
+	if err := decodeRequest(request, &createGroupRequest); err != nil {
+		handleError(response, err)
+		return
+	}
+
+	opts := CreateGroupOpts{
+		DisplayName: createGroupRequest.DisplayName,
+	}
+	for _, member := range createGroupRequest.Members {
+		opts.MemberIDs = append(opts.MemberIDs, member.Value)
+	}
+
+	createdGroup, err := controller.SCIMService.CreateGroup(request.Context(), opts)
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	location := resourceURL(request, "Groups", createdGroup.Group().ID())
+	groupResource := NewGroupResource(createdGroup, location)
+
+	response.Header().Set("Location", location)
+	writeResponse(response, http.StatusCreated, &groupResource)
+}
+
+// GetGroup handles requests to get a group by its ID.
+func (controller *Controller) GetGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	existingGroup, err := controller.SCIMService.GetGroup(request.Context(), chi.URLParam(request, "id"))
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	groupResource := NewGroupResource(existingGroup, resourceURL(request, "Groups", existingGroup.Group().ID()))
+	writeResponse(response, http.StatusOK, &groupResource)
+}
+
+// PatchGroup handles requests to change the members of a group.
+func (controller *Controller) PatchGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	var patchRequest PatchRequest
+	if err := decodeRequest(request, &patchRequest); err != nil {
+		handleError(response, err)
+		return
+	}
+
+	patchedGroup, err := controller.SCIMService.PatchGroup(
+		request.Context(),
+		chi.URLParam(request, "id"),
+		patchRequest.Operations,
+	)
+	if err != nil {
+		handleError(response, err)
+		return
+	}
+
+	groupResource := NewGroupResource(patchedGroup, resourceURL(request, "Groups", patchedGroup.Group().ID()))
+	writeResponse(response, http.StatusOK, &groupResource)
+}
+
+// DeleteGroup handles requests to delete a group.
+func (controller *Controller) DeleteGroup(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	if err := controller.SCIMService.DeleteGroup(request.Context(), chi.URLParam(request, "id")); err != nil {
+		handleError(response, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// parseListQuery reads the filter and the 1-based page of results that
+// a list request asks for. Without a count every result from the start
+// index on is returned.
+func parseListQuery(request *http.Request) (*Filter, int, int, error) {
+	query := request.URL.Query()
+
+	var filter *Filter
+	if rawFilter := query.Get("filter"); rawFilter != "" {
+		var err error
+		if filter, err = ParseFilter(rawFilter); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	startIndex := 1
+	if rawStartIndex := query.Get("startIndex"); rawStartIndex != "" {
+		parsedStartIndex, err := strconv.Atoi(rawStartIndex)
+		if err != nil {
+			return nil, 0, 0, invalidValueError("Query parameter 'startIndex' must be a number")
+		}
+
+		// Start indexes below 1 are treated as 1.
+		if parsedStartIndex > 1 {
+			startIndex = parsedStartIndex
+		}
+	}
+
+	count := -1
+	if rawCount := query.Get("count"); rawCount != "" {
+		parsedCount, err := strconv.Atoi(rawCount)
+		if err != nil {
+			return nil, 0, 0, invalidValueError("Query parameter 'count' must be a number")
+		}
+
+		// Negative counts are treated as 0.
+		count = 0
+		if parsedCount > 0 {
+			count = parsedCount
+		}
+	}
+
+	return filter, startIndex, count, nil
+}
+
+// paginate gives the items of a page, where a negative count means
+// every item from the start index on.
+func paginate[Item any](items []Item, startIndex int, count int) []Item {
+	if startIndex > len(items) {
+		return items[:0]
+	}
+
+	items = items[startIndex-1:]
+	if count >= 0 && count < len(items) {
+		items = items[:count]
+	}
+
+	return items
+}
+
+// resourceURL gives the URL of a resource, based on the URL that the
+// request was made to.
+func resourceURL(request *http.Request, resourceType string, id string) string {
+	scheme := "http"
+	if request.TLS != nil || request.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	basePath, _, _ := strings.Cut(request.URL.Path, "/"+resourceType)
+
+	return scheme + "://" + request.Host + basePath + "/" + resourceType + "/" + id
+}
+
+func decodeRequest(request *http.Request, body any) error {
+	if err := json.NewDecoder(request.Body).Decode(body); err != nil {
+		return Error{
+			UserError: errortypes.UserError{
+				SafeMessage:  "The request body must be a JSON object",
+				WrappedError: err,
+			},
+			Status: http.StatusBadRequest,
+			Type:   "invalidSyntax",
+		}
+	}
+
+	return nil
+}
+
+func writeResponse(response http.ResponseWriter, statusCode int, body any) {
+	response.Header().Set("Content-Type", ContentType)
+	response.WriteHeader(statusCode)
+	_ = json.NewEncoder(response).Encode(body)
+}
+
+func handleError(response http.ResponseWriter, err error) {
+	var scimError Error
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
+	var unauthorizedError errortypes.UnauthorizedError
+	var userError errortypes.UserError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &scimError):
+		writeError(response, scimError.Status, scimError.Type, scimError.SafeMessage)
+
+	case errors.As(err, &validationError):
+		writeError(response, http.StatusBadRequest, "invalidValue", validationError.SafeMessage)
+
+	case errors.As(err, &notFoundError):
+		writeError(response, http.StatusNotFound, "", notFoundError.SafeMessage)
+
+	case errors.As(err, &preconditionFailedError):
+		writeError(response, http.StatusPreconditionFailed, "", preconditionFailedError.SafeMessage)
+
+	case errors.As(err, &unauthorizedError):
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(response, http.StatusUnauthorized, "", unauthorizedError.SafeMessage)
+
+	case errors.As(err, &userError):
+		writeError(response, http.StatusBadRequest, "", userError.SafeMessage)
+
+	case errors.As(err, &systemError):
+		writeError(response, http.StatusInternalServerError, "", systemError.SafeMessage)
+
+	case err != nil:
+		writeError(response, http.StatusInternalServerError, "", "Internal server error, please try again later")
+	}
+}
+
+func writeError(response http.ResponseWriter, statusCode int, scimType string, detail string) {
+	writeResponse(response, statusCode, &ErrorResponse{
+		Schemas:  []string{ErrorSchema},
+		Status:   strconv.Itoa(statusCode),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
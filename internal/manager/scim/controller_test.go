@@ -0,0 +1,93 @@
+package scim_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/scim"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "provisioning-token"
+
+func TestControllerShouldRequireTheProvisioningToken(t *testing.T) {
+	testCases := map[string]struct {
+		config         configuration.SCIMConfiguration
+		authorization  string
+		expectedStatus int
+	}{
+		"not configured": {
+			authorization:  "Bearer " + testToken,
+			expectedStatus: http.StatusNotFound,
+		},
+		"missing token": {
+			config:         configuration.SCIMConfiguration{Token: testToken},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"wrong token": {
+			config:         configuration.SCIMConfiguration{Token: testToken},
+			authorization:  "Bearer another-token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"not a bearer token": {
+			config:         configuration.SCIMConfiguration{Token: testToken},
+			authorization:  testToken,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			router := newTestRouter(testCase.config)
+
+			request := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+			request.Header.Set("Authorization", testCase.authorization)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			require.Equal(t, testCase.expectedStatus, recorder.Code)
+			require.Equal(t, scim.ContentType, recorder.Header().Get("Content-Type"))
+
+			errorResponse := decodeError(t, recorder)
+			require.Equal(t, []string{scim.ErrorSchema}, errorResponse.Schemas)
+		})
+	}
+}
+
+func TestControllerShouldRejectUnsupportedFilters(t *testing.T) {
+	router := newTestRouter(configuration.SCIMConfiguration{Token: testToken})
+
+	request := httptest.NewRequest(http.MethodGet, `/scim/v2/Users?filter=userName+co+"ali"`, nil)
+	request.Header.Set("Authorization", "Bearer "+testToken)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	errorResponse := decodeError(t, recorder)
+	require.Equal(t, "400", errorResponse.Status)
+	require.Equal(t, "invalidFilter", errorResponse.ScimType)
+}
+
+func newTestRouter(config configuration.SCIMConfiguration) chi.Router {
+	controller := scim.Controller{
+		SCIMService: scim.NewService(nil, nil, config),
+	}
+
+	router := chi.NewRouter()
+	router.Route("/scim/v2", controller.RegisterRoutes)
+
+	return router
+}
+
+func decodeError(t *testing.T, recorder *httptest.ResponseRecorder) scim.ErrorResponse {
+	var errorResponse scim.ErrorResponse
+	err := json.NewDecoder(recorder.Body).Decode(&errorResponse)
+	require.Nil(t, err, "should be able to decode the error response")
+
+	return errorResponse
+}
@@ -0,0 +1,66 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Filter is a SCIM filter that compares an attribute with a value.
+// This is the only kind of filter that provisioning clients need to
+// find the resources they manage.
+type Filter struct {
+	Attribute string
+	Value     string
+}
+
+// ParseFilter reads a filter in the form `attribute eq "value"`.
+// Attributes may be given with the URN of their schema and are matched
+// without regard to case, like SCIM requires.
+func ParseFilter(filter string) (*Filter, error) {
+	attribute, rest, _ := strings.Cut(strings.TrimSpace(filter), " ")
+	operator, rawValue, _ := strings.Cut(strings.TrimSpace(rest), " ")
+
+	if attribute == "" || !strings.EqualFold(operator, "eq") {
+		return nil, invalidFilterError(filter)
+	}
+
+	var value string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(rawValue)), &value); err != nil {
+		return nil, invalidFilterError(filter)
+	}
+
+	return &Filter{
+		Attribute: attributeName(attribute),
+		Value:     value,
+	}, nil
+}
+
+// Is tells whether the filter is on the given attribute.
+func (filter *Filter) Is(attribute string) bool {
+	return strings.EqualFold(filter.Attribute, attribute)
+}
+
+// attributeName strips the URN of the schema from an attribute,
+// leaving any value filter of the attribute as it is.
+func attributeName(attribute string) string {
+	name := attribute
+	if index := strings.Index(attribute, "["); index >= 0 {
+		name = attribute[:index]
+	}
+
+	if index := strings.LastIndex(name, ":"); index >= 0 {
+		return attribute[index+1:]
+	}
+
+	return attribute
+}
+
+func invalidFilterError(filter string) Error {
+	return newError(
+		http.StatusBadRequest,
+		"invalidFilter",
+		"Unsupported filter '%s', only filters like 'attribute eq \"value\"' are supported",
+		filter,
+	)
+}
@@ -0,0 +1,52 @@
+package scim_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/scim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilterShouldReadEqualityFilters(t *testing.T) {
+	testCases := map[string]scim.Filter{
+		`userName eq "alice"`:     {Attribute: "userName", Value: "alice"},
+		`  userName  EQ "alice" `: {Attribute: "userName", Value: "alice"},
+		`displayName eq "a \"quoted\" name"`: {
+			Attribute: "displayName",
+			Value:     `a "quoted" name`,
+		},
+		`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bob"`: {
+			Attribute: "userName",
+			Value:     "bob",
+		},
+	}
+
+	for rawFilter, expected := range testCases {
+		filter, err := scim.ParseFilter(rawFilter)
+		require.Nil(t, err, "should be able to parse '%s'", rawFilter)
+		require.Equal(t, expected, *filter)
+	}
+
+	filter, err := scim.ParseFilter(`USERNAME eq "alice"`)
+	require.Nil(t, err, "should be able to parse the filter")
+	require.True(t, filter.Is("userName"), "should match attributes without regard to case")
+}
+
+func TestParseFilterShouldRejectUnsupportedFilters(t *testing.T) {
+	for _, rawFilter := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName eq alice`,
+		`userName co "ali"`,
+		`userName eq "alice" and active eq true`,
+		`userName pr`,
+	} {
+		_, err := scim.ParseFilter(rawFilter)
+
+		var scimError scim.Error
+		require.True(t, errors.As(err, &scimError), "should reject '%s'", rawFilter)
+		require.Equal(t, "invalidFilter", scimError.Type)
+	}
+}
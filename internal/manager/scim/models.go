@@ -0,0 +1,247 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/durandj/ley/internal/manager/conditional"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/group"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/durandj/ley/internal/manager/user"
+)
+
+const (
+	// UserSchema identifies user resources.
+	UserSchema = "urn:ietf:params:scim:schemas:core:2.0:User"
+
+	// GroupSchema identifies group resources.
+	GroupSchema = "urn:ietf:params:scim:schemas:core:2.0:Group"
+
+	// ListResponseSchema identifies responses that list resources.
+	ListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+
+	// PatchOpSchema identifies requests that patch a resource.
+	PatchOpSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+
+	// ErrorSchema identifies error responses.
+	ErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// Error is an error that is reported to SCIM clients with the status
+// and the scimType that they use to tell errors apart.
+type Error struct {
+	errortypes.UserError
+
+	Status int
+
+	// Type is the scimType of the error, like uniqueness for resources
+	// that already exist.
+	Type string
+}
+
+var _ error = (*Error)(nil)
+
+func newError(status int, scimType string, message string, values ...any) Error {
+	return Error{
+		UserError: errortypes.UserError{
+			SafeMessage: fmt.Sprintf(message, values...),
+		},
+		Status: status,
+		Type:   scimType,
+	}
+}
+
+func invalidValueError(message string, values ...any) Error {
+	return newError(http.StatusBadRequest, "invalidValue", message, values...)
+}
+
+// Group is a group along with the users and groups that were added
+// to it directly.
+type Group struct {
+	group   *group.Group
+	members []group.Member
+}
+
+// Group is the group itself.
+func (scimGroup *Group) Group() *group.Group {
+	return scimGroup.group
+}
+
+// Members are the users and groups that were added to the group
+// directly. They are nil when they weren't asked for.
+func (scimGroup *Group) Members() []group.Member {
+	return scimGroup.members
+}
+
+// Meta describes how a resource was changed over time.
+type Meta struct {
+	ResourceType string          `json:"resourceType"`
+	Created      renderable.Time `json:"created"`
+	LastModified renderable.Time `json:"lastModified"`
+	Version      string          `json:"version"`
+	Location     string          `json:"location"`
+}
+
+// UserResource is a user as it is given to SCIM clients.
+type UserResource struct {
+	Schemas     []string       `json:"schemas"`
+	ID          string         `json:"id"`
+	ExternalID  string         `json:"externalId,omitempty"`
+	UserName    string         `json:"userName"`
+	DisplayName string         `json:"displayName,omitempty"`
+	Emails      []EmailAddress `json:"emails,omitempty"`
+	Active      bool           `json:"active"`
+	Meta        Meta           `json:"meta"`
+}
+
+// NewUserResource creates a user resource that can be found at the
+// given location.
+func NewUserResource(provisionedUser *user.User, location string) UserResource {
+	var emails []EmailAddress
+	if provisionedUser.Email() != "" {
+		emails = []EmailAddress{{Value: provisionedUser.Email(), Primary: true}}
+	}
+
+	return UserResource{
+		Schemas:     []string{UserSchema},
+		ID:          provisionedUser.ID(),
+		ExternalID:  provisionedUser.ExternalID(),
+		UserName:    provisionedUser.Username(),
+		DisplayName: provisionedUser.DisplayName(),
+		Emails:      emails,
+		Active:      provisionedUser.Status() == user.StatusActive,
+		Meta: Meta{
+			ResourceType: "User",
+			Created:      renderable.Time(provisionedUser.CreatedOn()),
+			LastModified: renderable.Time(provisionedUser.ModifiedOn()),
+			Version:      "W/" + conditional.ETag(provisionedUser.Version()),
+			Location:     location,
+		},
+	}
+}
+
+// EmailAddress is one of the email addresses of a user. Users only
+// keep a single address, which is always the primary one.
+type EmailAddress struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// MemberReference points to a member of a group by its ID.
+type MemberReference struct {
+	Value string `json:"value"`
+
+	// Display is the username of the user or the name of the group.
+	Display string `json:"display,omitempty"`
+
+	// Type is either User or Group.
+	Type string `json:"type,omitempty"`
+}
+
+// GroupResource is a group as it is given to SCIM clients.
+type GroupResource struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id"`
+	DisplayName string            `json:"displayName"`
+	Members     []MemberReference `json:"members,omitempty"`
+	Meta        Meta              `json:"meta"`
+}
+
+// NewGroupResource creates a group resource that can be found at the
+// given location.
+func NewGroupResource(scimGroup *Group, location string) GroupResource {
+	var members []MemberReference
+	for index := range scimGroup.members {
+		member := &scimGroup.members[index]
+
+		memberType := "User"
+		if member.Kind() == group.MemberKindGroup {
+			memberType = "Group"
+		}
+
+		members = append(members, MemberReference{
+			Value:   member.ID(),
+			Display: member.Name(),
+			Type:    memberType,
+		})
+	}
+
+	return GroupResource{
+		Schemas:     []string{GroupSchema},
+		ID:          scimGroup.group.ID(),
+		DisplayName: scimGroup.group.Name(),
+		Members:     members,
+		Meta: Meta{
+			ResourceType: "Group",
+			Created:      renderable.Time(scimGroup.group.CreatedOn()),
+			LastModified: renderable.Time(scimGroup.group.ModifiedOn()),
+			Version:      "W/" + conditional.ETag(scimGroup.group.Version()),
+			Location:     location,
+		},
+	}
+}
+
+// CreateUserRequest is the request body for creating a user.
+// Attributes that aren't kept for users are ignored.
+type CreateUserRequest struct {
+	Schemas     []string       `json:"schemas"`
+	ExternalID  string         `json:"externalId,omitempty"`
+	UserName    string         `json:"userName"`
+	DisplayName string         `json:"displayName,omitempty"`
+	Emails      []EmailAddress `json:"emails,omitempty"`
+
+	// Active creates the user deactivated when it is false. Users are
+	// active when it is left out.
+	Active *bool `json:"active,omitempty"`
+}
+
+// CreateGroupRequest is the request body for creating a group.
+type CreateGroupRequest struct {
+	Schemas     []string          `json:"schemas"`
+	DisplayName string            `json:"displayName"`
+	Members     []MemberReference `json:"members,omitempty"`
+}
+
+// PatchRequest is the request body for changing a resource.
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is a single change to a resource. The value is
+// applied to the attribute at the path or, without a path, it is an
+// object of the attributes to change.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ListUsersResponse is the response body for listing users.
+type ListUsersResponse struct {
+	Schemas      []string       `json:"schemas"`
+	TotalResults int            `json:"totalResults"`
+	StartIndex   int            `json:"startIndex"`
+	ItemsPerPage int            `json:"itemsPerPage"`
+	Resources    []UserResource `json:"Resources"`
+}
+
+// ListGroupsResponse is the response body for listing groups.
+type ListGroupsResponse struct {
+	Schemas      []string        `json:"schemas"`
+	TotalResults int             `json:"totalResults"`
+	StartIndex   int             `json:"startIndex"`
+	ItemsPerPage int             `json:"itemsPerPage"`
+	Resources    []GroupResource `json:"Resources"`
+}
+
+// ErrorResponse is the response body for requests that failed.
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
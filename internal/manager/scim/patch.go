@@ -0,0 +1,159 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchOpAdd     = "add"
+	patchOpReplace = "replace"
+	patchOpRemove  = "remove"
+)
+
+// op gives the kind of change that the operation makes, which clients
+// don't all write in the same case.
+func (operation *PatchOperation) op() (string, error) {
+	switch op := strings.ToLower(operation.Op); op {
+	case patchOpAdd, patchOpReplace, patchOpRemove:
+		return op, nil
+
+	default:
+		return "", newError(
+			http.StatusBadRequest,
+			"invalidSyntax",
+			"Unsupported patch operation '%s'",
+			operation.Op,
+		)
+	}
+}
+
+// attributes gives the attributes that the operation changes along
+// with their new values, keyed by their lower case names.
+func (operation *PatchOperation) attributes() (map[string]json.RawMessage, error) {
+	if operation.Path != "" {
+		return map[string]json.RawMessage{
+			strings.ToLower(attributeName(operation.Path)): operation.Value,
+		}, nil
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(operation.Value, &values); err != nil {
+		return nil, invalidValueError("Patch operations without a path need an object as their value")
+	}
+
+	attributes := make(map[string]json.RawMessage, len(values))
+	for name, value := range values {
+		attributes[strings.ToLower(attributeName(name))] = value
+	}
+
+	return attributes, nil
+}
+
+// memberFilter gives the ID of the member that a path like
+// `members[value eq "ID"]` points to.
+func memberFilter(path string) (string, bool, error) {
+	path = attributeName(path)
+	if !strings.HasPrefix(strings.ToLower(path), "members[") || !strings.HasSuffix(path, "]") {
+		return "", false, nil
+	}
+
+	filter, err := ParseFilter(path[len("members[") : len(path)-1])
+	if err != nil {
+		return "", false, err
+	}
+
+	if !filter.Is("value") {
+		return "", false, newError(http.StatusBadRequest, "invalidPath", "Members can only be selected by their value")
+	}
+
+	return filter.Value, true, nil
+}
+
+// parseActive reads the active attribute, which some clients send as
+// a string.
+func parseActive(value json.RawMessage) (bool, error) {
+	var active bool
+	if err := json.Unmarshal(value, &active); err == nil {
+		return active, nil
+	}
+
+	var rawActive string
+	if err := json.Unmarshal(value, &rawActive); err == nil {
+		if active, err := strconv.ParseBool(rawActive); err == nil {
+			return active, nil
+		}
+	}
+
+	return false, invalidValueError("The active attribute must be a boolean")
+}
+
+// parseString reads an attribute that holds a string.
+func parseString(value json.RawMessage, attribute string) (string, error) {
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return "", invalidValueError("The %s attribute must be a string", attribute)
+	}
+
+	return text, nil
+}
+
+// isEmailValuePath tells whether a path like `emails[type eq
+// "work"].value` points to the address of one of the user's emails.
+// Users only keep one address so the filter itself is ignored.
+func isEmailValuePath(name string) bool {
+	return strings.HasPrefix(name, "emails[") && strings.HasSuffix(name, "].value")
+}
+
+// parseEmails reads the address to keep from a list of emails.
+func parseEmails(value json.RawMessage) (string, error) {
+	var emails []EmailAddress
+	if err := json.Unmarshal(value, &emails); err != nil {
+		return "", invalidValueError("Emails must be a list of objects with a value")
+	}
+
+	return primaryEmail(emails), nil
+}
+
+// primaryEmail picks the address that is marked as primary or else
+// the first one.
+func primaryEmail(emails []EmailAddress) string {
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+
+	if len(emails) == 0 {
+		return ""
+	}
+
+	return emails[0].Value
+}
+
+// parseMembers reads the IDs of the members in a list of member
+// references. A single reference is accepted as well.
+func parseMembers(value json.RawMessage) ([]string, error) {
+	var references []MemberReference
+	if err := json.Unmarshal(value, &references); err != nil {
+		var reference MemberReference
+		if err := json.Unmarshal(value, &reference); err != nil {
+			return nil, invalidValueError("Members must be a list of objects with a value")
+		}
+
+		references = []MemberReference{reference}
+	}
+
+	memberIDs := make([]string, 0, len(references))
+	for _, reference := range references {
+		if reference.Value == "" {
+			return nil, invalidValueError("Members must be a list of objects with a value")
+		}
+
+		memberIDs = append(memberIDs, reference.Value)
+	}
+
+	return memberIDs, nil
+}
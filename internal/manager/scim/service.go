@@ -0,0 +1,685 @@
+package scim
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/group"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/user"
+)
+
+// Service provisions users and groups on behalf of an identity
+// provider. Resources are identified by their database IDs, which
+// unlike names are stable for their whole life.
+type Service struct {
+	userService  *user.Service
+	groupService *group.Service
+	config       configuration.SCIMConfiguration
+}
+
+// NewService creates a new SCIM service.
+func NewService(
+	userService *user.Service,
+	groupService *group.Service,
+	config configuration.SCIMConfiguration,
+) *Service {
+	return &Service{
+		userService:  userService,
+		groupService: groupService,
+		config:       config,
+	}
+}
+
+// Authenticate checks that a bearer token is the one for the
+// provisioning client.
+func (service *Service) Authenticate(token string) error {
+	if !service.config.Enabled() {
+		return errortypes.NotFoundError{
+			UserError: errortypes.UserError{
+				SafeMessage: "SCIM provisioning isn't configured",
+			},
+		}
+	}
+
+	// Comparing hashes keeps the length of the token from leaking.
+	tokenHash := sha256.Sum256([]byte(token))
+	expectedHash := sha256.Sum256([]byte(service.config.Token))
+	if token == "" || subtle.ConstantTimeCompare(tokenHash[:], expectedHash[:]) != 1 {
+		return errortypes.UnauthorizedError{
+			UserError: errortypes.UserError{
+				SafeMessage: "A valid SCIM bearer token is required",
+			},
+		}
+	}
+
+	return nil
+}
+
+// CreateUserOpts gives the options for creating a user.
+type CreateUserOpts struct {
+	UserName    string
+	DisplayName string
+	Email       string
+	ExternalID  string
+	Active      bool
+}
+
+// CreateUser creates a user, deactivating them straight away if they
+// aren't active. Email addresses come from the identity provider, which
+// vouches for them, so they are marked as verified.
+func (service *Service) CreateUser(ctx context.Context, opts CreateUserOpts) (*user.User, error) {
+	createdUser, err := service.userService.CreateUser(ctx, user.CreateUserOpts{
+		Username:      opts.UserName,
+		DisplayName:   opts.DisplayName,
+		Email:         opts.Email,
+		EmailVerified: opts.Email != "",
+		ExternalID:    opts.ExternalID,
+	})
+	if err != nil {
+		return nil, explainConflict(err)
+	}
+
+	if opts.Active {
+		return createdUser, nil
+	}
+
+	return service.setStatus(ctx, createdUser, user.StatusDeactivated)
+}
+
+// GetUser fetches a user by their ID.
+func (service *Service) GetUser(ctx context.Context, id string) (*user.User, error) {
+	return service.userService.GetUserByID(ctx, id)
+}
+
+// ListUsers gives every user, sorted by when they were created, or
+// only the ones that match a filter on their userName or externalId.
+func (service *Service) ListUsers(ctx context.Context, filter *Filter) ([]user.User, error) {
+	if filter == nil {
+		return listAll(func(params listing.Params) (listing.Page[user.User], error) {
+			return service.userService.ListUsers(ctx, params)
+		})
+	}
+
+	var matchingUser *user.User
+	var err error
+	switch {
+	case filter.Is("userName"):
+		matchingUser, err = service.userService.GetUserByUsername(ctx, filter.Value)
+
+	case filter.Is("externalId"):
+		matchingUser, err = service.userService.GetUserByExternalID(ctx, filter.Value)
+
+	default:
+		return nil, newError(
+			http.StatusBadRequest,
+			"invalidFilter",
+			"Users can only be filtered by userName or externalId",
+		)
+	}
+
+	if err != nil {
+		var notFoundError errortypes.NotFoundError
+		if errors.As(err, &notFoundError) {
+			return []user.User{}, nil
+		}
+
+		return nil, err
+	}
+
+	return []user.User{*matchingUser}, nil
+}
+
+// PatchUser applies changes to a user. Setting active to false
+// deactivates the user and setting it to true activates them again.
+// A new email address is verified like those of new users. Usernames
+// can't be changed and attributes that aren't kept for users are
+// ignored.
+func (service *Service) PatchUser(
+	ctx context.Context,
+	id string,
+	operations []PatchOperation,
+) (*user.User, error) {
+	existingUser, err := service.userService.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := existingUser.Status()
+	displayName := existingUser.DisplayName()
+	email := existingUser.Email()
+	externalID := existingUser.ExternalID()
+	for index := range operations {
+		operation := &operations[index]
+
+		op, err := operation.op()
+		if err != nil {
+			return nil, err
+		}
+
+		if op == patchOpRemove {
+			switch name := strings.ToLower(attributeName(operation.Path)); {
+			case name == "displayname":
+				displayName = ""
+
+			case name == "emails" || isEmailValuePath(name):
+				email = ""
+
+			case name == "externalid":
+				externalID = ""
+			}
+
+			continue
+		}
+
+		attributes, err := operation.attributes()
+		if err != nil {
+			return nil, err
+		}
+
+		for name, value := range attributes {
+			switch {
+			case name == "active":
+				active, err := parseActive(value)
+				if err != nil {
+					return nil, err
+				}
+
+				status = user.StatusDeactivated
+				if active {
+					status = user.StatusActive
+				}
+
+			case name == "username":
+				var userName string
+				if err := json.Unmarshal(value, &userName); err != nil || userName != existingUser.Username() {
+					return nil, newError(http.StatusBadRequest, "mutability", "The userName of a user can't be changed")
+				}
+
+			case name == "displayname":
+				if displayName, err = parseString(value, "displayName"); err != nil {
+					return nil, err
+				}
+
+			case name == "emails":
+				if email, err = parseEmails(value); err != nil {
+					return nil, err
+				}
+
+			case isEmailValuePath(name):
+				if email, err = parseString(value, "email"); err != nil {
+					return nil, err
+				}
+
+			case name == "externalid":
+				if externalID, err = parseString(value, "externalId"); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	opts := user.UpdateUserOpts{
+		ExpectedVersions: []int64{existingUser.Version()},
+	}
+
+	changed := false
+	if status != existingUser.Status() {
+		opts.Status = &status
+		changed = true
+	}
+
+	if displayName != existingUser.DisplayName() {
+		opts.DisplayName = &displayName
+		changed = true
+	}
+
+	if email != existingUser.Email() {
+		emailVerified := email != ""
+		opts.Email = &email
+		opts.EmailVerified = &emailVerified
+		changed = true
+	}
+
+	if externalID != existingUser.ExternalID() {
+		opts.ExternalID = &externalID
+		changed = true
+	}
+
+	if !changed {
+		return existingUser, nil
+	}
+
+	updatedUser, err := service.userService.UpdateUser(ctx, existingUser.Username(), opts)
+	if err != nil {
+		return nil, explainConflict(err)
+	}
+
+	return updatedUser, nil
+}
+
+// DeleteUser removes a user.
+func (service *Service) DeleteUser(ctx context.Context, id string) error {
+	existingUser, err := service.userService.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return service.userService.DeleteUser(ctx, existingUser.Username(), []int64{existingUser.Version()})
+}
+
+func (service *Service) setStatus(
+	ctx context.Context,
+	existingUser *user.User,
+	status user.Status,
+) (*user.User, error) {
+	return service.userService.UpdateUser(ctx, existingUser.Username(), user.UpdateUserOpts{
+		Status:           &status,
+		ExpectedVersions: []int64{existingUser.Version()},
+	})
+}
+
+// explainConflict turns a clash with another user into a SCIM
+// uniqueness error.
+func explainConflict(err error) error {
+	var validationError errortypes.ValidationError
+	if !errors.As(err, &validationError) {
+		return err
+	}
+
+	switch validationError.SafeMessage {
+	case "Username is already taken":
+		return newError(http.StatusConflict, "uniqueness", "A user with that userName already exists")
+
+	case "Email address is already used by another user":
+		return newError(http.StatusConflict, "uniqueness", "A user with that email already exists")
+
+	case "External ID is already used by another user":
+		return newError(http.StatusConflict, "uniqueness", "A user with that externalId already exists")
+
+	default:
+		return err
+	}
+}
+
+// CreateGroupOpts gives the options for creating a group.
+type CreateGroupOpts struct {
+	DisplayName string
+
+	// MemberIDs are the IDs of the users and groups to add to the new
+	// group.
+	MemberIDs []string
+}
+
+// CreateGroup creates a group with the given members. Every member has
+// to exist before the group is created.
+func (service *Service) CreateGroup(ctx context.Context, opts CreateGroupOpts) (*Group, error) {
+	members := make([]resolvedMember, 0, len(opts.MemberIDs))
+	for _, memberID := range opts.MemberIDs {
+		member, err := service.resolveMember(ctx, memberID)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	createdGroup, err := service.groupService.CreateGroup(ctx, group.CreateGroupOpts{Name: opts.DisplayName})
+	if err != nil {
+		var validationError errortypes.ValidationError
+		if errors.As(err, &validationError) && validationError.SafeMessage == "Group name is already taken" {
+			return nil, newError(http.StatusConflict, "uniqueness", "A group with that displayName already exists")
+		}
+
+		return nil, err
+	}
+
+	for index := range members {
+		if err := service.addMember(ctx, createdGroup, members[index]); err != nil {
+			return nil, err
+		}
+	}
+
+	return service.GetGroup(ctx, createdGroup.ID())
+}
+
+// GetGroup fetches a group by its ID along with its members.
+func (service *Service) GetGroup(ctx context.Context, id string) (*Group, error) {
+	existingGroup, err := service.groupService.GetGroupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := service.listMembers(ctx, existingGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Group{
+		group:   existingGroup,
+		members: members,
+	}, nil
+}
+
+// ListGroups gives every group, sorted by when they were created, or
+// only the ones that match a filter on their displayName. Members are
+// left out unless they're asked for since they take a query per group.
+func (service *Service) ListGroups(ctx context.Context, filter *Filter, withMembers bool) ([]Group, error) {
+	var groups []group.Group
+	switch {
+	case filter == nil:
+		var err error
+		groups, err = listAll(func(params listing.Params) (listing.Page[group.Group], error) {
+			return service.groupService.ListGroups(ctx, params)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+	case filter.Is("displayName"):
+		matchingGroup, err := service.groupService.GetGroupByName(ctx, filter.Value)
+		if err != nil {
+			var notFoundError errortypes.NotFoundError
+			if errors.As(err, &notFoundError) {
+				return []Group{}, nil
+			}
+
+			return nil, err
+		}
+
+		groups = []group.Group{*matchingGroup}
+
+	default:
+		return nil, newError(http.StatusBadRequest, "invalidFilter", "Groups can only be filtered by displayName")
+	}
+
+	scimGroups := make([]Group, 0, len(groups))
+	for index := range groups {
+		scimGroup := Group{group: &groups[index]}
+		if withMembers {
+			members, err := service.listMembers(ctx, scimGroup.group)
+			if err != nil {
+				return nil, err
+			}
+
+			scimGroup.members = members
+		}
+
+		scimGroups = append(scimGroups, scimGroup)
+	}
+
+	return scimGroups, nil
+}
+
+// PatchGroup applies changes to the members of a group. Members can be
+// added, removed by their ID or replaced all at once. Groups can't be
+// renamed.
+func (service *Service) PatchGroup(ctx context.Context, id string, operations []PatchOperation) (*Group, error) {
+	existingGroup, err := service.groupService.GetGroupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for index := range operations {
+		if err := service.patchGroup(ctx, existingGroup, &operations[index]); err != nil {
+			return nil, err
+		}
+	}
+
+	return service.GetGroup(ctx, existingGroup.ID())
+}
+
+func (service *Service) patchGroup(ctx context.Context, existingGroup *group.Group, operation *PatchOperation) error {
+	op, err := operation.op()
+	if err != nil {
+		return err
+	}
+
+	if op == patchOpRemove {
+		memberID, ok, err := memberFilter(operation.Path)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return service.removeMembers(ctx, existingGroup, []string{memberID})
+		}
+
+		if !strings.EqualFold(attributeName(operation.Path), "members") {
+			return newError(http.StatusBadRequest, "invalidPath", "Only members can be removed from a group")
+		}
+
+		// Removing members without saying which removes all of them.
+		if len(operation.Value) == 0 || string(operation.Value) == "null" {
+			return service.replaceMembers(ctx, existingGroup, []string{})
+		}
+
+		memberIDs, err := parseMembers(operation.Value)
+		if err != nil {
+			return err
+		}
+
+		return service.removeMembers(ctx, existingGroup, memberIDs)
+	}
+
+	attributes, err := operation.attributes()
+	if err != nil {
+		return err
+	}
+
+	if value, ok := attributes["displayname"]; ok {
+		var displayName string
+		if err := json.Unmarshal(value, &displayName); err != nil || displayName != existingGroup.Name() {
+			return newError(http.StatusBadRequest, "mutability", "The displayName of a group can't be changed")
+		}
+	}
+
+	value, ok := attributes["members"]
+	if !ok {
+		return nil
+	}
+
+	memberIDs, err := parseMembers(value)
+	if err != nil {
+		return err
+	}
+
+	if op == patchOpReplace {
+		return service.replaceMembers(ctx, existingGroup, memberIDs)
+	}
+
+	for _, memberID := range memberIDs {
+		member, err := service.resolveMember(ctx, memberID)
+		if err != nil {
+			return err
+		}
+
+		if err := service.addMember(ctx, existingGroup, member); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteGroup removes a group.
+func (service *Service) DeleteGroup(ctx context.Context, id string) error {
+	existingGroup, err := service.groupService.GetGroupByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return service.groupService.DeleteGroup(ctx, existingGroup.Name(), []int64{existingGroup.Version()})
+}
+
+// replaceMembers makes the group's members exactly the ones with the
+// given IDs.
+func (service *Service) replaceMembers(ctx context.Context, existingGroup *group.Group, memberIDs []string) error {
+	wanted := map[string]bool{}
+	for _, memberID := range memberIDs {
+		wanted[memberID] = true
+	}
+
+	members, err := service.listMembers(ctx, existingGroup)
+	if err != nil {
+		return err
+	}
+
+	current := map[string]bool{}
+	for index := range members {
+		member := &members[index]
+		current[member.ID()] = true
+
+		if !wanted[member.ID()] {
+			if err := service.removeMember(ctx, existingGroup, member); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, memberID := range memberIDs {
+		if current[memberID] {
+			continue
+		}
+
+		member, err := service.resolveMember(ctx, memberID)
+		if err != nil {
+			return err
+		}
+
+		if err := service.addMember(ctx, existingGroup, member); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeMembers takes the members with the given IDs out of a group.
+// IDs that aren't members of the group are ignored.
+func (service *Service) removeMembers(ctx context.Context, existingGroup *group.Group, memberIDs []string) error {
+	members, err := service.listMembers(ctx, existingGroup)
+	if err != nil {
+		return err
+	}
+
+	unwanted := map[string]bool{}
+	for _, memberID := range memberIDs {
+		unwanted[memberID] = true
+	}
+
+	for index := range members {
+		if unwanted[members[index].ID()] {
+			if err := service.removeMember(ctx, existingGroup, &members[index]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolvedMember is a user or a group that is about to be added to a
+// group.
+type resolvedMember struct {
+	kind group.MemberKind
+	name string
+}
+
+// resolveMember finds the user or group with an ID.
+func (service *Service) resolveMember(ctx context.Context, memberID string) (resolvedMember, error) {
+	var notFoundError errortypes.NotFoundError
+
+	memberUser, err := service.userService.GetUserByID(ctx, memberID)
+	if err == nil {
+		return resolvedMember{kind: group.MemberKindUser, name: memberUser.Username()}, nil
+	}
+
+	if !errors.As(err, &notFoundError) {
+		return resolvedMember{}, err
+	}
+
+	memberGroup, err := service.groupService.GetGroupByID(ctx, memberID)
+	if err == nil {
+		return resolvedMember{kind: group.MemberKindGroup, name: memberGroup.Name()}, nil
+	}
+
+	if !errors.As(err, &notFoundError) {
+		return resolvedMember{}, err
+	}
+
+	return resolvedMember{}, invalidValueError("Could not find a user or group with the ID '%s'", memberID)
+}
+
+func (service *Service) addMember(ctx context.Context, existingGroup *group.Group, member resolvedMember) error {
+	var err error
+	if member.kind == group.MemberKindGroup {
+		_, err = service.groupService.AddGroup(ctx, existingGroup.Name(), member.name)
+	} else {
+		_, err = service.groupService.AddUser(ctx, existingGroup.Name(), member.name)
+	}
+
+	return err
+}
+
+// removeMember takes a member out of a group. Members that were
+// already removed by someone else are ignored.
+func (service *Service) removeMember(ctx context.Context, existingGroup *group.Group, member *group.Member) error {
+	var err error
+	if member.Kind() == group.MemberKindGroup {
+		err = service.groupService.RemoveGroup(ctx, existingGroup.Name(), member.Name())
+	} else {
+		err = service.groupService.RemoveUser(ctx, existingGroup.Name(), member.Name())
+	}
+
+	var notFoundError errortypes.NotFoundError
+	if errors.As(err, &notFoundError) {
+		return nil
+	}
+
+	return err
+}
+
+func (service *Service) listMembers(ctx context.Context, existingGroup *group.Group) ([]group.Member, error) {
+	return listAll(func(params listing.Params) (listing.Page[group.Member], error) {
+		return service.groupService.ListMembers(ctx, existingGroup.Name(), params)
+	})
+}
+
+// listAll goes through every page of a listing.
+func listAll[Item any](list func(params listing.Params) (listing.Page[Item], error)) ([]Item, error) {
+	params := listing.Params{
+		Limit:     listing.MaxLimit,
+		SortField: listing.SortFieldCreatedOn,
+		SortOrder: listing.SortOrderAscending,
+	}
+
+	items := []Item{}
+	for {
+		page, err := list(params)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Items...)
+
+		if page.NextCursor == "" {
+			return items, nil
+		}
+
+		if params.Cursor, err = listing.DecodeCursor(page.NextCursor); err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to list resources due to a system error",
+				UnsafeMessage: "Unable to decode next page cursor",
+				WrappedError:  err,
+			}
+		}
+	}
+}
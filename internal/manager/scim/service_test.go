@@ -0,0 +1,217 @@
+package scim_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/group"
+	"github.com/durandj/ley/internal/manager/scim"
+	"github.com/durandj/ley/internal/manager/user"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningShouldCreateFindDeactivateAndDeleteUsers(t *testing.T) {
+	ctx := context.Background()
+	scimService := newTestService(t)
+
+	userName := fmt.Sprintf("scim-test-%d", rng.RNG.Int63())
+	createdUser, err := scimService.CreateUser(ctx, scim.CreateUserOpts{UserName: userName, Active: true})
+	require.Nil(t, err, "should be able to create a user")
+	require.Equal(t, user.StatusActive, createdUser.Status())
+
+	_, err = scimService.CreateUser(ctx, scim.CreateUserOpts{UserName: userName, Active: true})
+
+	var scimError scim.Error
+	require.True(t, errors.As(err, &scimError), "should not create the same user twice")
+	require.Equal(t, http.StatusConflict, scimError.Status)
+	require.Equal(t, "uniqueness", scimError.Type)
+
+	users, err := scimService.ListUsers(ctx, &scim.Filter{Attribute: "userName", Value: userName})
+	require.Nil(t, err, "should be able to filter users")
+	require.Len(t, users, 1)
+	require.Equal(t, createdUser.ID(), users[0].ID())
+
+	users, err = scimService.ListUsers(ctx, &scim.Filter{Attribute: "userName", Value: userName + "-missing"})
+	require.Nil(t, err, "should be able to filter users")
+	require.Empty(t, users, "should not find users that don't exist")
+
+	patchedUser, err := scimService.PatchUser(ctx, createdUser.ID(), []scim.PatchOperation{
+		{Op: "Replace", Path: "active", Value: json.RawMessage(`false`)},
+	})
+	require.Nil(t, err, "should be able to deactivate the user")
+	require.Equal(t, user.StatusDeactivated, patchedUser.Status())
+
+	patchedUser, err = scimService.PatchUser(ctx, createdUser.ID(), []scim.PatchOperation{
+		{Op: "replace", Value: json.RawMessage(`{"active": "True", "displayName": "Ignored"}`)},
+	})
+	require.Nil(t, err, "should be able to activate the user again")
+	require.Equal(t, user.StatusActive, patchedUser.Status())
+
+	_, err = scimService.PatchUser(ctx, createdUser.ID(), []scim.PatchOperation{
+		{Op: "replace", Path: "userName", Value: json.RawMessage(`"someone-else"`)},
+	})
+	require.True(t, errors.As(err, &scimError), "should not rename the user")
+	require.Equal(t, "mutability", scimError.Type)
+
+	require.Nil(t, scimService.DeleteUser(ctx, createdUser.ID()), "should be able to delete the user")
+
+	var notFoundError errortypes.NotFoundError
+	_, err = scimService.GetUser(ctx, createdUser.ID())
+	require.True(t, errors.As(err, &notFoundError), "should have deleted the user")
+}
+
+func TestProvisioningShouldCreateUsersDeactivated(t *testing.T) {
+	ctx := context.Background()
+	scimService := newTestService(t)
+
+	userName := fmt.Sprintf("scim-test-%d", rng.RNG.Int63())
+	createdUser, err := scimService.CreateUser(ctx, scim.CreateUserOpts{UserName: userName})
+	require.Nil(t, err, "should be able to create a user")
+	require.Equal(t, user.StatusDeactivated, createdUser.Status())
+}
+
+func TestProvisioningShouldVerifyTheEmailAddressesItSets(t *testing.T) {
+	ctx := context.Background()
+	scimService := newTestService(t)
+
+	userName := fmt.Sprintf("scim-test-%d", rng.RNG.Int63())
+	createdUser, err := scimService.CreateUser(ctx, scim.CreateUserOpts{
+		UserName: userName,
+		Email:    userName + "@example.com",
+		Active:   true,
+	})
+	require.Nil(t, err, "should be able to create a user")
+	require.True(t, createdUser.EmailVerified(), "should trust the identity provider's address")
+
+	patchedUser, err := scimService.PatchUser(ctx, createdUser.ID(), []scim.PatchOperation{
+		{Op: "replace", Path: "emails", Value: json.RawMessage(fmt.Sprintf(`[{"value": "%s@example.org"}]`, userName))},
+	})
+	require.Nil(t, err, "should be able to change the email address")
+	require.Equal(t, userName+"@example.org", patchedUser.Email())
+	require.True(t, patchedUser.EmailVerified(), "should trust the identity provider's new address")
+
+	patchedUser, err = scimService.PatchUser(ctx, createdUser.ID(), []scim.PatchOperation{
+		{Op: "remove", Path: "emails"},
+	})
+	require.Nil(t, err, "should be able to remove the email address")
+	require.Empty(t, patchedUser.Email())
+	require.False(t, patchedUser.EmailVerified(), "should not keep a verification without an address")
+}
+
+func TestProvisioningShouldManageGroupMembers(t *testing.T) {
+	ctx := context.Background()
+	scimService := newTestService(t)
+
+	alice := newTestUser(ctx, t, scimService)
+	bob := newTestUser(ctx, t, scimService)
+
+	team, err := scimService.CreateGroup(ctx, scim.CreateGroupOpts{
+		DisplayName: fmt.Sprintf("scim-team-%d", rng.RNG.Int63()),
+	})
+	require.Nil(t, err, "should be able to create a group")
+
+	department, err := scimService.CreateGroup(ctx, scim.CreateGroupOpts{
+		DisplayName: fmt.Sprintf("scim-department-%d", rng.RNG.Int63()),
+		MemberIDs:   []string{alice, team.Group().ID()},
+	})
+	require.Nil(t, err, "should be able to create a group with members")
+	require.ElementsMatch(t, []string{alice, team.Group().ID()}, memberIDs(department))
+
+	groups, err := scimService.ListGroups(
+		ctx,
+		&scim.Filter{Attribute: "displayName", Value: department.Group().Name()},
+		true,
+	)
+	require.Nil(t, err, "should be able to filter groups")
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Members(), 2, "should include the members")
+
+	department, err = scimService.PatchGroup(ctx, department.Group().ID(), []scim.PatchOperation{
+		{Op: "add", Path: "members", Value: json.RawMessage(fmt.Sprintf(`[{"value": %q}]`, bob))},
+		{Op: "remove", Path: fmt.Sprintf(`members[value eq %q]`, alice)},
+	})
+	require.Nil(t, err, "should be able to add and remove members")
+	require.ElementsMatch(t, []string{bob, team.Group().ID()}, memberIDs(department))
+
+	department, err = scimService.PatchGroup(ctx, department.Group().ID(), []scim.PatchOperation{
+		{Op: "replace", Value: json.RawMessage(fmt.Sprintf(`{"members": [{"value": %q}]}`, alice))},
+	})
+	require.Nil(t, err, "should be able to replace the members")
+	require.ElementsMatch(t, []string{alice}, memberIDs(department))
+
+	_, err = scimService.PatchGroup(ctx, department.Group().ID(), []scim.PatchOperation{
+		{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "missing"}]`)},
+	})
+
+	var scimError scim.Error
+	require.True(t, errors.As(err, &scimError), "should not add members that don't exist")
+	require.Equal(t, "invalidValue", scimError.Type)
+
+	_, err = scimService.CreateGroup(ctx, scim.CreateGroupOpts{DisplayName: team.Group().Name()})
+	require.True(t, errors.As(err, &scimError), "should not create the same group twice")
+	require.Equal(t, "uniqueness", scimError.Type)
+
+	require.Nil(t, scimService.DeleteGroup(ctx, department.Group().ID()), "should be able to delete the group")
+
+	var notFoundError errortypes.NotFoundError
+	_, err = scimService.GetGroup(ctx, department.Group().ID())
+	require.True(t, errors.As(err, &notFoundError), "should have deleted the group")
+}
+
+func newTestService(t *testing.T) *scim.Service {
+	dbConfig := configuration.DBConfiguration{
+		Type: configuration.DBTypePostgres,
+		Postgres: configuration.PostgresConfiguration{
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Role:     "ley",
+			Password: "ley",
+			DBName:   "ley",
+			SSLMode:  "disable",
+		},
+	}
+
+	connectionString, err := dbConfig.ConnectionString()
+	require.Nil(t, err, "should be able to create a connection string")
+
+	db, err := sql.Open(string(dbConfig.Type), connectionString)
+	require.Nil(t, err, "should be able to open the database")
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return scim.NewService(
+		user.NewService(db),
+		group.NewService(db),
+		configuration.SCIMConfiguration{Token: testToken},
+	)
+}
+
+func newTestUser(ctx context.Context, t *testing.T, scimService *scim.Service) string {
+	createdUser, err := scimService.CreateUser(ctx, scim.CreateUserOpts{
+		UserName: fmt.Sprintf("scim-test-%d", rng.RNG.Int63()),
+		Active:   true,
+	})
+	require.Nil(t, err, "should be able to create a user")
+
+	return createdUser.ID()
+}
+
+func memberIDs(scimGroup *scim.Group) []string {
+	ids := []string{}
+	for index := range scimGroup.Members() {
+		ids = append(ids, scimGroup.Members()[index].ID())
+	}
+
+	return ids
+}
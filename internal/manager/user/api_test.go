@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -231,6 +232,129 @@ func TestUserAPIShouldGetAUserByTheirUsername(t *testing.T) {
 	)
 }
 
+func TestUserAPIShouldGetAUserByTheirEmailAndExternalID(t *testing.T) {
+	config, serverAddress := newServiceConfiguration()
+
+	service, err := manager.New(&config)
+	require.Nil(t, err, "should be able to create a manager instance")
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	go func() {
+		_ = service.Run(ctx)
+	}()
+
+	suffix := rng.RNG.Int63()
+	createUserRequest := user.CreateUserRequest{
+		Username:    fmt.Sprintf("profile-%d", suffix),
+		DisplayName: "Profile Test",
+		Email:       fmt.Sprintf("profile-%d@example.com", suffix),
+		ExternalID:  fmt.Sprintf("external-%d", suffix),
+		Metadata:    map[string]string{"team": "backend"},
+	}
+	requestBytes, err := json.Marshal(createUserRequest)
+	require.Nil(t, err, "should be able to marshal the request body")
+
+	httpClient := http.Client{}
+	response, err := httpClient.Post(
+		fmt.Sprintf("http://%s/user", serverAddress),
+		"application/json",
+		bytes.NewBuffer(requestBytes),
+	)
+	require.Nil(t, err, "should be able to complete the request")
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+
+	var newUser user.CreateUserResponse
+	err = json.NewDecoder(response.Body).Decode(&newUser)
+	require.Nil(t, err, "should be able to read response body")
+	require.Equal(t, createUserRequest.Username, newUser.Username)
+	require.Equal(t, createUserRequest.Username, newUser.Name, "should still give the deprecated name")
+	require.Equal(t, createUserRequest.Metadata, newUser.Metadata)
+	require.False(t, newUser.EmailVerified, "should not verify the email address by default")
+
+	for _, query := range []string{
+		"email=" + strings.ToUpper(createUserRequest.Email),
+		"externalId=" + createUserRequest.ExternalID,
+	} {
+		response, err := httpClient.Get(fmt.Sprintf("http://%s/user?%s", serverAddress, query))
+		require.Nil(t, err, "should be able to complete the request")
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		var returnedUser user.GetUserByUsernameResponse
+		err = json.NewDecoder(response.Body).Decode(&returnedUser)
+		require.Nil(t, err, "should be able to read response body")
+		require.Equal(t, newUser.RenderableUser, returnedUser.RenderableUser, "should find the user by %s", query)
+	}
+
+	createUserRequest.Username = fmt.Sprintf("profile-other-%d", suffix)
+	createUserRequest.ExternalID = ""
+	requestBytes, err = json.Marshal(createUserRequest)
+	require.Nil(t, err, "should be able to marshal the request body")
+
+	response, err = httpClient.Post(
+		fmt.Sprintf("http://%s/user", serverAddress),
+		"application/json",
+		bytes.NewBuffer(requestBytes),
+	)
+	require.Nil(t, err, "should be able to complete the request")
+	require.Equal(t, http.StatusBadRequest, response.StatusCode, "should not reuse an email address")
+}
+
+func TestUserAPIShouldNotLetCallersVerifyEmailAddresses(t *testing.T) {
+	config, serverAddress := newServiceConfiguration()
+
+	service, err := manager.New(&config)
+	require.Nil(t, err, "should be able to create a manager instance")
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	go func() {
+		_ = service.Run(ctx)
+	}()
+
+	suffix := rng.RNG.Int63()
+	username := fmt.Sprintf("verify-%d", suffix)
+	requestBody := fmt.Sprintf(
+		`{"username":%q,"email":"verify-%d@example.com","emailVerified":true}`,
+		username,
+		suffix,
+	)
+
+	httpClient := http.Client{}
+	response, err := httpClient.Post(
+		fmt.Sprintf("http://%s/user", serverAddress),
+		"application/json",
+		strings.NewReader(requestBody),
+	)
+	require.Nil(t, err, "should be able to complete the request")
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+
+	var newUser user.CreateUserResponse
+	err = json.NewDecoder(response.Body).Decode(&newUser)
+	require.Nil(t, err, "should be able to read response body")
+	require.False(t, newUser.EmailVerified, "should not verify an email address when creating a user")
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPatch,
+		fmt.Sprintf("http://%s/user/%s", serverAddress, username),
+		strings.NewReader(`{"emailVerified":true}`),
+	)
+	require.Nil(t, err, "should be able to create a PATCH request")
+	request.Header.Add("Content-Type", "application/json")
+
+	response, err = httpClient.Do(request)
+	require.Nil(t, err, "should be able to complete the request")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	var updatedUser user.UpdateUserResponse
+	err = json.NewDecoder(response.Body).Decode(&updatedUser)
+	require.Nil(t, err, "should be able to read response body")
+	require.False(t, updatedUser.EmailVerified, "should not verify an email address when updating a user")
+}
+
 func TestUserAPIShouldReturnAnErrorWhenMissingTheUsernameInGetByUsernameRequest(
 	t *testing.T,
 ) {
@@ -266,7 +390,7 @@ func TestUserAPIShouldReturnAnErrorWhenMissingTheUsernameInGetByUsernameRequest(
 
 	require.Equal(
 		t,
-		"Missing query parameter 'username'",
+		"Missing query parameter 'username', 'email' or 'externalId'",
 		getUserError.Message,
 		"should have an error message",
 	)
@@ -313,6 +437,111 @@ func TestUserAPIShouldReturnAnErrorForANonExistantUsername(
 	)
 }
 
+func TestUserAPIShouldReturnNotModifiedForTheCurrentVersion(t *testing.T) {
+	config, serverAddress := newServiceConfiguration()
+
+	service, err := manager.New(&config)
+	require.Nil(t, err, "should be able to create a manager instance")
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	go func() {
+		_ = service.Run(ctx)
+	}()
+
+	testUser, err := newTestUser(ctx, serverAddress)
+	require.Nil(t, err, "should be able to create a test user")
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("http://%s/user?username=%s", serverAddress, testUser.Name),
+		nil,
+	)
+	require.Nil(t, err, "should be able to create a GET request")
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("If-None-Match", fmt.Sprintf(`"%d"`, testUser.Version))
+
+	httpClient := http.Client{}
+	response, err := httpClient.Do(request)
+	require.Nil(t, err, "should be able to complete the request")
+	require.Equal(t, http.StatusNotModified, response.StatusCode)
+	require.Equal(
+		t,
+		fmt.Sprintf(`"%d"`, testUser.Version),
+		response.Header.Get("ETag"),
+		"should include the current entity tag",
+	)
+}
+
+func TestUserAPIShouldReplayCreatesWithTheSameIdempotencyKey(t *testing.T) {
+	config, serverAddress := newServiceConfiguration()
+
+	service, err := manager.New(&config)
+	require.Nil(t, err, "should be able to create a manager instance")
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	go func() {
+		_ = service.Run(ctx)
+	}()
+
+	idempotencyKey := fmt.Sprintf("test-%d", rng.RNG.Int63())
+	createUser := func(createUserRequest *user.CreateUserRequest) *http.Response {
+		requestBytes, err := json.Marshal(createUserRequest)
+		require.Nil(t, err, "should be able to marshal the request body")
+
+		request, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodPost,
+			fmt.Sprintf("http://%s/user", serverAddress),
+			bytes.NewBuffer(requestBytes),
+		)
+		require.Nil(t, err, "should be able to create a POST request")
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("Idempotency-Key", idempotencyKey)
+
+		httpClient := http.Client{}
+		response, err := httpClient.Do(request)
+		require.Nil(t, err, "should be able to complete the request")
+
+		return response
+	}
+
+	createUserRequest := newCreateUserRequest()
+
+	firstResponse := createUser(createUserRequest)
+	require.Equal(t, http.StatusCreated, firstResponse.StatusCode)
+
+	var firstUser user.CreateUserResponse
+	err = json.NewDecoder(firstResponse.Body).Decode(&firstUser)
+	require.Nil(t, err, "should be able to read response body")
+
+	secondResponse := createUser(createUserRequest)
+	require.Equal(t, http.StatusCreated, secondResponse.StatusCode, "should replay the first response")
+	require.Equal(t, "true", secondResponse.Header.Get("Idempotent-Replayed"))
+
+	var secondUser user.CreateUserResponse
+	err = json.NewDecoder(secondResponse.Body).Decode(&secondUser)
+	require.Nil(t, err, "should be able to read response body")
+	require.Equal(
+		t,
+		time.Time(firstUser.CreatedOn),
+		time.Time(secondUser.CreatedOn),
+		"should return the same user",
+	)
+
+	otherResponse := createUser(newCreateUserRequest())
+	require.Equal(
+		t,
+		http.StatusUnprocessableEntity,
+		otherResponse.StatusCode,
+		"should reject reusing the key for a different request",
+	)
+}
+
 func newServiceConfiguration() (configuration.Configuration, string) {
 	serverHost, serverPort := "localhost", 8080
 
@@ -322,6 +551,9 @@ func newServiceConfiguration() (configuration.Configuration, string) {
 			Host:            serverHost,
 			Port:            serverPort,
 		},
+		API: configuration.APIConfiguration{
+			IdempotencyWindow: time.Hour,
+		},
 		Logging: configuration.LoggingConfiguration{
 			Level: configuration.LogLevelInfo,
 		},
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/durandj/ley/internal/manager/conditional"
 	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
 	"github.com/durandj/ley/internal/manager/renderable"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -19,6 +21,9 @@ type Controller struct {
 func (controller *Controller) RegisterRoutes(router chi.Router) {
 	router.Post("/", controller.CreateUser)
 	router.Get("/", controller.GetUserByUsername)
+	router.Get("/list", controller.ListUsers)
+	router.Patch("/{username}", controller.UpdateUser)
+	router.Delete("/{username}", controller.DeleteUser)
 }
 
 // CreateUser handles requests to create a new user.
@@ -42,9 +47,20 @@ func (controller *Controller) CreateUser(
 		return
 	}
 
+	username := createUserRequest.Username
+	if username == "" {
+		username = createUserRequest.Name
+	}
+
 	user, err := controller.UserService.CreateUser(
 		ctx,
-		CreateUserOpts(createUserRequest),
+		CreateUserOpts{
+			Username:    username,
+			DisplayName: createUserRequest.DisplayName,
+			Email:       createUserRequest.Email,
+			ExternalID:  createUserRequest.ExternalID,
+			Metadata:    createUserRequest.Metadata,
+		},
 	)
 	if err != nil {
 		handleError(response, request, err)
@@ -55,22 +71,42 @@ func (controller *Controller) CreateUser(
 		RenderableUser: NewRenderableUser(user),
 	}
 
+	conditional.SetETag(response, user.Version())
 	response.WriteHeader(http.StatusCreated)
 	_ = render.Render(response, request, &createUserResponse)
 }
 
-// GetUserByUsername fetches user information given a username.
+// GetUserByUsername fetches user information given a username. A user
+// can also be looked up by their email address or external ID instead.
 func (controller *Controller) GetUserByUsername(
 	response http.ResponseWriter,
 	request *http.Request,
 ) {
 	queryParams := request.URL.Query()
 	username := queryParams.Get("username")
+	email := queryParams.Get("email")
+	externalID := queryParams.Get("externalId")
 
-	if username == "" {
+	lookups := 0
+	for _, value := range []string{username, email, externalID} {
+		if value != "" {
+			lookups++
+		}
+	}
+
+	if lookups == 0 {
 		response.WriteHeader(http.StatusBadRequest)
 		_ = render.Render(response, request, &renderable.ErrorResponse{
-			Message: "Missing query parameter 'username'",
+			Message: "Missing query parameter 'username', 'email' or 'externalId'",
+		})
+
+		return
+	}
+
+	if lookups > 1 {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: "Only one of the query parameters 'username', 'email' and 'externalId' can be given",
 		})
 
 		return
@@ -78,20 +114,132 @@ func (controller *Controller) GetUserByUsername(
 
 	ctx := request.Context()
 
-	user, err := controller.UserService.GetUserByUsername(ctx, username)
+	var user *User
+	var err error
+	switch {
+	case email != "":
+		user, err = controller.UserService.GetUserByEmail(ctx, email)
+
+	case externalID != "":
+		user, err = controller.UserService.GetUserByExternalID(ctx, externalID)
+
+	default:
+		user, err = controller.UserService.GetUserByUsername(ctx, username)
+	}
+
 	if err != nil {
 		handleError(response, request, err)
 		return
 	}
 
+	if conditional.NotModified(request, user.Version()) {
+		conditional.WriteNotModified(response, user.Version())
+		return
+	}
+
 	getUserByUsernameResponse := GetUserByUsernameResponse{
 		RenderableUser: NewRenderableUser(user),
 	}
 
+	conditional.SetETag(response, user.Version())
 	response.WriteHeader(http.StatusOK)
 	_ = render.Render(response, request, &getUserByUsernameResponse)
 }
 
+// UpdateUser handles requests to change a user. The If-Match header
+// can be used to make sure nobody else changed the user first.
+func (controller *Controller) UpdateUser(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var updateUserRequest UpdateUserRequest
+	if err := render.Bind(request, &updateUserRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	user, err := controller.UserService.UpdateUser(
+		ctx,
+		chi.URLParam(request, "username"),
+		UpdateUserOpts{
+			Status:           updateUserRequest.Status,
+			DisplayName:      updateUserRequest.DisplayName,
+			Email:            updateUserRequest.Email,
+			ExternalID:       updateUserRequest.ExternalID,
+			Metadata:         updateUserRequest.Metadata,
+			ExpectedVersions: conditional.ParseIfMatch(request),
+		},
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	updateUserResponse := UpdateUserResponse{
+		RenderableUser: NewRenderableUser(user),
+	}
+
+	conditional.SetETag(response, user.Version())
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &updateUserResponse)
+}
+
+// DeleteUser handles requests to remove a user. The If-Match header
+// can be used to make sure nobody else changed the user first.
+func (controller *Controller) DeleteUser(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	err := controller.UserService.DeleteUser(
+		ctx,
+		chi.URLParam(request, "username"),
+		conditional.ParseIfMatch(request),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ListUsers handles requests to list users a page at a time.
+func (controller *Controller) ListUsers(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	params, err := listing.ParseParams(request.URL.Query())
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	page, err := controller.UserService.ListUsers(ctx, params)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listUsersResponse := NewListUsersResponse(page)
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listUsersResponse)
+}
+
 func handleError(
 	response http.ResponseWriter,
 	request *http.Request,
@@ -99,6 +247,7 @@ func handleError(
 ) {
 	var validationError errortypes.ValidationError
 	var notFoundError errortypes.NotFoundError
+	var preconditionFailedError errortypes.PreconditionFailedError
 	var userError errortypes.UserError
 	var systemError errortypes.SystemError
 	switch {
@@ -114,6 +263,12 @@ func handleError(
 			Message: notFoundError.SafeMessage,
 		})
 
+	case errors.As(err, &preconditionFailedError):
+		response.WriteHeader(http.StatusPreconditionFailed)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: preconditionFailedError.SafeMessage,
+		})
+
 	case errors.As(err, &userError):
 		response.WriteHeader(http.StatusBadRequest)
 		_ = render.Render(response, request, &renderable.ErrorResponse{
@@ -137,20 +292,39 @@ func handleError(
 // RenderableUser gives the safe version of a user that can be returned
 // over HTTP.
 type RenderableUser struct {
-	Name       string          `json:"name"`
-	Status     Status          `json:"status"`
-	CreatedOn  renderable.Time `json:"createdOn"`
-	ModifiedOn renderable.Time `json:"modifiedOn"`
+	Username string `json:"username"`
+
+	// Name is the username of the user.
+	//
+	// Deprecated: Use Username.
+	Name string `json:"name"`
+
+	DisplayName   string            `json:"displayName"`
+	Email         string            `json:"email,omitempty"`
+	EmailVerified bool              `json:"emailVerified"`
+	ExternalID    string            `json:"externalId,omitempty"`
+	Metadata      map[string]string `json:"metadata"`
+	Status        Status            `json:"status"`
+	Version       int64             `json:"version"`
+	CreatedOn     renderable.Time   `json:"createdOn"`
+	ModifiedOn    renderable.Time   `json:"modifiedOn"`
 }
 
 // NewRenderableUser creates a renderable user from a backend user
 // instance.
 func NewRenderableUser(user *User) RenderableUser {
 	return RenderableUser{
-		Name:       user.Username(),
-		Status:     user.Status(),
-		CreatedOn:  renderable.Time(user.CreatedOn()),
-		ModifiedOn: renderable.Time(user.ModifiedOn()),
+		Username:      user.Username(),
+		Name:          user.Username(),
+		DisplayName:   user.DisplayName(),
+		Email:         user.Email(),
+		EmailVerified: user.EmailVerified(),
+		ExternalID:    user.ExternalID(),
+		Metadata:      user.Metadata(),
+		Status:        user.Status(),
+		Version:       user.Version(),
+		CreatedOn:     renderable.Time(user.CreatedOn()),
+		ModifiedOn:    renderable.Time(user.ModifiedOn()),
 	}
 }
 
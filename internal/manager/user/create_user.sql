@@ -1,6 +1,11 @@
 INSERT INTO Users (
     ID,
     Username,
+    DisplayName,
+    Email,
+    EmailVerified,
+    ExternalID,
+    Metadata,
     Status,
     CreatedOn
 )
@@ -8,7 +13,12 @@ VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
 )
-RETURNING ID, Username, Status, CreatedOn, ModifiedOn
+RETURNING ID, Username, DisplayName, Email, EmailVerified, ExternalID, Metadata, Status, Version, CreatedOn, ModifiedOn
 ;
@@ -0,0 +1,6 @@
+DELETE FROM Users
+WHERE
+    Username = $1
+    AND ($2::BIGINT[] IS NULL OR Version = ANY($2))
+RETURNING ID, Username, DisplayName, Email, EmailVerified, ExternalID, Metadata, Status, Version, CreatedOn, ModifiedOn
+;
@@ -0,0 +1,17 @@
+SELECT
+    ID,
+    Username,
+    DisplayName,
+    Email,
+    EmailVerified,
+    ExternalID,
+    Metadata,
+    Status,
+    Version,
+    CreatedOn,
+    ModifiedOn
+FROM Users
+WHERE
+    ID = $1
+LIMIT 1
+;
@@ -1,7 +1,13 @@
 SELECT
     ID,
     Username,
+    DisplayName,
+    Email,
+    EmailVerified,
+    ExternalID,
+    Metadata,
     Status,
+    Version,
     CreatedOn,
     ModifiedOn
 FROM Users
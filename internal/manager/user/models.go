@@ -4,16 +4,23 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/durandj/ley/internal/manager/listing"
 	"github.com/go-chi/render"
 )
 
 // User defines the backend view of what a user is.
 type User struct {
-	id         string
-	username   string
-	status     Status
-	createdOn  time.Time
-	modifiedOn time.Time
+	id            string
+	username      string
+	displayName   string
+	email         string
+	emailVerified bool
+	externalID    string
+	metadata      map[string]string
+	status        Status
+	version       int64
+	createdOn     time.Time
+	modifiedOn    time.Time
 }
 
 // ID gives the backend ID of the user.
@@ -26,6 +33,37 @@ func (user *User) Username() string {
 	return user.username
 }
 
+// DisplayName is the name that the user goes by. Unlike the username
+// it doesn't have to be unique and can be changed. It is empty when the
+// user didn't give one.
+func (user *User) DisplayName() string {
+	return user.displayName
+}
+
+// Email is the email address of the user or empty if they don't have
+// one. No two users share an address.
+func (user *User) Email() string {
+	return user.email
+}
+
+// EmailVerified tells whether the user was confirmed to own their
+// email address.
+func (user *User) EmailVerified() bool {
+	return user.emailVerified
+}
+
+// ExternalID identifies the user in the system that they were
+// provisioned from, like an identity provider. It is empty for users
+// that were created directly.
+func (user *User) ExternalID() string {
+	return user.externalID
+}
+
+// Metadata holds free-form key/value pairs about the user.
+func (user *User) Metadata() map[string]string {
+	return user.metadata
+}
+
 // CreatedOn gives the date the user was created on.
 func (user *User) CreatedOn() time.Time {
 	return user.createdOn
@@ -41,6 +79,12 @@ func (user *User) Status() Status {
 	return user.status
 }
 
+// Version is incremented every time the user is changed. It is used
+// to detect conflicting updates.
+func (user *User) Version() int64 {
+	return user.version
+}
+
 // Status tells if the user is active or not.
 type Status string
 
@@ -57,12 +101,27 @@ const (
 
 // CreateUserRequest holds the request body for creating a new user.
 type CreateUserRequest struct {
-	Name string `json:"name"`
+	Username string `json:"username,omitempty"`
+
+	// Name is the username of the user.
+	//
+	// Deprecated: Use Username, which takes precedence when both are
+	// given.
+	Name string `json:"name,omitempty"`
+
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Email is the email address of the user. It is only ever
+	// verified by single sign-on or provisioning.
+	Email string `json:"email,omitempty"`
+
+	ExternalID string            `json:"externalId,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
 }
 
-// SetName sets the name of the user in the creation request and returns
-// an instance of the request object. This can be used as a factory
-// builder style pattern.
+// SetName sets the deprecated name of the user in the creation request
+// and returns an instance of the request object. This can be used as a
+// factory builder style pattern.
 func (createUserRequest *CreateUserRequest) SetName(name string) *CreateUserRequest {
 	createUserRequest.Name = name
 
@@ -84,10 +143,66 @@ type CreateUserResponse struct {
 
 var _ render.Renderer = (*CreateUserResponse)(nil)
 
+// UpdateUserRequest holds the request body for changing a user.
+// Fields that are left out are not changed. An empty email address or
+// external ID removes it and metadata is replaced as a whole. Changing
+// the email address takes away its verification.
+type UpdateUserRequest struct {
+	Status      *Status           `json:"status,omitempty"`
+	DisplayName *string           `json:"displayName,omitempty"`
+	Email       *string           `json:"email,omitempty"`
+	ExternalID  *string           `json:"externalId,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Bind is a hook into the process for converting an HTTP request body
+// into a request object.
+func (updateUserRequest *UpdateUserRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*UpdateUserRequest)(nil)
+
+// UpdateUserResponse holds the response object for changing a user.
+type UpdateUserResponse struct {
+	RenderableUser
+}
+
+var _ render.Renderer = (*UpdateUserResponse)(nil)
+
 // GetUserByUsernameResponse is the response returned when requesting
-// a user by their username.
+// a user by their username, email address or external ID.
 type GetUserByUsernameResponse struct {
 	RenderableUser
 }
 
 var _ render.Renderer = (*GetUserByUsernameResponse)(nil)
+
+// ListUsersResponse is the response for requesting a page of users.
+type ListUsersResponse struct {
+	Users      []RenderableUser `json:"users"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
+
+// NewListUsersResponse creates a user list response.
+func NewListUsersResponse(page listing.Page[User]) ListUsersResponse {
+	renderableUsers := make([]RenderableUser, len(page.Items))
+	for index := range page.Items {
+		renderableUsers[index] = NewRenderableUser(&page.Items[index])
+	}
+
+	return ListUsersResponse{
+		Users:      renderableUsers,
+		NextCursor: page.NextCursor,
+	}
+}
+
+// Render customizes the rendering process for a response object.
+func (listUsersResponse *ListUsersResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ListUsersResponse)(nil)
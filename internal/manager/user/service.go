@@ -4,23 +4,62 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"encoding/json"
 	"fmt"
+	"net/mail"
 	"regexp"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/listing"
+	"github.com/durandj/ley/internal/manager/webhook"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
+const (
+	maxDisplayNameLength   = 128
+	maxEmailLength         = 254
+	maxExternalIDLength    = 255
+	maxMetadataEntries     = 64
+	maxMetadataValueLength = 256
+)
+
 var (
-	userNameRegex = regexp.MustCompile(`^\w[-\w_ ']+$`)
+	userNameRegex    = regexp.MustCompile(`^\w[-\w_ ']+$`)
+	metadataKeyRegex = regexp.MustCompile(`^[A-Za-z0-9][-A-Za-z0-9_./]{0,62}$`)
 
 	//go:embed create_user.sql
 	createUserSQL string
 
 	//go:embed get_user_by_username.sql
 	getUserByUsernameSQL string
+
+	//go:embed get_user_by_id.sql
+	getUserByIDSQL string
+
+	//go:embed get_user_by_email.sql
+	getUserByEmailSQL string
+
+	//go:embed get_user_by_external_id.sql
+	getUserByExternalIDSQL string
+
+	//go:embed list_users.sql
+	listUsersSQL string
+
+	//go:embed update_user.sql
+	updateUserSQL string
+
+	//go:embed delete_user.sql
+	deleteUserSQL string
+
+	listUsersColumns = listing.Columns{
+		ID:        "ID",
+		Name:      "Username",
+		CreatedOn: "CreatedOn",
+	}
 )
 
 // Service is a service for working with user objects.
@@ -35,18 +74,28 @@ func NewService(db *sql.DB) *Service {
 	}
 }
 
-// CreateUserOpts gives the options for creating a new user.
+// CreateUserOpts gives the options for creating a new user. Only the
+// username is required.
 type CreateUserOpts struct {
-	Name string
+	Username      string
+	DisplayName   string
+	Email         string
+	EmailVerified bool
+	ExternalID    string
+	Metadata      map[string]string
 }
 
 // Validate checks that the user creation options are valid.
 func (opts *CreateUserOpts) Validate() error {
-	if !userNameRegex.MatchString(opts.Name) {
-		return fmt.Errorf("Invalid user name '%s'", opts.Name)
+	if !userNameRegex.MatchString(opts.Username) {
+		return fmt.Errorf("Invalid user name '%s'", opts.Username)
 	}
 
-	return nil
+	if opts.EmailVerified && opts.Email == "" {
+		return fmt.Errorf("Cannot verify an email address without one")
+	}
+
+	return validateProfile(&opts.DisplayName, &opts.Email, &opts.ExternalID, opts.Metadata)
 }
 
 // CreateUser creates a new user object.
@@ -60,21 +109,37 @@ func (service *Service) CreateUser(
 
 	creationTime := time.Now().UTC()
 
-	var user User
-	err := service.db.QueryRowContext(
+	rawMetadata, err := encodeMetadata(opts.Metadata)
+	if err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to create user: Invalid metadata")
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new user due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	user, err := scanUser(tx.QueryRowContext(
 		ctx,
 		createUserSQL,
 		uuid.NewString(),
-		opts.Name,
+		opts.Username,
+		opts.DisplayName,
+		nullString(opts.Email),
+		opts.EmailVerified,
+		nullString(opts.ExternalID),
+		*rawMetadata,
 		StatusActive,
 		creationTime,
-	).Scan(
-		&user.id,
-		&user.username,
-		&user.status,
-		&user.createdOn,
-		&user.modifiedOn,
-	)
+	))
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
@@ -84,6 +149,10 @@ func (service *Service) CreateUser(
 				return nil, errortypes.NewValidationError("Username is already taken")
 			}
 
+			if err := explainUniqueViolation(pqErr); err != nil {
+				return nil, err
+			}
+
 			return nil, errortypes.SystemError{
 				SafeMessage:   "Unable to create new user due to a system error",
 				UnsafeMessage: "Unable to create new user due to a system error",
@@ -98,7 +167,26 @@ func (service *Service) CreateUser(
 		}
 	}
 
-	return &user, nil
+	err = recordWebhookEvent(
+		ctx,
+		tx,
+		webhook.EventTypeUserCreated,
+		user,
+		"Unable to create new user due to a system error",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create new user due to a system error",
+			UnsafeMessage: "Unable to commit new user",
+			WrappedError:  err,
+		}
+	}
+
+	return user, nil
 }
 
 // GetUserByUsername fetches a user by their username.
@@ -106,35 +194,473 @@ func (service *Service) GetUserByUsername(
 	ctx context.Context,
 	username string,
 ) (*User, error) {
-	var user User
-	err := service.db.QueryRowContext(
+	return service.getUser(
 		ctx,
 		getUserByUsernameSQL,
 		username,
-	).Scan(
-		&user.id,
-		&user.username,
-		&user.status,
-		&user.createdOn,
-		&user.modifiedOn,
+		"Could not find a user with that name",
+		"Unable to get user by username due to a system error",
+	)
+}
+
+// GetUserByID fetches a user by their database ID.
+func (service *Service) GetUserByID(
+	ctx context.Context,
+	id string,
+) (*User, error) {
+	return service.getUser(
+		ctx,
+		getUserByIDSQL,
+		id,
+		"Could not find a user with that ID",
+		"Unable to get user by ID due to a system error",
+	)
+}
+
+// GetUserByEmail fetches a user by their email address, without regard
+// to case.
+func (service *Service) GetUserByEmail(
+	ctx context.Context,
+	email string,
+) (*User, error) {
+	return service.getUser(
+		ctx,
+		getUserByEmailSQL,
+		email,
+		"Could not find a user with that email address",
+		"Unable to get user by email address due to a system error",
+	)
+}
+
+// GetUserByExternalID fetches a user by the ID they have in the system
+// they were provisioned from.
+func (service *Service) GetUserByExternalID(
+	ctx context.Context,
+	externalID string,
+) (*User, error) {
+	return service.getUser(
+		ctx,
+		getUserByExternalIDSQL,
+		externalID,
+		"Could not find a user with that external ID",
+		"Unable to get user by external ID due to a system error",
 	)
+}
 
+// getUser fetches the user that a query finds by one of their unique
+// attributes.
+func (service *Service) getUser(
+	ctx context.Context,
+	query string,
+	value string,
+	notFoundMessage string,
+	systemErrorMessage string,
+) (*User, error) {
+	user, err := scanUser(service.db.QueryRowContext(ctx, query, value))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errortypes.NotFoundError{
 				UserError: errortypes.UserError{
-					SafeMessage:  "Could not find a user with that name",
+					SafeMessage:  notFoundMessage,
 					WrappedError: err,
 				},
 			}
 		}
 
 		return nil, errortypes.SystemError{
-			SafeMessage:   "Unable to get user by username due to a system error",
-			UnsafeMessage: "Unable to get user by username due to a system error",
+			SafeMessage:   systemErrorMessage,
+			UnsafeMessage: systemErrorMessage,
+			WrappedError:  err,
+		}
+	}
+
+	return user, nil
+}
+
+// ListUsers retrieves a page of users.
+func (service *Service) ListUsers(
+	ctx context.Context,
+	params listing.Params,
+) (listing.Page[User], error) {
+	query, args, err := params.SQL(listUsersSQL, listUsersColumns, nil, nil)
+	if err != nil {
+		return listing.Page[User]{}, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return listing.Page[User]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list users due to a system error",
+			UnsafeMessage: "Unable to list users due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return listing.Page[User]{}, errortypes.SystemError{
+				SafeMessage:   "Unable to list users due to a system error",
+				UnsafeMessage: "Unable to read user row",
+				WrappedError:  err,
+			}
+		}
+
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return listing.Page[User]{}, errortypes.SystemError{
+			SafeMessage:   "Unable to list users due to a system error",
+			UnsafeMessage: "Unable to iterate over user rows",
+			WrappedError:  err,
+		}
+	}
+
+	return listing.NewPage(params, users, func(user *User) listing.Key {
+		return listing.Key{
+			ID:        user.ID(),
+			Name:      user.Username(),
+			CreatedOn: user.CreatedOn(),
+		}
+	}), nil
+}
+
+// UpdateUserOpts gives the changes to make to a user. Nil fields are
+// left as they are. An empty email address or external ID removes it
+// and metadata is replaced as a whole.
+type UpdateUserOpts struct {
+	Status      *Status
+	DisplayName *string
+	Email       *string
+
+	// EmailVerified marks the email address as verified or not.
+	// Changing the email address without it takes away the
+	// verification.
+	EmailVerified *bool
+
+	ExternalID *string
+	Metadata   map[string]string
+
+	// ExpectedVersions limits the update to these versions of the
+	// user. Nil allows any version.
+	ExpectedVersions []int64
+}
+
+// Validate checks that the update options are valid.
+func (opts *UpdateUserOpts) Validate() error {
+	if opts.Status != nil && *opts.Status != StatusActive && *opts.Status != StatusDeactivated {
+		return fmt.Errorf("Invalid user status '%s'", *opts.Status)
+	}
+
+	if opts.EmailVerified != nil && *opts.EmailVerified && opts.Email != nil && *opts.Email == "" {
+		return fmt.Errorf("Cannot verify an email address without one")
+	}
+
+	return validateProfile(opts.DisplayName, opts.Email, opts.ExternalID, opts.Metadata)
+}
+
+// UpdateUser changes a user.
+func (service *Service) UpdateUser(
+	ctx context.Context,
+	username string,
+	opts UpdateUserOpts,
+) (*User, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to update user: %v", err)
+	}
+
+	var rawMetadata *string
+	if opts.Metadata != nil {
+		var err error
+		if rawMetadata, err = encodeMetadata(opts.Metadata); err != nil {
+			return nil, errortypes.NewWrappedValidationError(err, "Unable to update user: Invalid metadata")
+		}
+	}
+
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to update user due to a system error",
+			UnsafeMessage: "Unable to start transaction",
 			WrappedError:  err,
 		}
 	}
 
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	user, err := scanUser(tx.QueryRowContext(
+		ctx,
+		updateUserSQL,
+		username,
+		opts.Status,
+		time.Now().UTC(),
+		pq.Array(opts.ExpectedVersions),
+		opts.DisplayName,
+		opts.Email,
+		opts.EmailVerified,
+		opts.ExternalID,
+		rawMetadata,
+	))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, service.explainMissingUser(ctx, username)
+		}
+
+		if pqErr, ok := err.(*pq.Error); ok {
+			if err := explainUniqueViolation(pqErr); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to update user due to a system error",
+			UnsafeMessage: "Unable to update user due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	err = recordWebhookEvent(
+		ctx,
+		tx,
+		webhook.EventTypeUserUpdated,
+		user,
+		"Unable to update user due to a system error",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to update user due to a system error",
+			UnsafeMessage: "Unable to commit user update",
+			WrappedError:  err,
+		}
+	}
+
+	return user, nil
+}
+
+// DeleteUser removes a user. The delete is limited to the expected
+// versions of the user unless they are nil.
+func (service *Service) DeleteUser(
+	ctx context.Context,
+	username string,
+	expectedVersions []int64,
+) error {
+	tx, err := service.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete user due to a system error",
+			UnsafeMessage: "Unable to start transaction",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	user, err := scanUser(tx.QueryRowContext(ctx, deleteUserSQL, username, pq.Array(expectedVersions)))
+	if err == sql.ErrNoRows {
+		return service.explainMissingUser(ctx, username)
+	}
+
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete user due to a system error",
+			UnsafeMessage: "Unable to delete user due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	err = recordWebhookEvent(
+		ctx,
+		tx,
+		webhook.EventTypeUserDeleted,
+		user,
+		"Unable to delete user due to a system error",
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete user due to a system error",
+			UnsafeMessage: "Unable to commit user removal",
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// recordWebhookEvent queues a change to a user for the webhooks
+// subscribed to it.
+func recordWebhookEvent(
+	ctx context.Context,
+	tx *sql.Tx,
+	eventType webhook.EventType,
+	user *User,
+	safeMessage string,
+) error {
+	renderableUser := NewRenderableUser(user)
+	if err := webhook.Record(ctx, tx, eventType, &renderableUser); err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   safeMessage,
+			UnsafeMessage: err.Error(),
+			WrappedError:  err,
+		}
+	}
+
+	return nil
+}
+
+// explainMissingUser figures out why a conditional change didn't
+// match any rows, either because the user doesn't exist or because it
+// was at a different version.
+func (service *Service) explainMissingUser(ctx context.Context, username string) error {
+	if _, err := service.GetUserByUsername(ctx, username); err != nil {
+		return err
+	}
+
+	return errortypes.PreconditionFailedError{
+		UserError: errortypes.UserError{
+			SafeMessage: "The user has been changed since it was last read",
+		},
+	}
+}
+
+// validateProfile checks the parts of a user that describe them. Nil
+// values aren't checked.
+func validateProfile(displayName *string, email *string, externalID *string, metadata map[string]string) error {
+	if displayName != nil {
+		if utf8.RuneCountInString(*displayName) > maxDisplayNameLength {
+			return fmt.Errorf("Display name cannot be longer than %d characters", maxDisplayNameLength)
+		}
+
+		for _, character := range *displayName {
+			if unicode.IsControl(character) {
+				return fmt.Errorf("Display name cannot contain control characters")
+			}
+		}
+	}
+
+	if email != nil && *email != "" {
+		// Only plain addresses are accepted, not ones with a name like
+		// "Alice <alice@example.com>".
+		address, err := mail.ParseAddress(*email)
+		if err != nil || address.Address != *email || len(*email) > maxEmailLength {
+			return fmt.Errorf("Invalid email address '%s'", *email)
+		}
+	}
+
+	if externalID != nil && len(*externalID) > maxExternalIDLength {
+		return fmt.Errorf("External ID cannot be longer than %d characters", maxExternalIDLength)
+	}
+
+	if len(metadata) > maxMetadataEntries {
+		return fmt.Errorf("Cannot have more than %d metadata entries", maxMetadataEntries)
+	}
+
+	for key, value := range metadata {
+		if !metadataKeyRegex.MatchString(key) {
+			return fmt.Errorf("Invalid metadata key '%s'", key)
+		}
+
+		if len(value) > maxMetadataValueLength {
+			return fmt.Errorf("Metadata '%s' cannot be longer than %d characters", key, maxMetadataValueLength)
+		}
+	}
+
+	return nil
+}
+
+// explainUniqueViolation turns a clash with another user's email
+// address or external ID into a validation error. Nil is returned for
+// other errors.
+func explainUniqueViolation(pqErr *pq.Error) error {
+	if pqErr.Code.Name() != "unique_violation" {
+		return nil
+	}
+
+	switch pqErr.Constraint {
+	case "users_email_key":
+		return errortypes.NewValidationError("Email address is already used by another user")
+
+	case "users_external_id_key":
+		return errortypes.NewValidationError("External ID is already used by another user")
+
+	default:
+		return nil
+	}
+}
+
+func encodeMetadata(metadata map[string]string) (*string, error) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	rawMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedMetadata := string(rawMetadata)
+
+	return &encodedMetadata, nil
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{
+		String: value,
+		Valid:  value != "",
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var user User
+	var email sql.NullString
+	var externalID sql.NullString
+	var rawMetadata []byte
+
+	err := row.Scan(
+		&user.id,
+		&user.username,
+		&user.displayName,
+		&email,
+		&user.emailVerified,
+		&externalID,
+		&rawMetadata,
+		&user.status,
+		&user.version,
+		&user.createdOn,
+		&user.modifiedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.email = email.String
+	user.externalID = externalID.String
+
+	if err := json.Unmarshal(rawMetadata, &user.metadata); err != nil {
+		return nil, fmt.Errorf("Unable to parse user metadata: %w", err)
+	}
+
 	return &user, nil
 }
@@ -0,0 +1,72 @@
+package user_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/user"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUserOptsShouldValidateTheProfile(t *testing.T) {
+	tooMuchMetadata := map[string]string{}
+	for index := 0; index <= 64; index++ {
+		tooMuchMetadata[fmt.Sprintf("key-%d", index)] = "value"
+	}
+
+	testCases := []struct {
+		name  string
+		opts  user.CreateUserOpts
+		valid bool
+	}{
+		{name: "username only", opts: user.CreateUserOpts{}, valid: true},
+		{
+			name: "full profile",
+			opts: user.CreateUserOpts{
+				DisplayName:   "Jane Doe",
+				Email:         "jane@example.com",
+				EmailVerified: true,
+				ExternalID:    "00u1abcd",
+				Metadata:      map[string]string{"team": "backend", "example.com/cost-center": "42"},
+			},
+			valid: true,
+		},
+		{name: "long display name", opts: user.CreateUserOpts{DisplayName: strings.Repeat("a", 129)}},
+		{name: "control characters", opts: user.CreateUserOpts{DisplayName: "Jane\nDoe"}},
+		{name: "invalid email", opts: user.CreateUserOpts{Email: "jane"}},
+		{name: "email with a name", opts: user.CreateUserOpts{Email: "Jane <jane@example.com>"}},
+		{name: "verified without an email", opts: user.CreateUserOpts{EmailVerified: true}},
+		{name: "long external ID", opts: user.CreateUserOpts{ExternalID: strings.Repeat("a", 256)}},
+		{name: "invalid metadata key", opts: user.CreateUserOpts{Metadata: map[string]string{"-team": "backend"}}},
+		{
+			name: "long metadata value",
+			opts: user.CreateUserOpts{Metadata: map[string]string{"team": strings.Repeat("a", 257)}},
+		},
+		{name: "too much metadata", opts: user.CreateUserOpts{Metadata: tooMuchMetadata}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.opts.Username = "jdoe"
+
+			err := testCase.opts.Validate()
+			if testCase.valid {
+				require.Nil(t, err, "should accept the profile")
+			} else {
+				require.NotNil(t, err, "should reject the profile")
+			}
+		})
+	}
+}
+
+func TestUpdateUserOptsShouldAllowRemovingTheEmail(t *testing.T) {
+	email := ""
+	opts := user.UpdateUserOpts{Email: &email}
+	require.Nil(t, opts.Validate(), "should accept an empty email address")
+
+	verified := true
+	opts.EmailVerified = &verified
+	require.NotNil(t, opts.Validate(), "should not verify a removed email address")
+}
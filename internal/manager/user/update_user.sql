@@ -0,0 +1,24 @@
+UPDATE Users
+SET
+    Status = COALESCE($2, Status),
+    DisplayName = COALESCE($5, DisplayName),
+    -- An empty email address or external ID removes it.
+    Email = CASE WHEN $6::TEXT IS NULL THEN Email ELSE NULLIF($6, '') END,
+    -- Changing the email address takes away its verification unless
+    -- the new address is verified too.
+    EmailVerified = COALESCE(
+        $7,
+        CASE
+            WHEN $6::TEXT IS NOT NULL AND LOWER(NULLIF($6, '')) IS DISTINCT FROM LOWER(Email) THEN FALSE
+            ELSE EmailVerified
+        END
+    ),
+    ExternalID = CASE WHEN $8::TEXT IS NULL THEN ExternalID ELSE NULLIF($8, '') END,
+    Metadata = COALESCE($9::JSONB, Metadata),
+    Version = Version + 1,
+    ModifiedOn = $3
+WHERE
+    Username = $1
+    AND ($4::BIGINT[] IS NULL OR Version = ANY($4))
+RETURNING ID, Username, DisplayName, Email, EmailVerified, ExternalID, Metadata, Status, Version, CreatedOn, ModifiedOn
+;
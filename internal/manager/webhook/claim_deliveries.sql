@@ -0,0 +1,32 @@
+WITH Claimed AS (
+    UPDATE WebhookDeliveries
+    SET
+        NextAttemptOn = $2
+    WHERE
+        ID IN (
+            SELECT
+                ID
+            FROM WebhookDeliveries
+            WHERE
+                Status = 'pending'
+                AND NextAttemptOn <= $1
+            ORDER BY NextAttemptOn
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+    RETURNING ID, SubscriptionID, EventID, Attempts
+)
+SELECT
+    Claimed.ID,
+    Claimed.Attempts,
+    WebhookSubscriptions.URL,
+    WebhookSubscriptions.Secret,
+    WebhookEvents.ID,
+    WebhookEvents.Type,
+    WebhookEvents.Payload,
+    WebhookEvents.CreatedOn
+FROM Claimed
+INNER JOIN WebhookSubscriptions ON WebhookSubscriptions.ID = Claimed.SubscriptionID
+INNER JOIN WebhookEvents ON WebhookEvents.ID = Claimed.EventID
+ORDER BY Claimed.ID
+;
@@ -0,0 +1,337 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/renderable"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+const defaultDeliveryLimit = 50
+
+// Controller handles all the HTTP requests for webhook related API's.
+type Controller struct {
+	WebhookService *Service
+}
+
+// RegisterRoutes registers HTTP request handlers for all webhook API's.
+func (controller *Controller) RegisterRoutes(router chi.Router) {
+	router.Get("/", controller.ListWebhooks)
+	router.Post("/", controller.CreateWebhook)
+	router.Get("/{id}", controller.GetWebhook)
+	router.Delete("/{id}", controller.DeleteWebhook)
+	router.Get("/{id}/deliveries", controller.ListDeliveries)
+}
+
+// CreateWebhookRequest is the expected request body for subscribing
+// to events.
+type CreateWebhookRequest struct {
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"eventTypes"`
+	Secret     string      `json:"secret"`
+}
+
+// Bind is used to determine how to map from a request body to a
+// webhook creation request.
+func (createWebhookRequest *CreateWebhookRequest) Bind(request *http.Request) error {
+	return nil
+}
+
+var _ render.Binder = (*CreateWebhookRequest)(nil)
+
+// CreateWebhookResponse is the response body for a successful webhook
+// creation.
+type CreateWebhookResponse struct {
+	RenderableWebhook
+}
+
+// Render provides a hook to customize the render process.
+func (createWebhookResponse *CreateWebhookResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*CreateWebhookResponse)(nil)
+
+// CreateWebhook handles requests to subscribe to events.
+func (controller *Controller) CreateWebhook(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	defer func() {
+		_ = request.Body.Close()
+	}()
+
+	var createWebhookRequest CreateWebhookRequest
+	if err := render.Bind(request, &createWebhookRequest); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: err.Error(),
+		})
+
+		return
+	}
+
+	subscription, err := controller.WebhookService.CreateSubscription(
+		ctx,
+		CreateSubscriptionOpts(createWebhookRequest),
+	)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	createWebhookResponse := CreateWebhookResponse{
+		RenderableWebhook: NewRenderableWebhook(subscription),
+	}
+
+	response.WriteHeader(http.StatusCreated)
+	_ = render.Render(response, request, &createWebhookResponse)
+}
+
+// GetWebhookResponse is the response body for requesting a webhook.
+type GetWebhookResponse struct {
+	RenderableWebhook
+}
+
+// Render provides a hook to customize the render process.
+func (getWebhookResponse *GetWebhookResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*GetWebhookResponse)(nil)
+
+// GetWebhook handles requests for a single webhook.
+func (controller *Controller) GetWebhook(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	subscription, err := controller.WebhookService.GetSubscription(ctx, chi.URLParam(request, "id"))
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	getWebhookResponse := GetWebhookResponse{
+		RenderableWebhook: NewRenderableWebhook(subscription),
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &getWebhookResponse)
+}
+
+// ListWebhooksResponse is the response for requesting every webhook.
+type ListWebhooksResponse struct {
+	Webhooks []RenderableWebhook `json:"webhooks"`
+}
+
+// Render provides a hook to customize the render process.
+func (listWebhooksResponse *ListWebhooksResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ListWebhooksResponse)(nil)
+
+// ListWebhooks handles requests for every webhook.
+func (controller *Controller) ListWebhooks(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	subscriptions, err := controller.WebhookService.ListSubscriptions(ctx)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listWebhooksResponse := ListWebhooksResponse{
+		Webhooks: make([]RenderableWebhook, len(subscriptions)),
+	}
+	for index := range subscriptions {
+		listWebhooksResponse.Webhooks[index] = NewRenderableWebhook(&subscriptions[index])
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listWebhooksResponse)
+}
+
+// DeleteWebhook handles requests to stop sending events to a webhook.
+func (controller *Controller) DeleteWebhook(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	if err := controller.WebhookService.DeleteSubscription(ctx, chi.URLParam(request, "id")); err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveriesResponse is the response for requesting the delivery
+// log of a webhook.
+type ListDeliveriesResponse struct {
+	Deliveries []RenderableDelivery `json:"deliveries"`
+}
+
+// Render provides a hook to customize the render process.
+func (listDeliveriesResponse *ListDeliveriesResponse) Render(
+	response http.ResponseWriter,
+	request *http.Request,
+) error {
+	return nil
+}
+
+var _ render.Renderer = (*ListDeliveriesResponse)(nil)
+
+// ListDeliveries handles requests for the most recent deliveries of a
+// webhook.
+func (controller *Controller) ListDeliveries(
+	response http.ResponseWriter,
+	request *http.Request,
+) {
+	ctx := request.Context()
+
+	limit := defaultDeliveryLimit
+	if rawLimit := request.URL.Query().Get("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			handleError(response, request, errortypes.NewWrappedValidationError(err, "Invalid limit '%s'", rawLimit))
+			return
+		}
+
+		limit = parsedLimit
+	}
+
+	deliveries, err := controller.WebhookService.ListDeliveries(ctx, chi.URLParam(request, "id"), limit)
+	if err != nil {
+		handleError(response, request, err)
+		return
+	}
+
+	listDeliveriesResponse := ListDeliveriesResponse{
+		Deliveries: make([]RenderableDelivery, len(deliveries)),
+	}
+	for index := range deliveries {
+		listDeliveriesResponse.Deliveries[index] = NewRenderableDelivery(&deliveries[index])
+	}
+
+	response.WriteHeader(http.StatusOK)
+	_ = render.Render(response, request, &listDeliveriesResponse)
+}
+
+func handleError(
+	response http.ResponseWriter,
+	request *http.Request,
+	err error,
+) {
+	var validationError errortypes.ValidationError
+	var notFoundError errortypes.NotFoundError
+	var systemError errortypes.SystemError
+	switch {
+	case errors.As(err, &validationError):
+		response.WriteHeader(http.StatusBadRequest)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: validationError.SafeMessage,
+		})
+
+	case errors.As(err, &notFoundError):
+		response.WriteHeader(http.StatusNotFound)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: notFoundError.SafeMessage,
+		})
+
+	case errors.As(err, &systemError):
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: systemError.SafeMessage,
+		})
+
+	case err != nil:
+		response.WriteHeader(http.StatusInternalServerError)
+		_ = render.Render(response, request, &renderable.ErrorResponse{
+			Message: "Internal server error, please try again later",
+		})
+	}
+}
+
+// RenderableWebhook defines what should be returned to a user for a
+// webhook. The secret is never returned.
+type RenderableWebhook struct {
+	ID         string          `json:"id"`
+	URL        string          `json:"url"`
+	EventTypes []EventType     `json:"eventTypes"`
+	CreatedOn  renderable.Time `json:"createdOn"`
+}
+
+// NewRenderableWebhook creates a new renderable webhook from a
+// backend subscription.
+func NewRenderableWebhook(subscription *Subscription) RenderableWebhook {
+	return RenderableWebhook{
+		ID:         subscription.ID(),
+		URL:        subscription.URL(),
+		EventTypes: subscription.EventTypes(),
+		CreatedOn:  renderable.Time(subscription.CreatedOn()),
+	}
+}
+
+// RenderableDelivery defines what should be returned to a user for a
+// delivery of an event to a webhook.
+type RenderableDelivery struct {
+	ID             int64            `json:"id"`
+	EventID        string           `json:"eventID"`
+	EventType      EventType        `json:"eventType"`
+	Status         DeliveryStatus   `json:"status"`
+	Attempts       int              `json:"attempts"`
+	NextAttemptOn  *renderable.Time `json:"nextAttemptOn,omitempty"`
+	LastAttemptOn  *renderable.Time `json:"lastAttemptOn,omitempty"`
+	LastStatusCode *int             `json:"lastStatusCode,omitempty"`
+	LastError      *string          `json:"lastError,omitempty"`
+	CreatedOn      renderable.Time  `json:"createdOn"`
+}
+
+// NewRenderableDelivery creates a new renderable delivery from a
+// backend delivery.
+func NewRenderableDelivery(delivery *Delivery) RenderableDelivery {
+	renderableDelivery := RenderableDelivery{
+		ID:             delivery.ID(),
+		EventID:        delivery.EventID(),
+		EventType:      delivery.EventType(),
+		Status:         delivery.Status(),
+		Attempts:       delivery.Attempts(),
+		LastStatusCode: delivery.LastStatusCode(),
+		LastError:      delivery.LastError(),
+		CreatedOn:      renderable.Time(delivery.CreatedOn()),
+	}
+
+	if nextAttemptOn := delivery.NextAttemptOn(); nextAttemptOn != nil {
+		renderableTime := renderable.Time(*nextAttemptOn)
+		renderableDelivery.NextAttemptOn = &renderableTime
+	}
+
+	if lastAttemptOn := delivery.LastAttemptOn(); lastAttemptOn != nil {
+		renderableTime := renderable.Time(*lastAttemptOn)
+		renderableDelivery.LastAttemptOn = &renderableTime
+	}
+
+	return renderableDelivery
+}
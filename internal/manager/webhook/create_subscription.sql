@@ -0,0 +1,16 @@
+INSERT INTO WebhookSubscriptions (
+    ID,
+    URL,
+    EventTypes,
+    Secret,
+    CreatedOn
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING ID, URL, EventTypes, CreatedOn
+;
@@ -0,0 +1,12 @@
+DELETE FROM WebhookEvents
+WHERE
+    CreatedOn < $1
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM WebhookDeliveries
+        WHERE
+            WebhookDeliveries.EventID = WebhookEvents.ID
+            AND WebhookDeliveries.Status = 'pending'
+    )
+;
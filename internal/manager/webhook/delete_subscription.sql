@@ -0,0 +1,4 @@
+DELETE FROM WebhookSubscriptions
+WHERE
+    ID = $1
+;
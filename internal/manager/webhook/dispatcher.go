@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/renderable"
+)
+
+const (
+	// EventIDHeaderName carries the ID of the event being delivered.
+	// It stays the same across retries.
+	EventIDHeaderName = "Ley-Webhook-ID"
+
+	// EventTypeHeaderName carries the type of the event being
+	// delivered.
+	EventTypeHeaderName = "Ley-Webhook-Event"
+
+	// TimestampHeaderName carries the Unix time the delivery was sent
+	// at. It is part of the signature so that old deliveries can't be
+	// replayed.
+	TimestampHeaderName = "Ley-Webhook-Timestamp"
+
+	// SignatureHeaderName carries the signature of the delivery made
+	// with Sign.
+	SignatureHeaderName = "Ley-Webhook-Signature"
+)
+
+// deliveryBatchSize is the most deliveries that are sent at once.
+const deliveryBatchSize = 50
+
+// Sign creates the signature of a delivery, which is the hex encoded
+// HMAC-SHA256 of the timestamp and body joined by a period, keyed with
+// the subscription's secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%d.", timestamp)
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliveryStore is where deliveries are claimed from and their
+// attempts are recorded.
+type DeliveryStore interface {
+	ClaimDeliveries(ctx context.Context, now time.Time, claimUntil time.Time, limit int) ([]PendingDelivery, error)
+	RecordAttempt(ctx context.Context, id int64, attempt Attempt) error
+}
+
+var _ DeliveryStore = (*Service)(nil)
+
+// Dispatcher sends pending deliveries to their receivers.
+type Dispatcher struct {
+	store      DeliveryStore
+	httpClient *http.Client
+	config     configuration.WebhookConfiguration
+}
+
+// NewDispatcher creates a dispatcher that sends deliveries with the
+// given HTTP client.
+func NewDispatcher(
+	store DeliveryStore,
+	httpClient *http.Client,
+	config configuration.WebhookConfiguration,
+) *Dispatcher {
+	return &Dispatcher{
+		store:      store,
+		httpClient: httpClient,
+		config:     config,
+	}
+}
+
+// Event is the body of every delivery.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      EventType       `json:"type"`
+	CreatedOn renderable.Time `json:"createdOn"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// DeliverDue sends every delivery that is due at the given time.
+// Deliveries that fail are retried later with exponential backoff
+// until they run out of attempts.
+func (dispatcher *Dispatcher) DeliverDue(ctx context.Context, now time.Time) error {
+	// A claim lasts long enough for every delivery in the batch to time
+	// out so that a slow receiver doesn't get the same event twice.
+	claimUntil := now.Add(2 * dispatcher.config.Timeout)
+	deliveries, err := dispatcher.store.ClaimDeliveries(ctx, now, claimUntil, deliveryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var waitGroup sync.WaitGroup
+	errs := make([]error, len(deliveries))
+	for index := range deliveries {
+		waitGroup.Add(1)
+		go func(index int) {
+			defer waitGroup.Done()
+
+			attempt := dispatcher.deliver(ctx, &deliveries[index])
+			errs[index] = dispatcher.store.RecordAttempt(ctx, deliveries[index].ID, attempt)
+		}(index)
+	}
+
+	waitGroup.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dispatcher *Dispatcher) deliver(ctx context.Context, delivery *PendingDelivery) Attempt {
+	attemptedOn := time.Now().UTC()
+
+	statusCode, err := dispatcher.send(ctx, delivery, attemptedOn)
+	attempt := Attempt{
+		Status:      DeliveryStatusSucceeded,
+		AttemptedOn: attemptedOn,
+	}
+
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	if err == nil {
+		return attempt
+	}
+
+	errorMessage := err.Error()
+	attempt.Error = &errorMessage
+
+	attempts := delivery.Attempts + 1
+	if attempts >= dispatcher.config.MaxAttempts {
+		attempt.Status = DeliveryStatusFailed
+
+		return attempt
+	}
+
+	nextAttemptOn := attemptedOn.Add(dispatcher.backoff(attempts))
+	attempt.Status = DeliveryStatusPending
+	attempt.NextAttemptOn = &nextAttemptOn
+
+	return attempt
+}
+
+// send posts the event to the receiver, giving the status code it
+// responded with, if any.
+func (dispatcher *Dispatcher) send(
+	ctx context.Context,
+	delivery *PendingDelivery,
+	attemptedOn time.Time,
+) (int, error) {
+	body, err := json.Marshal(&Event{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedOn: renderable.Time(delivery.EventCreatedOn),
+		Data:      delivery.EventPayload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Unable to marshal event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dispatcher.config.Timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("Unable to create request: %w", err)
+	}
+
+	timestamp := attemptedOn.Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventIDHeaderName, delivery.EventID)
+	request.Header.Set(EventTypeHeaderName, string(delivery.EventType))
+	request.Header.Set(TimestampHeaderName, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(SignatureHeaderName, Sign(delivery.Secret, timestamp, body))
+
+	// Only generic errors are kept for failed attempts since anyone
+	// that can list deliveries would otherwise learn about whatever
+	// the manager can reach.
+	response, err := dispatcher.httpClient.Do(request)
+	if err != nil {
+		if errors.Is(err, errPrivateTarget) {
+			return 0, errPrivateTarget
+		}
+
+		return 0, errors.New("Unable to reach the receiver")
+	}
+
+	_ = response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response.StatusCode, nil
+	}
+
+	return response.StatusCode, fmt.Errorf("Receiver responded with status %d", response.StatusCode)
+}
+
+// backoff is how long to wait after the given number of attempts,
+// doubling from the configured backoff up to the configured maximum.
+func (dispatcher *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := dispatcher.config.RetryBackoff
+	for attempt := 1; attempt < attempts && backoff < dispatcher.config.MaxRetryBackoff; attempt++ {
+		backoff *= 2
+	}
+
+	if backoff > dispatcher.config.MaxRetryBackoff {
+		return dispatcher.config.MaxRetryBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,247 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef"
+
+var testConfig = configuration.WebhookConfiguration{
+	Timeout:         time.Second,
+	MaxAttempts:     3,
+	RetryBackoff:    30 * time.Second,
+	MaxRetryBackoff: 45 * time.Second,
+}
+
+func TestDispatcherShouldSendSignedEvents(t *testing.T) {
+	var received []*http.Request
+	var receivedBodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		require.Nil(t, err)
+
+		received = append(received, request)
+		receivedBodies = append(receivedBodies, body)
+		response.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore()
+	store.add(receiver.URL, time.Now().UTC())
+
+	dispatcher := webhook.NewDispatcher(store, receiver.Client(), testConfig)
+	require.Nil(t, dispatcher.DeliverDue(context.Background(), time.Now().UTC()))
+
+	require.Len(t, received, 1, "should send the event")
+	request := received[0]
+	require.Equal(t, "event-1", request.Header.Get(webhook.EventIDHeaderName))
+	require.Equal(t, string(webhook.EventTypeNetworkCreated), request.Header.Get(webhook.EventTypeHeaderName))
+
+	timestamp, err := strconv.ParseInt(request.Header.Get(webhook.TimestampHeaderName), 10, 64)
+	require.Nil(t, err, "should send the timestamp")
+	require.Equal(
+		t,
+		webhook.Sign(testSecret, timestamp, receivedBodies[0]),
+		request.Header.Get(webhook.SignatureHeaderName),
+		"should sign the timestamp and body with the secret",
+	)
+
+	var event webhook.Event
+	require.Nil(t, json.Unmarshal(receivedBodies[0], &event))
+	require.Equal(t, "event-1", event.ID)
+	require.Equal(t, `{"name":"example"}`, string(event.Data), "should send the payload as the data")
+
+	attempts := store.attempts[1]
+	require.Len(t, attempts, 1)
+	require.Equal(t, webhook.DeliveryStatusSucceeded, attempts[0].Status)
+	require.Equal(t, http.StatusNoContent, *attempts[0].StatusCode)
+}
+
+func TestDispatcherShouldRetryWithExponentialBackoff(t *testing.T) {
+	requests := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		requests++
+		response.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = response.Write([]byte("try again later"))
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore()
+	store.add(receiver.URL, time.Now().UTC())
+
+	dispatcher := webhook.NewDispatcher(store, receiver.Client(), testConfig)
+	deliverAt := func(now time.Time) {
+		require.Nil(t, dispatcher.DeliverDue(context.Background(), now))
+	}
+
+	now := time.Now().UTC()
+	deliverAt(now)
+	require.Equal(t, 1, requests)
+
+	first := store.attempts[1][0]
+	require.Equal(t, webhook.DeliveryStatusPending, first.Status, "should retry a failed delivery")
+	require.Equal(t, http.StatusServiceUnavailable, *first.StatusCode)
+	require.Equal(t, "Receiver responded with status 503", *first.Error)
+	require.NotContains(t, *first.Error, "try again later", "should not keep the receiver's response")
+	require.Equal(t, testConfig.RetryBackoff, first.NextAttemptOn.Sub(first.AttemptedOn))
+
+	deliverAt(now)
+	require.Equal(t, 1, requests, "should wait for the backoff")
+
+	deliverAt(now.Add(time.Hour))
+	require.Equal(t, 2, requests)
+
+	second := store.attempts[1][1]
+	require.Equal(t, webhook.DeliveryStatusPending, second.Status)
+	require.Equal(
+		t,
+		testConfig.MaxRetryBackoff,
+		second.NextAttemptOn.Sub(second.AttemptedOn),
+		"should double the backoff up to the maximum",
+	)
+
+	deliverAt(now.Add(2 * time.Hour))
+	require.Equal(t, 3, requests)
+
+	third := store.attempts[1][2]
+	require.Equal(t, webhook.DeliveryStatusFailed, third.Status, "should give up after the last attempt")
+	require.Nil(t, third.NextAttemptOn)
+
+	deliverAt(now.Add(3 * time.Hour))
+	require.Equal(t, 3, requests, "should not send a failed delivery again")
+}
+
+func TestDispatcherShouldRetryUnreachableReceivers(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	receiverURL := receiver.URL
+	receiver.Close()
+
+	store := newMemoryStore()
+	store.add(receiverURL, time.Now().UTC())
+
+	dispatcher := webhook.NewDispatcher(store, http.DefaultClient, testConfig)
+	require.Nil(t, dispatcher.DeliverDue(context.Background(), time.Now().UTC()))
+
+	attempt := store.attempts[1][0]
+	require.Equal(t, webhook.DeliveryStatusPending, attempt.Status)
+	require.Nil(t, attempt.StatusCode, "should not have a status code without a response")
+	require.NotNil(t, attempt.Error)
+}
+
+func TestDispatcherShouldRefusePrivateReceivers(t *testing.T) {
+	requests := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		requests++
+		response.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore()
+	store.add(receiver.URL, time.Now().UTC())
+
+	dispatcher := webhook.NewDispatcher(store, webhook.NewHTTPClient(testConfig), testConfig)
+	require.Nil(t, dispatcher.DeliverDue(context.Background(), time.Now().UTC()))
+
+	require.Equal(t, 0, requests, "should not connect to a loopback address")
+	attempt := store.attempts[1][0]
+	require.Nil(t, attempt.StatusCode)
+	require.Equal(t, "Webhooks can't be sent to private addresses", *attempt.Error)
+
+	allowingConfig := testConfig
+	allowingConfig.AllowPrivateTargets = true
+	store = newMemoryStore()
+	store.add(receiver.URL, time.Now().UTC())
+
+	dispatcher = webhook.NewDispatcher(store, webhook.NewHTTPClient(allowingConfig), allowingConfig)
+	require.Nil(t, dispatcher.DeliverDue(context.Background(), time.Now().UTC()))
+	require.Equal(t, 1, requests, "should connect once private addresses are allowed")
+}
+
+type memoryDelivery struct {
+	delivery      webhook.PendingDelivery
+	status        webhook.DeliveryStatus
+	nextAttemptOn time.Time
+}
+
+type memoryStore struct {
+	lock       sync.Mutex
+	deliveries []*memoryDelivery
+	attempts   map[int64][]webhook.Attempt
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		attempts: map[int64][]webhook.Attempt{},
+	}
+}
+
+func (store *memoryStore) add(url string, dueOn time.Time) {
+	id := int64(len(store.deliveries) + 1)
+	store.deliveries = append(store.deliveries, &memoryDelivery{
+		delivery: webhook.PendingDelivery{
+			ID:             id,
+			URL:            url,
+			Secret:         testSecret,
+			EventID:        "event-" + strconv.FormatInt(id, 10),
+			EventType:      webhook.EventTypeNetworkCreated,
+			EventPayload:   json.RawMessage(`{"name":"example"}`),
+			EventCreatedOn: dueOn,
+		},
+		status:        webhook.DeliveryStatusPending,
+		nextAttemptOn: dueOn,
+	})
+}
+
+func (store *memoryStore) ClaimDeliveries(
+	ctx context.Context,
+	now time.Time,
+	claimUntil time.Time,
+	limit int,
+) ([]webhook.PendingDelivery, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	claimed := []webhook.PendingDelivery{}
+	for _, delivery := range store.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+
+		if delivery.status != webhook.DeliveryStatusPending || delivery.nextAttemptOn.After(now) {
+			continue
+		}
+
+		delivery.nextAttemptOn = claimUntil
+		claimed = append(claimed, delivery.delivery)
+	}
+
+	return claimed, nil
+}
+
+func (store *memoryStore) RecordAttempt(ctx context.Context, id int64, attempt webhook.Attempt) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	delivery := store.deliveries[id-1]
+	delivery.status = attempt.Status
+	delivery.delivery.Attempts++
+	if attempt.NextAttemptOn != nil {
+		delivery.nextAttemptOn = *attempt.NextAttemptOn
+	}
+
+	store.attempts[id] = append(store.attempts[id], attempt)
+
+	return nil
+}
@@ -0,0 +1,9 @@
+SELECT
+    ID,
+    URL,
+    EventTypes,
+    CreatedOn
+FROM WebhookSubscriptions
+WHERE
+    ID = $1
+;
@@ -0,0 +1,18 @@
+SELECT
+    WebhookDeliveries.ID,
+    WebhookEvents.ID,
+    WebhookEvents.Type,
+    WebhookDeliveries.Status,
+    WebhookDeliveries.Attempts,
+    WebhookDeliveries.NextAttemptOn,
+    WebhookDeliveries.LastAttemptOn,
+    WebhookDeliveries.LastStatusCode,
+    WebhookDeliveries.LastError,
+    WebhookDeliveries.CreatedOn
+FROM WebhookDeliveries
+INNER JOIN WebhookEvents ON WebhookEvents.ID = WebhookDeliveries.EventID
+WHERE
+    WebhookDeliveries.SubscriptionID = $1
+ORDER BY WebhookDeliveries.ID DESC
+LIMIT $2
+;
@@ -0,0 +1,8 @@
+SELECT
+    ID,
+    URL,
+    EventTypes,
+    CreatedOn
+FROM WebhookSubscriptions
+ORDER BY CreatedOn, ID
+;
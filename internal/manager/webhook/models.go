@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventType names a change that webhooks can subscribe to.
+type EventType string
+
+const (
+	// EventTypeNetworkCreated is sent when a network is created.
+	EventTypeNetworkCreated EventType = "network.created"
+
+	// EventTypeNetworkUpdated is sent when a network is changed.
+	EventTypeNetworkUpdated EventType = "network.updated"
+
+	// EventTypeNetworkDeleted is sent when a network is removed along
+	// with its nodes.
+	EventTypeNetworkDeleted EventType = "network.deleted"
+
+	// EventTypeNodeRegistered is sent when a node joins a network.
+	EventTypeNodeRegistered EventType = "node.registered"
+
+	// EventTypeNodeKeyRotated is sent when a node starts using a new
+	// key.
+	EventTypeNodeKeyRotated EventType = "node.key-rotated"
+
+	// EventTypeNodeKeyRotationRequested is sent when a node is asked to
+	// rotate its key ahead of schedule.
+	EventTypeNodeKeyRotationRequested EventType = "node.key-rotation-requested"
+
+	// EventTypeNodeRemoved is sent when a node is deleted.
+	EventTypeNodeRemoved EventType = "node.removed"
+
+	// EventTypeNodeExpired is sent when a node is removed because it
+	// reached its expiry.
+	EventTypeNodeExpired EventType = "node.expired"
+
+	// EventTypeNodeReaped is sent when an ephemeral node is removed
+	// because it was offline for too long.
+	EventTypeNodeReaped EventType = "node.reaped"
+
+	// EventTypeUserCreated is sent when a user is created.
+	EventTypeUserCreated EventType = "user.created"
+
+	// EventTypeUserUpdated is sent when a user is changed.
+	EventTypeUserUpdated EventType = "user.updated"
+
+	// EventTypeUserDeleted is sent when a user is removed.
+	EventTypeUserDeleted EventType = "user.deleted"
+)
+
+// EventTypes lists every event type that can be subscribed to.
+var EventTypes = []EventType{
+	EventTypeNetworkCreated,
+	EventTypeNetworkUpdated,
+	EventTypeNetworkDeleted,
+	EventTypeNodeRegistered,
+	EventTypeNodeKeyRotated,
+	EventTypeNodeKeyRotationRequested,
+	EventTypeNodeRemoved,
+	EventTypeNodeExpired,
+	EventTypeNodeReaped,
+	EventTypeUserCreated,
+	EventTypeUserUpdated,
+	EventTypeUserDeleted,
+}
+
+// Subscription sends events of the chosen types to a URL.
+type Subscription struct {
+	id         string
+	url        string
+	eventTypes []EventType
+	createdOn  time.Time
+}
+
+// ID gives the backend ID of the subscription.
+func (subscription *Subscription) ID() string {
+	return subscription.id
+}
+
+// URL is where events are posted to.
+func (subscription *Subscription) URL() string {
+	return subscription.url
+}
+
+// EventTypes are the types of event the subscription receives.
+func (subscription *Subscription) EventTypes() []EventType {
+	return subscription.eventTypes
+}
+
+// CreatedOn gives the date the subscription was created on.
+func (subscription *Subscription) CreatedOn() time.Time {
+	return subscription.createdOn
+}
+
+// DeliveryStatus tells where a delivery is at.
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending is used until the receiver accepts the
+	// event or every attempt has been used up.
+	DeliveryStatusPending DeliveryStatus = "pending"
+
+	// DeliveryStatusSucceeded is used once the receiver responded with
+	// a 2xx status code.
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+
+	// DeliveryStatusFailed is used when the receiver never accepted the
+	// event. It won't be tried again.
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+// Delivery is an event being sent to a single subscription.
+type Delivery struct {
+	id             int64
+	eventID        string
+	eventType      EventType
+	status         DeliveryStatus
+	attempts       int
+	nextAttemptOn  *time.Time
+	lastAttemptOn  *time.Time
+	lastStatusCode *int
+	lastError      *string
+	createdOn      time.Time
+}
+
+// ID orders the delivery among the other deliveries.
+func (delivery *Delivery) ID() int64 {
+	return delivery.id
+}
+
+// EventID is the ID of the event being delivered. Receivers can use it
+// to spot an event they've already handled.
+func (delivery *Delivery) EventID() string {
+	return delivery.eventID
+}
+
+// EventType is the type of the event being delivered.
+func (delivery *Delivery) EventType() EventType {
+	return delivery.eventType
+}
+
+// Status tells where the delivery is at.
+func (delivery *Delivery) Status() DeliveryStatus {
+	return delivery.status
+}
+
+// Attempts is how many times the event has been sent.
+func (delivery *Delivery) Attempts() int {
+	return delivery.attempts
+}
+
+// NextAttemptOn is when the event will be sent next. It is only set
+// while the delivery is pending.
+func (delivery *Delivery) NextAttemptOn() *time.Time {
+	return delivery.nextAttemptOn
+}
+
+// LastAttemptOn is when the event was last sent, if ever.
+func (delivery *Delivery) LastAttemptOn() *time.Time {
+	return delivery.lastAttemptOn
+}
+
+// LastStatusCode is the status code the receiver last responded with,
+// if it responded at all.
+func (delivery *Delivery) LastStatusCode() *int {
+	return delivery.lastStatusCode
+}
+
+// LastError explains why the last attempt failed.
+func (delivery *Delivery) LastError() *string {
+	return delivery.lastError
+}
+
+// CreatedOn is when the event was recorded.
+func (delivery *Delivery) CreatedOn() time.Time {
+	return delivery.createdOn
+}
+
+// PendingDelivery is a delivery that has been claimed for sending.
+type PendingDelivery struct {
+	ID       int64
+	Attempts int
+	URL      string
+	Secret   string
+
+	EventID        string
+	EventType      EventType
+	EventPayload   json.RawMessage
+	EventCreatedOn time.Time
+}
+
+// Attempt is the outcome of sending a delivery.
+type Attempt struct {
+	Status        DeliveryStatus
+	AttemptedOn   time.Time
+	NextAttemptOn *time.Time
+	StatusCode    *int
+	Error         *string
+}
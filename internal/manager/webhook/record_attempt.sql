@@ -0,0 +1,11 @@
+UPDATE WebhookDeliveries
+SET
+    Status = $2,
+    Attempts = Attempts + 1,
+    NextAttemptOn = $3,
+    LastAttemptOn = $4,
+    LastStatusCode = $5,
+    LastError = $6
+WHERE
+    ID = $1
+;
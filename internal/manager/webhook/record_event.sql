@@ -0,0 +1,33 @@
+WITH Event AS (
+    INSERT INTO WebhookEvents (
+        ID,
+        Type,
+        Payload,
+        CreatedOn
+    )
+    VALUES (
+        $1,
+        $2,
+        $3,
+        $4
+    )
+    RETURNING ID
+)
+INSERT INTO WebhookDeliveries (
+    SubscriptionID,
+    EventID,
+    Status,
+    NextAttemptOn,
+    CreatedOn
+)
+SELECT
+    WebhookSubscriptions.ID,
+    Event.ID,
+    'pending',
+    $4,
+    $4
+FROM WebhookSubscriptions
+CROSS JOIN Event
+WHERE
+    $2 = ANY(WebhookSubscriptions.EventTypes)
+;
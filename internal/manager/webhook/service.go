@@ -0,0 +1,463 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	minSecretLength = 16
+	maxSecretLength = 255
+
+	// maxDeliveryLimit is the most deliveries that are returned at once.
+	maxDeliveryLimit = 500
+)
+
+var (
+	//go:embed create_subscription.sql
+	createSubscriptionSQL string
+
+	//go:embed get_subscription.sql
+	getSubscriptionSQL string
+
+	//go:embed list_subscriptions.sql
+	listSubscriptionsSQL string
+
+	//go:embed delete_subscription.sql
+	deleteSubscriptionSQL string
+
+	//go:embed record_event.sql
+	recordEventSQL string
+
+	//go:embed claim_deliveries.sql
+	claimDeliveriesSQL string
+
+	//go:embed record_attempt.sql
+	recordAttemptSQL string
+
+	//go:embed list_deliveries.sql
+	listDeliveriesSQL string
+
+	//go:embed delete_events_before.sql
+	deleteEventsBeforeSQL string
+)
+
+// Record adds an event to the outbox along with a delivery for every
+// subscription that wants it. It has to be called in the transaction
+// making the change so that an event is recorded if and only if the
+// change is.
+func Record(ctx context.Context, tx *sql.Tx, eventType EventType, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("Unable to marshal '%s' event: %w", eventType, err)
+	}
+
+	_, err = tx.ExecContext(ctx, recordEventSQL, uuid.NewString(), eventType, payload, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("Unable to record '%s' event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// Service is a service for working with webhook subscriptions and
+// their deliveries.
+type Service struct {
+	db     *sql.DB
+	config configuration.WebhookConfiguration
+}
+
+// NewService creates a new webhook service.
+func NewService(db *sql.DB, config configuration.WebhookConfiguration) *Service {
+	return &Service{
+		db:     db,
+		config: config,
+	}
+}
+
+// CreateSubscriptionOpts gives the options for subscribing to events.
+type CreateSubscriptionOpts struct {
+	URL        string
+	EventTypes []EventType
+
+	// Secret is used to sign every delivery so that the receiver can
+	// tell that it came from the manager.
+	Secret string
+}
+
+// Validate checks that the subscription options are valid.
+func (opts *CreateSubscriptionOpts) Validate() error {
+	subscriptionURL, err := url.Parse(opts.URL)
+	if err != nil {
+		return fmt.Errorf("Invalid URL '%s': %w", opts.URL, err)
+	}
+
+	if subscriptionURL.Scheme != "http" && subscriptionURL.Scheme != "https" {
+		return fmt.Errorf("URL '%s' must use http or https", opts.URL)
+	}
+
+	if subscriptionURL.Host == "" {
+		return fmt.Errorf("URL '%s' must have a host", opts.URL)
+	}
+
+	if len(opts.EventTypes) == 0 {
+		return fmt.Errorf("At least one event type is required")
+	}
+
+	knownEventTypes := make(map[EventType]any, len(EventTypes))
+	for _, eventType := range EventTypes {
+		knownEventTypes[eventType] = nil
+	}
+
+	for _, eventType := range opts.EventTypes {
+		if _, ok := knownEventTypes[eventType]; !ok {
+			return fmt.Errorf("Unknown event type '%s'", eventType)
+		}
+	}
+
+	if len(opts.Secret) < minSecretLength || len(opts.Secret) > maxSecretLength {
+		return fmt.Errorf("Secret must be between %d and %d characters", minSecretLength, maxSecretLength)
+	}
+
+	return nil
+}
+
+// CreateSubscription starts sending events of the chosen types to a
+// URL.
+func (service *Service) CreateSubscription(
+	ctx context.Context,
+	opts CreateSubscriptionOpts,
+) (*Subscription, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errortypes.NewWrappedValidationError(err, "Unable to create webhook: %v", err)
+	}
+
+	if !service.config.AllowPrivateTargets {
+		subscriptionURL, _ := url.Parse(opts.URL)
+		if err := checkTargetHost(subscriptionURL.Hostname()); err != nil {
+			return nil, errortypes.NewWrappedValidationError(err, "Unable to create webhook: %v", err)
+		}
+	}
+
+	eventTypes := make([]string, 0, len(opts.EventTypes))
+	seenEventTypes := make(map[EventType]any, len(opts.EventTypes))
+	for _, eventType := range opts.EventTypes {
+		if _, ok := seenEventTypes[eventType]; ok {
+			continue
+		}
+
+		seenEventTypes[eventType] = nil
+		eventTypes = append(eventTypes, string(eventType))
+	}
+
+	subscription, err := scanSubscription(service.db.QueryRowContext(
+		ctx,
+		createSubscriptionSQL,
+		uuid.NewString(),
+		opts.URL,
+		pq.Array(eventTypes),
+		opts.Secret,
+		time.Now().UTC(),
+	))
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to create webhook due to a system error",
+			UnsafeMessage: "Unable to create webhook due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return subscription, nil
+}
+
+// GetSubscription finds a subscription by its ID.
+func (service *Service) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	subscription, err := scanSubscription(service.db.QueryRowContext(ctx, getSubscriptionSQL, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errortypes.NotFoundError{
+				UserError: errortypes.UserError{
+					SafeMessage:  "Could not find a webhook with that ID",
+					WrappedError: err,
+				},
+			}
+		}
+
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to get webhook due to a system error",
+			UnsafeMessage: "Unable to get webhook due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	return subscription, nil
+}
+
+// ListSubscriptions gives every subscription, oldest first.
+func (service *Service) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	rows, err := service.db.QueryContext(ctx, listSubscriptionsSQL)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list webhooks due to a system error",
+			UnsafeMessage: "Unable to list webhooks due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	subscriptions := []Subscription{}
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to list webhooks due to a system error",
+				UnsafeMessage: "Unable to read webhook row",
+				WrappedError:  err,
+			}
+		}
+
+		subscriptions = append(subscriptions, *subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list webhooks due to a system error",
+			UnsafeMessage: "Unable to iterate over webhook rows",
+			WrappedError:  err,
+		}
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteSubscription stops sending events to a subscription. Its
+// pending deliveries are dropped.
+func (service *Service) DeleteSubscription(ctx context.Context, id string) error {
+	result, err := service.db.ExecContext(ctx, deleteSubscriptionSQL, id)
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete webhook due to a system error",
+			UnsafeMessage: "Unable to delete webhook due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	deletedCount, err := result.RowsAffected()
+	if err != nil {
+		return errortypes.SystemError{
+			SafeMessage:   "Unable to delete webhook due to a system error",
+			UnsafeMessage: "Unable to count deleted webhooks",
+			WrappedError:  err,
+		}
+	}
+
+	if deletedCount == 0 {
+		return errortypes.NotFoundError{
+			UserError: errortypes.UserError{
+				SafeMessage: "Could not find a webhook with that ID",
+			},
+		}
+	}
+
+	return nil
+}
+
+// ListDeliveries gives the most recent deliveries of a subscription,
+// newest first.
+func (service *Service) ListDeliveries(
+	ctx context.Context,
+	subscriptionID string,
+	limit int,
+) ([]Delivery, error) {
+	if limit < 1 || limit > maxDeliveryLimit {
+		return nil, errortypes.NewValidationError("Delivery limit must be between 1 and %d", maxDeliveryLimit)
+	}
+
+	if _, err := service.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := service.db.QueryContext(ctx, listDeliveriesSQL, subscriptionID, limit)
+	if err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list webhook deliveries due to a system error",
+			UnsafeMessage: "Unable to list webhook deliveries due to a system error",
+			WrappedError:  err,
+		}
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var delivery Delivery
+		var nextAttemptOn sql.NullTime
+		var lastAttemptOn sql.NullTime
+		var lastStatusCode sql.NullInt32
+		var lastError sql.NullString
+		err := rows.Scan(
+			&delivery.id,
+			&delivery.eventID,
+			&delivery.eventType,
+			&delivery.status,
+			&delivery.attempts,
+			&nextAttemptOn,
+			&lastAttemptOn,
+			&lastStatusCode,
+			&lastError,
+			&delivery.createdOn,
+		)
+		if err != nil {
+			return nil, errortypes.SystemError{
+				SafeMessage:   "Unable to list webhook deliveries due to a system error",
+				UnsafeMessage: "Unable to read webhook delivery row",
+				WrappedError:  err,
+			}
+		}
+
+		if delivery.status == DeliveryStatusPending && nextAttemptOn.Valid {
+			delivery.nextAttemptOn = &nextAttemptOn.Time
+		}
+
+		if lastAttemptOn.Valid {
+			delivery.lastAttemptOn = &lastAttemptOn.Time
+		}
+
+		if lastStatusCode.Valid {
+			statusCode := int(lastStatusCode.Int32)
+			delivery.lastStatusCode = &statusCode
+		}
+
+		if lastError.Valid {
+			delivery.lastError = &lastError.String
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errortypes.SystemError{
+			SafeMessage:   "Unable to list webhook deliveries due to a system error",
+			UnsafeMessage: "Unable to iterate over webhook delivery rows",
+			WrappedError:  err,
+		}
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDeliveries picks up to limit deliveries that are due and hides
+// them from other claims until the given time. Deliveries that aren't
+// finished by then are picked up again.
+func (service *Service) ClaimDeliveries(
+	ctx context.Context,
+	now time.Time,
+	claimUntil time.Time,
+	limit int,
+) ([]PendingDelivery, error) {
+	rows, err := service.db.QueryContext(ctx, claimDeliveriesSQL, now, claimUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to claim webhook deliveries: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	deliveries := []PendingDelivery{}
+	for rows.Next() {
+		var delivery PendingDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.Attempts,
+			&delivery.URL,
+			&delivery.Secret,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.EventPayload,
+			&delivery.EventCreatedOn,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read webhook delivery row: %w", err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to iterate over webhook delivery rows: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt saves the outcome of sending a delivery.
+func (service *Service) RecordAttempt(ctx context.Context, id int64, attempt Attempt) error {
+	_, err := service.db.ExecContext(
+		ctx,
+		recordAttemptSQL,
+		id,
+		attempt.Status,
+		attempt.NextAttemptOn,
+		attempt.AttemptedOn,
+		attempt.StatusCode,
+		attempt.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("Unable to record attempt of webhook delivery %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// DeleteEventsBefore removes events recorded before the cutoff along
+// with their deliveries, unless a delivery is still pending. The
+// number of removed events is returned.
+func (service *Service) DeleteEventsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := service.db.ExecContext(ctx, deleteEventsBeforeSQL, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to delete webhook events: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Unable to count deleted webhook events: %w", err)
+	}
+
+	return deleted, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubscription(row rowScanner) (*Subscription, error) {
+	var subscription Subscription
+	var eventTypes []string
+	err := row.Scan(&subscription.id, &subscription.url, pq.Array(&eventTypes), &subscription.createdOn)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.eventTypes = make([]EventType, len(eventTypes))
+	for index, eventType := range eventTypes {
+		subscription.eventTypes[index] = EventType(eventType)
+	}
+
+	return &subscription, nil
+}
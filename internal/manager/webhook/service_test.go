@@ -0,0 +1,37 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/errortypes"
+	"github.com/durandj/ley/internal/manager/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSubscriptionShouldRejectPrivateTargets(t *testing.T) {
+	service := webhook.NewService(nil, configuration.WebhookConfiguration{})
+
+	for _, url := range []string{
+		"http://localhost/hook",
+		"http://api.localhost/hook",
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+	} {
+		_, err := service.CreateSubscription(context.Background(), webhook.CreateSubscriptionOpts{
+			URL:        url,
+			EventTypes: []webhook.EventType{webhook.EventTypeNetworkCreated},
+			Secret:     testSecret,
+		})
+
+		var validationError errortypes.ValidationError
+		require.True(t, errors.As(err, &validationError), "should reject %s", url)
+	}
+}
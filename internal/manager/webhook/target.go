@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/durandj/ley/internal/manager/configuration"
+)
+
+// errPrivateTarget is returned when a webhook would be sent to an
+// address that isn't allowed.
+var errPrivateTarget = errors.New("Webhooks can't be sent to private addresses")
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't public
+// either even though net.IP doesn't count it as private.
+var sharedAddressSpace = &net.IPNet{
+	IP:   net.IPv4(100, 64, 0, 0),
+	Mask: net.CIDRMask(10, 32),
+}
+
+// NewHTTPClient creates the client that deliveries are sent with.
+// Unless private targets are allowed it refuses to connect to
+// loopback, link-local and private addresses, whatever the name of the
+// receiver resolves to, so redirects and DNS changes can't get around
+// it either.
+func NewHTTPClient(config configuration.WebhookConfiguration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+	}
+	if !config.AllowPrivateTargets {
+		dialer.Control = func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return errPrivateTarget
+			}
+
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection on our behalf without the
+	// address being checked.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+// checkTargetHost rejects hosts that are plainly not public. Names are
+// checked again once they are resolved when deliveries are sent.
+func checkTargetHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("Host '%s' is not a public address", host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !isPublic(ip) {
+		return fmt.Errorf("Host '%s' is not a public address", host)
+	}
+
+	return nil
+}
+
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,8 @@
+// Package leyv1 is the gRPC API of the manager. It is generated from the
+// protocol buffers in api/ley/v1.
+package leyv1
+
+//go:generate -command messages protoc -I ../../../api --go_out=../../.. --go_opt=module=github.com/durandj/ley
+//go:generate -command grpc protoc -I ../../../api --go-grpc_out=../../.. --go-grpc_opt=module=github.com/durandj/ley
+//go:generate messages ley/v1/listing.proto ley/v1/user.proto ley/v1/network.proto ley/v1/node.proto
+//go:generate grpc ley/v1/user.proto ley/v1/network.proto ley/v1/node.proto
@@ -0,0 +1,515 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: ley/v1/node.proto
+
+package leyv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	NodeService_RegisterNode_FullMethodName       = "/ley.v1.NodeService/RegisterNode"
+	NodeService_GetNode_FullMethodName            = "/ley.v1.NodeService/GetNode"
+	NodeService_ListNodes_FullMethodName          = "/ley.v1.NodeService/ListNodes"
+	NodeService_DeleteNode_FullMethodName         = "/ley.v1.NodeService/DeleteNode"
+	NodeService_RotateNodeKey_FullMethodName      = "/ley.v1.NodeService/RotateNodeKey"
+	NodeService_RequestKeyRotation_FullMethodName = "/ley.v1.NodeService/RequestKeyRotation"
+	NodeService_GetNodeConfig_FullMethodName      = "/ley.v1.NodeService/GetNodeConfig"
+	NodeService_ListNodeEvents_FullMethodName     = "/ley.v1.NodeService/ListNodeEvents"
+	NodeService_RecordHeartbeat_FullMethodName    = "/ley.v1.NodeService/RecordHeartbeat"
+	NodeService_ListPeerStats_FullMethodName      = "/ley.v1.NodeService/ListPeerStats"
+	NodeService_WatchNodeConfig_FullMethodName    = "/ley.v1.NodeService/WatchNodeConfig"
+)
+
+// NodeServiceClient is the client API for NodeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NodeServiceClient interface {
+	RegisterNode(ctx context.Context, in *RegisterNodeRequest, opts ...grpc.CallOption) (*Node, error)
+	GetNode(ctx context.Context, in *GetNodeRequest, opts ...grpc.CallOption) (*Node, error)
+	ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error)
+	DeleteNode(ctx context.Context, in *DeleteNodeRequest, opts ...grpc.CallOption) (*DeleteNodeResponse, error)
+	RotateNodeKey(ctx context.Context, in *RotateNodeKeyRequest, opts ...grpc.CallOption) (*Node, error)
+	RequestKeyRotation(ctx context.Context, in *RequestKeyRotationRequest, opts ...grpc.CallOption) (*Node, error)
+	GetNodeConfig(ctx context.Context, in *GetNodeConfigRequest, opts ...grpc.CallOption) (*NodeConfig, error)
+	ListNodeEvents(ctx context.Context, in *ListNodeEventsRequest, opts ...grpc.CallOption) (*ListNodeEventsResponse, error)
+	RecordHeartbeat(ctx context.Context, in *RecordHeartbeatRequest, opts ...grpc.CallOption) (*Node, error)
+	ListPeerStats(ctx context.Context, in *ListPeerStatsRequest, opts ...grpc.CallOption) (*ListPeerStatsResponse, error)
+	// WatchNodeConfig sends the config of a node straight away, unless its
+	// revision is last_revision, and then again whenever it changes. The
+	// stream ends with a removed event when the node is deleted. Changes
+	// made through any manager replica are sent.
+	WatchNodeConfig(ctx context.Context, in *WatchNodeConfigRequest, opts ...grpc.CallOption) (NodeService_WatchNodeConfigClient, error)
+}
+
+type nodeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNodeServiceClient(cc grpc.ClientConnInterface) NodeServiceClient {
+	return &nodeServiceClient{cc}
+}
+
+func (c *nodeServiceClient) RegisterNode(ctx context.Context, in *RegisterNodeRequest, opts ...grpc.CallOption) (*Node, error) {
+	out := new(Node)
+	err := c.cc.Invoke(ctx, NodeService_RegisterNode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) GetNode(ctx context.Context, in *GetNodeRequest, opts ...grpc.CallOption) (*Node, error) {
+	out := new(Node)
+	err := c.cc.Invoke(ctx, NodeService_GetNode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error) {
+	out := new(ListNodesResponse)
+	err := c.cc.Invoke(ctx, NodeService_ListNodes_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) DeleteNode(ctx context.Context, in *DeleteNodeRequest, opts ...grpc.CallOption) (*DeleteNodeResponse, error) {
+	out := new(DeleteNodeResponse)
+	err := c.cc.Invoke(ctx, NodeService_DeleteNode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) RotateNodeKey(ctx context.Context, in *RotateNodeKeyRequest, opts ...grpc.CallOption) (*Node, error) {
+	out := new(Node)
+	err := c.cc.Invoke(ctx, NodeService_RotateNodeKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) RequestKeyRotation(ctx context.Context, in *RequestKeyRotationRequest, opts ...grpc.CallOption) (*Node, error) {
+	out := new(Node)
+	err := c.cc.Invoke(ctx, NodeService_RequestKeyRotation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) GetNodeConfig(ctx context.Context, in *GetNodeConfigRequest, opts ...grpc.CallOption) (*NodeConfig, error) {
+	out := new(NodeConfig)
+	err := c.cc.Invoke(ctx, NodeService_GetNodeConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) ListNodeEvents(ctx context.Context, in *ListNodeEventsRequest, opts ...grpc.CallOption) (*ListNodeEventsResponse, error) {
+	out := new(ListNodeEventsResponse)
+	err := c.cc.Invoke(ctx, NodeService_ListNodeEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) RecordHeartbeat(ctx context.Context, in *RecordHeartbeatRequest, opts ...grpc.CallOption) (*Node, error) {
+	out := new(Node)
+	err := c.cc.Invoke(ctx, NodeService_RecordHeartbeat_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) ListPeerStats(ctx context.Context, in *ListPeerStatsRequest, opts ...grpc.CallOption) (*ListPeerStatsResponse, error) {
+	out := new(ListPeerStatsResponse)
+	err := c.cc.Invoke(ctx, NodeService_ListPeerStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) WatchNodeConfig(ctx context.Context, in *WatchNodeConfigRequest, opts ...grpc.CallOption) (NodeService_WatchNodeConfigClient, error) {
+	stream, err := c.cc.NewStream(ctx, &NodeService_ServiceDesc.Streams[0], NodeService_WatchNodeConfig_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &nodeServiceWatchNodeConfigClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type NodeService_WatchNodeConfigClient interface {
+	Recv() (*WatchNodeConfigResponse, error)
+	grpc.ClientStream
+}
+
+type nodeServiceWatchNodeConfigClient struct {
+	grpc.ClientStream
+}
+
+func (x *nodeServiceWatchNodeConfigClient) Recv() (*WatchNodeConfigResponse, error) {
+	m := new(WatchNodeConfigResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NodeServiceServer is the server API for NodeService service.
+// All implementations must embed UnimplementedNodeServiceServer
+// for forward compatibility
+type NodeServiceServer interface {
+	RegisterNode(context.Context, *RegisterNodeRequest) (*Node, error)
+	GetNode(context.Context, *GetNodeRequest) (*Node, error)
+	ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error)
+	DeleteNode(context.Context, *DeleteNodeRequest) (*DeleteNodeResponse, error)
+	RotateNodeKey(context.Context, *RotateNodeKeyRequest) (*Node, error)
+	RequestKeyRotation(context.Context, *RequestKeyRotationRequest) (*Node, error)
+	GetNodeConfig(context.Context, *GetNodeConfigRequest) (*NodeConfig, error)
+	ListNodeEvents(context.Context, *ListNodeEventsRequest) (*ListNodeEventsResponse, error)
+	RecordHeartbeat(context.Context, *RecordHeartbeatRequest) (*Node, error)
+	ListPeerStats(context.Context, *ListPeerStatsRequest) (*ListPeerStatsResponse, error)
+	// WatchNodeConfig sends the config of a node straight away, unless its
+	// revision is last_revision, and then again whenever it changes. The
+	// stream ends with a removed event when the node is deleted. Changes
+	// made through any manager replica are sent.
+	WatchNodeConfig(*WatchNodeConfigRequest, NodeService_WatchNodeConfigServer) error
+	mustEmbedUnimplementedNodeServiceServer()
+}
+
+// UnimplementedNodeServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedNodeServiceServer struct {
+}
+
+func (UnimplementedNodeServiceServer) RegisterNode(context.Context, *RegisterNodeRequest) (*Node, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterNode not implemented")
+}
+func (UnimplementedNodeServiceServer) GetNode(context.Context, *GetNodeRequest) (*Node, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNode not implemented")
+}
+func (UnimplementedNodeServiceServer) ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNodes not implemented")
+}
+func (UnimplementedNodeServiceServer) DeleteNode(context.Context, *DeleteNodeRequest) (*DeleteNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteNode not implemented")
+}
+func (UnimplementedNodeServiceServer) RotateNodeKey(context.Context, *RotateNodeKeyRequest) (*Node, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateNodeKey not implemented")
+}
+func (UnimplementedNodeServiceServer) RequestKeyRotation(context.Context, *RequestKeyRotationRequest) (*Node, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestKeyRotation not implemented")
+}
+func (UnimplementedNodeServiceServer) GetNodeConfig(context.Context, *GetNodeConfigRequest) (*NodeConfig, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeConfig not implemented")
+}
+func (UnimplementedNodeServiceServer) ListNodeEvents(context.Context, *ListNodeEventsRequest) (*ListNodeEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNodeEvents not implemented")
+}
+func (UnimplementedNodeServiceServer) RecordHeartbeat(context.Context, *RecordHeartbeatRequest) (*Node, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordHeartbeat not implemented")
+}
+func (UnimplementedNodeServiceServer) ListPeerStats(context.Context, *ListPeerStatsRequest) (*ListPeerStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeerStats not implemented")
+}
+func (UnimplementedNodeServiceServer) WatchNodeConfig(*WatchNodeConfigRequest, NodeService_WatchNodeConfigServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchNodeConfig not implemented")
+}
+func (UnimplementedNodeServiceServer) mustEmbedUnimplementedNodeServiceServer() {}
+
+// UnsafeNodeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NodeServiceServer will
+// result in compilation errors.
+type UnsafeNodeServiceServer interface {
+	mustEmbedUnimplementedNodeServiceServer()
+}
+
+func RegisterNodeServiceServer(s grpc.ServiceRegistrar, srv NodeServiceServer) {
+	s.RegisterService(&NodeService_ServiceDesc, srv)
+}
+
+func _NodeService_RegisterNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).RegisterNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_RegisterNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).RegisterNode(ctx, req.(*RegisterNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_GetNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).GetNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_GetNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).GetNode(ctx, req.(*GetNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_ListNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).ListNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_ListNodes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).ListNodes(ctx, req.(*ListNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_DeleteNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).DeleteNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_DeleteNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).DeleteNode(ctx, req.(*DeleteNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_RotateNodeKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateNodeKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).RotateNodeKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_RotateNodeKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).RotateNodeKey(ctx, req.(*RotateNodeKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_RequestKeyRotation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestKeyRotationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).RequestKeyRotation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_RequestKeyRotation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).RequestKeyRotation(ctx, req.(*RequestKeyRotationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_GetNodeConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodeConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).GetNodeConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_GetNodeConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).GetNodeConfig(ctx, req.(*GetNodeConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_ListNodeEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNodeEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).ListNodeEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_ListNodeEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).ListNodeEvents(ctx, req.(*ListNodeEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_RecordHeartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordHeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).RecordHeartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_RecordHeartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).RecordHeartbeat(ctx, req.(*RecordHeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_ListPeerStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPeerStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).ListPeerStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_ListPeerStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).ListPeerStats(ctx, req.(*ListPeerStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_WatchNodeConfig_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchNodeConfigRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NodeServiceServer).WatchNodeConfig(m, &nodeServiceWatchNodeConfigServer{stream})
+}
+
+type NodeService_WatchNodeConfigServer interface {
+	Send(*WatchNodeConfigResponse) error
+	grpc.ServerStream
+}
+
+type nodeServiceWatchNodeConfigServer struct {
+	grpc.ServerStream
+}
+
+func (x *nodeServiceWatchNodeConfigServer) Send(m *WatchNodeConfigResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// NodeService_ServiceDesc is the grpc.ServiceDesc for NodeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NodeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ley.v1.NodeService",
+	HandlerType: (*NodeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterNode",
+			Handler:    _NodeService_RegisterNode_Handler,
+		},
+		{
+			MethodName: "GetNode",
+			Handler:    _NodeService_GetNode_Handler,
+		},
+		{
+			MethodName: "ListNodes",
+			Handler:    _NodeService_ListNodes_Handler,
+		},
+		{
+			MethodName: "DeleteNode",
+			Handler:    _NodeService_DeleteNode_Handler,
+		},
+		{
+			MethodName: "RotateNodeKey",
+			Handler:    _NodeService_RotateNodeKey_Handler,
+		},
+		{
+			MethodName: "RequestKeyRotation",
+			Handler:    _NodeService_RequestKeyRotation_Handler,
+		},
+		{
+			MethodName: "GetNodeConfig",
+			Handler:    _NodeService_GetNodeConfig_Handler,
+		},
+		{
+			MethodName: "ListNodeEvents",
+			Handler:    _NodeService_ListNodeEvents_Handler,
+		},
+		{
+			MethodName: "RecordHeartbeat",
+			Handler:    _NodeService_RecordHeartbeat_Handler,
+		},
+		{
+			MethodName: "ListPeerStats",
+			Handler:    _NodeService_ListPeerStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchNodeConfig",
+			Handler:       _NodeService_WatchNodeConfig_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ley/v1/node.proto",
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Apply makes the networks and users match the resources of a
+// manifest, or only plans the changes for a dry run.
+func (client *Client) Apply(ctx context.Context, applyRequest ApplyRequest) (*ApplyResponse, error) {
+	var applyResponse ApplyResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		"/apply",
+		nil,
+		nil,
+		applyRequest,
+		http.StatusOK,
+		&applyResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &applyResponse, nil
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetSession fetches the session that the client's token belongs to.
+// The token has to be a session token from logging in with single
+// sign-on.
+func (client *Client) GetSession(ctx context.Context) (*GetSessionResponse, error) {
+	var getSessionResponse GetSessionResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/auth/session",
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&getSessionResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getSessionResponse, nil
+}
+
+// Logout ends the session that the client's token belongs to.
+func (client *Client) Logout(ctx context.Context) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		"/auth/session",
+		nil,
+		nil,
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
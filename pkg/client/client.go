@@ -0,0 +1,286 @@
+// Package client provides a typed Go client for the Ley manager API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultMaxRetries is the number of times an idempotent request
+	// is retried when no other value is configured.
+	DefaultMaxRetries = 3
+
+	// DefaultRetryBackoff is the delay before the first retry when no
+	// other value is configured. Each following retry doubles it.
+	DefaultRetryBackoff = 250 * time.Millisecond
+
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// Client makes requests against the Ley manager API.
+type Client struct {
+	serverURL       string
+	token           string
+	organization    string
+	httpClient      *http.Client
+	maxRetries      int
+	retryBackoff    time.Duration
+	idempotencyKeys bool
+}
+
+// Opts gives the optional settings for a client.
+type Opts struct {
+	// Token is sent as a bearer token with every request when it is
+	// not empty. Nodes use the secret they were registered with, which
+	// the calls made by nodes, like GetNodeConfig, need.
+	Token string
+
+	// Organization is the organization whose networks and nodes are
+	// worked with. The default organization is used when this is empty.
+	Organization string
+
+	// HTTPClient is used to send requests. A default client is used
+	// when this is nil.
+	HTTPClient *http.Client
+
+	// MaxRetries is how many times an idempotent request is retried
+	// after a connection failure or a 5xx response. Rate limited
+	// requests are retried once the manager says they can be, whatever
+	// the request. Zero uses DefaultMaxRetries and a negative value
+	// disables retries.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry. Zero uses
+	// DefaultRetryBackoff.
+	RetryBackoff time.Duration
+
+	// IdempotencyKeys sends a fresh Idempotency-Key with every create
+	// request, which lets creates be retried like idempotent requests
+	// without the risk of creating something twice.
+	IdempotencyKeys bool
+}
+
+// New creates a client for the manager running at the given URL.
+func New(serverURL string, opts Opts) *Client {
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	maxRetries := opts.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = DefaultMaxRetries
+
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+
+	retryBackoff := opts.RetryBackoff
+	if retryBackoff == 0 {
+		retryBackoff = DefaultRetryBackoff
+	}
+
+	return &Client{
+		serverURL:       strings.TrimSuffix(serverURL, "/"),
+		token:           opts.Token,
+		organization:    opts.Organization,
+		httpClient:      httpClient,
+		maxRetries:      maxRetries,
+		retryBackoff:    retryBackoff,
+		idempotencyKeys: opts.IdempotencyKeys,
+	}
+}
+
+// Ping checks that the manager is up and able to handle requests.
+func (client *Client) Ping(ctx context.Context) error {
+	return client.do(ctx, http.MethodGet, "/healthcheck", nil, nil, nil, http.StatusOK, nil)
+}
+
+func (client *Client) do(
+	ctx context.Context,
+	method string,
+	path string,
+	query url.Values,
+	header http.Header,
+	requestBody any,
+	expectedStatus int,
+	responseBody any,
+) error {
+	requestURL := client.serverURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	var requestBytes []byte
+	if requestBody != nil {
+		var err error
+		requestBytes, err = json.Marshal(requestBody)
+		if err != nil {
+			return fmt.Errorf("Unable to marshal request body: %w", err)
+		}
+	}
+
+	if client.idempotencyKeys && method == http.MethodPost && header.Get(idempotencyKeyHeader) == "" {
+		header = header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+
+		header.Set(idempotencyKeyHeader, uuid.NewString())
+	}
+
+	safeToRepeat := isIdempotent(method) || header.Get(idempotencyKeyHeader) != ""
+
+	var err error
+	for attempt := 0; attempt <= client.maxRetries; attempt++ {
+		if attempt > 0 {
+			if sleepErr := sleep(ctx, client.retryDelay(attempt, err)); sleepErr != nil {
+				return err
+			}
+		}
+
+		var retryable bool
+		retryable, err = client.attempt(
+			ctx,
+			method,
+			requestURL,
+			header,
+			requestBytes,
+			expectedStatus,
+			responseBody,
+		)
+		if err == nil || !retryable {
+			return err
+		}
+
+		// Requests that were rate limited never reached the handler so
+		// they can be sent again whatever they do.
+		var tooManyRequestsError TooManyRequestsError
+		if !safeToRepeat && !errors.As(err, &tooManyRequestsError) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// retryDelay backs off exponentially unless the manager said how long
+// to wait.
+func (client *Client) retryDelay(attempt int, err error) time.Duration {
+	delay := client.retryBackoff << (attempt - 1)
+
+	var tooManyRequestsError TooManyRequestsError
+	if errors.As(err, &tooManyRequestsError) && tooManyRequestsError.RetryAfter > delay {
+		return tooManyRequestsError.RetryAfter
+	}
+
+	return delay
+}
+
+func (client *Client) attempt(
+	ctx context.Context,
+	method string,
+	requestURL string,
+	header http.Header,
+	requestBytes []byte,
+	expectedStatus int,
+	responseBody any,
+) (bool, error) {
+	var bodyReader io.Reader
+	if requestBytes != nil {
+		bodyReader = bytes.NewReader(requestBytes)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
+	if err != nil {
+		return false, fmt.Errorf("Unable to create request: %w", err)
+	}
+
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	request.Header.Set("Accept", "application/json")
+	if requestBytes != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	if client.token != "" {
+		request.Header.Set("Authorization", "Bearer "+client.token)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("Unable to complete request: %w", err)
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode != expectedStatus {
+		retryable := response.StatusCode >= http.StatusInternalServerError ||
+			response.StatusCode == http.StatusTooManyRequests
+
+		return retryable, newErrorFromResponse(response)
+	}
+
+	if responseBody == nil {
+		return false, nil
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(responseBody); err != nil {
+		return false, fmt.Errorf("Unable to parse response body: %w", err)
+	}
+
+	return false, nil
+}
+
+// ifMatch creates the headers for a change that should only be made
+// if the resource is still at the expected version. Zero means any
+// version.
+func ifMatch(expectedVersion int64) http.Header {
+	if expectedVersion == 0 {
+		return nil
+	}
+
+	return http.Header{
+		"If-Match": []string{`"` + strconv.FormatInt(expectedVersion, 10) + `"`},
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,927 @@
+package client_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/durandj/ley/internal/common/rng"
+	"github.com/durandj/ley/internal/manager"
+	"github.com/durandj/ley/internal/manager/configuration"
+	"github.com/durandj/ley/internal/manager/node"
+	"github.com/durandj/ley/pkg/client"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestClientShouldCreateAndGetAUser(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	username := newUsername()
+	createUserResponse, err := apiClient.CreateUser(ctx, client.CreateUserRequest{Name: username})
+	require.Nil(t, err, "should be able to create a user")
+	require.Equal(t, username, createUserResponse.Name, "should have requested user name")
+	require.Equal(t, client.UserStatusActive, createUserResponse.Status, "should be active")
+
+	getUserResponse, err := apiClient.GetUserByUsername(ctx, username)
+	require.Nil(t, err, "should be able to get the user")
+	require.Equal(
+		t,
+		createUserResponse.User,
+		getUserResponse.User,
+		"should have returned the created user",
+	)
+}
+
+func TestClientShouldReturnAValidationErrorForADuplicateUser(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	username := newUsername()
+	_, err := apiClient.CreateUser(ctx, client.CreateUserRequest{Name: username})
+	require.Nil(t, err, "should be able to create a user")
+
+	_, err = apiClient.CreateUser(ctx, client.CreateUserRequest{Name: username})
+
+	var validationError client.ValidationError
+	require.True(t, errors.As(err, &validationError), "should be a validation error")
+	require.Equal(t, http.StatusBadRequest, validationError.StatusCode)
+	require.Equal(t, "Username is already taken", validationError.Message)
+}
+
+func TestClientShouldReturnANotFoundErrorForAMissingUser(t *testing.T) {
+	apiClient := newTestClient(t)
+
+	_, err := apiClient.GetUserByUsername(context.Background(), "doesnotexist")
+
+	var notFoundError client.NotFoundError
+	require.True(t, errors.As(err, &notFoundError), "should be a not found error")
+	require.Equal(t, http.StatusNotFound, notFoundError.StatusCode)
+	require.Equal(t, "Could not find a user with that name", notFoundError.Message)
+}
+
+func TestClientShouldCreateAndListNetworks(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	networkName := fmt.Sprintf("client-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.0.0.0/24")
+	createNetworkResponse, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+	require.Equal(t, networkName, createNetworkResponse.Name, "should have requested network name")
+	require.Equal(t, &prefix, createNetworkResponse.IPv4CIDR, "should have requested CIDR")
+
+	listNetworksResponse, err := apiClient.ListNetworks(ctx, client.ListOpts{NamePrefix: networkName})
+	require.Nil(t, err, "should be able to list networks")
+	require.Equal(
+		t,
+		[]client.Network{createNetworkResponse.Network},
+		listNetworksResponse.Networks,
+		"should list the created network",
+	)
+}
+
+func TestClientShouldPageThroughNetworks(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	namePrefix := fmt.Sprintf("client-page-%d-", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.1.0.0/24")
+
+	var networkNames []string
+	for index := 0; index < 3; index++ {
+		networkName := fmt.Sprintf("%s%d", namePrefix, index)
+		_, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+			Name:         networkName,
+			IPv4CIDR:     &prefix,
+			Labels:       map[string]string{"page": "test"},
+			AllowOverlap: true,
+		})
+		require.Nil(t, err, "should be able to create a network")
+
+		networkNames = append(networkNames, networkName)
+	}
+
+	listOpts := client.ListOpts{
+		Limit:      2,
+		NamePrefix: namePrefix,
+		Labels:     map[string]string{"page": "test"},
+		Sort:       "name",
+		Order:      "desc",
+	}
+
+	firstPage, err := apiClient.ListNetworks(ctx, listOpts)
+	require.Nil(t, err, "should be able to get the first page")
+	require.Len(t, firstPage.Networks, 2, "should return a full page")
+	require.NotEmpty(t, firstPage.NextCursor, "should have a cursor for the next page")
+
+	listOpts.Cursor = firstPage.NextCursor
+	secondPage, err := apiClient.ListNetworks(ctx, listOpts)
+	require.Nil(t, err, "should be able to get the second page")
+	require.Len(t, secondPage.Networks, 1, "should return the remaining network")
+	require.Empty(t, secondPage.NextCursor, "should not have a cursor on the last page")
+
+	require.Equal(
+		t,
+		[]string{networkNames[2], networkNames[1], networkNames[0]},
+		[]string{firstPage.Networks[0].Name, firstPage.Networks[1].Name, secondPage.Networks[0].Name},
+		"should be sorted by name in descending order",
+	)
+}
+
+func TestClientShouldRejectChangesToStaleNetworks(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	networkName := fmt.Sprintf("client-stale-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.2.0.0/24")
+	createNetworkResponse, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	originalVersion := createNetworkResponse.Version
+	updateNetworkResponse, err := apiClient.UpdateNetwork(
+		ctx,
+		networkName,
+		originalVersion,
+		client.UpdateNetworkRequest{Labels: map[string]string{"owner": "first"}},
+	)
+	require.Nil(t, err, "should be able to update the current version")
+	require.Equal(t, originalVersion+1, updateNetworkResponse.Version, "should bump the version")
+
+	_, err = apiClient.UpdateNetwork(
+		ctx,
+		networkName,
+		originalVersion,
+		client.UpdateNetworkRequest{Labels: map[string]string{"owner": "second"}},
+	)
+
+	var preconditionFailedError client.PreconditionFailedError
+	require.True(t, errors.As(err, &preconditionFailedError), "should reject the stale update")
+	require.Equal(t, http.StatusPreconditionFailed, preconditionFailedError.StatusCode)
+
+	err = apiClient.DeleteNetwork(ctx, networkName, originalVersion)
+	require.True(t, errors.As(err, &preconditionFailedError), "should reject the stale delete")
+
+	err = apiClient.DeleteNetwork(ctx, networkName, updateNetworkResponse.Version)
+	require.Nil(t, err, "should be able to delete the current version")
+
+	_, err = apiClient.GetNetwork(ctx, networkName)
+
+	var notFoundError client.NotFoundError
+	require.True(t, errors.As(err, &notFoundError), "should have deleted the network")
+}
+
+func TestClientShouldReturnAValidationErrorForAnInvalidNetwork(t *testing.T) {
+	apiClient := newTestClient(t)
+
+	_, err := apiClient.CreateNetwork(context.Background(), client.CreateNetworkRequest{
+		Name: "missing-cidr",
+	})
+
+	var validationError client.ValidationError
+	require.True(t, errors.As(err, &validationError), "should be a validation error")
+	require.Equal(
+		t,
+		"Unable to create network: Must have at least one IP range defined",
+		validationError.Message,
+	)
+}
+
+func TestClientShouldRejectOverlappingNetworks(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	prefix := newTestPrefix()
+	networkName := fmt.Sprintf("client-overlap-%d", rng.RNG.Int63())
+	_, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:     networkName,
+		IPv4CIDR: &prefix,
+	})
+	require.Nil(t, err, "should be able to create a network")
+	t.Cleanup(func() {
+		_ = apiClient.DeleteNetwork(ctx, networkName, 0)
+	})
+
+	overlappingPrefix := netaddr.IPPrefixFrom(prefix.IP(), prefix.Bits()+1)
+	_, err = apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:     networkName + "-overlap",
+		IPv4CIDR: &overlappingPrefix,
+	})
+
+	var validationError client.ValidationError
+	require.True(t, errors.As(err, &validationError), "should reject the overlapping range")
+	require.Contains(t, validationError.Message, networkName, "should name the overlapping network")
+
+	_, err = apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:         networkName + "-isolated",
+		IPv4CIDR:     &overlappingPrefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should allow the overlap when asked to")
+	t.Cleanup(func() {
+		_ = apiClient.DeleteNetwork(ctx, networkName+"-isolated", 0)
+	})
+}
+
+func TestClientShouldAssignRangesToNetworks(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	prefixLength := 28
+	createdNetworks := []*client.CreateNetworkResponse{}
+	for index := 0; index < 2; index++ {
+		networkName := fmt.Sprintf("client-assign-%d", rng.RNG.Int63())
+		createNetworkResponse, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+			Name:             networkName,
+			IPv4PrefixLength: &prefixLength,
+			GenerateIPv6:     true,
+		})
+		require.Nil(t, err, "should be able to create a network")
+		t.Cleanup(func() {
+			_ = apiClient.DeleteNetwork(ctx, networkName, 0)
+		})
+
+		require.NotNil(t, createNetworkResponse.IPv4CIDR, "should have been given an IPv4 range")
+		require.Equal(t, uint8(prefixLength), createNetworkResponse.IPv4CIDR.Bits(), "should have the requested size")
+		require.True(t, testSupernet.Contains(createNetworkResponse.IPv4CIDR.IP()), "should come from the pool")
+
+		require.NotNil(t, createNetworkResponse.IPv6CIDR, "should have been given an IPv6 range")
+		require.Equal(t, uint8(64), createNetworkResponse.IPv6CIDR.Bits(), "should be given a /64")
+		require.Equal(t, byte(0xfd), createNetworkResponse.IPv6CIDR.IP().As16()[0], "should be a unique local range")
+
+		createdNetworks = append(createdNetworks, createNetworkResponse)
+	}
+
+	require.False(
+		t,
+		createdNetworks[0].IPv4CIDR.Overlaps(*createdNetworks[1].IPv4CIDR),
+		"should not give out the same range twice",
+	)
+}
+
+func TestClientShouldReportTheAddressSpace(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	prefix := newTestPrefix()
+	networkName := fmt.Sprintf("client-space-%d", rng.RNG.Int63())
+	_, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:     networkName,
+		IPv4CIDR: &prefix,
+	})
+	require.Nil(t, err, "should be able to create a network")
+	t.Cleanup(func() {
+		_ = apiClient.DeleteNetwork(ctx, networkName, 0)
+	})
+
+	addressSpace, err := apiClient.GetAddressSpace(ctx)
+	require.Nil(t, err, "should be able to get the address space")
+	require.NotNil(t, addressSpace.IPv4, "should report on the IPv4 supernet")
+	require.Equal(t, testSupernet, addressSpace.IPv4.Supernet)
+	require.Contains(
+		t,
+		addressSpace.IPv4.Used,
+		client.UsedAddressRange{Network: networkName, CIDR: prefix},
+		"should report the network's range as used",
+	)
+
+	for _, freeRange := range addressSpace.IPv4.Free {
+		require.False(t, freeRange.Overlaps(prefix), "should not report the network's range as free")
+	}
+}
+
+func TestClientShouldRotateNodeKeys(t *testing.T) {
+	serverURL := newTestServer(t)
+	apiClient := newClient(serverURL, testAdminToken)
+	ctx := context.Background()
+
+	networkName := fmt.Sprintf("client-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.1.0.0/24")
+	_, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	firstKey := newTestKey(t)
+	registerNodeResponse, err := apiClient.RegisterNode(ctx, client.RegisterNodeRequest{
+		Network:   networkName,
+		Name:      "first",
+		PublicKey: firstKey,
+	})
+	require.Nil(t, err, "should be able to register a node")
+	require.Equal(t, "10.1.0.1", registerNodeResponse.IPv4Address.String(), "should get the first address")
+	require.False(t, registerNodeResponse.KeyRotationDue, "should not need a new key yet")
+	require.NotEmpty(t, registerNodeResponse.Secret, "should issue the node a secret")
+
+	_, err = apiClient.RotateNodeKey(
+		ctx,
+		registerNodeResponse.ID,
+		0,
+		client.RotateNodeKeyRequest{PublicKey: newTestKey(t)},
+	)
+	var unauthorizedError client.UnauthorizedError
+	require.True(t, errors.As(err, &unauthorizedError), "should not rotate the key without the node's secret")
+
+	nodeClient := newClient(serverURL, registerNodeResponse.Secret)
+
+	requestKeyRotationResponse, err := apiClient.RequestNodeKeyRotation(ctx, registerNodeResponse.ID, 0)
+	require.Nil(t, err, "should be able to request a key rotation")
+	require.True(t, requestKeyRotationResponse.KeyRotationDue, "should need a new key")
+
+	secondKey := newTestKey(t)
+	rotateNodeKeyResponse, err := nodeClient.RotateNodeKey(
+		ctx,
+		registerNodeResponse.ID,
+		requestKeyRotationResponse.Version,
+		client.RotateNodeKeyRequest{PublicKey: secondKey},
+	)
+	require.Nil(t, err, "should be able to rotate the key")
+	require.Equal(t, secondKey, rotateNodeKeyResponse.PublicKey, "should use the new key")
+	require.Equal(t, &firstKey, rotateNodeKeyResponse.PreviousPublicKey, "should keep the old key")
+	require.False(t, rotateNodeKeyResponse.KeyRotationDue, "should no longer need a new key")
+
+	listNodesResponse, err := apiClient.ListNodes(ctx, client.ListNodesOpts{PublicKey: firstKey})
+	require.Nil(t, err, "should be able to list nodes")
+	require.Len(t, listNodesResponse.Nodes, 1, "should still find the node by its old key")
+	require.Equal(t, registerNodeResponse.ID, listNodesResponse.Nodes[0].ID)
+
+	_, err = nodeClient.RotateNodeKey(
+		ctx,
+		registerNodeResponse.ID,
+		0,
+		client.RotateNodeKeyRequest{PublicKey: firstKey},
+	)
+
+	var validationError client.ValidationError
+	require.True(t, errors.As(err, &validationError), "should not go back to the old key")
+
+	listNodeEventsResponse, err := apiClient.ListNodeEvents(ctx, registerNodeResponse.ID, 0, 0)
+	require.Nil(t, err, "should be able to list node events")
+
+	var eventTypes []client.NodeEventType
+	for _, event := range listNodeEventsResponse.Events {
+		eventTypes = append(eventTypes, event.Type)
+	}
+
+	require.Equal(
+		t,
+		[]client.NodeEventType{"registered", "key-rotation-requested", "key-rotated"},
+		eventTypes,
+		"should record every change",
+	)
+}
+
+func TestClientShouldSharePresharedKeysBetweenPeers(t *testing.T) {
+	serverURL := newTestServer(t)
+	apiClient := newClient(serverURL, testAdminToken)
+	ctx := context.Background()
+
+	networkName := fmt.Sprintf("client-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.2.0.0/24")
+	_, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:          networkName,
+		IPv4CIDR:      &prefix,
+		AllowOverlap:  true,
+		PresharedKeys: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	var nodeIDs []string
+	var nodeClients []*client.Client
+	for _, name := range []string{"first", "second"} {
+		registerNodeResponse, err := apiClient.RegisterNode(ctx, client.RegisterNodeRequest{
+			Network:   networkName,
+			Name:      name,
+			PublicKey: newTestKey(t),
+		})
+		require.Nil(t, err, "should be able to register a node")
+
+		nodeIDs = append(nodeIDs, registerNodeResponse.ID)
+		nodeClients = append(nodeClients, newClient(serverURL, registerNodeResponse.Secret))
+	}
+
+	_, err = nodeClients[1].GetNodeConfig(ctx, nodeIDs[0])
+	var unauthorizedError client.UnauthorizedError
+	require.True(t, errors.As(err, &unauthorizedError), "should not give a node's config to its peers")
+
+	firstConfig, err := nodeClients[0].GetNodeConfig(ctx, nodeIDs[0])
+	require.Nil(t, err, "should be able to get the first node's config")
+	require.Len(t, firstConfig.Peers, 1, "should peer with the other node")
+	require.NotEmpty(t, firstConfig.Peers[0].PresharedKey, "should have a preshared key")
+
+	secondConfig, err := nodeClients[1].GetNodeConfig(ctx, nodeIDs[1])
+	require.Nil(t, err, "should be able to get the second node's config")
+	require.Len(t, secondConfig.Peers, 1, "should peer with the other node")
+	require.Equal(
+		t,
+		firstConfig.Peers[0].PresharedKey,
+		secondConfig.Peers[0].PresharedKey,
+		"should share the same preshared key",
+	)
+
+	_, err = nodeClients[0].RotateNodeKey(ctx, nodeIDs[0], 0, client.RotateNodeKeyRequest{PublicKey: newTestKey(t)})
+	require.Nil(t, err, "should be able to rotate the key")
+
+	rotatedConfig, err := nodeClients[1].GetNodeConfig(ctx, nodeIDs[1])
+	require.Nil(t, err, "should be able to get the second node's config")
+	require.NotEqual(
+		t,
+		secondConfig.Peers[0].PresharedKey,
+		rotatedConfig.Peers[0].PresharedKey,
+		"should replace the preshared key when a key is rotated",
+	)
+}
+
+func TestClientShouldTrackNodeHeartbeats(t *testing.T) {
+	serverURL := newTestServer(t)
+	apiClient := newClient(serverURL, testAdminToken)
+	ctx := context.Background()
+
+	networkName := fmt.Sprintf("client-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.3.0.0/24")
+	_, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	peerKey := newTestKey(t)
+	_, err = apiClient.RegisterNode(ctx, client.RegisterNodeRequest{
+		Network:   networkName,
+		Name:      "peer",
+		PublicKey: peerKey,
+	})
+	require.Nil(t, err, "should be able to register the peer")
+
+	registerNodeResponse, err := apiClient.RegisterNode(ctx, client.RegisterNodeRequest{
+		Network:   networkName,
+		Name:      "node",
+		PublicKey: newTestKey(t),
+	})
+	require.Nil(t, err, "should be able to register the node")
+	require.Equal(t, client.NodeStatusOffline, registerNodeResponse.Status, "should be offline until it is seen")
+	require.Nil(t, registerNodeResponse.LastSeenOn, "should not have been seen yet")
+
+	nodeClient := newClient(serverURL, registerNodeResponse.Secret)
+	endpoint := "203.0.113.10:51820"
+	heartbeatResponse, err := nodeClient.SendHeartbeat(ctx, registerNodeResponse.ID, client.HeartbeatRequest{
+		Peers: []client.PeerHeartbeat{
+			{PublicKey: peerKey, ReceiveBytes: 100, TransmitBytes: 200, Endpoint: &endpoint},
+		},
+	})
+	require.Nil(t, err, "should be able to send a heartbeat")
+	require.Equal(t, client.NodeStatusOnline, heartbeatResponse.Status, "should be online")
+	require.NotNil(t, heartbeatResponse.LastSeenOn, "should have been seen")
+	require.Equal(t, registerNodeResponse.Version, heartbeatResponse.Version, "should not change the version")
+
+	listPeerStatsResponse, err := nodeClient.ListPeerStats(ctx, registerNodeResponse.ID)
+	require.Nil(t, err, "should be able to list peer stats")
+	require.Len(t, listPeerStatsResponse.Peers, 1, "should have the reported peer")
+	require.Equal(t, "peer", listPeerStatsResponse.Peers[0].Name)
+	require.Equal(t, int64(200), listPeerStatsResponse.Peers[0].TransmitBytes)
+	require.Equal(t, &endpoint, listPeerStatsResponse.Peers[0].Endpoint)
+
+	listNodesResponse, err := apiClient.ListNodes(ctx, client.ListNodesOpts{
+		Network: networkName,
+		Status:  client.NodeStatusOffline,
+	})
+	require.Nil(t, err, "should be able to list nodes")
+	require.Len(t, listNodesResponse.Nodes, 1, "should only list the peer")
+	require.Equal(t, "peer", listNodesResponse.Nodes[0].Name)
+}
+
+func TestClientShouldWatchNodeConfigChanges(t *testing.T) {
+	serverURL := newTestServer(t)
+	apiClient := newClient(serverURL, testAdminToken)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	networkName := fmt.Sprintf("client-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.3.0.0/24")
+	_, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	firstNode, err := apiClient.RegisterNode(ctx, client.RegisterNodeRequest{
+		Network:   networkName,
+		Name:      "first",
+		PublicKey: newTestKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+
+	events := make(chan client.NodeConfigEvent)
+	watchErr := make(chan error, 1)
+	nodeClient := newClient(serverURL, firstNode.Secret)
+	go func() {
+		_, err := nodeClient.WatchNode(ctx, firstNode.ID, "", func(event client.NodeConfigEvent) error {
+			events <- event
+			return nil
+		})
+		watchErr <- err
+	}()
+
+	initialEvent := <-events
+	require.Empty(t, initialEvent.Config.Peers, "should start without peers")
+
+	_, err = apiClient.RegisterNode(ctx, client.RegisterNodeRequest{
+		Network:   networkName,
+		Name:      "second",
+		PublicKey: newTestKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+
+	peerEvent := <-events
+	require.Len(t, peerEvent.Config.Peers, 1, "should send the new peer")
+	require.NotEqual(t, initialEvent.ID, peerEvent.ID, "should move to a new revision")
+
+	presharedKeys := true
+	_, err = apiClient.UpdateNetwork(ctx, networkName, 0, client.UpdateNetworkRequest{
+		PresharedKeys: &presharedKeys,
+	})
+	require.Nil(t, err, "should be able to update the network")
+
+	policyEvent := <-events
+	require.Len(t, policyEvent.Config.Peers, 1)
+	require.NotEmpty(t, policyEvent.Config.Peers[0].PresharedKey, "should send the new network policy")
+
+	require.Nil(t, apiClient.DeleteNode(ctx, firstNode.ID, 0), "should be able to delete the node")
+
+	err = <-watchErr
+	var notFoundError client.NotFoundError
+	require.True(t, errors.As(err, &notFoundError), "should end the watch once the node is removed")
+}
+
+func TestClientShouldResumeWatchingFromTheLastEventID(t *testing.T) {
+	serverURL := newTestServer(t)
+	apiClient := newClient(serverURL, testAdminToken)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	networkName := fmt.Sprintf("client-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.4.0.0/24")
+	_, err := apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	registerNodeResponse, err := apiClient.RegisterNode(ctx, client.RegisterNodeRequest{
+		Network:   networkName,
+		Name:      "first",
+		PublicKey: newTestKey(t),
+	})
+	require.Nil(t, err, "should be able to register a node")
+
+	nodeClient := newClient(serverURL, registerNodeResponse.Secret)
+	errStop := errors.New("stop")
+	var revision string
+	lastEventID, err := nodeClient.WatchNode(ctx, registerNodeResponse.ID, "", func(event client.NodeConfigEvent) error {
+		revision = event.ID
+		return errStop
+	})
+	require.True(t, errors.Is(err, errStop), "should pass on the handler's error")
+	require.Empty(t, lastEventID, "should not count events the handler failed on")
+	require.NotEmpty(t, revision, "should send the current config")
+
+	resumeCtx, resumeCancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer resumeCancel()
+
+	_, err = nodeClient.WatchNode(resumeCtx, registerNodeResponse.ID, revision, func(event client.NodeConfigEvent) error {
+		t.Error("should not send a config the node already has")
+		return nil
+	})
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "should wait for a change")
+}
+
+func TestClientShouldRecordWebhookDeliveriesWithChanges(t *testing.T) {
+	apiClient := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := apiClient.CreateWebhook(ctx, client.CreateWebhookRequest{
+		URL:        "ftp://example.com/hook",
+		EventTypes: []client.WebhookEventType{client.WebhookEventNetworkCreated},
+		Secret:     "0123456789abcdef",
+	})
+	var validationError client.ValidationError
+	require.True(t, errors.As(err, &validationError), "should reject URLs that aren't http or https")
+
+	createWebhookResponse, err := apiClient.CreateWebhook(ctx, client.CreateWebhookRequest{
+		URL:        "https://example.com/hook",
+		EventTypes: []client.WebhookEventType{client.WebhookEventNetworkCreated},
+		Secret:     "0123456789abcdef",
+	})
+	require.Nil(t, err, "should be able to create a webhook")
+	t.Cleanup(func() {
+		_ = apiClient.DeleteWebhook(context.Background(), createWebhookResponse.ID)
+	})
+
+	networkName := fmt.Sprintf("client-test-%d", rng.RNG.Int63())
+	prefix := netaddr.MustParseIPPrefix("10.5.0.0/24")
+	_, err = apiClient.CreateNetwork(ctx, client.CreateNetworkRequest{
+		Name:         networkName,
+		IPv4CIDR:     &prefix,
+		AllowOverlap: true,
+	})
+	require.Nil(t, err, "should be able to create a network")
+
+	_, err = apiClient.CreateUser(ctx, client.CreateUserRequest{Name: fmt.Sprintf("webhook-%d", rng.RNG.Int63())})
+	require.Nil(t, err, "should be able to create a user")
+
+	listDeliveriesResponse, err := apiClient.ListWebhookDeliveries(ctx, createWebhookResponse.ID, 0)
+	require.Nil(t, err, "should be able to list deliveries")
+	require.Len(t, listDeliveriesResponse.Deliveries, 1, "should only queue subscribed events")
+
+	delivery := listDeliveriesResponse.Deliveries[0]
+	require.Equal(t, client.WebhookEventNetworkCreated, delivery.EventType)
+	require.Equal(t, client.WebhookDeliveryPending, delivery.Status)
+	require.Equal(t, 0, delivery.Attempts)
+
+	require.Nil(t, apiClient.DeleteWebhook(ctx, createWebhookResponse.ID), "should be able to delete the webhook")
+
+	_, err = apiClient.GetWebhook(ctx, createWebhookResponse.ID)
+	var notFoundError client.NotFoundError
+	require.True(t, errors.As(err, &notFoundError), "should not find a deleted webhook")
+}
+
+func TestClientShouldLookUpNodesInItsOrganization(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(
+		func(response http.ResponseWriter, request *http.Request) {
+			paths = append(paths, request.URL.RequestURI())
+			response.WriteHeader(http.StatusOK)
+			_, _ = response.Write([]byte(`{"id":"some-node"}`))
+		},
+	))
+	defer server.Close()
+
+	ctx := context.Background()
+	organizationClient := client.New(server.URL, client.Opts{MaxRetries: -1, Organization: "acme"})
+
+	_, err := organizationClient.GetNode(ctx, "some-node")
+	require.Nil(t, err, "should be able to get a node")
+
+	_, err = organizationClient.GetNodeConfig(ctx, "some-node")
+	require.Nil(t, err, "should be able to get the config of a node")
+
+	_, err = client.New(server.URL, client.Opts{MaxRetries: -1}).GetNode(ctx, "some-node")
+	require.Nil(t, err, "should be able to get a node")
+
+	require.Equal(
+		t,
+		[]string{"/org/acme/node/some-node", "/node/some-node/config", "/node/some-node"},
+		paths,
+		"should only name an organization for the API's used to manage nodes",
+	)
+}
+
+func TestClientShouldPingTheManager(t *testing.T) {
+	apiClient := newTestClient(t)
+
+	require.Nil(t, apiClient.Ping(context.Background()), "should be able to ping the manager")
+}
+
+func TestClientShouldRetryIdempotentRequests(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(response http.ResponseWriter, request *http.Request) {
+			if atomic.AddInt32(&requestCount, 1) < 3 {
+				response.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			response.WriteHeader(http.StatusOK)
+			_, _ = response.Write([]byte(`{"networks":[]}`))
+		},
+	))
+	defer server.Close()
+
+	apiClient := client.New(server.URL, client.Opts{RetryBackoff: time.Millisecond})
+
+	_, err := apiClient.ListNetworks(context.Background(), client.ListOpts{})
+	require.Nil(t, err, "should succeed after retrying")
+	require.Equal(t, int32(3), atomic.LoadInt32(&requestCount), "should have retried twice")
+}
+
+func TestClientShouldNotRetryNonIdempotentRequests(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(response http.ResponseWriter, request *http.Request) {
+			atomic.AddInt32(&requestCount, 1)
+			response.WriteHeader(http.StatusServiceUnavailable)
+		},
+	))
+	defer server.Close()
+
+	apiClient := client.New(server.URL, client.Opts{RetryBackoff: time.Millisecond})
+
+	_, err := apiClient.CreateNetwork(context.Background(), client.CreateNetworkRequest{Name: "test"})
+
+	var systemError client.SystemError
+	require.True(t, errors.As(err, &systemError), "should be a system error")
+	require.Equal(t, http.StatusServiceUnavailable, systemError.StatusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requestCount), "should not have retried")
+}
+
+func TestClientShouldRetryRateLimitedRequests(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(response http.ResponseWriter, request *http.Request) {
+			if atomic.AddInt32(&requestCount, 1) < 2 {
+				response.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			response.WriteHeader(http.StatusCreated)
+			_, _ = response.Write([]byte(`{"name":"test"}`))
+		},
+	))
+	defer server.Close()
+
+	apiClient := client.New(server.URL, client.Opts{RetryBackoff: time.Millisecond})
+
+	_, err := apiClient.CreateNetwork(context.Background(), client.CreateNetworkRequest{Name: "test"})
+	require.Nil(t, err, "should succeed after retrying")
+	require.Equal(t, int32(2), atomic.LoadInt32(&requestCount), "should retry requests that were never handled")
+}
+
+func TestClientShouldReportWhenToRetryRateLimitedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(response http.ResponseWriter, request *http.Request) {
+			response.Header().Set("Retry-After", "2")
+			response.WriteHeader(http.StatusTooManyRequests)
+			_, _ = response.Write([]byte(`{"message":"Too many requests, try again in 2 seconds"}`))
+		},
+	))
+	defer server.Close()
+
+	apiClient := client.New(server.URL, client.Opts{MaxRetries: -1})
+
+	_, err := apiClient.ListNetworks(context.Background(), client.ListOpts{})
+
+	var tooManyRequestsError client.TooManyRequestsError
+	require.True(t, errors.As(err, &tooManyRequestsError), "should be a rate limiting error")
+	require.Equal(t, 2*time.Second, tooManyRequestsError.RetryAfter)
+	require.Equal(t, "Too many requests, try again in 2 seconds", tooManyRequestsError.Message)
+}
+
+func TestClientShouldRetryCreatesWithAnIdempotencyKey(t *testing.T) {
+	var requestCount int32
+	idempotencyKeys := make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(response http.ResponseWriter, request *http.Request) {
+			idempotencyKeys <- request.Header.Get("Idempotency-Key")
+			if atomic.AddInt32(&requestCount, 1) < 3 {
+				response.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			response.WriteHeader(http.StatusCreated)
+			_, _ = response.Write([]byte(`{"name":"test"}`))
+		},
+	))
+	defer server.Close()
+
+	apiClient := client.New(server.URL, client.Opts{
+		RetryBackoff:    time.Millisecond,
+		IdempotencyKeys: true,
+	})
+
+	_, err := apiClient.CreateNetwork(context.Background(), client.CreateNetworkRequest{Name: "test"})
+	require.Nil(t, err, "should succeed after retrying")
+	require.Equal(t, int32(3), atomic.LoadInt32(&requestCount), "should have retried twice")
+
+	firstKey := <-idempotencyKeys
+	require.NotEmpty(t, firstKey, "should have sent an idempotency key")
+	require.Equal(t, firstKey, <-idempotencyKeys, "should reuse the key when retrying")
+	require.Equal(t, firstKey, <-idempotencyKeys, "should reuse the key when retrying")
+}
+
+func TestClientShouldUseTheGivenHTTPClient(t *testing.T) {
+	var sawToken string
+	server := httptest.NewServer(http.HandlerFunc(
+		func(response http.ResponseWriter, request *http.Request) {
+			sawToken = request.Header.Get("Authorization")
+			response.WriteHeader(http.StatusOK)
+		},
+	))
+	defer server.Close()
+
+	httpClient := server.Client()
+	apiClient := client.New(server.URL, client.Opts{
+		Token:      "secret",
+		HTTPClient: httpClient,
+	})
+
+	require.Nil(t, apiClient.Ping(context.Background()), "should be able to ping the server")
+	require.Equal(t, "Bearer secret", sawToken, "should send the token")
+}
+
+func newTestClient(t *testing.T) *client.Client {
+	return newClient(newTestServer(t), testAdminToken)
+}
+
+// newClient creates a client for a test server that authenticates with
+// the given token, like the admin token or the secret of a node.
+func newClient(serverURL string, token string) *client.Client {
+	return client.New(serverURL, client.Opts{MaxRetries: -1, Token: token})
+}
+
+// newTestServer starts a manager for a test, giving its URL.
+func newTestServer(t *testing.T) string {
+	dbConfig := configuration.DBConfiguration{
+		Type: configuration.DBTypePostgres,
+		Postgres: configuration.PostgresConfiguration{
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Role:     "ley",
+			Password: "ley",
+			DBName:   "ley",
+			SSLMode:  "disable",
+		},
+	}
+
+	connectionString, err := dbConfig.ConnectionString()
+	require.Nil(t, err, "should be able to create a connection string")
+
+	db, err := sql.Open(string(dbConfig.Type), connectionString)
+	require.Nil(t, err, "should be able to open the database")
+
+	controller := manager.NewController(db, &configuration.Configuration{
+		API: configuration.APIConfiguration{
+			IdempotencyWindow: time.Hour,
+			AdminToken:        testAdminToken,
+		},
+		Network: configuration.NetworkConfiguration{
+			IPv4Supernet: testSupernet,
+			IPv4Pools:    []netaddr.IPPrefix{testSupernet},
+		},
+		Node: configuration.NodeConfiguration{
+			KeyRotationInterval: 24 * time.Hour,
+			KeyGracePeriod:      time.Hour,
+			StaleAfter:          time.Minute,
+			OfflineAfter:        5 * time.Minute,
+		},
+		DB: dbConfig,
+	})
+	server := httptest.NewServer(controller)
+	t.Cleanup(func() {
+		server.Close()
+		_ = controller.Close()
+		_ = db.Close()
+	})
+
+	return server.URL
+}
+
+// testAdminToken lets the tests use the admin endpoints.
+const testAdminToken = "test-admin-token"
+
+// testSupernet is where tests that check for overlaps carve their
+// ranges from. The other tests allow overlaps and stay out of it.
+var testSupernet = netaddr.MustParseIPPrefix("100.64.0.0/10")
+
+// newTestPrefix picks a random /24 in the test supernet so that runs
+// against the same database don't collide.
+func newTestPrefix() netaddr.IPPrefix {
+	ip := testSupernet.IP().As4()
+	offset := rng.RNG.Intn(1 << 14)
+	ip[1] += byte(offset >> 8)
+	ip[2] = byte(offset)
+
+	return netaddr.IPPrefixFrom(netaddr.IPFrom4(ip), 24)
+}
+
+func newTestKey(t *testing.T) string {
+	key, err := node.GeneratePresharedKey(rand.Reader)
+	require.Nil(t, err, "should be able to generate a key")
+
+	return key
+}
+
+func newUsername() string {
+	return fmt.Sprintf("client%d", rng.RNG.Int63())
+}
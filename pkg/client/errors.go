@@ -0,0 +1,125 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errorResponse is the body of every error the manager returns.
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// APIError is returned when the manager responds with an unexpected
+// status code. The more specific error types below embed it so the
+// status code and message are always available.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (err APIError) Error() string {
+	if err.Message == "" {
+		return http.StatusText(err.StatusCode)
+	}
+
+	return err.Message
+}
+
+var _ error = (*APIError)(nil)
+
+// UserError is returned when the request was rejected because of
+// something the caller did.
+type UserError struct {
+	APIError
+}
+
+// ValidationError is returned when the request contained invalid
+// data.
+type ValidationError struct {
+	UserError
+}
+
+// UnauthorizedError is returned when the request was missing valid
+// credentials, like a session, the admin token or a node's secret.
+type UnauthorizedError struct {
+	UserError
+}
+
+// NotFoundError is returned when the requested data could not be
+// found.
+type NotFoundError struct {
+	UserError
+}
+
+// PreconditionFailedError is returned when a change was conditional
+// on a version of the data that is no longer current.
+type PreconditionFailedError struct {
+	UserError
+}
+
+// TooManyRequestsError is returned when the caller made more requests
+// than the manager allows. RetryAfter is how long to wait before trying
+// again, when the manager said so.
+type TooManyRequestsError struct {
+	UserError
+	RetryAfter time.Duration
+}
+
+// SystemError is returned when the manager failed to handle the
+// request on its side. These are safe to retry.
+type SystemError struct {
+	APIError
+}
+
+var _ error = (*UserError)(nil)
+var _ error = (*ValidationError)(nil)
+var _ error = (*UnauthorizedError)(nil)
+var _ error = (*NotFoundError)(nil)
+var _ error = (*PreconditionFailedError)(nil)
+var _ error = (*TooManyRequestsError)(nil)
+var _ error = (*SystemError)(nil)
+
+func newErrorFromResponse(response *http.Response) error {
+	apiError := APIError{
+		StatusCode: response.StatusCode,
+	}
+
+	var body errorResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err == nil {
+		apiError.Message = body.Message
+	}
+
+	switch {
+	case response.StatusCode == http.StatusBadRequest:
+		return ValidationError{UserError: UserError{APIError: apiError}}
+
+	case response.StatusCode == http.StatusUnauthorized:
+		return UnauthorizedError{UserError: UserError{APIError: apiError}}
+
+	case response.StatusCode == http.StatusNotFound:
+		return NotFoundError{UserError: UserError{APIError: apiError}}
+
+	case response.StatusCode == http.StatusPreconditionFailed:
+		return PreconditionFailedError{UserError: UserError{APIError: apiError}}
+
+	case response.StatusCode == http.StatusTooManyRequests:
+		tooManyRequestsError := TooManyRequestsError{UserError: UserError{APIError: apiError}}
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			tooManyRequestsError.RetryAfter = time.Duration(seconds) * time.Second
+		}
+
+		return tooManyRequestsError
+
+	case response.StatusCode >= 400 && response.StatusCode < 500:
+		return UserError{APIError: apiError}
+
+	case response.StatusCode >= 500:
+		return SystemError{APIError: apiError}
+
+	default:
+		return apiError
+	}
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateGroup creates a new group.
+func (client *Client) CreateGroup(
+	ctx context.Context,
+	createGroupRequest CreateGroupRequest,
+) (*CreateGroupResponse, error) {
+	var createGroupResponse CreateGroupResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		"/group",
+		nil,
+		nil,
+		&createGroupRequest,
+		http.StatusCreated,
+		&createGroupResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createGroupResponse, nil
+}
+
+// ListGroups retrieves a page of groups.
+func (client *Client) ListGroups(ctx context.Context, opts ListOpts) (*ListGroupsResponse, error) {
+	var listGroupsResponse ListGroupsResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/group",
+		opts.query(),
+		nil,
+		nil,
+		http.StatusOK,
+		&listGroupsResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listGroupsResponse, nil
+}
+
+// GetGroup fetches a group by its name.
+func (client *Client) GetGroup(ctx context.Context, name string) (*GetGroupResponse, error) {
+	var getGroupResponse GetGroupResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/group/"+url.PathEscape(name),
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&getGroupResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getGroupResponse, nil
+}
+
+// DeleteGroup removes a group along with its memberships. When
+// expectedVersion isn't zero the group is only removed if it is still
+// at that version, otherwise a PreconditionFailedError is returned.
+func (client *Client) DeleteGroup(ctx context.Context, name string, expectedVersion int64) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		"/group/"+url.PathEscape(name),
+		nil,
+		ifMatch(expectedVersion),
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
+
+// AddGroupMember adds a user or a nested group to a group. Adding a
+// member that is already there changes nothing.
+func (client *Client) AddGroupMember(
+	ctx context.Context,
+	group string,
+	kind GroupMemberKind,
+	name string,
+) (*AddGroupMemberResponse, error) {
+	var addMemberResponse AddGroupMemberResponse
+	err := client.do(
+		ctx,
+		http.MethodPut,
+		groupMemberPath(group, kind, name),
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&addMemberResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &addMemberResponse, nil
+}
+
+// RemoveGroupMember takes a user or a nested group out of a group.
+func (client *Client) RemoveGroupMember(ctx context.Context, group string, kind GroupMemberKind, name string) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		groupMemberPath(group, kind, name),
+		nil,
+		nil,
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
+
+// ListGroupMembers retrieves a page of the users and groups that were
+// added directly to a group.
+func (client *Client) ListGroupMembers(
+	ctx context.Context,
+	group string,
+	opts ListOpts,
+) (*ListGroupMembersResponse, error) {
+	var listMembersResponse ListGroupMembersResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/group/"+url.PathEscape(group)+"/member",
+		opts.query(),
+		nil,
+		nil,
+		http.StatusOK,
+		&listMembersResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listMembersResponse, nil
+}
+
+// ListEffectiveGroups lists every group that a user belongs to,
+// directly or through nested groups, along with the chain of groups
+// that explains each one.
+func (client *Client) ListEffectiveGroups(ctx context.Context, username string) (*ListEffectiveGroupsResponse, error) {
+	var listEffectiveGroupsResponse ListEffectiveGroupsResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/user/"+url.PathEscape(username)+"/group",
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&listEffectiveGroupsResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listEffectiveGroupsResponse, nil
+}
+
+func groupMemberPath(group string, kind GroupMemberKind, name string) string {
+	return "/group/" + url.PathEscape(group) + "/member/" + url.PathEscape(string(kind)) + "/" + url.PathEscape(name)
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ListOpts gives the pagination, filtering and sorting options shared
+// by every list request. Zero values are left out of the request so
+// the manager's defaults are used.
+type ListOpts struct {
+	// Limit is the maximum number of results to return.
+	Limit int
+
+	// Cursor is the NextCursor value from the previous page.
+	Cursor string
+
+	// NamePrefix only includes results whose name starts with it.
+	NamePrefix string
+
+	// CreatedAfter only includes results created at or after it.
+	CreatedAfter time.Time
+
+	// CreatedBefore only includes results created before it.
+	CreatedBefore time.Time
+
+	// Labels only includes results with all of the given labels.
+	Labels map[string]string
+
+	// Sort is the field to order results by, either "createdOn" or
+	// "name".
+	Sort string
+
+	// Order is the direction to order results in, either "asc" or
+	// "desc".
+	Order string
+}
+
+func (opts ListOpts) query() url.Values {
+	query := url.Values{}
+
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+
+	if opts.NamePrefix != "" {
+		query.Set("namePrefix", opts.NamePrefix)
+	}
+
+	if !opts.CreatedAfter.IsZero() {
+		query.Set("createdAfter", opts.CreatedAfter.Format(time.RFC3339))
+	}
+
+	if !opts.CreatedBefore.IsZero() {
+		query.Set("createdBefore", opts.CreatedBefore.Format(time.RFC3339))
+	}
+
+	labelKeys := make([]string, 0, len(opts.Labels))
+	for key := range opts.Labels {
+		labelKeys = append(labelKeys, key)
+	}
+
+	sort.Strings(labelKeys)
+
+	for _, key := range labelKeys {
+		query.Add("label", key+"="+opts.Labels[key])
+	}
+
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+
+	if opts.Order != "" {
+		query.Set("order", opts.Order)
+	}
+
+	return query
+}
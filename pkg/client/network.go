@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateNetwork creates a new managed network.
+func (client *Client) CreateNetwork(
+	ctx context.Context,
+	createNetworkRequest CreateNetworkRequest,
+) (*CreateNetworkResponse, error) {
+	var createNetworkResponse CreateNetworkResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		client.networksPath(),
+		nil,
+		nil,
+		&createNetworkRequest,
+		http.StatusCreated,
+		&createNetworkResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createNetworkResponse, nil
+}
+
+// ListNetworks retrieves a page of managed networks.
+func (client *Client) ListNetworks(ctx context.Context, opts ListOpts) (*ListNetworksResponse, error) {
+	var listNetworksResponse ListNetworksResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		client.networksPath(),
+		opts.query(),
+		nil,
+		nil,
+		http.StatusOK,
+		&listNetworksResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listNetworksResponse, nil
+}
+
+// GetNetwork fetches a network by its name.
+func (client *Client) GetNetwork(ctx context.Context, name string) (*GetNetworkResponse, error) {
+	var getNetworkResponse GetNetworkResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		client.networksPath()+"/"+url.PathEscape(name),
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&getNetworkResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getNetworkResponse, nil
+}
+
+// UpdateNetwork changes a network. When expectedVersion isn't zero the
+// change is only made if the network is still at that version,
+// otherwise a PreconditionFailedError is returned.
+func (client *Client) UpdateNetwork(
+	ctx context.Context,
+	name string,
+	expectedVersion int64,
+	updateNetworkRequest UpdateNetworkRequest,
+) (*UpdateNetworkResponse, error) {
+	var updateNetworkResponse UpdateNetworkResponse
+	err := client.do(
+		ctx,
+		http.MethodPatch,
+		client.networksPath()+"/"+url.PathEscape(name),
+		nil,
+		ifMatch(expectedVersion),
+		&updateNetworkRequest,
+		http.StatusOK,
+		&updateNetworkResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updateNetworkResponse, nil
+}
+
+// DeleteNetwork removes a network. When expectedVersion isn't zero the
+// network is only removed if it is still at that version, otherwise a
+// PreconditionFailedError is returned.
+func (client *Client) DeleteNetwork(ctx context.Context, name string, expectedVersion int64) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		client.networksPath()+"/"+url.PathEscape(name),
+		nil,
+		ifMatch(expectedVersion),
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
+
+// GetAddressSpace reports the used and free ranges within the
+// supernets configured on the manager.
+func (client *Client) GetAddressSpace(ctx context.Context) (*AddressSpace, error) {
+	var addressSpace AddressSpace
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/network/address-space",
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&addressSpace,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &addressSpace, nil
+}
+
+// networksPath gives the path of the networks of the client's
+// organization.
+func (client *Client) networksPath() string {
+	if client.organization == "" {
+		return "/network"
+	}
+
+	return "/org/" + url.PathEscape(client.organization) + "/network"
+}
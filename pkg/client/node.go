@@ -0,0 +1,325 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListNodesOpts narrows down the nodes that are listed on top of the
+// usual list options.
+type ListNodesOpts struct {
+	ListOpts
+
+	// Organization only includes nodes in the networks of this
+	// organization. The client's organization is used when this is
+	// empty.
+	Organization string
+
+	// Network only includes nodes in the network with this name.
+	Network string
+
+	// PublicKey only includes the node using this key, including a
+	// previous key that is still accepted.
+	PublicKey string
+
+	// Status only includes nodes with this status.
+	Status NodeStatus
+}
+
+// RegisterNode adds a node to a network of the client's organization.
+// The response holds the secret the node authenticates with, which
+// can't be read again later.
+func (client *Client) RegisterNode(
+	ctx context.Context,
+	registerNodeRequest RegisterNodeRequest,
+) (*RegisterNodeResponse, error) {
+	var registerNodeResponse RegisterNodeResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		client.nodesPath(""),
+		nil,
+		nil,
+		&registerNodeRequest,
+		http.StatusCreated,
+		&registerNodeResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registerNodeResponse, nil
+}
+
+// ListNodes retrieves a page of nodes.
+func (client *Client) ListNodes(ctx context.Context, opts ListNodesOpts) (*ListNodesResponse, error) {
+	query := opts.query()
+	if opts.Network != "" {
+		query.Set("network", opts.Network)
+	}
+
+	if opts.PublicKey != "" {
+		query.Set("publicKey", opts.PublicKey)
+	}
+
+	if opts.Status != "" {
+		query.Set("status", string(opts.Status))
+	}
+
+	var listNodesResponse ListNodesResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		client.nodesPath(opts.Organization),
+		query,
+		nil,
+		nil,
+		http.StatusOK,
+		&listNodesResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listNodesResponse, nil
+}
+
+// nodesPath gives the path of the nodes of an organization, which is
+// the client's organization when none is given. Nodes of other
+// organizations can't be found under it.
+func (client *Client) nodesPath(organization string) string {
+	if organization == "" {
+		organization = client.organization
+	}
+
+	if organization == "" {
+		return "/node"
+	}
+
+	return "/org/" + url.PathEscape(organization) + "/node"
+}
+
+// GetNode fetches a node by its ID.
+func (client *Client) GetNode(ctx context.Context, id string) (*GetNodeResponse, error) {
+	var getNodeResponse GetNodeResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		client.nodesPath("")+"/"+url.PathEscape(id),
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&getNodeResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getNodeResponse, nil
+}
+
+// DeleteNode removes a node from its network. When expectedVersion
+// isn't zero the node is only removed if it is still at that version,
+// otherwise a PreconditionFailedError is returned.
+func (client *Client) DeleteNode(ctx context.Context, id string, expectedVersion int64) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		client.nodesPath("")+"/"+url.PathEscape(id),
+		nil,
+		ifMatch(expectedVersion),
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
+
+// RotateNodeKey submits a node's new public key. The previous key
+// keeps being accepted for the grace period configured on the manager.
+// When expectedVersion isn't zero the key is only changed if the node
+// is still at that version, otherwise a PreconditionFailedError is
+// returned.
+//
+// The client's token has to be the node's secret.
+func (client *Client) RotateNodeKey(
+	ctx context.Context,
+	id string,
+	expectedVersion int64,
+	rotateNodeKeyRequest RotateNodeKeyRequest,
+) (*RotateNodeKeyResponse, error) {
+	var rotateNodeKeyResponse RotateNodeKeyResponse
+	err := client.do(
+		ctx,
+		http.MethodPut,
+		"/node/"+url.PathEscape(id)+"/key",
+		nil,
+		ifMatch(expectedVersion),
+		&rotateNodeKeyRequest,
+		http.StatusOK,
+		&rotateNodeKeyResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rotateNodeKeyResponse, nil
+}
+
+// RequestNodeKeyRotation asks a node to rotate its key the next time
+// it fetches its config. When expectedVersion isn't zero the request
+// is only made if the node is still at that version, otherwise a
+// PreconditionFailedError is returned.
+func (client *Client) RequestNodeKeyRotation(
+	ctx context.Context,
+	id string,
+	expectedVersion int64,
+) (*RequestKeyRotationResponse, error) {
+	var requestKeyRotationResponse RequestKeyRotationResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		client.nodesPath("")+"/"+url.PathEscape(id)+"/key/rotation",
+		nil,
+		ifMatch(expectedVersion),
+		nil,
+		http.StatusAccepted,
+		&requestKeyRotationResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &requestKeyRotationResponse, nil
+}
+
+// ResetNodeSecret issues a new secret to a node. Its old secret stops
+// working straight away.
+func (client *Client) ResetNodeSecret(ctx context.Context, id string) (*ResetNodeSecretResponse, error) {
+	var resetNodeSecretResponse ResetNodeSecretResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		client.nodesPath("")+"/"+url.PathEscape(id)+"/secret",
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&resetNodeSecretResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resetNodeSecretResponse, nil
+}
+
+// GetNodeConfig fetches the WireGuard configuration of a node.
+//
+// The client's token has to be the node's secret.
+func (client *Client) GetNodeConfig(ctx context.Context, id string) (*NodeConfig, error) {
+	var nodeConfig NodeConfig
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/node/"+url.PathEscape(id)+"/config",
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&nodeConfig,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nodeConfig, nil
+}
+
+// ListNodeEvents retrieves the changes in a node's network after the
+// given event ID, oldest first. A limit of zero uses the manager's
+// default.
+func (client *Client) ListNodeEvents(
+	ctx context.Context,
+	id string,
+	afterEventID int64,
+	limit int,
+) (*ListNodeEventsResponse, error) {
+	query := url.Values{}
+	if afterEventID > 0 {
+		query.Set("after", strconv.FormatInt(afterEventID, 10))
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var listNodeEventsResponse ListNodeEventsResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		client.nodesPath("")+"/"+url.PathEscape(id)+"/events",
+		query,
+		nil,
+		nil,
+		http.StatusOK,
+		&listNodeEventsResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listNodeEventsResponse, nil
+}
+
+// SendHeartbeat reports that a node is alive along with the stats of
+// its WireGuard peers.
+//
+// The client's token has to be the node's secret.
+func (client *Client) SendHeartbeat(
+	ctx context.Context,
+	id string,
+	heartbeatRequest HeartbeatRequest,
+) (*HeartbeatResponse, error) {
+	var heartbeatResponse HeartbeatResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		"/node/"+url.PathEscape(id)+"/heartbeat",
+		nil,
+		nil,
+		&heartbeatRequest,
+		http.StatusOK,
+		&heartbeatResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &heartbeatResponse, nil
+}
+
+// ListPeerStats fetches the stats a node reported about its peers in
+// its last heartbeat.
+//
+// The client's token has to be the node's secret.
+func (client *Client) ListPeerStats(ctx context.Context, id string) (*ListPeerStatsResponse, error) {
+	var listPeerStatsResponse ListPeerStatsResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/node/"+url.PathEscape(id)+"/peer-stats",
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&listPeerStatsResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listPeerStatsResponse, nil
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListOrganizationsOpts narrows down the organizations that are listed
+// on top of the usual list options.
+type ListOrganizationsOpts struct {
+	ListOpts
+
+	// Member only includes the organizations that the user with this
+	// username belongs to.
+	Member string
+}
+
+// CreateOrganization creates a new organization.
+func (client *Client) CreateOrganization(
+	ctx context.Context,
+	createOrganizationRequest CreateOrganizationRequest,
+) (*CreateOrganizationResponse, error) {
+	var createOrganizationResponse CreateOrganizationResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		"/org",
+		nil,
+		nil,
+		&createOrganizationRequest,
+		http.StatusCreated,
+		&createOrganizationResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createOrganizationResponse, nil
+}
+
+// ListOrganizations retrieves a page of organizations.
+func (client *Client) ListOrganizations(
+	ctx context.Context,
+	opts ListOrganizationsOpts,
+) (*ListOrganizationsResponse, error) {
+	query := opts.query()
+	if opts.Member != "" {
+		query.Set("member", opts.Member)
+	}
+
+	var listOrganizationsResponse ListOrganizationsResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/org",
+		query,
+		nil,
+		nil,
+		http.StatusOK,
+		&listOrganizationsResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listOrganizationsResponse, nil
+}
+
+// GetOrganization fetches an organization by its name.
+func (client *Client) GetOrganization(ctx context.Context, name string) (*GetOrganizationResponse, error) {
+	var getOrganizationResponse GetOrganizationResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/org/"+url.PathEscape(name),
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&getOrganizationResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getOrganizationResponse, nil
+}
+
+// DeleteOrganization removes an organization that has no networks
+// left. When expectedVersion isn't zero the organization is only
+// removed if it is still at that version, otherwise a
+// PreconditionFailedError is returned.
+func (client *Client) DeleteOrganization(ctx context.Context, name string, expectedVersion int64) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		"/org/"+url.PathEscape(name),
+		nil,
+		ifMatch(expectedVersion),
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
+
+// AddOrganizationMember makes a user a member of an organization.
+// Adding a user that already is a member changes nothing.
+func (client *Client) AddOrganizationMember(
+	ctx context.Context,
+	organization string,
+	username string,
+) (*AddOrganizationMemberResponse, error) {
+	var addMemberResponse AddOrganizationMemberResponse
+	err := client.do(
+		ctx,
+		http.MethodPut,
+		"/org/"+url.PathEscape(organization)+"/member/"+url.PathEscape(username),
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&addMemberResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &addMemberResponse, nil
+}
+
+// RemoveOrganizationMember takes a user out of an organization.
+func (client *Client) RemoveOrganizationMember(ctx context.Context, organization string, username string) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		"/org/"+url.PathEscape(organization)+"/member/"+url.PathEscape(username),
+		nil,
+		nil,
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
+
+// ListOrganizationMembers retrieves a page of the members of an
+// organization.
+func (client *Client) ListOrganizationMembers(
+	ctx context.Context,
+	organization string,
+	opts ListOpts,
+) (*ListOrganizationMembersResponse, error) {
+	var listMembersResponse ListOrganizationMembersResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/org/"+url.PathEscape(organization)+"/member",
+		opts.query(),
+		nil,
+		nil,
+		http.StatusOK,
+		&listMembersResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listMembersResponse, nil
+}
@@ -0,0 +1,720 @@
+package client
+
+import (
+	"time"
+
+	"inet.af/netaddr"
+)
+
+// The request and response types mirror the JSON bodies of the
+// manager's API, as described by its OpenAPI document, without
+// depending on the manager itself.
+
+// User is a user as returned by the API.
+type User struct {
+	Username string `json:"username"`
+
+	// Name is the username of the user.
+	//
+	// Deprecated: Use Username.
+	Name string `json:"name"`
+
+	DisplayName   string            `json:"displayName"`
+	Email         string            `json:"email,omitempty"`
+	EmailVerified bool              `json:"emailVerified"`
+	ExternalID    string            `json:"externalId,omitempty"`
+	Metadata      map[string]string `json:"metadata"`
+	Status        UserStatus        `json:"status"`
+	Version       int64             `json:"version"`
+	CreatedOn     time.Time         `json:"createdOn"`
+	ModifiedOn    time.Time         `json:"modifiedOn"`
+}
+
+// UserStatus tells if a user is active or not.
+type UserStatus string
+
+// CreateUserRequest holds the request body for creating a new user.
+type CreateUserRequest struct {
+	Username string `json:"username,omitempty"`
+
+	// Name is the username of the user.
+	//
+	// Deprecated: Use Username, which takes precedence when both are
+	// given.
+	Name string `json:"name,omitempty"`
+
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Email is the email address of the user. It is only ever
+	// verified by single sign-on or provisioning.
+	Email string `json:"email,omitempty"`
+
+	ExternalID string            `json:"externalId,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// CreateUserResponse holds the response body for creating a user.
+type CreateUserResponse struct {
+	User
+}
+
+// GetUserByUsernameResponse holds the response body for getting a
+// user by their username.
+type GetUserByUsernameResponse struct {
+	User
+}
+
+// UpdateUserRequest holds the request body for changing a user.
+// Fields that are left out are not changed. An empty email address or
+// external ID removes it and metadata is replaced as a whole.
+type UpdateUserRequest struct {
+	Status      *UserStatus       `json:"status,omitempty"`
+	DisplayName *string           `json:"displayName,omitempty"`
+	Email       *string           `json:"email,omitempty"`
+	ExternalID  *string           `json:"externalId,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// UpdateUserResponse holds the response body for changing a user.
+type UpdateUserResponse struct {
+	User
+}
+
+// ListUsersResponse holds the response body for listing users.
+type ListUsersResponse struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Organization is an organization as returned by the API.
+type Organization struct {
+	Name       string    `json:"name"`
+	Version    int64     `json:"version"`
+	CreatedOn  time.Time `json:"createdOn"`
+	ModifiedOn time.Time `json:"modifiedOn"`
+}
+
+// CreateOrganizationRequest holds the request body for creating an
+// organization.
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateOrganizationResponse holds the response body for creating an
+// organization.
+type CreateOrganizationResponse struct {
+	Organization
+}
+
+// GetOrganizationResponse holds the response body for getting an
+// organization.
+type GetOrganizationResponse struct {
+	Organization
+}
+
+// ListOrganizationsResponse holds the response body for listing
+// organizations.
+type ListOrganizationsResponse struct {
+	Organizations []Organization `json:"organizations"`
+	NextCursor    string         `json:"nextCursor,omitempty"`
+}
+
+// OrganizationMember is a member of an organization as returned by the
+// API.
+type OrganizationMember struct {
+	Username string    `json:"username"`
+	JoinedOn time.Time `json:"joinedOn"`
+}
+
+// AddOrganizationMemberResponse holds the response body for adding a
+// user to an organization.
+type AddOrganizationMemberResponse struct {
+	OrganizationMember
+}
+
+// ListOrganizationMembersResponse holds the response body for listing
+// the members of an organization.
+type ListOrganizationMembersResponse struct {
+	Members    []OrganizationMember `json:"members"`
+	NextCursor string               `json:"nextCursor,omitempty"`
+}
+
+// Group is a group as returned by the API.
+type Group struct {
+	Name       string    `json:"name"`
+	Version    int64     `json:"version"`
+	CreatedOn  time.Time `json:"createdOn"`
+	ModifiedOn time.Time `json:"modifiedOn"`
+}
+
+// CreateGroupRequest holds the request body for creating a group.
+type CreateGroupRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateGroupResponse holds the response body for creating a group.
+type CreateGroupResponse struct {
+	Group
+}
+
+// GetGroupResponse holds the response body for getting a group.
+type GetGroupResponse struct {
+	Group
+}
+
+// ListGroupsResponse holds the response body for listing groups.
+type ListGroupsResponse struct {
+	Groups     []Group `json:"groups"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// GroupMember is a user or group that was added to a group as
+// returned by the API.
+type GroupMember struct {
+	Kind    GroupMemberKind `json:"kind"`
+	Name    string          `json:"name"`
+	AddedOn time.Time       `json:"addedOn"`
+}
+
+// GroupMemberKind tells whether a member of a group is a user or a
+// group.
+type GroupMemberKind string
+
+// AddGroupMemberResponse holds the response body for adding a user or
+// a group to a group.
+type AddGroupMemberResponse struct {
+	GroupMember
+}
+
+// ListGroupMembersResponse holds the response body for listing the
+// members of a group.
+type ListGroupMembersResponse struct {
+	Members    []GroupMember `json:"members"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// EffectiveGroup is a group that a user belongs to along with why.
+type EffectiveGroup struct {
+	Name   string   `json:"name"`
+	Direct bool     `json:"direct"`
+	Path   []string `json:"path"`
+}
+
+// ListEffectiveGroupsResponse holds the response body for listing
+// every group that a user belongs to.
+type ListEffectiveGroupsResponse struct {
+	Username string           `json:"username"`
+	Groups   []EffectiveGroup `json:"groups"`
+}
+
+// Network is a network as returned by the API.
+type Network struct {
+	Name          string            `json:"name"`
+	Organization  string            `json:"organization"`
+	IPv4CIDR      *netaddr.IPPrefix `json:"ipv4CIDR,omitempty"`
+	IPv6CIDR      *netaddr.IPPrefix `json:"ipv6CIDR,omitempty"`
+	AllowOverlap  bool              `json:"allowOverlap,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Topology      Topology          `json:"topology"`
+	PresharedKeys bool              `json:"presharedKeys,omitempty"`
+	Version       int64             `json:"version"`
+	CreatedOn     time.Time         `json:"createdOn"`
+	ModifiedOn    time.Time         `json:"modifiedOn"`
+}
+
+// CreateNetworkRequest holds the request body for creating a network.
+type CreateNetworkRequest struct {
+	Name     string            `json:"name"`
+	IPv4CIDR *netaddr.IPPrefix `json:"ipv4CIDR,omitempty"`
+	IPv6CIDR *netaddr.IPPrefix `json:"ipv6CIDR,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+
+	AllowOverlap bool `json:"allowOverlap,omitempty"`
+
+	IPv4PrefixLength *int `json:"ipv4PrefixLength,omitempty"`
+	GenerateIPv6     bool `json:"generateIPv6,omitempty"`
+
+	Topology *Topology `json:"topology,omitempty"`
+
+	PresharedKeys bool `json:"presharedKeys,omitempty"`
+}
+
+// CreateNetworkResponse holds the response body for creating a
+// network.
+type CreateNetworkResponse struct {
+	Network
+}
+
+// GetNetworkResponse holds the response body for getting a network.
+type GetNetworkResponse struct {
+	Network
+}
+
+// UpdateNetworkRequest holds the request body for changing a network.
+// Fields that are left out are not changed.
+type UpdateNetworkRequest struct {
+	Name          *string           `json:"name,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Topology      *Topology         `json:"topology,omitempty"`
+	PresharedKeys *bool             `json:"presharedKeys,omitempty"`
+}
+
+// UpdateNetworkResponse holds the response body for changing a
+// network.
+type UpdateNetworkResponse struct {
+	Network
+}
+
+// ListNetworksResponse holds the response body for listing networks.
+type ListNetworksResponse struct {
+	Networks   []Network `json:"networks"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+// AddressSpace holds the response body for reporting how the
+// configured supernets are used.
+type AddressSpace struct {
+	IPv4 *AddressFamilySpace `json:"ipv4,omitempty"`
+	IPv6 *AddressFamilySpace `json:"ipv6,omitempty"`
+}
+
+// AddressFamilySpace is the use of a single supernet.
+type AddressFamilySpace struct {
+	Supernet netaddr.IPPrefix   `json:"supernet"`
+	Used     []UsedAddressRange `json:"used"`
+	Free     []netaddr.IPPrefix `json:"free"`
+}
+
+// UsedAddressRange is a range of a supernet that belongs to a network.
+type UsedAddressRange struct {
+	Network      string           `json:"network"`
+	Organization string           `json:"organization"`
+	CIDR         netaddr.IPPrefix `json:"cidr"`
+}
+
+// Topology describes which nodes in a network peer with each other.
+type Topology struct {
+	Mode TopologyMode `json:"mode"`
+
+	// Hubs are the nodes that every other node peers with when using
+	// hub and spoke.
+	Hubs []string `json:"hubs,omitempty"`
+
+	// PeerGroups are the groups of nodes that peer with each other when
+	// using a custom topology.
+	PeerGroups []PeerGroup `json:"peerGroups,omitempty"`
+}
+
+// TopologyMode decides which nodes in a network peer with each other.
+type TopologyMode string
+
+// PeerGroup is a named set of nodes that peer with each other in a
+// custom topology.
+type PeerGroup struct {
+	Name  string   `json:"name"`
+	Nodes []string `json:"nodes"`
+}
+
+// Node is a node as returned by the API.
+type Node struct {
+	ID                   string      `json:"id"`
+	Network              string      `json:"network"`
+	Name                 string      `json:"name"`
+	PublicKey            string      `json:"publicKey"`
+	PreviousPublicKey    *string     `json:"previousPublicKey,omitempty"`
+	PreviousKeyExpiresOn *time.Time  `json:"previousKeyExpiresOn,omitempty"`
+	KeyRotatedOn         time.Time   `json:"keyRotatedOn"`
+	KeyExpiresOn         *time.Time  `json:"keyExpiresOn,omitempty"`
+	KeyRotationDue       bool        `json:"keyRotationDue"`
+	Endpoint             *string     `json:"endpoint,omitempty"`
+	Status               NodeStatus  `json:"status"`
+	LastSeenOn           *time.Time  `json:"lastSeenOn,omitempty"`
+	Ephemeral            bool        `json:"ephemeral,omitempty"`
+	ExpiresOn            *time.Time  `json:"expiresOn,omitempty"`
+	IPv4Address          *netaddr.IP `json:"ipv4Address,omitempty"`
+	IPv6Address          *netaddr.IP `json:"ipv6Address,omitempty"`
+	Version              int64       `json:"version"`
+	CreatedOn            time.Time   `json:"createdOn"`
+	ModifiedOn           time.Time   `json:"modifiedOn"`
+}
+
+// NodeStatus tells how recently a node sent a heartbeat.
+type NodeStatus string
+
+// RegisterNodeRequest holds the request body for adding a node to a
+// network.
+type RegisterNodeRequest struct {
+	Network   string  `json:"network"`
+	Name      string  `json:"name"`
+	PublicKey string  `json:"publicKey"`
+	Endpoint  *string `json:"endpoint,omitempty"`
+
+	Ephemeral bool       `json:"ephemeral,omitempty"`
+	ExpiresOn *time.Time `json:"expiresOn,omitempty"`
+}
+
+// RegisterNodeResponse holds the response body for adding a node to a
+// network.
+type RegisterNodeResponse struct {
+	Node
+
+	// Secret is what the node authenticates with. It is only ever
+	// returned here.
+	Secret string `json:"secret"`
+}
+
+// GetNodeResponse holds the response body for getting a node.
+type GetNodeResponse struct {
+	Node
+}
+
+// ListNodesResponse holds the response body for listing nodes.
+type ListNodesResponse struct {
+	Nodes      []Node `json:"nodes"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// RotateNodeKeyRequest holds the request body for submitting a node's
+// new public key.
+type RotateNodeKeyRequest struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// RotateNodeKeyResponse holds the response body for rotating a node's
+// key.
+type RotateNodeKeyResponse struct {
+	Node
+}
+
+// ResetNodeSecretResponse holds the response body for issuing a new
+// secret to a node.
+type ResetNodeSecretResponse struct {
+	Node
+
+	// Secret is what the node authenticates with from now on. It is
+	// only ever returned here.
+	Secret string `json:"secret"`
+}
+
+// RequestKeyRotationResponse holds the response body for asking a
+// node to rotate its key.
+type RequestKeyRotationResponse struct {
+	Node
+}
+
+// NodeConfig holds the WireGuard configuration of a node.
+type NodeConfig struct {
+	Node           Node         `json:"node"`
+	Peers          []PeerConfig `json:"peers"`
+	DNS            *DNSConfig   `json:"dns,omitempty"`
+	KeyRotationDue bool         `json:"keyRotationDue"`
+	LastEventID    int64        `json:"lastEventID"`
+}
+
+// PeerConfig is a single peer in a node's configuration.
+type PeerConfig struct {
+	Name         string             `json:"name"`
+	PublicKey    string             `json:"publicKey"`
+	Endpoint     *string            `json:"endpoint,omitempty"`
+	AllowedIPs   []netaddr.IPPrefix `json:"allowedIPs"`
+	PresharedKey string             `json:"presharedKey,omitempty"`
+}
+
+// DNSConfig tells a node where to resolve the names of its peers.
+type DNSConfig struct {
+	Resolver netaddr.IP `json:"resolver"`
+	Domain   string     `json:"domain"`
+}
+
+// NodeEvent is a change in a node's network.
+type NodeEvent struct {
+	ID        int64         `json:"id"`
+	NodeID    string        `json:"nodeID"`
+	NodeName  string        `json:"nodeName"`
+	Type      NodeEventType `json:"type"`
+	CreatedOn time.Time     `json:"createdOn"`
+}
+
+// NodeEventType tells what happened to a node.
+type NodeEventType string
+
+// ListNodeEventsResponse holds the response body for listing the
+// events in a node's network.
+type ListNodeEventsResponse struct {
+	Events []NodeEvent `json:"events"`
+}
+
+// HeartbeatRequest holds the request body for a node reporting that it
+// is alive.
+type HeartbeatRequest struct {
+	Peers []PeerHeartbeat `json:"peers"`
+}
+
+// PeerHeartbeat is what a node reports about one of its peers.
+type PeerHeartbeat struct {
+	PublicKey       string     `json:"publicKey"`
+	LatestHandshake *time.Time `json:"latestHandshake,omitempty"`
+	ReceiveBytes    int64      `json:"receiveBytes"`
+	TransmitBytes   int64      `json:"transmitBytes"`
+	Endpoint        *string    `json:"endpoint,omitempty"`
+}
+
+// HeartbeatResponse holds the response body for a heartbeat.
+type HeartbeatResponse struct {
+	Node
+}
+
+// PeerStats is what a node last reported about one of its peers.
+type PeerStats struct {
+	Name            string     `json:"name,omitempty"`
+	PublicKey       string     `json:"publicKey"`
+	LatestHandshake *time.Time `json:"latestHandshake,omitempty"`
+	ReceiveBytes    int64      `json:"receiveBytes"`
+	TransmitBytes   int64      `json:"transmitBytes"`
+	Endpoint        *string    `json:"endpoint,omitempty"`
+	ReportedOn      time.Time  `json:"reportedOn"`
+}
+
+// ListPeerStatsResponse holds the response body for listing the stats
+// of a node's peers.
+type ListPeerStatsResponse struct {
+	Peers []PeerStats `json:"peers"`
+}
+
+// Webhook is a webhook subscription as returned by the API. The secret
+// is never returned.
+type Webhook struct {
+	ID         string             `json:"id"`
+	URL        string             `json:"url"`
+	EventTypes []WebhookEventType `json:"eventTypes"`
+	CreatedOn  time.Time          `json:"createdOn"`
+}
+
+// WebhookEventType names a change that webhooks can subscribe to.
+type WebhookEventType string
+
+// CreateWebhookRequest holds the request body for subscribing to
+// events.
+type CreateWebhookRequest struct {
+	URL        string             `json:"url"`
+	EventTypes []WebhookEventType `json:"eventTypes"`
+	Secret     string             `json:"secret"`
+}
+
+// CreateWebhookResponse holds the response body for subscribing to
+// events.
+type CreateWebhookResponse struct {
+	Webhook
+}
+
+// GetWebhookResponse holds the response body for getting a webhook.
+type GetWebhookResponse struct {
+	Webhook
+}
+
+// ListWebhooksResponse holds the response body for listing webhooks.
+type ListWebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookDelivery is an event being sent to a webhook.
+type WebhookDelivery struct {
+	ID             int64                 `json:"id"`
+	EventID        string                `json:"eventID"`
+	EventType      WebhookEventType      `json:"eventType"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptOn  *time.Time            `json:"nextAttemptOn,omitempty"`
+	LastAttemptOn  *time.Time            `json:"lastAttemptOn,omitempty"`
+	LastStatusCode *int                  `json:"lastStatusCode,omitempty"`
+	LastError      *string               `json:"lastError,omitempty"`
+	CreatedOn      time.Time             `json:"createdOn"`
+}
+
+// WebhookDeliveryStatus tells where a delivery is at.
+type WebhookDeliveryStatus string
+
+// ListWebhookDeliveriesResponse holds the response body for listing
+// the deliveries of a webhook.
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// Resource is a single resource of a manifest. The spec depends on the
+// kind of resource.
+type Resource struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       ResourceKind     `json:"kind"`
+	Metadata   ResourceMetadata `json:"metadata"`
+	Spec       map[string]any   `json:"spec,omitempty"`
+}
+
+// ResourceKind names a type of resource in a manifest.
+type ResourceKind string
+
+// ResourceMetadata identifies a resource in a manifest.
+type ResourceMetadata struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ApplyRequest holds the request body for applying a manifest.
+type ApplyRequest struct {
+	Resources []Resource `json:"resources"`
+	DryRun    bool       `json:"dryRun,omitempty"`
+	Prune     bool       `json:"prune,omitempty"`
+}
+
+// ApplyResponse holds the response body for applying a manifest. It
+// lists the changes that were made, or that would have been made for a
+// dry run.
+type ApplyResponse struct {
+	DryRun  bool     `json:"dryRun"`
+	Changes []Change `json:"changes"`
+}
+
+// Change is a change to a single resource of a manifest.
+type Change struct {
+	Kind   ResourceKind  `json:"kind"`
+	Name   string        `json:"name"`
+	Action ChangeAction  `json:"action"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// ChangeAction tells what happens to a resource of a manifest.
+type ChangeAction string
+
+// FieldChange is a change to a single field of a resource. From is
+// left out for new values and To is left out for removed values.
+type FieldChange struct {
+	Field string  `json:"field"`
+	From  *string `json:"from,omitempty"`
+	To    *string `json:"to,omitempty"`
+}
+
+// GetSessionResponse holds the response body for getting the current
+// session.
+type GetSessionResponse struct {
+	Username  string    `json:"username"`
+	CreatedOn time.Time `json:"createdOn"`
+	ExpiresOn time.Time `json:"expiresOn"`
+}
+
+const (
+	// TopologyModeFullMesh peers every node with every other node.
+	TopologyModeFullMesh TopologyMode = "full-mesh"
+
+	// TopologyModeHubAndSpoke peers spokes only with the hubs.
+	TopologyModeHubAndSpoke TopologyMode = "hub-and-spoke"
+
+	// TopologyModeCustom peers nodes that share a peer group.
+	TopologyModeCustom TopologyMode = "custom"
+)
+
+const (
+	// NodeStatusOnline means the node sent a heartbeat recently.
+	NodeStatusOnline NodeStatus = "online"
+
+	// NodeStatusStale means the node has missed some heartbeats.
+	NodeStatusStale NodeStatus = "stale"
+
+	// NodeStatusOffline means the node hasn't sent a heartbeat in a
+	// long time, or never has.
+	NodeStatusOffline NodeStatus = "offline"
+)
+
+const (
+	// UserStatusActive marks the user as active.
+	UserStatusActive UserStatus = "active"
+
+	// UserStatusDeactivated marks the user as no longer allowed to use
+	// the system.
+	UserStatusDeactivated UserStatus = "deactivated"
+)
+
+const (
+	// GroupMemberKindUser is a user that was added to a group.
+	GroupMemberKindUser GroupMemberKind = "user"
+
+	// GroupMemberKindGroup is a group that was nested in a group.
+	GroupMemberKindGroup GroupMemberKind = "group"
+)
+
+const (
+	// WebhookEventNetworkCreated is sent when a network is created.
+	WebhookEventNetworkCreated WebhookEventType = "network.created"
+
+	// WebhookEventNetworkUpdated is sent when a network is changed.
+	WebhookEventNetworkUpdated WebhookEventType = "network.updated"
+
+	// WebhookEventNetworkDeleted is sent when a network is removed.
+	WebhookEventNetworkDeleted WebhookEventType = "network.deleted"
+
+	// WebhookEventNodeRegistered is sent when a node joins a network.
+	WebhookEventNodeRegistered WebhookEventType = "node.registered"
+
+	// WebhookEventNodeKeyRotated is sent when a node starts using a new
+	// key.
+	WebhookEventNodeKeyRotated WebhookEventType = "node.key-rotated"
+
+	// WebhookEventNodeKeyRotationRequested is sent when a node is asked
+	// to rotate its key.
+	WebhookEventNodeKeyRotationRequested WebhookEventType = "node.key-rotation-requested"
+
+	// WebhookEventNodeRemoved is sent when a node is deleted.
+	WebhookEventNodeRemoved WebhookEventType = "node.removed"
+
+	// WebhookEventNodeExpired is sent when a node reaches its expiry.
+	WebhookEventNodeExpired WebhookEventType = "node.expired"
+
+	// WebhookEventNodeReaped is sent when an ephemeral node is removed
+	// for being offline too long.
+	WebhookEventNodeReaped WebhookEventType = "node.reaped"
+
+	// WebhookEventUserCreated is sent when a user is created.
+	WebhookEventUserCreated WebhookEventType = "user.created"
+
+	// WebhookEventUserUpdated is sent when a user is changed.
+	WebhookEventUserUpdated WebhookEventType = "user.updated"
+
+	// WebhookEventUserDeleted is sent when a user is removed.
+	WebhookEventUserDeleted WebhookEventType = "user.deleted"
+)
+
+const (
+	// WebhookDeliveryPending means the event hasn't been accepted yet
+	// and will be sent again.
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+
+	// WebhookDeliverySucceeded means the receiver accepted the event.
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+
+	// WebhookDeliveryFailed means every attempt to send the event
+	// failed.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+const (
+	// ManifestAPIVersion is the version of the manifest format.
+	ManifestAPIVersion = "ley/v1"
+
+	// ResourceKindNetwork describes a network in a manifest.
+	ResourceKindNetwork ResourceKind = "Network"
+
+	// ResourceKindUser describes a user in a manifest.
+	ResourceKindUser ResourceKind = "User"
+)
+
+const (
+	// ChangeActionCreate means the resource will be created.
+	ChangeActionCreate ChangeAction = "create"
+
+	// ChangeActionUpdate means the resource will be changed.
+	ChangeActionUpdate ChangeAction = "update"
+
+	// ChangeActionDelete means the resource will be removed.
+	ChangeActionDelete ChangeAction = "delete"
+
+	// ChangeActionUnchanged means the resource already matches.
+	ChangeActionUnchanged ChangeAction = "unchanged"
+)
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateUser creates a new user.
+func (client *Client) CreateUser(
+	ctx context.Context,
+	createUserRequest CreateUserRequest,
+) (*CreateUserResponse, error) {
+	var createUserResponse CreateUserResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		"/user",
+		nil,
+		nil,
+		&createUserRequest,
+		http.StatusCreated,
+		&createUserResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createUserResponse, nil
+}
+
+// GetUserByUsername fetches a user by their username.
+func (client *Client) GetUserByUsername(
+	ctx context.Context,
+	username string,
+) (*GetUserByUsernameResponse, error) {
+	return client.getUser(ctx, url.Values{"username": []string{username}})
+}
+
+// GetUserByEmail fetches a user by their email address, without regard
+// to case.
+func (client *Client) GetUserByEmail(
+	ctx context.Context,
+	email string,
+) (*GetUserByUsernameResponse, error) {
+	return client.getUser(ctx, url.Values{"email": []string{email}})
+}
+
+// GetUserByExternalID fetches a user by the ID they have in the system
+// they were provisioned from.
+func (client *Client) GetUserByExternalID(
+	ctx context.Context,
+	externalID string,
+) (*GetUserByUsernameResponse, error) {
+	return client.getUser(ctx, url.Values{"externalId": []string{externalID}})
+}
+
+func (client *Client) getUser(ctx context.Context, query url.Values) (*GetUserByUsernameResponse, error) {
+	var getUserByUsernameResponse GetUserByUsernameResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/user",
+		query,
+		nil,
+		nil,
+		http.StatusOK,
+		&getUserByUsernameResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getUserByUsernameResponse, nil
+}
+
+// ListUsers retrieves a page of users.
+func (client *Client) ListUsers(ctx context.Context, opts ListOpts) (*ListUsersResponse, error) {
+	var listUsersResponse ListUsersResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/user/list",
+		opts.query(),
+		nil,
+		nil,
+		http.StatusOK,
+		&listUsersResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listUsersResponse, nil
+}
+
+// UpdateUser changes a user. When expectedVersion isn't zero the
+// change is only made if the user is still at that version, otherwise
+// a PreconditionFailedError is returned.
+func (client *Client) UpdateUser(
+	ctx context.Context,
+	username string,
+	expectedVersion int64,
+	updateUserRequest UpdateUserRequest,
+) (*UpdateUserResponse, error) {
+	var updateUserResponse UpdateUserResponse
+	err := client.do(
+		ctx,
+		http.MethodPatch,
+		"/user/"+url.PathEscape(username),
+		nil,
+		ifMatch(expectedVersion),
+		&updateUserRequest,
+		http.StatusOK,
+		&updateUserResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updateUserResponse, nil
+}
+
+// DeleteUser removes a user. When expectedVersion isn't zero the user
+// is only removed if it is still at that version, otherwise a
+// PreconditionFailedError is returned.
+func (client *Client) DeleteUser(ctx context.Context, username string, expectedVersion int64) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		"/user/"+url.PathEscape(username),
+		nil,
+		ifMatch(expectedVersion),
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// watchEventConfig carries the latest config of the watched node.
+	watchEventConfig = "config"
+
+	// watchEventRemoved is sent when the watched node no longer exists.
+	watchEventRemoved = "removed"
+
+	// watchEventError is sent when the config couldn't be built.
+	watchEventError = "error"
+)
+
+// NodeConfigEvent is a new config sent to a watching node.
+type NodeConfigEvent struct {
+	// ID is the revision of the config. Passing it back when watching
+	// again skips the config if it hasn't changed since.
+	ID     string
+	Config *NodeConfig
+}
+
+// WatchNode streams the config of a node to the handler until the
+// stream ends, the context is done or the handler returns an error.
+// The last event ID is the revision the node already has, if any. The
+// ID of the last event that was handled is returned so that the
+// caller can keep watching from there. A NotFoundError is returned
+// once the node has been removed.
+//
+// The client's token has to be the node's secret.
+func (client *Client) WatchNode(
+	ctx context.Context,
+	id string,
+	lastEventID string,
+	handle func(event NodeConfigEvent) error,
+) (string, error) {
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		client.serverURL+"/node/"+url.PathEscape(id)+"/watch",
+		nil,
+	)
+	if err != nil {
+		return lastEventID, fmt.Errorf("Unable to create request: %w", err)
+	}
+
+	request.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	if client.token != "" {
+		request.Header.Set("Authorization", "Bearer "+client.token)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return lastEventID, fmt.Errorf("Unable to complete request: %w", err)
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode != http.StatusOK {
+		return lastEventID, newErrorFromResponse(response)
+	}
+
+	reader := bufio.NewReader(response.Body)
+	for {
+		event, err := readEvent(reader)
+		if errors.Is(err, io.EOF) {
+			return lastEventID, nil
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return lastEventID, ctx.Err()
+			}
+
+			return lastEventID, err
+		}
+
+		switch event.eventType {
+		case watchEventConfig:
+			var config NodeConfig
+			if err := json.Unmarshal([]byte(event.data), &config); err != nil {
+				return lastEventID, fmt.Errorf("Unable to parse node config: %w", err)
+			}
+
+			if err := handle(NodeConfigEvent{ID: event.id, Config: &config}); err != nil {
+				return lastEventID, err
+			}
+
+			lastEventID = event.id
+
+		case watchEventRemoved:
+			return lastEventID, NotFoundError{UserError: UserError{APIError: APIError{
+				StatusCode: http.StatusNotFound,
+				Message:    "The node has been removed",
+			}}}
+
+		case watchEventError:
+			var body errorResponse
+			_ = json.Unmarshal([]byte(event.data), &body)
+
+			return lastEventID, SystemError{APIError: APIError{
+				StatusCode: http.StatusInternalServerError,
+				Message:    body.Message,
+			}}
+		}
+	}
+}
+
+type serverSentEvent struct {
+	id        string
+	eventType string
+	data      string
+}
+
+// readEvent reads the next server-sent event, skipping comments.
+func readEvent(reader *bufio.Reader) (serverSentEvent, error) {
+	var event serverSentEvent
+	var dataLines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return event, fmt.Errorf("Unable to read event: %w", err)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if event.eventType == "" && dataLines == nil {
+				continue
+			}
+
+			event.data = strings.Join(dataLines, "\n")
+
+			return event, nil
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			event.id = value
+
+		case "event":
+			event.eventType = value
+
+		case "data":
+			dataLines = append(dataLines, value)
+		}
+	}
+}
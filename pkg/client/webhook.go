@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateWebhook subscribes a URL to events.
+func (client *Client) CreateWebhook(
+	ctx context.Context,
+	createWebhookRequest CreateWebhookRequest,
+) (*CreateWebhookResponse, error) {
+	var createWebhookResponse CreateWebhookResponse
+	err := client.do(
+		ctx,
+		http.MethodPost,
+		"/admin/webhook",
+		nil,
+		nil,
+		createWebhookRequest,
+		http.StatusCreated,
+		&createWebhookResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createWebhookResponse, nil
+}
+
+// ListWebhooks lists every webhook subscription.
+func (client *Client) ListWebhooks(ctx context.Context) (*ListWebhooksResponse, error) {
+	var listWebhooksResponse ListWebhooksResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/admin/webhook",
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&listWebhooksResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listWebhooksResponse, nil
+}
+
+// GetWebhook fetches a webhook subscription by its ID.
+func (client *Client) GetWebhook(ctx context.Context, id string) (*GetWebhookResponse, error) {
+	var getWebhookResponse GetWebhookResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/admin/webhook/"+url.PathEscape(id),
+		nil,
+		nil,
+		nil,
+		http.StatusOK,
+		&getWebhookResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getWebhookResponse, nil
+}
+
+// DeleteWebhook stops sending events to a webhook.
+func (client *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return client.do(
+		ctx,
+		http.MethodDelete,
+		"/admin/webhook/"+url.PathEscape(id),
+		nil,
+		nil,
+		nil,
+		http.StatusNoContent,
+		nil,
+	)
+}
+
+// ListWebhookDeliveries lists the most recent deliveries of a
+// webhook, newest first. A limit of zero uses the server's default.
+func (client *Client) ListWebhookDeliveries(
+	ctx context.Context,
+	id string,
+	limit int,
+) (*ListWebhookDeliveriesResponse, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var listWebhookDeliveriesResponse ListWebhookDeliveriesResponse
+	err := client.do(
+		ctx,
+		http.MethodGet,
+		"/admin/webhook/"+url.PathEscape(id)+"/deliveries",
+		query,
+		nil,
+		nil,
+		http.StatusOK,
+		&listWebhookDeliveriesResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listWebhookDeliveriesResponse, nil
+}